@@ -23,6 +23,7 @@ const (
 	CommentService_DeleteComment_FullMethodName  = "/comment.v1.CommentService/DeleteComment"
 	CommentService_CreateComment_FullMethodName  = "/comment.v1.CommentService/CreateComment"
 	CommentService_GetMoreReplies_FullMethodName = "/comment.v1.CommentService/GetMoreReplies"
+	CommentService_CountByBizIds_FullMethodName  = "/comment.v1.CommentService/CountByBizIds"
 )
 
 // CommentServiceClient is the client API for CommentService service.
@@ -36,6 +37,8 @@ type CommentServiceClient interface {
 	// CreateComment 创建评论
 	CreateComment(ctx context.Context, in *CreateCommentRequest, opts ...grpc.CallOption) (*CreateCommentResponse, error)
 	GetMoreReplies(ctx context.Context, in *GetMoreRepliesRequest, opts ...grpc.CallOption) (*GetMoreRepliesResponse, error)
+	// CountByBizIds 批量查询评论数，包括回复，热榜这些批量计算的场景用
+	CountByBizIds(ctx context.Context, in *CountByBizIdsRequest, opts ...grpc.CallOption) (*CountByBizIdsResponse, error)
 }
 
 type commentServiceClient struct {
@@ -82,6 +85,15 @@ func (c *commentServiceClient) GetMoreReplies(ctx context.Context, in *GetMoreRe
 	return out, nil
 }
 
+func (c *commentServiceClient) CountByBizIds(ctx context.Context, in *CountByBizIdsRequest, opts ...grpc.CallOption) (*CountByBizIdsResponse, error) {
+	out := new(CountByBizIdsResponse)
+	err := c.cc.Invoke(ctx, CommentService_CountByBizIds_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // CommentServiceServer is the server API for CommentService service.
 // All implementations must embed UnimplementedCommentServiceServer
 // for forward compatibility
@@ -93,6 +105,8 @@ type CommentServiceServer interface {
 	// CreateComment 创建评论
 	CreateComment(context.Context, *CreateCommentRequest) (*CreateCommentResponse, error)
 	GetMoreReplies(context.Context, *GetMoreRepliesRequest) (*GetMoreRepliesResponse, error)
+	// CountByBizIds 批量查询评论数，包括回复，热榜这些批量计算的场景用
+	CountByBizIds(context.Context, *CountByBizIdsRequest) (*CountByBizIdsResponse, error)
 	mustEmbedUnimplementedCommentServiceServer()
 }
 
@@ -112,6 +126,9 @@ func (UnimplementedCommentServiceServer) CreateComment(context.Context, *CreateC
 func (UnimplementedCommentServiceServer) GetMoreReplies(context.Context, *GetMoreRepliesRequest) (*GetMoreRepliesResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetMoreReplies not implemented")
 }
+func (UnimplementedCommentServiceServer) CountByBizIds(context.Context, *CountByBizIdsRequest) (*CountByBizIdsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CountByBizIds not implemented")
+}
 func (UnimplementedCommentServiceServer) mustEmbedUnimplementedCommentServiceServer() {}
 
 // UnsafeCommentServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -197,6 +214,24 @@ func _CommentService_GetMoreReplies_Handler(srv interface{}, ctx context.Context
 	return interceptor(ctx, in, info, handler)
 }
 
+func _CommentService_CountByBizIds_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CountByBizIdsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CommentServiceServer).CountByBizIds(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CommentService_CountByBizIds_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CommentServiceServer).CountByBizIds(ctx, req.(*CountByBizIdsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // CommentService_ServiceDesc is the grpc.ServiceDesc for CommentService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -220,6 +255,10 @@ var CommentService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetMoreReplies",
 			Handler:    _CommentService_GetMoreReplies_Handler,
 		},
+		{
+			MethodName: "CountByBizIds",
+			Handler:    _CommentService_CountByBizIds_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "comment/v1/comment.proto",
@@ -0,0 +1,287 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: webook/api/proto/gen/comment/v1/comment_grpc.pb.go
+//
+// Generated by this command:
+//
+//	mockgen -source=webook/api/proto/gen/comment/v1/comment_grpc.pb.go -package=commentmocks -destination=webook/api/proto/gen/comment/v1/mocks/comment_grpc.mock.go
+//
+
+// Package commentmocks is a generated GoMock package.
+package commentmocks
+
+import (
+	context "context"
+	reflect "reflect"
+	commentv1 "xiaoweishu/webook/api/proto/gen/comment/v1"
+
+	gomock "go.uber.org/mock/gomock"
+	grpc "google.golang.org/grpc"
+)
+
+// MockCommentServiceClient is a mock of CommentServiceClient interface.
+type MockCommentServiceClient struct {
+	ctrl     *gomock.Controller
+	recorder *MockCommentServiceClientMockRecorder
+}
+
+// MockCommentServiceClientMockRecorder is the mock recorder for MockCommentServiceClient.
+type MockCommentServiceClientMockRecorder struct {
+	mock *MockCommentServiceClient
+}
+
+// NewMockCommentServiceClient creates a new mock instance.
+func NewMockCommentServiceClient(ctrl *gomock.Controller) *MockCommentServiceClient {
+	mock := &MockCommentServiceClient{ctrl: ctrl}
+	mock.recorder = &MockCommentServiceClientMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockCommentServiceClient) EXPECT() *MockCommentServiceClientMockRecorder {
+	return m.recorder
+}
+
+// CountByBizIds mocks base method.
+func (m *MockCommentServiceClient) CountByBizIds(ctx context.Context, in *commentv1.CountByBizIdsRequest, opts ...grpc.CallOption) (*commentv1.CountByBizIdsResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "CountByBizIds", varargs...)
+	ret0, _ := ret[0].(*commentv1.CountByBizIdsResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountByBizIds indicates an expected call of CountByBizIds.
+func (mr *MockCommentServiceClientMockRecorder) CountByBizIds(ctx, in any, opts ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountByBizIds", reflect.TypeOf((*MockCommentServiceClient)(nil).CountByBizIds), varargs...)
+}
+
+// CreateComment mocks base method.
+func (m *MockCommentServiceClient) CreateComment(ctx context.Context, in *commentv1.CreateCommentRequest, opts ...grpc.CallOption) (*commentv1.CreateCommentResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "CreateComment", varargs...)
+	ret0, _ := ret[0].(*commentv1.CreateCommentResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateComment indicates an expected call of CreateComment.
+func (mr *MockCommentServiceClientMockRecorder) CreateComment(ctx, in any, opts ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateComment", reflect.TypeOf((*MockCommentServiceClient)(nil).CreateComment), varargs...)
+}
+
+// DeleteComment mocks base method.
+func (m *MockCommentServiceClient) DeleteComment(ctx context.Context, in *commentv1.DeleteCommentRequest, opts ...grpc.CallOption) (*commentv1.DeleteCommentResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DeleteComment", varargs...)
+	ret0, _ := ret[0].(*commentv1.DeleteCommentResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteComment indicates an expected call of DeleteComment.
+func (mr *MockCommentServiceClientMockRecorder) DeleteComment(ctx, in any, opts ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteComment", reflect.TypeOf((*MockCommentServiceClient)(nil).DeleteComment), varargs...)
+}
+
+// GetCommentList mocks base method.
+func (m *MockCommentServiceClient) GetCommentList(ctx context.Context, in *commentv1.CommentListRequest, opts ...grpc.CallOption) (*commentv1.CommentListResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetCommentList", varargs...)
+	ret0, _ := ret[0].(*commentv1.CommentListResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCommentList indicates an expected call of GetCommentList.
+func (mr *MockCommentServiceClientMockRecorder) GetCommentList(ctx, in any, opts ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCommentList", reflect.TypeOf((*MockCommentServiceClient)(nil).GetCommentList), varargs...)
+}
+
+// GetMoreReplies mocks base method.
+func (m *MockCommentServiceClient) GetMoreReplies(ctx context.Context, in *commentv1.GetMoreRepliesRequest, opts ...grpc.CallOption) (*commentv1.GetMoreRepliesResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetMoreReplies", varargs...)
+	ret0, _ := ret[0].(*commentv1.GetMoreRepliesResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMoreReplies indicates an expected call of GetMoreReplies.
+func (mr *MockCommentServiceClientMockRecorder) GetMoreReplies(ctx, in any, opts ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMoreReplies", reflect.TypeOf((*MockCommentServiceClient)(nil).GetMoreReplies), varargs...)
+}
+
+// MockCommentServiceServer is a mock of CommentServiceServer interface.
+type MockCommentServiceServer struct {
+	ctrl     *gomock.Controller
+	recorder *MockCommentServiceServerMockRecorder
+}
+
+// MockCommentServiceServerMockRecorder is the mock recorder for MockCommentServiceServer.
+type MockCommentServiceServerMockRecorder struct {
+	mock *MockCommentServiceServer
+}
+
+// NewMockCommentServiceServer creates a new mock instance.
+func NewMockCommentServiceServer(ctrl *gomock.Controller) *MockCommentServiceServer {
+	mock := &MockCommentServiceServer{ctrl: ctrl}
+	mock.recorder = &MockCommentServiceServerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockCommentServiceServer) EXPECT() *MockCommentServiceServerMockRecorder {
+	return m.recorder
+}
+
+// CountByBizIds mocks base method.
+func (m *MockCommentServiceServer) CountByBizIds(arg0 context.Context, arg1 *commentv1.CountByBizIdsRequest) (*commentv1.CountByBizIdsResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountByBizIds", arg0, arg1)
+	ret0, _ := ret[0].(*commentv1.CountByBizIdsResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountByBizIds indicates an expected call of CountByBizIds.
+func (mr *MockCommentServiceServerMockRecorder) CountByBizIds(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountByBizIds", reflect.TypeOf((*MockCommentServiceServer)(nil).CountByBizIds), arg0, arg1)
+}
+
+// CreateComment mocks base method.
+func (m *MockCommentServiceServer) CreateComment(arg0 context.Context, arg1 *commentv1.CreateCommentRequest) (*commentv1.CreateCommentResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateComment", arg0, arg1)
+	ret0, _ := ret[0].(*commentv1.CreateCommentResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateComment indicates an expected call of CreateComment.
+func (mr *MockCommentServiceServerMockRecorder) CreateComment(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateComment", reflect.TypeOf((*MockCommentServiceServer)(nil).CreateComment), arg0, arg1)
+}
+
+// DeleteComment mocks base method.
+func (m *MockCommentServiceServer) DeleteComment(arg0 context.Context, arg1 *commentv1.DeleteCommentRequest) (*commentv1.DeleteCommentResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteComment", arg0, arg1)
+	ret0, _ := ret[0].(*commentv1.DeleteCommentResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteComment indicates an expected call of DeleteComment.
+func (mr *MockCommentServiceServerMockRecorder) DeleteComment(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteComment", reflect.TypeOf((*MockCommentServiceServer)(nil).DeleteComment), arg0, arg1)
+}
+
+// GetCommentList mocks base method.
+func (m *MockCommentServiceServer) GetCommentList(arg0 context.Context, arg1 *commentv1.CommentListRequest) (*commentv1.CommentListResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCommentList", arg0, arg1)
+	ret0, _ := ret[0].(*commentv1.CommentListResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCommentList indicates an expected call of GetCommentList.
+func (mr *MockCommentServiceServerMockRecorder) GetCommentList(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCommentList", reflect.TypeOf((*MockCommentServiceServer)(nil).GetCommentList), arg0, arg1)
+}
+
+// GetMoreReplies mocks base method.
+func (m *MockCommentServiceServer) GetMoreReplies(arg0 context.Context, arg1 *commentv1.GetMoreRepliesRequest) (*commentv1.GetMoreRepliesResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMoreReplies", arg0, arg1)
+	ret0, _ := ret[0].(*commentv1.GetMoreRepliesResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMoreReplies indicates an expected call of GetMoreReplies.
+func (mr *MockCommentServiceServerMockRecorder) GetMoreReplies(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMoreReplies", reflect.TypeOf((*MockCommentServiceServer)(nil).GetMoreReplies), arg0, arg1)
+}
+
+// mustEmbedUnimplementedCommentServiceServer mocks base method.
+func (m *MockCommentServiceServer) mustEmbedUnimplementedCommentServiceServer() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "mustEmbedUnimplementedCommentServiceServer")
+}
+
+// mustEmbedUnimplementedCommentServiceServer indicates an expected call of mustEmbedUnimplementedCommentServiceServer.
+func (mr *MockCommentServiceServerMockRecorder) mustEmbedUnimplementedCommentServiceServer() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "mustEmbedUnimplementedCommentServiceServer", reflect.TypeOf((*MockCommentServiceServer)(nil).mustEmbedUnimplementedCommentServiceServer))
+}
+
+// MockUnsafeCommentServiceServer is a mock of UnsafeCommentServiceServer interface.
+type MockUnsafeCommentServiceServer struct {
+	ctrl     *gomock.Controller
+	recorder *MockUnsafeCommentServiceServerMockRecorder
+}
+
+// MockUnsafeCommentServiceServerMockRecorder is the mock recorder for MockUnsafeCommentServiceServer.
+type MockUnsafeCommentServiceServerMockRecorder struct {
+	mock *MockUnsafeCommentServiceServer
+}
+
+// NewMockUnsafeCommentServiceServer creates a new mock instance.
+func NewMockUnsafeCommentServiceServer(ctrl *gomock.Controller) *MockUnsafeCommentServiceServer {
+	mock := &MockUnsafeCommentServiceServer{ctrl: ctrl}
+	mock.recorder = &MockUnsafeCommentServiceServerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockUnsafeCommentServiceServer) EXPECT() *MockUnsafeCommentServiceServerMockRecorder {
+	return m.recorder
+}
+
+// mustEmbedUnimplementedCommentServiceServer mocks base method.
+func (m *MockUnsafeCommentServiceServer) mustEmbedUnimplementedCommentServiceServer() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "mustEmbedUnimplementedCommentServiceServer")
+}
+
+// mustEmbedUnimplementedCommentServiceServer indicates an expected call of mustEmbedUnimplementedCommentServiceServer.
+func (mr *MockUnsafeCommentServiceServerMockRecorder) mustEmbedUnimplementedCommentServiceServer() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "mustEmbedUnimplementedCommentServiceServer", reflect.TypeOf((*MockUnsafeCommentServiceServer)(nil).mustEmbedUnimplementedCommentServiceServer))
+}
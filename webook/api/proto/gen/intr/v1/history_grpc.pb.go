@@ -0,0 +1,220 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: intr/v1/history.proto
+
+package intrv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	ReadHistoryService_List_FullMethodName   = "/intr.v1.ReadHistoryService/List"
+	ReadHistoryService_Delete_FullMethodName = "/intr.v1.ReadHistoryService/Delete"
+	ReadHistoryService_Clear_FullMethodName  = "/intr.v1.ReadHistoryService/Clear"
+	ReadHistoryService_Pause_FullMethodName  = "/intr.v1.ReadHistoryService/Pause"
+)
+
+// ReadHistoryServiceClient is the client API for ReadHistoryService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ReadHistoryServiceClient interface {
+	List(ctx context.Context, in *ListReadHistoryRequest, opts ...grpc.CallOption) (*ListReadHistoryResponse, error)
+	Delete(ctx context.Context, in *DeleteReadHistoryRequest, opts ...grpc.CallOption) (*DeleteReadHistoryResponse, error)
+	Clear(ctx context.Context, in *ClearReadHistoryRequest, opts ...grpc.CallOption) (*ClearReadHistoryResponse, error)
+	Pause(ctx context.Context, in *PauseReadHistoryRequest, opts ...grpc.CallOption) (*PauseReadHistoryResponse, error)
+}
+
+type readHistoryServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewReadHistoryServiceClient(cc grpc.ClientConnInterface) ReadHistoryServiceClient {
+	return &readHistoryServiceClient{cc}
+}
+
+func (c *readHistoryServiceClient) List(ctx context.Context, in *ListReadHistoryRequest, opts ...grpc.CallOption) (*ListReadHistoryResponse, error) {
+	out := new(ListReadHistoryResponse)
+	err := c.cc.Invoke(ctx, ReadHistoryService_List_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *readHistoryServiceClient) Delete(ctx context.Context, in *DeleteReadHistoryRequest, opts ...grpc.CallOption) (*DeleteReadHistoryResponse, error) {
+	out := new(DeleteReadHistoryResponse)
+	err := c.cc.Invoke(ctx, ReadHistoryService_Delete_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *readHistoryServiceClient) Clear(ctx context.Context, in *ClearReadHistoryRequest, opts ...grpc.CallOption) (*ClearReadHistoryResponse, error) {
+	out := new(ClearReadHistoryResponse)
+	err := c.cc.Invoke(ctx, ReadHistoryService_Clear_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *readHistoryServiceClient) Pause(ctx context.Context, in *PauseReadHistoryRequest, opts ...grpc.CallOption) (*PauseReadHistoryResponse, error) {
+	out := new(PauseReadHistoryResponse)
+	err := c.cc.Invoke(ctx, ReadHistoryService_Pause_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ReadHistoryServiceServer is the server API for ReadHistoryService service.
+// All implementations must embed UnimplementedReadHistoryServiceServer
+// for forward compatibility
+type ReadHistoryServiceServer interface {
+	List(context.Context, *ListReadHistoryRequest) (*ListReadHistoryResponse, error)
+	Delete(context.Context, *DeleteReadHistoryRequest) (*DeleteReadHistoryResponse, error)
+	Clear(context.Context, *ClearReadHistoryRequest) (*ClearReadHistoryResponse, error)
+	Pause(context.Context, *PauseReadHistoryRequest) (*PauseReadHistoryResponse, error)
+	mustEmbedUnimplementedReadHistoryServiceServer()
+}
+
+// UnimplementedReadHistoryServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedReadHistoryServiceServer struct {
+}
+
+func (UnimplementedReadHistoryServiceServer) List(context.Context, *ListReadHistoryRequest) (*ListReadHistoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method List not implemented")
+}
+func (UnimplementedReadHistoryServiceServer) Delete(context.Context, *DeleteReadHistoryRequest) (*DeleteReadHistoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Delete not implemented")
+}
+func (UnimplementedReadHistoryServiceServer) Clear(context.Context, *ClearReadHistoryRequest) (*ClearReadHistoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Clear not implemented")
+}
+func (UnimplementedReadHistoryServiceServer) Pause(context.Context, *PauseReadHistoryRequest) (*PauseReadHistoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Pause not implemented")
+}
+func (UnimplementedReadHistoryServiceServer) mustEmbedUnimplementedReadHistoryServiceServer() {}
+
+// UnsafeReadHistoryServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ReadHistoryServiceServer will
+// result in compilation errors.
+type UnsafeReadHistoryServiceServer interface {
+	mustEmbedUnimplementedReadHistoryServiceServer()
+}
+
+func RegisterReadHistoryServiceServer(s grpc.ServiceRegistrar, srv ReadHistoryServiceServer) {
+	s.RegisterService(&ReadHistoryService_ServiceDesc, srv)
+}
+
+func _ReadHistoryService_List_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListReadHistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReadHistoryServiceServer).List(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ReadHistoryService_List_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReadHistoryServiceServer).List(ctx, req.(*ListReadHistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ReadHistoryService_Delete_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteReadHistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReadHistoryServiceServer).Delete(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ReadHistoryService_Delete_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReadHistoryServiceServer).Delete(ctx, req.(*DeleteReadHistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ReadHistoryService_Clear_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ClearReadHistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReadHistoryServiceServer).Clear(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ReadHistoryService_Clear_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReadHistoryServiceServer).Clear(ctx, req.(*ClearReadHistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ReadHistoryService_Pause_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PauseReadHistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReadHistoryServiceServer).Pause(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ReadHistoryService_Pause_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReadHistoryServiceServer).Pause(ctx, req.(*PauseReadHistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// ReadHistoryService_ServiceDesc is the grpc.ServiceDesc for ReadHistoryService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var ReadHistoryService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "intr.v1.ReadHistoryService",
+	HandlerType: (*ReadHistoryServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "List",
+			Handler:    _ReadHistoryService_List_Handler,
+		},
+		{
+			MethodName: "Delete",
+			Handler:    _ReadHistoryService_Delete_Handler,
+		},
+		{
+			MethodName: "Clear",
+			Handler:    _ReadHistoryService_Clear_Handler,
+		},
+		{
+			MethodName: "Pause",
+			Handler:    _ReadHistoryService_Pause_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "intr/v1/history.proto",
+}
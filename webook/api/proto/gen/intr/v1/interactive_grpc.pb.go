@@ -19,12 +19,14 @@ import (
 const _ = grpc.SupportPackageIsVersion7
 
 const (
-	InteractiveService_IncrReadCnt_FullMethodName = "/intr.v1.InteractiveService/IncrReadCnt"
-	InteractiveService_Like_FullMethodName        = "/intr.v1.InteractiveService/Like"
-	InteractiveService_CancelLike_FullMethodName  = "/intr.v1.InteractiveService/CancelLike"
-	InteractiveService_Collect_FullMethodName     = "/intr.v1.InteractiveService/Collect"
-	InteractiveService_Get_FullMethodName         = "/intr.v1.InteractiveService/Get"
-	InteractiveService_GetByIds_FullMethodName    = "/intr.v1.InteractiveService/GetByIds"
+	InteractiveService_IncrReadCnt_FullMethodName    = "/intr.v1.InteractiveService/IncrReadCnt"
+	InteractiveService_Like_FullMethodName           = "/intr.v1.InteractiveService/Like"
+	InteractiveService_CancelLike_FullMethodName     = "/intr.v1.InteractiveService/CancelLike"
+	InteractiveService_Collect_FullMethodName        = "/intr.v1.InteractiveService/Collect"
+	InteractiveService_Get_FullMethodName            = "/intr.v1.InteractiveService/Get"
+	InteractiveService_GetByIds_FullMethodName       = "/intr.v1.InteractiveService/GetByIds"
+	InteractiveService_React_FullMethodName          = "/intr.v1.InteractiveService/React"
+	InteractiveService_CancelReaction_FullMethodName = "/intr.v1.InteractiveService/CancelReaction"
 )
 
 // InteractiveServiceClient is the client API for InteractiveService service.
@@ -37,6 +39,8 @@ type InteractiveServiceClient interface {
 	Collect(ctx context.Context, in *CollectRequest, opts ...grpc.CallOption) (*CollectResponse, error)
 	Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*GetResponse, error)
 	GetByIds(ctx context.Context, in *GetByIdsRequest, opts ...grpc.CallOption) (*GetByIdsResponse, error)
+	React(ctx context.Context, in *ReactRequest, opts ...grpc.CallOption) (*ReactResponse, error)
+	CancelReaction(ctx context.Context, in *CancelReactionRequest, opts ...grpc.CallOption) (*CancelReactionResponse, error)
 }
 
 type interactiveServiceClient struct {
@@ -101,6 +105,24 @@ func (c *interactiveServiceClient) GetByIds(ctx context.Context, in *GetByIdsReq
 	return out, nil
 }
 
+func (c *interactiveServiceClient) React(ctx context.Context, in *ReactRequest, opts ...grpc.CallOption) (*ReactResponse, error) {
+	out := new(ReactResponse)
+	err := c.cc.Invoke(ctx, InteractiveService_React_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *interactiveServiceClient) CancelReaction(ctx context.Context, in *CancelReactionRequest, opts ...grpc.CallOption) (*CancelReactionResponse, error) {
+	out := new(CancelReactionResponse)
+	err := c.cc.Invoke(ctx, InteractiveService_CancelReaction_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // InteractiveServiceServer is the server API for InteractiveService service.
 // All implementations must embed UnimplementedInteractiveServiceServer
 // for forward compatibility
@@ -111,6 +133,8 @@ type InteractiveServiceServer interface {
 	Collect(context.Context, *CollectRequest) (*CollectResponse, error)
 	Get(context.Context, *GetRequest) (*GetResponse, error)
 	GetByIds(context.Context, *GetByIdsRequest) (*GetByIdsResponse, error)
+	React(context.Context, *ReactRequest) (*ReactResponse, error)
+	CancelReaction(context.Context, *CancelReactionRequest) (*CancelReactionResponse, error)
 	mustEmbedUnimplementedInteractiveServiceServer()
 }
 
@@ -136,6 +160,12 @@ func (UnimplementedInteractiveServiceServer) Get(context.Context, *GetRequest) (
 func (UnimplementedInteractiveServiceServer) GetByIds(context.Context, *GetByIdsRequest) (*GetByIdsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetByIds not implemented")
 }
+func (UnimplementedInteractiveServiceServer) React(context.Context, *ReactRequest) (*ReactResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method React not implemented")
+}
+func (UnimplementedInteractiveServiceServer) CancelReaction(context.Context, *CancelReactionRequest) (*CancelReactionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelReaction not implemented")
+}
 func (UnimplementedInteractiveServiceServer) mustEmbedUnimplementedInteractiveServiceServer() {}
 
 // UnsafeInteractiveServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -257,6 +287,42 @@ func _InteractiveService_GetByIds_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _InteractiveService_React_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReactRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InteractiveServiceServer).React(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: InteractiveService_React_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InteractiveServiceServer).React(ctx, req.(*ReactRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _InteractiveService_CancelReaction_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelReactionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InteractiveServiceServer).CancelReaction(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: InteractiveService_CancelReaction_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InteractiveServiceServer).CancelReaction(ctx, req.(*CancelReactionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // InteractiveService_ServiceDesc is the grpc.ServiceDesc for InteractiveService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -288,6 +354,14 @@ var InteractiveService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetByIds",
 			Handler:    _InteractiveService_GetByIds_Handler,
 		},
+		{
+			MethodName: "React",
+			Handler:    _InteractiveService_React_Handler,
+		},
+		{
+			MethodName: "CancelReaction",
+			Handler:    _InteractiveService_CancelReaction_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "intr/v1/interactive.proto",
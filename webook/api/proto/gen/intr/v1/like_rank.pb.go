@@ -0,0 +1,334 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.32.0
+// 	protoc        (unknown)
+// source: intr/v1/like_rank.proto
+
+package intrv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type LikeRankItem struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Biz     string `protobuf:"bytes,1,opt,name=biz,proto3" json:"biz,omitempty"`
+	BizId   int64  `protobuf:"varint,2,opt,name=biz_id,json=bizId,proto3" json:"biz_id,omitempty"`
+	LikeCnt int64  `protobuf:"varint,3,opt,name=like_cnt,json=likeCnt,proto3" json:"like_cnt,omitempty"`
+}
+
+func (x *LikeRankItem) Reset() {
+	*x = LikeRankItem{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_intr_v1_like_rank_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *LikeRankItem) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LikeRankItem) ProtoMessage() {}
+
+func (x *LikeRankItem) ProtoReflect() protoreflect.Message {
+	mi := &file_intr_v1_like_rank_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LikeRankItem.ProtoReflect.Descriptor instead.
+func (*LikeRankItem) Descriptor() ([]byte, []int) {
+	return file_intr_v1_like_rank_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *LikeRankItem) GetBiz() string {
+	if x != nil {
+		return x.Biz
+	}
+	return ""
+}
+
+func (x *LikeRankItem) GetBizId() int64 {
+	if x != nil {
+		return x.BizId
+	}
+	return 0
+}
+
+func (x *LikeRankItem) GetLikeCnt() int64 {
+	if x != nil {
+		return x.LikeCnt
+	}
+	return 0
+}
+
+type LikeRankTopNRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Biz    string `protobuf:"bytes,1,opt,name=biz,proto3" json:"biz,omitempty"`
+	Window string `protobuf:"bytes,2,opt,name=window,proto3" json:"window,omitempty"`
+	Offset int32  `protobuf:"varint,3,opt,name=offset,proto3" json:"offset,omitempty"`
+	Limit  int32  `protobuf:"varint,4,opt,name=limit,proto3" json:"limit,omitempty"`
+}
+
+func (x *LikeRankTopNRequest) Reset() {
+	*x = LikeRankTopNRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_intr_v1_like_rank_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *LikeRankTopNRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LikeRankTopNRequest) ProtoMessage() {}
+
+func (x *LikeRankTopNRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_intr_v1_like_rank_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LikeRankTopNRequest.ProtoReflect.Descriptor instead.
+func (*LikeRankTopNRequest) Descriptor() ([]byte, []int) {
+	return file_intr_v1_like_rank_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *LikeRankTopNRequest) GetBiz() string {
+	if x != nil {
+		return x.Biz
+	}
+	return ""
+}
+
+func (x *LikeRankTopNRequest) GetWindow() string {
+	if x != nil {
+		return x.Window
+	}
+	return ""
+}
+
+func (x *LikeRankTopNRequest) GetOffset() int32 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *LikeRankTopNRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type LikeRankTopNResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Items []*LikeRankItem `protobuf:"bytes,1,rep,name=items,proto3" json:"items,omitempty"`
+}
+
+func (x *LikeRankTopNResponse) Reset() {
+	*x = LikeRankTopNResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_intr_v1_like_rank_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *LikeRankTopNResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LikeRankTopNResponse) ProtoMessage() {}
+
+func (x *LikeRankTopNResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_intr_v1_like_rank_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LikeRankTopNResponse.ProtoReflect.Descriptor instead.
+func (*LikeRankTopNResponse) Descriptor() ([]byte, []int) {
+	return file_intr_v1_like_rank_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *LikeRankTopNResponse) GetItems() []*LikeRankItem {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+var File_intr_v1_like_rank_proto protoreflect.FileDescriptor
+
+var file_intr_v1_like_rank_proto_rawDesc = []byte{
+	0x0a, 0x17, 0x69, 0x6e, 0x74, 0x72, 0x2f, 0x76, 0x31, 0x2f, 0x6c, 0x69, 0x6b, 0x65, 0x5f, 0x72,
+	0x61, 0x6e, 0x6b, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x07, 0x69, 0x6e, 0x74, 0x72, 0x2e,
+	0x76, 0x31, 0x22, 0x52, 0x0a, 0x0c, 0x4c, 0x69, 0x6b, 0x65, 0x52, 0x61, 0x6e, 0x6b, 0x49, 0x74,
+	0x65, 0x6d, 0x12, 0x10, 0x0a, 0x03, 0x62, 0x69, 0x7a, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x03, 0x62, 0x69, 0x7a, 0x12, 0x15, 0x0a, 0x06, 0x62, 0x69, 0x7a, 0x5f, 0x69, 0x64, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x03, 0x52, 0x05, 0x62, 0x69, 0x7a, 0x49, 0x64, 0x12, 0x19, 0x0a, 0x08, 0x6c,
+	0x69, 0x6b, 0x65, 0x5f, 0x63, 0x6e, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x07, 0x6c,
+	0x69, 0x6b, 0x65, 0x43, 0x6e, 0x74, 0x22, 0x6d, 0x0a, 0x13, 0x4c, 0x69, 0x6b, 0x65, 0x52, 0x61,
+	0x6e, 0x6b, 0x54, 0x6f, 0x70, 0x4e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x10, 0x0a,
+	0x03, 0x62, 0x69, 0x7a, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x62, 0x69, 0x7a, 0x12,
+	0x16, 0x0a, 0x06, 0x77, 0x69, 0x6e, 0x64, 0x6f, 0x77, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x06, 0x77, 0x69, 0x6e, 0x64, 0x6f, 0x77, 0x12, 0x16, 0x0a, 0x06, 0x6f, 0x66, 0x66, 0x73, 0x65,
+	0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x06, 0x6f, 0x66, 0x66, 0x73, 0x65, 0x74, 0x12,
+	0x14, 0x0a, 0x05, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x05, 0x52, 0x05,
+	0x6c, 0x69, 0x6d, 0x69, 0x74, 0x22, 0x43, 0x0a, 0x14, 0x4c, 0x69, 0x6b, 0x65, 0x52, 0x61, 0x6e,
+	0x6b, 0x54, 0x6f, 0x70, 0x4e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x2b, 0x0a,
+	0x05, 0x69, 0x74, 0x65, 0x6d, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x15, 0x2e, 0x69,
+	0x6e, 0x74, 0x72, 0x2e, 0x76, 0x31, 0x2e, 0x4c, 0x69, 0x6b, 0x65, 0x52, 0x61, 0x6e, 0x6b, 0x49,
+	0x74, 0x65, 0x6d, 0x52, 0x05, 0x69, 0x74, 0x65, 0x6d, 0x73, 0x32, 0x56, 0x0a, 0x0f, 0x4c, 0x69,
+	0x6b, 0x65, 0x52, 0x61, 0x6e, 0x6b, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x43, 0x0a,
+	0x04, 0x54, 0x6f, 0x70, 0x4e, 0x12, 0x1c, 0x2e, 0x69, 0x6e, 0x74, 0x72, 0x2e, 0x76, 0x31, 0x2e,
+	0x4c, 0x69, 0x6b, 0x65, 0x52, 0x61, 0x6e, 0x6b, 0x54, 0x6f, 0x70, 0x4e, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x1a, 0x1d, 0x2e, 0x69, 0x6e, 0x74, 0x72, 0x2e, 0x76, 0x31, 0x2e, 0x4c, 0x69,
+	0x6b, 0x65, 0x52, 0x61, 0x6e, 0x6b, 0x54, 0x6f, 0x70, 0x4e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
+	0x73, 0x65, 0x42, 0x9b, 0x01, 0x0a, 0x0b, 0x63, 0x6f, 0x6d, 0x2e, 0x69, 0x6e, 0x74, 0x72, 0x2e,
+	0x76, 0x31, 0x42, 0x0e, 0x4c, 0x69, 0x6b, 0x65, 0x5f, 0x72, 0x61, 0x6e, 0x6b, 0x50, 0x72, 0x6f,
+	0x74, 0x6f, 0x50, 0x01, 0x5a, 0x3f, 0x67, 0x69, 0x74, 0x65, 0x65, 0x2e, 0x63, 0x6f, 0x6d, 0x2f,
+	0x67, 0x65, 0x65, 0x6b, 0x62, 0x61, 0x6e, 0x67, 0x2f, 0x62, 0x61, 0x73, 0x69, 0x63, 0x2d, 0x67,
+	0x6f, 0x2f, 0x77, 0x65, 0x62, 0x6f, 0x6f, 0x6b, 0x2f, 0x61, 0x70, 0x69, 0x2f, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x2f, 0x67, 0x65, 0x6e, 0x2f, 0x69, 0x6e, 0x74, 0x72, 0x2f, 0x76, 0x31, 0x3b, 0x69,
+	0x6e, 0x74, 0x72, 0x76, 0x31, 0xa2, 0x02, 0x03, 0x49, 0x58, 0x58, 0xaa, 0x02, 0x07, 0x49, 0x6e,
+	0x74, 0x72, 0x2e, 0x56, 0x31, 0xca, 0x02, 0x07, 0x49, 0x6e, 0x74, 0x72, 0x5c, 0x56, 0x31, 0xe2,
+	0x02, 0x13, 0x49, 0x6e, 0x74, 0x72, 0x5c, 0x56, 0x31, 0x5c, 0x47, 0x50, 0x42, 0x4d, 0x65, 0x74,
+	0x61, 0x64, 0x61, 0x74, 0x61, 0xea, 0x02, 0x08, 0x49, 0x6e, 0x74, 0x72, 0x3a, 0x3a, 0x56, 0x31,
+	0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_intr_v1_like_rank_proto_rawDescOnce sync.Once
+	file_intr_v1_like_rank_proto_rawDescData = file_intr_v1_like_rank_proto_rawDesc
+)
+
+func file_intr_v1_like_rank_proto_rawDescGZIP() []byte {
+	file_intr_v1_like_rank_proto_rawDescOnce.Do(func() {
+		file_intr_v1_like_rank_proto_rawDescData = protoimpl.X.CompressGZIP(file_intr_v1_like_rank_proto_rawDescData)
+	})
+	return file_intr_v1_like_rank_proto_rawDescData
+}
+
+var file_intr_v1_like_rank_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_intr_v1_like_rank_proto_goTypes = []interface{}{
+	(*LikeRankItem)(nil),         // 0: intr.v1.LikeRankItem
+	(*LikeRankTopNRequest)(nil),  // 1: intr.v1.LikeRankTopNRequest
+	(*LikeRankTopNResponse)(nil), // 2: intr.v1.LikeRankTopNResponse
+}
+var file_intr_v1_like_rank_proto_depIdxs = []int32{
+	0, // 0: intr.v1.LikeRankTopNResponse.items:type_name -> intr.v1.LikeRankItem
+	1, // 1: intr.v1.LikeRankService.TopN:input_type -> intr.v1.LikeRankTopNRequest
+	2, // 2: intr.v1.LikeRankService.TopN:output_type -> intr.v1.LikeRankTopNResponse
+	2, // [2:3] is the sub-list for method output_type
+	1, // [1:2] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_intr_v1_like_rank_proto_init() }
+func file_intr_v1_like_rank_proto_init() {
+	if File_intr_v1_like_rank_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_intr_v1_like_rank_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*LikeRankItem); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_intr_v1_like_rank_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*LikeRankTopNRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_intr_v1_like_rank_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*LikeRankTopNResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_intr_v1_like_rank_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   3,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_intr_v1_like_rank_proto_goTypes,
+		DependencyIndexes: file_intr_v1_like_rank_proto_depIdxs,
+		MessageInfos:      file_intr_v1_like_rank_proto_msgTypes,
+	}.Build()
+	File_intr_v1_like_rank_proto = out.File
+	file_intr_v1_like_rank_proto_rawDesc = nil
+	file_intr_v1_like_rank_proto_goTypes = nil
+	file_intr_v1_like_rank_proto_depIdxs = nil
+}
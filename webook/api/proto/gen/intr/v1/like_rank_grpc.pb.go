@@ -0,0 +1,109 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: intr/v1/like_rank.proto
+
+package intrv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	LikeRankService_TopN_FullMethodName = "/intr.v1.LikeRankService/TopN"
+)
+
+// LikeRankServiceClient is the client API for LikeRankService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type LikeRankServiceClient interface {
+	TopN(ctx context.Context, in *LikeRankTopNRequest, opts ...grpc.CallOption) (*LikeRankTopNResponse, error)
+}
+
+type likeRankServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewLikeRankServiceClient(cc grpc.ClientConnInterface) LikeRankServiceClient {
+	return &likeRankServiceClient{cc}
+}
+
+func (c *likeRankServiceClient) TopN(ctx context.Context, in *LikeRankTopNRequest, opts ...grpc.CallOption) (*LikeRankTopNResponse, error) {
+	out := new(LikeRankTopNResponse)
+	err := c.cc.Invoke(ctx, LikeRankService_TopN_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// LikeRankServiceServer is the server API for LikeRankService service.
+// All implementations must embed UnimplementedLikeRankServiceServer
+// for forward compatibility
+type LikeRankServiceServer interface {
+	TopN(context.Context, *LikeRankTopNRequest) (*LikeRankTopNResponse, error)
+	mustEmbedUnimplementedLikeRankServiceServer()
+}
+
+// UnimplementedLikeRankServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedLikeRankServiceServer struct {
+}
+
+func (UnimplementedLikeRankServiceServer) TopN(context.Context, *LikeRankTopNRequest) (*LikeRankTopNResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method TopN not implemented")
+}
+func (UnimplementedLikeRankServiceServer) mustEmbedUnimplementedLikeRankServiceServer() {}
+
+// UnsafeLikeRankServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to LikeRankServiceServer will
+// result in compilation errors.
+type UnsafeLikeRankServiceServer interface {
+	mustEmbedUnimplementedLikeRankServiceServer()
+}
+
+func RegisterLikeRankServiceServer(s grpc.ServiceRegistrar, srv LikeRankServiceServer) {
+	s.RegisterService(&LikeRankService_ServiceDesc, srv)
+}
+
+func _LikeRankService_TopN_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LikeRankTopNRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LikeRankServiceServer).TopN(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LikeRankService_TopN_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LikeRankServiceServer).TopN(ctx, req.(*LikeRankTopNRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// LikeRankService_ServiceDesc is the grpc.ServiceDesc for LikeRankService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var LikeRankService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "intr.v1.LikeRankService",
+	HandlerType: (*LikeRankServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "TopN",
+			Handler:    _LikeRankService_TopN_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "intr/v1/like_rank.proto",
+}
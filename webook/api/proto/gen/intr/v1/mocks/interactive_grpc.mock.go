@@ -5,14 +5,15 @@
 //
 //	mockgen -source=webook/api/proto/gen/intr/v1/interactive_grpc.pb.go -package=intrmocks -destination=webook/api/proto/gen/intr/v1/mocks/interactive_grpc.mock.go
 //
+
 // Package intrmocks is a generated GoMock package.
 package intrmocks
 
 import (
 	context "context"
 	reflect "reflect"
+	intrv1 "xiaoweishu/webook/api/proto/gen/intr/v1"
 
-	intrv1 "gitee.com/geekbang/basic-go/webook/api/proto/gen/intr/v1"
 	gomock "go.uber.org/mock/gomock"
 	grpc "google.golang.org/grpc"
 )
@@ -60,6 +61,26 @@ func (mr *MockInteractiveServiceClientMockRecorder) CancelLike(ctx, in any, opts
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelLike", reflect.TypeOf((*MockInteractiveServiceClient)(nil).CancelLike), varargs...)
 }
 
+// CancelReaction mocks base method.
+func (m *MockInteractiveServiceClient) CancelReaction(ctx context.Context, in *intrv1.CancelReactionRequest, opts ...grpc.CallOption) (*intrv1.CancelReactionResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "CancelReaction", varargs...)
+	ret0, _ := ret[0].(*intrv1.CancelReactionResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CancelReaction indicates an expected call of CancelReaction.
+func (mr *MockInteractiveServiceClientMockRecorder) CancelReaction(ctx, in any, opts ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelReaction", reflect.TypeOf((*MockInteractiveServiceClient)(nil).CancelReaction), varargs...)
+}
+
 // Collect mocks base method.
 func (m *MockInteractiveServiceClient) Collect(ctx context.Context, in *intrv1.CollectRequest, opts ...grpc.CallOption) (*intrv1.CollectResponse, error) {
 	m.ctrl.T.Helper()
@@ -160,6 +181,26 @@ func (mr *MockInteractiveServiceClientMockRecorder) Like(ctx, in any, opts ...an
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Like", reflect.TypeOf((*MockInteractiveServiceClient)(nil).Like), varargs...)
 }
 
+// React mocks base method.
+func (m *MockInteractiveServiceClient) React(ctx context.Context, in *intrv1.ReactRequest, opts ...grpc.CallOption) (*intrv1.ReactResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "React", varargs...)
+	ret0, _ := ret[0].(*intrv1.ReactResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// React indicates an expected call of React.
+func (mr *MockInteractiveServiceClientMockRecorder) React(ctx, in any, opts ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "React", reflect.TypeOf((*MockInteractiveServiceClient)(nil).React), varargs...)
+}
+
 // MockInteractiveServiceServer is a mock of InteractiveServiceServer interface.
 type MockInteractiveServiceServer struct {
 	ctrl     *gomock.Controller
@@ -198,6 +239,21 @@ func (mr *MockInteractiveServiceServerMockRecorder) CancelLike(arg0, arg1 any) *
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelLike", reflect.TypeOf((*MockInteractiveServiceServer)(nil).CancelLike), arg0, arg1)
 }
 
+// CancelReaction mocks base method.
+func (m *MockInteractiveServiceServer) CancelReaction(arg0 context.Context, arg1 *intrv1.CancelReactionRequest) (*intrv1.CancelReactionResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CancelReaction", arg0, arg1)
+	ret0, _ := ret[0].(*intrv1.CancelReactionResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CancelReaction indicates an expected call of CancelReaction.
+func (mr *MockInteractiveServiceServerMockRecorder) CancelReaction(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelReaction", reflect.TypeOf((*MockInteractiveServiceServer)(nil).CancelReaction), arg0, arg1)
+}
+
 // Collect mocks base method.
 func (m *MockInteractiveServiceServer) Collect(arg0 context.Context, arg1 *intrv1.CollectRequest) (*intrv1.CollectResponse, error) {
 	m.ctrl.T.Helper()
@@ -273,6 +329,21 @@ func (mr *MockInteractiveServiceServerMockRecorder) Like(arg0, arg1 any) *gomock
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Like", reflect.TypeOf((*MockInteractiveServiceServer)(nil).Like), arg0, arg1)
 }
 
+// React mocks base method.
+func (m *MockInteractiveServiceServer) React(arg0 context.Context, arg1 *intrv1.ReactRequest) (*intrv1.ReactResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "React", arg0, arg1)
+	ret0, _ := ret[0].(*intrv1.ReactResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// React indicates an expected call of React.
+func (mr *MockInteractiveServiceServerMockRecorder) React(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "React", reflect.TypeOf((*MockInteractiveServiceServer)(nil).React), arg0, arg1)
+}
+
 // mustEmbedUnimplementedInteractiveServiceServer mocks base method.
 func (m *MockInteractiveServiceServer) mustEmbedUnimplementedInteractiveServiceServer() {
 	m.ctrl.T.Helper()
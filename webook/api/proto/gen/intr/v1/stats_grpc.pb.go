@@ -0,0 +1,110 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: intr/v1/stats.proto
+
+package intrv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	InteractiveStatsService_Dashboard_FullMethodName = "/intr.v1.InteractiveStatsService/Dashboard"
+)
+
+// InteractiveStatsServiceClient is the client API for InteractiveStatsService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type InteractiveStatsServiceClient interface {
+	Dashboard(ctx context.Context, in *DashboardRequest, opts ...grpc.CallOption) (*DashboardResponse, error)
+}
+
+type interactiveStatsServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewInteractiveStatsServiceClient(cc grpc.ClientConnInterface) InteractiveStatsServiceClient {
+	return &interactiveStatsServiceClient{cc}
+}
+
+func (c *interactiveStatsServiceClient) Dashboard(ctx context.Context, in *DashboardRequest, opts ...grpc.CallOption) (*DashboardResponse, error) {
+	out := new(DashboardResponse)
+	err := c.cc.Invoke(ctx, InteractiveStatsService_Dashboard_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// InteractiveStatsServiceServer is the server API for InteractiveStatsService service.
+// All implementations must embed UnimplementedInteractiveStatsServiceServer
+// for forward compatibility
+type InteractiveStatsServiceServer interface {
+	Dashboard(context.Context, *DashboardRequest) (*DashboardResponse, error)
+	mustEmbedUnimplementedInteractiveStatsServiceServer()
+}
+
+// UnimplementedInteractiveStatsServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedInteractiveStatsServiceServer struct {
+}
+
+func (UnimplementedInteractiveStatsServiceServer) Dashboard(context.Context, *DashboardRequest) (*DashboardResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Dashboard not implemented")
+}
+func (UnimplementedInteractiveStatsServiceServer) mustEmbedUnimplementedInteractiveStatsServiceServer() {
+}
+
+// UnsafeInteractiveStatsServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to InteractiveStatsServiceServer will
+// result in compilation errors.
+type UnsafeInteractiveStatsServiceServer interface {
+	mustEmbedUnimplementedInteractiveStatsServiceServer()
+}
+
+func RegisterInteractiveStatsServiceServer(s grpc.ServiceRegistrar, srv InteractiveStatsServiceServer) {
+	s.RegisterService(&InteractiveStatsService_ServiceDesc, srv)
+}
+
+func _InteractiveStatsService_Dashboard_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DashboardRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InteractiveStatsServiceServer).Dashboard(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: InteractiveStatsService_Dashboard_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InteractiveStatsServiceServer).Dashboard(ctx, req.(*DashboardRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// InteractiveStatsService_ServiceDesc is the grpc.ServiceDesc for InteractiveStatsService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var InteractiveStatsService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "intr.v1.InteractiveStatsService",
+	HandlerType: (*InteractiveStatsServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Dashboard",
+			Handler:    _InteractiveStatsService_Dashboard_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "intr/v1/stats.proto",
+}
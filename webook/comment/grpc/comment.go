@@ -66,6 +66,16 @@ func (c *CommentServiceServer) CreateComment(ctx context.Context, request *comme
 	return &commentv1.CreateCommentResponse{}, err
 }
 
+func (c *CommentServiceServer) CountByBizIds(ctx context.Context, request *commentv1.CountByBizIdsRequest) (*commentv1.CountByBizIdsResponse, error) {
+	cnts, err := c.svc.CountByBizIds(ctx, request.GetBiz(), request.GetBizids())
+	if err != nil {
+		return nil, err
+	}
+	return &commentv1.CountByBizIdsResponse{
+		Cnts: cnts,
+	}, nil
+}
+
 func (c *CommentServiceServer) toDTO(domainComments []domain.Comment) []*commentv1.Comment {
 	rpcComments := make([]*commentv1.Comment, 0, len(domainComments))
 	for _, domainComment := range domainComments {
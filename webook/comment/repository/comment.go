@@ -22,6 +22,8 @@ type CommentRepository interface {
 	// GetCommentByIds 获取单条评论 支持批量获取
 	GetCommentByIds(ctx context.Context, id []int64) ([]domain.Comment, error)
 	GetMoreReplies(ctx context.Context, rid int64, id int64, limit int64) ([]domain.Comment, error)
+	// CountByBizIds 批量查询评论数，没有评论的资源不在结果里面
+	CountByBizIds(ctx context.Context, biz string, bizIds []int64) (map[int64]int64, error)
 }
 
 type CachedCommentRepo struct {
@@ -83,6 +85,10 @@ func (c *CachedCommentRepo) CreateComment(ctx context.Context, comment domain.Co
 	return c.dao.Insert(ctx, c.toEntity(comment))
 }
 
+func (c *CachedCommentRepo) CountByBizIds(ctx context.Context, biz string, bizIds []int64) (map[int64]int64, error) {
+	return c.dao.CountByBizIds(ctx, biz, bizIds)
+}
+
 func (c *CachedCommentRepo) GetCommentByIds(ctx context.Context, ids []int64) ([]domain.Comment, error) {
 	vals, err := c.dao.FindOneByIDs(ctx, ids)
 	if err != nil {
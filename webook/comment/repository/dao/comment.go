@@ -22,6 +22,8 @@ type CommentDAO interface {
 	Delete(ctx context.Context, u Comment) error
 	FindOneByIDs(ctx context.Context, id []int64) ([]Comment, error)
 	FindRepliesByRid(ctx context.Context, rid int64, id int64, limit int64) ([]Comment, error)
+	// CountByBizIds 每个资源的评论数，包括回复，没有评论的资源不在结果里面
+	CountByBizIds(ctx context.Context, biz string, bizIds []int64) (map[int64]int64, error)
 }
 
 type TreeBase struct {
@@ -81,6 +83,28 @@ func NewCommentDAO(db *gorm.DB) CommentDAO {
 	}
 }
 
+func (c *GORMCommentDAO) CountByBizIds(ctx context.Context, biz string, bizIds []int64) (map[int64]int64, error) {
+	type cnt struct {
+		BizID int64
+		Cnt   int64
+	}
+	var cnts []cnt
+	// 走 biz_type_id 这个索引
+	err := c.db.WithContext(ctx).Model(&Comment{}).
+		Select("biz_id", "COUNT(*) AS cnt").
+		Where("biz = ? AND biz_id IN ?", biz, bizIds).
+		Group("biz_id").
+		Scan(&cnts).Error
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[int64]int64, len(cnts))
+	for _, item := range cnts {
+		res[item.BizID] = item.Cnt
+	}
+	return res, nil
+}
+
 func (c *GORMCommentDAO) FindOneByIDs(ctx context.Context, ids []int64) ([]Comment, error) {
 	var res []Comment
 	err := c.db.WithContext(ctx).
@@ -5,14 +5,15 @@
 //
 //	mockgen -source=./comment.go -package=daomocks -destination=mocks/comment.mock.go CommentDAO
 //
+
 // Package daomocks is a generated GoMock package.
 package daomocks
 
 import (
 	context "context"
 	reflect "reflect"
+	dao "xiaoweishu/webook/comment/repository/dao"
 
-	dao "gitee.com/geekbang/basic-go/webook/comment/repository/dao"
 	gomock "go.uber.org/mock/gomock"
 )
 
@@ -39,6 +40,21 @@ func (m *MockCommentDAO) EXPECT() *MockCommentDAOMockRecorder {
 	return m.recorder
 }
 
+// CountByBizIds mocks base method.
+func (m *MockCommentDAO) CountByBizIds(ctx context.Context, biz string, bizIds []int64) (map[int64]int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountByBizIds", ctx, biz, bizIds)
+	ret0, _ := ret[0].(map[int64]int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountByBizIds indicates an expected call of CountByBizIds.
+func (mr *MockCommentDAOMockRecorder) CountByBizIds(ctx, biz, bizIds any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountByBizIds", reflect.TypeOf((*MockCommentDAO)(nil).CountByBizIds), ctx, biz, bizIds)
+}
+
 // Delete mocks base method.
 func (m *MockCommentDAO) Delete(ctx context.Context, u dao.Comment) error {
 	m.ctrl.T.Helper()
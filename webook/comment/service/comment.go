@@ -15,6 +15,8 @@ type CommentService interface {
 	// CreateComment 创建评论
 	CreateComment(ctx context.Context, comment domain.Comment) error
 	GetMoreReplies(ctx context.Context, rid int64, maxID int64, limit int64) ([]domain.Comment, error)
+	// CountByBizIds 批量查询评论数，包括回复
+	CountByBizIds(ctx context.Context, biz string, bizIds []int64) (map[int64]int64, error)
 }
 
 type commentService struct {
@@ -51,3 +53,10 @@ func (c *commentService) DeleteComment(ctx context.Context, id int64) error {
 func (c *commentService) CreateComment(ctx context.Context, comment domain.Comment) error {
 	return c.repo.CreateComment(ctx, comment)
 }
+
+func (c *commentService) CountByBizIds(ctx context.Context, biz string, bizIds []int64) (map[int64]int64, error) {
+	if len(bizIds) == 0 {
+		return map[int64]int64{}, nil
+	}
+	return c.repo.CountByBizIds(ctx, biz, bizIds)
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"xiaoweishu/webook/interactive/ioc"
 	"xiaoweishu/webook/internal/events"
 	"xiaoweishu/webook/pkg/ginx"
 	"xiaoweishu/webook/pkg/grpcx"
@@ -10,4 +11,6 @@ type App struct {
 	consumers   []events.Consumer
 	server      *grpcx.Server
 	adminServer *ginx.Server
+	// intrAdminServer 交互服务自己的运维接口
+	intrAdminServer ioc.AdminServer
 }
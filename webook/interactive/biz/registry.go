@@ -0,0 +1,146 @@
+package biz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+	"xiaoweishu/webook/pkg/limiter"
+	"xiaoweishu/webook/pkg/logger"
+)
+
+var (
+	ErrUnknownBiz       = errors.New("未知的 biz")
+	ErrActionNotAllowed = errors.New("该 biz 不支持这个操作")
+	ErrTargetNotFound   = errors.New("操作的资源不存在")
+	ErrRateLimited      = errors.New("操作太频繁")
+)
+
+// Action 交互服务支持的操作
+type Action string
+
+const (
+	ActionRead    Action = "read"
+	ActionLike    Action = "like"
+	ActionCollect Action = "collect"
+)
+
+// defaultCacheTTL 没有单独配置的 biz 用这个过期时间
+const defaultCacheTTL = time.Minute * 15
+
+// ExistChecker 检查资源是否存在，比如文章有没有被删除
+type ExistChecker func(ctx context.Context, bizId int64) (bool, error)
+
+// Config 一种 biz 的配置
+type Config struct {
+	Name    string
+	Actions []Action
+	// Limiter 按照用户限流，为 nil 就是不限流
+	Limiter limiter.Limiter
+	// CacheTTL 交互数据缓存的过期时间，0 就用默认的
+	CacheTTL time.Duration
+	// Exists 可选，为 nil 就不检查资源是否存在
+	Exists ExistChecker
+}
+
+func (c Config) allow(action Action) bool {
+	for _, a := range c.Actions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+// Registry 所有合法的 biz 都要在这里注册，没注册的 biz 直接拒绝，避免写错 biz 产生孤儿数据
+type Registry struct {
+	lock sync.RWMutex
+	bizs map[string]Config
+	l    logger.LoggerV1
+}
+
+func NewRegistry(l logger.LoggerV1, cfgs ...Config) *Registry {
+	r := &Registry{bizs: make(map[string]Config, len(cfgs)), l: l}
+	for _, cfg := range cfgs {
+		r.Register(cfg)
+	}
+	return r
+}
+
+func (r *Registry) Register(cfg Config) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.bizs[cfg.Name] = cfg
+}
+
+func (r *Registry) Get(biz string) (Config, bool) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	cfg, ok := r.bizs[biz]
+	return cfg, ok
+}
+
+// CacheTTL 未注册的 biz 也返回默认值，缓存层不需要关心校验
+func (r *Registry) CacheTTL(biz string) time.Duration {
+	cfg, ok := r.Get(biz)
+	if !ok || cfg.CacheTTL <= 0 {
+		return defaultCacheTTL
+	}
+	return cfg.CacheTTL
+}
+
+// Validate 只检查 biz 是否注册过，查询类的接口用
+func (r *Registry) Validate(biz string) error {
+	if _, ok := r.Get(biz); !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownBiz, biz)
+	}
+	return nil
+}
+
+// Check 写操作之前的检查，uid 小于等于 0 的不限流
+func (r *Registry) Check(ctx context.Context, biz string, bizId int64, uid int64, action Action) error {
+	cfg, ok := r.Get(biz)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownBiz, biz)
+	}
+	if !cfg.allow(action) {
+		return fmt.Errorf("%w: %s %s", ErrActionNotAllowed, biz, action)
+	}
+	err := r.limit(ctx, cfg, uid, action)
+	if err != nil {
+		return err
+	}
+	if cfg.Exists == nil {
+		return nil
+	}
+	exist, err := cfg.Exists(ctx, bizId)
+	if err != nil {
+		return err
+	}
+	if !exist {
+		return fmt.Errorf("%w: %s %d", ErrTargetNotFound, biz, bizId)
+	}
+	return nil
+}
+
+func (r *Registry) limit(ctx context.Context, cfg Config, uid int64, action Action) error {
+	if cfg.Limiter == nil || uid <= 0 {
+		return nil
+	}
+	key := fmt.Sprintf("intr:limit:%s:%s:%d", cfg.Name, action, uid)
+	limited, err := cfg.Limiter.Limit(ctx, key)
+	if err != nil {
+		//限流器出问题的时候放行，不能因为 redis 影响正常的点赞收藏，但是要留下记录
+		r.l.Warn("交互限流判断失败，放行",
+			logger.String("biz", cfg.Name),
+			logger.String("action", string(action)),
+			logger.Int64("uid", uid),
+			logger.Error(err))
+		return nil
+	}
+	if limited {
+		return ErrRateLimited
+	}
+	return nil
+}
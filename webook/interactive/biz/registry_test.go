@@ -0,0 +1,145 @@
+package biz
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"xiaoweishu/webook/pkg/limiter"
+	limitermocks "xiaoweishu/webook/pkg/limiter/mocks"
+	"xiaoweishu/webook/pkg/logger"
+)
+
+func TestRegistry_Check(t *testing.T) {
+	errDB := errors.New("db 错误")
+	testCases := []struct {
+		name    string
+		mock    func(ctrl *gomock.Controller) limiter.Limiter
+		exists  ExistChecker
+		biz     string
+		uid     int64
+		action  Action
+		wantErr error
+	}{
+		{
+			name: "通过",
+			mock: func(ctrl *gomock.Controller) limiter.Limiter {
+				l := limitermocks.NewMockLimiter(ctrl)
+				l.EXPECT().Limit(gomock.Any(), "intr:limit:article:like:1").Return(false, nil)
+				return l
+			},
+			biz:    "article",
+			uid:    1,
+			action: ActionLike,
+		},
+		{
+			name: "没有注册的 biz",
+			mock: func(ctrl *gomock.Controller) limiter.Limiter {
+				return limitermocks.NewMockLimiter(ctrl)
+			},
+			biz:     "unknown",
+			uid:     1,
+			action:  ActionLike,
+			wantErr: ErrUnknownBiz,
+		},
+		{
+			name: "不支持的操作",
+			mock: func(ctrl *gomock.Controller) limiter.Limiter {
+				return limitermocks.NewMockLimiter(ctrl)
+			},
+			biz:     "article",
+			uid:     1,
+			action:  ActionCollect,
+			wantErr: ErrActionNotAllowed,
+		},
+		{
+			name: "限流",
+			mock: func(ctrl *gomock.Controller) limiter.Limiter {
+				l := limitermocks.NewMockLimiter(ctrl)
+				l.EXPECT().Limit(gomock.Any(), gomock.Any()).Return(true, nil)
+				return l
+			},
+			biz:     "article",
+			uid:     1,
+			action:  ActionLike,
+			wantErr: ErrRateLimited,
+		},
+		{
+			name: "限流器出错，放行",
+			mock: func(ctrl *gomock.Controller) limiter.Limiter {
+				l := limitermocks.NewMockLimiter(ctrl)
+				l.EXPECT().Limit(gomock.Any(), gomock.Any()).Return(false, errors.New("redis 错误"))
+				return l
+			},
+			biz:    "article",
+			uid:    1,
+			action: ActionLike,
+		},
+		{
+			name: "没有登录的不限流",
+			mock: func(ctrl *gomock.Controller) limiter.Limiter {
+				return limitermocks.NewMockLimiter(ctrl)
+			},
+			biz:    "article",
+			action: ActionRead,
+		},
+		{
+			name: "资源不存在",
+			mock: func(ctrl *gomock.Controller) limiter.Limiter {
+				l := limitermocks.NewMockLimiter(ctrl)
+				l.EXPECT().Limit(gomock.Any(), gomock.Any()).Return(false, nil)
+				return l
+			},
+			exists: func(ctx context.Context, bizId int64) (bool, error) {
+				return bizId != 1, nil
+			},
+			biz:     "article",
+			uid:     1,
+			action:  ActionLike,
+			wantErr: ErrTargetNotFound,
+		},
+		{
+			name: "检查资源出错",
+			mock: func(ctrl *gomock.Controller) limiter.Limiter {
+				return limitermocks.NewMockLimiter(ctrl)
+			},
+			exists: func(ctx context.Context, bizId int64) (bool, error) {
+				return false, errDB
+			},
+			biz:     "article",
+			action:  ActionRead,
+			wantErr: errDB,
+		},
+		{
+			name: "被限流的不用检查资源",
+			mock: func(ctrl *gomock.Controller) limiter.Limiter {
+				l := limitermocks.NewMockLimiter(ctrl)
+				l.EXPECT().Limit(gomock.Any(), gomock.Any()).Return(true, nil)
+				return l
+			},
+			exists: func(ctx context.Context, bizId int64) (bool, error) {
+				t.Fatal("不应该检查资源")
+				return false, nil
+			},
+			biz:     "article",
+			uid:     1,
+			action:  ActionLike,
+			wantErr: ErrRateLimited,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			r := NewRegistry(logger.NewNopLogger(), Config{
+				Name:    "article",
+				Actions: []Action{ActionRead, ActionLike},
+				Limiter: tc.mock(ctrl),
+				Exists:  tc.exists,
+			})
+			err := r.Check(context.Background(), tc.biz, 1, tc.uid, tc.action)
+			assert.ErrorIs(t, err, tc.wantErr)
+		})
+	}
+}
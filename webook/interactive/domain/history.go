@@ -0,0 +1,12 @@
+package domain
+
+import "time"
+
+// ReadHistory 用户的一条阅读记录，同一个用户对同一个资源只保留一条
+type ReadHistory struct {
+	Uid   int64
+	Biz   string
+	BizId int64
+	// 最近一次阅读的时间
+	Rtime time.Time
+}
@@ -1,11 +1,26 @@
 package domain
 
 type Interactive struct {
-	Biz        string
-	BizId      int64
-	ReadCnt    int64
+	Biz   string
+	BizId int64
+	// ReadCnt 原始的阅读数，也就是 PV
+	ReadCnt int64
+	// UvCnt 去重之后的阅读人数
+	UvCnt      int64
 	LikeCnt    int64
 	CollectCnt int64
 	Liked      bool
 	Collected  bool
+	// Reaction 当前用户的表态，点赞就是 ReactionLike
+	Reaction string
+	// ReactionCnts 每种表态的人数，like 的人数就是 LikeCnt
+	ReactionCnts map[string]int64
+}
+
+// CntDelta 写回模式下攒起来的计数增量
+type CntDelta struct {
+	Biz     string
+	BizId   int64
+	ReadCnt int64
+	LikeCnt int64
 }
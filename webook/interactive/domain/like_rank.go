@@ -0,0 +1,70 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// LikeRankWindow 点赞榜的时间窗口
+type LikeRankWindow string
+
+const (
+	LikeRankDay  LikeRankWindow = "day"
+	LikeRankWeek LikeRankWindow = "week"
+	LikeRankAll  LikeRankWindow = "all"
+)
+
+// LikeRankWindows 点赞事件要同时计入的所有窗口
+var LikeRankWindows = []LikeRankWindow{LikeRankDay, LikeRankWeek, LikeRankAll}
+
+func (w LikeRankWindow) Valid() bool {
+	return w == LikeRankDay || w == LikeRankWeek || w == LikeRankAll
+}
+
+// Period t 所在的窗口的编号，按天是 20061018，按周是 ISO 周 2006W42
+func (w LikeRankWindow) Period(t time.Time) string {
+	switch w {
+	case LikeRankDay:
+		return t.Format("20060102")
+	case LikeRankWeek:
+		y, wk := t.ISOWeek()
+		return fmt.Sprintf("%dW%02d", y, wk)
+	default:
+		return "all"
+	}
+}
+
+// Range t 所在的窗口的起止时间 [start, end)，总榜没有范围
+func (w LikeRankWindow) Range(t time.Time) (time.Time, time.Time) {
+	y, m, d := t.Date()
+	day := time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+	switch w {
+	case LikeRankDay:
+		return day, day.AddDate(0, 0, 1)
+	case LikeRankWeek:
+		//ISO 周从周一开始
+		offset := (int(day.Weekday()) + 6) % 7
+		start := day.AddDate(0, 0, -offset)
+		return start, start.AddDate(0, 0, 7)
+	default:
+		return time.Time{}, time.Time{}
+	}
+}
+
+// LikeRankDelta 一次点赞或者取消点赞
+type LikeRankDelta struct {
+	Biz   string
+	BizId int64
+	// Delta 点赞是 1，取消是 -1
+	Delta int64
+	// Time 计入哪个窗口，取消点赞的时候是原来点赞的时间
+	Time time.Time
+	// Ctime 点赞或者取消实际发生的时间，重建榜单的时候用来判断数据库里面有没有这个增量
+	Ctime time.Time
+}
+
+type LikeRankItem struct {
+	Biz     string
+	BizId   int64
+	LikeCnt int64
+}
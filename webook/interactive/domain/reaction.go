@@ -0,0 +1,28 @@
+package domain
+
+import "time"
+
+// ReactionLike 👍，也就是原来的点赞，数据还是存在点赞的表里面
+const ReactionLike = "like"
+
+// Reactions 支持的表态，key 是存储和接口里面用的名字
+var Reactions = map[string]string{
+	ReactionLike: "👍",
+	"love":       "❤️",
+	"laugh":      "😂",
+	"celebrate":  "🎉",
+	"wow":        "😮",
+	"sad":        "😢",
+}
+
+func ValidReaction(reaction string) bool {
+	_, ok := Reactions[reaction]
+	return ok
+}
+
+// UserReaction 用户的表态和表态的时间
+type UserReaction struct {
+	Reaction string
+	// Utime 表态的时间，取消 👍 的时候点赞榜要按照它找到当初计入的窗口
+	Utime time.Time
+}
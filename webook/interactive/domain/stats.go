@@ -0,0 +1,70 @@
+package domain
+
+import "time"
+
+// StatsGranularity 统计桶的粒度
+type StatsGranularity string
+
+const (
+	StatsHour StatsGranularity = "hour"
+	StatsDay  StatsGranularity = "day"
+)
+
+// Truncate 算出 t 所在的桶的开始时间，按天的桶用本地时间的零点
+func (g StatsGranularity) Truncate(t time.Time) time.Time {
+	if g == StatsHour {
+		return t.Truncate(time.Hour)
+	}
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// Add 往后数 n 个桶，n 可以是负数
+// 按天的桶是本地零点，遇到夏令时一天不一定是 24 小时，所以不能直接加时间
+func (g StatsGranularity) Add(t time.Time, n int) time.Time {
+	if g == StatsHour {
+		return t.Add(time.Hour * time.Duration(n))
+	}
+	return t.AddDate(0, 0, n)
+}
+
+func (g StatsGranularity) Valid() bool {
+	return g == StatsHour || g == StatsDay
+}
+
+// StatsDelta 某个资源在某个时间点的计数变化
+type StatsDelta struct {
+	Biz        string
+	BizId      int64
+	Time       time.Time
+	ReadCnt    int64
+	LikeCnt    int64
+	CollectCnt int64
+}
+
+// StatsBucket 趋势图上的一个点，也用来表示一段时间的总数
+type StatsBucket struct {
+	Time       time.Time
+	ReadCnt    int64
+	LikeCnt    int64
+	CollectCnt int64
+}
+
+// StatsItem 一段时间内单个资源的总数
+type StatsItem struct {
+	Biz        string
+	BizId      int64
+	ReadCnt    int64
+	LikeCnt    int64
+	CollectCnt int64
+}
+
+// StatsDashboard 创作者看板
+type StatsDashboard struct {
+	Trend []StatsBucket
+	// Total 这个周期的总数，PrevTotal 上一个周期的总数，用来算环比
+	Total     StatsBucket
+	PrevTotal StatsBucket
+	// Top 这个周期里面数据最好的资源
+	Top []StatsItem
+}
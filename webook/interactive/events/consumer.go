@@ -3,6 +3,7 @@ package events
 import (
 	"context"
 	"github.com/IBM/sarama"
+	"strconv"
 	"time"
 	"xiaoweishu/webook/interactive/repository"
 	"xiaoweishu/webook/internal/events/article"
@@ -17,6 +18,7 @@ const TopicReadEvent = "article_read"
 type InteractiveReadEventConsumer struct {
 	repo   repository.InteractiveRepository
 	client sarama.Client
+	filter ReadEventFilter
 	l      logger2.LoggerV1
 }
 
@@ -36,23 +38,60 @@ func (i *InteractiveReadEventConsumer) Start() error {
 }
 
 func NewInteractiveReadEventConsumer(repo repository.InteractiveRepository,
-	client sarama.Client, l logger2.LoggerV1) *InteractiveReadEventConsumer {
+	client sarama.Client, filter ReadEventFilter, l logger2.LoggerV1) *InteractiveReadEventConsumer {
 	return &InteractiveReadEventConsumer{
 		repo:   repo,
 		client: client,
+		filter: filter,
 		l:      l,
 	}
 }
 func (i *InteractiveReadEventConsumer) BatchConsume(msgs []*sarama.ConsumerMessage,
 	events []article.ReadEvent) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
 	bizs := make([]string, 0, len(events))
 	bizIds := make([]int64, 0, len(events))
+	visitors := make([]string, 0, len(events))
 	for _, evt := range events {
+		//爬虫和刷量的流量 PV 和 UV 都不算
+		if !i.filter.Allow(ctx, "interactive", evt) {
+			continue
+		}
 		//通过biz和bizid定位到具体的文章
 		bizs = append(bizs, "article")
 		bizIds = append(bizIds, evt.Aid)
+		visitors = append(visitors, visitor(evt))
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
-	defer cancel()
-	return i.repo.BatchIncrReadCnt(ctx, bizs, bizIds)
+	if len(bizs) == 0 {
+		return nil
+	}
+	err := i.repo.BatchIncrReadCnt(ctx, bizs, bizIds)
+	if err != nil {
+		return err
+	}
+	uvBizs := make([]string, 0, len(bizs))
+	uvBizIds := make([]int64, 0, len(bizIds))
+	uvVisitors := make([]string, 0, len(visitors))
+	for idx, v := range visitors {
+		//既没有登录也拿不到 IP 的，没办法去重，只算 PV
+		if v == "" {
+			continue
+		}
+		uvBizs = append(uvBizs, bizs[idx])
+		uvBizIds = append(uvBizIds, bizIds[idx])
+		uvVisitors = append(uvVisitors, v)
+	}
+	return i.repo.BatchIncrUvCnt(ctx, uvBizs, uvBizIds, uvVisitors)
+}
+
+// visitor 登录用户用 uid 去重，未登录的用 IP
+func visitor(evt article.ReadEvent) string {
+	if evt.Uid > 0 {
+		return "u:" + strconv.FormatInt(evt.Uid, 10)
+	}
+	if evt.IP != "" {
+		return "ip:" + evt.IP
+	}
+	return ""
 }
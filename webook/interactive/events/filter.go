@@ -0,0 +1,71 @@
+package events
+
+import (
+	"context"
+	"strings"
+	"time"
+	"xiaoweishu/webook/internal/events/article"
+	"xiaoweishu/webook/pkg/limiter"
+	logger2 "xiaoweishu/webook/pkg/logger"
+)
+
+//go:generate mockgen -source=./filter.go -package=evtmocks -destination=mocks/filter.mock.go ReadEventFilter
+
+// ReadEventFilter 在计数之前过滤掉爬虫和刷量的阅读事件
+type ReadEventFilter interface {
+	// Allow scope 区分不同的消费者，每个消费者单独计数，
+	// 不然同一条阅读事件被几个消费者处理，就会在限流器里面算几次
+	Allow(ctx context.Context, scope string, evt article.ReadEvent) bool
+}
+
+// HeuristicReadEventFilter 根据 User-Agent 和 IP 的访问频率做简单的判断
+type HeuristicReadEventFilter struct {
+	// 按照 IP 限流，单个 IP 在窗口内阅读次数太多的，认为是刷量。
+	// 窗口按照阅读发生的时间算，不然消费积压之后正常的阅读也会被限流
+	limiter     limiter.EventTimeLimiter
+	l           logger2.LoggerV1
+	botKeywords []string
+}
+
+func NewHeuristicReadEventFilter(limiter limiter.EventTimeLimiter, l logger2.LoggerV1) *HeuristicReadEventFilter {
+	return &HeuristicReadEventFilter{
+		limiter: limiter,
+		l:       l,
+		botKeywords: []string{"bot", "spider", "crawler", "curl", "wget",
+			"python-requests", "httpclient", "okhttp", "headless", "scrapy", "go-http-client"},
+	}
+}
+
+func (f *HeuristicReadEventFilter) Allow(ctx context.Context, scope string, evt article.ReadEvent) bool {
+	//老版本的事件没有这两个字段，不做判断
+	if evt.IP == "" && evt.UserAgent == "" {
+		return true
+	}
+	//正常的浏览器都会带上 User-Agent
+	if evt.UserAgent == "" {
+		return false
+	}
+	ua := strings.ToLower(evt.UserAgent)
+	for _, kw := range f.botKeywords {
+		if strings.Contains(ua, kw) {
+			return false
+		}
+	}
+	if evt.IP == "" {
+		return true
+	}
+	rtime := time.Now()
+	//老版本的事件没有阅读时间，只能按照消费的时间算
+	if evt.Rtime > 0 {
+		rtime = time.UnixMilli(evt.Rtime)
+	}
+	limited, err := f.limiter.LimitAt(ctx, "read_event:"+scope+":ip:"+evt.IP, rtime)
+	if err != nil {
+		//限流器出问题的时候宁可放过，不能因此丢掉正常的阅读
+		f.l.Warn("阅读事件限流判断失败",
+			logger2.String("ip", evt.IP),
+			logger2.Error(err))
+		return true
+	}
+	return !limited
+}
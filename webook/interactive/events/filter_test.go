@@ -0,0 +1,77 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+	"xiaoweishu/webook/internal/events/article"
+	"xiaoweishu/webook/pkg/limiter"
+	limitermocks "xiaoweishu/webook/pkg/limiter/mocks"
+	"xiaoweishu/webook/pkg/logger"
+)
+
+func TestHeuristicReadEventFilter_Allow(t *testing.T) {
+	const ua = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7)"
+	testCases := []struct {
+		name  string
+		mock  func(ctrl *gomock.Controller) limiter.EventTimeLimiter
+		evt   article.ReadEvent
+		allow bool
+	}{
+		{
+			name: "老版本的事件",
+			mock: func(ctrl *gomock.Controller) limiter.EventTimeLimiter {
+				return limitermocks.NewMockEventTimeLimiter(ctrl)
+			},
+			evt:   article.ReadEvent{Aid: 1},
+			allow: true,
+		},
+		{
+			name: "爬虫",
+			mock: func(ctrl *gomock.Controller) limiter.EventTimeLimiter {
+				return limitermocks.NewMockEventTimeLimiter(ctrl)
+			},
+			evt: article.ReadEvent{Aid: 1, IP: "1.1.1.1", UserAgent: "Googlebot/2.1"},
+		},
+		{
+			name: "按照消费者分开限流",
+			mock: func(ctrl *gomock.Controller) limiter.EventTimeLimiter {
+				l := limitermocks.NewMockEventTimeLimiter(ctrl)
+				l.EXPECT().LimitAt(gomock.Any(), "read_event:stats:ip:1.1.1.1", gomock.Any()).Return(false, nil)
+				return l
+			},
+			evt:   article.ReadEvent{Aid: 1, IP: "1.1.1.1", UserAgent: ua},
+			allow: true,
+		},
+		{
+			name: "刷量",
+			mock: func(ctrl *gomock.Controller) limiter.EventTimeLimiter {
+				l := limitermocks.NewMockEventTimeLimiter(ctrl)
+				l.EXPECT().LimitAt(gomock.Any(), gomock.Any(), time.UnixMilli(1700000000000)).Return(true, nil)
+				return l
+			},
+			evt: article.ReadEvent{Aid: 1, IP: "1.1.1.1", UserAgent: ua, Rtime: 1700000000000},
+		},
+		{
+			name: "限流器出错，放过",
+			mock: func(ctrl *gomock.Controller) limiter.EventTimeLimiter {
+				l := limitermocks.NewMockEventTimeLimiter(ctrl)
+				l.EXPECT().LimitAt(gomock.Any(), gomock.Any(), gomock.Any()).Return(false, errors.New("redis 错误"))
+				return l
+			},
+			evt:   article.ReadEvent{Aid: 1, IP: "1.1.1.1", UserAgent: ua},
+			allow: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			f := NewHeuristicReadEventFilter(tc.mock(ctrl), logger.NewNopLogger())
+			assert.Equal(t, tc.allow, f.Allow(context.Background(), "stats", tc.evt))
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package events
+
+import (
+	"context"
+	"github.com/IBM/sarama"
+	"time"
+	"xiaoweishu/webook/interactive/domain"
+	"xiaoweishu/webook/interactive/service"
+	"xiaoweishu/webook/internal/events/article"
+	logger2 "xiaoweishu/webook/pkg/logger"
+	"xiaoweishu/webook/pkg/samarax"
+)
+
+// ReadHistoryConsumer 同样消费 article_read，但是用独立的消费者组，
+// 和阅读计数互不影响
+type ReadHistoryConsumer struct {
+	svc    service.ReadHistoryService
+	client sarama.Client
+	l      logger2.LoggerV1
+}
+
+func NewReadHistoryConsumer(svc service.ReadHistoryService,
+	client sarama.Client, l logger2.LoggerV1) *ReadHistoryConsumer {
+	return &ReadHistoryConsumer{
+		svc:    svc,
+		client: client,
+		l:      l,
+	}
+}
+
+func (r *ReadHistoryConsumer) Start() error {
+	cg, err := sarama.NewConsumerGroupFromClient("read_history", r.client)
+	if err != nil {
+		return err
+	}
+	go func() {
+		er := cg.Consume(context.Background(), []string{TopicReadEvent},
+			samarax.NewBatchHandler[article.ReadEvent](r.l, r.BatchConsume))
+		if er != nil {
+			r.l.Error("退出消费", logger2.Error(er))
+		}
+	}()
+	return err
+}
+
+func (r *ReadHistoryConsumer) BatchConsume(msgs []*sarama.ConsumerMessage,
+	events []article.ReadEvent) error {
+	now := time.Now()
+	hs := make([]domain.ReadHistory, 0, len(events))
+	for _, evt := range events {
+		//没有登录的用户不记录
+		if evt.Uid <= 0 {
+			continue
+		}
+		hs = append(hs, domain.ReadHistory{
+			Uid:   evt.Uid,
+			Biz:   "article",
+			BizId: evt.Aid,
+			Rtime: now,
+		})
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	return r.svc.Record(ctx, hs)
+}
@@ -0,0 +1,68 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./producer.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./producer.go -package=evtmocks -destination=mocks/producer.mock.go Producer
+//
+
+// Package evtmocks is a generated GoMock package.
+package evtmocks
+
+import (
+	reflect "reflect"
+	intr "xiaoweishu/webook/interactive/events/intr"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockProducer is a mock of Producer interface.
+type MockProducer struct {
+	ctrl     *gomock.Controller
+	recorder *MockProducerMockRecorder
+}
+
+// MockProducerMockRecorder is the mock recorder for MockProducer.
+type MockProducerMockRecorder struct {
+	mock *MockProducer
+}
+
+// NewMockProducer creates a new mock instance.
+func NewMockProducer(ctrl *gomock.Controller) *MockProducer {
+	mock := &MockProducer{ctrl: ctrl}
+	mock.recorder = &MockProducerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockProducer) EXPECT() *MockProducerMockRecorder {
+	return m.recorder
+}
+
+// ProduceCollectEvent mocks base method.
+func (m *MockProducer) ProduceCollectEvent(evt intr.CollectEvent) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ProduceCollectEvent", evt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ProduceCollectEvent indicates an expected call of ProduceCollectEvent.
+func (mr *MockProducerMockRecorder) ProduceCollectEvent(evt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ProduceCollectEvent", reflect.TypeOf((*MockProducer)(nil).ProduceCollectEvent), evt)
+}
+
+// ProduceLikeEvent mocks base method.
+func (m *MockProducer) ProduceLikeEvent(evt intr.LikeEvent) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ProduceLikeEvent", evt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ProduceLikeEvent indicates an expected call of ProduceLikeEvent.
+func (mr *MockProducerMockRecorder) ProduceLikeEvent(evt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ProduceLikeEvent", reflect.TypeOf((*MockProducer)(nil).ProduceLikeEvent), evt)
+}
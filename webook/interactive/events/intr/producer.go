@@ -0,0 +1,77 @@
+package intr
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/IBM/sarama"
+)
+
+// TopicLikeEvent 点赞和取消点赞都发到这个 topic
+const TopicLikeEvent = "interactive_like"
+
+// TopicCollectEvent 收藏事件
+const TopicCollectEvent = "interactive_collect"
+
+//go:generate mockgen -source=./producer.go -package=evtmocks -destination=mocks/producer.mock.go Producer
+type Producer interface {
+	ProduceLikeEvent(evt LikeEvent) error
+	ProduceCollectEvent(evt CollectEvent) error
+}
+
+type LikeEvent struct {
+	Biz   string
+	BizId int64
+	Uid   int64
+	// Liked 为 true 是点赞，false 是取消点赞
+	Liked bool
+	// Counted 为 true 说明点赞数已经同步更新过了，写回的消费者要跳过
+	Counted bool
+	// Ctime 毫秒数
+	Ctime int64
+	// LikeTime 取消点赞的时候是原来点赞的时间，毫秒数，点赞榜按照它找到要减掉的窗口
+	LikeTime int64
+}
+
+type CollectEvent struct {
+	Biz   string
+	BizId int64
+	Uid   int64
+	Cid   int64
+	// Ctime 毫秒数
+	Ctime int64
+}
+
+type SaramaSyncProducer struct {
+	producer sarama.SyncProducer
+}
+
+func NewSaramaSyncProducer(producer sarama.SyncProducer) Producer {
+	return &SaramaSyncProducer{producer: producer}
+}
+
+func (s *SaramaSyncProducer) ProduceLikeEvent(evt LikeEvent) error {
+	val, err := json.Marshal(evt)
+	if err != nil {
+		return err
+	}
+	_, _, err = s.producer.SendMessage(&sarama.ProducerMessage{
+		Topic: TopicLikeEvent,
+		//同一个资源的点赞事件落到同一个分区，保证顺序
+		Key:   sarama.StringEncoder(fmt.Sprintf("%s:%d", evt.Biz, evt.BizId)),
+		Value: sarama.StringEncoder(val),
+	})
+	return err
+}
+
+func (s *SaramaSyncProducer) ProduceCollectEvent(evt CollectEvent) error {
+	val, err := json.Marshal(evt)
+	if err != nil {
+		return err
+	}
+	_, _, err = s.producer.SendMessage(&sarama.ProducerMessage{
+		Topic: TopicCollectEvent,
+		Key:   sarama.StringEncoder(fmt.Sprintf("%s:%d", evt.Biz, evt.BizId)),
+		Value: sarama.StringEncoder(val),
+	})
+	return err
+}
@@ -0,0 +1,71 @@
+package events
+
+import (
+	"context"
+	"github.com/IBM/sarama"
+	"time"
+	"xiaoweishu/webook/interactive/domain"
+	"xiaoweishu/webook/interactive/events/intr"
+	"xiaoweishu/webook/interactive/service"
+	logger2 "xiaoweishu/webook/pkg/logger"
+	"xiaoweishu/webook/pkg/samarax"
+)
+
+// LikeRankConsumer 根据点赞事件实时更新点赞榜
+type LikeRankConsumer struct {
+	svc    service.LikeRankService
+	client sarama.Client
+	l      logger2.LoggerV1
+}
+
+func NewLikeRankConsumer(svc service.LikeRankService,
+	client sarama.Client, l logger2.LoggerV1) *LikeRankConsumer {
+	return &LikeRankConsumer{
+		svc:    svc,
+		client: client,
+		l:      l,
+	}
+}
+
+func (c *LikeRankConsumer) Start() error {
+	cg, err := sarama.NewConsumerGroupFromClient("interactive_like_rank", c.client)
+	if err != nil {
+		return err
+	}
+	go func() {
+		er := cg.Consume(context.Background(), []string{intr.TopicLikeEvent},
+			samarax.NewBatchHandler[intr.LikeEvent](c.l, c.BatchConsume))
+		if er != nil {
+			c.l.Error("退出消费", logger2.Error(er))
+		}
+	}()
+	return err
+}
+
+func (c *LikeRankConsumer) BatchConsume(msgs []*sarama.ConsumerMessage,
+	events []intr.LikeEvent) error {
+	deltas := make([]domain.LikeRankDelta, 0, len(events))
+	for _, evt := range events {
+		d := domain.LikeRankDelta{
+			Biz:   evt.Biz,
+			BizId: evt.BizId,
+			Delta: 1,
+			Time:  time.UnixMilli(evt.Ctime),
+		}
+		if !evt.Liked {
+			d.Delta = -1
+		}
+		if evt.Ctime <= 0 {
+			d.Time = time.Now()
+		}
+		d.Ctime = d.Time
+		//取消点赞要从当初点赞的那个窗口里面减，不然日榜周榜会减错
+		if !evt.Liked && evt.LikeTime > 0 {
+			d.Time = time.UnixMilli(evt.LikeTime)
+		}
+		deltas = append(deltas, d)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	return c.svc.Record(ctx, deltas)
+}
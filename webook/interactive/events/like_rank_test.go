@@ -0,0 +1,34 @@
+package events
+
+import (
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+	"xiaoweishu/webook/interactive/domain"
+	"xiaoweishu/webook/interactive/events/intr"
+	svcmocks "xiaoweishu/webook/interactive/service/mocks"
+	"xiaoweishu/webook/pkg/logger"
+)
+
+func TestLikeRankConsumer_BatchConsume(t *testing.T) {
+	now := time.UnixMilli(time.Now().UnixMilli())
+	lastWeek := now.AddDate(0, 0, -7)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	svc := svcmocks.NewMockLikeRankService(ctrl)
+	svc.EXPECT().Record(gomock.Any(), []domain.LikeRankDelta{
+		{Biz: "article", BizId: 1, Delta: 1, Time: now, Ctime: now},
+		{Biz: "article", BizId: 2, Delta: -1, Time: lastWeek, Ctime: now},
+		{Biz: "article", BizId: 3, Delta: -1, Time: now, Ctime: now},
+	}).Return(nil)
+	c := NewLikeRankConsumer(svc, nil, logger.NewNopLogger())
+	err := c.BatchConsume(nil, []intr.LikeEvent{
+		{Biz: "article", BizId: 1, Liked: true, Ctime: now.UnixMilli()},
+		//上周点的赞今天取消，要从上周的窗口里面减
+		{Biz: "article", BizId: 2, Liked: false, Ctime: now.UnixMilli(), LikeTime: lastWeek.UnixMilli()},
+		//老版本的事件没有点赞时间
+		{Biz: "article", BizId: 3, Liked: false, Ctime: now.UnixMilli()},
+	})
+	require.NoError(t, err)
+}
@@ -0,0 +1,55 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./filter.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./filter.go -package=evtmocks -destination=mocks/filter.mock.go ReadEventFilter
+//
+
+// Package evtmocks is a generated GoMock package.
+package evtmocks
+
+import (
+	context "context"
+	reflect "reflect"
+	article "xiaoweishu/webook/internal/events/article"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockReadEventFilter is a mock of ReadEventFilter interface.
+type MockReadEventFilter struct {
+	ctrl     *gomock.Controller
+	recorder *MockReadEventFilterMockRecorder
+}
+
+// MockReadEventFilterMockRecorder is the mock recorder for MockReadEventFilter.
+type MockReadEventFilterMockRecorder struct {
+	mock *MockReadEventFilter
+}
+
+// NewMockReadEventFilter creates a new mock instance.
+func NewMockReadEventFilter(ctrl *gomock.Controller) *MockReadEventFilter {
+	mock := &MockReadEventFilter{ctrl: ctrl}
+	mock.recorder = &MockReadEventFilterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockReadEventFilter) EXPECT() *MockReadEventFilterMockRecorder {
+	return m.recorder
+}
+
+// Allow mocks base method.
+func (m *MockReadEventFilter) Allow(ctx context.Context, scope string, evt article.ReadEvent) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Allow", ctx, scope, evt)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// Allow indicates an expected call of Allow.
+func (mr *MockReadEventFilterMockRecorder) Allow(ctx, scope, evt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Allow", reflect.TypeOf((*MockReadEventFilter)(nil).Allow), ctx, scope, evt)
+}
@@ -0,0 +1,181 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/IBM/sarama"
+	"time"
+	"xiaoweishu/webook/interactive/domain"
+	"xiaoweishu/webook/interactive/events/intr"
+	"xiaoweishu/webook/interactive/service"
+	"xiaoweishu/webook/internal/events/article"
+	logger2 "xiaoweishu/webook/pkg/logger"
+)
+
+// StatsConsumer 把阅读、点赞和收藏事件按照时间分桶，给创作者看板用
+type StatsConsumer struct {
+	svc    service.InteractiveStatsService
+	client sarama.Client
+	filter ReadEventFilter
+	l      logger2.LoggerV1
+	// interval 写入间隔，batchSize 攒够这么多条消息也会写入
+	interval  time.Duration
+	batchSize int
+	// maxPending 写入一直失败的时候最多攒这么多条，超过了就丢掉，统计数据允许这点误差
+	maxPending int
+	// cleanInterval 清理过期数据的间隔
+	cleanInterval time.Duration
+}
+
+func NewStatsConsumer(svc service.InteractiveStatsService,
+	client sarama.Client, filter ReadEventFilter, l logger2.LoggerV1) *StatsConsumer {
+	return &StatsConsumer{
+		svc:           svc,
+		client:        client,
+		filter:        filter,
+		l:             l,
+		interval:      time.Second * 5,
+		batchSize:     1000,
+		maxPending:    10000,
+		cleanInterval: time.Hour,
+	}
+}
+
+func (s *StatsConsumer) Start() error {
+	cg, err := sarama.NewConsumerGroupFromClient("interactive_stats", s.client)
+	if err != nil {
+		return err
+	}
+	go func() {
+		er := cg.Consume(context.Background(),
+			[]string{TopicReadEvent, intr.TopicLikeEvent, intr.TopicCollectEvent}, s)
+		if er != nil {
+			s.l.Error("退出消费", logger2.Error(er))
+		}
+	}()
+	go s.cleanLoop()
+	return nil
+}
+
+// cleanLoop 多个实例同时删也没关系，删除是幂等的
+func (s *StatsConsumer) cleanLoop() {
+	ticker := time.NewTicker(s.cleanInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		err := s.svc.Clean(ctx)
+		cancel()
+		if err != nil {
+			s.l.Error("清理过期的统计数据失败", logger2.Error(err))
+		}
+	}
+}
+
+func (s *StatsConsumer) Setup(session sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+func (s *StatsConsumer) Cleanup(session sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+func (s *StatsConsumer) ConsumeClaim(session sarama.ConsumerGroupSession,
+	claim sarama.ConsumerGroupClaim) error {
+	msgs := claim.Messages()
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	deltas := make([]domain.StatsDelta, 0, s.batchSize)
+	var last *sarama.ConsumerMessage
+	flush := func() {
+		if last == nil {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+		err := s.svc.Record(ctx, deltas)
+		cancel()
+		if err != nil && len(deltas) < s.maxPending {
+			//不提交 offset，下一次连同新的消息一起重试
+			s.l.Error("写入统计数据失败", logger2.Error(err))
+			return
+		}
+		if err != nil {
+			//数据库一直写不进去的时候不能无限攒下去
+			s.l.Error("写入统计数据一直失败，丢弃增量",
+				logger2.Int("cnt", len(deltas)),
+				logger2.Error(err))
+		}
+		session.MarkMessage(last, "")
+		deltas = deltas[:0]
+		last = nil
+	}
+	for {
+		select {
+		case msg, ok := <-msgs:
+			if !ok {
+				flush()
+				return nil
+			}
+			last = msg
+			if d, ok := s.toDelta(msg); ok {
+				deltas = append(deltas, d)
+			}
+			if len(deltas) >= s.batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-session.Context().Done():
+			return nil
+		}
+	}
+}
+
+func (s *StatsConsumer) toDelta(msg *sarama.ConsumerMessage) (domain.StatsDelta, bool) {
+	var err error
+	var d domain.StatsDelta
+	switch msg.Topic {
+	case TopicReadEvent:
+		var evt article.ReadEvent
+		err = json.Unmarshal(msg.Value, &evt)
+		if err == nil && !s.allow(evt) {
+			//爬虫和刷量的阅读和计数一样不算
+			return domain.StatsDelta{}, false
+		}
+		d = domain.StatsDelta{Biz: "article", BizId: evt.Aid, Time: time.UnixMilli(evt.Rtime), ReadCnt: 1}
+		if evt.Rtime <= 0 {
+			//老版本的阅读事件里面没有时间，用消息的时间
+			d.Time = msg.Timestamp
+		}
+	case intr.TopicLikeEvent:
+		var evt intr.LikeEvent
+		err = json.Unmarshal(msg.Value, &evt)
+		d = domain.StatsDelta{Biz: evt.Biz, BizId: evt.BizId, Time: time.UnixMilli(evt.Ctime), LikeCnt: 1}
+		if !evt.Liked {
+			d.LikeCnt = -1
+		}
+	case intr.TopicCollectEvent:
+		var evt intr.CollectEvent
+		err = json.Unmarshal(msg.Value, &evt)
+		d = domain.StatsDelta{Biz: evt.Biz, BizId: evt.BizId, Time: time.UnixMilli(evt.Ctime), CollectCnt: 1}
+	default:
+		return domain.StatsDelta{}, false
+	}
+	if err != nil {
+		s.l.Error("反序列化失败",
+			logger2.String("topic", msg.Topic),
+			logger2.Int32("partition", msg.Partition),
+			logger2.Int64("offset", msg.Offset),
+			logger2.Error(err))
+		return domain.StatsDelta{}, false
+	}
+	if d.Time.UnixMilli() <= 0 {
+		d.Time = time.Now()
+	}
+	return d, true
+}
+
+func (s *StatsConsumer) allow(evt article.ReadEvent) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	return s.filter.Allow(ctx, "interactive_stats", evt)
+}
@@ -0,0 +1,225 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/IBM/sarama"
+	"time"
+	"xiaoweishu/webook/interactive/domain"
+	"xiaoweishu/webook/interactive/events/intr"
+	"xiaoweishu/webook/interactive/repository"
+	"xiaoweishu/webook/internal/events/article"
+	logger2 "xiaoweishu/webook/pkg/logger"
+)
+
+// WriteBehindConsumer 写回模式下的计数消费者
+// 阅读和点赞事件先在内存里面按照资源聚合成增量，定时或者攒够了一批再写回数据库，
+// 这样一篇热点文章一批只会更新一次 interactives，
+// 只有写回成功之后才提交 offset，进程挂了就从 kafka 里面重新消费，不会丢计数
+type WriteBehindConsumer struct {
+	repo   repository.InteractiveRepository
+	client sarama.Client
+	filter ReadEventFilter
+	l      logger2.LoggerV1
+	// interval 写回间隔
+	interval time.Duration
+	// batchSize 攒够这么多条消息也会写回
+	batchSize int
+}
+
+func NewWriteBehindConsumer(repo repository.InteractiveRepository,
+	client sarama.Client, filter ReadEventFilter, l logger2.LoggerV1) *WriteBehindConsumer {
+	return &WriteBehindConsumer{
+		repo:      repo,
+		client:    client,
+		filter:    filter,
+		l:         l,
+		interval:  time.Second,
+		batchSize: 1000,
+	}
+}
+
+func (w *WriteBehindConsumer) Start() error {
+	//和 InteractiveReadEventConsumer 不能用同一个消费者组，否则会分走对方的消息
+	cg, err := sarama.NewConsumerGroupFromClient("interactive_write_behind", w.client)
+	if err != nil {
+		return err
+	}
+	go func() {
+		er := cg.Consume(context.Background(),
+			[]string{TopicReadEvent, intr.TopicLikeEvent}, w)
+		if er != nil {
+			w.l.Error("退出消费", logger2.Error(er))
+		}
+	}()
+	return nil
+}
+
+func (w *WriteBehindConsumer) Setup(session sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+func (w *WriteBehindConsumer) Cleanup(session sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+// ConsumeClaim 每个分区单独聚合，这样提交 offset 的时候不用考虑别的分区
+func (w *WriteBehindConsumer) ConsumeClaim(session sarama.ConsumerGroupSession,
+	claim sarama.ConsumerGroupClaim) error {
+	msgs := claim.Messages()
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	agg := newCntAggregator()
+	for {
+		select {
+		case msg, ok := <-msgs:
+			if !ok {
+				//分区被回收了，把手上的尽量写回去
+				w.flush(session, agg)
+				return nil
+			}
+			w.add(agg, msg)
+			if agg.msgCnt >= w.batchSize {
+				w.flush(session, agg)
+			}
+		case <-ticker.C:
+			w.flush(session, agg)
+		case <-session.Context().Done():
+			return nil
+		}
+	}
+}
+
+func (w *WriteBehindConsumer) add(agg *cntAggregator, msg *sarama.ConsumerMessage) {
+	agg.msgCnt++
+	agg.last = msg
+	switch msg.Topic {
+	case TopicReadEvent:
+		var evt article.ReadEvent
+		if err := json.Unmarshal(msg.Value, &evt); err != nil {
+			w.logDecodeErr(msg, err)
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		allow := w.filter.Allow(ctx, "interactive_write_behind", evt)
+		cancel()
+		if !allow {
+			return
+		}
+		agg.addRead("article", evt.Aid, visitor(evt))
+	case intr.TopicLikeEvent:
+		var evt intr.LikeEvent
+		if err := json.Unmarshal(msg.Value, &evt); err != nil {
+			w.logDecodeErr(msg, err)
+			return
+		}
+		//已经同步更新过点赞数的事件，比如从 👍 切换到别的表态
+		if evt.Counted {
+			return
+		}
+		delta := int64(1)
+		if !evt.Liked {
+			delta = -1
+		}
+		agg.addLike(evt.Biz, evt.BizId, delta)
+	}
+}
+
+// flush 写回失败的话保留增量，也不提交 offset，下一次再试
+func (w *WriteBehindConsumer) flush(session sarama.ConsumerGroupSession, agg *cntAggregator) {
+	if agg.msgCnt == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+	if deltas := agg.deltaSlice(); len(deltas) > 0 {
+		err := w.repo.BatchAddCnt(ctx, deltas)
+		if err != nil {
+			w.l.Error("写回计数失败", logger2.Error(err))
+			return
+		}
+	}
+	if len(agg.uvBizs) > 0 {
+		//计数已经写进去了，UV 失败只能少算，不能为了它重放整批
+		err := w.repo.BatchIncrUvCnt(ctx, agg.uvBizs, agg.uvBizIds, agg.uvVisitors)
+		if err != nil {
+			w.l.Error("写回 UV 失败", logger2.Error(err))
+		}
+	}
+	session.MarkMessage(agg.last, "")
+	agg.reset()
+}
+
+func (w *WriteBehindConsumer) logDecodeErr(msg *sarama.ConsumerMessage, err error) {
+	w.l.Error("反序列化失败",
+		logger2.String("topic", msg.Topic),
+		logger2.Int32("partition", msg.Partition),
+		logger2.Int64("offset", msg.Offset),
+		logger2.Error(err))
+}
+
+type cntKey struct {
+	biz   string
+	bizId int64
+}
+
+// cntAggregator 一个分区在一次写回周期里面攒下来的增量
+type cntAggregator struct {
+	deltas     map[cntKey]*domain.CntDelta
+	uvBizs     []string
+	uvBizIds   []int64
+	uvVisitors []string
+	msgCnt     int
+	// last 这一批最后一条消息，写回成功之后提交它的 offset
+	last *sarama.ConsumerMessage
+}
+
+func newCntAggregator() *cntAggregator {
+	agg := &cntAggregator{}
+	agg.reset()
+	return agg
+}
+
+func (a *cntAggregator) reset() {
+	a.deltas = make(map[cntKey]*domain.CntDelta)
+	a.uvBizs = nil
+	a.uvBizIds = nil
+	a.uvVisitors = nil
+	a.msgCnt = 0
+	a.last = nil
+}
+
+func (a *cntAggregator) delta(biz string, bizId int64) *domain.CntDelta {
+	key := cntKey{biz: biz, bizId: bizId}
+	d, ok := a.deltas[key]
+	if !ok {
+		d = &domain.CntDelta{Biz: biz, BizId: bizId}
+		a.deltas[key] = d
+	}
+	return d
+}
+
+func (a *cntAggregator) addRead(biz string, bizId int64, visitor string) {
+	a.delta(biz, bizId).ReadCnt++
+	if visitor != "" {
+		a.uvBizs = append(a.uvBizs, biz)
+		a.uvBizIds = append(a.uvBizIds, bizId)
+		a.uvVisitors = append(a.uvVisitors, visitor)
+	}
+}
+
+func (a *cntAggregator) addLike(biz string, bizId int64, delta int64) {
+	a.delta(biz, bizId).LikeCnt += delta
+}
+
+func (a *cntAggregator) deltaSlice() []domain.CntDelta {
+	res := make([]domain.CntDelta, 0, len(a.deltas))
+	for _, d := range a.deltas {
+		//点赞又取消的，抵消掉就不用写了
+		if d.ReadCnt == 0 && d.LikeCnt == 0 {
+			continue
+		}
+		res = append(res, *d)
+	}
+	return res
+}
@@ -0,0 +1,59 @@
+package grpc
+
+import (
+	"context"
+	"github.com/ecodeclub/ekit/slice"
+	"google.golang.org/grpc"
+	intrv1 "xiaoweishu/webook/api/proto/gen/intr/v1"
+	"xiaoweishu/webook/interactive/domain"
+	"xiaoweishu/webook/interactive/service"
+)
+
+type ReadHistoryServiceServer struct {
+	intrv1.UnimplementedReadHistoryServiceServer
+	svc service.ReadHistoryService
+}
+
+func NewReadHistoryServiceServer(svc service.ReadHistoryService) *ReadHistoryServiceServer {
+	return &ReadHistoryServiceServer{svc: svc}
+}
+
+func (r *ReadHistoryServiceServer) Register(s *grpc.Server) {
+	intrv1.RegisterReadHistoryServiceServer(s, r)
+}
+
+func (r *ReadHistoryServiceServer) List(ctx context.Context, request *intrv1.ListReadHistoryRequest) (*intrv1.ListReadHistoryResponse, error) {
+	hs, err := r.svc.List(ctx, request.GetUid(), int(request.GetOffset()), int(request.GetLimit()))
+	if err != nil {
+		return nil, err
+	}
+	paused, err := r.svc.Paused(ctx, request.GetUid())
+	if err != nil {
+		return nil, err
+	}
+	return &intrv1.ListReadHistoryResponse{
+		Histories: slice.Map(hs, func(idx int, src domain.ReadHistory) *intrv1.ReadHistory {
+			return &intrv1.ReadHistory{
+				Biz:   src.Biz,
+				BizId: src.BizId,
+				Rtime: src.Rtime.UnixMilli(),
+			}
+		}),
+		Paused: paused,
+	}, nil
+}
+
+func (r *ReadHistoryServiceServer) Delete(ctx context.Context, request *intrv1.DeleteReadHistoryRequest) (*intrv1.DeleteReadHistoryResponse, error) {
+	err := r.svc.Delete(ctx, request.GetUid(), request.GetBiz(), request.GetBizId())
+	return &intrv1.DeleteReadHistoryResponse{}, err
+}
+
+func (r *ReadHistoryServiceServer) Clear(ctx context.Context, request *intrv1.ClearReadHistoryRequest) (*intrv1.ClearReadHistoryResponse, error) {
+	err := r.svc.Clear(ctx, request.GetUid())
+	return &intrv1.ClearReadHistoryResponse{}, err
+}
+
+func (r *ReadHistoryServiceServer) Pause(ctx context.Context, request *intrv1.PauseReadHistoryRequest) (*intrv1.PauseReadHistoryResponse, error) {
+	err := r.svc.Pause(ctx, request.GetUid(), request.GetPaused())
+	return &intrv1.PauseReadHistoryResponse{}, err
+}
@@ -2,8 +2,12 @@ package grpc
 
 import (
 	"context"
+	"errors"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	intrv1 "xiaoweishu/webook/api/proto/gen/intr/v1"
+	"xiaoweishu/webook/interactive/biz"
 	"xiaoweishu/webook/interactive/domain"
 	"xiaoweishu/webook/interactive/service"
 )
@@ -27,28 +31,28 @@ func NewInteractiveServiceServer(svc service.InteractiveService) *InteractiveSer
 func (i *InteractiveServiceServer) IncrReadCnt(ctx context.Context, request *intrv1.IncrReadCntRequest) (*intrv1.IncrReadCntResponse, error) {
 	//相当于把grpc通信得到的任务，转为微服务模块中的进行执行
 	err := i.svc.IncrReadCnt(ctx, request.GetBiz(), request.GetBizId())
-	return &intrv1.IncrReadCntResponse{}, err
+	return &intrv1.IncrReadCntResponse{}, toStatusErr(err)
 }
 
 func (i *InteractiveServiceServer) Like(ctx context.Context, request *intrv1.LikeRequest) (*intrv1.LikeResponse, error) {
 	err := i.svc.Like(ctx, request.GetBiz(), request.GetBizId(), request.GetUid())
-	return &intrv1.LikeResponse{}, err
+	return &intrv1.LikeResponse{}, toStatusErr(err)
 }
 
 func (i *InteractiveServiceServer) CancelLike(ctx context.Context, request *intrv1.CancelLikeRequest) (*intrv1.CancelLikeResponse, error) {
 	err := i.svc.CancelLike(ctx, request.GetBiz(), request.GetBizId(), request.GetUid())
-	return &intrv1.CancelLikeResponse{}, err
+	return &intrv1.CancelLikeResponse{}, toStatusErr(err)
 }
 
 func (i *InteractiveServiceServer) Collect(ctx context.Context, request *intrv1.CollectRequest) (*intrv1.CollectResponse, error) {
 	err := i.svc.Collect(ctx, request.GetBiz(), request.GetBizId(), request.GetCid(), request.GetUid())
-	return &intrv1.CollectResponse{}, err
+	return &intrv1.CollectResponse{}, toStatusErr(err)
 }
 
 func (i *InteractiveServiceServer) Get(ctx context.Context, request *intrv1.GetRequest) (*intrv1.GetResponse, error) {
 	intr, err := i.svc.Get(ctx, request.GetBiz(), request.GetBizId(), request.GetUid())
 	if err != nil {
-		return nil, err
+		return nil, toStatusErr(err)
 	}
 	return &intrv1.GetResponse{
 		Intr: i.toDTO(intr),
@@ -58,7 +62,7 @@ func (i *InteractiveServiceServer) Get(ctx context.Context, request *intrv1.GetR
 func (i *InteractiveServiceServer) GetByIds(ctx context.Context, request *intrv1.GetByIdsRequest) (*intrv1.GetByIdsResponse, error) {
 	res, err := i.svc.GetByIds(ctx, request.GetBiz(), request.GetIds())
 	if err != nil {
-		return nil, err
+		return nil, toStatusErr(err)
 	}
 	intrs := make(map[int64]*intrv1.Interactive, len(res))
 	for k, v := range res {
@@ -69,6 +73,16 @@ func (i *InteractiveServiceServer) GetByIds(ctx context.Context, request *intrv1
 	}, nil
 }
 
+func (i *InteractiveServiceServer) React(ctx context.Context, request *intrv1.ReactRequest) (*intrv1.ReactResponse, error) {
+	err := i.svc.React(ctx, request.GetBiz(), request.GetBizId(), request.GetUid(), request.GetReaction())
+	return &intrv1.ReactResponse{}, toStatusErr(err)
+}
+
+func (i *InteractiveServiceServer) CancelReaction(ctx context.Context, request *intrv1.CancelReactionRequest) (*intrv1.CancelReactionResponse, error) {
+	err := i.svc.CancelReaction(ctx, request.GetBiz(), request.GetBizId(), request.GetUid())
+	return &intrv1.CancelReactionResponse{}, toStatusErr(err)
+}
+
 func (i *InteractiveServiceServer) mustEmbedUnimplementedInteractiveServiceServer() {
 	//TODO implement me
 	panic("implement me")
@@ -77,12 +91,33 @@ func (i *InteractiveServiceServer) mustEmbedUnimplementedInteractiveServiceServe
 // 把领域对象转换成grpc中的定义，必选要转，哪怕类型一样，通信的语言不同
 func (i *InteractiveServiceServer) toDTO(intr domain.Interactive) *intrv1.Interactive {
 	return &intrv1.Interactive{
-		Biz:        intr.Biz,
-		BizId:      intr.BizId,
-		ReadCnt:    intr.ReadCnt,
-		CollectCnt: intr.CollectCnt,
-		Collected:  intr.Collected,
-		Liked:      intr.Liked,
-		LikeCnt:    intr.LikeCnt,
+		Biz:          intr.Biz,
+		BizId:        intr.BizId,
+		ReadCnt:      intr.ReadCnt,
+		UvCnt:        intr.UvCnt,
+		CollectCnt:   intr.CollectCnt,
+		Collected:    intr.Collected,
+		Liked:        intr.Liked,
+		LikeCnt:      intr.LikeCnt,
+		Reaction:     intr.Reaction,
+		ReactionCnts: intr.ReactionCnts,
+	}
+}
+
+// toStatusErr 把 biz 校验的错误转成对应的 grpc 错误码，调用方可以据此区分是参数错了还是被限流了
+func toStatusErr(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, biz.ErrUnknownBiz), errors.Is(err, service.ErrInvalidReaction):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, biz.ErrActionNotAllowed):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, biz.ErrTargetNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, biz.ErrRateLimited):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	default:
+		return err
 	}
 }
@@ -0,0 +1,46 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"github.com/ecodeclub/ekit/slice"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	intrv1 "xiaoweishu/webook/api/proto/gen/intr/v1"
+	"xiaoweishu/webook/interactive/domain"
+	"xiaoweishu/webook/interactive/service"
+)
+
+type LikeRankServiceServer struct {
+	intrv1.UnimplementedLikeRankServiceServer
+	svc service.LikeRankService
+}
+
+func NewLikeRankServiceServer(svc service.LikeRankService) *LikeRankServiceServer {
+	return &LikeRankServiceServer{svc: svc}
+}
+
+func (s *LikeRankServiceServer) Register(server *grpc.Server) {
+	intrv1.RegisterLikeRankServiceServer(server, s)
+}
+
+func (s *LikeRankServiceServer) TopN(ctx context.Context, request *intrv1.LikeRankTopNRequest) (*intrv1.LikeRankTopNResponse, error) {
+	items, err := s.svc.TopN(ctx, request.GetBiz(), domain.LikeRankWindow(request.GetWindow()),
+		int(request.GetOffset()), int(request.GetLimit()))
+	if errors.Is(err, service.ErrInvalidLikeRankQuery) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &intrv1.LikeRankTopNResponse{
+		Items: slice.Map(items, func(idx int, src domain.LikeRankItem) *intrv1.LikeRankItem {
+			return &intrv1.LikeRankItem{
+				Biz:     src.Biz,
+				BizId:   src.BizId,
+				LikeCnt: src.LikeCnt,
+			}
+		}),
+	}, nil
+}
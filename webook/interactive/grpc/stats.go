@@ -0,0 +1,66 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"github.com/ecodeclub/ekit/slice"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"time"
+	intrv1 "xiaoweishu/webook/api/proto/gen/intr/v1"
+	"xiaoweishu/webook/interactive/domain"
+	"xiaoweishu/webook/interactive/service"
+)
+
+type InteractiveStatsServiceServer struct {
+	intrv1.UnimplementedInteractiveStatsServiceServer
+	svc service.InteractiveStatsService
+}
+
+func NewInteractiveStatsServiceServer(svc service.InteractiveStatsService) *InteractiveStatsServiceServer {
+	return &InteractiveStatsServiceServer{svc: svc}
+}
+
+func (s *InteractiveStatsServiceServer) Register(server *grpc.Server) {
+	intrv1.RegisterInteractiveStatsServiceServer(server, s)
+}
+
+func (s *InteractiveStatsServiceServer) Dashboard(ctx context.Context, request *intrv1.DashboardRequest) (*intrv1.DashboardResponse, error) {
+	end := time.Now()
+	if request.GetEnd() > 0 {
+		end = time.UnixMilli(request.GetEnd())
+	}
+	res, err := s.svc.Dashboard(ctx, request.GetBiz(), request.GetBizIds(),
+		domain.StatsGranularity(request.GetGranularity()), end,
+		int(request.GetPeriods()), int(request.GetTopN()))
+	if errors.Is(err, service.ErrInvalidStatsQuery) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &intrv1.DashboardResponse{
+		Trend:     slice.Map(res.Trend, func(idx int, src domain.StatsBucket) *intrv1.StatsBucket { return s.toBucketDTO(src) }),
+		Total:     s.toBucketDTO(res.Total),
+		PrevTotal: s.toBucketDTO(res.PrevTotal),
+		Top: slice.Map(res.Top, func(idx int, src domain.StatsItem) *intrv1.StatsItem {
+			return &intrv1.StatsItem{
+				Biz:        src.Biz,
+				BizId:      src.BizId,
+				ReadCnt:    src.ReadCnt,
+				LikeCnt:    src.LikeCnt,
+				CollectCnt: src.CollectCnt,
+			}
+		}),
+	}, nil
+}
+
+func (s *InteractiveStatsServiceServer) toBucketDTO(b domain.StatsBucket) *intrv1.StatsBucket {
+	return &intrv1.StatsBucket{
+		Time:       b.Time.UnixMilli(),
+		ReadCnt:    b.ReadCnt,
+		LikeCnt:    b.LikeCnt,
+		CollectCnt: b.CollectCnt,
+	}
+}
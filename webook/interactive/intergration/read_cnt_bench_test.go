@@ -0,0 +1,125 @@
+package intergration
+
+import (
+	"context"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"testing"
+	"time"
+	"xiaoweishu/webook/interactive/domain"
+	"xiaoweishu/webook/interactive/intergration/startup"
+	"xiaoweishu/webook/interactive/repository/cache"
+	"xiaoweishu/webook/interactive/repository/dao"
+)
+
+// 对比一批阅读事件逐条 upsert 和聚合之后多行 upsert 的吞吐
+// 需要本地的 MySQL 和 Redis，运行：
+// go test -run=^$ -bench=ReadCnt ./webook/interactive/intergration/
+
+// readCntBatch 模拟一批阅读事件，hot 篇热点文章占了大部分的阅读
+func readCntBatch(size int, hot int) ([]string, []int64) {
+	bizs := make([]string, 0, size)
+	ids := make([]int64, 0, size)
+	for i := 0; i < size; i++ {
+		bizs = append(bizs, "bench")
+		ids = append(ids, int64(i%hot+1))
+	}
+	return bizs, ids
+}
+
+// loopIncrReadCnt 原来的实现，一个事件一条 INSERT ... ON DUPLICATE KEY UPDATE
+func loopIncrReadCnt(ctx context.Context, db *gorm.DB, bizs []string, ids []int64) error {
+	for i := 0; i < len(bizs); i++ {
+		now := time.Now().UnixMilli()
+		err := db.WithContext(ctx).Clauses(clause.OnConflict{
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"read_cnt": gorm.Expr("`read_cnt` +1"),
+				"utime":    now,
+			}),
+		}).Create(&dao.Interactive{
+			Biz:     bizs[i],
+			BizId:   ids[i],
+			Ctime:   now,
+			Utime:   now,
+			ReadCnt: 1,
+		}).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func BenchmarkBatchIncrReadCnt(b *testing.B) {
+	db := startup.InitDB()
+	d := dao.NewGORMInteractiveDAO(db)
+	ctx := context.Background()
+	for _, size := range []int{10, 100, 500} {
+		bizs, ids := readCntBatch(size, 5)
+		b.Run(fmt.Sprintf("loop-%d", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if err := loopIncrReadCnt(ctx, db, bizs, ids); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(b.N*size)/b.Elapsed().Seconds(), "events/s")
+		})
+		b.Run(fmt.Sprintf("multi-row-%d", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if err := d.BatchIncrReadCnt(ctx, bizs, ids); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(b.N*size)/b.Elapsed().Seconds(), "events/s")
+		})
+	}
+	db.Exec("DELETE FROM `interactives` WHERE `biz` = ?", "bench")
+}
+
+func BenchmarkBatchIncrReadCntCache(b *testing.B) {
+	rdb := startup.InitRedis()
+	c := cache.NewInteractiveRedisCache(rdb)
+	ctx := context.Background()
+	for _, size := range []int{10, 100, 500} {
+		bizs, ids := readCntBatch(size, size)
+		// 预热，让 key 都在缓存里，不然 lua 什么也不做
+		for i := range ids {
+			if err := c.Set(ctx, bizs[i], ids[i], domain.Interactive{Biz: bizs[i], BizId: ids[i]}); err != nil {
+				b.Fatal(err)
+			}
+		}
+		deltas := make([]domain.CntDelta, 0, size)
+		for i := range ids {
+			deltas = append(deltas, domain.CntDelta{Biz: bizs[i], BizId: ids[i], ReadCnt: 1})
+		}
+		b.Run(fmt.Sprintf("loop-%d", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for j := range ids {
+					if err := c.IncrReadCntIfPresent(ctx, bizs[j], ids[j]); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+			b.ReportMetric(float64(b.N*size)/b.Elapsed().Seconds(), "events/s")
+		})
+		b.Run(fmt.Sprintf("pipeline-%d", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if err := c.BatchAddCntIfPresent(ctx, deltas); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(b.N*size)/b.Elapsed().Seconds(), "events/s")
+		})
+	}
+	cleanBenchKeys(ctx, rdb)
+}
+
+func cleanBenchKeys(ctx context.Context, rdb redis.Cmdable) {
+	keys, err := rdb.Keys(ctx, "interactive:bench:*").Result()
+	if err != nil || len(keys) == 0 {
+		return
+	}
+	rdb.Del(ctx, keys...)
+}
@@ -0,0 +1,28 @@
+package ioc
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"xiaoweishu/webook/interactive/web"
+	"xiaoweishu/webook/pkg/ginx"
+)
+
+// AdminServer 对账、重建点赞榜这些运维接口，和迁移的接口分开，默认只监听本机
+type AdminServer struct {
+	*ginx.Server
+}
+
+func InitAdminServer(adminHdl *web.AdminHandler) AdminServer {
+	engine := gin.Default()
+	adminHdl.RegisterRoutes(engine.Group("/interactive"))
+	addr := viper.GetString("admin.http.addr")
+	if addr == "" {
+		addr = "127.0.0.1:8084"
+	}
+	return AdminServer{
+		Server: &ginx.Server{
+			Engine: engine,
+			Addr:   addr,
+		},
+	}
+}
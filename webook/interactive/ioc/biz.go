@@ -0,0 +1,57 @@
+package ioc
+
+import (
+	"github.com/redis/go-redis/v9"
+	"github.com/spf13/viper"
+	"time"
+	"xiaoweishu/webook/interactive/biz"
+	"xiaoweishu/webook/interactive/repository/dao"
+	"xiaoweishu/webook/pkg/limiter"
+	"xiaoweishu/webook/pkg/logger"
+)
+
+// InitArticleDAO 文章在 webook 库里面，也就是迁移之前的源库，不走双写
+func InitArticleDAO(src SrcDB) dao.ArticleDAO {
+	return dao.NewGORMArticleDAO(src)
+}
+
+// InitBizRegistry 从配置里面读取所有合法的 biz，没有配置就用默认的那几种。
+// 文章在点赞、收藏和阅读之前要检查还在不在，免得给删掉的文章产生交互数据
+func InitBizRegistry(cmd redis.Cmdable, articles dao.ArticleDAO, l logger.LoggerV1) *biz.Registry {
+	type Config struct {
+		Actions []string `yaml:"actions"`
+		// RateLimit 单个用户一分钟内最多操作多少次，0 表示不限流
+		RateLimit int           `yaml:"rateLimit"`
+		CacheTTL  time.Duration `yaml:"cacheTTL"`
+	}
+	cfgs := map[string]Config{
+		"article": {Actions: []string{"read", "like", "collect"}, RateLimit: 60},
+		"comment": {Actions: []string{"like"}, RateLimit: 120, CacheTTL: time.Minute * 5},
+		"video":   {Actions: []string{"read", "like", "collect"}, RateLimit: 60},
+	}
+	if viper.IsSet("interactive.bizs") {
+		cfgs = map[string]Config{}
+		err := viper.UnmarshalKey("interactive.bizs", &cfgs)
+		if err != nil {
+			panic(err)
+		}
+	}
+	registry := biz.NewRegistry(l)
+	for name, cfg := range cfgs {
+		bc := biz.Config{
+			Name:     name,
+			CacheTTL: cfg.CacheTTL,
+		}
+		for _, a := range cfg.Actions {
+			bc.Actions = append(bc.Actions, biz.Action(a))
+		}
+		if name == "article" {
+			bc.Exists = articles.Exists
+		}
+		if cfg.RateLimit > 0 {
+			bc.Limiter = limiter.NewRedisSlidingWindowLimiter(cmd, time.Minute, cfg.RateLimit)
+		}
+		registry.Register(bc)
+	}
+	return registry
+}
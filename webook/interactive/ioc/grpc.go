@@ -9,7 +9,11 @@ import (
 	"xiaoweishu/webook/pkg/logger"
 )
 
-func NewGrpcxServer(intrSvc *grpc2.InteractiveServiceServer, ecli *clientv3.Client, l logger.LoggerV1) *grpcx.Server {
+func NewGrpcxServer(intrSvc *grpc2.InteractiveServiceServer,
+	historySvc *grpc2.ReadHistoryServiceServer,
+	statsSvc *grpc2.InteractiveStatsServiceServer,
+	likeRankSvc *grpc2.LikeRankServiceServer,
+	ecli *clientv3.Client, l logger.LoggerV1) *grpcx.Server {
 	type Config struct {
 		Port     int    `yaml:"port"`
 		EtcdAddr string `yaml:"etcdAddr"`
@@ -22,6 +26,9 @@ func NewGrpcxServer(intrSvc *grpc2.InteractiveServiceServer, ecli *clientv3.Clie
 	}
 	server := grpc.NewServer()
 	intrSvc.Register(server)
+	historySvc.Register(server)
+	statsSvc.Register(server)
+	likeRankSvc.Register(server)
 	return &grpcx.Server{
 		Server:     server,
 		Port:       cfg.Port,
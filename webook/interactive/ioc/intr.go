@@ -0,0 +1,30 @@
+package ioc
+
+import (
+	"github.com/IBM/sarama"
+	"github.com/spf13/viper"
+	"xiaoweishu/webook/interactive/biz"
+	"xiaoweishu/webook/interactive/events/intr"
+	"xiaoweishu/webook/interactive/repository"
+	"xiaoweishu/webook/interactive/service"
+	"xiaoweishu/webook/pkg/logger"
+)
+
+// writeBehindEnabled 打开之后点赞和阅读的计数都走 kafka 攒批写回
+func writeBehindEnabled() bool {
+	return viper.GetBool("interactive.writeBehind")
+}
+
+func InitIntrProducer(p sarama.SyncProducer) intr.Producer {
+	return intr.NewSaramaSyncProducer(p)
+}
+
+// InitInteractiveService 对外的接口都要经过 biz 的校验
+func InitInteractiveService(repo repository.InteractiveRepository,
+	producer intr.Producer, registry *biz.Registry, l logger.LoggerV1) service.InteractiveService {
+	svc := service.NewInteractiveServiceV1(repo, producer, l)
+	if writeBehindEnabled() {
+		svc = service.NewWriteBehindInteractiveService(repo, producer, l)
+	}
+	return service.NewBizCheckedInteractiveService(svc, registry)
+}
@@ -37,6 +37,15 @@ func InitSaramaSyncProducer(client sarama.Client) sarama.SyncProducer {
 }
 
 // 每种事件都需要初始化一个消费者
-func InitConsumers(c1 *events2.InteractiveReadEventConsumer, fixConsumer *fixer.Consumer[dao.Interactive]) []events.Consumer {
-	return []events.Consumer{c1, fixConsumer}
+// 写回模式下阅读计数也由 WriteBehindConsumer 负责，不能两个都启动，否则会重复计数
+func InitConsumers(c1 *events2.InteractiveReadEventConsumer,
+	writeBehindConsumer *events2.WriteBehindConsumer,
+	historyConsumer *events2.ReadHistoryConsumer,
+	statsConsumer *events2.StatsConsumer,
+	likeRankConsumer *events2.LikeRankConsumer,
+	fixConsumer *fixer.Consumer[dao.Interactive]) []events.Consumer {
+	if writeBehindEnabled() {
+		return []events.Consumer{writeBehindConsumer, historyConsumer, statsConsumer, likeRankConsumer, fixConsumer}
+	}
+	return []events.Consumer{c1, historyConsumer, statsConsumer, likeRankConsumer, fixConsumer}
 }
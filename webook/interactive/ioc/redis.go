@@ -3,6 +3,10 @@ package ioc
 import (
 	"github.com/redis/go-redis/v9"
 	"github.com/spf13/viper"
+	"time"
+	"xiaoweishu/webook/interactive/events"
+	"xiaoweishu/webook/pkg/limiter"
+	"xiaoweishu/webook/pkg/logger"
 )
 
 func InitRedis() redis.Cmdable {
@@ -10,3 +14,9 @@ func InitRedis() redis.Cmdable {
 		Addr: viper.GetString("redis.addr"),
 	})
 }
+
+// InitReadEventFilter 单个 IP 一分钟内超过 120 次阅读就认为是在刷量
+func InitReadEventFilter(cmd redis.Cmdable, l logger.LoggerV1) events.ReadEventFilter {
+	return events.NewHeuristicReadEventFilter(
+		limiter.NewRedisSlidingWindowLimiter(cmd, time.Minute, 120), l)
+}
@@ -13,6 +13,7 @@ import (
 	"xiaoweishu/webook/interactive/repository/cache"
 	"xiaoweishu/webook/interactive/repository/dao"
 	"xiaoweishu/webook/interactive/service"
+	"xiaoweishu/webook/interactive/web"
 	events2 "xiaoweishu/webook/internal/events"
 	ioc2 "xiaoweishu/webook/ioc"
 	"xiaoweishu/webook/pkg/ginx"
@@ -23,6 +24,8 @@ type App struct {
 	consumers   []events2.Consumer
 	server      *grpcx.Server
 	adminServer *ginx.Server
+	// intrAdminServer 交互服务自己的运维接口
+	intrAdminServer ioc.AdminServer
 }
 
 func InitApp() *App {
@@ -33,23 +36,47 @@ func InitApp() *App {
 	db := ioc.InitBizDB(doubleWritePool)
 	interactiveDAO := dao.NewGORMInteractiveDAO(db)
 	cmdable := ioc.InitRedis()
-	interactiveCache := cache.NewInteractiveRedisCache(cmdable)
+	articleDAO := ioc.InitArticleDAO(srcDB)
+	registry := ioc.InitBizRegistry(cmdable, articleDAO, loggerV1)
+	interactiveCache := cache.NewInteractiveRedisCacheV1(cmdable, registry)
 	interactiveRepository := repository.NewCachedInteractiveRepository(interactiveDAO, interactiveCache, loggerV1)
 	client := ioc.InitSaramaClient()
-	interactiveReadEventConsumer := events.NewInteractiveReadEventConsumer(interactiveRepository, client, loggerV1)
+	readEventFilter := ioc.InitReadEventFilter(cmdable, loggerV1)
+	interactiveReadEventConsumer := events.NewInteractiveReadEventConsumer(interactiveRepository, client, readEventFilter, loggerV1)
+	writeBehindConsumer := events.NewWriteBehindConsumer(interactiveRepository, client, readEventFilter, loggerV1)
+	readHistoryDAO := dao.NewGORMReadHistoryDAO(db)
+	readHistoryRepository := repository.NewGORMReadHistoryRepository(readHistoryDAO)
+	readHistoryService := service.NewReadHistoryService(readHistoryRepository, loggerV1)
+	readHistoryConsumer := events.NewReadHistoryConsumer(readHistoryService, client, loggerV1)
+	interactiveStatsDAO := dao.NewGORMInteractiveStatsDAO(db)
+	interactiveStatsRepository := repository.NewGORMInteractiveStatsRepository(interactiveStatsDAO)
+	interactiveStatsService := service.NewInteractiveStatsService(interactiveStatsRepository, loggerV1)
+	statsConsumer := events.NewStatsConsumer(interactiveStatsService, client, readEventFilter, loggerV1)
+	likeRankDAO := dao.NewGORMLikeRankDAO(db)
+	likeRankCache := cache.NewLikeRankRedisCache(cmdable)
+	likeRankRepository := repository.NewCachedLikeRankRepository(likeRankDAO, likeRankCache)
+	likeRankService := service.NewLikeRankService(likeRankRepository)
+	likeRankConsumer := events.NewLikeRankConsumer(likeRankService, client, loggerV1)
 	consumer := ioc.InitFixerConsumer(client, loggerV1, srcDB, dstDB)
-	v := ioc.InitConsumers(interactiveReadEventConsumer, consumer)
-	interactiveService := service.NewInteractiveService(interactiveRepository)
+	v := ioc.InitConsumers(interactiveReadEventConsumer, writeBehindConsumer, readHistoryConsumer, statsConsumer, likeRankConsumer, consumer)
+	syncProducer := ioc.InitSaramaSyncProducer(client)
+	intrProducer := ioc.InitIntrProducer(syncProducer)
+	interactiveService := ioc.InitInteractiveService(interactiveRepository, intrProducer, registry, loggerV1)
 	interactiveServiceServer := grpc.NewInteractiveServiceServer(interactiveService)
+	readHistoryServiceServer := grpc.NewReadHistoryServiceServer(readHistoryService)
+	interactiveStatsServiceServer := grpc.NewInteractiveStatsServiceServer(interactiveStatsService)
+	likeRankServiceServer := grpc.NewLikeRankServiceServer(likeRankService)
 	clientv3Client := ioc2.InitEtcd()
-	server := ioc.NewGrpcxServer(interactiveServiceServer, clientv3Client, loggerV1)
-	syncProducer := ioc.InitSaramaSyncProducer(client)
+	server := ioc.NewGrpcxServer(interactiveServiceServer, readHistoryServiceServer, interactiveStatsServiceServer, likeRankServiceServer, clientv3Client, loggerV1)
 	producer := ioc.InitInteractiveProducer(syncProducer)
+	adminHandler := web.NewAdminHandler(interactiveService, likeRankService, loggerV1)
 	ginxServer := ioc.InitGinxSever(loggerV1, srcDB, dstDB, doubleWritePool, producer)
+	adminServer := ioc.InitAdminServer(adminHandler)
 	app := &App{
-		consumers:   v,
-		server:      server,
-		adminServer: ginxServer,
+		consumers:       v,
+		server:          server,
+		adminServer:     ginxServer,
+		intrAdminServer: adminServer,
 	}
 	return app
 }
@@ -68,6 +95,10 @@ func main() {
 		err1 := app.adminServer.Start()
 		panic(err1)
 	}()
+	go func() {
+		err1 := app.intrAdminServer.Start()
+		panic(err1)
+	}()
 	err := app.server.Serve()
 	if err != nil {
 		panic(err)
@@ -6,7 +6,9 @@ import (
 	"fmt"
 	"github.com/redis/go-redis/v9"
 	"strconv"
+	"strings"
 	"time"
+	bizreg "xiaoweishu/webook/interactive/biz"
 	"xiaoweishu/webook/interactive/domain"
 	"xiaoweishu/webook/interactive/repository/dao"
 )
@@ -14,27 +16,68 @@ import (
 var (
 	//go:embed lua/incr_cnt.lua
 	luaIncrCnt string
+	//go:embed lua/uv_check.lua
+	luaUvCheck string
+	//go:embed lua/uv_add.lua
+	luaUvAdd string
 )
 
 const fieldReadCnt = "read_cnt"
+const fieldUvCnt = "uv_cnt"
 const fieldLikeCnt = "like_cnt"
 const fieldCollectCnt = "collect_cnt"
 
+// fieldReactionPrefix 每种表态的人数存成 reaction:love 这样的字段，like 还是用 like_cnt
+const fieldReactionPrefix = "reaction:"
+
+//go:generate mockgen -source=./interactive.go -package=cachemocks -destination=mocks/interactive.mock.go InteractiveCache
 type InteractiveCache interface {
 	IncrReadCntIfPresent(ctx context.Context, biz string, bizId int64) error
+	IncrUvCntIfPresent(ctx context.Context, biz string, bizId int64) error
+	// CheckVisitors 判断访客在当前时间窗口内是不是第一次出现，一个 pipeline 批量判断。
+	// 只判断不记录，数据库更新成功之后再用 AddVisitors 记下来
+	CheckVisitors(ctx context.Context, bizs []string, bizIds []int64, visitors []string) ([]bool, error)
+	// AddVisitors 把访客记到当前时间窗口的 HyperLogLog 里面
+	AddVisitors(ctx context.Context, bizs []string, bizIds []int64, visitors []string) error
 	IncrLikeCntIfPresent(ctx context.Context, biz string, id int64) error
 	DecrLikeCntIfPresent(ctx context.Context, biz string, id int64) error
 	IncrCollectCntIfPresent(ctx context.Context, biz string, id int64) error
+	// IncrReactionCntIfPresent delta 可以是负数，切换表态的时候旧的减一新的加一
+	IncrReactionCntIfPresent(ctx context.Context, biz string, id int64, reaction string, delta int64) error
+	// BatchAddCntIfPresent 把聚合好的增量加到缓存上，所有的 lua 调用放在一个 pipeline 里面
+	BatchAddCntIfPresent(ctx context.Context, deltas []domain.CntDelta) error
 	Get(ctx context.Context, biz string, id int64) (domain.Interactive, error)
 	Set(ctx context.Context, biz string, bizId int64, res domain.Interactive) error
+	// GetByIds 一个 pipeline 批量查询，只返回缓存命中的
+	GetByIds(ctx context.Context, biz string, ids []int64) (map[int64]domain.Interactive, error)
+	// BatchSet 一个 pipeline 批量回写缓存
+	BatchSet(ctx context.Context, intrs []domain.Interactive) error
+	Del(ctx context.Context, biz string, bizId int64) error
 }
 type InteractiveRedisCache struct {
 	client redis.Cmdable
+	// 统计 UV 的时间窗口，同一个访客在一个窗口内只算一次
+	uvWindow time.Duration
+	// ttl 不同的 biz 可以有不同的缓存过期时间
+	ttl func(biz string) time.Duration
 }
 
 func NewInteractiveRedisCache(client redis.Cmdable) InteractiveCache {
 	return &InteractiveRedisCache{
-		client: client,
+		client:   client,
+		uvWindow: time.Hour * 24,
+		ttl: func(biz string) time.Duration {
+			return time.Minute * 15
+		},
+	}
+}
+
+// NewInteractiveRedisCacheV1 每种 biz 的缓存过期时间按照注册中心里面的配置来
+func NewInteractiveRedisCacheV1(client redis.Cmdable, registry *bizreg.Registry) InteractiveCache {
+	return &InteractiveRedisCache{
+		client:   client,
+		uvWindow: time.Hour * 24,
+		ttl:      registry.CacheTTL,
 	}
 }
 func (i InteractiveRedisCache) IncrReadCntIfPresent(ctx context.Context, biz string, bizId int64) error {
@@ -43,6 +86,58 @@ func (i InteractiveRedisCache) IncrReadCntIfPresent(ctx context.Context, biz str
 	return i.client.Eval(ctx, luaIncrCnt, []string{key}, fieldReadCnt, 1).Err()
 }
 
+func (i InteractiveRedisCache) IncrUvCntIfPresent(ctx context.Context, biz string, bizId int64) error {
+	key := i.key(biz, bizId)
+	return i.client.Eval(ctx, luaIncrCnt, []string{key}, fieldUvCnt, 1).Err()
+}
+
+func (i InteractiveRedisCache) CheckVisitors(ctx context.Context, bizs []string, bizIds []int64,
+	visitors []string) ([]bool, error) {
+	//HyperLogLog 只有 12KB，PFADD 返回 1 就说明基数变了，也就是新访客。
+	//有一点误差，但是对于阅读数来说完全可以接受
+	window := i.uvWindowIdx()
+	pipe := i.client.Pipeline()
+	cmds := make([]*redis.Cmd, 0, len(bizs))
+	for idx := range bizs {
+		key := i.uvKey(bizs[idx], bizIds[idx], window)
+		cmds = append(cmds, pipe.Eval(ctx, luaUvCheck, []string{key, key + ":check"}, visitors[idx]))
+	}
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]bool, 0, len(cmds))
+	for _, cmd := range cmds {
+		added, er := cmd.Int()
+		if er != nil {
+			return nil, er
+		}
+		res = append(res, added == 1)
+	}
+	return res, nil
+}
+
+func (i InteractiveRedisCache) AddVisitors(ctx context.Context, bizs []string, bizIds []int64,
+	visitors []string) error {
+	window := i.uvWindowIdx()
+	pipe := i.client.Pipeline()
+	for idx := range bizs {
+		//多留一个窗口，窗口切换的时候不至于立刻丢失
+		pipe.Eval(ctx, luaUvAdd, []string{i.uvKey(bizs[idx], bizIds[idx], window)},
+			visitors[idx], (i.uvWindow * 2).Milliseconds())
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (i InteractiveRedisCache) uvWindowIdx() int64 {
+	return time.Now().UnixMilli() / i.uvWindow.Milliseconds()
+}
+
+func (i InteractiveRedisCache) uvKey(biz string, bizId int64, window int64) string {
+	return fmt.Sprintf("interactive:uv:%s:%d:%d", biz, bizId, window)
+}
+
 func (i InteractiveRedisCache) IncrLikeCntIfPresent(ctx context.Context, biz string, id int64) error {
 	key := i.key(biz, id)
 	return i.client.Eval(ctx, luaIncrCnt, []string{key}, fieldLikeCnt, 1).Err()
@@ -59,6 +154,41 @@ func (i InteractiveRedisCache) IncrCollectCntIfPresent(ctx context.Context, biz
 
 }
 
+func (i InteractiveRedisCache) IncrReactionCntIfPresent(ctx context.Context, biz string, id int64, reaction string, delta int64) error {
+	key := i.key(biz, id)
+	return i.client.Eval(ctx, luaIncrCnt, []string{key}, i.reactionField(reaction), delta).Err()
+}
+
+func (i InteractiveRedisCache) reactionField(reaction string) string {
+	if reaction == domain.ReactionLike {
+		return fieldLikeCnt
+	}
+	return fieldReactionPrefix + reaction
+}
+
+func (i InteractiveRedisCache) BatchAddCntIfPresent(ctx context.Context, deltas []domain.CntDelta) error {
+	if len(deltas) == 0 {
+		return nil
+	}
+	//一批只有一次网络往返，每个 lua 脚本自己还是原子的
+	pipe := i.client.Pipeline()
+	for _, d := range deltas {
+		key := i.key(d.Biz, d.BizId)
+		if d.ReadCnt != 0 {
+			pipe.Eval(ctx, luaIncrCnt, []string{key}, fieldReadCnt, d.ReadCnt)
+		}
+		if d.LikeCnt != 0 {
+			pipe.Eval(ctx, luaIncrCnt, []string{key}, fieldLikeCnt, d.LikeCnt)
+		}
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (i InteractiveRedisCache) Del(ctx context.Context, biz string, bizId int64) error {
+	return i.client.Del(ctx, i.key(biz, bizId)).Err()
+}
+
 func (i InteractiveRedisCache) Get(ctx context.Context, biz string, id int64) (domain.Interactive, error) {
 	//当redis中某个键的值是一堆键值对时，那么存储的时候用哈希表进行存储更合适
 	key := i.key(biz, id)
@@ -69,27 +199,94 @@ func (i InteractiveRedisCache) Get(ctx context.Context, biz string, id int64) (d
 	if len(res) == 0 {
 		return domain.Interactive{}, dao.ErrRecordNotFound
 	}
-	var intr domain.Interactive
-	intr.Biz = biz
-	intr.BizId = id
-	intr.CollectCnt, _ = strconv.ParseInt(res[fieldCollectCnt], 10, 64)
-	intr.LikeCnt, _ = strconv.ParseInt(res[fieldLikeCnt], 10, 64)
-	intr.ReadCnt, _ = strconv.ParseInt(res[fieldReadCnt], 10, 64)
-	return intr, nil
+	return i.toDomain(biz, id, res), nil
+}
+
+func (i InteractiveRedisCache) GetByIds(ctx context.Context, biz string, ids []int64) (map[int64]domain.Interactive, error) {
+	pipe := i.client.Pipeline()
+	cmds := make([]*redis.MapStringStringCmd, 0, len(ids))
+	for _, id := range ids {
+		cmds = append(cmds, pipe.HGetAll(ctx, i.key(biz, id)))
+	}
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[int64]domain.Interactive, len(ids))
+	for idx, cmd := range cmds {
+		vals := cmd.Val()
+		//空的哈希表就是没有命中
+		if len(vals) == 0 {
+			continue
+		}
+		res[ids[idx]] = i.toDomain(biz, ids[idx], vals)
+	}
+	return res, nil
 }
 
 func (i InteractiveRedisCache) Set(ctx context.Context, biz string, bizId int64, res domain.Interactive) error {
 	key := i.key(biz, bizId)
 	//redis中若某个键的值是键值对的话，那么就用哈希表来存储，如果就是单独的一个值的话， 那么直接set /get就行了
 	//否则就要用hset/hgetall
-	err := i.client.HSet(ctx, key, fieldCollectCnt, res.CollectCnt,
-		fieldLikeCnt, res.LikeCnt,
-		fieldReadCnt, res.ReadCnt).Err()
+	err := i.client.HSet(ctx, key, i.fields(res)...).Err()
 	//这里也要写成哈希表的样子
 	if err != nil {
 		return err //回写缓存失败
 	}
-	return i.client.Expire(ctx, key, time.Minute*15).Err()
+	return i.client.Expire(ctx, key, i.ttl(biz)).Err()
+}
+
+func (i InteractiveRedisCache) BatchSet(ctx context.Context, intrs []domain.Interactive) error {
+	if len(intrs) == 0 {
+		return nil
+	}
+	pipe := i.client.Pipeline()
+	for _, intr := range intrs {
+		key := i.key(intr.Biz, intr.BizId)
+		pipe.HSet(ctx, key, i.fields(intr)...)
+		pipe.Expire(ctx, key, i.ttl(intr.Biz))
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (i InteractiveRedisCache) fields(intr domain.Interactive) []any {
+	res := []any{fieldCollectCnt, intr.CollectCnt,
+		fieldLikeCnt, intr.LikeCnt,
+		fieldReadCnt, intr.ReadCnt,
+		fieldUvCnt, intr.UvCnt}
+	for reaction, cnt := range intr.ReactionCnts {
+		if reaction == domain.ReactionLike {
+			continue
+		}
+		res = append(res, fieldReactionPrefix+reaction, cnt)
+	}
+	return res
+}
+
+func (i InteractiveRedisCache) toDomain(biz string, id int64, res map[string]string) domain.Interactive {
+	var intr domain.Interactive
+	intr.Biz = biz
+	intr.BizId = id
+	intr.CollectCnt, _ = strconv.ParseInt(res[fieldCollectCnt], 10, 64)
+	intr.LikeCnt, _ = strconv.ParseInt(res[fieldLikeCnt], 10, 64)
+	intr.ReadCnt, _ = strconv.ParseInt(res[fieldReadCnt], 10, 64)
+	intr.UvCnt, _ = strconv.ParseInt(res[fieldUvCnt], 10, 64)
+	intr.ReactionCnts = make(map[string]int64)
+	if intr.LikeCnt > 0 {
+		intr.ReactionCnts[domain.ReactionLike] = intr.LikeCnt
+	}
+	for field, val := range res {
+		reaction, ok := strings.CutPrefix(field, fieldReactionPrefix)
+		if !ok {
+			continue
+		}
+		cnt, _ := strconv.ParseInt(val, 10, 64)
+		if cnt > 0 {
+			intr.ReactionCnts[reaction] = cnt
+		}
+	}
+	return intr
 }
 
 func (i *InteractiveRedisCache) key(biz string, bizId int64) string {
@@ -0,0 +1,150 @@
+package cache
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"strconv"
+	"time"
+	"xiaoweishu/webook/interactive/domain"
+)
+
+var (
+	//go:embed lua/like_rank_incr.lua
+	luaLikeRankIncr string
+	//go:embed lua/like_rank_replace.lua
+	luaLikeRankReplace string
+)
+
+const (
+	// likeRankRebuildBatch 重建的时候一次 ZADD 多少个
+	likeRankRebuildBatch = 500
+	// likeRankRebuildTimeout 重建超过这个时间还没有结束的，就不再记录增量了
+	likeRankRebuildTimeout = time.Minute * 10
+)
+
+//go:generate mockgen -source=./like_rank.go -package=cachemocks -destination=mocks/like_rank.mock.go LikeRankCache
+type LikeRankCache interface {
+	// BatchIncr 每个增量同时计入所有窗口，一个 pipeline 执行
+	BatchIncr(ctx context.Context, deltas []domain.LikeRankDelta) error
+	// Top 按照点赞数从高到低，返回 t 所在窗口的 [offset, offset+limit)
+	Top(ctx context.Context, biz string, w domain.LikeRankWindow, t time.Time, offset, limit int) ([]domain.LikeRankItem, error)
+	// BeginReplace 开始重建 t 所在窗口的榜单，之后的增量会另外记一份，Replace 的时候重放
+	BeginReplace(ctx context.Context, biz string, w domain.LikeRankWindow, t time.Time) error
+	// Replace 用 items 整体替换 t 所在窗口的榜单，再重放 since 之后的增量。
+	// since 是开始从数据库加载数据的时间
+	Replace(ctx context.Context, biz string, w domain.LikeRankWindow, t time.Time,
+		items []domain.LikeRankItem, since time.Time) error
+}
+
+type LikeRankRedisCache struct {
+	client redis.Cmdable
+}
+
+func NewLikeRankRedisCache(client redis.Cmdable) LikeRankCache {
+	return &LikeRankRedisCache{client: client}
+}
+
+func (c *LikeRankRedisCache) BatchIncr(ctx context.Context, deltas []domain.LikeRankDelta) error {
+	if len(deltas) == 0 {
+		return nil
+	}
+	pipe := c.client.Pipeline()
+	n := len(domain.LikeRankWindows)
+	for _, d := range deltas {
+		keys := make([]string, n*3)
+		args := []any{strconv.FormatInt(d.BizId, 10), d.Delta, d.Ctime.UnixMilli()}
+		for i, w := range domain.LikeRankWindows {
+			key := c.key(d.Biz, w, d.Time)
+			keys[i], keys[n+i], keys[2*n+i] = key, c.markerKey(key), c.journalKey(key)
+			args = append(args, int64(c.ttl(w)/time.Second))
+		}
+		pipe.Eval(ctx, luaLikeRankIncr, keys, args...)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (c *LikeRankRedisCache) Top(ctx context.Context, biz string, w domain.LikeRankWindow, t time.Time, offset, limit int) ([]domain.LikeRankItem, error) {
+	zs, err := c.client.ZRevRangeWithScores(ctx, c.key(biz, w, t),
+		int64(offset), int64(offset+limit-1)).Result()
+	if err != nil {
+		return nil, err
+	}
+	res := make([]domain.LikeRankItem, 0, len(zs))
+	for _, z := range zs {
+		id, err := strconv.ParseInt(z.Member.(string), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, domain.LikeRankItem{
+			Biz:     biz,
+			BizId:   id,
+			LikeCnt: int64(z.Score),
+		})
+	}
+	return res, nil
+}
+
+func (c *LikeRankRedisCache) BeginReplace(ctx context.Context, biz string, w domain.LikeRankWindow, t time.Time) error {
+	key := c.key(biz, w, t)
+	//上一次重建失败留下来的增量日志不能再用
+	err := c.client.Del(ctx, c.journalKey(key)).Err()
+	if err != nil {
+		return err
+	}
+	return c.client.Set(ctx, c.markerKey(key), 1, likeRankRebuildTimeout).Err()
+}
+
+// Replace 先写到临时的 key 上，最后 RENAME 过去，读的人不会看到写了一半的榜单。
+// 重建期间的增量照样落在旧的 key 上，RENAME 之后在同一个 lua 脚本里面重放到新的 key 上
+func (c *LikeRankRedisCache) Replace(ctx context.Context, biz string, w domain.LikeRankWindow, t time.Time,
+	items []domain.LikeRankItem, since time.Time) error {
+	key := c.key(biz, w, t)
+	tmp := key + ":rebuild"
+	err := c.client.Del(ctx, tmp).Err()
+	if err != nil {
+		return err
+	}
+	for start := 0; start < len(items); start += likeRankRebuildBatch {
+		end := min(start+likeRankRebuildBatch, len(items))
+		zs := make([]redis.Z, 0, end-start)
+		for _, item := range items[start:end] {
+			zs = append(zs, redis.Z{Score: float64(item.LikeCnt), Member: strconv.FormatInt(item.BizId, 10)})
+		}
+		err = c.client.ZAdd(ctx, tmp, zs...).Err()
+		if err != nil {
+			return err
+		}
+	}
+	return c.client.Eval(ctx, luaLikeRankReplace,
+		[]string{key, tmp, c.markerKey(key), c.journalKey(key)},
+		since.UnixMilli(), int64(c.ttl(w)/time.Second)).Err()
+}
+
+// ttl 窗口结束之后再保留一段时间，方便看上一个窗口的榜单，总榜不过期
+func (c *LikeRankRedisCache) ttl(w domain.LikeRankWindow) time.Duration {
+	switch w {
+	case domain.LikeRankDay:
+		return time.Hour * 48
+	case domain.LikeRankWeek:
+		return time.Hour * 24 * 14
+	default:
+		return 0
+	}
+}
+
+// markerKey 存在就说明 key 正在重建
+func (c *LikeRankRedisCache) markerKey(key string) string {
+	return key + ":rebuilding"
+}
+
+// journalKey 重建期间的增量
+func (c *LikeRankRedisCache) journalKey(key string) string {
+	return key + ":journal"
+}
+
+func (c *LikeRankRedisCache) key(biz string, w domain.LikeRankWindow, t time.Time) string {
+	return fmt.Sprintf("like_rank:%s:%s:%s", biz, w, w.Period(t))
+}
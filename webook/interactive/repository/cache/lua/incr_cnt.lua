@@ -0,0 +1,14 @@
+-- 具体业务
+local key = KEYS[1]
+-- 是阅读数，点赞数还是收藏数
+local cntKey = ARGV[1]
+-- 增量，可以是负数
+local delta = tonumber(ARGV[2])
+local exist = redis.call("EXISTS", key)
+if exist == 1 then
+    redis.call("HINCRBY", key, cntKey, delta)
+    return 1
+else
+    -- 缓存里面没有，说明不是热点数据，不需要处理
+    return 0
+end
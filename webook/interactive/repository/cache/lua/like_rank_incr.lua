@@ -0,0 +1,31 @@
+-- 按天、按周和总榜三个 zset，KEYS 依次是所有的榜单、重建标记和重建期间的增量日志
+local n = #KEYS / 3
+-- 资源
+local member = ARGV[1]
+-- 增量，点赞是 1，取消是 -1
+local delta = tonumber(ARGV[2])
+-- 点赞或者取消发生的时间，毫秒
+local ctime = ARGV[3]
+for i = 1, n do
+    local key = KEYS[i]
+    -- 正在重建的榜单，这个增量重建完了要重放一次，不然会被整体替换掉
+    if redis.call("EXISTS", KEYS[n + i]) == 1 then
+        local journal = KEYS[2 * n + i]
+        redis.call("RPUSH", journal, member .. ":" .. delta .. ":" .. ctime)
+        redis.call("EXPIRE", journal, redis.call("TTL", KEYS[n + i]))
+    end
+    local score = redis.call("ZSCORE", key, member)
+    -- 取消点赞的时候不在榜上，说明是之前的窗口点的赞，不需要处理
+    if delta > 0 or score then
+        local cnt = tonumber(redis.call("ZINCRBY", key, delta, member))
+        if cnt <= 0 then
+            redis.call("ZREM", key, member)
+        end
+        -- 0 就是不过期
+        local ttl = tonumber(ARGV[i + 3])
+        if ttl > 0 then
+            redis.call("EXPIRE", key, ttl)
+        end
+    end
+end
+return 0
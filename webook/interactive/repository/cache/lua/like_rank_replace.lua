@@ -0,0 +1,34 @@
+-- 用重建好的临时榜单替换线上的榜单，再把重建期间的增量重放一次
+local key = KEYS[1]
+local tmp = KEYS[2]
+local marker = KEYS[3]
+local journal = KEYS[4]
+-- 开始从数据库加载的时间，在这之前的增量数据库里面已经有了
+local since = tonumber(ARGV[1])
+-- 0 就是不过期
+local ttl = tonumber(ARGV[2])
+
+if redis.call("EXISTS", tmp) == 1 then
+    redis.call("RENAME", tmp, key)
+else
+    redis.call("DEL", key)
+end
+local entries = redis.call("LRANGE", journal, 0, -1)
+for _, entry in ipairs(entries) do
+    local member, delta, ctime = string.match(entry, "^(.-):(-?%d+):(%d+)$")
+    delta = tonumber(delta)
+    if member and tonumber(ctime) >= since then
+        local score = redis.call("ZSCORE", key, member)
+        if delta > 0 or score then
+            local cnt = tonumber(redis.call("ZINCRBY", key, delta, member))
+            if cnt <= 0 then
+                redis.call("ZREM", key, member)
+            end
+        end
+    end
+end
+if ttl > 0 and redis.call("EXISTS", key) == 1 then
+    redis.call("EXPIRE", key, ttl)
+end
+redis.call("DEL", marker, journal)
+return #entries
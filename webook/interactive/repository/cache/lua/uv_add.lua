@@ -0,0 +1,10 @@
+-- 把访客记到 HyperLogLog 里面，第一次出现的时候顺便设置过期时间，两步在一起执行
+local key = KEYS[1]
+local visitor = ARGV[1]
+-- 过期时间，毫秒
+local ttl = tonumber(ARGV[2])
+local added = redis.call("PFADD", key, visitor)
+if added == 1 then
+    redis.call("PEXPIRE", key, ttl)
+end
+return added
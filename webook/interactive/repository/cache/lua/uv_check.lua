@@ -0,0 +1,13 @@
+-- 判断访客在这个窗口内是不是第一次出现，不修改原来的 HyperLogLog
+local key = KEYS[1]
+-- 复制一份到临时的 key 上面试着加一下
+local tmp = KEYS[2]
+local visitor = ARGV[1]
+local hll = redis.call("GET", key)
+if not hll then
+    return 1
+end
+redis.call("SET", tmp, hll)
+local added = redis.call("PFADD", tmp, visitor)
+redis.call("DEL", tmp)
+return added
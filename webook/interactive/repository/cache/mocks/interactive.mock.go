@@ -0,0 +1,240 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./interactive.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./interactive.go -package=cachemocks -destination=mocks/interactive.mock.go InteractiveCache
+//
+
+// Package cachemocks is a generated GoMock package.
+package cachemocks
+
+import (
+	context "context"
+	reflect "reflect"
+	domain "xiaoweishu/webook/interactive/domain"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockInteractiveCache is a mock of InteractiveCache interface.
+type MockInteractiveCache struct {
+	ctrl     *gomock.Controller
+	recorder *MockInteractiveCacheMockRecorder
+}
+
+// MockInteractiveCacheMockRecorder is the mock recorder for MockInteractiveCache.
+type MockInteractiveCacheMockRecorder struct {
+	mock *MockInteractiveCache
+}
+
+// NewMockInteractiveCache creates a new mock instance.
+func NewMockInteractiveCache(ctrl *gomock.Controller) *MockInteractiveCache {
+	mock := &MockInteractiveCache{ctrl: ctrl}
+	mock.recorder = &MockInteractiveCacheMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockInteractiveCache) EXPECT() *MockInteractiveCacheMockRecorder {
+	return m.recorder
+}
+
+// AddVisitors mocks base method.
+func (m *MockInteractiveCache) AddVisitors(ctx context.Context, bizs []string, bizIds []int64, visitors []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddVisitors", ctx, bizs, bizIds, visitors)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddVisitors indicates an expected call of AddVisitors.
+func (mr *MockInteractiveCacheMockRecorder) AddVisitors(ctx, bizs, bizIds, visitors any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddVisitors", reflect.TypeOf((*MockInteractiveCache)(nil).AddVisitors), ctx, bizs, bizIds, visitors)
+}
+
+// BatchAddCntIfPresent mocks base method.
+func (m *MockInteractiveCache) BatchAddCntIfPresent(ctx context.Context, deltas []domain.CntDelta) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BatchAddCntIfPresent", ctx, deltas)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// BatchAddCntIfPresent indicates an expected call of BatchAddCntIfPresent.
+func (mr *MockInteractiveCacheMockRecorder) BatchAddCntIfPresent(ctx, deltas any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BatchAddCntIfPresent", reflect.TypeOf((*MockInteractiveCache)(nil).BatchAddCntIfPresent), ctx, deltas)
+}
+
+// BatchSet mocks base method.
+func (m *MockInteractiveCache) BatchSet(ctx context.Context, intrs []domain.Interactive) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BatchSet", ctx, intrs)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// BatchSet indicates an expected call of BatchSet.
+func (mr *MockInteractiveCacheMockRecorder) BatchSet(ctx, intrs any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BatchSet", reflect.TypeOf((*MockInteractiveCache)(nil).BatchSet), ctx, intrs)
+}
+
+// CheckVisitors mocks base method.
+func (m *MockInteractiveCache) CheckVisitors(ctx context.Context, bizs []string, bizIds []int64, visitors []string) ([]bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckVisitors", ctx, bizs, bizIds, visitors)
+	ret0, _ := ret[0].([]bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CheckVisitors indicates an expected call of CheckVisitors.
+func (mr *MockInteractiveCacheMockRecorder) CheckVisitors(ctx, bizs, bizIds, visitors any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckVisitors", reflect.TypeOf((*MockInteractiveCache)(nil).CheckVisitors), ctx, bizs, bizIds, visitors)
+}
+
+// DecrLikeCntIfPresent mocks base method.
+func (m *MockInteractiveCache) DecrLikeCntIfPresent(ctx context.Context, biz string, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DecrLikeCntIfPresent", ctx, biz, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DecrLikeCntIfPresent indicates an expected call of DecrLikeCntIfPresent.
+func (mr *MockInteractiveCacheMockRecorder) DecrLikeCntIfPresent(ctx, biz, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DecrLikeCntIfPresent", reflect.TypeOf((*MockInteractiveCache)(nil).DecrLikeCntIfPresent), ctx, biz, id)
+}
+
+// Del mocks base method.
+func (m *MockInteractiveCache) Del(ctx context.Context, biz string, bizId int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Del", ctx, biz, bizId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Del indicates an expected call of Del.
+func (mr *MockInteractiveCacheMockRecorder) Del(ctx, biz, bizId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Del", reflect.TypeOf((*MockInteractiveCache)(nil).Del), ctx, biz, bizId)
+}
+
+// Get mocks base method.
+func (m *MockInteractiveCache) Get(ctx context.Context, biz string, id int64) (domain.Interactive, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", ctx, biz, id)
+	ret0, _ := ret[0].(domain.Interactive)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockInteractiveCacheMockRecorder) Get(ctx, biz, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockInteractiveCache)(nil).Get), ctx, biz, id)
+}
+
+// GetByIds mocks base method.
+func (m *MockInteractiveCache) GetByIds(ctx context.Context, biz string, ids []int64) (map[int64]domain.Interactive, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByIds", ctx, biz, ids)
+	ret0, _ := ret[0].(map[int64]domain.Interactive)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByIds indicates an expected call of GetByIds.
+func (mr *MockInteractiveCacheMockRecorder) GetByIds(ctx, biz, ids any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByIds", reflect.TypeOf((*MockInteractiveCache)(nil).GetByIds), ctx, biz, ids)
+}
+
+// IncrCollectCntIfPresent mocks base method.
+func (m *MockInteractiveCache) IncrCollectCntIfPresent(ctx context.Context, biz string, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IncrCollectCntIfPresent", ctx, biz, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// IncrCollectCntIfPresent indicates an expected call of IncrCollectCntIfPresent.
+func (mr *MockInteractiveCacheMockRecorder) IncrCollectCntIfPresent(ctx, biz, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncrCollectCntIfPresent", reflect.TypeOf((*MockInteractiveCache)(nil).IncrCollectCntIfPresent), ctx, biz, id)
+}
+
+// IncrLikeCntIfPresent mocks base method.
+func (m *MockInteractiveCache) IncrLikeCntIfPresent(ctx context.Context, biz string, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IncrLikeCntIfPresent", ctx, biz, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// IncrLikeCntIfPresent indicates an expected call of IncrLikeCntIfPresent.
+func (mr *MockInteractiveCacheMockRecorder) IncrLikeCntIfPresent(ctx, biz, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncrLikeCntIfPresent", reflect.TypeOf((*MockInteractiveCache)(nil).IncrLikeCntIfPresent), ctx, biz, id)
+}
+
+// IncrReactionCntIfPresent mocks base method.
+func (m *MockInteractiveCache) IncrReactionCntIfPresent(ctx context.Context, biz string, id int64, reaction string, delta int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IncrReactionCntIfPresent", ctx, biz, id, reaction, delta)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// IncrReactionCntIfPresent indicates an expected call of IncrReactionCntIfPresent.
+func (mr *MockInteractiveCacheMockRecorder) IncrReactionCntIfPresent(ctx, biz, id, reaction, delta any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncrReactionCntIfPresent", reflect.TypeOf((*MockInteractiveCache)(nil).IncrReactionCntIfPresent), ctx, biz, id, reaction, delta)
+}
+
+// IncrReadCntIfPresent mocks base method.
+func (m *MockInteractiveCache) IncrReadCntIfPresent(ctx context.Context, biz string, bizId int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IncrReadCntIfPresent", ctx, biz, bizId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// IncrReadCntIfPresent indicates an expected call of IncrReadCntIfPresent.
+func (mr *MockInteractiveCacheMockRecorder) IncrReadCntIfPresent(ctx, biz, bizId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncrReadCntIfPresent", reflect.TypeOf((*MockInteractiveCache)(nil).IncrReadCntIfPresent), ctx, biz, bizId)
+}
+
+// IncrUvCntIfPresent mocks base method.
+func (m *MockInteractiveCache) IncrUvCntIfPresent(ctx context.Context, biz string, bizId int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IncrUvCntIfPresent", ctx, biz, bizId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// IncrUvCntIfPresent indicates an expected call of IncrUvCntIfPresent.
+func (mr *MockInteractiveCacheMockRecorder) IncrUvCntIfPresent(ctx, biz, bizId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncrUvCntIfPresent", reflect.TypeOf((*MockInteractiveCache)(nil).IncrUvCntIfPresent), ctx, biz, bizId)
+}
+
+// Set mocks base method.
+func (m *MockInteractiveCache) Set(ctx context.Context, biz string, bizId int64, res domain.Interactive) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Set", ctx, biz, bizId, res)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Set indicates an expected call of Set.
+func (mr *MockInteractiveCacheMockRecorder) Set(ctx, biz, bizId, res any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Set", reflect.TypeOf((*MockInteractiveCache)(nil).Set), ctx, biz, bizId, res)
+}
@@ -0,0 +1,99 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./like_rank.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./like_rank.go -package=cachemocks -destination=mocks/like_rank.mock.go LikeRankCache
+//
+
+// Package cachemocks is a generated GoMock package.
+package cachemocks
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+	domain "xiaoweishu/webook/interactive/domain"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockLikeRankCache is a mock of LikeRankCache interface.
+type MockLikeRankCache struct {
+	ctrl     *gomock.Controller
+	recorder *MockLikeRankCacheMockRecorder
+}
+
+// MockLikeRankCacheMockRecorder is the mock recorder for MockLikeRankCache.
+type MockLikeRankCacheMockRecorder struct {
+	mock *MockLikeRankCache
+}
+
+// NewMockLikeRankCache creates a new mock instance.
+func NewMockLikeRankCache(ctrl *gomock.Controller) *MockLikeRankCache {
+	mock := &MockLikeRankCache{ctrl: ctrl}
+	mock.recorder = &MockLikeRankCacheMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLikeRankCache) EXPECT() *MockLikeRankCacheMockRecorder {
+	return m.recorder
+}
+
+// BatchIncr mocks base method.
+func (m *MockLikeRankCache) BatchIncr(ctx context.Context, deltas []domain.LikeRankDelta) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BatchIncr", ctx, deltas)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// BatchIncr indicates an expected call of BatchIncr.
+func (mr *MockLikeRankCacheMockRecorder) BatchIncr(ctx, deltas any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BatchIncr", reflect.TypeOf((*MockLikeRankCache)(nil).BatchIncr), ctx, deltas)
+}
+
+// BeginReplace mocks base method.
+func (m *MockLikeRankCache) BeginReplace(ctx context.Context, biz string, w domain.LikeRankWindow, t time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BeginReplace", ctx, biz, w, t)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// BeginReplace indicates an expected call of BeginReplace.
+func (mr *MockLikeRankCacheMockRecorder) BeginReplace(ctx, biz, w, t any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BeginReplace", reflect.TypeOf((*MockLikeRankCache)(nil).BeginReplace), ctx, biz, w, t)
+}
+
+// Replace mocks base method.
+func (m *MockLikeRankCache) Replace(ctx context.Context, biz string, w domain.LikeRankWindow, t time.Time, items []domain.LikeRankItem, since time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Replace", ctx, biz, w, t, items, since)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Replace indicates an expected call of Replace.
+func (mr *MockLikeRankCacheMockRecorder) Replace(ctx, biz, w, t, items, since any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Replace", reflect.TypeOf((*MockLikeRankCache)(nil).Replace), ctx, biz, w, t, items, since)
+}
+
+// Top mocks base method.
+func (m *MockLikeRankCache) Top(ctx context.Context, biz string, w domain.LikeRankWindow, t time.Time, offset, limit int) ([]domain.LikeRankItem, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Top", ctx, biz, w, t, offset, limit)
+	ret0, _ := ret[0].([]domain.LikeRankItem)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Top indicates an expected call of Top.
+func (mr *MockLikeRankCacheMockRecorder) Top(ctx, biz, w, t, offset, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Top", reflect.TypeOf((*MockLikeRankCache)(nil).Top), ctx, biz, w, t, offset, limit)
+}
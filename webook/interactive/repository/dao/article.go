@@ -0,0 +1,42 @@
+package dao
+
+import (
+	"context"
+	"gorm.io/gorm"
+)
+
+// articleStatusPublished 和文章服务里面的已发表状态保持一致
+const articleStatusPublished = 2
+
+// ArticleDAO 交互服务只需要知道文章是不是还在，文章的数据还是归文章服务管
+type ArticleDAO interface {
+	// Exists 文章是否已经发表，并且没有撤回或者删除
+	Exists(ctx context.Context, id int64) (bool, error)
+}
+
+type GORMArticleDAO struct {
+	db *gorm.DB
+}
+
+// NewGORMArticleDAO db 要用线上库所在的 webook 库，交互服务自己的库里面没有文章
+func NewGORMArticleDAO(db *gorm.DB) ArticleDAO {
+	return &GORMArticleDAO{db: db}
+}
+
+func (g *GORMArticleDAO) Exists(ctx context.Context, id int64) (bool, error) {
+	var cnt int64
+	err := g.db.WithContext(ctx).Model(&PublishedArticle{}).
+		Where("id = ? AND status = ?", id, articleStatusPublished).
+		Count(&cnt).Error
+	return cnt > 0, err
+}
+
+// PublishedArticle 线上库的文章，只读，只用到这两个字段
+type PublishedArticle struct {
+	Id     int64
+	Status uint8
+}
+
+func (PublishedArticle) TableName() string {
+	return "published_articles"
+}
@@ -0,0 +1,140 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+//go:generate mockgen -source=./history.go -package=daomocks -destination=mocks/history.mock.go ReadHistoryDAO
+type ReadHistoryDAO interface {
+	// BatchUpsert 同一个用户重复阅读同一篇内容只会刷新 rtime，以此做到去重
+	BatchUpsert(ctx context.Context, hs []UserReadHistory) error
+	// Trim 只保留用户最近的 keep 条阅读记录
+	Trim(ctx context.Context, uid int64, keep int) error
+	List(ctx context.Context, uid int64, offset int, limit int) ([]UserReadHistory, error)
+	Delete(ctx context.Context, uid int64, biz string, bizId int64) error
+	Clear(ctx context.Context, uid int64) error
+	SetPaused(ctx context.Context, uid int64, paused bool) error
+	GetSetting(ctx context.Context, uid int64) (UserReadHistorySetting, error)
+	// FindPausedUids 从 uids 中找出暂停了阅读历史的用户
+	FindPausedUids(ctx context.Context, uids []int64) ([]int64, error)
+}
+
+type GORMReadHistoryDAO struct {
+	db *gorm.DB
+}
+
+func NewGORMReadHistoryDAO(db *gorm.DB) ReadHistoryDAO {
+	return &GORMReadHistoryDAO{
+		db: db,
+	}
+}
+
+func (dao *GORMReadHistoryDAO) BatchUpsert(ctx context.Context, hs []UserReadHistory) error {
+	if len(hs) == 0 {
+		return nil
+	}
+	now := time.Now().UnixMilli()
+	for i := range hs {
+		hs[i].Ctime = now
+		hs[i].Utime = now
+	}
+	//uid+biz+biz_id 上有唯一索引，冲突的时候只需要更新阅读时间
+	return dao.db.WithContext(ctx).Clauses(clause.OnConflict{
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"rtime": gorm.Expr("GREATEST(`rtime`, VALUES(`rtime`))"),
+			"utime": now,
+		}),
+	}).Create(&hs).Error
+}
+
+func (dao *GORMReadHistoryDAO) Trim(ctx context.Context, uid int64, keep int) error {
+	db := dao.db.WithContext(ctx)
+	//先找到第 keep 条记录，排在它后面的都删掉。
+	//阅读时间一样的按照 id 排，不然阅读时间相同的记录会多留下来
+	var boundary UserReadHistory
+	err := db.Where("uid = ?", uid).
+		Order("rtime DESC, id DESC").Offset(keep - 1).Limit(1).
+		First(&boundary).Error
+	if errors.Is(err, ErrRecordNotFound) {
+		//还没有超过上限
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return db.Where("uid = ? AND (rtime < ? OR (rtime = ? AND id < ?))",
+		uid, boundary.Rtime, boundary.Rtime, boundary.Id).
+		Delete(&UserReadHistory{}).Error
+}
+
+func (dao *GORMReadHistoryDAO) List(ctx context.Context, uid int64, offset int, limit int) ([]UserReadHistory, error) {
+	var res []UserReadHistory
+	err := dao.db.WithContext(ctx).Where("uid = ?", uid).
+		Order("rtime DESC, id DESC").Offset(offset).Limit(limit).
+		Find(&res).Error
+	return res, err
+}
+
+func (dao *GORMReadHistoryDAO) Delete(ctx context.Context, uid int64, biz string, bizId int64) error {
+	return dao.db.WithContext(ctx).
+		Where("uid = ? AND biz = ? AND biz_id = ?", uid, biz, bizId).
+		Delete(&UserReadHistory{}).Error
+}
+
+func (dao *GORMReadHistoryDAO) Clear(ctx context.Context, uid int64) error {
+	return dao.db.WithContext(ctx).Where("uid = ?", uid).
+		Delete(&UserReadHistory{}).Error
+}
+
+func (dao *GORMReadHistoryDAO) SetPaused(ctx context.Context, uid int64, paused bool) error {
+	now := time.Now().UnixMilli()
+	return dao.db.WithContext(ctx).Clauses(clause.OnConflict{
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"paused": paused,
+			"utime":  now,
+		}),
+	}).Create(&UserReadHistorySetting{
+		Uid:    uid,
+		Paused: paused,
+		Ctime:  now,
+		Utime:  now,
+	}).Error
+}
+
+func (dao *GORMReadHistoryDAO) GetSetting(ctx context.Context, uid int64) (UserReadHistorySetting, error) {
+	var res UserReadHistorySetting
+	err := dao.db.WithContext(ctx).Where("uid = ?", uid).First(&res).Error
+	return res, err
+}
+
+func (dao *GORMReadHistoryDAO) FindPausedUids(ctx context.Context, uids []int64) ([]int64, error) {
+	var res []int64
+	err := dao.db.WithContext(ctx).Model(&UserReadHistorySetting{}).
+		Where("uid IN ? AND paused = ?", uids, true).
+		Pluck("uid", &res).Error
+	return res, err
+}
+
+type UserReadHistory struct {
+	Id    int64  `gorm:"primaryKey,autoIncrement"`
+	Uid   int64  `gorm:"uniqueIndex:uid_biz_type_id;index:uid_rtime"`
+	BizId int64  `gorm:"uniqueIndex:uid_biz_type_id"`
+	Biz   string `gorm:"type:varchar(128);uniqueIndex:uid_biz_type_id"`
+	// 最近一次阅读的时间，列表按照它倒序
+	Rtime int64 `gorm:"index:uid_rtime"`
+	Utime int64
+	Ctime int64
+}
+
+// UserReadHistorySetting 用户的阅读历史设置，目前只有是否暂停记录
+type UserReadHistorySetting struct {
+	Id     int64 `gorm:"primaryKey,autoIncrement"`
+	Uid    int64 `gorm:"unique"`
+	Paused bool
+	Utime  int64
+	Ctime  int64
+}
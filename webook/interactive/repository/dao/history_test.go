@@ -0,0 +1,62 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"testing"
+)
+
+func TestGORMReadHistoryDAO_Trim(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(t *testing.T) *sql.DB
+
+		wantErr error
+	}{
+		{
+			name: "阅读时间一样的按照 id 删",
+			mock: func(t *testing.T) *sql.DB {
+				db, mock, err := sqlmock.New()
+				require.NoError(t, err)
+				mock.ExpectQuery("SELECT \\* FROM `user_read_histories` WHERE uid = \\? "+
+					"ORDER BY rtime DESC, id DESC,`user_read_histories`.`id` LIMIT \\? OFFSET \\?").
+					WithArgs(int64(1), 1, 2).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "uid", "rtime"}).AddRow(5, 1, 100))
+				mock.ExpectExec("DELETE FROM `user_read_histories` "+
+					"WHERE uid = \\? AND \\(rtime < \\? OR \\(rtime = \\? AND id < \\?\\)\\)").
+					WithArgs(int64(1), int64(100), int64(100), int64(5)).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				return db
+			},
+		},
+		{
+			name: "没有超过上限",
+			mock: func(t *testing.T) *sql.DB {
+				db, mock, err := sqlmock.New()
+				require.NoError(t, err)
+				mock.ExpectQuery("SELECT \\* FROM `user_read_histories`.*").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "uid", "rtime"}))
+				return db
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, err := gorm.Open(mysql.New(mysql.Config{
+				Conn:                      tc.mock(t),
+				SkipInitializeWithVersion: true,
+			}), &gorm.Config{
+				SkipDefaultTransaction: true,
+			})
+			require.NoError(t, err)
+			dao := NewGORMReadHistoryDAO(db)
+			err = dao.Trim(context.Background(), 1, 3)
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
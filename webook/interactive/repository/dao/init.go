@@ -8,5 +8,10 @@ func InitTables(db *gorm.DB) error {
 		&Interactive{},
 		&UserLikeBiz{},
 		&UserCollectionBiz{},
+		&UserReactionBiz{},
+		&ReactionCnt{},
+		&InteractiveStat{},
+		&UserReadHistory{},
+		&UserReadHistorySetting{},
 	)
 }
@@ -2,12 +2,18 @@ package dao
 
 import (
 	"context"
+	"errors"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
+	"sort"
 	"time"
 	"xiaoweishu/webook/pkg/migrator"
 )
 
+// upsertBatchSize 一条多行 upsert 最多带多少行，太长的 SQL 容易超过 max_allowed_packet
+const upsertBatchSize = 500
+
+//go:generate mockgen -source=./interactive.go -package=daomocks -destination=mocks/interactive.mock.go InteractiveDAO
 type InteractiveDAO interface {
 	IncrReadCnt(ctx context.Context, biz string, bizId int64) error
 	InsertLikeInfo(ctx context.Context, biz string, id int64, uid int64) error
@@ -17,7 +23,28 @@ type InteractiveDAO interface {
 	GetCollectInfo(ctx context.Context, biz string, id int64, uid int64) (UserCollectionBiz, error)
 	Get(ctx context.Context, biz string, id int64) (Interactive, error)
 	BatchIncrReadCnt(ctx context.Context, bizs []string, ids []int64) error
+	BatchIncrUvCnt(ctx context.Context, bizs []string, ids []int64) error
 	GetByIds(ctx context.Context, biz string, ids []int64) ([]Interactive, error)
+
+	// LikeBiz 和 UnlikeBiz 只修改用户的点赞记录，不动 interactives，给写回模式用
+	// 返回值表示点赞状态是否真的发生了变化
+	LikeBiz(ctx context.Context, biz string, id int64, uid int64) (bool, error)
+	// UnlikeBiz 返回被取消的那次点赞的时间，没有点过赞就是 0
+	UnlikeBiz(ctx context.Context, biz string, id int64, uid int64) (int64, error)
+	// BatchAddCnt 把攒起来的增量一次性写进去
+	BatchAddCnt(ctx context.Context, deltas []CntDelta) error
+	// FindLikedBizIds 找出 since 之后点赞状态有变化的资源
+	FindLikedBizIds(ctx context.Context, biz string, since int64, offset int, limit int) ([]int64, error)
+	// ReconcileLikeCnt 用 user_like_bizs 里面的记录重新计算点赞数。
+	// before 之后还有点赞变化的不修改，返回 false，因为这些变化的增量可能还在 kafka 里面没有写回
+	ReconcileLikeCnt(ctx context.Context, biz string, id int64, before int64) (bool, error)
+
+	// SetReaction 设置或者切换用户的表态，返回之前的表态
+	SetReaction(ctx context.Context, biz string, id int64, uid int64, reaction string) (UserReaction, error)
+	// RemoveReaction 撤销表态，返回被撤销的表态
+	RemoveReaction(ctx context.Context, biz string, id int64, uid int64, reaction string) (UserReaction, error)
+	GetReaction(ctx context.Context, biz string, id int64, uid int64) (string, error)
+	GetReactionCnts(ctx context.Context, biz string, ids []int64) ([]ReactionCnt, error)
 }
 type GORMInteractiveDAO struct {
 	db *gorm.DB
@@ -32,25 +59,39 @@ func (DAO GORMInteractiveDAO) GetByIds(ctx context.Context, biz string, ids []in
 
 //  单个增加阅读数的代码跟批量增加阅读数的代码大部分一样，可以做一个复用版本
 
+// BatchIncrReadCnt 一条多行的 INSERT ... ON DUPLICATE KEY UPDATE 写进去，
+// 不做聚合，同一个资源出现多次的话 MySQL 会在同一条语句里面累加。要聚合的话调用方先聚合好再用 BatchAddCnt
 func (DAO GORMInteractiveDAO) BatchIncrReadCnt(ctx context.Context, bizs []string, ids []int64) error {
+	deltas := make([]CntDelta, 0, len(bizs))
 	for i := 0; i < len(bizs); i++ {
-		err := DAO.db.WithContext(ctx).Clauses(clause.OnConflict{
-			DoUpdates: clause.Assignments(map[string]interface{}{
-				"read_cnt": gorm.Expr("`read_cnt` +1"), //由gorm来为我们做自增1
-				"utime":    time.Now().UnixMilli(),
-			}),
-		}).Create(&Interactive{
-			Biz:     bizs[i],
-			BizId:   ids[i],
-			Ctime:   time.Now().UnixMilli(),
-			Utime:   time.Now().UnixMilli(),
-			ReadCnt: 1,
-		}).Error
-		if err != nil {
-			return err
-		}
+		deltas = append(deltas, CntDelta{Biz: bizs[i], BizId: ids[i], ReadCnt: 1})
 	}
-	return nil
+	return DAO.BatchAddCnt(ctx, deltas)
+}
+
+// BatchIncrUvCnt 上层已经去过重了，这里只管加一
+func (DAO GORMInteractiveDAO) BatchIncrUvCnt(ctx context.Context, bizs []string, ids []int64) error {
+	return DAO.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now().UnixMilli()
+		for i := 0; i < len(bizs); i++ {
+			err := tx.Clauses(clause.OnConflict{
+				DoUpdates: clause.Assignments(map[string]interface{}{
+					"uv_cnt": gorm.Expr("`uv_cnt` +1"),
+					"utime":  now,
+				}),
+			}).Create(&Interactive{
+				Biz:   bizs[i],
+				BizId: ids[i],
+				Ctime: now,
+				Utime: now,
+				UvCnt: 1,
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
 
 // 复用单个增加阅读数的代码
@@ -199,6 +240,144 @@ func (DAO GORMInteractiveDAO) Get(ctx context.Context, biz string, id int64) (In
 
 }
 
+func (DAO GORMInteractiveDAO) LikeBiz(ctx context.Context, biz string, id int64, uid int64) (bool, error) {
+	now := time.Now().UnixMilli()
+	db := DAO.db.WithContext(ctx)
+	//之前取消过点赞的，直接把状态改回来
+	res := db.Model(&UserLikeBiz{}).
+		Where("uid=? AND biz=? AND biz_id=? AND status=?", uid, biz, id, 0).
+		Updates(map[string]interface{}{
+			"utime":  now,
+			"status": 1,
+		})
+	if res.Error != nil {
+		return false, res.Error
+	}
+	if res.RowsAffected > 0 {
+		return true, nil
+	}
+	//冲突说明已经点过赞了，什么都不用做
+	res = db.Clauses(clause.OnConflict{DoNothing: true}).Create(&UserLikeBiz{
+		Uid:    uid,
+		Biz:    biz,
+		BizId:  id,
+		Status: 1,
+		Utime:  now,
+		Ctime:  now,
+	})
+	return res.RowsAffected > 0, res.Error
+}
+
+func (DAO GORMInteractiveDAO) UnlikeBiz(ctx context.Context, biz string, id int64, uid int64) (int64, error) {
+	var likeTime int64
+	err := DAO.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		//取消之后 utime 就被覆盖了，先把点赞的时间拿出来
+		var like UserLikeBiz
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("uid=? AND biz=? AND biz_id=? AND status=?", uid, biz, id, 1).
+			First(&like).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		err = tx.Model(&UserLikeBiz{}).
+			Where("id=?", like.Id).
+			Updates(map[string]interface{}{
+				"utime":  time.Now().UnixMilli(),
+				"status": 0,
+			}).Error
+		if err != nil {
+			return err
+		}
+		likeTime = like.Utime
+		return nil
+	})
+	return likeTime, err
+}
+
+func (DAO GORMInteractiveDAO) BatchAddCnt(ctx context.Context, deltas []CntDelta) error {
+	if len(deltas) == 0 {
+		return nil
+	}
+	now := time.Now().UnixMilli()
+	rows := make([]Interactive, 0, len(deltas))
+	for _, d := range deltas {
+		rows = append(rows, Interactive{
+			Biz:     d.Biz,
+			BizId:   d.BizId,
+			ReadCnt: d.ReadCnt,
+			LikeCnt: d.LikeCnt,
+			Ctime:   now,
+			Utime:   now,
+		})
+	}
+	//按照唯一索引排序，多个消费者同时写的时候加锁顺序一致，不会互相死锁
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Biz != rows[j].Biz {
+			return rows[i].Biz < rows[j].Biz
+		}
+		return rows[i].BizId < rows[j].BizId
+	})
+	return DAO.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		//VALUES(col) 取的是 INSERT 这一行里面的值，也就是增量
+		return tx.Clauses(clause.OnConflict{
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"read_cnt": gorm.Expr("`read_cnt` + VALUES(`read_cnt`)"),
+				"like_cnt": gorm.Expr("`like_cnt` + VALUES(`like_cnt`)"),
+				"utime":    now,
+			}),
+		}).CreateInBatches(&rows, upsertBatchSize).Error
+	})
+}
+
+func (DAO GORMInteractiveDAO) FindLikedBizIds(ctx context.Context, biz string, since int64, offset int, limit int) ([]int64, error) {
+	var res []int64
+	err := DAO.db.WithContext(ctx).Model(&UserLikeBiz{}).
+		Distinct("biz_id").
+		Where("biz = ? AND utime >= ?", biz, since).
+		Order("biz_id").Offset(offset).Limit(limit).
+		Pluck("biz_id", &res).Error
+	return res, err
+}
+
+func (DAO GORMInteractiveDAO) ReconcileLikeCnt(ctx context.Context, biz string, id int64, before int64) (bool, error) {
+	now := time.Now().UnixMilli()
+	reconciled := false
+	err := DAO.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		//同一个事务里面的两次查询看到的是同一个快照
+		var recent int64
+		err := tx.Model(&UserLikeBiz{}).
+			Where("biz = ? AND biz_id = ? AND utime >= ?", biz, id, before).
+			Count(&recent).Error
+		if err != nil || recent > 0 {
+			return err
+		}
+		var cnt int64
+		err = tx.Model(&UserLikeBiz{}).
+			Where("biz = ? AND biz_id = ? AND status = ?", biz, id, 1).
+			Count(&cnt).Error
+		if err != nil {
+			return err
+		}
+		reconciled = true
+		return tx.Clauses(clause.OnConflict{
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"like_cnt": cnt,
+				"utime":    now,
+			}),
+		}).Create(&Interactive{
+			Biz:     biz,
+			BizId:   id,
+			LikeCnt: cnt,
+			Ctime:   now,
+			Utime:   now,
+		}).Error
+	})
+	return reconciled && err == nil, err
+}
+
 func NewGORMInteractiveDAO(db *gorm.DB) InteractiveDAO {
 	return &GORMInteractiveDAO{
 		db: db,
@@ -228,6 +407,14 @@ type UserCollectionBiz struct {
 	Ctime int64
 }
 
+// CntDelta 计数的增量，不是表
+type CntDelta struct {
+	Biz     string
+	BizId   int64
+	ReadCnt int64
+	LikeCnt int64
+}
+
 type Interactive struct {
 	Id int64 `gorm:"primaryKey,autoIncrement"`
 	// <bizid, biz>
@@ -235,7 +422,9 @@ type Interactive struct {
 	// WHERE biz = ?
 	Biz string `gorm:"type:varchar(128);uniqueIndex:biz_type_id"`
 
-	ReadCnt    int64
+	ReadCnt int64
+	// UvCnt 同一个访客在一个时间窗口内只算一次的阅读数
+	UvCnt      int64
 	LikeCnt    int64
 	CollectCnt int64
 	Utime      int64
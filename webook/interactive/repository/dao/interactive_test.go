@@ -0,0 +1,146 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"testing"
+)
+
+func TestGORMInteractiveDAO_ReconcileLikeCnt(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(t *testing.T) *sql.DB
+
+		wantOk bool
+	}{
+		{
+			name: "最近没有点赞变化，修正点赞数",
+			mock: func(t *testing.T) *sql.DB {
+				db, mock, err := sqlmock.New()
+				require.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT count.*utime >= .*").
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+				mock.ExpectQuery("SELECT count.*status = .*").
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+				mock.ExpectExec("INSERT INTO `interactives`.*ON DUPLICATE KEY UPDATE.*").
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectCommit()
+				return db
+			},
+			wantOk: true,
+		},
+		{
+			name: "最近有点赞变化，跳过",
+			mock: func(t *testing.T) *sql.DB {
+				db, mock, err := sqlmock.New()
+				require.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT count.*utime >= .*").
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+				mock.ExpectCommit()
+				return db
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, err := gorm.Open(mysql.New(mysql.Config{
+				Conn:                      tc.mock(t),
+				SkipInitializeWithVersion: true,
+			}), &gorm.Config{
+				SkipDefaultTransaction: true,
+			})
+			require.NoError(t, err)
+			dao := NewGORMInteractiveDAO(db)
+			ok, err := dao.ReconcileLikeCnt(context.Background(), "article", 1, 100)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantOk, ok)
+		})
+	}
+}
+
+func TestGORMInteractiveDAO_UnlikeBiz(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(t *testing.T) *sql.DB
+
+		wantLikeTime int64
+	}{
+		{
+			name: "取消点赞，返回原来点赞的时间",
+			mock: func(t *testing.T) *sql.DB {
+				db, mock, err := sqlmock.New()
+				require.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT .* FROM `user_like_bizs` .* FOR UPDATE").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "uid", "biz", "biz_id", "status", "utime", "ctime"}).
+						AddRow(1, 123, "article", 1, 1, 100, 50))
+				mock.ExpectExec("UPDATE `user_like_bizs` SET .*").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+				return db
+			},
+			wantLikeTime: 100,
+		},
+		{
+			name: "没有点过赞",
+			mock: func(t *testing.T) *sql.DB {
+				db, mock, err := sqlmock.New()
+				require.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT .* FROM `user_like_bizs` .* FOR UPDATE").
+					WillReturnRows(sqlmock.NewRows([]string{"id"}))
+				mock.ExpectCommit()
+				return db
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, err := gorm.Open(mysql.New(mysql.Config{
+				Conn:                      tc.mock(t),
+				SkipInitializeWithVersion: true,
+			}), &gorm.Config{
+				SkipDefaultTransaction: true,
+			})
+			require.NoError(t, err)
+			dao := NewGORMInteractiveDAO(db)
+			likeTime, err := dao.UnlikeBiz(context.Background(), "article", 1, 123)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantLikeTime, likeTime)
+		})
+	}
+}
+
+func TestGORMInteractiveDAO_RemoveReaction(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT .* FROM `user_like_bizs` .* FOR UPDATE").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "uid", "biz", "biz_id", "status", "utime", "ctime"}).
+			AddRow(1, 123, "article", 1, 1, 100, 50))
+	mock.ExpectExec("UPDATE `user_like_bizs` SET .*").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE `interactives` SET .*").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	db, err := gorm.Open(mysql.New(mysql.Config{
+		Conn:                      sqlDB,
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{
+		SkipDefaultTransaction: true,
+	})
+	require.NoError(t, err)
+	dao := NewGORMInteractiveDAO(db)
+	old, err := dao.RemoveReaction(context.Background(), "article", 1, 123, "like")
+	require.NoError(t, err)
+	//取消 👍 要带上点赞的时间，点赞榜靠它找到对应的窗口
+	assert.Equal(t, UserReaction{Reaction: "like", Utime: 100}, old)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
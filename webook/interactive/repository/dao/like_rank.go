@@ -0,0 +1,51 @@
+package dao
+
+import (
+	"context"
+	"gorm.io/gorm"
+)
+
+//go:generate mockgen -source=./like_rank.go -package=daomocks -destination=mocks/like_rank.mock.go LikeRankDAO
+
+// LikeRankDAO 重建点赞榜的时候从数据库里面捞数据
+type LikeRankDAO interface {
+	// ListLikeCnt 按照 id 翻页，返回 id 大于 minId 的、有点赞的资源
+	ListLikeCnt(ctx context.Context, biz string, minId int64, limit int) ([]Interactive, error)
+	// CountLikesBetween 按照 biz_id 翻页，统计 [start, end) 之间点赞并且现在还是点赞状态的人数
+	CountLikesBetween(ctx context.Context, biz string, start, end int64, minBizId int64, limit int) ([]LikeCnt, error)
+}
+
+type GORMLikeRankDAO struct {
+	db *gorm.DB
+}
+
+func NewGORMLikeRankDAO(db *gorm.DB) LikeRankDAO {
+	return &GORMLikeRankDAO{db: db}
+}
+
+func (g *GORMLikeRankDAO) ListLikeCnt(ctx context.Context, biz string, minId int64, limit int) ([]Interactive, error) {
+	var res []Interactive
+	err := g.db.WithContext(ctx).
+		Select("id", "biz", "biz_id", "like_cnt").
+		Where("biz = ? AND id > ? AND like_cnt > 0", biz, minId).
+		Order("id").Limit(limit).
+		Find(&res).Error
+	return res, err
+}
+
+func (g *GORMLikeRankDAO) CountLikesBetween(ctx context.Context, biz string, start, end int64, minBizId int64, limit int) ([]LikeCnt, error) {
+	var res []LikeCnt
+	//重复点赞会更新 utime，所以这里算的是窗口内最后一次点赞
+	err := g.db.WithContext(ctx).Model(&UserLikeBiz{}).
+		Select("biz_id", "COUNT(*) AS cnt").
+		Where("biz = ? AND status = 1 AND utime >= ? AND utime < ? AND biz_id > ?",
+			biz, start, end, minBizId).
+		Group("biz_id").Order("biz_id").Limit(limit).
+		Scan(&res).Error
+	return res, err
+}
+
+type LikeCnt struct {
+	BizId int64
+	Cnt   int64
+}
@@ -0,0 +1,156 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./history.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./history.go -package=daomocks -destination=mocks/history.mock.go ReadHistoryDAO
+//
+
+// Package daomocks is a generated GoMock package.
+package daomocks
+
+import (
+	context "context"
+	reflect "reflect"
+	dao "xiaoweishu/webook/interactive/repository/dao"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockReadHistoryDAO is a mock of ReadHistoryDAO interface.
+type MockReadHistoryDAO struct {
+	ctrl     *gomock.Controller
+	recorder *MockReadHistoryDAOMockRecorder
+}
+
+// MockReadHistoryDAOMockRecorder is the mock recorder for MockReadHistoryDAO.
+type MockReadHistoryDAOMockRecorder struct {
+	mock *MockReadHistoryDAO
+}
+
+// NewMockReadHistoryDAO creates a new mock instance.
+func NewMockReadHistoryDAO(ctrl *gomock.Controller) *MockReadHistoryDAO {
+	mock := &MockReadHistoryDAO{ctrl: ctrl}
+	mock.recorder = &MockReadHistoryDAOMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockReadHistoryDAO) EXPECT() *MockReadHistoryDAOMockRecorder {
+	return m.recorder
+}
+
+// BatchUpsert mocks base method.
+func (m *MockReadHistoryDAO) BatchUpsert(ctx context.Context, hs []dao.UserReadHistory) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BatchUpsert", ctx, hs)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// BatchUpsert indicates an expected call of BatchUpsert.
+func (mr *MockReadHistoryDAOMockRecorder) BatchUpsert(ctx, hs any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BatchUpsert", reflect.TypeOf((*MockReadHistoryDAO)(nil).BatchUpsert), ctx, hs)
+}
+
+// Clear mocks base method.
+func (m *MockReadHistoryDAO) Clear(ctx context.Context, uid int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Clear", ctx, uid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Clear indicates an expected call of Clear.
+func (mr *MockReadHistoryDAOMockRecorder) Clear(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Clear", reflect.TypeOf((*MockReadHistoryDAO)(nil).Clear), ctx, uid)
+}
+
+// Delete mocks base method.
+func (m *MockReadHistoryDAO) Delete(ctx context.Context, uid int64, biz string, bizId int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, uid, biz, bizId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockReadHistoryDAOMockRecorder) Delete(ctx, uid, biz, bizId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockReadHistoryDAO)(nil).Delete), ctx, uid, biz, bizId)
+}
+
+// FindPausedUids mocks base method.
+func (m *MockReadHistoryDAO) FindPausedUids(ctx context.Context, uids []int64) ([]int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindPausedUids", ctx, uids)
+	ret0, _ := ret[0].([]int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindPausedUids indicates an expected call of FindPausedUids.
+func (mr *MockReadHistoryDAOMockRecorder) FindPausedUids(ctx, uids any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindPausedUids", reflect.TypeOf((*MockReadHistoryDAO)(nil).FindPausedUids), ctx, uids)
+}
+
+// GetSetting mocks base method.
+func (m *MockReadHistoryDAO) GetSetting(ctx context.Context, uid int64) (dao.UserReadHistorySetting, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSetting", ctx, uid)
+	ret0, _ := ret[0].(dao.UserReadHistorySetting)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSetting indicates an expected call of GetSetting.
+func (mr *MockReadHistoryDAOMockRecorder) GetSetting(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSetting", reflect.TypeOf((*MockReadHistoryDAO)(nil).GetSetting), ctx, uid)
+}
+
+// List mocks base method.
+func (m *MockReadHistoryDAO) List(ctx context.Context, uid int64, offset, limit int) ([]dao.UserReadHistory, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", ctx, uid, offset, limit)
+	ret0, _ := ret[0].([]dao.UserReadHistory)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockReadHistoryDAOMockRecorder) List(ctx, uid, offset, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockReadHistoryDAO)(nil).List), ctx, uid, offset, limit)
+}
+
+// SetPaused mocks base method.
+func (m *MockReadHistoryDAO) SetPaused(ctx context.Context, uid int64, paused bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetPaused", ctx, uid, paused)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetPaused indicates an expected call of SetPaused.
+func (mr *MockReadHistoryDAOMockRecorder) SetPaused(ctx, uid, paused any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPaused", reflect.TypeOf((*MockReadHistoryDAO)(nil).SetPaused), ctx, uid, paused)
+}
+
+// Trim mocks base method.
+func (m *MockReadHistoryDAO) Trim(ctx context.Context, uid int64, keep int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Trim", ctx, uid, keep)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Trim indicates an expected call of Trim.
+func (mr *MockReadHistoryDAOMockRecorder) Trim(ctx, uid, keep any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Trim", reflect.TypeOf((*MockReadHistoryDAO)(nil).Trim), ctx, uid, keep)
+}
@@ -0,0 +1,319 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./interactive.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./interactive.go -package=daomocks -destination=mocks/interactive.mock.go InteractiveDAO
+//
+
+// Package daomocks is a generated GoMock package.
+package daomocks
+
+import (
+	context "context"
+	reflect "reflect"
+	dao "xiaoweishu/webook/interactive/repository/dao"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockInteractiveDAO is a mock of InteractiveDAO interface.
+type MockInteractiveDAO struct {
+	ctrl     *gomock.Controller
+	recorder *MockInteractiveDAOMockRecorder
+}
+
+// MockInteractiveDAOMockRecorder is the mock recorder for MockInteractiveDAO.
+type MockInteractiveDAOMockRecorder struct {
+	mock *MockInteractiveDAO
+}
+
+// NewMockInteractiveDAO creates a new mock instance.
+func NewMockInteractiveDAO(ctrl *gomock.Controller) *MockInteractiveDAO {
+	mock := &MockInteractiveDAO{ctrl: ctrl}
+	mock.recorder = &MockInteractiveDAOMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockInteractiveDAO) EXPECT() *MockInteractiveDAOMockRecorder {
+	return m.recorder
+}
+
+// BatchAddCnt mocks base method.
+func (m *MockInteractiveDAO) BatchAddCnt(ctx context.Context, deltas []dao.CntDelta) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BatchAddCnt", ctx, deltas)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// BatchAddCnt indicates an expected call of BatchAddCnt.
+func (mr *MockInteractiveDAOMockRecorder) BatchAddCnt(ctx, deltas any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BatchAddCnt", reflect.TypeOf((*MockInteractiveDAO)(nil).BatchAddCnt), ctx, deltas)
+}
+
+// BatchIncrReadCnt mocks base method.
+func (m *MockInteractiveDAO) BatchIncrReadCnt(ctx context.Context, bizs []string, ids []int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BatchIncrReadCnt", ctx, bizs, ids)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// BatchIncrReadCnt indicates an expected call of BatchIncrReadCnt.
+func (mr *MockInteractiveDAOMockRecorder) BatchIncrReadCnt(ctx, bizs, ids any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BatchIncrReadCnt", reflect.TypeOf((*MockInteractiveDAO)(nil).BatchIncrReadCnt), ctx, bizs, ids)
+}
+
+// BatchIncrUvCnt mocks base method.
+func (m *MockInteractiveDAO) BatchIncrUvCnt(ctx context.Context, bizs []string, ids []int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BatchIncrUvCnt", ctx, bizs, ids)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// BatchIncrUvCnt indicates an expected call of BatchIncrUvCnt.
+func (mr *MockInteractiveDAOMockRecorder) BatchIncrUvCnt(ctx, bizs, ids any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BatchIncrUvCnt", reflect.TypeOf((*MockInteractiveDAO)(nil).BatchIncrUvCnt), ctx, bizs, ids)
+}
+
+// DeleteLikeInfo mocks base method.
+func (m *MockInteractiveDAO) DeleteLikeInfo(ctx context.Context, biz string, id, uid int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteLikeInfo", ctx, biz, id, uid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteLikeInfo indicates an expected call of DeleteLikeInfo.
+func (mr *MockInteractiveDAOMockRecorder) DeleteLikeInfo(ctx, biz, id, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteLikeInfo", reflect.TypeOf((*MockInteractiveDAO)(nil).DeleteLikeInfo), ctx, biz, id, uid)
+}
+
+// FindLikedBizIds mocks base method.
+func (m *MockInteractiveDAO) FindLikedBizIds(ctx context.Context, biz string, since int64, offset, limit int) ([]int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindLikedBizIds", ctx, biz, since, offset, limit)
+	ret0, _ := ret[0].([]int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindLikedBizIds indicates an expected call of FindLikedBizIds.
+func (mr *MockInteractiveDAOMockRecorder) FindLikedBizIds(ctx, biz, since, offset, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindLikedBizIds", reflect.TypeOf((*MockInteractiveDAO)(nil).FindLikedBizIds), ctx, biz, since, offset, limit)
+}
+
+// Get mocks base method.
+func (m *MockInteractiveDAO) Get(ctx context.Context, biz string, id int64) (dao.Interactive, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", ctx, biz, id)
+	ret0, _ := ret[0].(dao.Interactive)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockInteractiveDAOMockRecorder) Get(ctx, biz, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockInteractiveDAO)(nil).Get), ctx, biz, id)
+}
+
+// GetByIds mocks base method.
+func (m *MockInteractiveDAO) GetByIds(ctx context.Context, biz string, ids []int64) ([]dao.Interactive, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByIds", ctx, biz, ids)
+	ret0, _ := ret[0].([]dao.Interactive)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByIds indicates an expected call of GetByIds.
+func (mr *MockInteractiveDAOMockRecorder) GetByIds(ctx, biz, ids any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByIds", reflect.TypeOf((*MockInteractiveDAO)(nil).GetByIds), ctx, biz, ids)
+}
+
+// GetCollectInfo mocks base method.
+func (m *MockInteractiveDAO) GetCollectInfo(ctx context.Context, biz string, id, uid int64) (dao.UserCollectionBiz, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCollectInfo", ctx, biz, id, uid)
+	ret0, _ := ret[0].(dao.UserCollectionBiz)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCollectInfo indicates an expected call of GetCollectInfo.
+func (mr *MockInteractiveDAOMockRecorder) GetCollectInfo(ctx, biz, id, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCollectInfo", reflect.TypeOf((*MockInteractiveDAO)(nil).GetCollectInfo), ctx, biz, id, uid)
+}
+
+// GetLikeInfo mocks base method.
+func (m *MockInteractiveDAO) GetLikeInfo(ctx context.Context, biz string, id, uid int64) (dao.UserLikeBiz, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLikeInfo", ctx, biz, id, uid)
+	ret0, _ := ret[0].(dao.UserLikeBiz)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLikeInfo indicates an expected call of GetLikeInfo.
+func (mr *MockInteractiveDAOMockRecorder) GetLikeInfo(ctx, biz, id, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLikeInfo", reflect.TypeOf((*MockInteractiveDAO)(nil).GetLikeInfo), ctx, biz, id, uid)
+}
+
+// GetReaction mocks base method.
+func (m *MockInteractiveDAO) GetReaction(ctx context.Context, biz string, id, uid int64) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetReaction", ctx, biz, id, uid)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetReaction indicates an expected call of GetReaction.
+func (mr *MockInteractiveDAOMockRecorder) GetReaction(ctx, biz, id, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetReaction", reflect.TypeOf((*MockInteractiveDAO)(nil).GetReaction), ctx, biz, id, uid)
+}
+
+// GetReactionCnts mocks base method.
+func (m *MockInteractiveDAO) GetReactionCnts(ctx context.Context, biz string, ids []int64) ([]dao.ReactionCnt, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetReactionCnts", ctx, biz, ids)
+	ret0, _ := ret[0].([]dao.ReactionCnt)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetReactionCnts indicates an expected call of GetReactionCnts.
+func (mr *MockInteractiveDAOMockRecorder) GetReactionCnts(ctx, biz, ids any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetReactionCnts", reflect.TypeOf((*MockInteractiveDAO)(nil).GetReactionCnts), ctx, biz, ids)
+}
+
+// IncrReadCnt mocks base method.
+func (m *MockInteractiveDAO) IncrReadCnt(ctx context.Context, biz string, bizId int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IncrReadCnt", ctx, biz, bizId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// IncrReadCnt indicates an expected call of IncrReadCnt.
+func (mr *MockInteractiveDAOMockRecorder) IncrReadCnt(ctx, biz, bizId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncrReadCnt", reflect.TypeOf((*MockInteractiveDAO)(nil).IncrReadCnt), ctx, biz, bizId)
+}
+
+// InsertCollectionBiz mocks base method.
+func (m *MockInteractiveDAO) InsertCollectionBiz(ctx context.Context, cb dao.UserCollectionBiz) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertCollectionBiz", ctx, cb)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InsertCollectionBiz indicates an expected call of InsertCollectionBiz.
+func (mr *MockInteractiveDAOMockRecorder) InsertCollectionBiz(ctx, cb any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertCollectionBiz", reflect.TypeOf((*MockInteractiveDAO)(nil).InsertCollectionBiz), ctx, cb)
+}
+
+// InsertLikeInfo mocks base method.
+func (m *MockInteractiveDAO) InsertLikeInfo(ctx context.Context, biz string, id, uid int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertLikeInfo", ctx, biz, id, uid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InsertLikeInfo indicates an expected call of InsertLikeInfo.
+func (mr *MockInteractiveDAOMockRecorder) InsertLikeInfo(ctx, biz, id, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertLikeInfo", reflect.TypeOf((*MockInteractiveDAO)(nil).InsertLikeInfo), ctx, biz, id, uid)
+}
+
+// LikeBiz mocks base method.
+func (m *MockInteractiveDAO) LikeBiz(ctx context.Context, biz string, id, uid int64) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LikeBiz", ctx, biz, id, uid)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LikeBiz indicates an expected call of LikeBiz.
+func (mr *MockInteractiveDAOMockRecorder) LikeBiz(ctx, biz, id, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LikeBiz", reflect.TypeOf((*MockInteractiveDAO)(nil).LikeBiz), ctx, biz, id, uid)
+}
+
+// ReconcileLikeCnt mocks base method.
+func (m *MockInteractiveDAO) ReconcileLikeCnt(ctx context.Context, biz string, id, before int64) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReconcileLikeCnt", ctx, biz, id, before)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReconcileLikeCnt indicates an expected call of ReconcileLikeCnt.
+func (mr *MockInteractiveDAOMockRecorder) ReconcileLikeCnt(ctx, biz, id, before any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReconcileLikeCnt", reflect.TypeOf((*MockInteractiveDAO)(nil).ReconcileLikeCnt), ctx, biz, id, before)
+}
+
+// RemoveReaction mocks base method.
+func (m *MockInteractiveDAO) RemoveReaction(ctx context.Context, biz string, id, uid int64, reaction string) (dao.UserReaction, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveReaction", ctx, biz, id, uid, reaction)
+	ret0, _ := ret[0].(dao.UserReaction)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RemoveReaction indicates an expected call of RemoveReaction.
+func (mr *MockInteractiveDAOMockRecorder) RemoveReaction(ctx, biz, id, uid, reaction any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveReaction", reflect.TypeOf((*MockInteractiveDAO)(nil).RemoveReaction), ctx, biz, id, uid, reaction)
+}
+
+// SetReaction mocks base method.
+func (m *MockInteractiveDAO) SetReaction(ctx context.Context, biz string, id, uid int64, reaction string) (dao.UserReaction, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetReaction", ctx, biz, id, uid, reaction)
+	ret0, _ := ret[0].(dao.UserReaction)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetReaction indicates an expected call of SetReaction.
+func (mr *MockInteractiveDAOMockRecorder) SetReaction(ctx, biz, id, uid, reaction any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetReaction", reflect.TypeOf((*MockInteractiveDAO)(nil).SetReaction), ctx, biz, id, uid, reaction)
+}
+
+// UnlikeBiz mocks base method.
+func (m *MockInteractiveDAO) UnlikeBiz(ctx context.Context, biz string, id, uid int64) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnlikeBiz", ctx, biz, id, uid)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UnlikeBiz indicates an expected call of UnlikeBiz.
+func (mr *MockInteractiveDAOMockRecorder) UnlikeBiz(ctx, biz, id, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnlikeBiz", reflect.TypeOf((*MockInteractiveDAO)(nil).UnlikeBiz), ctx, biz, id, uid)
+}
@@ -0,0 +1,71 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./like_rank.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./like_rank.go -package=daomocks -destination=mocks/like_rank.mock.go LikeRankDAO
+//
+
+// Package daomocks is a generated GoMock package.
+package daomocks
+
+import (
+	context "context"
+	reflect "reflect"
+	dao "xiaoweishu/webook/interactive/repository/dao"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockLikeRankDAO is a mock of LikeRankDAO interface.
+type MockLikeRankDAO struct {
+	ctrl     *gomock.Controller
+	recorder *MockLikeRankDAOMockRecorder
+}
+
+// MockLikeRankDAOMockRecorder is the mock recorder for MockLikeRankDAO.
+type MockLikeRankDAOMockRecorder struct {
+	mock *MockLikeRankDAO
+}
+
+// NewMockLikeRankDAO creates a new mock instance.
+func NewMockLikeRankDAO(ctrl *gomock.Controller) *MockLikeRankDAO {
+	mock := &MockLikeRankDAO{ctrl: ctrl}
+	mock.recorder = &MockLikeRankDAOMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLikeRankDAO) EXPECT() *MockLikeRankDAOMockRecorder {
+	return m.recorder
+}
+
+// CountLikesBetween mocks base method.
+func (m *MockLikeRankDAO) CountLikesBetween(ctx context.Context, biz string, start, end, minBizId int64, limit int) ([]dao.LikeCnt, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountLikesBetween", ctx, biz, start, end, minBizId, limit)
+	ret0, _ := ret[0].([]dao.LikeCnt)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountLikesBetween indicates an expected call of CountLikesBetween.
+func (mr *MockLikeRankDAOMockRecorder) CountLikesBetween(ctx, biz, start, end, minBizId, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountLikesBetween", reflect.TypeOf((*MockLikeRankDAO)(nil).CountLikesBetween), ctx, biz, start, end, minBizId, limit)
+}
+
+// ListLikeCnt mocks base method.
+func (m *MockLikeRankDAO) ListLikeCnt(ctx context.Context, biz string, minId int64, limit int) ([]dao.Interactive, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListLikeCnt", ctx, biz, minId, limit)
+	ret0, _ := ret[0].([]dao.Interactive)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListLikeCnt indicates an expected call of ListLikeCnt.
+func (mr *MockLikeRankDAOMockRecorder) ListLikeCnt(ctx, biz, minId, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListLikeCnt", reflect.TypeOf((*MockLikeRankDAO)(nil).ListLikeCnt), ctx, biz, minId, limit)
+}
@@ -0,0 +1,243 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+// reactionLike 👍 还是存在 user_like_bizs 和 interactives.like_cnt 里面，兼容原来的点赞
+const reactionLike = "like"
+
+// UserReaction 用户的表态，Utime 是表态的时间，毫秒数
+type UserReaction struct {
+	Reaction string
+	Utime    int64
+}
+
+// SetReaction 一个用户对一个资源只能有一种表态，切换表态的时候要先把之前的撤销
+// 返回之前的表态，之前没有表态 Reaction 就是空字符串
+func (DAO GORMInteractiveDAO) SetReaction(ctx context.Context, biz string, id int64, uid int64, reaction string) (UserReaction, error) {
+	var old UserReaction
+	err := DAO.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		old, err = DAO.currentReaction(tx, biz, id, uid)
+		if err != nil {
+			return err
+		}
+		if old.Reaction == reaction {
+			return nil
+		}
+		if old.Reaction != "" {
+			err = DAO.removeReaction(tx, biz, id, uid, old.Reaction)
+			if err != nil {
+				return err
+			}
+		}
+		return DAO.addReaction(tx, biz, id, uid, reaction)
+	})
+	return old, err
+}
+
+// RemoveReaction reaction 为空的时候撤销任意表态，否则只有当前表态是 reaction 才撤销
+// 返回被撤销的表态，没有撤销 Reaction 就是空字符串
+func (DAO GORMInteractiveDAO) RemoveReaction(ctx context.Context, biz string, id int64, uid int64, reaction string) (UserReaction, error) {
+	var old UserReaction
+	err := DAO.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		cur, err := DAO.currentReaction(tx, biz, id, uid)
+		if err != nil {
+			return err
+		}
+		if cur.Reaction == "" || (reaction != "" && cur.Reaction != reaction) {
+			return nil
+		}
+		old = cur
+		return DAO.removeReaction(tx, biz, id, uid, cur.Reaction)
+	})
+	return old, err
+}
+
+func (DAO GORMInteractiveDAO) GetReaction(ctx context.Context, biz string, id int64, uid int64) (string, error) {
+	r, err := DAO.currentReaction(DAO.db.WithContext(ctx), biz, id, uid)
+	return r.Reaction, err
+}
+
+func (DAO GORMInteractiveDAO) GetReactionCnts(ctx context.Context, biz string, ids []int64) ([]ReactionCnt, error) {
+	var res []ReactionCnt
+	err := DAO.db.WithContext(ctx).
+		Where("biz = ? AND biz_id IN ? AND cnt > 0", biz, ids).
+		Find(&res).Error
+	return res, err
+}
+
+// currentReaction 在事务里面调用的时候会锁住用户的表态记录，避免并发切换把计数算错
+func (DAO GORMInteractiveDAO) currentReaction(tx *gorm.DB, biz string, id int64, uid int64) (UserReaction, error) {
+	locking := clause.Locking{Strength: "UPDATE"}
+	var like UserLikeBiz
+	err := tx.Clauses(locking).
+		Where("uid = ? AND biz = ? AND biz_id = ?", uid, biz, id).
+		First(&like).Error
+	switch {
+	case err == nil:
+		if like.Status == 1 {
+			return UserReaction{Reaction: reactionLike, Utime: like.Utime}, nil
+		}
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return UserReaction{}, err
+	}
+	var r UserReactionBiz
+	err = tx.Clauses(locking).
+		Where("uid = ? AND biz = ? AND biz_id = ?", uid, biz, id).
+		First(&r).Error
+	switch {
+	case err == nil:
+		if r.Status == 1 {
+			return UserReaction{Reaction: r.Reaction, Utime: r.Utime}, nil
+		}
+		return UserReaction{}, nil
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return UserReaction{}, nil
+	default:
+		return UserReaction{}, err
+	}
+}
+
+func (DAO GORMInteractiveDAO) addReaction(tx *gorm.DB, biz string, id int64, uid int64, reaction string) error {
+	now := time.Now().UnixMilli()
+	if reaction == reactionLike {
+		err := tx.Clauses(clause.OnConflict{
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"utime":  now,
+				"status": 1,
+			}),
+		}).Create(&UserLikeBiz{
+			Uid:    uid,
+			Biz:    biz,
+			BizId:  id,
+			Status: 1,
+			Utime:  now,
+			Ctime:  now,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Clauses(clause.OnConflict{
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"like_cnt": gorm.Expr("`like_cnt` +1"),
+				"utime":    now,
+			}),
+		}).Create(&Interactive{
+			Biz:     biz,
+			BizId:   id,
+			Ctime:   now,
+			Utime:   now,
+			LikeCnt: 1,
+		}).Error
+	}
+	err := tx.Clauses(clause.OnConflict{
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"reaction": reaction,
+			"utime":    now,
+			"status":   1,
+		}),
+	}).Create(&UserReactionBiz{
+		Uid:      uid,
+		Biz:      biz,
+		BizId:    id,
+		Reaction: reaction,
+		Status:   1,
+		Utime:    now,
+		Ctime:    now,
+	}).Error
+	if err != nil {
+		return err
+	}
+	err = tx.Clauses(clause.OnConflict{
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"cnt":   gorm.Expr("`cnt` +1"),
+			"utime": now,
+		}),
+	}).Create(&ReactionCnt{
+		Biz:      biz,
+		BizId:    id,
+		Reaction: reaction,
+		Cnt:      1,
+		Ctime:    now,
+		Utime:    now,
+	}).Error
+	if err != nil {
+		return err
+	}
+	//保证 interactives 里面有这一行，不然查询的时候会认为资源没有交互数据
+	return tx.Clauses(clause.OnConflict{
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"utime": now,
+		}),
+	}).Create(&Interactive{
+		Biz:   biz,
+		BizId: id,
+		Ctime: now,
+		Utime: now,
+	}).Error
+}
+
+func (DAO GORMInteractiveDAO) removeReaction(tx *gorm.DB, biz string, id int64, uid int64, reaction string) error {
+	now := time.Now().UnixMilli()
+	if reaction == reactionLike {
+		err := tx.Model(&UserLikeBiz{}).
+			Where("uid = ? AND biz = ? AND biz_id = ?", uid, biz, id).
+			Updates(map[string]interface{}{
+				"utime":  now,
+				"status": 0,
+			}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(&Interactive{}).Where("biz = ? AND biz_id = ?", biz, id).
+			Updates(map[string]interface{}{
+				"utime":    now,
+				"like_cnt": gorm.Expr("`like_cnt` -1"),
+			}).Error
+	}
+	err := tx.Model(&UserReactionBiz{}).
+		Where("uid = ? AND biz = ? AND biz_id = ?", uid, biz, id).
+		Updates(map[string]interface{}{
+			"utime":  now,
+			"status": 0,
+		}).Error
+	if err != nil {
+		return err
+	}
+	return tx.Model(&ReactionCnt{}).
+		Where("biz = ? AND biz_id = ? AND reaction = ?", biz, id, reaction).
+		Updates(map[string]interface{}{
+			"utime": now,
+			"cnt":   gorm.Expr("`cnt` -1"),
+		}).Error
+}
+
+// UserReactionBiz 用户除了 👍 之外的表态，一个用户对一个资源只有一条记录
+type UserReactionBiz struct {
+	Id       int64  `gorm:"primaryKey,autoIncrement"`
+	Uid      int64  `gorm:"uniqueIndex:uid_biz_type_id"`
+	BizId    int64  `gorm:"uniqueIndex:uid_biz_type_id"`
+	Biz      string `gorm:"type:varchar(128);uniqueIndex:uid_biz_type_id"`
+	Reaction string `gorm:"type:varchar(32)"`
+	// 1 有效，0 已经撤销
+	Status uint8
+	Utime  int64
+	Ctime  int64
+}
+
+// ReactionCnt 每个资源每种表态的人数
+type ReactionCnt struct {
+	Id       int64  `gorm:"primaryKey,autoIncrement"`
+	BizId    int64  `gorm:"uniqueIndex:biz_type_id_reaction"`
+	Biz      string `gorm:"type:varchar(128);uniqueIndex:biz_type_id_reaction"`
+	Reaction string `gorm:"type:varchar(32);uniqueIndex:biz_type_id_reaction"`
+	Cnt      int64
+	Utime    int64
+	Ctime    int64
+}
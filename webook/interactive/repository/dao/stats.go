@@ -0,0 +1,180 @@
+package dao
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"sort"
+	"time"
+)
+
+// statsQueryBatchSize 按照 biz_id 查询统计数据的时候，一条 SQL 最多带多少个 id
+const statsQueryBatchSize = 500
+
+type InteractiveStatsDAO interface {
+	// BatchUpsert 计数是增量，已经有的桶直接加上去
+	BatchUpsert(ctx context.Context, stats []InteractiveStat) error
+	// SumByBucket 把 ids 在每个桶里面的数据加起来
+	SumByBucket(ctx context.Context, biz string, ids []int64, granularity string, start, end int64) ([]StatsSum, error)
+	// TopByTotal 按照 [start, end) 之间的阅读数排序
+	TopByTotal(ctx context.Context, biz string, ids []int64, granularity string, start, end int64, limit int) ([]StatsSum, error)
+	// DeleteBefore 删除 bucket 之前的数据，返回删除的行数
+	DeleteBefore(ctx context.Context, granularity string, bucket int64, limit int) (int64, error)
+}
+
+type GORMInteractiveStatsDAO struct {
+	db *gorm.DB
+}
+
+func NewGORMInteractiveStatsDAO(db *gorm.DB) InteractiveStatsDAO {
+	return &GORMInteractiveStatsDAO{db: db}
+}
+
+func (g *GORMInteractiveStatsDAO) BatchUpsert(ctx context.Context, stats []InteractiveStat) error {
+	if len(stats) == 0 {
+		return nil
+	}
+	now := time.Now().UnixMilli()
+	for i := range stats {
+		stats[i].Ctime = now
+		stats[i].Utime = now
+	}
+	//和 interactives 一样，按照唯一索引排序避免死锁
+	sort.Slice(stats, func(i, j int) bool {
+		a, b := stats[i], stats[j]
+		if a.Biz != b.Biz {
+			return a.Biz < b.Biz
+		}
+		if a.BizId != b.BizId {
+			return a.BizId < b.BizId
+		}
+		if a.Granularity != b.Granularity {
+			return a.Granularity < b.Granularity
+		}
+		return a.Bucket < b.Bucket
+	})
+	return g.db.WithContext(ctx).Clauses(clause.OnConflict{
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"read_cnt":    gorm.Expr("`read_cnt` + VALUES(`read_cnt`)"),
+			"like_cnt":    gorm.Expr("`like_cnt` + VALUES(`like_cnt`)"),
+			"collect_cnt": gorm.Expr("`collect_cnt` + VALUES(`collect_cnt`)"),
+			"utime":       now,
+		}),
+	}).CreateInBatches(&stats, upsertBatchSize).Error
+}
+
+func (g *GORMInteractiveStatsDAO) SumByBucket(ctx context.Context, biz string, ids []int64, granularity string, start, end int64) ([]StatsSum, error) {
+	//每个桶的数据是可以直接相加的，分批查完再合并
+	merged := make(map[int64]*StatsSum)
+	for _, batch := range splitIds(ids) {
+		var sums []StatsSum
+		err := g.db.WithContext(ctx).Model(&InteractiveStat{}).
+			Select("`bucket`, SUM(`read_cnt`) AS `read_cnt`, SUM(`like_cnt`) AS `like_cnt`, SUM(`collect_cnt`) AS `collect_cnt`").
+			Where("biz = ? AND biz_id IN ? AND granularity = ? AND bucket >= ? AND bucket < ?",
+				biz, batch, granularity, start, end).
+			Group("bucket").
+			Scan(&sums).Error
+		if err != nil {
+			return nil, err
+		}
+		for _, sum := range sums {
+			m, ok := merged[sum.Bucket]
+			if !ok {
+				m = &StatsSum{Bucket: sum.Bucket}
+				merged[sum.Bucket] = m
+			}
+			m.ReadCnt += sum.ReadCnt
+			m.LikeCnt += sum.LikeCnt
+			m.CollectCnt += sum.CollectCnt
+		}
+	}
+	res := make([]StatsSum, 0, len(merged))
+	for _, m := range merged {
+		res = append(res, *m)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Bucket < res[j].Bucket
+	})
+	return res, nil
+}
+
+func (g *GORMInteractiveStatsDAO) TopByTotal(ctx context.Context, biz string, ids []int64, granularity string, start, end int64, limit int) ([]StatsSum, error) {
+	//一个 biz_id 只会出现在一批里面，所以全局的前 limit 名一定在每一批的前 limit 名里面
+	var res []StatsSum
+	for _, batch := range splitIds(ids) {
+		var sums []StatsSum
+		err := g.db.WithContext(ctx).Model(&InteractiveStat{}).
+			Select("`biz_id`, SUM(`read_cnt`) AS `read_cnt`, SUM(`like_cnt`) AS `like_cnt`, SUM(`collect_cnt`) AS `collect_cnt`").
+			Where("biz = ? AND biz_id IN ? AND granularity = ? AND bucket >= ? AND bucket < ?",
+				biz, batch, granularity, start, end).
+			Group("biz_id").
+			Order("`read_cnt` DESC, `like_cnt` DESC, `biz_id`").
+			Limit(limit).
+			Scan(&sums).Error
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, sums...)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		a, b := res[i], res[j]
+		if a.ReadCnt != b.ReadCnt {
+			return a.ReadCnt > b.ReadCnt
+		}
+		if a.LikeCnt != b.LikeCnt {
+			return a.LikeCnt > b.LikeCnt
+		}
+		return a.BizId < b.BizId
+	})
+	if len(res) > limit {
+		res = res[:limit]
+	}
+	return res, nil
+}
+
+// splitIds IN 里面的 id 太多的话 SQL 会很长，优化器也容易放弃走索引，所以分批查
+func splitIds(ids []int64) [][]int64 {
+	res := make([][]int64, 0, (len(ids)+statsQueryBatchSize-1)/statsQueryBatchSize)
+	for len(ids) > statsQueryBatchSize {
+		res = append(res, ids[:statsQueryBatchSize])
+		ids = ids[statsQueryBatchSize:]
+	}
+	if len(ids) > 0 {
+		res = append(res, ids)
+	}
+	return res
+}
+
+func (g *GORMInteractiveStatsDAO) DeleteBefore(ctx context.Context, granularity string, bucket int64, limit int) (int64, error) {
+	//分批删除，避免一次删太多锁表
+	res := g.db.WithContext(ctx).
+		Where("granularity = ? AND bucket < ?", granularity, bucket).
+		Limit(limit).
+		Delete(&InteractiveStat{})
+	return res.RowsAffected, res.Error
+}
+
+// InteractiveStat 按小时和按天分桶的交互数据
+type InteractiveStat struct {
+	Id    int64  `gorm:"primaryKey,autoIncrement"`
+	BizId int64  `gorm:"uniqueIndex:biz_type_id_bucket"`
+	Biz   string `gorm:"type:varchar(128);uniqueIndex:biz_type_id_bucket"`
+	// Granularity hour 或者 day
+	Granularity string `gorm:"type:varchar(8);uniqueIndex:biz_type_id_bucket;index:granularity_bucket"`
+	// Bucket 桶的开始时间，毫秒数
+	Bucket     int64 `gorm:"uniqueIndex:biz_type_id_bucket;index:granularity_bucket"`
+	ReadCnt    int64
+	LikeCnt    int64
+	CollectCnt int64
+	Utime      int64
+	Ctime      int64
+}
+
+// StatsSum 聚合查询的结果
+type StatsSum struct {
+	Bucket     int64
+	BizId      int64
+	ReadCnt    int64
+	LikeCnt    int64
+	CollectCnt int64
+}
@@ -0,0 +1,67 @@
+package dao
+
+import (
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"testing"
+)
+
+// statsIds 刚好分成两批
+func statsIds() []int64 {
+	ids := make([]int64, 0, statsQueryBatchSize+1)
+	for i := 1; i <= statsQueryBatchSize+1; i++ {
+		ids = append(ids, int64(i))
+	}
+	return ids
+}
+
+func newStatsMockDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db, err := gorm.Open(mysql.New(mysql.Config{
+		Conn:                      sqlDB,
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{
+		SkipDefaultTransaction: true,
+	})
+	require.NoError(t, err)
+	return db, mock
+}
+
+func TestGORMInteractiveStatsDAO_SumByBucket(t *testing.T) {
+	db, mock := newStatsMockDB(t)
+	cols := []string{"bucket", "read_cnt", "like_cnt", "collect_cnt"}
+	mock.ExpectQuery("SELECT .* FROM `interactive_stats` WHERE .* GROUP BY `bucket`").
+		WillReturnRows(sqlmock.NewRows(cols).AddRow(200, 1, 1, 0).AddRow(100, 2, 0, 1))
+	mock.ExpectQuery("SELECT .* FROM `interactive_stats` WHERE .* GROUP BY `bucket`").
+		WillReturnRows(sqlmock.NewRows(cols).AddRow(100, 3, 1, 0))
+	dao := NewGORMInteractiveStatsDAO(db)
+	res, err := dao.SumByBucket(context.Background(), "article", statsIds(), "day", 0, 1000)
+	require.NoError(t, err)
+	assert.Equal(t, []StatsSum{
+		{Bucket: 100, ReadCnt: 5, LikeCnt: 1, CollectCnt: 1},
+		{Bucket: 200, ReadCnt: 1, LikeCnt: 1},
+	}, res)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGORMInteractiveStatsDAO_TopByTotal(t *testing.T) {
+	db, mock := newStatsMockDB(t)
+	cols := []string{"biz_id", "read_cnt", "like_cnt", "collect_cnt"}
+	mock.ExpectQuery("SELECT .* FROM `interactive_stats` WHERE .* GROUP BY `biz_id` .* LIMIT \\?").
+		WillReturnRows(sqlmock.NewRows(cols).AddRow(1, 10, 0, 0).AddRow(2, 5, 3, 0))
+	mock.ExpectQuery("SELECT .* FROM `interactive_stats` WHERE .* GROUP BY `biz_id` .* LIMIT \\?").
+		WillReturnRows(sqlmock.NewRows(cols).AddRow(501, 5, 4, 0))
+	dao := NewGORMInteractiveStatsDAO(db)
+	res, err := dao.TopByTotal(context.Background(), "article", statsIds(), "day", 0, 1000, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []StatsSum{
+		{BizId: 1, ReadCnt: 10},
+		{BizId: 501, ReadCnt: 5, LikeCnt: 4},
+	}, res)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"github.com/ecodeclub/ekit/slice"
+	"time"
+	"xiaoweishu/webook/interactive/domain"
+	"xiaoweishu/webook/interactive/repository/dao"
+)
+
+type ReadHistoryRepository interface {
+	// AddRecords 批量写入阅读记录，暂停了阅读历史的用户的记录会被丢弃
+	AddRecords(ctx context.Context, hs []domain.ReadHistory) error
+	Trim(ctx context.Context, uid int64, keep int) error
+	List(ctx context.Context, uid int64, offset int, limit int) ([]domain.ReadHistory, error)
+	Delete(ctx context.Context, uid int64, biz string, bizId int64) error
+	Clear(ctx context.Context, uid int64) error
+	SetPaused(ctx context.Context, uid int64, paused bool) error
+	Paused(ctx context.Context, uid int64) (bool, error)
+}
+
+type GORMReadHistoryRepository struct {
+	dao dao.ReadHistoryDAO
+}
+
+func NewGORMReadHistoryRepository(dao dao.ReadHistoryDAO) ReadHistoryRepository {
+	return &GORMReadHistoryRepository{
+		dao: dao,
+	}
+}
+
+func (r *GORMReadHistoryRepository) AddRecords(ctx context.Context, hs []domain.ReadHistory) error {
+	if len(hs) == 0 {
+		return nil
+	}
+	uids := slice.Map(hs, func(idx int, src domain.ReadHistory) int64 {
+		return src.Uid
+	})
+	paused, err := r.dao.FindPausedUids(ctx, uids)
+	if err != nil {
+		return err
+	}
+	pausedSet := make(map[int64]struct{}, len(paused))
+	for _, uid := range paused {
+		pausedSet[uid] = struct{}{}
+	}
+	//一批消息里面同一个用户可能多次阅读同一篇文章，先在内存里去重
+	type key struct {
+		uid   int64
+		biz   string
+		bizId int64
+	}
+	idx := make(map[key]int, len(hs))
+	entities := make([]dao.UserReadHistory, 0, len(hs))
+	for _, h := range hs {
+		if _, ok := pausedSet[h.Uid]; ok {
+			continue
+		}
+		k := key{uid: h.Uid, biz: h.Biz, bizId: h.BizId}
+		rtime := h.Rtime.UnixMilli()
+		if i, ok := idx[k]; ok {
+			if entities[i].Rtime < rtime {
+				entities[i].Rtime = rtime
+			}
+			continue
+		}
+		idx[k] = len(entities)
+		entities = append(entities, r.toEntity(h))
+	}
+	return r.dao.BatchUpsert(ctx, entities)
+}
+
+func (r *GORMReadHistoryRepository) Trim(ctx context.Context, uid int64, keep int) error {
+	return r.dao.Trim(ctx, uid, keep)
+}
+
+func (r *GORMReadHistoryRepository) List(ctx context.Context, uid int64, offset int, limit int) ([]domain.ReadHistory, error) {
+	hs, err := r.dao.List(ctx, uid, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	return slice.Map(hs, func(idx int, src dao.UserReadHistory) domain.ReadHistory {
+		return r.toDomain(src)
+	}), nil
+}
+
+func (r *GORMReadHistoryRepository) Delete(ctx context.Context, uid int64, biz string, bizId int64) error {
+	return r.dao.Delete(ctx, uid, biz, bizId)
+}
+
+func (r *GORMReadHistoryRepository) Clear(ctx context.Context, uid int64) error {
+	return r.dao.Clear(ctx, uid)
+}
+
+func (r *GORMReadHistoryRepository) SetPaused(ctx context.Context, uid int64, paused bool) error {
+	return r.dao.SetPaused(ctx, uid, paused)
+}
+
+func (r *GORMReadHistoryRepository) Paused(ctx context.Context, uid int64) (bool, error) {
+	s, err := r.dao.GetSetting(ctx, uid)
+	switch {
+	case err == nil:
+		return s.Paused, nil
+	case errors.Is(err, dao.ErrRecordNotFound):
+		//没有设置过，默认是记录的
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+func (r *GORMReadHistoryRepository) toEntity(h domain.ReadHistory) dao.UserReadHistory {
+	return dao.UserReadHistory{
+		Uid:   h.Uid,
+		Biz:   h.Biz,
+		BizId: h.BizId,
+		Rtime: h.Rtime.UnixMilli(),
+	}
+}
+
+func (r *GORMReadHistoryRepository) toDomain(h dao.UserReadHistory) domain.ReadHistory {
+	return domain.ReadHistory{
+		Uid:   h.Uid,
+		Biz:   h.Biz,
+		BizId: h.BizId,
+		Rtime: time.UnixMilli(h.Rtime),
+	}
+}
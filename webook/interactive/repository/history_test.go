@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+	"xiaoweishu/webook/interactive/domain"
+	"xiaoweishu/webook/interactive/repository/dao"
+	daomocks "xiaoweishu/webook/interactive/repository/dao/mocks"
+)
+
+func TestGORMReadHistoryRepository_AddRecords(t *testing.T) {
+	now := time.UnixMilli(time.Now().UnixMilli())
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	d := daomocks.NewMockReadHistoryDAO(ctrl)
+	d.EXPECT().FindPausedUids(gomock.Any(), gomock.Any()).Return([]int64{2}, nil)
+	//暂停了的用户 2 不记录，同一篇文章读了两次，只保留最后一次的阅读时间
+	d.EXPECT().BatchUpsert(gomock.Any(), []dao.UserReadHistory{
+		{Uid: 1, Biz: "article", BizId: 10, Rtime: now.UnixMilli()},
+		{Uid: 1, Biz: "article", BizId: 11, Rtime: now.UnixMilli()},
+	}).Return(nil)
+	repo := NewGORMReadHistoryRepository(d)
+	err := repo.AddRecords(context.Background(), []domain.ReadHistory{
+		{Uid: 1, Biz: "article", BizId: 10, Rtime: now.Add(-time.Minute)},
+		{Uid: 2, Biz: "article", BizId: 10, Rtime: now},
+		{Uid: 1, Biz: "article", BizId: 10, Rtime: now},
+		{Uid: 1, Biz: "article", BizId: 11, Rtime: now},
+	})
+	require.NoError(t, err)
+}
+
+func TestGORMReadHistoryRepository_Paused(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) dao.ReadHistoryDAO
+
+		wantPaused bool
+		wantErr    bool
+	}{
+		{
+			name: "暂停了",
+			mock: func(ctrl *gomock.Controller) dao.ReadHistoryDAO {
+				d := daomocks.NewMockReadHistoryDAO(ctrl)
+				d.EXPECT().GetSetting(gomock.Any(), int64(1)).
+					Return(dao.UserReadHistorySetting{Uid: 1, Paused: true}, nil)
+				return d
+			},
+			wantPaused: true,
+		},
+		{
+			name: "没有设置过",
+			mock: func(ctrl *gomock.Controller) dao.ReadHistoryDAO {
+				d := daomocks.NewMockReadHistoryDAO(ctrl)
+				d.EXPECT().GetSetting(gomock.Any(), int64(1)).
+					Return(dao.UserReadHistorySetting{}, dao.ErrRecordNotFound)
+				return d
+			},
+		},
+		{
+			name: "数据库出错",
+			mock: func(ctrl *gomock.Controller) dao.ReadHistoryDAO {
+				d := daomocks.NewMockReadHistoryDAO(ctrl)
+				d.EXPECT().GetSetting(gomock.Any(), int64(1)).
+					Return(dao.UserReadHistorySetting{}, errors.New("db 错误"))
+				return d
+			},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			repo := NewGORMReadHistoryRepository(tc.mock(ctrl))
+			paused, err := repo.Paused(context.Background(), 1)
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.wantPaused, paused)
+		})
+	}
+}
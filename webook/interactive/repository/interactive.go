@@ -3,13 +3,16 @@ package repository
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/ecodeclub/ekit/slice"
+	"time"
 	"xiaoweishu/webook/interactive/domain"
 	"xiaoweishu/webook/interactive/repository/cache"
 	dao "xiaoweishu/webook/interactive/repository/dao"
 	logger2 "xiaoweishu/webook/pkg/logger"
 )
 
+//go:generate mockgen -source=./interactive.go -package=repomocks -destination=mocks/interactive.mock.go InteractiveRepository
 type InteractiveRepository interface {
 	IncrReadCnt(ctx context.Context, biz string, bizId int64) error
 	IncrLike(ctx context.Context, biz string, id int64, uid int64) error
@@ -19,41 +22,223 @@ type InteractiveRepository interface {
 	Liked(ctx context.Context, biz string, id int64, uid int64) (bool, error)
 	Collected(ctx context.Context, biz string, id int64, uid int64) (bool, error)
 	BatchIncrReadCnt(ctx context.Context, bizs []string, ids []int64) error
+	// BatchIncrUvCnt visitors 是访客标识，登录用户用 uid，未登录的用 IP
+	BatchIncrUvCnt(ctx context.Context, bizs []string, ids []int64, visitors []string) error
 	GetByIds(ctx context.Context, biz string, ids []int64) ([]domain.Interactive, error)
+
+	// SetLiked 只修改用户的点赞状态，计数交给写回模式异步处理
+	// 返回 true 说明状态真的变了，需要发送点赞事件
+	// 返回之前的点赞状态，取消点赞的时候 Utime 是原来点赞的时间
+	SetLiked(ctx context.Context, biz string, id int64, uid int64, liked bool) (domain.UserReaction, error)
+	BatchAddCnt(ctx context.Context, deltas []domain.CntDelta) error
+	// ReconcileLikeCnt 对账，让 since 之后有点赞变化的资源的点赞数和 user_like_bizs 保持一致
+	ReconcileLikeCnt(ctx context.Context, biz string, since time.Time) error
+
+	// SetReaction 设置或者切换表态，返回之前的表态
+	SetReaction(ctx context.Context, biz string, id int64, uid int64, reaction string) (domain.UserReaction, error)
+	// RemoveReaction reaction 为空就撤销任意表态，返回被撤销的表态
+	RemoveReaction(ctx context.Context, biz string, id int64, uid int64, reaction string) (domain.UserReaction, error)
+	// Reaction 用户当前的表态，没有就是空字符串
+	Reaction(ctx context.Context, biz string, id int64, uid int64) (string, error)
 }
+
+// reconcileSettleTime 写回模式下点赞的增量在 kafka 里面排队的时间上限，
+// 这段时间内有点赞变化的资源对账的时候会跳过，不然增量写回的时候会被算两次
+const reconcileSettleTime = time.Minute * 5
+
 type CachedInteractiveRepository struct {
 	dao   dao.InteractiveDAO
 	cache cache.InteractiveCache
 	l     logger2.LoggerV1
 }
 
+// GetByIds 先批量查缓存，没命中的再去数据库查，然后回写缓存
+// 数据库里面也没有的返回零值，调用方不需要再判断是否存在
 func (c *CachedInteractiveRepository) GetByIds(ctx context.Context, biz string, ids []int64) ([]domain.Interactive, error) {
-	intrs, err := c.dao.GetByIds(ctx, biz, ids)
+	cached, err := c.cache.GetByIds(ctx, biz, ids)
 	if err != nil {
-		return nil, err
+		//缓存崩了就全部去查数据库
+		c.l.Error("批量查询缓存失败", logger2.String("biz", biz), logger2.Error(err))
+		cached = map[int64]domain.Interactive{}
+	}
+	missIds := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		if _, ok := cached[id]; !ok {
+			missIds = append(missIds, id)
+		}
+	}
+	if len(missIds) > 0 {
+		intrs, err := c.dao.GetByIds(ctx, biz, missIds)
+		if err != nil {
+			return nil, err
+		}
+		cnts, err := c.dao.GetReactionCnts(ctx, biz, missIds)
+		if err != nil {
+			return nil, err
+		}
+		for _, intr := range intrs {
+			cached[intr.BizId] = c.withReactionCnts(c.ToDomain(intr), cnts)
+		}
+		backfill := make([]domain.Interactive, 0, len(missIds))
+		for _, id := range missIds {
+			intr, ok := cached[id]
+			if !ok {
+				//没有记录的也缓存零值，避免这些 id 每次都打到数据库
+				intr = domain.Interactive{Biz: biz, BizId: id}
+				cached[id] = intr
+			}
+			backfill = append(backfill, intr)
+		}
+		err = c.cache.BatchSet(ctx, backfill)
+		if err != nil {
+			c.l.Error("批量回写缓存失败", logger2.String("biz", biz), logger2.Error(err))
+		}
 	}
-	return slice.Map[dao.Interactive, domain.Interactive](intrs, func(idx int, src dao.Interactive) domain.Interactive {
-		return c.ToDomain(src)
+	return slice.Map(ids, func(idx int, id int64) domain.Interactive {
+		return cached[id]
 	}), nil
-
 }
 
+// BatchIncrReadCnt 只在这里按照资源聚合一次，数据库和缓存用同一批增量，
+// 热点文章一批里面只会更新一次
 func (c *CachedInteractiveRepository) BatchIncrReadCnt(ctx context.Context, bizs []string, bizIds []int64) error {
-	err := c.dao.BatchIncrReadCnt(ctx, bizs, bizIds)
+	deltas := make([]domain.CntDelta, 0, len(bizs))
+	index := make(map[string]int, len(bizs))
+	for i := 0; i < len(bizs); i++ {
+		key := fmt.Sprintf("%s:%d", bizs[i], bizIds[i])
+		if idx, ok := index[key]; ok {
+			deltas[idx].ReadCnt++
+			continue
+		}
+		index[key] = len(deltas)
+		deltas = append(deltas, domain.CntDelta{Biz: bizs[i], BizId: bizIds[i], ReadCnt: 1})
+	}
+	return c.BatchAddCnt(ctx, deltas)
+}
+
+// BatchIncrUvCnt 先判断哪些是新访客，数据库更新成功之后再把访客记到 HyperLogLog 里面。
+// 反过来的话数据库失败重试的时候访客已经记下来了，这个 UV 就永远丢了
+func (c *CachedInteractiveRepository) BatchIncrUvCnt(ctx context.Context, bizs []string, ids []int64, visitors []string) error {
+	if len(bizs) == 0 {
+		return nil
+	}
+	isNew, err := c.cache.CheckVisitors(ctx, bizs, ids, visitors)
+	if err != nil {
+		return err
+	}
+	newBizs := make([]string, 0, len(bizs))
+	newIds := make([]int64, 0, len(ids))
+	newVisitors := make([]string, 0, len(visitors))
+	//同一批里面同一个访客读了几次，只算一次
+	seen := make(map[string]struct{}, len(bizs))
+	for i := 0; i < len(bizs); i++ {
+		if !isNew[i] {
+			continue
+		}
+		key := fmt.Sprintf("%s:%d:%s", bizs[i], ids[i], visitors[i])
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		newBizs = append(newBizs, bizs[i])
+		newIds = append(newIds, ids[i])
+		newVisitors = append(newVisitors, visitors[i])
+	}
+	if len(newBizs) == 0 {
+		return nil
+	}
+	err = c.dao.BatchIncrUvCnt(ctx, newBizs, newIds)
+	if err != nil {
+		return err
+	}
+	err = c.cache.AddVisitors(ctx, newBizs, newIds, newVisitors)
+	if err != nil {
+		//数据库已经加上了，不能返回 error 让上层重试，不然会重复计数。
+		//最坏的情况是这些访客在这个窗口内再读一次会多算一次
+		c.l.Error("记录 UV 访客失败", logger2.Error(err))
+	}
+	for i := 0; i < len(newBizs); i++ {
+		er := c.cache.IncrUvCntIfPresent(ctx, newBizs[i], newIds[i])
+		if er != nil {
+			c.l.Debug("更新缓存 UV 失败", logger2.Error(er))
+		}
+	}
+	return nil
+}
+
+func (c *CachedInteractiveRepository) SetLiked(ctx context.Context, biz string, id int64, uid int64, liked bool) (domain.UserReaction, error) {
+	if liked {
+		changed, err := c.dao.LikeBiz(ctx, biz, id, uid)
+		if err != nil || changed {
+			return domain.UserReaction{}, err
+		}
+		return domain.UserReaction{Reaction: domain.ReactionLike}, nil
+	}
+	likeTime, err := c.dao.UnlikeBiz(ctx, biz, id, uid)
+	if err != nil || likeTime == 0 {
+		return domain.UserReaction{}, err
+	}
+	return domain.UserReaction{Reaction: domain.ReactionLike, Utime: time.UnixMilli(likeTime)}, nil
+}
+
+func (c *CachedInteractiveRepository) BatchAddCnt(ctx context.Context, deltas []domain.CntDelta) error {
+	err := c.dao.BatchAddCnt(ctx, slice.Map(deltas, func(idx int, src domain.CntDelta) dao.CntDelta {
+		return dao.CntDelta{
+			Biz:     src.Biz,
+			BizId:   src.BizId,
+			ReadCnt: src.ReadCnt,
+			LikeCnt: src.LikeCnt,
+		}
+	}))
 	if err != nil {
 		return err
 	}
-	//到这说明数据库更新成功，可以开启异步更新redis缓存
-	go func() {
-		for i := 0; i < len(bizs); i++ {
-			er := c.cache.IncrReadCntIfPresent(ctx, bizs[i], bizIds[i])
+	er := c.cache.BatchAddCntIfPresent(ctx, deltas)
+	if er != nil {
+		//缓存更新失败问题不大，过期之后会重新从数据库加载
+		c.l.Debug("更新缓存计数失败", logger2.Error(er))
+	}
+	return nil
+}
+
+func (c *CachedInteractiveRepository) ReconcileLikeCnt(ctx context.Context, biz string, since time.Time) error {
+	const batchSize = 100
+	//最近有点赞变化的先跳过，等增量写回之后下一次再对账
+	before := time.Now().Add(-reconcileSettleTime).UnixMilli()
+	offset, skipped := 0, 0
+	for {
+		ids, err := c.dao.FindLikedBizIds(ctx, biz, since.UnixMilli(), offset, batchSize)
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			ok, err := c.dao.ReconcileLikeCnt(ctx, biz, id, before)
 			if err != nil {
-				c.l.Debug("批量缓存阅读数失败", logger2.Error(er))
+				return err
+			}
+			if !ok {
+				skipped++
+				continue
+			}
+			//直接删掉缓存，下次查询的时候从数据库加载
+			er := c.cache.Del(ctx, biz, id)
+			if er != nil {
+				c.l.Error("对账之后删除缓存失败",
+					logger2.String("biz", biz),
+					logger2.Int64("bizId", id),
+					logger2.Error(er))
 			}
 		}
-	}()
-	//这里不需要等待redis缓存完，因为就算是缓存失败也不是什么大事
-	return nil
+		if len(ids) < batchSize {
+			if skipped > 0 {
+				c.l.Info("最近有点赞变化，跳过对账",
+					logger2.String("biz", biz),
+					logger2.Int("cnt", skipped))
+			}
+			return nil
+		}
+		offset += len(ids)
+	}
 }
 
 func (c *CachedInteractiveRepository) IncrReadCnt(ctx context.Context, biz string, bizId int64) error {
@@ -110,7 +295,11 @@ func (c *CachedInteractiveRepository) Get(ctx context.Context, biz string, id in
 	if err != nil {
 		return domain.Interactive{}, err
 	}
-	res := c.ToDomain(ie)
+	cnts, err := c.dao.GetReactionCnts(ctx, biz, []int64{id})
+	if err != nil {
+		return domain.Interactive{}, err
+	}
+	res := c.withReactionCnts(c.ToDomain(ie), cnts)
 	//回写缓存
 	err = c.cache.Set(ctx, biz, id, res)
 	if err != nil {
@@ -158,11 +347,72 @@ func NewCachedInteractiveRepository(dao dao.InteractiveDAO,
 	}
 
 }
+func (c *CachedInteractiveRepository) SetReaction(ctx context.Context, biz string, id int64, uid int64, reaction string) (domain.UserReaction, error) {
+	r, err := c.dao.SetReaction(ctx, biz, id, uid, reaction)
+	old := c.toUserReaction(r)
+	if err != nil || old.Reaction == reaction {
+		return old, err
+	}
+	if old.Reaction != "" {
+		err = c.cache.IncrReactionCntIfPresent(ctx, biz, id, old.Reaction, -1)
+		if err != nil {
+			c.l.Error("更新表态缓存失败", logger2.String("biz", biz),
+				logger2.Int64("bizId", id), logger2.Error(err))
+		}
+	}
+	err = c.cache.IncrReactionCntIfPresent(ctx, biz, id, reaction, 1)
+	if err != nil {
+		c.l.Error("更新表态缓存失败", logger2.String("biz", biz),
+			logger2.Int64("bizId", id), logger2.Error(err))
+	}
+	return old, nil
+}
+
+func (c *CachedInteractiveRepository) RemoveReaction(ctx context.Context, biz string, id int64, uid int64, reaction string) (domain.UserReaction, error) {
+	r, err := c.dao.RemoveReaction(ctx, biz, id, uid, reaction)
+	old := c.toUserReaction(r)
+	if err != nil || old.Reaction == "" {
+		return old, err
+	}
+	err = c.cache.IncrReactionCntIfPresent(ctx, biz, id, old.Reaction, -1)
+	if err != nil {
+		c.l.Error("更新表态缓存失败", logger2.String("biz", biz),
+			logger2.Int64("bizId", id), logger2.Error(err))
+	}
+	return old, nil
+}
+
+func (c *CachedInteractiveRepository) toUserReaction(r dao.UserReaction) domain.UserReaction {
+	if r.Reaction == "" {
+		return domain.UserReaction{}
+	}
+	return domain.UserReaction{Reaction: r.Reaction, Utime: time.UnixMilli(r.Utime)}
+}
+
+func (c *CachedInteractiveRepository) Reaction(ctx context.Context, biz string, id int64, uid int64) (string, error) {
+	return c.dao.GetReaction(ctx, biz, id, uid)
+}
+
+// withReactionCnts 把这个资源的表态人数填进去，cnts 里面可能有别的资源的数据
+func (c *CachedInteractiveRepository) withReactionCnts(intr domain.Interactive, cnts []dao.ReactionCnt) domain.Interactive {
+	intr.ReactionCnts = make(map[string]int64)
+	if intr.LikeCnt > 0 {
+		intr.ReactionCnts[domain.ReactionLike] = intr.LikeCnt
+	}
+	for _, cnt := range cnts {
+		if cnt.BizId == intr.BizId {
+			intr.ReactionCnts[cnt.Reaction] = cnt.Cnt
+		}
+	}
+	return intr
+}
+
 func (c *CachedInteractiveRepository) ToDomain(ie dao.Interactive) domain.Interactive {
 	return domain.Interactive{
 		Biz:        ie.Biz,
 		BizId:      ie.BizId,
 		ReadCnt:    ie.ReadCnt,
+		UvCnt:      ie.UvCnt,
 		CollectCnt: ie.CollectCnt,
 		LikeCnt:    ie.LikeCnt,
 	}
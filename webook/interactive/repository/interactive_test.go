@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"xiaoweishu/webook/interactive/domain"
+	"xiaoweishu/webook/interactive/repository/cache"
+	cachemocks "xiaoweishu/webook/interactive/repository/cache/mocks"
+	"xiaoweishu/webook/interactive/repository/dao"
+	daomocks "xiaoweishu/webook/interactive/repository/dao/mocks"
+	"xiaoweishu/webook/pkg/logger"
+)
+
+// TestCachedInteractiveRepository_BatchIncrReadCnt 数据库和缓存拿到的是同一批聚合好的增量
+func TestCachedInteractiveRepository_BatchIncrReadCnt(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	d := daomocks.NewMockInteractiveDAO(ctrl)
+	c := cachemocks.NewMockInteractiveCache(ctrl)
+	d.EXPECT().BatchAddCnt(gomock.Any(), []dao.CntDelta{
+		{Biz: "article", BizId: 1, ReadCnt: 2},
+		{Biz: "article", BizId: 2, ReadCnt: 1},
+		{Biz: "video", BizId: 1, ReadCnt: 1},
+	}).Return(nil)
+	c.EXPECT().BatchAddCntIfPresent(gomock.Any(), []domain.CntDelta{
+		{Biz: "article", BizId: 1, ReadCnt: 2},
+		{Biz: "article", BizId: 2, ReadCnt: 1},
+		{Biz: "video", BizId: 1, ReadCnt: 1},
+	}).Return(nil)
+	repo := NewCachedInteractiveRepository(d, c, logger.NewNopLogger())
+	err := repo.BatchIncrReadCnt(context.Background(),
+		[]string{"article", "article", "video", "article"},
+		[]int64{1, 2, 1, 1})
+	require.NoError(t, err)
+}
+
+func TestCachedInteractiveRepository_BatchIncrUvCnt(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (dao.InteractiveDAO, cache.InteractiveCache)
+
+		bizs     []string
+		ids      []int64
+		visitors []string
+
+		wantErr error
+	}{
+		{
+			name: "先写数据库再记访客",
+			mock: func(ctrl *gomock.Controller) (dao.InteractiveDAO, cache.InteractiveCache) {
+				d := daomocks.NewMockInteractiveDAO(ctrl)
+				c := cachemocks.NewMockInteractiveCache(ctrl)
+				check := c.EXPECT().CheckVisitors(gomock.Any(),
+					[]string{"article", "article", "article"}, []int64{1, 2, 1},
+					[]string{"u:1", "u:1", "u:1"}).
+					Return([]bool{true, false, true}, nil)
+				// 同一批里面重复的访客只算一次
+				incr := d.EXPECT().BatchIncrUvCnt(gomock.Any(), []string{"article"}, []int64{1}).
+					Return(nil).After(check)
+				c.EXPECT().AddVisitors(gomock.Any(), []string{"article"}, []int64{1}, []string{"u:1"}).
+					Return(nil).After(incr)
+				c.EXPECT().IncrUvCntIfPresent(gomock.Any(), "article", int64(1)).Return(nil)
+				return d, c
+			},
+			bizs:     []string{"article", "article", "article"},
+			ids:      []int64{1, 2, 1},
+			visitors: []string{"u:1", "u:1", "u:1"},
+		},
+		{
+			name: "数据库失败不记访客",
+			mock: func(ctrl *gomock.Controller) (dao.InteractiveDAO, cache.InteractiveCache) {
+				d := daomocks.NewMockInteractiveDAO(ctrl)
+				c := cachemocks.NewMockInteractiveCache(ctrl)
+				c.EXPECT().CheckVisitors(gomock.Any(), []string{"article"}, []int64{1}, []string{"u:1"}).
+					Return([]bool{true}, nil)
+				d.EXPECT().BatchIncrUvCnt(gomock.Any(), []string{"article"}, []int64{1}).
+					Return(errors.New("db 错误"))
+				return d, c
+			},
+			bizs:     []string{"article"},
+			ids:      []int64{1},
+			visitors: []string{"u:1"},
+			wantErr:  errors.New("db 错误"),
+		},
+		{
+			name: "记访客失败不返回错误",
+			mock: func(ctrl *gomock.Controller) (dao.InteractiveDAO, cache.InteractiveCache) {
+				d := daomocks.NewMockInteractiveDAO(ctrl)
+				c := cachemocks.NewMockInteractiveCache(ctrl)
+				c.EXPECT().CheckVisitors(gomock.Any(), []string{"article"}, []int64{1}, []string{"u:1"}).
+					Return([]bool{true}, nil)
+				d.EXPECT().BatchIncrUvCnt(gomock.Any(), []string{"article"}, []int64{1}).Return(nil)
+				c.EXPECT().AddVisitors(gomock.Any(), []string{"article"}, []int64{1}, []string{"u:1"}).
+					Return(errors.New("redis 错误"))
+				c.EXPECT().IncrUvCntIfPresent(gomock.Any(), "article", int64(1)).Return(nil)
+				return d, c
+			},
+			bizs:     []string{"article"},
+			ids:      []int64{1},
+			visitors: []string{"u:1"},
+		},
+		{
+			name: "都是老访客",
+			mock: func(ctrl *gomock.Controller) (dao.InteractiveDAO, cache.InteractiveCache) {
+				d := daomocks.NewMockInteractiveDAO(ctrl)
+				c := cachemocks.NewMockInteractiveCache(ctrl)
+				c.EXPECT().CheckVisitors(gomock.Any(), []string{"article"}, []int64{1}, []string{"u:1"}).
+					Return([]bool{false}, nil)
+				return d, c
+			},
+			bizs:     []string{"article"},
+			ids:      []int64{1},
+			visitors: []string{"u:1"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			d, c := tc.mock(ctrl)
+			repo := NewCachedInteractiveRepository(d, c, logger.NewNopLogger())
+			err := repo.BatchIncrUvCnt(context.Background(), tc.bizs, tc.ids, tc.visitors)
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
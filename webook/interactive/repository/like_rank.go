@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"time"
+	"xiaoweishu/webook/interactive/domain"
+	"xiaoweishu/webook/interactive/repository/cache"
+	"xiaoweishu/webook/interactive/repository/dao"
+)
+
+// likeRankLoadBatch 重建的时候一次从数据库捞多少行
+const likeRankLoadBatch = 1000
+
+type LikeRankRepository interface {
+	BatchIncr(ctx context.Context, deltas []domain.LikeRankDelta) error
+	Top(ctx context.Context, biz string, w domain.LikeRankWindow, t time.Time, offset, limit int) ([]domain.LikeRankItem, error)
+	// Rebuild 从数据库重新算 t 所在窗口的榜单，总榜用 interactives，
+	// 按天和按周的用 user_like_bizs
+	Rebuild(ctx context.Context, biz string, w domain.LikeRankWindow, t time.Time) error
+}
+
+type CachedLikeRankRepository struct {
+	dao   dao.LikeRankDAO
+	cache cache.LikeRankCache
+}
+
+func NewCachedLikeRankRepository(dao dao.LikeRankDAO, cache cache.LikeRankCache) LikeRankRepository {
+	return &CachedLikeRankRepository{
+		dao:   dao,
+		cache: cache,
+	}
+}
+
+func (r *CachedLikeRankRepository) BatchIncr(ctx context.Context, deltas []domain.LikeRankDelta) error {
+	return r.cache.BatchIncr(ctx, deltas)
+}
+
+func (r *CachedLikeRankRepository) Top(ctx context.Context, biz string, w domain.LikeRankWindow, t time.Time, offset, limit int) ([]domain.LikeRankItem, error) {
+	return r.cache.Top(ctx, biz, w, t, offset, limit)
+}
+
+// Rebuild 从数据库加载之前先开始记录增量，加载期间的点赞替换完榜单之后重放，不会丢。
+// 加载的时候已经读到的点赞会重放两次，误差只限于加载的这一小段时间
+func (r *CachedLikeRankRepository) Rebuild(ctx context.Context, biz string, w domain.LikeRankWindow, t time.Time) error {
+	since := time.Now()
+	err := r.cache.BeginReplace(ctx, biz, w, t)
+	if err != nil {
+		return err
+	}
+	var items []domain.LikeRankItem
+	if w == domain.LikeRankAll {
+		items, err = r.loadAll(ctx, biz)
+	} else {
+		start, end := w.Range(t)
+		items, err = r.loadBetween(ctx, biz, start, end)
+	}
+	if err != nil {
+		return err
+	}
+	return r.cache.Replace(ctx, biz, w, t, items, since)
+}
+
+func (r *CachedLikeRankRepository) loadAll(ctx context.Context, biz string) ([]domain.LikeRankItem, error) {
+	var res []domain.LikeRankItem
+	var minId int64
+	for {
+		intrs, err := r.dao.ListLikeCnt(ctx, biz, minId, likeRankLoadBatch)
+		if err != nil {
+			return nil, err
+		}
+		for _, intr := range intrs {
+			res = append(res, domain.LikeRankItem{Biz: biz, BizId: intr.BizId, LikeCnt: intr.LikeCnt})
+		}
+		if len(intrs) < likeRankLoadBatch {
+			return res, nil
+		}
+		minId = intrs[len(intrs)-1].Id
+	}
+}
+
+func (r *CachedLikeRankRepository) loadBetween(ctx context.Context, biz string, start, end time.Time) ([]domain.LikeRankItem, error) {
+	var res []domain.LikeRankItem
+	var minBizId int64
+	for {
+		cnts, err := r.dao.CountLikesBetween(ctx, biz, start.UnixMilli(), end.UnixMilli(), minBizId, likeRankLoadBatch)
+		if err != nil {
+			return nil, err
+		}
+		for _, cnt := range cnts {
+			res = append(res, domain.LikeRankItem{Biz: biz, BizId: cnt.BizId, LikeCnt: cnt.Cnt})
+		}
+		if len(cnts) < likeRankLoadBatch {
+			return res, nil
+		}
+		minBizId = cnts[len(cnts)-1].BizId
+	}
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+	"xiaoweishu/webook/interactive/domain"
+	"xiaoweishu/webook/interactive/repository/cache"
+	cachemocks "xiaoweishu/webook/interactive/repository/cache/mocks"
+	"xiaoweishu/webook/interactive/repository/dao"
+	daomocks "xiaoweishu/webook/interactive/repository/dao/mocks"
+)
+
+func TestCachedLikeRankRepository_Rebuild(t *testing.T) {
+	now := time.Now()
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (dao.LikeRankDAO, cache.LikeRankCache)
+		w    domain.LikeRankWindow
+
+		wantErr error
+	}{
+		{
+			name: "先开始记录增量再加载",
+			mock: func(ctrl *gomock.Controller) (dao.LikeRankDAO, cache.LikeRankCache) {
+				d := daomocks.NewMockLikeRankDAO(ctrl)
+				c := cachemocks.NewMockLikeRankCache(ctrl)
+				begin := c.EXPECT().BeginReplace(gomock.Any(), "article", domain.LikeRankAll, now).Return(nil)
+				load := d.EXPECT().ListLikeCnt(gomock.Any(), "article", int64(0), likeRankLoadBatch).
+					Return([]dao.Interactive{{Id: 1, BizId: 11, LikeCnt: 3}}, nil).After(begin)
+				c.EXPECT().Replace(gomock.Any(), "article", domain.LikeRankAll, now,
+					[]domain.LikeRankItem{{Biz: "article", BizId: 11, LikeCnt: 3}},
+					gomock.Cond(func(x any) bool {
+						// 开始记录增量的时间不能晚于开始加载的时间
+						return !x.(time.Time).After(time.Now())
+					})).Return(nil).After(load)
+				return d, c
+			},
+			w: domain.LikeRankAll,
+		},
+		{
+			name: "开始重建失败",
+			mock: func(ctrl *gomock.Controller) (dao.LikeRankDAO, cache.LikeRankCache) {
+				d := daomocks.NewMockLikeRankDAO(ctrl)
+				c := cachemocks.NewMockLikeRankCache(ctrl)
+				c.EXPECT().BeginReplace(gomock.Any(), "article", domain.LikeRankDay, now).
+					Return(errors.New("redis 错误"))
+				return d, c
+			},
+			w:       domain.LikeRankDay,
+			wantErr: errors.New("redis 错误"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			d, c := tc.mock(ctrl)
+			repo := NewCachedLikeRankRepository(d, c)
+			err := repo.Rebuild(context.Background(), "article", tc.w, now)
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
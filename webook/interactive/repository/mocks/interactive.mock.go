@@ -0,0 +1,274 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./interactive.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./interactive.go -package=repomocks -destination=mocks/interactive.mock.go InteractiveRepository
+//
+
+// Package repomocks is a generated GoMock package.
+package repomocks
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+	domain "xiaoweishu/webook/interactive/domain"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockInteractiveRepository is a mock of InteractiveRepository interface.
+type MockInteractiveRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockInteractiveRepositoryMockRecorder
+}
+
+// MockInteractiveRepositoryMockRecorder is the mock recorder for MockInteractiveRepository.
+type MockInteractiveRepositoryMockRecorder struct {
+	mock *MockInteractiveRepository
+}
+
+// NewMockInteractiveRepository creates a new mock instance.
+func NewMockInteractiveRepository(ctrl *gomock.Controller) *MockInteractiveRepository {
+	mock := &MockInteractiveRepository{ctrl: ctrl}
+	mock.recorder = &MockInteractiveRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockInteractiveRepository) EXPECT() *MockInteractiveRepositoryMockRecorder {
+	return m.recorder
+}
+
+// AddCollectionItem mocks base method.
+func (m *MockInteractiveRepository) AddCollectionItem(ctx context.Context, biz string, id, cid, uid int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddCollectionItem", ctx, biz, id, cid, uid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddCollectionItem indicates an expected call of AddCollectionItem.
+func (mr *MockInteractiveRepositoryMockRecorder) AddCollectionItem(ctx, biz, id, cid, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddCollectionItem", reflect.TypeOf((*MockInteractiveRepository)(nil).AddCollectionItem), ctx, biz, id, cid, uid)
+}
+
+// BatchAddCnt mocks base method.
+func (m *MockInteractiveRepository) BatchAddCnt(ctx context.Context, deltas []domain.CntDelta) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BatchAddCnt", ctx, deltas)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// BatchAddCnt indicates an expected call of BatchAddCnt.
+func (mr *MockInteractiveRepositoryMockRecorder) BatchAddCnt(ctx, deltas any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BatchAddCnt", reflect.TypeOf((*MockInteractiveRepository)(nil).BatchAddCnt), ctx, deltas)
+}
+
+// BatchIncrReadCnt mocks base method.
+func (m *MockInteractiveRepository) BatchIncrReadCnt(ctx context.Context, bizs []string, ids []int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BatchIncrReadCnt", ctx, bizs, ids)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// BatchIncrReadCnt indicates an expected call of BatchIncrReadCnt.
+func (mr *MockInteractiveRepositoryMockRecorder) BatchIncrReadCnt(ctx, bizs, ids any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BatchIncrReadCnt", reflect.TypeOf((*MockInteractiveRepository)(nil).BatchIncrReadCnt), ctx, bizs, ids)
+}
+
+// BatchIncrUvCnt mocks base method.
+func (m *MockInteractiveRepository) BatchIncrUvCnt(ctx context.Context, bizs []string, ids []int64, visitors []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BatchIncrUvCnt", ctx, bizs, ids, visitors)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// BatchIncrUvCnt indicates an expected call of BatchIncrUvCnt.
+func (mr *MockInteractiveRepositoryMockRecorder) BatchIncrUvCnt(ctx, bizs, ids, visitors any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BatchIncrUvCnt", reflect.TypeOf((*MockInteractiveRepository)(nil).BatchIncrUvCnt), ctx, bizs, ids, visitors)
+}
+
+// Collected mocks base method.
+func (m *MockInteractiveRepository) Collected(ctx context.Context, biz string, id, uid int64) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Collected", ctx, biz, id, uid)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Collected indicates an expected call of Collected.
+func (mr *MockInteractiveRepositoryMockRecorder) Collected(ctx, biz, id, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Collected", reflect.TypeOf((*MockInteractiveRepository)(nil).Collected), ctx, biz, id, uid)
+}
+
+// DecrLike mocks base method.
+func (m *MockInteractiveRepository) DecrLike(ctx context.Context, biz string, id, uid int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DecrLike", ctx, biz, id, uid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DecrLike indicates an expected call of DecrLike.
+func (mr *MockInteractiveRepositoryMockRecorder) DecrLike(ctx, biz, id, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DecrLike", reflect.TypeOf((*MockInteractiveRepository)(nil).DecrLike), ctx, biz, id, uid)
+}
+
+// Get mocks base method.
+func (m *MockInteractiveRepository) Get(ctx context.Context, biz string, id int64) (domain.Interactive, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", ctx, biz, id)
+	ret0, _ := ret[0].(domain.Interactive)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockInteractiveRepositoryMockRecorder) Get(ctx, biz, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockInteractiveRepository)(nil).Get), ctx, biz, id)
+}
+
+// GetByIds mocks base method.
+func (m *MockInteractiveRepository) GetByIds(ctx context.Context, biz string, ids []int64) ([]domain.Interactive, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByIds", ctx, biz, ids)
+	ret0, _ := ret[0].([]domain.Interactive)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByIds indicates an expected call of GetByIds.
+func (mr *MockInteractiveRepositoryMockRecorder) GetByIds(ctx, biz, ids any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByIds", reflect.TypeOf((*MockInteractiveRepository)(nil).GetByIds), ctx, biz, ids)
+}
+
+// IncrLike mocks base method.
+func (m *MockInteractiveRepository) IncrLike(ctx context.Context, biz string, id, uid int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IncrLike", ctx, biz, id, uid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// IncrLike indicates an expected call of IncrLike.
+func (mr *MockInteractiveRepositoryMockRecorder) IncrLike(ctx, biz, id, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncrLike", reflect.TypeOf((*MockInteractiveRepository)(nil).IncrLike), ctx, biz, id, uid)
+}
+
+// IncrReadCnt mocks base method.
+func (m *MockInteractiveRepository) IncrReadCnt(ctx context.Context, biz string, bizId int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IncrReadCnt", ctx, biz, bizId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// IncrReadCnt indicates an expected call of IncrReadCnt.
+func (mr *MockInteractiveRepositoryMockRecorder) IncrReadCnt(ctx, biz, bizId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncrReadCnt", reflect.TypeOf((*MockInteractiveRepository)(nil).IncrReadCnt), ctx, biz, bizId)
+}
+
+// Liked mocks base method.
+func (m *MockInteractiveRepository) Liked(ctx context.Context, biz string, id, uid int64) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Liked", ctx, biz, id, uid)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Liked indicates an expected call of Liked.
+func (mr *MockInteractiveRepositoryMockRecorder) Liked(ctx, biz, id, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Liked", reflect.TypeOf((*MockInteractiveRepository)(nil).Liked), ctx, biz, id, uid)
+}
+
+// Reaction mocks base method.
+func (m *MockInteractiveRepository) Reaction(ctx context.Context, biz string, id, uid int64) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Reaction", ctx, biz, id, uid)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Reaction indicates an expected call of Reaction.
+func (mr *MockInteractiveRepositoryMockRecorder) Reaction(ctx, biz, id, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reaction", reflect.TypeOf((*MockInteractiveRepository)(nil).Reaction), ctx, biz, id, uid)
+}
+
+// ReconcileLikeCnt mocks base method.
+func (m *MockInteractiveRepository) ReconcileLikeCnt(ctx context.Context, biz string, since time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReconcileLikeCnt", ctx, biz, since)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReconcileLikeCnt indicates an expected call of ReconcileLikeCnt.
+func (mr *MockInteractiveRepositoryMockRecorder) ReconcileLikeCnt(ctx, biz, since any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReconcileLikeCnt", reflect.TypeOf((*MockInteractiveRepository)(nil).ReconcileLikeCnt), ctx, biz, since)
+}
+
+// RemoveReaction mocks base method.
+func (m *MockInteractiveRepository) RemoveReaction(ctx context.Context, biz string, id, uid int64, reaction string) (domain.UserReaction, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveReaction", ctx, biz, id, uid, reaction)
+	ret0, _ := ret[0].(domain.UserReaction)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RemoveReaction indicates an expected call of RemoveReaction.
+func (mr *MockInteractiveRepositoryMockRecorder) RemoveReaction(ctx, biz, id, uid, reaction any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveReaction", reflect.TypeOf((*MockInteractiveRepository)(nil).RemoveReaction), ctx, biz, id, uid, reaction)
+}
+
+// SetLiked mocks base method.
+func (m *MockInteractiveRepository) SetLiked(ctx context.Context, biz string, id, uid int64, liked bool) (domain.UserReaction, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetLiked", ctx, biz, id, uid, liked)
+	ret0, _ := ret[0].(domain.UserReaction)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetLiked indicates an expected call of SetLiked.
+func (mr *MockInteractiveRepositoryMockRecorder) SetLiked(ctx, biz, id, uid, liked any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetLiked", reflect.TypeOf((*MockInteractiveRepository)(nil).SetLiked), ctx, biz, id, uid, liked)
+}
+
+// SetReaction mocks base method.
+func (m *MockInteractiveRepository) SetReaction(ctx context.Context, biz string, id, uid int64, reaction string) (domain.UserReaction, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetReaction", ctx, biz, id, uid, reaction)
+	ret0, _ := ret[0].(domain.UserReaction)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetReaction indicates an expected call of SetReaction.
+func (mr *MockInteractiveRepositoryMockRecorder) SetReaction(ctx, biz, id, uid, reaction any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetReaction", reflect.TypeOf((*MockInteractiveRepository)(nil).SetReaction), ctx, biz, id, uid, reaction)
+}
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"github.com/ecodeclub/ekit/slice"
+	"time"
+	"xiaoweishu/webook/interactive/domain"
+	"xiaoweishu/webook/interactive/repository/dao"
+)
+
+type InteractiveStatsRepository interface {
+	// AddDeltas 每个增量同时计入按小时和按天的桶
+	AddDeltas(ctx context.Context, deltas []domain.StatsDelta) error
+	// Trend 返回 [start, end) 之间有数据的桶，ids 的数据会加在一起
+	Trend(ctx context.Context, biz string, ids []int64, g domain.StatsGranularity, start, end time.Time) ([]domain.StatsBucket, error)
+	Top(ctx context.Context, biz string, ids []int64, g domain.StatsGranularity, start, end time.Time, limit int) ([]domain.StatsItem, error)
+	// DeleteBefore 删除过期的桶，返回删除的行数
+	DeleteBefore(ctx context.Context, g domain.StatsGranularity, before time.Time, limit int) (int64, error)
+}
+
+type GORMInteractiveStatsRepository struct {
+	dao dao.InteractiveStatsDAO
+}
+
+func NewGORMInteractiveStatsRepository(dao dao.InteractiveStatsDAO) InteractiveStatsRepository {
+	return &GORMInteractiveStatsRepository{dao: dao}
+}
+
+func (r *GORMInteractiveStatsRepository) AddDeltas(ctx context.Context, deltas []domain.StatsDelta) error {
+	type key struct {
+		biz         string
+		bizId       int64
+		granularity domain.StatsGranularity
+		bucket      int64
+	}
+	//同一个桶的增量先合并，一个桶只写一行
+	merged := make(map[key]*dao.InteractiveStat, len(deltas)*2)
+	stats := make([]*dao.InteractiveStat, 0, len(deltas)*2)
+	for _, d := range deltas {
+		for _, g := range []domain.StatsGranularity{domain.StatsHour, domain.StatsDay} {
+			k := key{biz: d.Biz, bizId: d.BizId, granularity: g,
+				bucket: g.Truncate(d.Time).UnixMilli()}
+			s, ok := merged[k]
+			if !ok {
+				s = &dao.InteractiveStat{
+					Biz:         d.Biz,
+					BizId:       d.BizId,
+					Granularity: string(g),
+					Bucket:      k.bucket,
+				}
+				merged[k] = s
+				stats = append(stats, s)
+			}
+			s.ReadCnt += d.ReadCnt
+			s.LikeCnt += d.LikeCnt
+			s.CollectCnt += d.CollectCnt
+		}
+	}
+	return r.dao.BatchUpsert(ctx, slice.Map(stats, func(idx int, src *dao.InteractiveStat) dao.InteractiveStat {
+		return *src
+	}))
+}
+
+func (r *GORMInteractiveStatsRepository) Trend(ctx context.Context, biz string, ids []int64, g domain.StatsGranularity, start, end time.Time) ([]domain.StatsBucket, error) {
+	sums, err := r.dao.SumByBucket(ctx, biz, ids, string(g), start.UnixMilli(), end.UnixMilli())
+	if err != nil {
+		return nil, err
+	}
+	return slice.Map(sums, func(idx int, src dao.StatsSum) domain.StatsBucket {
+		return domain.StatsBucket{
+			Time:       time.UnixMilli(src.Bucket),
+			ReadCnt:    src.ReadCnt,
+			LikeCnt:    src.LikeCnt,
+			CollectCnt: src.CollectCnt,
+		}
+	}), nil
+}
+
+func (r *GORMInteractiveStatsRepository) Top(ctx context.Context, biz string, ids []int64, g domain.StatsGranularity, start, end time.Time, limit int) ([]domain.StatsItem, error) {
+	sums, err := r.dao.TopByTotal(ctx, biz, ids, string(g), start.UnixMilli(), end.UnixMilli(), limit)
+	if err != nil {
+		return nil, err
+	}
+	return slice.Map(sums, func(idx int, src dao.StatsSum) domain.StatsItem {
+		return domain.StatsItem{
+			Biz:        biz,
+			BizId:      src.BizId,
+			ReadCnt:    src.ReadCnt,
+			LikeCnt:    src.LikeCnt,
+			CollectCnt: src.CollectCnt,
+		}
+	}), nil
+}
+
+func (r *GORMInteractiveStatsRepository) DeleteBefore(ctx context.Context, g domain.StatsGranularity, before time.Time, limit int) (int64, error) {
+	return r.dao.DeleteBefore(ctx, string(g), before.UnixMilli(), limit)
+}
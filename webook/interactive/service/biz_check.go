@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"time"
+	"xiaoweishu/webook/interactive/biz"
+	"xiaoweishu/webook/interactive/domain"
+)
+
+// BizCheckedInteractiveService 装饰器，在真正执行之前校验 biz、操作类型、限流和资源是否存在
+type BizCheckedInteractiveService struct {
+	InteractiveService
+	registry *biz.Registry
+}
+
+func NewBizCheckedInteractiveService(svc InteractiveService, registry *biz.Registry) InteractiveService {
+	return &BizCheckedInteractiveService{
+		InteractiveService: svc,
+		registry:           registry,
+	}
+}
+
+func (b *BizCheckedInteractiveService) IncrReadCnt(ctx context.Context, bizName string, bizId int64) error {
+	err := b.registry.Check(ctx, bizName, bizId, 0, biz.ActionRead)
+	if err != nil {
+		return err
+	}
+	return b.InteractiveService.IncrReadCnt(ctx, bizName, bizId)
+}
+
+func (b *BizCheckedInteractiveService) Like(ctx context.Context, bizName string, id int64, uid int64) error {
+	err := b.registry.Check(ctx, bizName, id, uid, biz.ActionLike)
+	if err != nil {
+		return err
+	}
+	return b.InteractiveService.Like(ctx, bizName, id, uid)
+}
+
+// CancelLike 取消点赞不限流，也不检查资源是否存在，资源删了也要能取消
+func (b *BizCheckedInteractiveService) CancelLike(ctx context.Context, bizName string, id int64, uid int64) error {
+	err := b.registry.Validate(bizName)
+	if err != nil {
+		return err
+	}
+	return b.InteractiveService.CancelLike(ctx, bizName, id, uid)
+}
+
+func (b *BizCheckedInteractiveService) Collect(ctx context.Context, bizName string, bizId, cid, uid int64) error {
+	err := b.registry.Check(ctx, bizName, bizId, uid, biz.ActionCollect)
+	if err != nil {
+		return err
+	}
+	return b.InteractiveService.Collect(ctx, bizName, bizId, cid, uid)
+}
+
+func (b *BizCheckedInteractiveService) Get(ctx context.Context, bizName string, id int64, uid int64) (domain.Interactive, error) {
+	err := b.registry.Validate(bizName)
+	if err != nil {
+		return domain.Interactive{}, err
+	}
+	return b.InteractiveService.Get(ctx, bizName, id, uid)
+}
+
+func (b *BizCheckedInteractiveService) GetByIds(ctx context.Context, bizName string, ids []int64) (map[int64]domain.Interactive, error) {
+	err := b.registry.Validate(bizName)
+	if err != nil {
+		return nil, err
+	}
+	return b.InteractiveService.GetByIds(ctx, bizName, ids)
+}
+
+func (b *BizCheckedInteractiveService) ReconcileLikeCnt(ctx context.Context, bizName string, since time.Time) error {
+	err := b.registry.Validate(bizName)
+	if err != nil {
+		return err
+	}
+	return b.InteractiveService.ReconcileLikeCnt(ctx, bizName, since)
+}
+
+func (b *BizCheckedInteractiveService) React(ctx context.Context, bizName string, id int64, uid int64, reaction string) error {
+	//表态和点赞共用一个开关和限流
+	err := b.registry.Check(ctx, bizName, id, uid, biz.ActionLike)
+	if err != nil {
+		return err
+	}
+	return b.InteractiveService.React(ctx, bizName, id, uid, reaction)
+}
+
+func (b *BizCheckedInteractiveService) CancelReaction(ctx context.Context, bizName string, id int64, uid int64) error {
+	err := b.registry.Validate(bizName)
+	if err != nil {
+		return err
+	}
+	return b.InteractiveService.CancelReaction(ctx, bizName, id, uid)
+}
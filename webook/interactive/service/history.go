@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"xiaoweishu/webook/interactive/domain"
+	"xiaoweishu/webook/interactive/repository"
+	"xiaoweishu/webook/pkg/logger"
+)
+
+type ReadHistoryService interface {
+	// Record 记录一批阅读行为，由消费者调用
+	Record(ctx context.Context, hs []domain.ReadHistory) error
+	List(ctx context.Context, uid int64, offset int, limit int) ([]domain.ReadHistory, error)
+	Delete(ctx context.Context, uid int64, biz string, bizId int64) error
+	Clear(ctx context.Context, uid int64) error
+	// Pause 暂停或者恢复记录阅读历史，暂停期间的阅读不会被记录
+	Pause(ctx context.Context, uid int64, paused bool) error
+	Paused(ctx context.Context, uid int64) (bool, error)
+}
+
+type readHistoryService struct {
+	repo repository.ReadHistoryRepository
+	l    logger.LoggerV1
+	// 每个用户最多保留多少条阅读历史
+	maxCnt int
+}
+
+func NewReadHistoryService(repo repository.ReadHistoryRepository, l logger.LoggerV1) ReadHistoryService {
+	return &readHistoryService{
+		repo:   repo,
+		l:      l,
+		maxCnt: 100,
+	}
+}
+
+func (r *readHistoryService) Record(ctx context.Context, hs []domain.ReadHistory) error {
+	err := r.repo.AddRecords(ctx, hs)
+	if err != nil {
+		return err
+	}
+	//写入之后再把超出上限的老记录删掉
+	uids := make(map[int64]struct{}, len(hs))
+	for _, h := range hs {
+		uids[h.Uid] = struct{}{}
+	}
+	for uid := range uids {
+		er := r.repo.Trim(ctx, uid, r.maxCnt)
+		if er != nil {
+			//删不掉问题也不大，下一次记录的时候还会再删
+			r.l.Error("清理多余的阅读历史失败",
+				logger.Int64("uid", uid),
+				logger.Error(er))
+		}
+	}
+	return nil
+}
+
+func (r *readHistoryService) List(ctx context.Context, uid int64, offset int, limit int) ([]domain.ReadHistory, error) {
+	return r.repo.List(ctx, uid, offset, limit)
+}
+
+func (r *readHistoryService) Delete(ctx context.Context, uid int64, biz string, bizId int64) error {
+	return r.repo.Delete(ctx, uid, biz, bizId)
+}
+
+func (r *readHistoryService) Clear(ctx context.Context, uid int64) error {
+	return r.repo.Clear(ctx, uid)
+}
+
+func (r *readHistoryService) Pause(ctx context.Context, uid int64, paused bool) error {
+	return r.repo.SetPaused(ctx, uid, paused)
+}
+
+func (r *readHistoryService) Paused(ctx context.Context, uid int64) (bool, error) {
+	return r.repo.Paused(ctx, uid)
+}
@@ -2,8 +2,11 @@ package service
 
 import (
 	"context"
+	"errors"
 	"golang.org/x/sync/errgroup"
+	"time"
 	"xiaoweishu/webook/interactive/domain"
+	"xiaoweishu/webook/interactive/events/intr"
 	"xiaoweishu/webook/interactive/repository"
 	"xiaoweishu/webook/pkg/logger"
 )
@@ -15,11 +18,22 @@ type InteractiveService interface {
 	Collect(ctx context.Context, biz string, bizId, cid, uid int64) error
 	Get(ctx context.Context, biz string, id int64, uid int64) (domain.Interactive, error)
 	GetByIds(ctx context.Context, biz string, ids []int64) (map[int64]domain.Interactive, error)
+	// ReconcileLikeCnt 用 user_like_bizs 修正 since 之后有变动的点赞数
+	ReconcileLikeCnt(ctx context.Context, biz string, since time.Time) error
+	// React 表态，已经有别的表态的话就是切换，点赞就是 domain.ReactionLike
+	React(ctx context.Context, biz string, id int64, uid int64, reaction string) error
+	CancelReaction(ctx context.Context, biz string, id int64, uid int64) error
 }
 
+var ErrInvalidReaction = errors.New("不支持的表态")
+
 type interactiveService struct {
 	repo repository.InteractiveRepository
-	l    logger.LoggerV1
+	// producer 不为 nil 的时候点赞和收藏都会发事件，给写回和统计这些下游用
+	producer intr.Producer
+	// writeBehind 写回模式，点赞只改状态，计数由消费者攒批写回
+	writeBehind bool
+	l           logger.LoggerV1
 }
 
 func (i interactiveService) GetByIds(ctx context.Context, biz string, ids []int64) (map[int64]domain.Interactive, error) {
@@ -46,26 +60,164 @@ func (i interactiveService) IncrReadCnt(ctx context.Context, biz string, bizId i
 }
 
 func (i interactiveService) Like(ctx context.Context, biz string, id int64, uid int64) error {
-	err := i.repo.IncrLike(ctx, biz, id, uid)
-	if err != nil {
+	if i.writeBehind {
+		return i.setLiked(ctx, biz, id, uid, true)
+	}
+	//点赞就是 👍 这种表态
+	old, err := i.repo.SetReaction(ctx, biz, id, uid, domain.ReactionLike)
+	if err != nil || old.Reaction == domain.ReactionLike {
 		return err
 	}
+	i.produceLikeEvent(biz, id, uid, true, true, time.Time{})
 	return nil
 }
 
 func (i interactiveService) CancelLike(ctx context.Context, biz string, id int64, uid int64) error {
-	err := i.repo.DecrLike(ctx, biz, id, uid)
+	if i.writeBehind {
+		return i.setLiked(ctx, biz, id, uid, false)
+	}
+	//只有当前是 👍 才取消，别的表态不受影响
+	removed, err := i.repo.RemoveReaction(ctx, biz, id, uid, domain.ReactionLike)
+	if err != nil || removed.Reaction == "" {
+		return err
+	}
+	i.produceLikeEvent(biz, id, uid, false, true, removed.Utime)
+	return nil
+}
+
+func (i interactiveService) React(ctx context.Context, biz string, id int64, uid int64, reaction string) error {
+	if !domain.ValidReaction(reaction) {
+		return ErrInvalidReaction
+	}
+	if reaction == domain.ReactionLike {
+		return i.Like(ctx, biz, id, uid)
+	}
+	old, err := i.repo.SetReaction(ctx, biz, id, uid, reaction)
+	if err != nil {
+		return err
+	}
+	//从 👍 切换到别的表态，点赞数已经在事务里面减掉了
+	if old.Reaction == domain.ReactionLike {
+		i.produceLikeEvent(biz, id, uid, false, true, old.Utime)
+	}
+	return nil
+}
+
+func (i interactiveService) CancelReaction(ctx context.Context, biz string, id int64, uid int64) error {
+	if i.writeBehind {
+		cur, err := i.repo.Reaction(ctx, biz, id, uid)
+		if err != nil {
+			return err
+		}
+		//写回模式下 👍 的计数要走点赞事件
+		if cur == domain.ReactionLike {
+			return i.setLiked(ctx, biz, id, uid, false)
+		}
+	}
+	removed, err := i.repo.RemoveReaction(ctx, biz, id, uid, "")
 	if err != nil {
 		return err
 	}
+	if removed.Reaction == domain.ReactionLike {
+		i.produceLikeEvent(biz, id, uid, false, true, removed.Utime)
+	}
 	return nil
 }
 
+// setLiked 写回模式下的点赞，不再去碰 interactives 这一行热点数据
+func (i interactiveService) setLiked(ctx context.Context, biz string, id int64, uid int64, liked bool) error {
+	if liked {
+		//一个人只能有一种表态，从别的表态切换到 👍 的时候先撤销之前的
+		cur, err := i.repo.Reaction(ctx, biz, id, uid)
+		if err != nil {
+			return err
+		}
+		if cur != "" && cur != domain.ReactionLike {
+			_, err = i.repo.RemoveReaction(ctx, biz, id, uid, cur)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	old, err := i.repo.SetLiked(ctx, biz, id, uid, liked)
+	if err != nil || (old.Reaction == domain.ReactionLike) == liked {
+		//重复点赞或者重复取消，计数不用动
+		return err
+	}
+	//计数全靠这个事件，发不出去就把状态改回去，让用户重试
+	err = i.produceLikeEvent(biz, id, uid, liked, false, old.Utime)
+	if err != nil {
+		_, er := i.repo.SetLiked(ctx, biz, id, uid, !liked)
+		if er != nil {
+			//回滚也失败了，只能等对账的时候修正
+			i.l.Error("回滚点赞状态失败",
+				logger.String("biz", biz),
+				logger.Int64("bizId", id),
+				logger.Int64("uid", uid),
+				logger.Error(er))
+		}
+		return err
+	}
+	return nil
+}
+
+// produceLikeEvent counted 表示点赞数是否已经同步更新过了，
+// likeTime 是取消点赞的时候原来点赞的时间。
+// 计数已经更新过的事件只给统计这些下游用，发送失败只记日志，返回 nil
+func (i interactiveService) produceLikeEvent(biz string, id int64, uid int64, liked bool, counted bool, likeTime time.Time) error {
+	if i.producer == nil {
+		return nil
+	}
+	evt := intr.LikeEvent{
+		Biz:     biz,
+		BizId:   id,
+		Uid:     uid,
+		Liked:   liked,
+		Counted: counted,
+		Ctime:   time.Now().UnixMilli(),
+	}
+	if !liked && !likeTime.IsZero() {
+		evt.LikeTime = likeTime.UnixMilli()
+	}
+	err := i.producer.ProduceLikeEvent(evt)
+	if err != nil {
+		i.l.Error("发送点赞事件失败",
+			logger.String("biz", biz),
+			logger.Int64("bizId", id),
+			logger.Int64("uid", uid),
+			logger.Error(err))
+		if counted {
+			return nil
+		}
+	}
+	return err
+}
+
+func (i interactiveService) ReconcileLikeCnt(ctx context.Context, biz string, since time.Time) error {
+	return i.repo.ReconcileLikeCnt(ctx, biz, since)
+}
+
 func (i interactiveService) Collect(ctx context.Context, biz string, bizId, cid, uid int64) error {
 	err := i.repo.AddCollectionItem(ctx, biz, bizId, cid, uid)
 	if err != nil {
 		return err
 	}
+	if i.producer != nil {
+		er := i.producer.ProduceCollectEvent(intr.CollectEvent{
+			Biz:   biz,
+			BizId: bizId,
+			Uid:   uid,
+			Cid:   cid,
+			Ctime: time.Now().UnixMilli(),
+		})
+		if er != nil {
+			i.l.Error("发送收藏事件失败",
+				logger.String("biz", biz),
+				logger.Int64("bizId", bizId),
+				logger.Int64("uid", uid),
+				logger.Error(er))
+		}
+	}
 	return nil
 }
 
@@ -77,7 +229,8 @@ func (i interactiveService) Get(ctx context.Context, biz string, id int64, uid i
 	var eg errgroup.Group
 	eg.Go(func() error {
 		var er error
-		intr.Liked, er = i.repo.Liked(ctx, biz, id, uid)
+		intr.Reaction, er = i.repo.Reaction(ctx, biz, id, uid)
+		intr.Liked = intr.Reaction == domain.ReactionLike
 		return er
 	})
 	eg.Go(func() error {
@@ -98,3 +251,24 @@ func NewInteractiveService(repo repository.InteractiveRepository) InteractiveSer
 	}
 
 }
+
+// NewInteractiveServiceV1 点赞和收藏之后会发送事件
+func NewInteractiveServiceV1(repo repository.InteractiveRepository,
+	producer intr.Producer, l logger.LoggerV1) InteractiveService {
+	return &interactiveService{
+		repo:     repo,
+		producer: producer,
+		l:        l,
+	}
+}
+
+// NewWriteBehindInteractiveService 写回模式，计数的持久化依赖 kafka 里面的点赞和阅读事件
+func NewWriteBehindInteractiveService(repo repository.InteractiveRepository,
+	producer intr.Producer, l logger.LoggerV1) InteractiveService {
+	return &interactiveService{
+		repo:        repo,
+		producer:    producer,
+		writeBehind: true,
+		l:           l,
+	}
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+	"xiaoweishu/webook/interactive/domain"
+	"xiaoweishu/webook/interactive/events/intr"
+	evtmocks "xiaoweishu/webook/interactive/events/intr/mocks"
+	"xiaoweishu/webook/interactive/repository"
+	repomocks "xiaoweishu/webook/interactive/repository/mocks"
+	"xiaoweishu/webook/pkg/logger"
+)
+
+func TestInteractiveService_WriteBehindLike(t *testing.T) {
+	likeTime := time.UnixMilli(1700000000000)
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (repository.InteractiveRepository, intr.Producer)
+
+		liked bool
+
+		wantErr error
+	}{
+		{
+			name: "点赞成功",
+			mock: func(ctrl *gomock.Controller) (repository.InteractiveRepository, intr.Producer) {
+				repo := repomocks.NewMockInteractiveRepository(ctrl)
+				producer := evtmocks.NewMockProducer(ctrl)
+				repo.EXPECT().Reaction(gomock.Any(), "article", int64(1), int64(123)).Return("", nil)
+				repo.EXPECT().SetLiked(gomock.Any(), "article", int64(1), int64(123), true).
+					Return(domain.UserReaction{}, nil)
+				producer.EXPECT().ProduceLikeEvent(gomock.Any()).Return(nil)
+				return repo, producer
+			},
+			liked: true,
+		},
+		{
+			name: "重复点赞不发事件",
+			mock: func(ctrl *gomock.Controller) (repository.InteractiveRepository, intr.Producer) {
+				repo := repomocks.NewMockInteractiveRepository(ctrl)
+				producer := evtmocks.NewMockProducer(ctrl)
+				repo.EXPECT().Reaction(gomock.Any(), "article", int64(1), int64(123)).
+					Return(domain.ReactionLike, nil)
+				repo.EXPECT().SetLiked(gomock.Any(), "article", int64(1), int64(123), true).
+					Return(domain.UserReaction{Reaction: domain.ReactionLike}, nil)
+				return repo, producer
+			},
+			liked: true,
+		},
+		{
+			name: "点赞事件发送失败，回滚",
+			mock: func(ctrl *gomock.Controller) (repository.InteractiveRepository, intr.Producer) {
+				repo := repomocks.NewMockInteractiveRepository(ctrl)
+				producer := evtmocks.NewMockProducer(ctrl)
+				repo.EXPECT().Reaction(gomock.Any(), "article", int64(1), int64(123)).Return("", nil)
+				set := repo.EXPECT().SetLiked(gomock.Any(), "article", int64(1), int64(123), true).
+					Return(domain.UserReaction{}, nil)
+				produce := producer.EXPECT().ProduceLikeEvent(gomock.Any()).
+					Return(errors.New("kafka 错误")).After(set)
+				repo.EXPECT().SetLiked(gomock.Any(), "article", int64(1), int64(123), false).
+					Return(domain.UserReaction{Reaction: domain.ReactionLike}, nil).After(produce)
+				return repo, producer
+			},
+			liked:   true,
+			wantErr: errors.New("kafka 错误"),
+		},
+		{
+			name: "取消点赞事件发送失败，回滚",
+			mock: func(ctrl *gomock.Controller) (repository.InteractiveRepository, intr.Producer) {
+				repo := repomocks.NewMockInteractiveRepository(ctrl)
+				producer := evtmocks.NewMockProducer(ctrl)
+				set := repo.EXPECT().SetLiked(gomock.Any(), "article", int64(1), int64(123), false).
+					Return(domain.UserReaction{Reaction: domain.ReactionLike, Utime: likeTime}, nil)
+				// 取消点赞的事件要带上原来点赞的时间
+				produce := producer.EXPECT().ProduceLikeEvent(gomock.Cond(func(x any) bool {
+					evt := x.(intr.LikeEvent)
+					return !evt.Liked && !evt.Counted && evt.LikeTime == likeTime.UnixMilli()
+				})).Return(errors.New("kafka 错误")).After(set)
+				repo.EXPECT().SetLiked(gomock.Any(), "article", int64(1), int64(123), true).
+					Return(domain.UserReaction{}, nil).After(produce)
+				return repo, producer
+			},
+			wantErr: errors.New("kafka 错误"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			repo, producer := tc.mock(ctrl)
+			svc := NewWriteBehindInteractiveService(repo, producer, logger.NewNopLogger())
+			var err error
+			if tc.liked {
+				err = svc.Like(context.Background(), "article", 1, 123)
+			} else {
+				err = svc.CancelLike(context.Background(), "article", 1, 123)
+			}
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+	"xiaoweishu/webook/interactive/domain"
+	"xiaoweishu/webook/interactive/repository"
+)
+
+var ErrInvalidLikeRankQuery = errors.New("点赞榜查询参数不合法")
+
+// likeRankMaxLimit 一页最多多少个
+const likeRankMaxLimit = 100
+
+//go:generate mockgen -source=./like_rank.go -package=svcmocks -destination=mocks/like_rank.mock.go LikeRankService
+type LikeRankService interface {
+	// Record 记录一批点赞和取消点赞，由消费者调用
+	Record(ctx context.Context, deltas []domain.LikeRankDelta) error
+	// TopN 当前窗口的榜单
+	TopN(ctx context.Context, biz string, w domain.LikeRankWindow, offset, limit int) ([]domain.LikeRankItem, error)
+	// Rebuild 重建当前窗口的榜单，w 为空的时候重建所有窗口
+	Rebuild(ctx context.Context, biz string, w domain.LikeRankWindow) error
+}
+
+type likeRankService struct {
+	repo repository.LikeRankRepository
+	now  func() time.Time
+}
+
+func NewLikeRankService(repo repository.LikeRankRepository) LikeRankService {
+	return &likeRankService{
+		repo: repo,
+		now:  time.Now,
+	}
+}
+
+func (s *likeRankService) Record(ctx context.Context, deltas []domain.LikeRankDelta) error {
+	if len(deltas) == 0 {
+		return nil
+	}
+	return s.repo.BatchIncr(ctx, deltas)
+}
+
+func (s *likeRankService) TopN(ctx context.Context, biz string, w domain.LikeRankWindow, offset, limit int) ([]domain.LikeRankItem, error) {
+	if !w.Valid() || offset < 0 || limit <= 0 || limit > likeRankMaxLimit {
+		return nil, ErrInvalidLikeRankQuery
+	}
+	return s.repo.Top(ctx, biz, w, s.now(), offset, limit)
+}
+
+func (s *likeRankService) Rebuild(ctx context.Context, biz string, w domain.LikeRankWindow) error {
+	windows := domain.LikeRankWindows
+	if w != "" {
+		if !w.Valid() {
+			return ErrInvalidLikeRankQuery
+		}
+		windows = []domain.LikeRankWindow{w}
+	}
+	now := s.now()
+	for _, win := range windows {
+		err := s.repo.Rebuild(ctx, biz, win, now)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
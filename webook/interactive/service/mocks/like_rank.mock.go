@@ -0,0 +1,84 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./like_rank.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./like_rank.go -package=svcmocks -destination=mocks/like_rank.mock.go LikeRankService
+//
+
+// Package svcmocks is a generated GoMock package.
+package svcmocks
+
+import (
+	context "context"
+	reflect "reflect"
+	domain "xiaoweishu/webook/interactive/domain"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockLikeRankService is a mock of LikeRankService interface.
+type MockLikeRankService struct {
+	ctrl     *gomock.Controller
+	recorder *MockLikeRankServiceMockRecorder
+}
+
+// MockLikeRankServiceMockRecorder is the mock recorder for MockLikeRankService.
+type MockLikeRankServiceMockRecorder struct {
+	mock *MockLikeRankService
+}
+
+// NewMockLikeRankService creates a new mock instance.
+func NewMockLikeRankService(ctrl *gomock.Controller) *MockLikeRankService {
+	mock := &MockLikeRankService{ctrl: ctrl}
+	mock.recorder = &MockLikeRankServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLikeRankService) EXPECT() *MockLikeRankServiceMockRecorder {
+	return m.recorder
+}
+
+// Rebuild mocks base method.
+func (m *MockLikeRankService) Rebuild(ctx context.Context, biz string, w domain.LikeRankWindow) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Rebuild", ctx, biz, w)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Rebuild indicates an expected call of Rebuild.
+func (mr *MockLikeRankServiceMockRecorder) Rebuild(ctx, biz, w any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Rebuild", reflect.TypeOf((*MockLikeRankService)(nil).Rebuild), ctx, biz, w)
+}
+
+// Record mocks base method.
+func (m *MockLikeRankService) Record(ctx context.Context, deltas []domain.LikeRankDelta) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Record", ctx, deltas)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Record indicates an expected call of Record.
+func (mr *MockLikeRankServiceMockRecorder) Record(ctx, deltas any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Record", reflect.TypeOf((*MockLikeRankService)(nil).Record), ctx, deltas)
+}
+
+// TopN mocks base method.
+func (m *MockLikeRankService) TopN(ctx context.Context, biz string, w domain.LikeRankWindow, offset, limit int) ([]domain.LikeRankItem, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TopN", ctx, biz, w, offset, limit)
+	ret0, _ := ret[0].([]domain.LikeRankItem)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TopN indicates an expected call of TopN.
+func (mr *MockLikeRankServiceMockRecorder) TopN(ctx, biz, w, offset, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TopN", reflect.TypeOf((*MockLikeRankService)(nil).TopN), ctx, biz, w, offset, limit)
+}
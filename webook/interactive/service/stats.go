@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+	"xiaoweishu/webook/interactive/domain"
+	"xiaoweishu/webook/interactive/repository"
+	"xiaoweishu/webook/pkg/logger"
+)
+
+var ErrInvalidStatsQuery = errors.New("统计查询参数不合法")
+
+// maxStatsIds 一次最多查多少个资源的统计数据，数据库那边会分批查
+const maxStatsIds = 10000
+
+type InteractiveStatsService interface {
+	// Record 记录一批计数变化，由消费者调用
+	Record(ctx context.Context, deltas []domain.StatsDelta) error
+	// Dashboard 以 end 所在的桶为最后一个桶，往前取 periods 个桶作为这个周期，
+	// 再往前 periods 个桶是上一个周期，用来算环比
+	Dashboard(ctx context.Context, biz string, ids []int64, g domain.StatsGranularity,
+		end time.Time, periods int, topN int) (domain.StatsDashboard, error)
+	// Clean 删除超过保留时间的数据
+	Clean(ctx context.Context) error
+}
+
+type interactiveStatsService struct {
+	repo repository.InteractiveStatsRepository
+	l    logger.LoggerV1
+	// 按小时的数据只用来看最近 48 小时，多留一个周期给环比用
+	hourRetention time.Duration
+	dayRetention  time.Duration
+}
+
+func NewInteractiveStatsService(repo repository.InteractiveStatsRepository, l logger.LoggerV1) InteractiveStatsService {
+	return &interactiveStatsService{
+		repo:          repo,
+		l:             l,
+		hourRetention: time.Hour * 96,
+		dayRetention:  time.Hour * 24 * 365,
+	}
+}
+
+func (s *interactiveStatsService) Record(ctx context.Context, deltas []domain.StatsDelta) error {
+	if len(deltas) == 0 {
+		return nil
+	}
+	return s.repo.AddDeltas(ctx, deltas)
+}
+
+func (s *interactiveStatsService) Dashboard(ctx context.Context, biz string, ids []int64, g domain.StatsGranularity,
+	end time.Time, periods int, topN int) (domain.StatsDashboard, error) {
+	if !g.Valid() || periods <= 0 || len(ids) == 0 || len(ids) > maxStatsIds {
+		return domain.StatsDashboard{}, ErrInvalidStatsQuery
+	}
+	curEnd := g.Add(g.Truncate(end), 1)
+	curStart := g.Add(curEnd, -periods)
+	prevStart := g.Add(curStart, -periods)
+	//两个周期都要在保留时间以内，不然环比没有意义
+	retention := s.dayRetention
+	if g == domain.StatsHour {
+		retention = s.hourRetention
+	}
+	if curEnd.Sub(prevStart) > retention {
+		return domain.StatsDashboard{}, ErrInvalidStatsQuery
+	}
+
+	buckets, err := s.repo.Trend(ctx, biz, ids, g, prevStart, curEnd)
+	if err != nil {
+		return domain.StatsDashboard{}, err
+	}
+	var res domain.StatsDashboard
+	//没有数据的桶也要补上零，趋势图才是连续的
+	byTime := make(map[int64]domain.StatsBucket, len(buckets))
+	for _, b := range buckets {
+		byTime[b.Time.UnixMilli()] = b
+		if b.Time.Before(curStart) {
+			res.PrevTotal = addBucket(res.PrevTotal, b)
+		} else {
+			res.Total = addBucket(res.Total, b)
+		}
+	}
+	res.Trend = make([]domain.StatsBucket, 0, periods)
+	for t := curStart; t.Before(curEnd); t = g.Add(t, 1) {
+		b, ok := byTime[t.UnixMilli()]
+		if !ok {
+			b = domain.StatsBucket{Time: t}
+		}
+		res.Trend = append(res.Trend, b)
+	}
+	res.Total.Time = curStart
+	res.PrevTotal.Time = prevStart
+
+	if topN > 0 {
+		res.Top, err = s.repo.Top(ctx, biz, ids, g, curStart, curEnd, topN)
+		if err != nil {
+			return domain.StatsDashboard{}, err
+		}
+	}
+	return res, nil
+}
+
+func (s *interactiveStatsService) Clean(ctx context.Context) error {
+	now := time.Now()
+	for g, retention := range map[domain.StatsGranularity]time.Duration{
+		domain.StatsHour: s.hourRetention,
+		domain.StatsDay:  s.dayRetention,
+	} {
+		before := now.Add(-retention)
+		for {
+			cnt, err := s.repo.DeleteBefore(ctx, g, before, 1000)
+			if err != nil {
+				return err
+			}
+			if cnt < 1000 {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+func addBucket(dst domain.StatsBucket, src domain.StatsBucket) domain.StatsBucket {
+	dst.ReadCnt += src.ReadCnt
+	dst.LikeCnt += src.LikeCnt
+	dst.CollectCnt += src.CollectCnt
+	return dst
+}
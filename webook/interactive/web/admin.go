@@ -0,0 +1,92 @@
+package web
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"time"
+	"xiaoweishu/webook/interactive/domain"
+	"xiaoweishu/webook/interactive/service"
+	"xiaoweishu/webook/pkg/ginx"
+	"xiaoweishu/webook/pkg/logger"
+)
+
+// AdminHandler 交互服务的运维接口，只挂在 admin server 上，不对外暴露
+type AdminHandler struct {
+	svc     service.InteractiveService
+	rankSvc service.LikeRankService
+	l       logger.LoggerV1
+}
+
+func NewAdminHandler(svc service.InteractiveService, rankSvc service.LikeRankService, l logger.LoggerV1) *AdminHandler {
+	return &AdminHandler{
+		svc:     svc,
+		rankSvc: rankSvc,
+		l:       l,
+	}
+}
+
+func (h *AdminHandler) RegisterRoutes(server *gin.RouterGroup) {
+	server.POST("/reconcile/like", ginx.WrapBody[ReconcileLikeReq](h.ReconcileLike))
+	server.POST("/rank/like/rebuild", ginx.WrapBody[RebuildLikeRankReq](h.RebuildLikeRank))
+}
+
+type ReconcileLikeReq struct {
+	Biz string `json:"biz"`
+	// 毫秒数，只对账这个时间之后有点赞变化的资源
+	Since int64 `json:"since"`
+}
+
+// ReconcileLike 对账可能比较久，在后台跑，结果看日志
+func (h *AdminHandler) ReconcileLike(ctx *gin.Context, req ReconcileLikeReq) (ginx.Result, error) {
+	if req.Biz == "" {
+		return ginx.Result{Code: 4, Msg: "biz 不能为空"}, nil
+	}
+	since := time.UnixMilli(req.Since)
+	go func() {
+		start := time.Now()
+		err := h.svc.ReconcileLikeCnt(context.Background(), req.Biz, since)
+		if err != nil {
+			h.l.Error("点赞数对账失败",
+				logger.String("biz", req.Biz),
+				logger.Error(err))
+			return
+		}
+		h.l.Info("点赞数对账完成",
+			logger.String("biz", req.Biz),
+			logger.String("cost", time.Since(start).String()))
+	}()
+	return ginx.Result{Msg: "OK"}, nil
+}
+
+type RebuildLikeRankReq struct {
+	Biz string `json:"biz"`
+	// Window day、week 或者 all，不传就是全部重建
+	Window string `json:"window"`
+}
+
+// RebuildLikeRank 用数据库里面的数据重建当前窗口的点赞榜，榜单是整体替换的，重建期间照样可以读
+func (h *AdminHandler) RebuildLikeRank(ctx *gin.Context, req RebuildLikeRankReq) (ginx.Result, error) {
+	if req.Biz == "" {
+		return ginx.Result{Code: 4, Msg: "biz 不能为空"}, nil
+	}
+	w := domain.LikeRankWindow(req.Window)
+	if w != "" && !w.Valid() {
+		return ginx.Result{Code: 4, Msg: "不支持的窗口"}, nil
+	}
+	go func() {
+		start := time.Now()
+		err := h.rankSvc.Rebuild(context.Background(), req.Biz, w)
+		if err != nil {
+			h.l.Error("重建点赞榜失败",
+				logger.String("biz", req.Biz),
+				logger.String("window", req.Window),
+				logger.Error(err))
+			return
+		}
+		h.l.Info("重建点赞榜完成",
+			logger.String("biz", req.Biz),
+			logger.String("window", req.Window),
+			logger.String("cost", time.Since(start).String()))
+	}()
+	return ginx.Result{Msg: "OK"}, nil
+}
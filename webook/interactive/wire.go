@@ -11,6 +11,7 @@ import (
 	cache2 "xiaoweishu/webook/interactive/repository/cache"
 	dao2 "xiaoweishu/webook/interactive/repository/dao"
 	service2 "xiaoweishu/webook/interactive/service"
+	"xiaoweishu/webook/interactive/web"
 	ioc2 "xiaoweishu/webook/ioc"
 )
 
@@ -21,24 +22,56 @@ var thirdPartySet = wire.NewSet(ioc.InitSrcDB,
 	ioc.InitLogger,
 	ioc.InitSaramaClient,
 	ioc.InitSaramaSyncProducer,
-	ioc.InitRedis)
+	ioc.InitRedis,
+	ioc.InitReadEventFilter)
 
 var interactiveSvcSet = wire.NewSet(dao2.NewGORMInteractiveDAO,
-	cache2.NewInteractiveRedisCache,
+	ioc.InitArticleDAO,
+	ioc.InitBizRegistry,
+	cache2.NewInteractiveRedisCacheV1,
 	repository2.NewCachedInteractiveRepository,
-	service2.NewInteractiveService,
+	ioc.InitIntrProducer,
+	ioc.InitInteractiveService,
+)
+
+var statsSvcSet = wire.NewSet(dao2.NewGORMInteractiveStatsDAO,
+	repository2.NewGORMInteractiveStatsRepository,
+	service2.NewInteractiveStatsService,
+)
+
+var likeRankSvcSet = wire.NewSet(dao2.NewGORMLikeRankDAO,
+	cache2.NewLikeRankRedisCache,
+	repository2.NewCachedLikeRankRepository,
+	service2.NewLikeRankService,
+)
+
+var readHistorySvcSet = wire.NewSet(dao2.NewGORMReadHistoryDAO,
+	repository2.NewGORMReadHistoryRepository,
+	service2.NewReadHistoryService,
 )
 
 func InitApp() *App {
 	wire.Build(thirdPartySet,
 		interactiveSvcSet,
+		readHistorySvcSet,
+		statsSvcSet,
+		likeRankSvcSet,
 		grpc.NewInteractiveServiceServer,
+		grpc.NewReadHistoryServiceServer,
+		grpc.NewInteractiveStatsServiceServer,
+		grpc.NewLikeRankServiceServer,
 		events.NewInteractiveReadEventConsumer,
+		events.NewWriteBehindConsumer,
+		events.NewReadHistoryConsumer,
+		events.NewStatsConsumer,
+		events.NewLikeRankConsumer,
 		ioc.InitInteractiveProducer,
 		ioc.InitFixerConsumer,
 		ioc.InitConsumers,
 		ioc.NewGrpcxServer,
+		web.NewAdminHandler,
 		ioc.InitGinxSever,
+		ioc.InitAdminServer,
 		ioc2.InitEtcd,
 		wire.Struct(new(App), "*"),
 	)
@@ -15,6 +15,7 @@ import (
 	"xiaoweishu/webook/interactive/repository/cache"
 	"xiaoweishu/webook/interactive/repository/dao"
 	"xiaoweishu/webook/interactive/service"
+	"xiaoweishu/webook/interactive/web"
 	ioc2 "xiaoweishu/webook/ioc"
 )
 
@@ -28,29 +29,59 @@ func InitApp() *App {
 	db := ioc.InitBizDB(doubleWritePool)
 	interactiveDAO := dao.NewGORMInteractiveDAO(db)
 	cmdable := ioc.InitRedis()
-	interactiveCache := cache.NewInteractiveRedisCache(cmdable)
+	articleDAO := ioc.InitArticleDAO(srcDB)
+	registry := ioc.InitBizRegistry(cmdable, articleDAO, loggerV1)
+	interactiveCache := cache.NewInteractiveRedisCacheV1(cmdable, registry)
 	interactiveRepository := repository.NewCachedInteractiveRepository(interactiveDAO, interactiveCache, loggerV1)
 	client := ioc.InitSaramaClient()
-	interactiveReadEventConsumer := events.NewInteractiveReadEventConsumer(interactiveRepository, client, loggerV1)
+	readEventFilter := ioc.InitReadEventFilter(cmdable, loggerV1)
+	interactiveReadEventConsumer := events.NewInteractiveReadEventConsumer(interactiveRepository, client, readEventFilter, loggerV1)
+	writeBehindConsumer := events.NewWriteBehindConsumer(interactiveRepository, client, readEventFilter, loggerV1)
+	readHistoryDAO := dao.NewGORMReadHistoryDAO(db)
+	readHistoryRepository := repository.NewGORMReadHistoryRepository(readHistoryDAO)
+	readHistoryService := service.NewReadHistoryService(readHistoryRepository, loggerV1)
+	readHistoryConsumer := events.NewReadHistoryConsumer(readHistoryService, client, loggerV1)
+	interactiveStatsDAO := dao.NewGORMInteractiveStatsDAO(db)
+	interactiveStatsRepository := repository.NewGORMInteractiveStatsRepository(interactiveStatsDAO)
+	interactiveStatsService := service.NewInteractiveStatsService(interactiveStatsRepository, loggerV1)
+	statsConsumer := events.NewStatsConsumer(interactiveStatsService, client, readEventFilter, loggerV1)
+	likeRankDAO := dao.NewGORMLikeRankDAO(db)
+	likeRankCache := cache.NewLikeRankRedisCache(cmdable)
+	likeRankRepository := repository.NewCachedLikeRankRepository(likeRankDAO, likeRankCache)
+	likeRankService := service.NewLikeRankService(likeRankRepository)
+	likeRankConsumer := events.NewLikeRankConsumer(likeRankService, client, loggerV1)
 	consumer := ioc.InitFixerConsumer(client, loggerV1, srcDB, dstDB)
-	v := ioc.InitConsumers(interactiveReadEventConsumer, consumer)
-	interactiveService := service.NewInteractiveService(interactiveRepository)
+	v := ioc.InitConsumers(interactiveReadEventConsumer, writeBehindConsumer, readHistoryConsumer, statsConsumer, likeRankConsumer, consumer)
+	syncProducer := ioc.InitSaramaSyncProducer(client)
+	intrProducer := ioc.InitIntrProducer(syncProducer)
+	interactiveService := ioc.InitInteractiveService(interactiveRepository, intrProducer, registry, loggerV1)
 	interactiveServiceServer := grpc.NewInteractiveServiceServer(interactiveService)
+	readHistoryServiceServer := grpc.NewReadHistoryServiceServer(readHistoryService)
+	interactiveStatsServiceServer := grpc.NewInteractiveStatsServiceServer(interactiveStatsService)
+	likeRankServiceServer := grpc.NewLikeRankServiceServer(likeRankService)
 	clientv3Client := ioc2.InitEtcd()
-	server := ioc.NewGrpcxServer(interactiveServiceServer, clientv3Client, loggerV1)
-	syncProducer := ioc.InitSaramaSyncProducer(client)
+	server := ioc.NewGrpcxServer(interactiveServiceServer, readHistoryServiceServer, interactiveStatsServiceServer, likeRankServiceServer, clientv3Client, loggerV1)
 	producer := ioc.InitInteractiveProducer(syncProducer)
+	adminHandler := web.NewAdminHandler(interactiveService, likeRankService, loggerV1)
 	ginxServer := ioc.InitGinxSever(loggerV1, srcDB, dstDB, doubleWritePool, producer)
+	adminServer := ioc.InitAdminServer(adminHandler)
 	app := &App{
-		consumers:   v,
-		server:      server,
-		adminServer: ginxServer,
+		consumers:       v,
+		server:          server,
+		adminServer:     ginxServer,
+		intrAdminServer: adminServer,
 	}
 	return app
 }
 
 // wire.go:
 
-var thirdPartySet = wire.NewSet(ioc.InitSrcDB, ioc.InitDstDB, ioc.InitDoubleWritePool, ioc.InitBizDB, ioc.InitLogger, ioc.InitSaramaClient, ioc.InitSaramaSyncProducer, ioc.InitRedis)
+var thirdPartySet = wire.NewSet(ioc.InitSrcDB, ioc.InitDstDB, ioc.InitDoubleWritePool, ioc.InitBizDB, ioc.InitLogger, ioc.InitSaramaClient, ioc.InitSaramaSyncProducer, ioc.InitRedis, ioc.InitReadEventFilter)
+
+var interactiveSvcSet = wire.NewSet(dao.NewGORMInteractiveDAO, ioc.InitArticleDAO, ioc.InitBizRegistry, cache.NewInteractiveRedisCacheV1, repository.NewCachedInteractiveRepository, ioc.InitIntrProducer, ioc.InitInteractiveService)
+
+var statsSvcSet = wire.NewSet(dao.NewGORMInteractiveStatsDAO, repository.NewGORMInteractiveStatsRepository, service.NewInteractiveStatsService)
+
+var likeRankSvcSet = wire.NewSet(dao.NewGORMLikeRankDAO, cache.NewLikeRankRedisCache, repository.NewCachedLikeRankRepository, service.NewLikeRankService)
 
-var interactiveSvcSet = wire.NewSet(dao.NewGORMInteractiveDAO, cache.NewInteractiveRedisCache, repository.NewCachedInteractiveRepository, service.NewInteractiveService)
+var readHistorySvcSet = wire.NewSet(dao.NewGORMReadHistoryDAO, repository.NewGORMReadHistoryRepository, service.NewReadHistoryService)
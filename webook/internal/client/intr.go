@@ -39,6 +39,14 @@ func (i *InteractiveClient) GetByIds(ctx context.Context, in *intrv1.GetByIdsReq
 	return i.selectClient().GetByIds(ctx, in, opts...)
 }
 
+func (i *InteractiveClient) React(ctx context.Context, in *intrv1.ReactRequest, opts ...grpc.CallOption) (*intrv1.ReactResponse, error) {
+	return i.selectClient().React(ctx, in, opts...)
+}
+
+func (i *InteractiveClient) CancelReaction(ctx context.Context, in *intrv1.CancelReactionRequest, opts ...grpc.CallOption) (*intrv1.CancelReactionResponse, error) {
+	return i.selectClient().CancelReaction(ctx, in, opts...)
+}
+
 func (i *InteractiveClient) selectClient() intrv1.InteractiveServiceClient {
 	// [0, 100) 的随机数
 	num := rand.Int31n(100)
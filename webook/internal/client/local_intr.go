@@ -62,14 +62,27 @@ func (l *LocalInteractiveServiceAdapter) GetByIds(ctx context.Context, in *intrv
 	}, nil
 }
 
+func (l *LocalInteractiveServiceAdapter) React(ctx context.Context, in *intrv1.ReactRequest, opts ...grpc.CallOption) (*intrv1.ReactResponse, error) {
+	err := l.svc.React(ctx, in.GetBiz(), in.GetBizId(), in.GetUid(), in.GetReaction())
+	return &intrv1.ReactResponse{}, err
+}
+
+func (l *LocalInteractiveServiceAdapter) CancelReaction(ctx context.Context, in *intrv1.CancelReactionRequest, opts ...grpc.CallOption) (*intrv1.CancelReactionResponse, error) {
+	err := l.svc.CancelReaction(ctx, in.GetBiz(), in.GetBizId(), in.GetUid())
+	return &intrv1.CancelReactionResponse{}, err
+}
+
 func (l *LocalInteractiveServiceAdapter) toDTO(intr domain.Interactive) *intrv1.Interactive {
 	return &intrv1.Interactive{
-		Biz:        intr.Biz,
-		BizId:      intr.BizId,
-		ReadCnt:    intr.ReadCnt,
-		CollectCnt: intr.CollectCnt,
-		Collected:  intr.Collected,
-		Liked:      intr.Liked,
-		LikeCnt:    intr.LikeCnt,
+		Biz:          intr.Biz,
+		BizId:        intr.BizId,
+		ReadCnt:      intr.ReadCnt,
+		UvCnt:        intr.UvCnt,
+		CollectCnt:   intr.CollectCnt,
+		Collected:    intr.Collected,
+		Liked:        intr.Liked,
+		LikeCnt:      intr.LikeCnt,
+		Reaction:     intr.Reaction,
+		ReactionCnts: intr.ReactionCnts,
 	}
 }
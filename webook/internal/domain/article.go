@@ -17,10 +17,12 @@ type Article struct {
 	Id      int64
 	Title   string
 	Content string
-	Author  Author
-	Status  ArticleStatus
-	Ctime   time.Time
-	Utime   time.Time
+	// Category 分类，分类热榜用
+	Category string
+	Author   Author
+	Status   ArticleStatus
+	Ctime    time.Time
+	Utime    time.Time
 }
 type ArticleStatus uint8
 
@@ -28,6 +30,12 @@ func (s ArticleStatus) ToUint8() uint8 {
 	return uint8(s)
 }
 
+// ClientInfo 发起请求的客户端信息
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+}
+
 type Author struct {
 	Id   int64
 	Name string
@@ -42,9 +50,3 @@ func (a Article) Abstract() string {
 	}
 	return string(str)
 }
-
-type Like100 struct {
-	Biz     string
-	BizId   int64
-	LikeCnt int64
-}
@@ -7,4 +7,6 @@ type AsyncSms struct {
 	Numbers []string
 	// 重试的配置
 	RetryMax int
+	// RetryCnt 抢占到的时候是第几次发送，从 1 开始
+	RetryCnt int
 }
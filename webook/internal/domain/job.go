@@ -0,0 +1,248 @@
+package domain
+
+import (
+	"github.com/robfig/cron/v3"
+	"math"
+	"time"
+)
+
+// jobCronParser 支持秒级别的 cron 表达式，也支持 @every 这种写法
+var jobCronParser = cron.NewParser(cron.Second | cron.Minute | cron.Hour |
+	cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+type Job struct {
+	Id         int64
+	Name       string
+	Expression string
+	Executor   string
+	Cfg        string
+	Status     JobStatus
+	// NextExecTime 下一次调度的时间
+	NextExecTime time.Time
+	// Owner 正在执行这个任务的实例，没有在执行的时候为空
+	Owner string
+	// Version 抢占之后的版本号，续约和释放的时候用来确认任务还在自己手上
+	Version int
+	// MaxRetries 失败之后最多重试几次，0 就是不重试
+	MaxRetries int
+	// Retries 这一轮已经重试了几次，成功或者放弃之后清零
+	Retries int
+	// Timeout 一次执行的超时时间，0 就是不限制
+	Timeout time.Duration
+	// MisfirePolicy 错过了调度时间怎么办
+	MisfirePolicy JobMisfirePolicy
+	// Shards 大于 1 的时候每一轮拆成这么多片，不同的实例可以并行执行
+	Shards int
+	// Shard 抢占到的是一个分片的时候才有，Total 为 0 就不是分片
+	Shard JobShard
+	// Upstreams 上游任务的 id，有上游的任务不按照 cron 表达式调度，上游都成功了才会执行
+	Upstreams []int64
+	// RunId 被上游触发的时候是所属的工作流的执行记录，0 就是不在工作流里面
+	RunId int64
+	Ctime time.Time
+	Utime time.Time
+	// Lost 续约失败、任务可能已经被别的实例抢走的时候会被关闭，执行的一方要马上停下来，
+	// 也不能再更新任务的状态
+	Lost       <-chan struct{}
+	CancelFunc func()
+}
+
+// ParseJobExpression 校验 cron 表达式
+func ParseJobExpression(expr string) (cron.Schedule, error) {
+	return jobCronParser.Parse(expr)
+}
+
+// JobNeverExecTime 只由上游触发的任务平时的下一次调度时间，永远不会到
+var JobNeverExecTime = time.UnixMilli(math.MaxInt64)
+
+func (j Job) NextTime() time.Time {
+	return j.NextTimeAfter(time.Now())
+}
+
+// NextTimeAfter t 之后的下一次调度时间，没有 cron 表达式的任务返回 JobNeverExecTime
+func (j Job) NextTimeAfter(t time.Time) time.Time {
+	if j.Expression == "" {
+		return JobNeverExecTime
+	}
+	s, _ := jobCronParser.Parse(j.Expression)
+	return s.Next(t)
+}
+
+// Misfired 抢占到的时候已经比预定的时间晚了 threshold 以上
+func (j Job) Misfired(now time.Time, threshold time.Duration) bool {
+	return now.Sub(j.NextExecTime) > threshold
+}
+
+// JobMisfirePolicy 调度器停机或者任务积压，错过了预定时间之后的处理方式
+type JobMisfirePolicy uint8
+
+const (
+	// JobMisfireFireOnce 马上执行一次，下一次从现在开始算，错过的多次只补一次
+	JobMisfireFireOnce JobMisfirePolicy = iota
+	// JobMisfireSkip 错过的不执行了，直接等下一次
+	JobMisfireSkip
+	// JobMisfireCatchUp 错过的每一次都补上，下一次从错过的时间开始算
+	JobMisfireCatchUp
+)
+
+func (p JobMisfirePolicy) String() string {
+	switch p {
+	case JobMisfireSkip:
+		return "skip"
+	case JobMisfireCatchUp:
+		return "catch_up"
+	default:
+		return "fire_once"
+	}
+}
+
+// ParseJobMisfirePolicy 空字符串就是默认的 fire_once
+func ParseJobMisfirePolicy(s string) (JobMisfirePolicy, bool) {
+	switch s {
+	case "", "fire_once":
+		return JobMisfireFireOnce, true
+	case "skip":
+		return JobMisfireSkip, true
+	case "catch_up":
+		return JobMisfireCatchUp, true
+	default:
+		return JobMisfireFireOnce, false
+	}
+}
+
+type JobStatus uint8
+
+const (
+	// JobStatusWaiting 等待调度
+	JobStatusWaiting JobStatus = iota
+	// JobStatusRunning 正在被某个实例执行
+	JobStatusRunning
+	// JobStatusPaused 暂停了，不会被调度
+	JobStatusPaused
+)
+
+func (s JobStatus) String() string {
+	switch s {
+	case JobStatusWaiting:
+		return "waiting"
+	case JobStatusRunning:
+		return "running"
+	case JobStatusPaused:
+		return "paused"
+	default:
+		return "unknown"
+	}
+}
+
+// JobExecution 任务的一次执行记录
+type JobExecution struct {
+	Id   int64
+	Jid  int64
+	Name string
+	// Instance 执行的实例
+	Instance string
+	// Attempt 第几次尝试，从 1 开始
+	Attempt int
+	Status  JobExecutionStatus
+	// Err 失败的原因
+	Err string
+	// Output 执行器返回的结果摘要
+	Output string
+	Stime  time.Time
+	Etime  time.Time
+}
+
+type JobExecutionStatus uint8
+
+const (
+	JobExecutionStatusUnknown JobExecutionStatus = iota
+	JobExecutionStatusRunning
+	JobExecutionStatusSuccess
+	JobExecutionStatusFailed
+)
+
+func (s JobExecutionStatus) String() string {
+	switch s {
+	case JobExecutionStatusRunning:
+		return "running"
+	case JobExecutionStatusSuccess:
+		return "success"
+	case JobExecutionStatusFailed:
+		return "failed"
+	default:
+		return "unknown"
+	}
+}
+
+// JobShard 大任务每一轮拆出来的一片
+type JobShard struct {
+	Id  int64
+	Jid int64
+	// Round 属于哪一轮，就是父任务这一轮预定的调度时间
+	Round time.Time
+	// Idx 从 0 开始
+	Idx     int
+	Total   int
+	Status  JobShardStatus
+	Owner   string
+	Version int
+	Retries int
+	Err     string
+	// NextExecTime 失败重试的时候是重试的时间
+	NextExecTime time.Time
+	Ctime        time.Time
+	Utime        time.Time
+}
+
+// Range 把 [minId, maxId] 平均分成 Total 段，返回这一片负责的 [start, end)
+func (s JobShard) Range(minId, maxId int64) (int64, int64) {
+	if s.Total <= 1 {
+		return minId, maxId + 1
+	}
+	total := int64(s.Total)
+	size := (maxId - minId + total) / total
+	start := minId + size*int64(s.Idx)
+	//id 比分片少的时候后面几片是空的
+	start = min(start, maxId+1)
+	end := min(start+size, maxId+1)
+	return start, end
+}
+
+type JobShardStatus uint8
+
+const (
+	JobShardStatusWaiting JobShardStatus = iota
+	JobShardStatusRunning
+	JobShardStatusSuccess
+	// JobShardStatusFailed 重试次数用完了还是失败
+	JobShardStatusFailed
+)
+
+func (s JobShardStatus) String() string {
+	switch s {
+	case JobShardStatusWaiting:
+		return "waiting"
+	case JobShardStatusRunning:
+		return "running"
+	case JobShardStatusSuccess:
+		return "success"
+	case JobShardStatusFailed:
+		return "failed"
+	default:
+		return "unknown"
+	}
+}
+
+// JobShardProgress 一轮里面各个状态的分片有多少
+type JobShardProgress struct {
+	Total   int
+	Waiting int
+	Running int
+	Success int
+	Failed  int
+}
+
+// Done 所有的分片都执行完了，不管成功还是失败
+func (p JobShardProgress) Done() bool {
+	return p.Total > 0 && p.Success+p.Failed >= p.Total
+}
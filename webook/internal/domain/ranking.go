@@ -0,0 +1,55 @@
+package domain
+
+import (
+	"github.com/ecodeclub/ekit/slice"
+	"time"
+)
+
+// RankingBoard 一个热榜，比如日榜、周榜或者某个分类的榜
+type RankingBoard struct {
+	Name string
+	// Window 只看这段时间内更新过的文章
+	Window time.Duration
+	// Size 榜上有多少篇文章
+	Size int
+	// Categories 不为空的时候只要这些分类的文章
+	Categories []string
+	// AuthorIds 不为空的时候只要这些作者的文章，比如签约作者榜
+	AuthorIds []int64
+}
+
+// Match 文章是否满足这个榜的过滤条件，时间窗口不在这里判断
+func (b RankingBoard) Match(art Article) bool {
+	if len(b.Categories) > 0 && !slice.Contains(b.Categories, art.Category) {
+		return false
+	}
+	if len(b.AuthorIds) > 0 && !slice.Contains(b.AuthorIds, art.Author.Id) {
+		return false
+	}
+	return true
+}
+
+// RankingDecay 增量热榜的打分参数，分数是加权和按照半衰期指数衰减
+type RankingDecay struct {
+	ReadWeight    float64
+	LikeWeight    float64
+	CollectWeight float64
+	HalfLife      time.Duration
+}
+
+// RankingDelta 增量热榜里一篇文章的交互数据变化
+type RankingDelta struct {
+	Aid        int64
+	ReadCnt    int64
+	LikeCnt    int64
+	CollectCnt int64
+}
+
+// RankingCandidate 增量热榜的候选文章，校正的时候用数据库里的数据整个覆盖
+type RankingCandidate struct {
+	Aid        int64
+	Utime      time.Time
+	ReadCnt    int64
+	LikeCnt    int64
+	CollectCnt int64
+}
@@ -0,0 +1,77 @@
+package domain
+
+import "time"
+
+// SmsMessage 发给一个手机号的一条短信，用来跟踪有没有送达
+type SmsMessage struct {
+	Id       int64
+	Provider string
+	// BizId 供应商的消息 id，发送失败的时候没有
+	BizId string
+	Phone string
+	// TplName 逻辑上的模板名字
+	TplName string
+	Status  SmsMessageStatus
+	// Reason 发送失败或者没有送达的原因
+	Reason string
+	// ReportTime 供应商回执里面的送达时间
+	ReportTime time.Time
+	Ctime      time.Time
+	Utime      time.Time
+}
+
+type SmsMessageStatus uint8
+
+const (
+	SmsMessageStatusUnknown SmsMessageStatus = iota
+	// SmsMessageStatusSent 供应商受理了，还没有收到回执
+	SmsMessageStatusSent
+	// SmsMessageStatusDelivered 用户收到了
+	SmsMessageStatusDelivered
+	// SmsMessageStatusFailed 发送失败，或者回执说没有送达
+	SmsMessageStatusFailed
+)
+
+func (s SmsMessageStatus) String() string {
+	switch s {
+	case SmsMessageStatusSent:
+		return "sent"
+	case SmsMessageStatusDelivered:
+		return "delivered"
+	case SmsMessageStatusFailed:
+		return "failed"
+	default:
+		return "unknown"
+	}
+}
+
+// SmsMessageQuery 管理后台查询短信，Phone 为空就是不限手机号，时间是发送时间
+type SmsMessageQuery struct {
+	Phone  string
+	Start  time.Time
+	End    time.Time
+	Offset int
+	Limit  int
+}
+
+// SmsDeliveryStats 一个供应商一段时间内的送达情况
+type SmsDeliveryStats struct {
+	Provider  string
+	Sent      int64
+	Delivered int64
+	Failed    int64
+}
+
+// Total 所有的短信，包括还没有收到回执的
+func (s SmsDeliveryStats) Total() int64 {
+	return s.Sent + s.Delivered + s.Failed
+}
+
+// DeliveryRate 送达率，没有短信的时候是 0
+func (s SmsDeliveryStats) DeliveryRate() float64 {
+	total := s.Total()
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Delivered) / float64(total)
+}
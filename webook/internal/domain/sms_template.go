@@ -0,0 +1,98 @@
+package domain
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+	"unicode/utf8"
+)
+
+// SmsTemplate 业务方只认识逻辑上的模板名字，发送的时候再换成各个供应商自己的模板 id 和签名
+type SmsTemplate struct {
+	Id   int64
+	Name string
+	// Content 模板的内容，给运营看的，真正发出去的内容以供应商那边审核通过的为准
+	Content string
+	Args    []SmsTemplateArg
+	// Providers 各个供应商的模板，一个供应商最多一个
+	Providers []SmsProviderTemplate
+	Ctime     time.Time
+	Utime     time.Time
+}
+
+// SmsTemplateArg 模板参数，按照顺序传
+type SmsTemplateArg struct {
+	Name string
+	// Pattern 参数要满足的正则表达式，为空就不校验
+	Pattern string
+	// MaxLen 参数最多多少个字符，0 就是不限制。供应商一般都有限制，比如说腾讯云是 6 个字符
+	MaxLen int
+}
+
+// Validate 发送之前校验参数的个数和格式
+func (t SmsTemplate) Validate(args []string) error {
+	if len(args) != len(t.Args) {
+		return fmt.Errorf("模板 %s 需要 %d 个参数，传了 %d 个", t.Name, len(t.Args), len(args))
+	}
+	for i, arg := range t.Args {
+		if arg.MaxLen > 0 && utf8.RuneCountInString(args[i]) > arg.MaxLen {
+			return fmt.Errorf("模板 %s 的参数 %s 超过了 %d 个字符", t.Name, arg.Name, arg.MaxLen)
+		}
+		if arg.Pattern == "" {
+			continue
+		}
+		ok, err := regexp.MatchString(arg.Pattern, args[i])
+		if err != nil {
+			return fmt.Errorf("模板 %s 的参数 %s 的正则表达式不对 %w", t.Name, arg.Name, err)
+		}
+		if !ok {
+			return fmt.Errorf("模板 %s 的参数 %s 格式不对", t.Name, arg.Name)
+		}
+	}
+	return nil
+}
+
+// Provider 找到 provider 的模板，没有的话返回 false
+func (t SmsTemplate) Provider(provider string) (SmsProviderTemplate, bool) {
+	for _, p := range t.Providers {
+		if p.Provider == provider {
+			return p, true
+		}
+	}
+	return SmsProviderTemplate{}, false
+}
+
+// SmsProviderTemplate 模板在某个供应商那边的 id 和签名，供应商审核通过之后管理员标记为通过才能用
+type SmsProviderTemplate struct {
+	Provider string
+	TplId    string
+	// SignName 为空的时候用供应商默认的签名
+	SignName string
+	Status   SmsTemplateStatus
+	// Reason 审核不通过的原因
+	Reason string
+	Utime  time.Time
+}
+
+type SmsTemplateStatus uint8
+
+const (
+	SmsTemplateStatusUnknown SmsTemplateStatus = iota
+	// SmsTemplateStatusPending 提交了还没审核，修改了模板 id 或者签名之后也要重新审核
+	SmsTemplateStatusPending
+	SmsTemplateStatusApproved
+	SmsTemplateStatusRejected
+)
+
+func (s SmsTemplateStatus) String() string {
+	switch s {
+	case SmsTemplateStatusPending:
+		return "pending"
+	case SmsTemplateStatusApproved:
+		return "approved"
+	case SmsTemplateStatusRejected:
+		return "rejected"
+	default:
+		return "unknown"
+	}
+}
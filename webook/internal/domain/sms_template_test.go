@@ -0,0 +1,65 @@
+package domain
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSmsTemplate_Validate(t *testing.T) {
+	tpl := SmsTemplate{
+		Name: "verify_code",
+		Args: []SmsTemplateArg{
+			{Name: "code", Pattern: `^\d{6}$`, MaxLen: 6},
+			{Name: "name", MaxLen: 3},
+		},
+	}
+	testCases := []struct {
+		name    string
+		tpl     SmsTemplate
+		args    []string
+		wantErr bool
+	}{
+		{
+			name: "合法",
+			tpl:  tpl,
+			args: []string{"123456", "小微书"},
+		},
+		{
+			name:    "参数个数不对",
+			tpl:     tpl,
+			args:    []string{"123456"},
+			wantErr: true,
+		},
+		{
+			name:    "格式不对",
+			tpl:     tpl,
+			args:    []string{"12345a", "a"},
+			wantErr: true,
+		},
+		{
+			name: "按照字符算长度",
+			tpl:  tpl,
+			args: []string{"123456", "小微书!"},
+			// 4 个字符，超过了 3 个
+			wantErr: true,
+		},
+		{
+			name: "正则表达式不对",
+			tpl: SmsTemplate{Name: "bad", Args: []SmsTemplateArg{
+				{Name: "code", Pattern: `(`},
+			}},
+			args:    []string{"1"},
+			wantErr: true,
+		},
+		{
+			name: "没有参数",
+			tpl:  SmsTemplate{Name: "notice"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.tpl.Validate(tc.args)
+			assert.Equal(t, tc.wantErr, err != nil, err)
+		})
+	}
+}
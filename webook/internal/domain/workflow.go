@@ -0,0 +1,87 @@
+package domain
+
+import "time"
+
+// WorkflowRun 工作流的一次执行。有下游的 cron 任务开始执行的时候创建，
+// 从它出发能到达的任务都是这次执行的步骤
+type WorkflowRun struct {
+	Id int64
+	// RootJid 触发这次执行的任务
+	RootJid int64
+	Name    string
+	Status  WorkflowRunStatus
+	Steps   []WorkflowStep
+	Stime   time.Time
+	// Etime 还没结束的时候是零值
+	Etime time.Time
+}
+
+// WorkflowStep 工作流里面的一个任务
+type WorkflowStep struct {
+	Id     int64
+	RunId  int64
+	Jid    int64
+	Name   string
+	Status WorkflowStepStatus
+	Err    string
+	// Stime 和 Etime 还没开始或者还没结束的时候是零值
+	Stime time.Time
+	Etime time.Time
+}
+
+type WorkflowRunStatus uint8
+
+const (
+	WorkflowRunStatusUnknown WorkflowRunStatus = iota
+	WorkflowRunStatusRunning
+	WorkflowRunStatusSuccess
+	WorkflowRunStatusFailed
+)
+
+func (s WorkflowRunStatus) String() string {
+	switch s {
+	case WorkflowRunStatusRunning:
+		return "running"
+	case WorkflowRunStatusSuccess:
+		return "success"
+	case WorkflowRunStatusFailed:
+		return "failed"
+	default:
+		return "unknown"
+	}
+}
+
+type WorkflowStepStatus uint8
+
+const (
+	// WorkflowStepStatusPending 等上游执行完
+	WorkflowStepStatusPending WorkflowStepStatus = iota
+	WorkflowStepStatusRunning
+	WorkflowStepStatusSuccess
+	WorkflowStepStatusFailed
+	// WorkflowStepStatusSkipped 前面有步骤失败了，这一步不会执行了
+	WorkflowStepStatusSkipped
+)
+
+func (s WorkflowStepStatus) String() string {
+	switch s {
+	case WorkflowStepStatusPending:
+		return "pending"
+	case WorkflowStepStatusRunning:
+		return "running"
+	case WorkflowStepStatusSuccess:
+		return "success"
+	case WorkflowStepStatusFailed:
+		return "failed"
+	case WorkflowStepStatusSkipped:
+		return "skipped"
+	default:
+		return "unknown"
+	}
+}
+
+// Finished 成功、失败或者跳过了
+func (s WorkflowStepStatus) Finished() bool {
+	return s == WorkflowStepStatusSuccess || s == WorkflowStepStatusFailed ||
+		s == WorkflowStepStatusSkipped
+}
@@ -3,17 +3,37 @@ package article
 import (
 	"encoding/json"
 	"github.com/IBM/sarama"
+	"strconv"
 )
 
 const TopicReadEvent = "article_read"
 
+// TopicPublishEvent 发表和撤回文章都发到这个 topic，增量热榜用
+const TopicPublishEvent = "article_publish"
+
 type Producer interface {
 	ProduceReadEvent(evt ReadEvent) error
+	ProducePublishEvent(evt PublishEvent) error
 }
 
 type ReadEvent struct {
 	Aid int64
 	Uid int64
+	// IP 和 UserAgent 用于在消费端识别爬虫和刷阅读数的流量
+	IP        string
+	UserAgent string
+	// Rtime 阅读发生的时间，毫秒。消费端按照这个时间限流，消费积压的时候才不会误判
+	Rtime int64
+}
+
+type PublishEvent struct {
+	Aid      int64
+	Uid      int64
+	Category string
+	// Withdrawn 为 true 说明文章被撤回了
+	Withdrawn bool
+	// Utime 毫秒数
+	Utime int64
 }
 
 type BatchReadEvent struct {
@@ -40,3 +60,17 @@ func (s *SaramaSyncProducer) ProduceReadEvent(evt ReadEvent) error {
 	})
 	return err
 }
+
+func (s *SaramaSyncProducer) ProducePublishEvent(evt PublishEvent) error {
+	val, err := json.Marshal(evt)
+	if err != nil {
+		return err
+	}
+	_, _, err = s.producer.SendMessage(&sarama.ProducerMessage{
+		Topic: TopicPublishEvent,
+		//同一篇文章的发表和撤回落到同一个分区，保证顺序
+		Key:   sarama.StringEncoder(strconv.FormatInt(evt.Aid, 10)),
+		Value: sarama.StringEncoder(val),
+	})
+	return err
+}
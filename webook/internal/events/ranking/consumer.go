@@ -0,0 +1,204 @@
+package ranking
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/IBM/sarama"
+	"github.com/prometheus/client_golang/prometheus"
+	"time"
+	"xiaoweishu/webook/interactive/events"
+	"xiaoweishu/webook/interactive/events/intr"
+	"xiaoweishu/webook/internal/domain"
+	"xiaoweishu/webook/internal/events/article"
+	"xiaoweishu/webook/internal/service"
+	logger2 "xiaoweishu/webook/pkg/logger"
+)
+
+// StreamRankingConsumer 增量热榜的输入，交互事件攒一批再写，发表和撤回事件来一条处理一条
+type StreamRankingConsumer struct {
+	svc    service.StreamRankingService
+	client sarama.Client
+	// filter 和全量计算用的阅读数一样，过滤掉爬虫和刷量
+	filter events.ReadEventFilter
+	l      logger2.LoggerV1
+	// interval 写入间隔，batchSize 攒够这么多条消息也会写入
+	interval  time.Duration
+	batchSize int
+	// maxPending 写入一直失败的时候最多攒这么多条，超过了就丢掉，等校正任务修正
+	maxPending int
+	// dropped 丢掉了多少条增量
+	dropped prometheus.Counter
+}
+
+func NewStreamRankingConsumer(svc service.StreamRankingService,
+	client sarama.Client, filter events.ReadEventFilter, l logger2.LoggerV1,
+	opts prometheus.CounterOpts) *StreamRankingConsumer {
+	dropped := prometheus.NewCounter(opts)
+	prometheus.MustRegister(dropped)
+	return &StreamRankingConsumer{
+		svc:        svc,
+		client:     client,
+		filter:     filter,
+		l:          l,
+		interval:   time.Second,
+		batchSize:  500,
+		maxPending: 5000,
+		dropped:    dropped,
+	}
+}
+
+func (s *StreamRankingConsumer) Start() error {
+	cg, err := sarama.NewConsumerGroupFromClient("ranking_stream", s.client)
+	if err != nil {
+		return err
+	}
+	go func() {
+		er := cg.Consume(context.Background(),
+			[]string{article.TopicReadEvent, article.TopicPublishEvent,
+				intr.TopicLikeEvent, intr.TopicCollectEvent}, s)
+		if er != nil {
+			s.l.Error("退出消费", logger2.Error(er))
+		}
+	}()
+	return nil
+}
+
+func (s *StreamRankingConsumer) Setup(session sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+func (s *StreamRankingConsumer) Cleanup(session sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+func (s *StreamRankingConsumer) ConsumeClaim(session sarama.ConsumerGroupSession,
+	claim sarama.ConsumerGroupClaim) error {
+	msgs := claim.Messages()
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	deltas := make([]domain.RankingDelta, 0, s.batchSize)
+	var last *sarama.ConsumerMessage
+	flush := func() {
+		if last == nil {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+		err := s.svc.Record(ctx, deltas)
+		cancel()
+		if err != nil && len(deltas) < s.maxPending {
+			//不提交 offset，下一次连同新的消息一起重试
+			s.l.Error("更新增量热榜失败", logger2.Error(err))
+			return
+		}
+		if err != nil {
+			//redis 一直写不进去的时候不能无限攒下去，丢掉的部分等校正任务修正
+			s.dropped.Add(float64(len(deltas)))
+			s.l.Error("更新增量热榜一直失败，丢弃增量",
+				logger2.Int("cnt", len(deltas)),
+				logger2.Error(err))
+		}
+		session.MarkMessage(last, "")
+		deltas = deltas[:0]
+		last = nil
+	}
+	for {
+		select {
+		case msg, ok := <-msgs:
+			if !ok {
+				flush()
+				return nil
+			}
+			if msg.Topic == article.TopicPublishEvent {
+				//一个 claim 只对应一个分区，发表事件很少，来一条处理一条
+				s.handlePublish(msg)
+				session.MarkMessage(msg, "")
+				continue
+			}
+			last = msg
+			if d, ok := s.toDelta(msg); ok {
+				deltas = append(deltas, d)
+			}
+			if len(deltas) >= s.batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-session.Context().Done():
+			return nil
+		}
+	}
+}
+
+// handlePublish 处理失败只记录日志，校正任务会修正
+func (s *StreamRankingConsumer) handlePublish(msg *sarama.ConsumerMessage) {
+	var evt article.PublishEvent
+	err := json.Unmarshal(msg.Value, &evt)
+	if err != nil {
+		s.logUnmarshalErr(msg, err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if evt.Withdrawn {
+		err = s.svc.Withdraw(ctx, evt.Aid)
+	} else {
+		err = s.svc.Publish(ctx, evt.Aid, time.UnixMilli(evt.Utime))
+	}
+	if err != nil {
+		s.l.Error("处理发表事件失败",
+			logger2.Int64("aid", evt.Aid),
+			logger2.Error(err))
+	}
+}
+
+func (s *StreamRankingConsumer) toDelta(msg *sarama.ConsumerMessage) (domain.RankingDelta, bool) {
+	var err error
+	var d domain.RankingDelta
+	switch msg.Topic {
+	case article.TopicReadEvent:
+		var evt article.ReadEvent
+		err = json.Unmarshal(msg.Value, &evt)
+		if err == nil && !s.allow(evt) {
+			return domain.RankingDelta{}, false
+		}
+		d = domain.RankingDelta{Aid: evt.Aid, ReadCnt: 1}
+	case intr.TopicLikeEvent:
+		var evt intr.LikeEvent
+		err = json.Unmarshal(msg.Value, &evt)
+		if err == nil && evt.Biz != "article" {
+			return domain.RankingDelta{}, false
+		}
+		d = domain.RankingDelta{Aid: evt.BizId, LikeCnt: 1}
+		if !evt.Liked {
+			d.LikeCnt = -1
+		}
+	case intr.TopicCollectEvent:
+		var evt intr.CollectEvent
+		err = json.Unmarshal(msg.Value, &evt)
+		if err == nil && evt.Biz != "article" {
+			return domain.RankingDelta{}, false
+		}
+		d = domain.RankingDelta{Aid: evt.BizId, CollectCnt: 1}
+	default:
+		return domain.RankingDelta{}, false
+	}
+	if err != nil {
+		s.logUnmarshalErr(msg, err)
+		return domain.RankingDelta{}, false
+	}
+	return d, true
+}
+
+func (s *StreamRankingConsumer) allow(evt article.ReadEvent) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	return s.filter.Allow(ctx, "ranking_stream", evt)
+}
+
+func (s *StreamRankingConsumer) logUnmarshalErr(msg *sarama.ConsumerMessage, err error) {
+	s.l.Error("反序列化失败",
+		logger2.String("topic", msg.Topic),
+		logger2.Int32("partition", msg.Partition),
+		logger2.Int64("offset", msg.Offset),
+		logger2.Error(err))
+}
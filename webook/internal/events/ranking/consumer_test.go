@@ -0,0 +1,125 @@
+package ranking
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/IBM/sarama"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+	"xiaoweishu/webook/interactive/events"
+	evtmocks "xiaoweishu/webook/interactive/events/mocks"
+	"xiaoweishu/webook/internal/domain"
+	"xiaoweishu/webook/internal/events/article"
+	"xiaoweishu/webook/internal/service"
+	svcmocks "xiaoweishu/webook/internal/service/mocks"
+	"xiaoweishu/webook/pkg/logger"
+	saramamocks "xiaoweishu/webook/pkg/samarax/mocks"
+)
+
+func readMsg(t *testing.T, offset int64, evt article.ReadEvent) *sarama.ConsumerMessage {
+	val, err := json.Marshal(evt)
+	require.NoError(t, err)
+	return &sarama.ConsumerMessage{Topic: article.TopicReadEvent, Offset: offset, Value: val}
+}
+
+func newTestConsumer(svc service.StreamRankingService, filter events.ReadEventFilter) *StreamRankingConsumer {
+	return &StreamRankingConsumer{
+		svc:        svc,
+		filter:     filter,
+		l:          logger.NewNopLogger(),
+		interval:   time.Hour,
+		batchSize:  1,
+		maxPending: 2,
+		//不注册到全局，避免重复注册
+		dropped: prometheus.NewCounter(prometheus.CounterOpts{Name: "ranking_stream_dropped"}),
+	}
+}
+
+func TestStreamRankingConsumer_ConsumeClaim(t *testing.T) {
+	testCases := []struct {
+		name string
+		msgs func(t *testing.T) []*sarama.ConsumerMessage
+		mock func(ctrl *gomock.Controller) (service.StreamRankingService, events.ReadEventFilter)
+
+		wantMarked  []int64
+		wantDropped float64
+	}{
+		{
+			name: "过滤掉爬虫的阅读",
+			msgs: func(t *testing.T) []*sarama.ConsumerMessage {
+				return []*sarama.ConsumerMessage{
+					readMsg(t, 1, article.ReadEvent{Aid: 1, IP: "1.1.1.1", UserAgent: "bot"}),
+					readMsg(t, 2, article.ReadEvent{Aid: 2, IP: "1.1.1.1", UserAgent: "Mozilla"}),
+				}
+			},
+			mock: func(ctrl *gomock.Controller) (service.StreamRankingService, events.ReadEventFilter) {
+				filter := evtmocks.NewMockReadEventFilter(ctrl)
+				filter.EXPECT().Allow(gomock.Any(), "ranking_stream",
+					article.ReadEvent{Aid: 1, IP: "1.1.1.1", UserAgent: "bot"}).Return(false)
+				filter.EXPECT().Allow(gomock.Any(), "ranking_stream",
+					article.ReadEvent{Aid: 2, IP: "1.1.1.1", UserAgent: "Mozilla"}).Return(true)
+				svc := svcmocks.NewMockStreamRankingService(ctrl)
+				svc.EXPECT().Record(gomock.Any(), []domain.RankingDelta{{Aid: 2, ReadCnt: 1}}).Return(nil)
+				return svc, filter
+			},
+			wantMarked: []int64{2},
+		},
+		{
+			name: "一直写入失败，攒够上限就丢掉",
+			msgs: func(t *testing.T) []*sarama.ConsumerMessage {
+				return []*sarama.ConsumerMessage{
+					readMsg(t, 1, article.ReadEvent{Aid: 1}),
+					readMsg(t, 2, article.ReadEvent{Aid: 2}),
+					readMsg(t, 3, article.ReadEvent{Aid: 3}),
+				}
+			},
+			mock: func(ctrl *gomock.Controller) (service.StreamRankingService, events.ReadEventFilter) {
+				filter := evtmocks.NewMockReadEventFilter(ctrl)
+				filter.EXPECT().Allow(gomock.Any(), "ranking_stream", gomock.Any()).Return(true).Times(3)
+				svc := svcmocks.NewMockStreamRankingService(ctrl)
+				err := errors.New("redis 崩了")
+				//失败了不提交，下一次连同新的消息一起重试
+				svc.EXPECT().Record(gomock.Any(), []domain.RankingDelta{{Aid: 1, ReadCnt: 1}}).Return(err)
+				svc.EXPECT().Record(gomock.Any(), []domain.RankingDelta{
+					{Aid: 1, ReadCnt: 1}, {Aid: 2, ReadCnt: 1},
+				}).Return(err)
+				//退出之前还会再试一次
+				svc.EXPECT().Record(gomock.Any(), []domain.RankingDelta{{Aid: 3, ReadCnt: 1}}).
+					Return(err).Times(2)
+				return svc, filter
+			},
+			wantMarked:  []int64{2},
+			wantDropped: 2,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc, filter := tc.mock(ctrl)
+			c := newTestConsumer(svc, filter)
+			msgs := tc.msgs(t)
+			ch := make(chan *sarama.ConsumerMessage, len(msgs))
+			for _, msg := range msgs {
+				ch <- msg
+			}
+			close(ch)
+			claim := saramamocks.NewMockConsumerGroupClaim(ctrl)
+			claim.EXPECT().Messages().Return((<-chan *sarama.ConsumerMessage)(ch))
+			session := saramamocks.NewMockConsumerGroupSession(ctrl)
+			session.EXPECT().Context().Return(context.Background()).AnyTimes()
+			for _, offset := range tc.wantMarked {
+				session.EXPECT().MarkMessage(msgs[offset-1], "")
+			}
+			err := c.ConsumeClaim(session, claim)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantDropped, testutil.ToFloat64(c.dropped))
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package job
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	etcdv3 "go.etcd.io/etcd/client/v3"
+	resolver2 "go.etcd.io/etcd/client/v3/naming/resolver"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"sync"
+	"time"
+	jobv1 "xiaoweishu/webook/api/proto/gen/job/v1"
+	"xiaoweishu/webook/internal/domain"
+)
+
+// GRPCJobCfg grpc 执行器的任务配置，存在任务的 Cfg 字段里面
+type GRPCJobCfg struct {
+	// Service 注册在 etcd 上的服务名，和 grpcx.Server 的 Name 一样
+	Service string `json:"service"`
+	// Timeout 比如 30s，不填就用执行器默认的超时时间
+	Timeout string `json:"timeout"`
+	// Params 原样透传给对方
+	Params string `json:"params"`
+	// Token 对方给这个任务分配的 token，放在 authorization 里面，对方用来校验请求是不是调度器发的
+	Token string `json:"token"`
+}
+
+// GRPCExecutor 调用实现了 JobExecutorService 的服务来执行任务，服务通过 etcd 发现
+type GRPCExecutor struct {
+	client *etcdv3.Client
+	// allowedServices 只能调用这些服务，防止任务被用来调用内部的其他服务
+	allowedServices map[string]struct{}
+	timeout         time.Duration
+
+	lock  sync.Mutex
+	conns map[string]*grpc.ClientConn
+}
+
+func NewGRPCExecutor(client *etcdv3.Client, allowedServices []string, timeout time.Duration) *GRPCExecutor {
+	g := &GRPCExecutor{
+		client:          client,
+		allowedServices: make(map[string]struct{}, len(allowedServices)),
+		timeout:         timeout,
+		conns:           map[string]*grpc.ClientConn{},
+	}
+	for _, service := range allowedServices {
+		g.allowedServices[service] = struct{}{}
+	}
+	return g
+}
+
+func (g *GRPCExecutor) Name() string {
+	return "grpc"
+}
+
+// ValidateCfg 服务名不能为空，超时时间要能解析
+func (g *GRPCExecutor) ValidateCfg(cfg string) error {
+	_, _, err := g.parseCfg(cfg)
+	return err
+}
+
+func (g *GRPCExecutor) parseCfg(val string) (GRPCJobCfg, time.Duration, error) {
+	var cfg GRPCJobCfg
+	err := json.Unmarshal([]byte(val), &cfg)
+	if err != nil {
+		return GRPCJobCfg{}, 0, err
+	}
+	if cfg.Service == "" {
+		return GRPCJobCfg{}, 0, errors.New("没有配置服务名")
+	}
+	if _, ok := g.allowedServices[cfg.Service]; !ok {
+		return GRPCJobCfg{}, 0, fmt.Errorf("服务 %s 不在白名单里面", cfg.Service)
+	}
+	timeout, err := execTimeout(cfg.Timeout, g.timeout)
+	if err != nil {
+		return GRPCJobCfg{}, 0, err
+	}
+	return cfg, timeout, nil
+}
+
+func (g *GRPCExecutor) Exec(ctx context.Context, j domain.Job) (string, error) {
+	cfg, timeout, err := g.parseCfg(j.Cfg)
+	if err != nil {
+		return "", fmt.Errorf("任务 %s 的配置不对 %w", j.Name, err)
+	}
+	cc, err := g.conn(cfg.Service)
+	if err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	if cfg.Token != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+cfg.Token)
+	}
+	resp, err := jobv1.NewJobExecutorServiceClient(cc).Execute(ctx, &jobv1.ExecuteRequest{
+		JobId:      j.Id,
+		Name:       j.Name,
+		Params:     cfg.Params,
+		ShardIndex: int32(j.Shard.Idx),
+		ShardTotal: int32(j.Shard.Total),
+	})
+	if err != nil {
+		return "", err
+	}
+	if !resp.GetSuccess() {
+		return resp.GetMsg(), fmt.Errorf("任务 %s 执行失败 %s", j.Name, resp.GetMsg())
+	}
+	return resp.GetMsg(), nil
+}
+
+// conn 每个服务一个连接，建立之后一直复用
+func (g *GRPCExecutor) conn(service string) (*grpc.ClientConn, error) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	if cc, ok := g.conns[service]; ok {
+		return cc, nil
+	}
+	resolver, err := resolver2.NewBuilder(g.client)
+	if err != nil {
+		return nil, err
+	}
+	cc, err := grpc.Dial("etcd:///service/"+service,
+		grpc.WithResolvers(resolver),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+	g.conns[service] = cc
+	return cc, nil
+}
+
+// Close 关掉所有的连接
+func (g *GRPCExecutor) Close() error {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	var errs []error
+	for _, cc := range g.conns {
+		errs = append(errs, cc.Close())
+	}
+	g.conns = map[string]*grpc.ClientConn{}
+	return errors.Join(errs...)
+}
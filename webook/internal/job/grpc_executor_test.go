@@ -0,0 +1,137 @@
+package job
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/test/bufconn"
+	"net"
+	"testing"
+	"time"
+	jobv1 "xiaoweishu/webook/api/proto/gen/job/v1"
+	"xiaoweishu/webook/internal/domain"
+)
+
+type fakeJobExecutorServer struct {
+	jobv1.UnimplementedJobExecutorServiceServer
+	t    *testing.T
+	resp *jobv1.ExecuteResponse
+}
+
+func (f *fakeJobExecutorServer) Execute(ctx context.Context, req *jobv1.ExecuteRequest) (*jobv1.ExecuteResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	assert.Equal(f.t, []string{"Bearer token"}, md.Get("authorization"))
+	assert.Equal(f.t, int64(1), req.GetJobId())
+	assert.Equal(f.t, "abc", req.GetParams())
+	assert.Equal(f.t, int32(1), req.GetShardIndex())
+	assert.Equal(f.t, int32(2), req.GetShardTotal())
+	return f.resp, nil
+}
+
+func TestGRPCExecutor_Exec(t *testing.T) {
+	testCases := []struct {
+		name string
+		cfg  string
+		resp *jobv1.ExecuteResponse
+
+		wantMsg string
+		wantErr bool
+	}{
+		{
+			name:    "执行成功",
+			cfg:     `{"service":"job","params":"abc","token":"token"}`,
+			resp:    &jobv1.ExecuteResponse{Success: true, Msg: "done"},
+			wantMsg: "done",
+		},
+		{
+			name:    "执行失败",
+			cfg:     `{"service":"job","params":"abc","token":"token"}`,
+			resp:    &jobv1.ExecuteResponse{Msg: "failed"},
+			wantMsg: "failed",
+			wantErr: true,
+		},
+		{
+			name:    "没有配置服务名",
+			cfg:     `{"params":"abc"}`,
+			wantErr: true,
+		},
+		{
+			name:    "服务不在白名单里面",
+			cfg:     `{"service":"user","params":"abc"}`,
+			wantErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			lis := bufconn.Listen(1024 * 1024)
+			server := grpc.NewServer()
+			jobv1.RegisterJobExecutorServiceServer(server, &fakeJobExecutorServer{t: t, resp: tc.resp})
+			go func() {
+				_ = server.Serve(lis)
+			}()
+			defer server.Stop()
+			cc, err := grpc.Dial("bufnet",
+				grpc.WithContextDialer(func(ctx context.Context, s string) (net.Conn, error) {
+					return lis.DialContext(ctx)
+				}),
+				grpc.WithTransportCredentials(insecure.NewCredentials()))
+			require.NoError(t, err)
+
+			exec := NewGRPCExecutor(nil, []string{"job"}, time.Second)
+			// 不走 etcd，直接把连接放进去
+			exec.conns["job"] = cc
+			defer exec.Close()
+			msg, err := exec.Exec(context.Background(), domain.Job{
+				Id:    1,
+				Name:  "job",
+				Cfg:   tc.cfg,
+				Shard: domain.JobShard{Idx: 1, Total: 2},
+			})
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.wantMsg, msg)
+		})
+	}
+}
+
+func TestGRPCExecutor_ValidateCfg(t *testing.T) {
+	testCases := []struct {
+		name    string
+		cfg     string
+		wantErr bool
+	}{
+		{
+			name: "正确的配置",
+			cfg:  `{"service":"job","timeout":"30s"}`,
+		},
+		{
+			name:    "不是 json",
+			cfg:     "abc",
+			wantErr: true,
+		},
+		{
+			name:    "没有服务名",
+			cfg:     `{"timeout":"30s"}`,
+			wantErr: true,
+		},
+		{
+			name:    "超时时间不对",
+			cfg:     `{"service":"job","timeout":"abc"}`,
+			wantErr: true,
+		},
+		{
+			name:    "服务不在白名单里面",
+			cfg:     `{"service":"user"}`,
+			wantErr: true,
+		},
+	}
+	exec := NewGRPCExecutor(nil, []string{"job"}, time.Second)
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := exec.ValidateCfg(tc.cfg)
+			assert.Equal(t, tc.wantErr, err != nil)
+		})
+	}
+}
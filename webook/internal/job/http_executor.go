@@ -0,0 +1,194 @@
+package job
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"xiaoweishu/webook/internal/domain"
+)
+
+// HTTPJobCfg http 执行器的任务配置，存在任务的 Cfg 字段里面
+type HTTPJobCfg struct {
+	Url string `json:"url"`
+	// Timeout 比如 30s，不填就用执行器默认的超时时间
+	Timeout string `json:"timeout"`
+	// Params 原样透传给对方
+	Params string `json:"params"`
+	// Token 对方给这个任务分配的 token，放在 Authorization 头部，对方用来校验请求是不是调度器发的。
+	// 每个任务各用各的，泄露了也只影响这一个任务
+	Token string `json:"token"`
+	// Headers 额外的请求头，比如对方网关要求的 appId，不能覆盖 Authorization 这些
+	Headers map[string]string `json:"headers"`
+}
+
+// httpReservedHeaders 执行器自己设置的请求头，任务不能配置
+var httpReservedHeaders = map[string]struct{}{
+	"Authorization":  {},
+	"Content-Type":   {},
+	"Content-Length": {},
+	"Host":           {},
+}
+
+// HTTPJobRequest 发给对方的请求体
+type HTTPJobRequest struct {
+	JobId  int64  `json:"jobId"`
+	Name   string `json:"name"`
+	Params string `json:"params"`
+	// ShardIndex 和 ShardTotal 分片任务才有，ShardTotal 为 0 就是不分片
+	ShardIndex int `json:"shardIndex"`
+	ShardTotal int `json:"shardTotal"`
+}
+
+// HTTPJobResponse 对方的响应体，code 为 0 是成功
+type HTTPJobResponse struct {
+	Code int    `json:"code"`
+	Msg  string `json:"msg"`
+}
+
+// HTTPExecutor 调用别的团队提供的 http 接口来执行任务
+type HTTPExecutor struct {
+	client *http.Client
+	// allowedHosts 只能调用这些域名，防止任务被用来访问内网的其他服务，重定向也要在里面
+	allowedHosts map[string]struct{}
+	timeout      time.Duration
+}
+
+func NewHTTPExecutor(client *http.Client, allowedHosts []string, timeout time.Duration) *HTTPExecutor {
+	h := &HTTPExecutor{
+		allowedHosts: make(map[string]struct{}, len(allowedHosts)),
+		timeout:      timeout,
+	}
+	for _, host := range allowedHosts {
+		h.allowedHosts[strings.ToLower(host)] = struct{}{}
+	}
+	c := *client
+	c.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		//和 http.Client 默认的一样，最多重定向十次
+		if len(via) >= 10 {
+			return errors.New("重定向次数太多")
+		}
+		return h.checkHost(req.URL)
+	}
+	h.client = &c
+	return h
+}
+
+func (h *HTTPExecutor) Name() string {
+	return "http"
+}
+
+// ValidateCfg url 必须是 http 或者 https 的绝对地址，超时时间要能解析
+func (h *HTTPExecutor) ValidateCfg(cfg string) error {
+	_, _, err := h.parseCfg(cfg)
+	return err
+}
+
+func (h *HTTPExecutor) parseCfg(val string) (HTTPJobCfg, time.Duration, error) {
+	var cfg HTTPJobCfg
+	err := json.Unmarshal([]byte(val), &cfg)
+	if err != nil {
+		return HTTPJobCfg{}, 0, err
+	}
+	u, err := url.Parse(cfg.Url)
+	if err != nil {
+		return HTTPJobCfg{}, 0, err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return HTTPJobCfg{}, 0, fmt.Errorf("url %s 不是 http 地址", cfg.Url)
+	}
+	err = h.checkHost(u)
+	if err != nil {
+		return HTTPJobCfg{}, 0, err
+	}
+	for key := range cfg.Headers {
+		if _, ok := httpReservedHeaders[http.CanonicalHeaderKey(key)]; ok {
+			return HTTPJobCfg{}, 0, fmt.Errorf("请求头 %s 不能配置", key)
+		}
+	}
+	timeout, err := execTimeout(cfg.Timeout, h.timeout)
+	if err != nil {
+		return HTTPJobCfg{}, 0, err
+	}
+	return cfg, timeout, nil
+}
+
+func (h *HTTPExecutor) checkHost(u *url.URL) error {
+	if _, ok := h.allowedHosts[strings.ToLower(u.Hostname())]; !ok {
+		return fmt.Errorf("域名 %s 不在白名单里面", u.Hostname())
+	}
+	return nil
+}
+
+func (h *HTTPExecutor) Exec(ctx context.Context, j domain.Job) (string, error) {
+	cfg, timeout, err := h.parseCfg(j.Cfg)
+	if err != nil {
+		return "", fmt.Errorf("任务 %s 的配置不对 %w", j.Name, err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	body, err := json.Marshal(HTTPJobRequest{
+		JobId:      j.Id,
+		Name:       j.Name,
+		Params:     cfg.Params,
+		ShardIndex: j.Shard.Idx,
+		ShardTotal: j.Shard.Total,
+	})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.Url, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	for key, val := range cfg.Headers {
+		req.Header.Set(key, val)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if cfg.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+cfg.Token)
+	}
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	//只读前面一部分，避免对方返回一个很大的响应
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return string(data), fmt.Errorf("任务 %s 执行失败，响应码 %d", j.Name, resp.StatusCode)
+	}
+	var res HTTPJobResponse
+	err = json.Unmarshal(data, &res)
+	if err != nil {
+		return "", fmt.Errorf("任务 %s 的响应解析失败 %w", j.Name, err)
+	}
+	if res.Code != 0 {
+		return res.Msg, fmt.Errorf("任务 %s 执行失败 %d %s", j.Name, res.Code, res.Msg)
+	}
+	return res.Msg, nil
+}
+
+// execTimeout 任务配置了超时时间就用任务的，否则用执行器默认的
+func execTimeout(val string, def time.Duration) (time.Duration, error) {
+	if val == "" {
+		return def, nil
+	}
+	timeout, err := time.ParseDuration(val)
+	if err != nil {
+		return 0, err
+	}
+	if timeout <= 0 {
+		return 0, fmt.Errorf("超时时间 %s 必须大于 0", val)
+	}
+	return timeout, nil
+}
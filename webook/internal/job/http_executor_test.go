@@ -0,0 +1,169 @@
+package job
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"xiaoweishu/webook/internal/domain"
+)
+
+func TestHTTPExecutor_Exec(t *testing.T) {
+	testCases := []struct {
+		name    string
+		handler http.HandlerFunc
+		cfg     func(url string) string
+
+		wantMsg string
+		wantErr bool
+	}{
+		{
+			name: "执行成功",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				//token 和请求头都是任务自己配置的
+				assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+				assert.Equal(t, "webook", r.Header.Get("X-App-Id"))
+				var req HTTPJobRequest
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+				assert.Equal(t, HTTPJobRequest{JobId: 1, Name: "job", Params: "abc", ShardIndex: 1, ShardTotal: 2}, req)
+				_, _ = w.Write([]byte(`{"code":0,"msg":"done"}`))
+			},
+			cfg: func(url string) string {
+				return `{"url":"` + url + `","params":"abc","token":"token","headers":{"X-App-Id":"webook"}}`
+			},
+			wantMsg: "done",
+		},
+		{
+			name: "对方返回了错误码",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(`{"code":1,"msg":"failed"}`))
+			},
+			cfg: func(url string) string {
+				return `{"url":"` + url + `"}`
+			},
+			wantMsg: "failed",
+			wantErr: true,
+		},
+		{
+			name: "响应码不是 2xx",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+				_, _ = w.Write([]byte("boom"))
+			},
+			cfg: func(url string) string {
+				return `{"url":"` + url + `"}`
+			},
+			wantMsg: "boom",
+			wantErr: true,
+		},
+		{
+			name: "超时",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				select {
+				case <-r.Context().Done():
+				case <-time.After(time.Millisecond * 200):
+				}
+			},
+			cfg: func(url string) string {
+				return `{"url":"` + url + `","timeout":"10ms"}`
+			},
+			wantErr: true,
+		},
+		{
+			name: "配置不对",
+			cfg: func(url string) string {
+				return `{"url":"abc"}`
+			},
+			wantErr: true,
+		},
+		{
+			name: "重定向到白名单之外的地址",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.Redirect(w, r, "http://169.254.169.254/latest/meta-data", http.StatusFound)
+			},
+			cfg: func(url string) string {
+				return `{"url":"` + url + `"}`
+			},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(tc.handler)
+			defer server.Close()
+			//httptest 监听的是 127.0.0.1
+			exec := NewHTTPExecutor(server.Client(), []string{"127.0.0.1"}, time.Second)
+			msg, err := exec.Exec(context.Background(), domain.Job{
+				Id:    1,
+				Name:  "job",
+				Cfg:   tc.cfg(server.URL),
+				Shard: domain.JobShard{Idx: 1, Total: 2},
+			})
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.wantMsg, msg)
+		})
+	}
+}
+
+func TestHTTPExecutor_ValidateCfg(t *testing.T) {
+	testCases := []struct {
+		name    string
+		cfg     string
+		wantErr bool
+	}{
+		{
+			name: "正确的配置",
+			cfg:  `{"url":"https://example.com/job","timeout":"30s"}`,
+		},
+		{
+			name:    "不是 json",
+			cfg:     "abc",
+			wantErr: true,
+		},
+		{
+			name:    "没有 url",
+			cfg:     `{}`,
+			wantErr: true,
+		},
+		{
+			name:    "不是 http 地址",
+			cfg:     `{"url":"ftp://example.com/job"}`,
+			wantErr: true,
+		},
+		{
+			name:    "超时时间不对",
+			cfg:     `{"url":"https://example.com/job","timeout":"abc"}`,
+			wantErr: true,
+		},
+		{
+			name:    "超时时间是负数",
+			cfg:     `{"url":"https://example.com/job","timeout":"-1s"}`,
+			wantErr: true,
+		},
+		{
+			name: "白名单里面的域名不区分大小写",
+			cfg:  `{"url":"https://Example.com:8443/job","token":"abc","headers":{"X-App-Id":"webook"}}`,
+		},
+		{
+			name:    "域名不在白名单里面",
+			cfg:     `{"url":"http://10.0.0.1/admin"}`,
+			wantErr: true,
+		},
+		{
+			name:    "请求头覆盖 Authorization",
+			cfg:     `{"url":"https://example.com/job","headers":{"authorization":"Bearer abc"}}`,
+			wantErr: true,
+		},
+	}
+	exec := NewHTTPExecutor(http.DefaultClient, []string{"example.com"}, time.Second)
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := exec.ValidateCfg(tc.cfg)
+			assert.Equal(t, tc.wantErr, err != nil)
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package job
+
+import (
+	"context"
+	"time"
+	"xiaoweishu/webook/internal/service"
+	logger2 "xiaoweishu/webook/pkg/logger"
+)
+
+// JobExecutionCleanJob 删除超过保留期限的执行历史，删除是幂等的，多个实例同时跑也没关系
+type JobExecutionCleanJob struct {
+	svc     service.JobExecutionService
+	l       logger2.LoggerV1
+	timeout time.Duration
+}
+
+func NewJobExecutionCleanJob(svc service.JobExecutionService, l logger2.LoggerV1,
+	timeout time.Duration) *JobExecutionCleanJob {
+	return &JobExecutionCleanJob{
+		svc:     svc,
+		l:       l,
+		timeout: timeout,
+	}
+}
+
+func (c *JobExecutionCleanJob) Name() string {
+	return "job_execution_clean"
+}
+
+func (c *JobExecutionCleanJob) Run() error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	cnt, err := c.svc.Clean(ctx)
+	if err != nil {
+		return err
+	}
+	c.l.Info("清理任务执行历史", logger2.Int64("cnt", cnt))
+	return nil
+}
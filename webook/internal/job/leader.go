@@ -0,0 +1,339 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	rlock "github.com/gotomicro/redis-lock"
+	etcdv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/concurrency"
+	"sync"
+	"time"
+	"xiaoweishu/webook/internal/service"
+	logger2 "xiaoweishu/webook/pkg/logger"
+)
+
+var (
+	// ErrNotLeader 当前实例不是 leader，这一次不执行
+	ErrNotLeader = errors.New("当前实例不是 leader")
+	// ErrLeadershipLost 执行到一半失去了 leader 身份，任务的 ctx 被取消了
+	ErrLeadershipLost = errors.New("执行过程中失去了 leader 身份")
+)
+
+//go:generate mockgen -source=./leader.go -package=jobmocks -destination=mocks/leader.mock.go Leader
+
+// Leader 选主，同一时刻只有一个实例是 leader
+type Leader interface {
+	// Acquire 不会阻塞等待，不是 leader 的时候返回 ErrNotLeader。
+	// 已经是 leader 的时候直接返回，返回的 channel 在失去 leader 身份的时候关闭
+	Acquire(ctx context.Context) (<-chan struct{}, error)
+	// Release 主动放弃 leader 身份，没有成为过 leader 也可以调用
+	Release(ctx context.Context) error
+	// IsLeader 现在是不是 leader。后台参选的实现在 Acquire 返回 ErrNotLeader 之后也可能选上
+	IsLeader() bool
+}
+
+// ContextJob 可以被取消的任务，失去 leader 身份的时候 LeaderJob 会取消 ctx
+type ContextJob interface {
+	Job
+	RunContext(ctx context.Context) error
+}
+
+// LeaderJob 只在 leader 上执行 job，不是 leader 的实例直接跳过。
+// 一直持有 leader 身份直到关机，避免任务在实例之间来回切换
+type LeaderJob struct {
+	job    Job
+	leader Leader
+	l      logger2.LoggerV1
+}
+
+func NewLeaderJob(job Job, leader Leader, l logger2.LoggerV1) *LeaderJob {
+	return &LeaderJob{
+		job:    job,
+		leader: leader,
+		l:      l,
+	}
+}
+
+func (j *LeaderJob) Name() string {
+	return j.job.Name()
+}
+
+func (j *LeaderJob) Run() error {
+	//4秒内完成抢锁或者选主
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*4)
+	lost, err := j.leader.Acquire(ctx)
+	cancel()
+	if errors.Is(err, ErrNotLeader) {
+		return nil
+	}
+	if err != nil {
+		j.l.Warn("选主失败", logger2.Error(err), logger2.String("name", j.Name()))
+		return nil
+	}
+	runCtx, cancelRun := context.WithCancelCause(context.Background())
+	defer cancelRun(nil)
+	go func() {
+		select {
+		case <-lost:
+			cancelRun(ErrLeadershipLost)
+		case <-runCtx.Done():
+		}
+	}()
+	if cj, ok := j.job.(ContextJob); ok {
+		err = cj.RunContext(runCtx)
+	} else {
+		//不支持取消的任务只能等它自己结束
+		err = j.job.Run()
+	}
+	if errors.Is(context.Cause(runCtx), ErrLeadershipLost) {
+		return fmt.Errorf("%w, name: %s, err: %v", ErrLeadershipLost, j.Name(), err)
+	}
+	return err
+}
+
+// Close 关机的时候释放 leader 身份，让别的实例立刻接手。
+// 不调用也可以，锁或者租约过期之后别的实例一样能接手
+func (j *LeaderJob) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	return j.leader.Release(ctx)
+}
+
+// RedisLeader 抢到分布式锁的就是 leader，抢到之后自动续约，续约失败就失去了 leader 身份
+type RedisLeader struct {
+	client     *rlock.Client
+	key        string
+	expiration time.Duration
+	l          logger2.LoggerV1
+	//续约的 goroutine 也会修改 lock，所以要用本地锁保护起来
+	mu   sync.Mutex
+	lock *rlock.Lock
+	lost chan struct{}
+}
+
+func NewRedisLeader(client *rlock.Client, key string, expiration time.Duration,
+	l logger2.LoggerV1) *RedisLeader {
+	return &RedisLeader{
+		client:     client,
+		key:        key,
+		expiration: expiration,
+		l:          l,
+	}
+}
+
+func (r *RedisLeader) Acquire(ctx context.Context) (<-chan struct{}, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.lock != nil {
+		return r.lost, nil
+	}
+	lock, err := r.client.Lock(ctx, r.key, r.expiration, &rlock.FixIntervalRetry{
+		Interval: time.Millisecond * 100,
+		Max:      3,
+	}, time.Second)
+	if errors.Is(err, rlock.ErrFailedToPreemptLock) {
+		return nil, ErrNotLeader
+	}
+	if err != nil {
+		return nil, err
+	}
+	lost := make(chan struct{})
+	r.lock = lock
+	r.lost = lost
+	go func() {
+		//每过一半的过期时间续约一次，主动释放的时候返回 nil
+		er := lock.AutoRefresh(r.expiration/2, time.Second)
+		if er != nil {
+			r.l.Error("分布式锁续约失败", logger2.Error(er), logger2.String("key", r.key))
+		}
+		r.mu.Lock()
+		if r.lock == lock {
+			r.lock = nil
+			r.lost = nil
+		}
+		r.mu.Unlock()
+		close(lost)
+	}()
+	return lost, nil
+}
+
+func (r *RedisLeader) IsLeader() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.lock != nil
+}
+
+func (r *RedisLeader) Release(ctx context.Context) error {
+	r.mu.Lock()
+	lock := r.lock
+	r.lock = nil
+	r.lost = nil
+	r.mu.Unlock()
+	if lock == nil {
+		return nil
+	}
+	return lock.Unlock(ctx)
+}
+
+// EtcdLeader 用 etcd 的选举，第一次 Acquire 的时候开始在后台参选，选上之前都返回 ErrNotLeader。
+// 租约过期（比如说和 etcd 断开太久了）就失去了 leader 身份，然后重新参选
+type EtcdLeader struct {
+	client *etcdv3.Client
+	prefix string
+	// val 选上之后写到 etcd 里面，一般是实例的名字
+	val string
+	// ttl 租约的秒数
+	ttl int
+	l   logger2.LoggerV1
+	// elect 参选一次，阻塞到选上为止。返回的 channel 在租约过期的时候关闭，resign 放弃 leader 身份并撤销租约
+	elect func(ctx context.Context) (expired <-chan struct{}, resign func(), err error)
+
+	mu     sync.Mutex
+	lost   chan struct{}
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewEtcdLeader(client *etcdv3.Client, prefix string, val string, ttl int,
+	l logger2.LoggerV1) *EtcdLeader {
+	e := &EtcdLeader{
+		client: client,
+		prefix: prefix,
+		val:    val,
+		ttl:    ttl,
+		l:      l,
+	}
+	e.elect = e.etcdElect
+	return e
+}
+
+func (e *EtcdLeader) Acquire(ctx context.Context) (<-chan struct{}, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.lost != nil {
+		return e.lost, nil
+	}
+	if e.cancel == nil {
+		campaignCtx, cancel := context.WithCancel(context.Background())
+		e.cancel = cancel
+		e.done = make(chan struct{})
+		go e.campaign(campaignCtx, e.done)
+	}
+	return nil, ErrNotLeader
+}
+
+func (e *EtcdLeader) campaign(ctx context.Context, done chan struct{}) {
+	defer close(done)
+	for ctx.Err() == nil {
+		err := e.campaignOnce(ctx)
+		if err == nil || ctx.Err() != nil {
+			continue
+		}
+		e.l.Error("参选失败", logger2.Error(err), logger2.String("prefix", e.prefix))
+		select {
+		case <-time.After(time.Second):
+		case <-ctx.Done():
+		}
+	}
+}
+
+// campaignOnce 阻塞到选上，然后一直等到失去 leader 身份或者主动放弃
+func (e *EtcdLeader) campaignOnce(ctx context.Context) error {
+	expired, resign, err := e.elect(ctx)
+	if err != nil {
+		return err
+	}
+	//撤销租约，选上了的话别的实例立刻就能选上
+	defer resign()
+	lost := make(chan struct{})
+	e.mu.Lock()
+	e.lost = lost
+	e.mu.Unlock()
+	select {
+	case <-expired:
+		e.l.Warn("etcd 租约过期，失去 leader 身份", logger2.String("prefix", e.prefix))
+	case <-ctx.Done():
+	}
+	e.mu.Lock()
+	e.lost = nil
+	e.mu.Unlock()
+	close(lost)
+	return nil
+}
+
+func (e *EtcdLeader) etcdElect(ctx context.Context) (<-chan struct{}, func(), error) {
+	sess, err := concurrency.NewSession(e.client, concurrency.WithTTL(e.ttl))
+	if err != nil {
+		return nil, nil, err
+	}
+	election := concurrency.NewElection(sess, e.prefix)
+	err = election.Campaign(ctx, e.val)
+	if err != nil {
+		_ = sess.Close()
+		return nil, nil, err
+	}
+	return sess.Done(), func() {
+		_ = sess.Close()
+	}, nil
+}
+
+func (e *EtcdLeader) IsLeader() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.lost != nil
+}
+
+func (e *EtcdLeader) Release(ctx context.Context) error {
+	e.mu.Lock()
+	cancel, done := e.cancel, e.done
+	e.cancel = nil
+	e.done = nil
+	e.mu.Unlock()
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// LoadAwareLeader 还不是 leader 的时候，只有负载最低的实例才去竞争。
+// 已经是 leader 的实例不会因为负载变高就放弃，避免 leader 在实例之间来回切换
+type LoadAwareLeader struct {
+	leader  Leader
+	loadSvc service.JobLoadService
+}
+
+func NewLoadAwareLeader(leader Leader, loadSvc service.JobLoadService) *LoadAwareLeader {
+	return &LoadAwareLeader{
+		leader:  leader,
+		loadSvc: loadSvc,
+	}
+}
+
+func (r *LoadAwareLeader) Acquire(ctx context.Context) (<-chan struct{}, error) {
+	//要看底层实际的状态，后台参选的实现（比如说 etcd）上一次 Acquire 返回 ErrNotLeader 之后可能已经选上了
+	if !r.leader.IsLeader() && r.loadSvc.Rank() > 0 {
+		//负载不是最低的，不参与竞争。
+		//后台参选的实现可能已经在竞争了，要停下来
+		err := r.leader.Release(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return nil, ErrNotLeader
+	}
+	return r.leader.Acquire(ctx)
+}
+
+func (r *LoadAwareLeader) IsLeader() bool {
+	return r.leader.IsLeader()
+}
+
+func (r *LoadAwareLeader) Release(ctx context.Context) error {
+	return r.leader.Release(ctx)
+}
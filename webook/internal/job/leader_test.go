@@ -0,0 +1,418 @@
+package job
+
+import (
+	"context"
+	"errors"
+	rlock "github.com/gotomicro/redis-lock"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"sync/atomic"
+	"testing"
+	"time"
+	jobmocks "xiaoweishu/webook/internal/job/mocks"
+	"xiaoweishu/webook/internal/repository/cache/redismocks"
+	"xiaoweishu/webook/internal/service"
+	svcmocks "xiaoweishu/webook/internal/service/mocks"
+	"xiaoweishu/webook/pkg/logger"
+)
+
+func TestLeaderJob_Run(t *testing.T) {
+	testCases := []struct {
+		name    string
+		mock    func(ctrl *gomock.Controller) (Leader, Job)
+		wantErr error
+	}{
+		{
+			name: "不是leader，跳过",
+			mock: func(ctrl *gomock.Controller) (Leader, Job) {
+				leader := jobmocks.NewMockLeader(ctrl)
+				leader.EXPECT().Acquire(gomock.Any()).Return(nil, ErrNotLeader)
+				//不应该执行任务
+				j := jobmocks.NewMockContextJob(ctrl)
+				j.EXPECT().Name().Return("test").AnyTimes()
+				return leader, j
+			},
+		},
+		{
+			name: "选主出错，跳过",
+			mock: func(ctrl *gomock.Controller) (Leader, Job) {
+				leader := jobmocks.NewMockLeader(ctrl)
+				leader.EXPECT().Acquire(gomock.Any()).Return(nil, errors.New("redis 崩了"))
+				j := jobmocks.NewMockContextJob(ctrl)
+				j.EXPECT().Name().Return("test").AnyTimes()
+				return leader, j
+			},
+		},
+		{
+			name: "执行成功",
+			mock: func(ctrl *gomock.Controller) (Leader, Job) {
+				leader := jobmocks.NewMockLeader(ctrl)
+				leader.EXPECT().Acquire(gomock.Any()).Return(make(chan struct{}), nil)
+				j := jobmocks.NewMockContextJob(ctrl)
+				j.EXPECT().Name().Return("test").AnyTimes()
+				j.EXPECT().RunContext(gomock.Any()).Return(nil)
+				return leader, j
+			},
+		},
+		{
+			name: "执行失败",
+			mock: func(ctrl *gomock.Controller) (Leader, Job) {
+				leader := jobmocks.NewMockLeader(ctrl)
+				leader.EXPECT().Acquire(gomock.Any()).Return(make(chan struct{}), nil)
+				j := jobmocks.NewMockContextJob(ctrl)
+				j.EXPECT().Name().Return("test").AnyTimes()
+				j.EXPECT().RunContext(gomock.Any()).Return(errors.New("执行失败"))
+				return leader, j
+			},
+			wantErr: errors.New("执行失败"),
+		},
+		{
+			name: "执行过程中失去leader身份",
+			mock: func(ctrl *gomock.Controller) (Leader, Job) {
+				leader := jobmocks.NewMockLeader(ctrl)
+				lost := make(chan struct{})
+				leader.EXPECT().Acquire(gomock.Any()).Return(lost, nil)
+				j := jobmocks.NewMockContextJob(ctrl)
+				j.EXPECT().Name().Return("test").AnyTimes()
+				j.EXPECT().RunContext(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+					close(lost)
+					select {
+					case <-ctx.Done():
+						return ctx.Err()
+					case <-time.After(time.Second):
+						return errors.New("ctx 没有被取消")
+					}
+				})
+				return leader, j
+			},
+			wantErr: ErrLeadershipLost,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			leader, j := tc.mock(ctrl)
+			err := NewLeaderJob(j, leader, logger.NewNopLogger()).Run()
+			if errors.Is(tc.wantErr, ErrLeadershipLost) {
+				assert.ErrorIs(t, err, ErrLeadershipLost)
+				return
+			}
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
+
+func TestRedisLeader(t *testing.T) {
+	const key = "job:ranking"
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) redis.Cmdable
+		// expiration 决定了多久续约一次
+		expiration time.Duration
+		test       func(t *testing.T, leader *RedisLeader)
+	}{
+		{
+			name: "没有抢到锁",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismocks.NewMockCmdable(ctrl)
+				res := redis.NewCmd(context.Background())
+				//锁被别人持有，lua 脚本返回的不是 OK，重试 3 次
+				res.SetVal(nil)
+				cmd.EXPECT().Eval(gomock.Any(), gomock.Any(), []string{key}, gomock.Any(), gomock.Any()).
+					Return(res).Times(4)
+				return cmd
+			},
+			expiration: time.Minute,
+			test: func(t *testing.T, leader *RedisLeader) {
+				_, err := leader.Acquire(context.Background())
+				assert.Equal(t, ErrNotLeader, err)
+			},
+		},
+		{
+			name: "没有抢到锁也可以释放",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				return redismocks.NewMockCmdable(ctrl)
+			},
+			expiration: time.Minute,
+			test: func(t *testing.T, leader *RedisLeader) {
+				assert.NoError(t, leader.Release(context.Background()))
+			},
+		},
+		{
+			name: "抢到锁之后续约失败",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismocks.NewMockCmdable(ctrl)
+				locked := redis.NewCmd(context.Background())
+				locked.SetVal("OK")
+				//锁被人删了，续约的 lua 脚本返回 0
+				refreshed := redis.NewCmd(context.Background())
+				refreshed.SetVal(int64(0))
+				gomock.InOrder(
+					cmd.EXPECT().Eval(gomock.Any(), gomock.Any(), []string{key}, gomock.Any(), gomock.Any()).
+						Return(locked),
+					cmd.EXPECT().Eval(gomock.Any(), gomock.Any(), []string{key}, gomock.Any(), gomock.Any()).
+						Return(refreshed),
+				)
+				return cmd
+			},
+			expiration: time.Millisecond * 100,
+			test: func(t *testing.T, leader *RedisLeader) {
+				lost, err := leader.Acquire(context.Background())
+				require.NoError(t, err)
+				//已经是 leader 了，不会再去抢锁
+				again, err := leader.Acquire(context.Background())
+				require.NoError(t, err)
+				assert.Equal(t, lost, again)
+				select {
+				case <-lost:
+				case <-time.After(time.Second):
+					t.Fatal("续约失败之后没有失去 leader 身份")
+				}
+				//锁已经丢了，不需要再解锁
+				assert.NoError(t, leader.Release(context.Background()))
+			},
+		},
+		{
+			name: "主动释放",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismocks.NewMockCmdable(ctrl)
+				locked := redis.NewCmd(context.Background())
+				locked.SetVal("OK")
+				unlocked := redis.NewCmd(context.Background())
+				unlocked.SetVal(int64(1))
+				gomock.InOrder(
+					cmd.EXPECT().Eval(gomock.Any(), gomock.Any(), []string{key}, gomock.Any(), gomock.Any()).
+						Return(locked),
+					cmd.EXPECT().Eval(gomock.Any(), gomock.Any(), []string{key}, gomock.Any()).
+						Return(unlocked),
+				)
+				return cmd
+			},
+			expiration: time.Minute,
+			test: func(t *testing.T, leader *RedisLeader) {
+				lost, err := leader.Acquire(context.Background())
+				require.NoError(t, err)
+				assert.NoError(t, leader.Release(context.Background()))
+				select {
+				case <-lost:
+				case <-time.After(time.Second):
+					t.Fatal("释放之后没有失去 leader 身份")
+				}
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			client := rlock.NewClient(tc.mock(ctrl))
+			tc.test(t, NewRedisLeader(client, key, tc.expiration, logger.NewNopLogger()))
+		})
+	}
+}
+
+// TestLeaderJob_RedisLockLost 执行到一半续约失败，任务的 ctx 要被取消
+func TestLeaderJob_RedisLockLost(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	cmd := redismocks.NewMockCmdable(ctrl)
+	locked := redis.NewCmd(context.Background())
+	locked.SetVal("OK")
+	refreshed := redis.NewCmd(context.Background())
+	refreshed.SetVal(int64(0))
+	gomock.InOrder(
+		cmd.EXPECT().Eval(gomock.Any(), gomock.Any(), []string{"job:test"}, gomock.Any(), gomock.Any()).
+			Return(locked),
+		cmd.EXPECT().Eval(gomock.Any(), gomock.Any(), []string{"job:test"}, gomock.Any(), gomock.Any()).
+			Return(refreshed),
+	)
+	leader := NewRedisLeader(rlock.NewClient(cmd), "job:test", time.Millisecond*100, logger.NewNopLogger())
+	j := NewRankingJobV2("test", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, logger.NewNopLogger(), time.Minute)
+	lj := NewLeaderJob(j, leader, logger.NewNopLogger())
+	err := lj.Run()
+	assert.ErrorIs(t, err, ErrLeadershipLost)
+	//没有持有锁的时候关闭不会出错
+	assert.NoError(t, lj.Close())
+}
+
+// fakeElection 每从 terms 里面收到一个 channel 就选上一次，channel 关闭就是租约过期了
+type fakeElection struct {
+	terms    chan chan struct{}
+	resigned atomic.Int32
+}
+
+func newFakeElection() *fakeElection {
+	return &fakeElection{terms: make(chan chan struct{})}
+}
+
+func (f *fakeElection) elect(ctx context.Context) (<-chan struct{}, func(), error) {
+	select {
+	case expired := <-f.terms:
+		return expired, func() {
+			f.resigned.Add(1)
+		}, nil
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+}
+
+// assertNotCampaigning 没有人在参选的话，选票是送不出去的
+func assertNotCampaigning(t *testing.T, election *fakeElection) {
+	select {
+	case election.terms <- make(chan struct{}):
+		t.Fatal("不应该参选")
+	case <-time.After(time.Millisecond * 50):
+	}
+}
+
+func newTestEtcdLeader(election *fakeElection) *EtcdLeader {
+	leader := NewEtcdLeader(nil, "/webook/leader/test", "test", 10, logger.NewNopLogger())
+	leader.elect = election.elect
+	return leader
+}
+
+func TestEtcdLeader(t *testing.T) {
+	testCases := []struct {
+		name string
+		test func(t *testing.T, leader *EtcdLeader, election *fakeElection)
+	}{
+		{
+			name: "后台选上之后才是 leader，租约过期之后重新参选",
+			test: func(t *testing.T, leader *EtcdLeader, election *fakeElection) {
+				_, err := leader.Acquire(context.Background())
+				assert.Equal(t, ErrNotLeader, err)
+				assert.False(t, leader.IsLeader())
+
+				expired := make(chan struct{})
+				election.terms <- expired
+				assert.Eventually(t, leader.IsLeader, time.Second, time.Millisecond*10)
+				lost, err := leader.Acquire(context.Background())
+				require.NoError(t, err)
+
+				close(expired)
+				select {
+				case <-lost:
+				case <-time.After(time.Second):
+					t.Fatal("租约过期之后没有失去 leader 身份")
+				}
+				assert.False(t, leader.IsLeader())
+				assert.Equal(t, int32(1), election.resigned.Load())
+				// 还在后台参选，再次选上
+				election.terms <- make(chan struct{})
+				assert.Eventually(t, leader.IsLeader, time.Second, time.Millisecond*10)
+				assert.NoError(t, leader.Release(context.Background()))
+			},
+		},
+		{
+			name: "主动释放",
+			test: func(t *testing.T, leader *EtcdLeader, election *fakeElection) {
+				_, _ = leader.Acquire(context.Background())
+				election.terms <- make(chan struct{})
+				assert.Eventually(t, leader.IsLeader, time.Second, time.Millisecond*10)
+				lost, err := leader.Acquire(context.Background())
+				require.NoError(t, err)
+				assert.NoError(t, leader.Release(context.Background()))
+				_, ok := <-lost
+				assert.False(t, ok)
+				assert.False(t, leader.IsLeader())
+				assert.Equal(t, int32(1), election.resigned.Load())
+			},
+		},
+		{
+			name: "没有选上的时候释放，停止参选",
+			test: func(t *testing.T, leader *EtcdLeader, election *fakeElection) {
+				_, _ = leader.Acquire(context.Background())
+				assert.NoError(t, leader.Release(context.Background()))
+				assertNotCampaigning(t, election)
+				assert.False(t, leader.IsLeader())
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			election := newFakeElection()
+			tc.test(t, newTestEtcdLeader(election), election)
+		})
+	}
+}
+
+// TestLoadAwareLeader_EtcdLeader etcd 是在后台选上的，第一次 Acquire 返回的是 ErrNotLeader
+func TestLoadAwareLeader_EtcdLeader(t *testing.T) {
+	testCases := []struct {
+		name string
+		// mock 没有选上的时候每次 Acquire 都要看一下自己的负载排名
+		mock func(ctrl *gomock.Controller) service.JobLoadService
+		test func(t *testing.T, leader *LoadAwareLeader, election *fakeElection)
+	}{
+		{
+			name: "选上之后负载变高了也不放弃",
+			mock: func(ctrl *gomock.Controller) service.JobLoadService {
+				loadSvc := svcmocks.NewMockJobLoadService(ctrl)
+				// 选上之后就不再看负载了
+				loadSvc.EXPECT().Rank().Return(0)
+				return loadSvc
+			},
+			test: func(t *testing.T, leader *LoadAwareLeader, election *fakeElection) {
+				_, err := leader.Acquire(context.Background())
+				assert.Equal(t, ErrNotLeader, err)
+				election.terms <- make(chan struct{})
+				assert.Eventually(t, leader.IsLeader, time.Second, time.Millisecond*10)
+
+				lost, err := leader.Acquire(context.Background())
+				require.NoError(t, err)
+				assert.NotNil(t, lost)
+				assert.True(t, leader.IsLeader())
+				assert.Equal(t, int32(0), election.resigned.Load())
+			},
+		},
+		{
+			name: "还没选上的时候负载变高了，停止参选",
+			mock: func(ctrl *gomock.Controller) service.JobLoadService {
+				loadSvc := svcmocks.NewMockJobLoadService(ctrl)
+				gomock.InOrder(
+					loadSvc.EXPECT().Rank().Return(0),
+					loadSvc.EXPECT().Rank().Return(1),
+				)
+				return loadSvc
+			},
+			test: func(t *testing.T, leader *LoadAwareLeader, election *fakeElection) {
+				_, err := leader.Acquire(context.Background())
+				assert.Equal(t, ErrNotLeader, err)
+
+				_, err = leader.Acquire(context.Background())
+				assert.Equal(t, ErrNotLeader, err)
+				assertNotCampaigning(t, election)
+				assert.False(t, leader.IsLeader())
+			},
+		},
+		{
+			name: "负载不是最低的不参选",
+			mock: func(ctrl *gomock.Controller) service.JobLoadService {
+				loadSvc := svcmocks.NewMockJobLoadService(ctrl)
+				loadSvc.EXPECT().Rank().Return(1)
+				return loadSvc
+			},
+			test: func(t *testing.T, leader *LoadAwareLeader, election *fakeElection) {
+				_, err := leader.Acquire(context.Background())
+				assert.Equal(t, ErrNotLeader, err)
+				assertNotCampaigning(t, election)
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			election := newFakeElection()
+			leader := NewLoadAwareLeader(newTestEtcdLeader(election), tc.mock(ctrl))
+			defer leader.Release(context.Background())
+			tc.test(t, leader, election)
+		})
+	}
+}
@@ -0,0 +1,148 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./leader.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./leader.go -package=jobmocks -destination=mocks/leader.mock.go Leader
+//
+
+// Package jobmocks is a generated GoMock package.
+package jobmocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockLeader is a mock of Leader interface.
+type MockLeader struct {
+	ctrl     *gomock.Controller
+	recorder *MockLeaderMockRecorder
+}
+
+// MockLeaderMockRecorder is the mock recorder for MockLeader.
+type MockLeaderMockRecorder struct {
+	mock *MockLeader
+}
+
+// NewMockLeader creates a new mock instance.
+func NewMockLeader(ctrl *gomock.Controller) *MockLeader {
+	mock := &MockLeader{ctrl: ctrl}
+	mock.recorder = &MockLeaderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLeader) EXPECT() *MockLeaderMockRecorder {
+	return m.recorder
+}
+
+// Acquire mocks base method.
+func (m *MockLeader) Acquire(ctx context.Context) (<-chan struct{}, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Acquire", ctx)
+	ret0, _ := ret[0].(<-chan struct{})
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Acquire indicates an expected call of Acquire.
+func (mr *MockLeaderMockRecorder) Acquire(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Acquire", reflect.TypeOf((*MockLeader)(nil).Acquire), ctx)
+}
+
+// IsLeader mocks base method.
+func (m *MockLeader) IsLeader() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsLeader")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// IsLeader indicates an expected call of IsLeader.
+func (mr *MockLeaderMockRecorder) IsLeader() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsLeader", reflect.TypeOf((*MockLeader)(nil).IsLeader))
+}
+
+// Release mocks base method.
+func (m *MockLeader) Release(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Release", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Release indicates an expected call of Release.
+func (mr *MockLeaderMockRecorder) Release(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Release", reflect.TypeOf((*MockLeader)(nil).Release), ctx)
+}
+
+// MockContextJob is a mock of ContextJob interface.
+type MockContextJob struct {
+	ctrl     *gomock.Controller
+	recorder *MockContextJobMockRecorder
+}
+
+// MockContextJobMockRecorder is the mock recorder for MockContextJob.
+type MockContextJobMockRecorder struct {
+	mock *MockContextJob
+}
+
+// NewMockContextJob creates a new mock instance.
+func NewMockContextJob(ctrl *gomock.Controller) *MockContextJob {
+	mock := &MockContextJob{ctrl: ctrl}
+	mock.recorder = &MockContextJobMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockContextJob) EXPECT() *MockContextJobMockRecorder {
+	return m.recorder
+}
+
+// Name mocks base method.
+func (m *MockContextJob) Name() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Name")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// Name indicates an expected call of Name.
+func (mr *MockContextJobMockRecorder) Name() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Name", reflect.TypeOf((*MockContextJob)(nil).Name))
+}
+
+// Run mocks base method.
+func (m *MockContextJob) Run() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Run")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Run indicates an expected call of Run.
+func (mr *MockContextJobMockRecorder) Run() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Run", reflect.TypeOf((*MockContextJob)(nil).Run))
+}
+
+// RunContext mocks base method.
+func (m *MockContextJob) RunContext(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RunContext", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RunContext indicates an expected call of RunContext.
+func (mr *MockContextJobMockRecorder) RunContext(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RunContext", reflect.TypeOf((*MockContextJob)(nil).RunContext), ctx)
+}
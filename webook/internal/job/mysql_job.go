@@ -2,8 +2,12 @@ package job
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"github.com/prometheus/client_golang/prometheus"
 	"golang.org/x/sync/semaphore"
+	"strconv"
+	"sync/atomic"
 	"time"
 	"xiaoweishu/webook/internal/domain"
 	"xiaoweishu/webook/internal/service"
@@ -16,7 +20,14 @@ import (
 type Executor interface {
 	Name() string
 	//Exec ctx 是全局控制，Executor的实现者注意要正确处理ctx超时或取消
-	Exec(ctx context.Context, j domain.Job) error
+	//返回的字符串是执行结果的摘要，会记录在执行历史里面
+	Exec(ctx context.Context, j domain.Job) (string, error)
+}
+
+// CfgValidator 需要任务配置的执行器实现这个接口，创建和修改任务的时候就会校验配置，
+// 不用等到调度的时候才发现配错了
+type CfgValidator interface {
+	ValidateCfg(cfg string) error
 }
 
 // LocalFuncExecutor 调用本地方法的
@@ -28,39 +39,128 @@ func (l *LocalFuncExecutor) Name() string {
 	return "local"
 }
 
-func (l *LocalFuncExecutor) Exec(ctx context.Context, j domain.Job) error {
+func (l *LocalFuncExecutor) Exec(ctx context.Context, j domain.Job) (string, error) {
 	//根据调度器的名字取出对应的执行方法
 	fn, ok := l.funcs[j.Name]
 	if !ok {
-		return fmt.Errorf("未注册本地方法%s", j.Name)
+		return "", fmt.Errorf("未注册本地方法%s", j.Name)
 	}
-	return fn(ctx, j) //执行该函数，并返回错误
+	return "", fn(ctx, j) //执行该函数，并返回错误
 }
 
 type Scheduler struct {
 	dbTimeout time.Duration
-	svc       service.CronJobService
-	l         logger2.LoggerV1
-	limiter   *semaphore.Weighted
+	// misfireThreshold 抢占到的时候比预定时间晚了这么多，就算错过了调度
+	misfireThreshold time.Duration
+	svc              service.CronJobService
+	l                logger2.LoggerV1
+	limiter          *semaphore.Weighted
+	// capacity 最多同时执行多少个任务，和 limiter 的大小一样，用来算负载
+	capacity int64
+	// running 正在执行的任务数
+	running   atomic.Int64
 	executors map[string]Executor
+	// execSvc 记录执行历史，为 nil 的时候不记录
+	execSvc service.JobExecutionService
+	// vector 每个任务执行的耗时，按照是否成功区分，失败的次数就是 success=false 的 count
+	vector *prometheus.SummaryVec
+	// shardSvc 为 nil 的时候分片的任务也当成普通任务执行
+	shardSvc service.JobShardService
+	// loadSvc 为 nil 的时候不考虑负载
+	loadSvc        service.JobLoadService
+	reportInterval time.Duration
+	// preemptDelay 负载每比别人高一名，抢占之前多等这么久
+	preemptDelay time.Duration
+	// shardPollInterval 拆分之后多久查一次分片的进度
+	shardPollInterval time.Duration
+	// wfSvc 为 nil 的时候任务之间的依赖不起作用
+	wfSvc service.WorkflowService
 }
 
 func NewScheduler(svc service.CronJobService, l logger2.LoggerV1) *Scheduler {
 	return &Scheduler{
-		svc:       svc,
-		l:         l,
-		limiter:   semaphore.NewWeighted(100),
-		executors: map[string]Executor{},
+		svc:              svc,
+		l:                l,
+		dbTimeout:        time.Second,
+		misfireThreshold: time.Minute,
+		limiter:          semaphore.NewWeighted(100),
+		capacity:         100,
+		executors:        map[string]Executor{},
 	}
 }
+
+// NewSchedulerV1 记录每一次执行的历史，并且上报执行耗时
+func NewSchedulerV1(svc service.CronJobService, execSvc service.JobExecutionService,
+	l logger2.LoggerV1, opt prometheus.SummaryOpts) *Scheduler {
+	vector := prometheus.NewSummaryVec(opt, []string{"job", "success"})
+	prometheus.MustRegister(vector)
+	return &Scheduler{
+		svc:              svc,
+		l:                l,
+		dbTimeout:        time.Second,
+		misfireThreshold: time.Minute,
+		limiter:          semaphore.NewWeighted(100),
+		capacity:         100,
+		executors:        map[string]Executor{},
+		execSvc:          execSvc,
+		vector:           vector,
+	}
+}
+
+// NewSchedulerV2 支持分片执行大任务和按照依赖执行工作流，最多同时执行 capacity 个任务，
+// 并且定时上报负载，负载低的实例优先抢占
+func NewSchedulerV2(svc service.CronJobService, execSvc service.JobExecutionService,
+	shardSvc service.JobShardService, loadSvc service.JobLoadService, wfSvc service.WorkflowService,
+	l logger2.LoggerV1, opt prometheus.SummaryOpts, capacity int64) *Scheduler {
+	s := NewSchedulerV1(svc, execSvc, l, opt)
+	s.limiter = semaphore.NewWeighted(capacity)
+	s.capacity = capacity
+	s.shardSvc = shardSvc
+	s.loadSvc = loadSvc
+	s.wfSvc = wfSvc
+	s.reportInterval = time.Second * 3
+	s.preemptDelay = time.Millisecond * 200
+	s.shardPollInterval = time.Second
+	return s
+}
+
+// RegisterExecutor 任务的 Executor 字段就是执行器的名字
+func (s *Scheduler) RegisterExecutor(exec Executor) {
+	s.executors[exec.Name()] = exec
+}
 func (l *LocalFuncExecutor) RegisterFunc(name string, fn func(ctx context.Context, j domain.Job) error) {
 	l.funcs[name] = fn
 }
 
+// RegisterShardedFunc 按照 id 范围拆分的任务，idRange 返回要处理的数据的最小和最大 id，
+// fn 只处理这一片负责的 [start, end)。不分片执行的时候处理整个范围
+func (l *LocalFuncExecutor) RegisterShardedFunc(name string,
+	idRange func(ctx context.Context) (int64, int64, error),
+	fn func(ctx context.Context, j domain.Job, start, end int64) error) {
+	l.funcs[name] = func(ctx context.Context, j domain.Job) error {
+		minId, maxId, err := idRange(ctx)
+		if err != nil {
+			return err
+		}
+		if minId > maxId {
+			//没有数据
+			return nil
+		}
+		start, end := j.Shard.Range(minId, maxId)
+		if start >= end {
+			return nil
+		}
+		return fn(ctx, j, start, end)
+	}
+}
+
 func NewLocalFuncExecutor() *LocalFuncExecutor {
 	return &LocalFuncExecutor{funcs: map[string]func(ctx context.Context, j domain.Job) error{}}
 }
 func (s *Scheduler) Schedule(ctx context.Context) error {
+	if s.loadSvc != nil {
+		go s.reportLoad(ctx)
+	}
 	//上面会传入一个带超时的ctx来进行调度
 	//超市后就会退出for循环
 	for {
@@ -72,13 +172,18 @@ func (s *Scheduler) Schedule(ctx context.Context) error {
 		if err != nil {
 			return err
 		}
+		s.waitForLoad(ctx)
 		//给下面的数据库操作设置了一个时间期限
 		dbctx, cancel := context.WithTimeout(ctx, s.dbTimeout)
-		j, err := s.svc.Preempt(dbctx)
+		j, err := s.preempt(dbctx)
 		cancel() //数据库操作执行完之后直接cancel .及时释放资源
 		if err != nil {
-			//抢锁失败
-			//直接下一轮抢锁，或者睡一段时间再抢
+			//抢锁失败，一般是没有到时间的任务，睡一段时间再抢
+			s.limiter.Release(1)
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Second):
+			}
 			continue
 		}
 		//此时已经拿到了锁，要开始调度执行j
@@ -87,6 +192,46 @@ func (s *Scheduler) Schedule(ctx context.Context) error {
 			//直接中断，也可以下一轮
 			s.l.Error("找不到执行器", logger2.Int64("jid", j.Id),
 				logger2.String("executor", j.Executor))
+			//跳过这一次，不然会被立刻再次抢占
+			execErr := fmt.Errorf("找不到执行器 %s", j.Executor)
+			if j.Shard.Total > 0 {
+				dbctx, cancel = context.WithTimeout(ctx, s.dbTimeout)
+				_ = s.shardSvc.Finish(dbctx, j, execErr)
+				cancel()
+			} else {
+				//在工作流里面的话这一步算失败，不然整个工作流会一直停在这里
+				s.startStep(ctx, &j)
+				dbctx, cancel = context.WithTimeout(ctx, s.dbTimeout)
+				_ = s.svc.ResetNextTime(dbctx, j)
+				cancel()
+				s.finishStep(j, execErr)
+			}
+			s.limiter.Release(1)
+			j.CancelFunc()
+			continue
+		}
+
+		if j.Shard.Total > 0 {
+			go s.runShard(ctx, exec, j)
+			continue
+		}
+
+		//上游触发的任务没有错过调度这种说法，跳过了整个工作流就停住了
+		if j.MisfirePolicy == domain.JobMisfireSkip && j.Expression != "" &&
+			j.Misfired(time.Now(), s.misfireThreshold) {
+			//错过的这一次不执行了，直接等下一次
+			s.l.Warn("任务错过了调度时间，跳过", logger2.Int64("jid", j.Id),
+				logger2.String("nextTime", j.NextExecTime.String()))
+			dbctx, cancel = context.WithTimeout(ctx, s.dbTimeout)
+			_ = s.svc.ResetNextTime(dbctx, j)
+			cancel()
+			s.limiter.Release(1)
+			j.CancelFunc()
+			continue
+		}
+
+		if j.Shards > 1 && s.shardSvc != nil {
+			go s.runSharded(ctx, j)
 			continue
 		}
 
@@ -96,19 +241,301 @@ func (s *Scheduler) Schedule(ctx context.Context) error {
 				s.limiter.Release(1)
 				j.CancelFunc() //执行任务完成，取消掉定时器，下一此调用会重新生成
 			}()
-			err1 := exec.Exec(ctx, j)
+			s.startStep(ctx, &j)
+			err1 := s.exec(ctx, exec, j)
+			if leaseLost(j) {
+				s.l.Warn("任务已经被别的实例抢占了，放弃这一次的结果", logger2.Int64("jid", j.Id),
+					logger2.Error(err1))
+				return
+			}
 			if err1 != nil {
 				s.l.Error("任务执行失败", logger2.Int64("jid", j.Id),
+					logger2.Int("retries", j.Retries),
 					logger2.Error(err1))
+				dbctx, cancel := context.WithTimeout(context.Background(), s.dbTimeout)
+				retried, err2 := s.svc.Retry(dbctx, j)
+				cancel()
+				if err2 != nil {
+					s.l.Error("安排任务重试失败",
+						logger2.Int64("jid", j.Id),
+						logger2.Error(err2))
+					return
+				}
+				if !retried {
+					//重试次数用完了，工作流到这里就停了
+					s.finishStep(j, err1)
+				}
 				return
 			}
 			//只有成功了，才会去设置下次调度的时间，调度的任务和当前的任务是一样的
 			err1 = s.svc.ResetNextTime(ctx, j)
+			if errors.Is(err1, service.ErrJobPreempted) {
+				s.l.Warn("任务已经被别的实例抢占了，放弃这一次的结果", logger2.Int64("jid", j.Id))
+				return
+			}
 			if err1 != nil {
 				s.l.Error("重置下次执行时间失败",
 					logger2.Int64("jid", j.Id),
 					logger2.Error(err1))
 			}
+			s.finishStep(j, nil)
 		}()
 	}
 }
+
+// preempt 先抢分片，分片都在执行中了再抢任务，这样大任务能尽快执行完
+func (s *Scheduler) preempt(ctx context.Context) (domain.Job, error) {
+	if s.shardSvc != nil {
+		j, err := s.shardSvc.Preempt(ctx)
+		if err == nil {
+			return j, nil
+		}
+	}
+	return s.svc.Preempt(ctx)
+}
+
+// runShard 执行一个分片，失败了由 shardSvc 决定要不要重试
+func (s *Scheduler) runShard(ctx context.Context, exec Executor, j domain.Job) {
+	defer func() {
+		s.limiter.Release(1)
+		j.CancelFunc()
+	}()
+	err := s.exec(ctx, exec, j)
+	if leaseLost(j) {
+		s.l.Warn("分片已经被别的实例抢占了，放弃这一次的结果", logger2.Int64("jid", j.Id),
+			logger2.Int("shard", j.Shard.Idx))
+		return
+	}
+	if err != nil {
+		s.l.Error("分片执行失败", logger2.Int64("jid", j.Id),
+			logger2.Int("shard", j.Shard.Idx),
+			logger2.Int("retries", j.Shard.Retries),
+			logger2.Error(err))
+	}
+	dbctx, cancel := context.WithTimeout(context.Background(), s.dbTimeout)
+	defer cancel()
+	err = s.shardSvc.Finish(dbctx, j, err)
+	if err != nil {
+		s.l.Error("更新分片执行结果失败", logger2.Int64("jid", j.Id),
+			logger2.Int("shard", j.Shard.Idx),
+			logger2.Error(err))
+	}
+}
+
+// runSharded 把任务这一轮拆成分片，然后等所有的分片执行完，分片本身由各个实例抢占执行。
+// 等待期间一直在续约，这个实例挂了的话别的实例会抢到任务，重新拆分的时候分片不会重复创建
+func (s *Scheduler) runSharded(ctx context.Context, j domain.Job) {
+	defer func() {
+		s.limiter.Release(1)
+		j.CancelFunc()
+	}()
+	s.startStep(ctx, &j)
+	err := s.record(ctx, j, func(ctx context.Context) (string, error) {
+		return s.waitShards(ctx, j)
+	})
+	if leaseLost(j) {
+		//任务被别人抢走了，抢到任务的实例会继续等这一轮的分片，工作流的这一步也交给它
+		return
+	}
+	if ctx.Err() != nil {
+		//调度器退出了，工作流的这一步算失败。任务释放之后会被重新抢占，继续等这一轮的分片
+		s.finishStep(j, ctx.Err())
+		return
+	}
+	if err != nil {
+		//分片自己已经重试过了，这里不再重试整个任务
+		s.l.Error("分片任务执行失败", logger2.Int64("jid", j.Id), logger2.Error(err))
+	}
+	dbctx, cancel := context.WithTimeout(context.Background(), s.dbTimeout)
+	defer cancel()
+	er := s.svc.ResetNextTime(dbctx, j)
+	if errors.Is(er, service.ErrJobPreempted) {
+		s.l.Warn("任务已经被别的实例抢占了，放弃这一次的结果", logger2.Int64("jid", j.Id))
+		return
+	}
+	if er != nil {
+		s.l.Error("重置下次执行时间失败",
+			logger2.Int64("jid", j.Id),
+			logger2.Error(er))
+	}
+	s.finishStep(j, err)
+}
+
+// startStep 任务在工作流里面的话记录这一步开始了，j.RunId 改成所属的执行记录
+func (s *Scheduler) startStep(ctx context.Context, j *domain.Job) {
+	if s.wfSvc == nil {
+		return
+	}
+	dbctx, cancel := context.WithTimeout(ctx, s.dbTimeout)
+	runId, err := s.wfSvc.StartStep(dbctx, *j)
+	cancel()
+	if err != nil {
+		s.l.Error("记录工作流步骤开始失败", logger2.Int64("jid", j.Id),
+			logger2.Int64("runId", j.RunId), logger2.Error(err))
+	}
+	j.RunId = runId
+}
+
+// finishStep 记录这一步的结果，成功了触发下游，失败了结束整个工作流
+func (s *Scheduler) finishStep(j domain.Job, execErr error) {
+	if s.wfSvc == nil || j.RunId == 0 {
+		return
+	}
+	//要查步骤、查依赖、触发下游，比一般的数据库操作多给点时间
+	ctx, cancel := context.WithTimeout(context.Background(), s.dbTimeout*3)
+	defer cancel()
+	err := s.wfSvc.FinishStep(ctx, j, execErr)
+	if err != nil {
+		s.l.Error("记录工作流步骤结果失败", logger2.Int64("jid", j.Id),
+			logger2.Int64("runId", j.RunId), logger2.Error(err))
+	}
+}
+
+// waitShards 返回所有分片的汇总结果，有分片失败就返回 error
+func (s *Scheduler) waitShards(ctx context.Context, j domain.Job) (string, error) {
+	dbctx, cancel := context.WithTimeout(ctx, s.dbTimeout)
+	err := s.shardSvc.Split(dbctx, j)
+	cancel()
+	if err != nil {
+		return "", err
+	}
+	ticker := time.NewTicker(s.shardPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-ticker.C:
+		}
+		dbctx, cancel = context.WithTimeout(ctx, s.dbTimeout)
+		p, err := s.shardSvc.Progress(dbctx, j)
+		cancel()
+		if err != nil {
+			s.l.Error("查询分片进度失败", logger2.Int64("jid", j.Id), logger2.Error(err))
+			continue
+		}
+		if !p.Done() {
+			continue
+		}
+		output := fmt.Sprintf("%d 个分片，成功 %d 个，失败 %d 个", p.Total, p.Success, p.Failed)
+		if p.Failed > 0 {
+			return output, fmt.Errorf("任务 %s 有 %d 个分片失败", j.Name, p.Failed)
+		}
+		return output, nil
+	}
+}
+
+// waitForLoad 负载不是最低的实例晚一点再抢，让负载低的实例先抢到
+func (s *Scheduler) waitForLoad(ctx context.Context) {
+	if s.loadSvc == nil {
+		return
+	}
+	rank := s.loadSvc.Rank()
+	if rank == 0 {
+		return
+	}
+	delay := min(time.Duration(rank)*s.preemptDelay, time.Second)
+	select {
+	case <-ctx.Done():
+	case <-time.After(delay):
+	}
+}
+
+// reportLoad 定时上报负载，负载是正在执行的任务占 capacity 的百分比
+func (s *Scheduler) reportLoad(ctx context.Context) {
+	ticker := time.NewTicker(s.reportInterval)
+	defer ticker.Stop()
+	for {
+		load := int(s.running.Load() * 100 / s.capacity)
+		rctx, cancel := context.WithTimeout(ctx, s.dbTimeout)
+		err := s.loadSvc.Report(rctx, load)
+		cancel()
+		if err != nil {
+			s.l.Warn("上报负载失败", logger2.Error(err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// exec 用执行器执行任务或者分片，配置了超时时间的超时之后取消 ctx
+func (s *Scheduler) exec(ctx context.Context, exec Executor, j domain.Job) error {
+	s.running.Add(1)
+	defer s.running.Add(-1)
+	return s.record(ctx, j, func(ctx context.Context) (string, error) {
+		if j.Timeout > 0 {
+			//超时之后 ctx 会被取消，执行器要自己处理好
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, j.Timeout)
+			defer cancel()
+		}
+		return exec.Exec(ctx, j)
+	})
+}
+
+// record 执行 fn，记录执行历史和耗时。执行历史写失败不影响任务本身，
+// 任务续约失败之后 fn 的 ctx 会被取消
+func (s *Scheduler) record(ctx context.Context, j domain.Job,
+	fn func(ctx context.Context) (string, error)) error {
+	ctx, cancel := withLease(ctx, j)
+	defer cancel()
+	var eid int64
+	if s.execSvc != nil {
+		attempt := j.Retries + 1
+		if j.Shard.Total > 0 {
+			attempt = j.Shard.Retries + 1
+		}
+		dbctx, cancel := context.WithTimeout(ctx, s.dbTimeout)
+		id, err := s.execSvc.Start(dbctx, j, attempt)
+		cancel()
+		if err != nil {
+			s.l.Error("记录任务开始执行失败", logger2.Int64("jid", j.Id), logger2.Error(err))
+		}
+		eid = id
+	}
+	start := time.Now()
+	output, err := fn(ctx)
+	if s.vector != nil {
+		s.vector.WithLabelValues(j.Name, strconv.FormatBool(err == nil)).
+			Observe(float64(time.Since(start).Milliseconds()))
+	}
+	if eid > 0 {
+		//任务的 ctx 可能已经超时了，这里用新的
+		dbctx, cancel := context.WithTimeout(context.Background(), s.dbTimeout)
+		er := s.execSvc.Finish(dbctx, eid, output, err)
+		cancel()
+		if er != nil {
+			s.l.Error("记录任务执行结果失败", logger2.Int64("jid", j.Id), logger2.Error(er))
+		}
+	}
+	return err
+}
+
+// withLease 任务续约失败之后取消 ctx，让执行器尽快停下来
+func withLease(ctx context.Context, j domain.Job) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	if j.Lost == nil {
+		return ctx, cancel
+	}
+	go func() {
+		select {
+		case <-j.Lost:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
+// leaseLost 任务已经不在自己手上了，不能再更新它的状态，也不能推进工作流，这些交给新抢到它的实例
+func leaseLost(j domain.Job) bool {
+	select {
+	case <-j.Lost:
+		return true
+	default:
+		return false
+	}
+}
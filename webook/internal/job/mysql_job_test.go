@@ -0,0 +1,277 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"sync/atomic"
+	"testing"
+	"time"
+	"xiaoweishu/webook/internal/domain"
+	"xiaoweishu/webook/internal/service"
+	svcmocks "xiaoweishu/webook/internal/service/mocks"
+	"xiaoweishu/webook/pkg/logger"
+)
+
+// blockingExecutor 一直执行到 ctx 被取消
+type blockingExecutor struct {
+	canceled atomic.Bool
+}
+
+func (b *blockingExecutor) Name() string {
+	return "blocking"
+}
+
+func (b *blockingExecutor) Exec(ctx context.Context, j domain.Job) (string, error) {
+	select {
+	case <-ctx.Done():
+		b.canceled.Store(true)
+		return "", ctx.Err()
+	case <-time.After(time.Millisecond * 100):
+		return "", errors.New("执行失败")
+	}
+}
+
+func TestScheduler_Schedule(t *testing.T) {
+	testCases := []struct {
+		name string
+		// lose 执行中途任务被别人抢走
+		lose bool
+		mock func(ctrl *gomock.Controller, j domain.Job) service.CronJobService
+
+		wantCanceled bool
+	}{
+		{
+			name: "执行失败，安排重试",
+			mock: func(ctrl *gomock.Controller, j domain.Job) service.CronJobService {
+				svc := svcmocks.NewMockCronJobService(ctrl)
+				svc.EXPECT().Preempt(gomock.Any()).Return(j, nil)
+				//第一次之后都抢不到
+				svc.EXPECT().Preempt(gomock.Any()).Return(domain.Job{}, errors.New("没有可以抢占的任务")).AnyTimes()
+				svc.EXPECT().Retry(gomock.Any(), gomock.Any()).Return(true, nil)
+				return svc
+			},
+		},
+		{
+			name: "执行中途被别人抢走了",
+			lose: true,
+			// 被抢走的任务不能再更新状态，不会重试也不会重置下次执行的时间
+			mock: func(ctrl *gomock.Controller, j domain.Job) service.CronJobService {
+				svc := svcmocks.NewMockCronJobService(ctrl)
+				svc.EXPECT().Preempt(gomock.Any()).Return(j, nil)
+				svc.EXPECT().Preempt(gomock.Any()).Return(domain.Job{}, errors.New("没有可以抢占的任务")).AnyTimes()
+				return svc
+			},
+			wantCanceled: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			lost := make(chan struct{})
+			released := make(chan struct{})
+			j := domain.Job{Id: 1, Name: "job", Executor: "blocking", Lost: lost,
+				CancelFunc: func() {
+					close(released)
+				}}
+			exec := &blockingExecutor{}
+			s := NewScheduler(tc.mock(ctrl, j), logger.NewNopLogger())
+			s.RegisterExecutor(exec)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go func() {
+				_ = s.Schedule(ctx)
+			}()
+			if tc.lose {
+				time.Sleep(time.Millisecond * 20)
+				close(lost)
+			}
+			select {
+			case <-released:
+			case <-time.After(time.Second):
+				t.Fatal("任务没有释放")
+			}
+			assert.Equal(t, tc.wantCanceled, exec.canceled.Load())
+		})
+	}
+}
+
+func TestLocalFuncExecutor_RegisterShardedFunc(t *testing.T) {
+	testCases := []struct {
+		name  string
+		minId int64
+		maxId int64
+		shard domain.JobShard
+
+		wantCalled bool
+		wantStart  int64
+		wantEnd    int64
+	}{
+		{
+			name:       "不分片处理全部",
+			minId:      1,
+			maxId:      100,
+			wantCalled: true,
+			wantStart:  1,
+			wantEnd:    101,
+		},
+		{
+			name:       "第一片",
+			minId:      1,
+			maxId:      100,
+			shard:      domain.JobShard{Idx: 0, Total: 3},
+			wantCalled: true,
+			wantStart:  1,
+			wantEnd:    35,
+		},
+		{
+			name:       "最后一片不满",
+			minId:      1,
+			maxId:      100,
+			shard:      domain.JobShard{Idx: 2, Total: 3},
+			wantCalled: true,
+			wantStart:  69,
+			wantEnd:    101,
+		},
+		{
+			name:  "id 比分片少，后面的分片是空的",
+			minId: 1,
+			maxId: 2,
+			shard: domain.JobShard{Idx: 3, Total: 4},
+		},
+		{
+			name:  "没有数据",
+			minId: 1,
+			maxId: 0,
+			shard: domain.JobShard{Idx: 0, Total: 4},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var called bool
+			var start, end int64
+			l := NewLocalFuncExecutor()
+			l.RegisterShardedFunc("job", func(ctx context.Context) (int64, int64, error) {
+				return tc.minId, tc.maxId, nil
+			}, func(ctx context.Context, j domain.Job, s, e int64) error {
+				called = true
+				start, end = s, e
+				return nil
+			})
+			_, err := l.Exec(context.Background(), domain.Job{Name: "job", Shard: tc.shard})
+			assert.NoError(t, err)
+			assert.Equal(t, tc.wantCalled, called)
+			assert.Equal(t, tc.wantStart, start)
+			assert.Equal(t, tc.wantEnd, end)
+		})
+	}
+}
+
+func TestScheduler_RunSharded(t *testing.T) {
+	testCases := []struct {
+		name     string
+		splitErr error
+		// progresses 依次是每一次查询到的分片进度
+		progresses []domain.JobShardProgress
+
+		wantResets int
+	}{
+		{
+			name: "等所有分片执行完",
+			progresses: []domain.JobShardProgress{
+				{Total: 3, Waiting: 2, Running: 1},
+				{Total: 3, Running: 1, Success: 2},
+				{Total: 3, Success: 3},
+			},
+			wantResets: 1,
+		},
+		{
+			// 分片自己重试过了，整个任务不再重试，等下一轮
+			name: "有分片失败",
+			progresses: []domain.JobShardProgress{
+				{Total: 3, Success: 2, Failed: 1},
+			},
+			wantResets: 1,
+		},
+		{
+			name:       "拆分失败",
+			splitErr:   errors.New("mock db error"),
+			wantResets: 1,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := svcmocks.NewMockCronJobService(ctrl)
+			svc.EXPECT().ResetNextTime(gomock.Any(), gomock.Any()).Return(nil).Times(tc.wantResets)
+			released := make(chan struct{})
+			shardSvc := svcmocks.NewMockJobShardService(ctrl)
+			shardSvc.EXPECT().Split(gomock.Any(), gomock.Any()).Return(tc.splitErr)
+			calls := make([]any, 0, len(tc.progresses))
+			for _, p := range tc.progresses {
+				calls = append(calls, shardSvc.EXPECT().Progress(gomock.Any(), gomock.Any()).Return(p, nil))
+			}
+			gomock.InOrder(calls...)
+			s := NewScheduler(svc, logger.NewNopLogger())
+			s.shardSvc = shardSvc
+			s.shardPollInterval = time.Millisecond
+			require.NoError(t, s.limiter.Acquire(context.Background(), 1))
+			s.runSharded(context.Background(), domain.Job{Id: 1, Name: "job", Shards: 3,
+				CancelFunc: func() {
+					close(released)
+				}})
+			// 执行完释放了任务，也归还了额度
+			assert.True(t, s.limiter.TryAcquire(100))
+			_, ok := <-released
+			assert.False(t, ok)
+		})
+	}
+}
+
+func TestScheduler_WaitForLoad(t *testing.T) {
+	testCases := []struct {
+		name string
+		rank int
+
+		wantMin time.Duration
+		wantMax time.Duration
+	}{
+		{
+			name:    "负载最低的不等",
+			rank:    0,
+			wantMax: time.Millisecond * 10,
+		},
+		{
+			name:    "排第二的等两倍",
+			rank:    2,
+			wantMin: time.Millisecond * 40,
+			wantMax: time.Millisecond * 100,
+		},
+		{
+			name:    "最多等一秒",
+			rank:    1000,
+			wantMin: time.Second,
+			wantMax: time.Second + time.Millisecond*100,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			loadSvc := svcmocks.NewMockJobLoadService(ctrl)
+			loadSvc.EXPECT().Rank().Return(tc.rank)
+			s := NewScheduler(nil, logger.NewNopLogger())
+			s.loadSvc = loadSvc
+			s.preemptDelay = time.Millisecond * 20
+			start := time.Now()
+			s.waitForLoad(context.Background())
+			duration := time.Since(start)
+			assert.True(t, duration >= tc.wantMin, duration)
+			assert.True(t, duration <= tc.wantMax, duration)
+		})
+	}
+}
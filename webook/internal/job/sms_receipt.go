@@ -0,0 +1,61 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"time"
+	"xiaoweishu/webook/internal/service"
+	"xiaoweishu/webook/internal/service/sms"
+	logger2 "xiaoweishu/webook/pkg/logger"
+)
+
+// SmsReceiptPullJob 去需要主动拉取回执的供应商那里拉回执。
+// 拉过的回执供应商不会再返回，所以要套上 LeaderJob，只在一个实例上执行
+type SmsReceiptPullJob struct {
+	// pullers 供应商的名字到 puller
+	pullers map[string]sms.ReceiptPuller
+	svc     service.SmsMessageService
+	l       logger2.LoggerV1
+	timeout time.Duration
+}
+
+func NewSmsReceiptPullJob(pullers map[string]sms.ReceiptPuller, svc service.SmsMessageService,
+	l logger2.LoggerV1, timeout time.Duration) *SmsReceiptPullJob {
+	return &SmsReceiptPullJob{
+		pullers: pullers,
+		svc:     svc,
+		l:       l,
+		timeout: timeout,
+	}
+}
+
+func (j *SmsReceiptPullJob) Name() string {
+	return "sms_receipt_pull"
+}
+
+func (j *SmsReceiptPullJob) Run() error {
+	return j.RunContext(context.Background())
+}
+
+// RunContext 一个供应商出错了不影响别的供应商
+func (j *SmsReceiptPullJob) RunContext(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, j.timeout)
+	defer cancel()
+	var errs []error
+	for provider, puller := range j.pullers {
+		receipts, err := puller.PullReceipts(ctx)
+		if err != nil {
+			j.l.Error("拉取短信回执失败", logger2.String("provider", provider), logger2.Error(err))
+			errs = append(errs, err)
+			continue
+		}
+		if len(receipts) == 0 {
+			continue
+		}
+		err = j.svc.HandleReceipts(ctx, provider, receipts)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
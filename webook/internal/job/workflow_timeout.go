@@ -0,0 +1,41 @@
+package job
+
+import (
+	"context"
+	"time"
+	"xiaoweishu/webook/internal/service"
+	logger2 "xiaoweishu/webook/pkg/logger"
+)
+
+// WorkflowTimeoutJob 结束执行太久的工作流，结束是幂等的，多个实例同时跑也没关系
+type WorkflowTimeoutJob struct {
+	svc     service.WorkflowService
+	l       logger2.LoggerV1
+	timeout time.Duration
+}
+
+func NewWorkflowTimeoutJob(svc service.WorkflowService, l logger2.LoggerV1,
+	timeout time.Duration) *WorkflowTimeoutJob {
+	return &WorkflowTimeoutJob{
+		svc:     svc,
+		l:       l,
+		timeout: timeout,
+	}
+}
+
+func (w *WorkflowTimeoutJob) Name() string {
+	return "workflow_timeout"
+}
+
+func (w *WorkflowTimeoutJob) Run() error {
+	ctx, cancel := context.WithTimeout(context.Background(), w.timeout)
+	defer cancel()
+	cnt, err := w.svc.Timeout(ctx)
+	if err != nil {
+		return err
+	}
+	if cnt > 0 {
+		w.l.Warn("结束了超时的工作流", logger2.Int64("cnt", cnt))
+	}
+	return nil
+}
@@ -3,7 +3,6 @@ package repository
 import (
 	"context"
 	"github.com/ecodeclub/ekit/slice"
-	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 	"time"
 	"xiaoweishu/webook/internal/domain"
@@ -12,6 +11,10 @@ import (
 	"xiaoweishu/webook/pkg/logger"
 )
 
+// ErrArticleNotFound 文章不存在，比如线上库里还没有这篇文章
+var ErrArticleNotFound = dao.ErrRecordNotFound
+
+//go:generate mockgen -source=./article.go -package=repomocks -destination=mocks/article.mock.go ArticleRepository
 type ArticleRepository interface {
 	Create(ctx context.Context, art domain.Article) (int64, error)
 	Update(ctx context.Context, art domain.Article) error
@@ -21,8 +24,8 @@ type ArticleRepository interface {
 	GetById(ctx context.Context, id int64) (domain.Article, error)
 	GetPubById(ctx context.Context, id int64) (domain.Article, error)
 	ListPub(ctx context.Context, start time.Time, offset int, limit int) ([]domain.Article, error)
-	Like100(ctx *gin.Context, biz string) ([]domain.Like100, error)
-	GetTopArticles(ctx context.Context, biz string, number int) error
+	// ListPubByIds 批量查询还是发表状态的文章，不查作者，也不走缓存
+	ListPubByIds(ctx context.Context, ids []int64) ([]domain.Article, error)
 }
 
 type CachedArticleRepository struct {
@@ -32,18 +35,6 @@ type CachedArticleRepository struct {
 	userRepo UserRepository
 }
 
-func (c *CachedArticleRepository) GetTopArticles(ctx context.Context, biz string, number int) error {
-	articles, err := c.dao.GetTopArticles(ctx, biz, number)
-	if err != nil {
-		return err
-	}
-	return c.cache.UpdateTopArticles(ctx, biz, articles)
-}
-
-func (c *CachedArticleRepository) Like100(ctx *gin.Context, biz string) ([]domain.Like100, error) {
-	return c.cache.Like100(biz)
-}
-
 func NewCachedArticleRepository(dao dao.ArticleDAO,
 	userRepo UserRepository,
 	cache cache.ArticleCache) ArticleRepository {
@@ -63,6 +54,20 @@ func (c *CachedArticleRepository) ListPub(ctx context.Context, start time.Time,
 			return c.toDomain(dao.Article(src))
 		}), nil
 }
+
+func (c *CachedArticleRepository) ListPubByIds(ctx context.Context, ids []int64) ([]domain.Article, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	arts, err := c.dao.ListPubByIds(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	return slice.Map[dao.PublishedArticle, domain.Article](arts,
+		func(idx int, src dao.PublishedArticle) domain.Article {
+			return c.toDomain(dao.Article(src))
+		}), nil
+}
 func (c CachedArticleRepository) Create(ctx context.Context, art domain.Article) (int64, error) {
 	id, err := c.dao.Insert(ctx, c.toEntity(art))
 	if err != nil {
@@ -197,15 +202,17 @@ func (c *CachedArticleRepository) toEntity(art domain.Article) dao.Article {
 		Id:       art.Id,
 		Title:    art.Title,
 		Content:  art.Content,
+		Category: art.Category,
 		AuthorId: art.Author.Id,
 		Status:   art.Status.ToUint8(),
 	}
 }
 func (c *CachedArticleRepository) toDomain(art dao.Article) domain.Article {
 	return domain.Article{
-		Id:      art.Id,
-		Title:   art.Title,
-		Content: art.Content,
+		Id:       art.Id,
+		Title:    art.Title,
+		Content:  art.Content,
+		Category: art.Category,
 		Author: domain.Author{
 			Id: art.AuthorId,
 		},
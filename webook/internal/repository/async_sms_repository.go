@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 	"xiaoweishu/webook/internal/domain"
 	"xiaoweishu/webook/internal/repository/dao"
 
@@ -16,7 +17,8 @@ type AsyncSmsRepository interface {
 	// 你叫做 Create 或者 Insert 也可以
 	Add(ctx context.Context, s domain.AsyncSms) error
 	PreemptWaitingSMS(ctx context.Context) (domain.AsyncSms, error)
-	ReportScheduleResult(ctx context.Context, id int64, success bool) error
+	// ReportScheduleResult 失败了并且还能重试的，等 backoff 之后再重试
+	ReportScheduleResult(ctx context.Context, id int64, success bool, backoff time.Duration) error
 }
 
 type asyncSmsRepository struct {
@@ -54,12 +56,13 @@ func (a *asyncSmsRepository) PreemptWaitingSMS(ctx context.Context) (domain.Asyn
 		Numbers:  as.Config.Val.Numbers,
 		Args:     as.Config.Val.Args,
 		RetryMax: as.RetryMax,
+		RetryCnt: as.RetryCnt,
 	}, nil
 }
 
-func (a *asyncSmsRepository) ReportScheduleResult(ctx context.Context, id int64, success bool) error {
+func (a *asyncSmsRepository) ReportScheduleResult(ctx context.Context, id int64, success bool, backoff time.Duration) error {
 	if success {
 		return a.dao.MarkSuccess(ctx, id)
 	}
-	return a.dao.MarkFailed(ctx, id)
+	return a.dao.MarkFailed(ctx, id, time.Now().Add(backoff).UnixMilli())
 }
@@ -5,7 +5,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/redis/go-redis/v9"
-	"strconv"
 	"time"
 	"xiaoweishu/webook/internal/domain"
 )
@@ -18,46 +17,12 @@ type ArticleCache interface {
 	Set(ctx context.Context, art domain.Article) error
 	GetPub(ctx context.Context, id int64) (domain.Article, error)
 	SetPub(ctx context.Context, res domain.Article) error
-	Like100(biz string) ([]domain.Like100, error)
-	UpdateTopArticles(ctx context.Context, biz string, articles map[string]int64) error
 }
 
 type ArticleRedisCache struct {
 	client redis.Cmdable
 }
 
-func (a ArticleRedisCache) UpdateTopArticles(ctx context.Context, biz string, articles map[string]int64) error {
-	key := biz + "_likes"
-	a.client.Del(ctx, key)
-
-	for bizID, likes := range articles {
-		a.client.ZAdd(ctx, key, redis.Z{Score: float64(likes), Member: bizID})
-	}
-	return nil
-}
-
-func (a ArticleRedisCache) Like100(biz string) ([]domain.Like100, error) {
-	ctx := context.Background()
-	key := biz + "_likes"
-	likes, err := a.client.ZRevRangeWithScores(ctx, key, 0, 99).Result()
-	if err != nil {
-		return nil, err
-	}
-	var topLikes []domain.Like100
-	for _, like := range likes {
-		bizId, err := strconv.ParseInt(like.Member.(string), 10, 64)
-		if err != nil {
-			return nil, err
-		}
-		topLikes = append(topLikes, domain.Like100{
-			BizId:   bizId,
-			LikeCnt: int64(like.Score),
-			Biz:     biz,
-		})
-	}
-	return topLikes, nil
-}
-
 func (a ArticleRedisCache) GetFirstPage(ctx context.Context, uid int64) ([]domain.Article, error) {
 	key := a.firstKey(uid)
 	val, err := a.client.Get(ctx, key).Bytes()
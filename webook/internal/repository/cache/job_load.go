@@ -0,0 +1,51 @@
+package cache
+
+import (
+	"context"
+	_ "embed"
+	"github.com/redis/go-redis/v9"
+	"strconv"
+	"time"
+)
+
+//go:embed lua/job_load_report.lua
+var luaJobLoadReport string
+
+// JobLoadCache 各个实例上报的负载
+type JobLoadCache interface {
+	// Report 顺便清理掉 expiration 之内没有上报过的实例
+	Report(ctx context.Context, instance string, load int, expiration time.Duration) error
+	// Rank 负载比 instance 低的实例有几个，0 就是负载最低的。没有上报过返回 ErrKeyNotExist
+	Rank(ctx context.Context, instance string) (int, error)
+}
+
+type JobLoadRedisCache struct {
+	client   redis.Cmdable
+	loadKey  string
+	aliveKey string
+}
+
+func NewJobLoadRedisCache(client redis.Cmdable) JobLoadCache {
+	return &JobLoadRedisCache{
+		client:   client,
+		loadKey:  "job:load",
+		aliveKey: "job:load:alive",
+	}
+}
+
+func (c *JobLoadRedisCache) Report(ctx context.Context, instance string, load int, expiration time.Duration) error {
+	now := time.Now()
+	return c.client.Eval(ctx, luaJobLoadReport, []string{c.loadKey, c.aliveKey},
+		instance, load, now.UnixMilli(), now.Add(-expiration).UnixMilli()).Err()
+}
+
+func (c *JobLoadRedisCache) Rank(ctx context.Context, instance string) (int, error) {
+	load, err := c.client.ZScore(ctx, c.loadKey, instance).Result()
+	if err != nil {
+		return 0, err
+	}
+	//负载一样的不算，不然同样负载的实例里面永远是名字最小的那个优先
+	cnt, err := c.client.ZCount(ctx, c.loadKey, "-inf",
+		"("+strconv.FormatFloat(load, 'f', -1, 64)).Result()
+	return int(cnt), err
+}
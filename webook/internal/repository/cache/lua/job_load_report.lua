@@ -0,0 +1,10 @@
+-- KEYS[1] 实例的负载，KEYS[2] 实例上报的时间
+-- ARGV: 实例名, 负载, 现在的毫秒数, 这个时间之前上报的实例算是下线了
+redis.call("ZADD", KEYS[1], ARGV[2], ARGV[1])
+redis.call("ZADD", KEYS[2], ARGV[3], ARGV[1])
+local expired = redis.call("ZRANGEBYSCORE", KEYS[2], "-inf", "(" .. ARGV[4])
+if #expired > 0 then
+    redis.call("ZREM", KEYS[1], unpack(expired))
+    redis.call("ZREM", KEYS[2], unpack(expired))
+end
+return #expired
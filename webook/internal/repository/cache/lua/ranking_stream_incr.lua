@@ -0,0 +1,20 @@
+-- KEYS[1] 候选文章的更新时间，KEYS[2] 交互计数，KEYS[3] 分数
+-- ARGV: aid, 阅读数变化, 点赞数变化, 收藏数变化, 阅读权重, 点赞权重, 收藏权重, 半衰期毫秒数
+local aid = ARGV[1]
+local utime = redis.call("ZSCORE", KEYS[1], aid)
+if not utime then
+    -- 不是候选文章，比如已经出了时间窗口，或者还没等到校正任务加进来
+    return 0
+end
+local readCnt = redis.call("HINCRBY", KEYS[2], aid .. ":read", ARGV[2])
+local likeCnt = redis.call("HINCRBY", KEYS[2], aid .. ":like", ARGV[3])
+local collectCnt = redis.call("HINCRBY", KEYS[2], aid .. ":collect", ARGV[4])
+local w = readCnt * tonumber(ARGV[5]) + likeCnt * tonumber(ARGV[6]) + collectCnt * tonumber(ARGV[7])
+if w <= 0 then
+    redis.call("ZREM", KEYS[3], aid)
+    return 1
+end
+-- w * 2^((utime - now) / halfLife) 的排序和 log2(w) + utime / halfLife 一样，和 now 无关
+-- 所以衰减不用定时去算，读的时候再换算成真正的分数
+redis.call("ZADD", KEYS[3], math.log(w) / math.log(2) + tonumber(utime) / tonumber(ARGV[8]), aid)
+return 1
@@ -0,0 +1,17 @@
+-- KEYS[1] 候选文章的更新时间，KEYS[2] 交互计数，KEYS[3] 分数
+-- ARGV: aid, 更新时间毫秒数, 阅读权重, 点赞权重, 收藏权重, 半衰期毫秒数, 是否覆盖计数, 阅读数, 点赞数, 收藏数
+-- 发表的时候只更新时间，计数沿用原来的；校正的时候连计数一起覆盖
+local aid = ARGV[1]
+redis.call("ZADD", KEYS[1], ARGV[2], aid)
+if ARGV[7] == "1" then
+    redis.call("HSET", KEYS[2], aid .. ":read", ARGV[8], aid .. ":like", ARGV[9], aid .. ":collect", ARGV[10])
+end
+local cnts = redis.call("HMGET", KEYS[2], aid .. ":read", aid .. ":like", aid .. ":collect")
+local w = (tonumber(cnts[1]) or 0) * tonumber(ARGV[3]) + (tonumber(cnts[2]) or 0) * tonumber(ARGV[4])
+        + (tonumber(cnts[3]) or 0) * tonumber(ARGV[5])
+if w <= 0 then
+    redis.call("ZREM", KEYS[3], aid)
+    return 1
+end
+redis.call("ZADD", KEYS[3], math.log(w) / math.log(2) + tonumber(ARGV[2]) / tonumber(ARGV[6]), aid)
+return 1
@@ -40,6 +40,16 @@ func (r *RankingLocalCache) ForceGet(ctx context.Context) ([]domain.Article, err
 	return arts, nil
 }
 
+// NewRankingLocalCacheV1 返回具体类型，要用到 ForceGet
+func NewRankingLocalCacheV1() *RankingLocalCache {
+	return &RankingLocalCache{
+		topN: atomicx.NewValue[[]domain.Article](),
+		ddl:  atomicx.NewValueOf(time.Now()),
+		//比计算热榜的间隔长一点，计算的实例每次算完都会刷新
+		expiration: time.Minute * 3,
+	}
+}
+
 //不能redis和local同时new，这样会造成接口重复，只能和V1一样，。使用combined interfaced来解决
 
 func NewRankingLocalCache(topN *atomicx.Value[[]domain.Article],
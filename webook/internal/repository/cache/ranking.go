@@ -8,6 +8,7 @@ import (
 	"xiaoweishu/webook/internal/domain"
 )
 
+//go:generate mockgen -source=./ranking.go -package=cachemocks -destination=mocks/ranking.mock.go RankingCache
 type RankingCache interface {
 	Set(ctx context.Context, arts []domain.Article) error
 	Get(ctx context.Context) ([]domain.Article, error)
@@ -0,0 +1,97 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"sync"
+	"time"
+	"xiaoweishu/webook/internal/domain"
+)
+
+var ErrRankingBoardNotFound = errors.New("本地缓存里面没有这个榜")
+
+// RankingBoardCache 每个榜单独一个 key
+type RankingBoardCache interface {
+	Set(ctx context.Context, board string, arts []domain.Article) error
+	Get(ctx context.Context, board string) ([]domain.Article, error)
+}
+
+type RankingBoardRedisCache struct {
+	client     redis.Cmdable
+	expiration time.Duration
+}
+
+func NewRankingBoardRedisCache(client redis.Cmdable) *RankingBoardRedisCache {
+	return &RankingBoardRedisCache{
+		client: client,
+		//和总榜一样设置得比计算间隔长很多，任务出问题的时候也能读到旧的榜
+		expiration: time.Hour * 3,
+	}
+}
+
+func (r *RankingBoardRedisCache) Set(ctx context.Context, board string, arts []domain.Article) error {
+	//热榜只展示摘要，复制一份，不改调用方的数据
+	abstracts := make([]domain.Article, len(arts))
+	for i, art := range arts {
+		art.Content = art.Abstract()
+		abstracts[i] = art
+	}
+	val, err := json.Marshal(abstracts)
+	if err != nil {
+		return err
+	}
+	return r.client.Set(ctx, r.key(board), val, r.expiration).Err()
+}
+
+func (r *RankingBoardRedisCache) Get(ctx context.Context, board string) ([]domain.Article, error) {
+	val, err := r.client.Get(ctx, r.key(board)).Bytes()
+	if err != nil {
+		return nil, err
+	}
+	var res []domain.Article
+	err = json.Unmarshal(val, &res)
+	return res, err
+}
+
+func (r *RankingBoardRedisCache) key(board string) string {
+	return fmt.Sprintf("ranking:board:%s", board)
+}
+
+// RankingBoardLocalCache 所有的榜放在一个 map 里面，榜的数量不多
+type RankingBoardLocalCache struct {
+	lock       sync.RWMutex
+	boards     map[string]rankingBoardEntry
+	expiration time.Duration
+}
+
+type rankingBoardEntry struct {
+	arts []domain.Article
+	ddl  time.Time
+}
+
+func NewRankingBoardLocalCache() *RankingBoardLocalCache {
+	return &RankingBoardLocalCache{
+		boards:     make(map[string]rankingBoardEntry),
+		expiration: time.Minute * 3,
+	}
+}
+
+func (r *RankingBoardLocalCache) Set(ctx context.Context, board string, arts []domain.Article) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.boards[board] = rankingBoardEntry{arts: arts, ddl: time.Now().Add(r.expiration)}
+	return nil
+}
+
+func (r *RankingBoardLocalCache) Get(ctx context.Context, board string) ([]domain.Article, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	entry, ok := r.boards[board]
+	if !ok || len(entry.arts) == 0 || entry.ddl.Before(time.Now()) {
+		return nil, ErrRankingBoardNotFound
+	}
+	return entry.arts, nil
+}
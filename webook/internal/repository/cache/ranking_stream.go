@@ -0,0 +1,151 @@
+package cache
+
+import (
+	"context"
+	_ "embed"
+	"github.com/ecodeclub/ekit/slice"
+	"github.com/redis/go-redis/v9"
+	"strconv"
+	"time"
+	"xiaoweishu/webook/internal/domain"
+)
+
+//go:embed lua/ranking_stream_incr.lua
+var luaRankingStreamIncr string
+
+//go:embed lua/ranking_stream_set.lua
+var luaRankingStreamSet string
+
+// RankingStreamCache 增量热榜的候选文章和分数
+type RankingStreamCache interface {
+	// Put 把文章加入候选，已经在候选里面的只更新时间
+	Put(ctx context.Context, aid int64, utime time.Time) error
+	// BatchIncr 不在候选里面的文章直接忽略
+	BatchIncr(ctx context.Context, deltas []domain.RankingDelta) error
+	// Reset 用 candidates 的数据覆盖原来的计数
+	Reset(ctx context.Context, candidates []domain.RankingCandidate) error
+	Remove(ctx context.Context, aids ...int64) error
+	// Prune 删掉 before 之前更新的文章，返回删掉了多少篇
+	Prune(ctx context.Context, before time.Time) (int, error)
+	// Top 分数最高的 n 篇文章的 id
+	Top(ctx context.Context, n int) ([]int64, error)
+}
+
+// RankingStreamRedisCache 三个 key：候选文章的更新时间、交互计数和分数。
+// 分数存的是 log2(加权和) + 更新时间 / 半衰期，排序和衰减之后的分数一样，
+// 所以不需要定时给所有文章重新打分
+type RankingStreamRedisCache struct {
+	client   redis.Cmdable
+	decay    domain.RankingDecay
+	utimeKey string
+	cntKey   string
+	scoreKey string
+}
+
+func NewRankingStreamRedisCache(client redis.Cmdable, decay domain.RankingDecay) RankingStreamCache {
+	return &RankingStreamRedisCache{
+		client:   client,
+		decay:    decay,
+		utimeKey: "ranking:stream:utime",
+		cntKey:   "ranking:stream:cnt",
+		scoreKey: "ranking:stream:score",
+	}
+}
+
+func (r *RankingStreamRedisCache) keys() []string {
+	return []string{r.utimeKey, r.cntKey, r.scoreKey}
+}
+
+// weights 打分参数，顺序和 lua 脚本里面的一致
+func (r *RankingStreamRedisCache) weights() []any {
+	return []any{r.decay.ReadWeight, r.decay.LikeWeight, r.decay.CollectWeight,
+		r.decay.HalfLife.Milliseconds()}
+}
+
+func (r *RankingStreamRedisCache) Put(ctx context.Context, aid int64, utime time.Time) error {
+	args := append([]any{aid, utime.UnixMilli()}, r.weights()...)
+	args = append(args, "0")
+	return r.client.Eval(ctx, luaRankingStreamSet, r.keys(), args...).Err()
+}
+
+func (r *RankingStreamRedisCache) BatchIncr(ctx context.Context, deltas []domain.RankingDelta) error {
+	if len(deltas) == 0 {
+		return nil
+	}
+	pipe := r.client.Pipeline()
+	for _, d := range deltas {
+		args := append([]any{d.Aid, d.ReadCnt, d.LikeCnt, d.CollectCnt}, r.weights()...)
+		pipe.Eval(ctx, luaRankingStreamIncr, r.keys(), args...)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (r *RankingStreamRedisCache) Reset(ctx context.Context, candidates []domain.RankingCandidate) error {
+	if len(candidates) == 0 {
+		return nil
+	}
+	pipe := r.client.Pipeline()
+	for _, c := range candidates {
+		args := append([]any{c.Aid, c.Utime.UnixMilli()}, r.weights()...)
+		args = append(args, "1", c.ReadCnt, c.LikeCnt, c.CollectCnt)
+		pipe.Eval(ctx, luaRankingStreamSet, r.keys(), args...)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (r *RankingStreamRedisCache) Remove(ctx context.Context, aids ...int64) error {
+	if len(aids) == 0 {
+		return nil
+	}
+	members := slice.Map(aids, func(idx int, src int64) any {
+		return src
+	})
+	fields := make([]string, 0, len(aids)*3)
+	for _, aid := range aids {
+		id := strconv.FormatInt(aid, 10)
+		fields = append(fields, id+":read", id+":like", id+":collect")
+	}
+	pipe := r.client.TxPipeline()
+	pipe.ZRem(ctx, r.utimeKey, members...)
+	pipe.ZRem(ctx, r.scoreKey, members...)
+	pipe.HDel(ctx, r.cntKey, fields...)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (r *RankingStreamRedisCache) Prune(ctx context.Context, before time.Time) (int, error) {
+	vals, err := r.client.ZRangeByScore(ctx, r.utimeKey, &redis.ZRangeBy{
+		Min: "-inf",
+		//不包括 before 这个时间点
+		Max: "(" + strconv.FormatInt(before.UnixMilli(), 10),
+	}).Result()
+	if err != nil || len(vals) == 0 {
+		return 0, err
+	}
+	aids := make([]int64, 0, len(vals))
+	for _, val := range vals {
+		aid, er := strconv.ParseInt(val, 10, 64)
+		if er == nil {
+			aids = append(aids, aid)
+		}
+	}
+	return len(aids), r.Remove(ctx, aids...)
+}
+
+func (r *RankingStreamRedisCache) Top(ctx context.Context, n int) ([]int64, error) {
+	vals, err := r.client.ZRevRange(ctx, r.scoreKey, 0, int64(n-1)).Result()
+	if err != nil {
+		return nil, err
+	}
+	res := make([]int64, 0, len(vals))
+	for _, val := range vals {
+		aid, er := strconv.ParseInt(val, 10, 64)
+		if er != nil {
+			continue
+		}
+		res = append(res, aid)
+	}
+	return res, nil
+}
@@ -12,6 +12,8 @@ type Article struct {
 	Id      int64  `gorm:"primaryKey,autoIncrement" bson:"id,omitempty"`
 	Title   string `gorm:"type=varchar(4096)" bson:"title,omitempty"`
 	Content string `gorm:"type=BLOB" bson:"content,omitempty"`
+	// 分类热榜用
+	Category string `gorm:"type:varchar(64)" bson:"category,omitempty"`
 	// 我要根据创作者ID来查询
 	AuthorId int64 `gorm:"index" bson:"author_id,omitempty"`
 	Status   uint8 `bson:"status,omitempty"`
@@ -28,36 +30,22 @@ type ArticleDAO interface {
 	GetById(ctx context.Context, id int64) (Article, error)
 	GetPubById(ctx context.Context, id int64) (PublishedArticle, error)
 	ListPub(ctx context.Context, start time.Time, offset int, limit int) ([]PublishedArticle, error)
-	GetTopArticles(ctx context.Context, biz string, number int) (map[string]int64, error)
+	// ListPubByIds 只返回还是发表状态的文章，不保证顺序
+	ListPubByIds(ctx context.Context, ids []int64) ([]PublishedArticle, error)
 }
 
 type ArticleGORMDAO struct {
 	db *gorm.DB
 }
 
-func (a *ArticleGORMDAO) GetTopArticles(ctx context.Context, biz string, number int) (map[string]int64, error) {
-	var results []struct {
-		BizID string `gorm:"column:bizid"`
-		Likes int64  `gorm:"column:likes"`
-	}
-	err := a.db.WithContext(ctx).Raw("SELECT bizid, likes FROM articles WHERE biz = ? ORDER BY likes DESC LIMIT ?", biz, number).Scan(&results).Error
-	if err != nil {
-		return nil, err
-	}
-
-	articles := make(map[string]int64)
-	for _, result := range results {
-		articles[result.BizID] = result.Likes
-	}
-	return articles, nil
-}
-
 func (a *ArticleGORMDAO) ListPub(ctx context.Context, start time.Time, offset int, limit int) ([]PublishedArticle, error) {
 	var res []PublishedArticle
 	const ArticleStatusPublished = 2
 	err := a.db.WithContext(ctx).
 		Where("utime < ? AND status = ?",
 			start.UnixMilli(), ArticleStatusPublished).
+		//热榜按照更新时间从新到旧翻页，翻到窗口之外就停下来
+		Order("utime DESC").
 		Offset(offset).Limit(limit).
 		Find(&res).Error
 	return res, err
@@ -76,10 +64,11 @@ func (a ArticleGORMDAO) UpdateById(ctx context.Context, art Article) error {
 	//这里是数据操作必须文章id和作者id都需要命中，否则不会更新，就保证了避免别人乱更新文章的问题
 	res := a.db.WithContext(ctx).Model(&art).
 		Where("id=? AND author_id=?", art.Id, art.AuthorId).Updates(map[string]interface{}{
-		"title":   art.Title,
-		"content": art.Content,
-		"utime":   now,
-		"status":  art.Status,
+		"title":    art.Title,
+		"content":  art.Content,
+		"category": art.Category,
+		"utime":    now,
+		"status":   art.Status,
 	})
 	if res.Error != nil {
 		return res.Error
@@ -118,10 +107,11 @@ func (a ArticleGORMDAO) Sync(ctx context.Context, art Article) (int64, error) {
 		err = tx.Clauses(clause.OnConflict{
 			Columns: []clause.Column{{Name: "id"}},
 			DoUpdates: clause.Assignments(map[string]interface{}{
-				"title":   pubArt.Title,
-				"content": pubArt.Content,
-				"utime":   now,
-				"status":  pubArt.Status,
+				"title":    pubArt.Title,
+				"content":  pubArt.Content,
+				"category": pubArt.Category,
+				"utime":    now,
+				"status":   pubArt.Status,
 			}),
 		}).Create(&pubArt).Error
 		return err
@@ -171,9 +161,10 @@ func (a ArticleGORMDAO) SyncV1(ctx context.Context, art Article) (int64, error)
 	err = tx.Clauses(clause.OnConflict{
 		Columns: []clause.Column{{Name: "id"}},
 		DoUpdates: clause.Assignments(map[string]interface{}{
-			"title":   pubArt.Title,
-			"content": pubArt.Content,
-			"utime":   pubArt.Utime,
+			"title":    pubArt.Title,
+			"content":  pubArt.Content,
+			"category": pubArt.Category,
+			"utime":    pubArt.Utime,
 		}),
 	}).Create(&pubArt).Error
 	if err != nil {
@@ -227,6 +218,15 @@ func (a ArticleGORMDAO) GetPubById(ctx context.Context, id int64) (PublishedArti
 	return res, err
 }
 
+func (a *ArticleGORMDAO) ListPubByIds(ctx context.Context, ids []int64) ([]PublishedArticle, error) {
+	var res []PublishedArticle
+	const ArticleStatusPublished = 2
+	err := a.db.WithContext(ctx).
+		Where("id IN ? AND status = ?", ids, ArticleStatusPublished).
+		Find(&res).Error
+	return res, err
+}
+
 func NewArticleGORMDAO(db *gorm.DB) ArticleDAO {
 	return &ArticleGORMDAO{
 		db: db,
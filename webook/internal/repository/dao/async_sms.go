@@ -15,7 +15,8 @@ type AsyncSmsDAO interface {
 	Insert(ctx context.Context, s AsyncSms) error
 	GetWaitingSMS(ctx context.Context) (AsyncSms, error)
 	MarkSuccess(ctx context.Context, id int64) error
-	MarkFailed(ctx context.Context, id int64) error
+	// MarkFailed 没有到达重试次数的，nextTime 之后再重试
+	MarkFailed(ctx context.Context, id int64, nextTime int64) error
 }
 
 const (
@@ -26,6 +27,10 @@ const (
 	asyncStatusSuccess
 )
 
+// asyncSmsLease 抢占之后多久没有上报结果，别的节点可以再次抢占
+// 一般是发送过程中节点崩溃了
+const asyncSmsLease = time.Minute
+
 type GORMAsyncSmsDAO struct {
 	db *gorm.DB
 }
@@ -37,7 +42,11 @@ func NewGORMAsyncSmsDAO(db *gorm.DB) AsyncSmsDAO {
 }
 
 func (g *GORMAsyncSmsDAO) Insert(ctx context.Context, s AsyncSms) error {
-	return g.db.Create(&s).Error
+	now := time.Now().UnixMilli()
+	s.Ctime = now
+	s.Utime = now
+	s.NextTime = now
+	return g.db.WithContext(ctx).Create(&s).Error
 }
 
 func (g *GORMAsyncSmsDAO) GetWaitingSMS(ctx context.Context) (AsyncSms, error) {
@@ -46,29 +55,32 @@ func (g *GORMAsyncSmsDAO) GetWaitingSMS(ctx context.Context) (AsyncSms, error) {
 	// 并发不过百，随便写
 	var s AsyncSms
 	err := g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		// 为了避开一些偶发性的失败，我们只找 1 分钟前的异步短信发送
+		// next_time 到了才能发送，失败重试的退避也是靠它
 		now := time.Now().UnixMilli()
-		endTime := now - time.Minute.Milliseconds()
 		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
-			Where("utime < ? and status = ?",
-				endTime, asyncStatusWaiting).First(&s).Error
+			Where("next_time <= ? and status = ?",
+				now, asyncStatusWaiting).First(&s).Error
 		// SELECT xx FROM xxx WHERE xx FOR UPDATE，锁住了
 		if err != nil {
 			return err
 		}
 
-		// 只要更新了更新时间，根据我们前面的规则，就不可能被别的节点抢占了
+		// 把 next_time 往后推，确保我在发送过程中，没人会再次抢到它
 		err = tx.Model(&AsyncSms{}).
 			Where("id = ?", s.Id).
 			Updates(map[string]any{
 				"retry_cnt": gorm.Expr("retry_cnt + 1"),
-				// 更新成了当前时间戳，确保我在发送过程中，没人会再次抢到它
-				// 也相当于，重试间隔一分钟
-				"utime": now,
+				"next_time": now + asyncSmsLease.Milliseconds(),
+				"utime":     now,
 			}).Error
 		return err
 	})
-	return s, err
+	if err != nil {
+		return AsyncSms{}, err
+	}
+	// 返回这一次是第几次发送
+	s.RetryCnt++
+	return s, nil
 }
 
 func (g *GORMAsyncSmsDAO) MarkSuccess(ctx context.Context, id int64) error {
@@ -81,14 +93,16 @@ func (g *GORMAsyncSmsDAO) MarkSuccess(ctx context.Context, id int64) error {
 		}).Error
 }
 
-func (g *GORMAsyncSmsDAO) MarkFailed(ctx context.Context, id int64) error {
+func (g *GORMAsyncSmsDAO) MarkFailed(ctx context.Context, id int64, nextTime int64) error {
 	now := time.Now().UnixMilli()
 	return g.db.WithContext(ctx).Model(&AsyncSms{}).
-		// 只有到达了重试次数才会更新
-		Where("id =? and `retry_cnt`>=`retry_max`", id).
+		Where("id =?", id).
 		Updates(map[string]any{
-			"utime":  now,
-			"status": asyncStatusFailed,
+			"utime": now,
+			// 到达了重试次数才标记为失败，不然等 next_time 到了再重试
+			"status": gorm.Expr("CASE WHEN `retry_cnt`>=`retry_max` THEN ? ELSE ? END",
+				asyncStatusFailed, asyncStatusWaiting),
+			"next_time": nextTime,
 		}).Error
 }
 
@@ -100,7 +114,9 @@ type AsyncSms struct {
 	RetryCnt int
 	// 重试的最大次数
 	RetryMax int
-	Status   uint8
+	Status   uint8 `gorm:"index:idx_status_next_time"`
+	// NextTime 什么时候可以发送，毫秒数
+	NextTime int64 `gorm:"index:idx_status_next_time"`
 	Ctime    int64
 	Utime    int64 `gorm:"index"`
 }
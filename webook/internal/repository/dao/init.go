@@ -8,5 +8,15 @@ import (
 func InitTable(db *gorm.DB) error {
 	return db.AutoMigrate(&User{},
 		&Article{},
-		&PublishedArticle{})
+		&PublishedArticle{},
+		&Job{},
+		&JobExecution{},
+		&JobShard{},
+		&JobDependency{},
+		&WorkflowRun{},
+		&WorkflowStep{},
+		&SmsTemplate{},
+		&SmsProviderTemplate{},
+		&AsyncSms{},
+		&SmsMessage{})
 }
@@ -2,15 +2,57 @@ package dao
 
 import (
 	"context"
+	"errors"
+	"github.com/go-sql-driver/mysql"
 	"gorm.io/gorm"
 	"time"
 )
 
+var (
+	ErrJobDuplicateName = errors.New("任务名字冲突")
+	// ErrJobStatusConflict 任务当前的状态不允许这个操作，比如恢复一个没有暂停的任务
+	ErrJobStatusConflict = errors.New("任务状态不对")
+	// ErrJobPreempted 续约太久没成功，任务已经被别的实例重新抢占了，原来的实例不能再更新它
+	ErrJobPreempted = errors.New("任务已经被别的实例抢占了")
+)
+
 type JobDAO interface {
-	Preempt(ctx context.Context) (Job, error)
-	Release(ctx context.Context, jid int64) error
-	UpdateUtime(ctx context.Context, id int64) error
-	UpdateNextTime(ctx context.Context, id int64, t time.Time) error
+	// Preempt 抢占到期的任务，或者 utime 在 staleBefore 之前的执行中的任务，
+	// 后者说明执行它的实例已经很久没有续约了，多半是挂了
+	Preempt(ctx context.Context, owner string, staleBefore time.Time) (Job, error)
+	// Release 和下面几个方法的 version 是抢占之后的版本号，
+	// 任务已经被别的实例重新抢占了的话，version 对不上，Release 什么也不会做，别的返回 ErrJobPreempted
+	Release(ctx context.Context, jid int64, version int) error
+	UpdateUtime(ctx context.Context, id int64, version int) error
+	// UpdateNextTime 同时清空重试次数
+	UpdateNextTime(ctx context.Context, id int64, version int, t time.Time) error
+	// UpdateRetry 失败之后 t 的时候重试，这是第 retries 次重试
+	UpdateRetry(ctx context.Context, id int64, version int, t time.Time, retries int) error
+
+	// Trigger 上游都成功之后立刻执行 jid，只有等待调度的任务能被触发。
+	// run_id 已经是 runId 的说明已经触发过了，和任务暂停了、正在执行一样返回 false
+	Trigger(ctx context.Context, jid int64, runId int64) (bool, error)
+	// UpdateRunId 工作流开始执行的时候记在第一个任务上，和 UpdateNextTime 一样要求任务还在自己手上
+	UpdateRunId(ctx context.Context, jid int64, version int, runId int64) error
+	// FindUpstreams jids 这些任务依赖的上游
+	FindUpstreams(ctx context.Context, jids []int64) ([]JobDependency, error)
+	// FindDownstreams 依赖 jid 的任务
+	FindDownstreams(ctx context.Context, jid int64) ([]int64, error)
+
+	// 下面是给管理后台用的
+	// Insert 和 Update 的 upstreams 是任务全部的上游，会覆盖原来的
+	Insert(ctx context.Context, j Job, upstreams []int64) (int64, error)
+	Update(ctx context.Context, j Job, upstreams []int64) error
+	// Delete 同时删掉和这个任务有关的依赖
+	Delete(ctx context.Context, id int64) error
+	FindById(ctx context.Context, id int64) (Job, error)
+	List(ctx context.Context, offset, limit int) ([]Job, error)
+	// Pause 只有等待调度的和正在执行的任务可以暂停
+	Pause(ctx context.Context, id int64) error
+	// Resume 只有暂停的任务可以恢复，恢复之后 t 的时候调度
+	Resume(ctx context.Context, id int64, t time.Time) error
+	// RunNow 只有等待调度的任务可以立刻执行，立刻执行的不算在原来的工作流里面
+	RunNow(ctx context.Context, id int64) error
 }
 type Job struct {
 	Id         int64  `gorm:"primaryKey,autoIncrement"`
@@ -20,6 +62,18 @@ type Job struct {
 	Cfg        string
 	// 状态来表达，是不是可以抢占，有没有被人抢占
 	Status int
+	// Owner 抢占到任务的实例
+	Owner string `gorm:"type:varchar(128)"`
+
+	MaxRetries int
+	Retries    int
+	// Timeout 毫秒数
+	Timeout       int64
+	MisfirePolicy uint8
+	// Shards 大于 1 的任务每一轮拆成分片执行
+	Shards int
+	// RunId 被上游触发的时候所属的工作流执行记录
+	RunId int64
 
 	Version int
 
@@ -32,13 +86,13 @@ type GORMJobDAO struct {
 	db *gorm.DB
 }
 
-func (dao *GORMJobDAO) Preempt(ctx context.Context) (Job, error) {
+func (dao *GORMJobDAO) Preempt(ctx context.Context, owner string, staleBefore time.Time) (Job, error) {
 	db := dao.db.WithContext(ctx)
 	for {
 		var j Job
 		now := time.Now().UnixMilli()
-		err := db.Where("status= ? AND next_time < ?",
-			jobStatusWaiting, now).First(&j).Error
+		err := db.Where("(status = ? AND next_time < ?) OR (status = ? AND utime < ?)",
+			jobStatusWaiting, now, jobStatusRunning, staleBefore.UnixMilli()).First(&j).Error
 		if err != nil {
 			return j, err
 		}
@@ -48,6 +102,7 @@ func (dao *GORMJobDAO) Preempt(ctx context.Context) (Job, error) {
 		res := db.WithContext(ctx).Model(&Job{}).Where("id=? AND version =?", j.Id, j.Version).
 			Updates(map[string]any{
 				"status":  jobStatusRunning,
+				"owner":   owner,
 				"version": j.Version + 1,
 				"utime":   now,
 			})
@@ -62,33 +117,234 @@ func (dao *GORMJobDAO) Preempt(ctx context.Context) (Job, error) {
 	}
 }
 
-// 释放锁
-func (dao *GORMJobDAO) Release(ctx context.Context, jid int64) error {
+// 释放锁，执行期间被暂停的任务保持暂停
+func (dao *GORMJobDAO) Release(ctx context.Context, jid int64, version int) error {
 	now := time.Now().UnixMilli()
-	return dao.db.WithContext(ctx).Model(&Job{}).Where("id=?", jid).
+	db := dao.db.WithContext(ctx).Model(&Job{})
+	err := db.Where("id=? AND version = ? AND status = ?", jid, version, jobStatusRunning).
 		Updates(map[string]any{
 			"status": jobStatusWaiting,
+			"owner":  "",
 			"utime":  now,
 		}).Error
+	if err != nil {
+		return err
+	}
+	return dao.db.WithContext(ctx).Model(&Job{}).Where("id=? AND version = ? AND status = ?", jid, version, jobStatusPaused).
+		Updates(map[string]any{
+			"owner": "",
+			"utime": now,
+		}).Error
 }
 
 // 续约，高层应该设计一些函数检查utime，utime时间太古老的，就要终止掉，可能进程已经死掉，不能让他继续持有锁
-func (dao *GORMJobDAO) UpdateUtime(ctx context.Context, jid int64) error {
+func (dao *GORMJobDAO) UpdateUtime(ctx context.Context, jid int64, version int) error {
 	now := time.Now().UnixMilli()
-	return dao.db.WithContext(ctx).Model(&Job{}).Where("id=?", jid).
-		Updates(map[string]any{
-			"utime": now,
-		}).Error
+	return dao.updateOwned(ctx, jid, version, map[string]any{
+		"utime": now,
+	})
 }
 
 // 更新下次定时任务的调度时间
-func (dao *GORMJobDAO) UpdateNextTime(ctx context.Context, id int64, t time.Time) error {
+func (dao *GORMJobDAO) UpdateNextTime(ctx context.Context, id int64, version int, t time.Time) error {
 	now := time.Now().UnixMilli()
-	return dao.db.WithContext(ctx).Model(&Job{}).Where("id=?", id).Updates(map[string]any{
+	return dao.updateOwned(ctx, id, version, map[string]any{
 		"utime": now,
 		//netxtime是上层计算好了传下来的，这里只负责数据库操作
 		"next_time": t.UnixMilli(),
-	}).Error
+		"retries":   0,
+	})
+}
+
+func (dao *GORMJobDAO) UpdateRetry(ctx context.Context, id int64, version int, t time.Time, retries int) error {
+	return dao.updateOwned(ctx, id, version, map[string]any{
+		"utime":     time.Now().UnixMilli(),
+		"next_time": t.UnixMilli(),
+		"retries":   retries,
+	})
+}
+
+// updateOwned 只有任务还在自己手上，也就是 version 没变的时候才更新，
+// 不然说明别的实例已经重新抢占了，返回 ErrJobPreempted
+func (dao *GORMJobDAO) updateOwned(ctx context.Context, id int64, version int, vals map[string]any) error {
+	res := dao.db.WithContext(ctx).Model(&Job{}).
+		Where("id = ? AND version = ?", id, version).Updates(vals)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrJobPreempted
+	}
+	return nil
+}
+
+func (dao *GORMJobDAO) Trigger(ctx context.Context, jid int64, runId int64) (bool, error) {
+	res := dao.db.WithContext(ctx).Model(&Job{}).
+		Where("id = ? AND run_id <> ? AND status = ?", jid, runId, jobStatusWaiting).
+		Updates(map[string]any{
+			"run_id":    runId,
+			"retries":   0,
+			"next_time": time.Now().UnixMilli(),
+			"utime":     time.Now().UnixMilli(),
+		})
+	return res.RowsAffected > 0, res.Error
+}
+
+func (dao *GORMJobDAO) UpdateRunId(ctx context.Context, jid int64, version int, runId int64) error {
+	return dao.updateOwned(ctx, jid, version, map[string]any{
+		"run_id": runId,
+		"utime":  time.Now().UnixMilli(),
+	})
+}
+
+func (dao *GORMJobDAO) FindUpstreams(ctx context.Context, jids []int64) ([]JobDependency, error) {
+	var res []JobDependency
+	err := dao.db.WithContext(ctx).Where("jid IN ?", jids).Find(&res).Error
+	return res, err
+}
+
+func (dao *GORMJobDAO) FindDownstreams(ctx context.Context, jid int64) ([]int64, error) {
+	var res []int64
+	err := dao.db.WithContext(ctx).Model(&JobDependency{}).
+		Where("upstream = ?", jid).Pluck("jid", &res).Error
+	return res, err
+}
+
+// setUpstreams 先删后插
+func (dao *GORMJobDAO) setUpstreams(tx *gorm.DB, jid int64, upstreams []int64) error {
+	err := tx.Where("jid = ?", jid).Delete(&JobDependency{}).Error
+	if err != nil || len(upstreams) == 0 {
+		return err
+	}
+	now := time.Now().UnixMilli()
+	deps := make([]JobDependency, 0, len(upstreams))
+	for _, up := range upstreams {
+		deps = append(deps, JobDependency{Jid: jid, Upstream: up, Ctime: now})
+	}
+	return tx.Create(&deps).Error
+}
+
+func (dao *GORMJobDAO) Insert(ctx context.Context, j Job, upstreams []int64) (int64, error) {
+	now := time.Now().UnixMilli()
+	j.Ctime = now
+	j.Utime = now
+	err := dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Create(&j).Error
+		if err != nil {
+			return err
+		}
+		return dao.setUpstreams(tx, j.Id, upstreams)
+	})
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		const uniqueConflictsErrNo uint16 = 1062
+		if mysqlErr.Number == uniqueConflictsErrNo {
+			return 0, ErrJobDuplicateName
+		}
+	}
+	return j.Id, err
+}
+
+// Update 只更新定义，不动状态，正在执行的任务这一次还是按照原来的定义执行
+func (dao *GORMJobDAO) Update(ctx context.Context, j Job, upstreams []int64) error {
+	var affected int64
+	err := dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&Job{}).Where("id = ?", j.Id).Updates(map[string]any{
+			"name":           j.Name,
+			"executor":       j.Executor,
+			"expression":     j.Expression,
+			"cfg":            j.Cfg,
+			"max_retries":    j.MaxRetries,
+			"timeout":        j.Timeout,
+			"misfire_policy": j.MisfirePolicy,
+			"shards":         j.Shards,
+			"retries":        0,
+			"next_time":      j.NextTime,
+			"utime":          time.Now().UnixMilli(),
+		})
+		affected = res.RowsAffected
+		if res.Error != nil || affected == 0 {
+			return res.Error
+		}
+		return dao.setUpstreams(tx, j.Id, upstreams)
+	})
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		const uniqueConflictsErrNo uint16 = 1062
+		if mysqlErr.Number == uniqueConflictsErrNo {
+			return ErrJobDuplicateName
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+func (dao *GORMJobDAO) Delete(ctx context.Context, id int64) error {
+	return dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("jid = ? OR upstream = ?", id, id).Delete(&JobDependency{}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("id = ?", id).Delete(&Job{}).Error
+	})
+}
+
+func (dao *GORMJobDAO) FindById(ctx context.Context, id int64) (Job, error) {
+	var j Job
+	err := dao.db.WithContext(ctx).Where("id = ?", id).First(&j).Error
+	return j, err
+}
+
+func (dao *GORMJobDAO) List(ctx context.Context, offset, limit int) ([]Job, error) {
+	var res []Job
+	err := dao.db.WithContext(ctx).Order("id").
+		Offset(offset).Limit(limit).Find(&res).Error
+	return res, err
+}
+
+func (dao *GORMJobDAO) Pause(ctx context.Context, id int64) error {
+	return dao.updateStatus(ctx, id, []int{jobStatusWaiting, jobStatusRunning}, map[string]any{
+		"status": jobStatusPaused,
+	})
+}
+
+func (dao *GORMJobDAO) Resume(ctx context.Context, id int64, t time.Time) error {
+	return dao.updateStatus(ctx, id, []int{jobStatusPaused}, map[string]any{
+		//暂停之前开始的那一次可能还没执行完，这种恢复成执行中，等它释放，避免两个实例同时执行
+		"status": gorm.Expr("CASE WHEN `owner` = '' THEN ? ELSE ? END",
+			jobStatusWaiting, jobStatusRunning),
+		"next_time": t.UnixMilli(),
+	})
+}
+
+func (dao *GORMJobDAO) RunNow(ctx context.Context, id int64) error {
+	return dao.updateStatus(ctx, id, []int{jobStatusWaiting}, map[string]any{
+		"next_time": time.Now().UnixMilli(),
+		"run_id":    0,
+	})
+}
+
+// updateStatus 任务的状态在 from 里面才更新，不存在的任务返回 ErrRecordNotFound
+func (dao *GORMJobDAO) updateStatus(ctx context.Context, id int64, from []int, vals map[string]any) error {
+	vals["utime"] = time.Now().UnixMilli()
+	res := dao.db.WithContext(ctx).Model(&Job{}).
+		Where("id = ? AND status IN ?", id, from).Updates(vals)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected > 0 {
+		return nil
+	}
+	_, err := dao.FindById(ctx, id)
+	if err != nil {
+		return err
+	}
+	return ErrJobStatusConflict
 }
 
 func NewGORMJobDAO(db *gorm.DB) JobDAO {
@@ -97,6 +353,14 @@ func NewGORMJobDAO(db *gorm.DB) JobDAO {
 	}
 }
 
+// JobDependency 任务之间的依赖，jid 依赖 upstream
+type JobDependency struct {
+	Id       int64 `gorm:"primaryKey,autoIncrement"`
+	Jid      int64 `gorm:"uniqueIndex:jid_upstream"`
+	Upstream int64 `gorm:"uniqueIndex:jid_upstream;index"`
+	Ctime    int64
+}
+
 const (
 	// jobStatusWaiting 没人抢
 	jobStatusWaiting = iota
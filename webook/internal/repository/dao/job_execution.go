@@ -0,0 +1,90 @@
+package dao
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"time"
+)
+
+type JobExecutionDAO interface {
+	Insert(ctx context.Context, e JobExecution) (int64, error)
+	// Finish 更新执行结果
+	Finish(ctx context.Context, e JobExecution) error
+	ListByJob(ctx context.Context, jid int64, offset, limit int) ([]JobExecution, error)
+	// DeleteBefore 删除 t 之前开始的执行记录，返回删除了多少条
+	DeleteBefore(ctx context.Context, t time.Time) (int64, error)
+}
+
+// JobExecution 任务的执行历史，每次执行一条
+type JobExecution struct {
+	Id       int64  `gorm:"primaryKey,autoIncrement"`
+	Jid      int64  `gorm:"index:jid_stime"`
+	Name     string `gorm:"type:varchar(128)"`
+	Instance string `gorm:"type:varchar(128)"`
+	Attempt  int
+	Status   uint8
+	Err      string `gorm:"type:varchar(1024)"`
+	Output   string `gorm:"type:varchar(1024)"`
+	Stime    int64  `gorm:"index:jid_stime;index"`
+	Etime    int64
+	Ctime    int64
+	Utime    int64
+}
+
+// jobExecutionDeleteBatch 一次最多删除这么多条，避免一个大事务长时间锁表、拖慢主从同步
+const jobExecutionDeleteBatch = 1000
+
+type GORMJobExecutionDAO struct {
+	db        *gorm.DB
+	batchSize int
+}
+
+func NewGORMJobExecutionDAO(db *gorm.DB) JobExecutionDAO {
+	return &GORMJobExecutionDAO{db: db, batchSize: jobExecutionDeleteBatch}
+}
+
+func (dao *GORMJobExecutionDAO) Insert(ctx context.Context, e JobExecution) (int64, error) {
+	now := time.Now().UnixMilli()
+	e.Ctime = now
+	e.Utime = now
+	err := dao.db.WithContext(ctx).Create(&e).Error
+	return e.Id, err
+}
+
+func (dao *GORMJobExecutionDAO) Finish(ctx context.Context, e JobExecution) error {
+	return dao.db.WithContext(ctx).Model(&JobExecution{}).Where("id = ?", e.Id).
+		Updates(map[string]any{
+			"status": e.Status,
+			"err":    e.Err,
+			"output": e.Output,
+			"etime":  e.Etime,
+			"utime":  time.Now().UnixMilli(),
+		}).Error
+}
+
+func (dao *GORMJobExecutionDAO) ListByJob(ctx context.Context, jid int64, offset, limit int) ([]JobExecution, error) {
+	var res []JobExecution
+	err := dao.db.WithContext(ctx).Where("jid = ?", jid).
+		Order("stime DESC").
+		Offset(offset).Limit(limit).Find(&res).Error
+	return res, err
+}
+
+// DeleteBefore 分批删除，删不满一批就说明删完了
+func (dao *GORMJobExecutionDAO) DeleteBefore(ctx context.Context, t time.Time) (int64, error) {
+	var total int64
+	for {
+		res := dao.db.WithContext(ctx).Where("stime < ?", t.UnixMilli()).
+			Limit(dao.batchSize).Delete(&JobExecution{})
+		if res.Error != nil {
+			return total, res.Error
+		}
+		total += res.RowsAffected
+		if res.RowsAffected < int64(dao.batchSize) {
+			return total, nil
+		}
+		if ctx.Err() != nil {
+			return total, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestGORMJobExecutionDAO_DeleteBefore(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(t *testing.T) (*sql.DB, sqlmock.Sqlmock)
+
+		wantCnt int64
+		wantErr error
+	}{
+		{
+			name: "分批删除",
+			mock: func(t *testing.T) (*sql.DB, sqlmock.Sqlmock) {
+				db, mock, err := sqlmock.New()
+				require.NoError(t, err)
+				sqlStr := "DELETE FROM `job_executions` WHERE stime < \\? LIMIT \\?"
+				mock.ExpectExec(sqlStr).WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectExec(sqlStr).WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectExec(sqlStr).WillReturnResult(sqlmock.NewResult(0, 1))
+				return db, mock
+			},
+			wantCnt: 5,
+		},
+		{
+			name: "刚好删完一批",
+			mock: func(t *testing.T) (*sql.DB, sqlmock.Sqlmock) {
+				db, mock, err := sqlmock.New()
+				require.NoError(t, err)
+				sqlStr := "DELETE FROM `job_executions` WHERE stime < \\? LIMIT \\?"
+				mock.ExpectExec(sqlStr).WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectExec(sqlStr).WillReturnResult(sqlmock.NewResult(0, 0))
+				return db, mock
+			},
+			wantCnt: 2,
+		},
+		{
+			name: "中途出错，返回已经删除的数量",
+			mock: func(t *testing.T) (*sql.DB, sqlmock.Sqlmock) {
+				db, mock, err := sqlmock.New()
+				require.NoError(t, err)
+				sqlStr := "DELETE FROM `job_executions` WHERE stime < \\? LIMIT \\?"
+				mock.ExpectExec(sqlStr).WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectExec(sqlStr).WillReturnError(errors.New("mock db error"))
+				return db, mock
+			},
+			wantCnt: 2,
+			wantErr: errors.New("mock db error"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sqlDB, mock := tc.mock(t)
+			dao := &GORMJobExecutionDAO{db: newTestGormDB(t, sqlDB), batchSize: 2}
+			cnt, err := dao.DeleteBefore(context.Background(), time.Now())
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantCnt, cnt)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
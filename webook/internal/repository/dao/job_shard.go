@@ -0,0 +1,162 @@
+package dao
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type JobShardDAO interface {
+	// Split 创建 jid 这一轮的 total 个分片，已经创建过的不会重复创建。
+	// 之前几轮的分片顺便删掉，只保留最近一轮的
+	Split(ctx context.Context, jid int64, round int64, total int) error
+	// Preempt 和任务一样，到期的等待中的分片，或者很久没有续约的执行中的分片都可以抢
+	Preempt(ctx context.Context, owner string, staleBefore time.Time) (JobShard, error)
+	// UpdateUtime 和 Finish 在分片已经被别的实例重新抢占的时候返回 ErrJobPreempted
+	UpdateUtime(ctx context.Context, id int64, version int) error
+	// Finish 更新执行结果，status 是等待中的时候 next 之后重试
+	Finish(ctx context.Context, id int64, version int, status uint8, errMsg string, retries int, next int64) error
+	ListByRound(ctx context.Context, jid int64, round int64) ([]JobShard, error)
+	// ListLatest 最近一轮的分片
+	ListLatest(ctx context.Context, jid int64) ([]JobShard, error)
+}
+
+// JobShard 大任务每一轮的分片
+type JobShard struct {
+	Id    int64 `gorm:"primaryKey,autoIncrement"`
+	Jid   int64 `gorm:"uniqueIndex:jid_round_idx"`
+	Round int64 `gorm:"uniqueIndex:jid_round_idx"`
+	Idx   int   `gorm:"uniqueIndex:jid_round_idx"`
+	Total int
+
+	Status   uint8  `gorm:"index:status_next_time"`
+	NextTime int64  `gorm:"index:status_next_time"`
+	Owner    string `gorm:"type:varchar(128)"`
+	Version  int
+	Retries  int
+	Err      string `gorm:"type:varchar(1024)"`
+
+	Ctime int64
+	Utime int64
+}
+
+type GORMJobShardDAO struct {
+	db *gorm.DB
+}
+
+func NewGORMJobShardDAO(db *gorm.DB) JobShardDAO {
+	return &GORMJobShardDAO{db: db}
+}
+
+func (dao *GORMJobShardDAO) Split(ctx context.Context, jid int64, round int64, total int) error {
+	now := time.Now().UnixMilli()
+	shards := make([]JobShard, 0, total)
+	for i := 0; i < total; i++ {
+		shards = append(shards, JobShard{
+			Jid:      jid,
+			Round:    round,
+			Idx:      i,
+			Total:    total,
+			Status:   jobShardStatusWaiting,
+			NextTime: now,
+			Ctime:    now,
+			Utime:    now,
+		})
+	}
+	return dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("jid = ? AND round < ?", jid, round).Delete(&JobShard{}).Error
+		if err != nil {
+			return err
+		}
+		//父任务被别的实例接手之后会再拆一次，已经有的分片保持原样
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&shards).Error
+	})
+}
+
+func (dao *GORMJobShardDAO) Preempt(ctx context.Context, owner string, staleBefore time.Time) (JobShard, error) {
+	db := dao.db.WithContext(ctx)
+	for {
+		var s JobShard
+		now := time.Now().UnixMilli()
+		err := db.Where("(status = ? AND next_time < ?) OR (status = ? AND utime < ?)",
+			jobShardStatusWaiting, now, jobShardStatusRunning, staleBefore.UnixMilli()).First(&s).Error
+		if err != nil {
+			return s, err
+		}
+		//和任务一样用 version 做乐观锁
+		res := db.Model(&JobShard{}).Where("id = ? AND version = ?", s.Id, s.Version).
+			Updates(map[string]any{
+				"status":  jobShardStatusRunning,
+				"owner":   owner,
+				"version": s.Version + 1,
+				"utime":   now,
+			})
+		if res.Error != nil {
+			return JobShard{}, res.Error
+		}
+		if res.RowsAffected == 0 {
+			continue
+		}
+		return s, nil
+	}
+}
+
+func (dao *GORMJobShardDAO) UpdateUtime(ctx context.Context, id int64, version int) error {
+	res := dao.db.WithContext(ctx).Model(&JobShard{}).
+		Where("id = ? AND version = ?", id, version).
+		Updates(map[string]any{
+			"utime": time.Now().UnixMilli(),
+		})
+	return dao.checkOwned(res)
+}
+
+func (dao *GORMJobShardDAO) Finish(ctx context.Context, id int64, version int, status uint8,
+	errMsg string, retries int, next int64) error {
+	res := dao.db.WithContext(ctx).Model(&JobShard{}).
+		Where("id = ? AND version = ? AND status = ?", id, version, jobShardStatusRunning).
+		Updates(map[string]any{
+			"status":    status,
+			"owner":     "",
+			"err":       errMsg,
+			"retries":   retries,
+			"next_time": next,
+			"utime":     time.Now().UnixMilli(),
+		})
+	return dao.checkOwned(res)
+}
+
+// checkOwned 和任务一样，一行都没有更新说明分片已经被别的实例重新抢占了
+func (dao *GORMJobShardDAO) checkOwned(res *gorm.DB) error {
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrJobPreempted
+	}
+	return nil
+}
+
+func (dao *GORMJobShardDAO) ListByRound(ctx context.Context, jid int64, round int64) ([]JobShard, error) {
+	var res []JobShard
+	err := dao.db.WithContext(ctx).Where("jid = ? AND round = ?", jid, round).
+		Order("idx").Find(&res).Error
+	return res, err
+}
+
+func (dao *GORMJobShardDAO) ListLatest(ctx context.Context, jid int64) ([]JobShard, error) {
+	var s JobShard
+	err := dao.db.WithContext(ctx).Where("jid = ?", jid).
+		Order("round DESC").First(&s).Error
+	if err != nil {
+		return nil, err
+	}
+	return dao.ListByRound(ctx, jid, s.Round)
+}
+
+const (
+	jobShardStatusWaiting uint8 = iota
+	jobShardStatusRunning
+	jobShardStatusSuccess
+	jobShardStatusFailed
+)
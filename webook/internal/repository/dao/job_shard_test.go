@@ -0,0 +1,34 @@
+package dao
+
+import (
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestGORMJobShardDAO_UpdateUtime(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	mock.ExpectExec("UPDATE `job_shards` SET `utime`=\\? WHERE id = \\? AND version = \\?").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE `job_shards` SET `utime`=\\? WHERE id = \\? AND version = \\?").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	dao := NewGORMJobShardDAO(newTestGormDB(t, db))
+	assert.NoError(t, dao.UpdateUtime(context.Background(), 1, 2))
+	// 分片已经被别人重新抢占了
+	assert.Equal(t, ErrJobPreempted, dao.UpdateUtime(context.Background(), 1, 2))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGORMJobShardDAO_Finish(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	mock.ExpectExec("UPDATE `job_shards` SET .* WHERE id = \\? AND version = \\? AND status = \\?").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	dao := NewGORMJobShardDAO(newTestGormDB(t, db))
+	err = dao.Finish(context.Background(), 1, 2, jobShardStatusSuccess, "", 0, 0)
+	assert.Equal(t, ErrJobPreempted, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
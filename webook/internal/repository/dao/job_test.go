@@ -0,0 +1,158 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"testing"
+	"time"
+)
+
+func newTestGormDB(t *testing.T, sqlDB *sql.DB) *gorm.DB {
+	db, err := gorm.Open(mysql.New(mysql.Config{
+		Conn:                      sqlDB,
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{
+		SkipDefaultTransaction: true,
+	})
+	require.NoError(t, err)
+	return db
+}
+
+func newJobTestDAO(t *testing.T, sqlDB *sql.DB) *GORMJobDAO {
+	return NewGORMJobDAO(newTestGormDB(t, sqlDB)).(*GORMJobDAO)
+}
+
+func TestGORMJobDAO_Pause(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(t *testing.T) *sql.DB
+
+		wantErr error
+	}{
+		{
+			name: "暂停成功",
+			mock: func(t *testing.T) *sql.DB {
+				db, mock, err := sqlmock.New()
+				require.NoError(t, err)
+				mock.ExpectExec("UPDATE `jobs` SET .* WHERE id = \\? AND status IN \\(\\?,\\?\\)").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				return db
+			},
+		},
+		{
+			name: "已经暂停了",
+			mock: func(t *testing.T) *sql.DB {
+				db, mock, err := sqlmock.New()
+				require.NoError(t, err)
+				mock.ExpectExec("UPDATE `jobs` SET .*").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery("SELECT \\* FROM `jobs` WHERE id = \\?.*").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "status"}).AddRow(1, jobStatusPaused))
+				return db
+			},
+			wantErr: ErrJobStatusConflict,
+		},
+		{
+			name: "任务不存在",
+			mock: func(t *testing.T) *sql.DB {
+				db, mock, err := sqlmock.New()
+				require.NoError(t, err)
+				mock.ExpectExec("UPDATE `jobs` SET .*").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery("SELECT \\* FROM `jobs` WHERE id = \\?.*").
+					WillReturnRows(sqlmock.NewRows([]string{"id"}))
+				return db
+			},
+			wantErr: gorm.ErrRecordNotFound,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dao := newJobTestDAO(t, tc.mock(t))
+			err := dao.Pause(context.Background(), 1)
+			assert.ErrorIs(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestGORMJobDAO_UpdateNextTime(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(t *testing.T) *sql.DB
+
+		wantErr error
+	}{
+		{
+			name: "任务还在自己手上",
+			mock: func(t *testing.T) *sql.DB {
+				db, mock, err := sqlmock.New()
+				require.NoError(t, err)
+				mock.ExpectExec("UPDATE `jobs` SET .* WHERE id = \\? AND version = \\?").
+					WithArgs(sqlmock.AnyArg(), 0, sqlmock.AnyArg(), int64(1), 2).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				return db
+			},
+		},
+		{
+			name: "任务已经被别人重新抢占了",
+			mock: func(t *testing.T) *sql.DB {
+				db, mock, err := sqlmock.New()
+				require.NoError(t, err)
+				mock.ExpectExec("UPDATE `jobs` SET .* WHERE id = \\? AND version = \\?").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				return db
+			},
+			wantErr: ErrJobPreempted,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dao := newJobTestDAO(t, tc.mock(t))
+			err := dao.UpdateNextTime(context.Background(), 1, 2, time.Now())
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
+
+func TestGORMJobDAO_UpdateUtime(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	mock.ExpectExec("UPDATE `jobs` SET `utime`=\\? WHERE id = \\? AND version = \\?").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE `jobs` SET `utime`=\\? WHERE id = \\? AND version = \\?").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	dao := newJobTestDAO(t, db)
+	assert.NoError(t, dao.UpdateUtime(context.Background(), 1, 2))
+	assert.Equal(t, ErrJobPreempted, dao.UpdateUtime(context.Background(), 1, 2))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGORMJobDAO_UpdateRetry(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	mock.ExpectExec("UPDATE `jobs` SET .* WHERE id = \\? AND version = \\?").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	dao := newJobTestDAO(t, db)
+	err = dao.UpdateRetry(context.Background(), 1, 2, time.Now(), 1)
+	assert.Equal(t, ErrJobPreempted, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGORMJobDAO_Trigger(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	// 只有等待调度的任务能被触发
+	mock.ExpectExec("UPDATE `jobs` SET .* WHERE id = \\? AND run_id <> \\? AND status = \\?").
+		WithArgs(sqlmock.AnyArg(), 0, int64(10), sqlmock.AnyArg(), int64(1), int64(10), jobStatusWaiting).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	dao := newJobTestDAO(t, db)
+	ok, err := dao.Trigger(context.Background(), 1, 10)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
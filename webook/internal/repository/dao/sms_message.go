@@ -0,0 +1,97 @@
+package dao
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"time"
+)
+
+type SmsMessageDAO interface {
+	Insert(ctx context.Context, msgs []SmsMessage) error
+	// UpdateStatus 只更新还在等回执的短信，phone 为空就只按照 bizId 找。返回更新了几条
+	UpdateStatus(ctx context.Context, provider, bizId, phone string, status uint8,
+		reason string, reportTime int64) (int64, error)
+	// List phone 为空就是不限手机号，按照发送时间倒序
+	List(ctx context.Context, phone string, start, end int64, offset, limit int) ([]SmsMessage, error)
+	// CountByStatus 按照供应商和状态分组统计
+	CountByStatus(ctx context.Context, start, end int64) ([]SmsMessageStatusCount, error)
+}
+
+// SmsMessage 一个手机号一条
+type SmsMessage struct {
+	Id       int64  `gorm:"primaryKey,autoIncrement"`
+	Provider string `gorm:"type:varchar(64);index:provider_biz_id"`
+	BizId    string `gorm:"type:varchar(128);index:provider_biz_id"`
+	Phone    string `gorm:"type:varchar(32);index:phone_ctime"`
+	TplName  string `gorm:"type:varchar(128)"`
+	Status   uint8
+	Reason   string `gorm:"type:varchar(1024)"`
+	// ReportTime 回执里面的送达时间
+	ReportTime int64
+	Ctime      int64 `gorm:"index:phone_ctime;index"`
+	Utime      int64
+}
+
+type SmsMessageStatusCount struct {
+	Provider string
+	Status   uint8
+	Cnt      int64
+}
+
+type GORMSmsMessageDAO struct {
+	db *gorm.DB
+}
+
+func NewGORMSmsMessageDAO(db *gorm.DB) SmsMessageDAO {
+	return &GORMSmsMessageDAO{db: db}
+}
+
+func (dao *GORMSmsMessageDAO) Insert(ctx context.Context, msgs []SmsMessage) error {
+	now := time.Now().UnixMilli()
+	for i := range msgs {
+		msgs[i].Ctime = now
+		msgs[i].Utime = now
+	}
+	return dao.db.WithContext(ctx).Create(&msgs).Error
+}
+
+func (dao *GORMSmsMessageDAO) UpdateStatus(ctx context.Context, provider, bizId, phone string, status uint8,
+	reason string, reportTime int64) (int64, error) {
+	query := dao.db.WithContext(ctx).Model(&SmsMessage{}).
+		// 回执可能重复推送，已经有结果的不再更新
+		Where("provider = ? AND biz_id = ? AND status = ?", provider, bizId, smsMessageStatusSent)
+	if phone != "" {
+		query = query.Where("phone = ?", phone)
+	}
+	res := query.Updates(map[string]any{
+		"status":      status,
+		"reason":      reason,
+		"report_time": reportTime,
+		"utime":       time.Now().UnixMilli(),
+	})
+	return res.RowsAffected, res.Error
+}
+
+func (dao *GORMSmsMessageDAO) List(ctx context.Context, phone string, start, end int64,
+	offset, limit int) ([]SmsMessage, error) {
+	var res []SmsMessage
+	query := dao.db.WithContext(ctx).Where("ctime >= ? AND ctime < ?", start, end)
+	if phone != "" {
+		query = query.Where("phone = ?", phone)
+	}
+	err := query.Order("ctime DESC").Offset(offset).Limit(limit).Find(&res).Error
+	return res, err
+}
+
+func (dao *GORMSmsMessageDAO) CountByStatus(ctx context.Context, start, end int64) ([]SmsMessageStatusCount, error) {
+	var res []SmsMessageStatusCount
+	err := dao.db.WithContext(ctx).Model(&SmsMessage{}).
+		Select("provider, status, COUNT(*) AS cnt").
+		Where("ctime >= ? AND ctime < ?", start, end).
+		Group("provider, status").
+		Scan(&res).Error
+	return res, err
+}
+
+// smsMessageStatusSent 和 domain.SmsMessageStatusSent 一致
+const smsMessageStatusSent = 1
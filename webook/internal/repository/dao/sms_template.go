@@ -0,0 +1,177 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"github.com/ecodeclub/ekit/sqlx"
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+var ErrSmsTemplateDuplicateName = errors.New("短信模板名字冲突")
+
+type SmsTemplateDAO interface {
+	Insert(ctx context.Context, t SmsTemplate) (int64, error)
+	// Update 只更新内容和参数，名字不能改，改了业务方就找不到了
+	Update(ctx context.Context, t SmsTemplate) error
+	// Delete 连同各个供应商的模板一起删掉
+	Delete(ctx context.Context, id int64) error
+	FindById(ctx context.Context, id int64) (SmsTemplate, error)
+	FindByName(ctx context.Context, name string) (SmsTemplate, error)
+	List(ctx context.Context, offset, limit int) ([]SmsTemplate, error)
+	// UpsertProvider 模板 id 或者签名变了的时候状态会变成 status
+	UpsertProvider(ctx context.Context, p SmsProviderTemplate) error
+	DeleteProvider(ctx context.Context, tid int64, provider string) error
+	// UpdateProviderStatus 审核，返回 ErrRecordNotFound 说明这个供应商还没有模板
+	UpdateProviderStatus(ctx context.Context, tid int64, provider string, status uint8, reason string) error
+	FindProviders(ctx context.Context, tids []int64) ([]SmsProviderTemplate, error)
+}
+
+// SmsTemplate 逻辑上的短信模板
+type SmsTemplate struct {
+	Id      int64  `gorm:"primaryKey,autoIncrement"`
+	Name    string `gorm:"type:varchar(128);uniqueIndex"`
+	Content string `gorm:"type:varchar(1024)"`
+	Args    sqlx.JsonColumn[[]SmsTemplateArg]
+	Ctime   int64
+	Utime   int64
+}
+
+type SmsTemplateArg struct {
+	Name    string
+	Pattern string
+	MaxLen  int
+}
+
+// SmsProviderTemplate 短信模板在某个供应商那边的模板
+type SmsProviderTemplate struct {
+	Id       int64  `gorm:"primaryKey,autoIncrement"`
+	Tid      int64  `gorm:"uniqueIndex:tid_provider"`
+	Provider string `gorm:"type:varchar(64);uniqueIndex:tid_provider"`
+	TplId    string `gorm:"type:varchar(128)"`
+	SignName string `gorm:"type:varchar(128)"`
+	Status   uint8
+	Reason   string `gorm:"type:varchar(1024)"`
+	Ctime    int64
+	Utime    int64
+}
+
+type GORMSmsTemplateDAO struct {
+	db *gorm.DB
+}
+
+func NewGORMSmsTemplateDAO(db *gorm.DB) SmsTemplateDAO {
+	return &GORMSmsTemplateDAO{db: db}
+}
+
+func (dao *GORMSmsTemplateDAO) Insert(ctx context.Context, t SmsTemplate) (int64, error) {
+	now := time.Now().UnixMilli()
+	t.Ctime = now
+	t.Utime = now
+	err := dao.db.WithContext(ctx).Create(&t).Error
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		const uniqueConflictsErrNo uint16 = 1062
+		if mysqlErr.Number == uniqueConflictsErrNo {
+			return 0, ErrSmsTemplateDuplicateName
+		}
+	}
+	return t.Id, err
+}
+
+func (dao *GORMSmsTemplateDAO) Update(ctx context.Context, t SmsTemplate) error {
+	res := dao.db.WithContext(ctx).Model(&SmsTemplate{}).
+		Where("id = ?", t.Id).
+		Updates(map[string]any{
+			"content": t.Content,
+			"args":    t.Args,
+			"utime":   time.Now().UnixMilli(),
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+func (dao *GORMSmsTemplateDAO) Delete(ctx context.Context, id int64) error {
+	return dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("tid = ?", id).Delete(&SmsProviderTemplate{}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("id = ?", id).Delete(&SmsTemplate{}).Error
+	})
+}
+
+func (dao *GORMSmsTemplateDAO) FindById(ctx context.Context, id int64) (SmsTemplate, error) {
+	var res SmsTemplate
+	err := dao.db.WithContext(ctx).Where("id = ?", id).First(&res).Error
+	return res, err
+}
+
+func (dao *GORMSmsTemplateDAO) FindByName(ctx context.Context, name string) (SmsTemplate, error) {
+	var res SmsTemplate
+	err := dao.db.WithContext(ctx).Where("name = ?", name).First(&res).Error
+	return res, err
+}
+
+func (dao *GORMSmsTemplateDAO) List(ctx context.Context, offset, limit int) ([]SmsTemplate, error) {
+	var res []SmsTemplate
+	err := dao.db.WithContext(ctx).Order("id").
+		Offset(offset).Limit(limit).Find(&res).Error
+	return res, err
+}
+
+func (dao *GORMSmsTemplateDAO) UpsertProvider(ctx context.Context, p SmsProviderTemplate) error {
+	now := time.Now().UnixMilli()
+	p.Ctime = now
+	p.Utime = now
+	same := "tpl_id = ? AND sign_name = ?"
+	return dao.db.WithContext(ctx).Clauses(clause.OnConflict{
+		//MySQL 按照顺序赋值，要先判断模板 id 和签名有没有变，再更新它们。变了就要重新审核
+		DoUpdates: clause.Set{
+			{Column: clause.Column{Name: "status"},
+				Value: gorm.Expr("CASE WHEN "+same+" THEN status ELSE ? END", p.TplId, p.SignName, p.Status)},
+			{Column: clause.Column{Name: "reason"},
+				Value: gorm.Expr("CASE WHEN "+same+" THEN reason ELSE '' END", p.TplId, p.SignName)},
+			{Column: clause.Column{Name: "tpl_id"}, Value: p.TplId},
+			{Column: clause.Column{Name: "sign_name"}, Value: p.SignName},
+			{Column: clause.Column{Name: "utime"}, Value: now},
+		},
+	}).Create(&p).Error
+}
+
+func (dao *GORMSmsTemplateDAO) DeleteProvider(ctx context.Context, tid int64, provider string) error {
+	return dao.db.WithContext(ctx).Where("tid = ? AND provider = ?", tid, provider).
+		Delete(&SmsProviderTemplate{}).Error
+}
+
+func (dao *GORMSmsTemplateDAO) UpdateProviderStatus(ctx context.Context, tid int64, provider string,
+	status uint8, reason string) error {
+	res := dao.db.WithContext(ctx).Model(&SmsProviderTemplate{}).
+		Where("tid = ? AND provider = ?", tid, provider).
+		Updates(map[string]any{
+			"status": status,
+			"reason": reason,
+			"utime":  time.Now().UnixMilli(),
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+func (dao *GORMSmsTemplateDAO) FindProviders(ctx context.Context, tids []int64) ([]SmsProviderTemplate, error) {
+	var res []SmsProviderTemplate
+	err := dao.db.WithContext(ctx).Where("tid IN ?", tids).
+		Order("id").Find(&res).Error
+	return res, err
+}
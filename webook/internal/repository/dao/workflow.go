@@ -0,0 +1,201 @@
+package dao
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"time"
+)
+
+type WorkflowDAO interface {
+	// CreateRun 同时创建这次执行的所有步骤
+	CreateRun(ctx context.Context, run WorkflowRun, steps []WorkflowStep) (int64, error)
+	// StartStep 等待中的步骤开始执行，重试的时候步骤已经在执行中了，只更新时间
+	StartStep(ctx context.Context, runId int64, jid int64) error
+	FinishStep(ctx context.Context, runId int64, jid int64, status uint8, errMsg string) error
+	// FailRun 结束这次执行，还没开始的步骤都跳过
+	FailRun(ctx context.Context, runId int64) error
+	// TimeoutRun 和 FailRun 一样，另外执行中的步骤也算失败
+	TimeoutRun(ctx context.Context, runId int64, errMsg string) error
+	// FindStaleRuns stime 在 before 之前还在执行中的
+	FindStaleRuns(ctx context.Context, before int64, limit int) ([]WorkflowRun, error)
+	// SucceedRun 只有执行中的才会更新
+	SucceedRun(ctx context.Context, runId int64) error
+	FindRun(ctx context.Context, id int64) (WorkflowRun, error)
+	FindSteps(ctx context.Context, runId int64) ([]WorkflowStep, error)
+	// ListRuns 最近开始的在前面
+	ListRuns(ctx context.Context, rootJid int64, offset, limit int) ([]WorkflowRun, error)
+}
+
+// WorkflowRun 工作流的一次执行
+type WorkflowRun struct {
+	Id      int64  `gorm:"primaryKey,autoIncrement"`
+	RootJid int64  `gorm:"index:root_stime"`
+	Name    string `gorm:"type:varchar(128)"`
+	Status  uint8  `gorm:"index:status_stime"`
+	Stime   int64  `gorm:"index:root_stime;index:status_stime"`
+	Etime   int64
+	Ctime   int64
+	Utime   int64
+}
+
+// WorkflowStep 工作流一次执行里面的一个任务
+type WorkflowStep struct {
+	Id     int64  `gorm:"primaryKey,autoIncrement"`
+	RunId  int64  `gorm:"uniqueIndex:run_jid"`
+	Jid    int64  `gorm:"uniqueIndex:run_jid"`
+	Name   string `gorm:"type:varchar(128)"`
+	Status uint8
+	Err    string `gorm:"type:varchar(1024)"`
+	Stime  int64
+	Etime  int64
+	Ctime  int64
+	Utime  int64
+}
+
+type GORMWorkflowDAO struct {
+	db *gorm.DB
+}
+
+func NewGORMWorkflowDAO(db *gorm.DB) WorkflowDAO {
+	return &GORMWorkflowDAO{db: db}
+}
+
+func (dao *GORMWorkflowDAO) CreateRun(ctx context.Context, run WorkflowRun, steps []WorkflowStep) (int64, error) {
+	now := time.Now().UnixMilli()
+	run.Ctime = now
+	run.Utime = now
+	err := dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Create(&run).Error
+		if err != nil {
+			return err
+		}
+		for i := range steps {
+			steps[i].RunId = run.Id
+			steps[i].Ctime = now
+			steps[i].Utime = now
+		}
+		return tx.Create(&steps).Error
+	})
+	return run.Id, err
+}
+
+func (dao *GORMWorkflowDAO) StartStep(ctx context.Context, runId int64, jid int64) error {
+	now := time.Now().UnixMilli()
+	return dao.db.WithContext(ctx).Model(&WorkflowStep{}).
+		Where("run_id = ? AND jid = ? AND status IN ?", runId, jid,
+			[]uint8{workflowStepStatusPending, workflowStepStatusRunning}).
+		Updates(map[string]any{
+			"status": workflowStepStatusRunning,
+			//重试的时候保留第一次开始的时间
+			"stime": gorm.Expr("CASE WHEN stime = 0 THEN ? ELSE stime END", now),
+			"utime": now,
+		}).Error
+}
+
+func (dao *GORMWorkflowDAO) FinishStep(ctx context.Context, runId int64, jid int64, status uint8, errMsg string) error {
+	now := time.Now().UnixMilli()
+	return dao.db.WithContext(ctx).Model(&WorkflowStep{}).
+		Where("run_id = ? AND jid = ?", runId, jid).
+		Updates(map[string]any{
+			"status": status,
+			"err":    errMsg,
+			"etime":  now,
+			"utime":  now,
+		}).Error
+}
+
+func (dao *GORMWorkflowDAO) FailRun(ctx context.Context, runId int64) error {
+	return dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return dao.failRun(tx, runId)
+	})
+}
+
+func (dao *GORMWorkflowDAO) TimeoutRun(ctx context.Context, runId int64, errMsg string) error {
+	now := time.Now().UnixMilli()
+	return dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&WorkflowStep{}).
+			Where("run_id = ? AND status = ?", runId, workflowStepStatusRunning).
+			Updates(map[string]any{
+				"status": workflowStepStatusFailed,
+				"err":    errMsg,
+				"etime":  now,
+				"utime":  now,
+			}).Error
+		if err != nil {
+			return err
+		}
+		return dao.failRun(tx, runId)
+	})
+}
+
+func (dao *GORMWorkflowDAO) failRun(tx *gorm.DB, runId int64) error {
+	now := time.Now().UnixMilli()
+	err := tx.Model(&WorkflowStep{}).
+		Where("run_id = ? AND status = ?", runId, workflowStepStatusPending).
+		Updates(map[string]any{
+			"status": workflowStepStatusSkipped,
+			"utime":  now,
+		}).Error
+	if err != nil {
+		return err
+	}
+	return tx.Model(&WorkflowRun{}).
+		Where("id = ? AND status = ?", runId, workflowRunStatusRunning).
+		Updates(map[string]any{
+			"status": workflowRunStatusFailed,
+			"etime":  now,
+			"utime":  now,
+		}).Error
+}
+
+func (dao *GORMWorkflowDAO) FindStaleRuns(ctx context.Context, before int64, limit int) ([]WorkflowRun, error) {
+	var res []WorkflowRun
+	err := dao.db.WithContext(ctx).Where("status = ? AND stime < ?", workflowRunStatusRunning, before).
+		Order("id").Limit(limit).Find(&res).Error
+	return res, err
+}
+
+func (dao *GORMWorkflowDAO) SucceedRun(ctx context.Context, runId int64) error {
+	now := time.Now().UnixMilli()
+	return dao.db.WithContext(ctx).Model(&WorkflowRun{}).
+		Where("id = ? AND status = ?", runId, workflowRunStatusRunning).
+		Updates(map[string]any{
+			"status": workflowRunStatusSuccess,
+			"etime":  now,
+			"utime":  now,
+		}).Error
+}
+
+func (dao *GORMWorkflowDAO) FindRun(ctx context.Context, id int64) (WorkflowRun, error) {
+	var res WorkflowRun
+	err := dao.db.WithContext(ctx).Where("id = ?", id).First(&res).Error
+	return res, err
+}
+
+func (dao *GORMWorkflowDAO) FindSteps(ctx context.Context, runId int64) ([]WorkflowStep, error) {
+	var res []WorkflowStep
+	err := dao.db.WithContext(ctx).Where("run_id = ?", runId).
+		Order("id").Find(&res).Error
+	return res, err
+}
+
+func (dao *GORMWorkflowDAO) ListRuns(ctx context.Context, rootJid int64, offset, limit int) ([]WorkflowRun, error) {
+	var res []WorkflowRun
+	err := dao.db.WithContext(ctx).Where("root_jid = ?", rootJid).
+		Order("stime DESC").Offset(offset).Limit(limit).Find(&res).Error
+	return res, err
+}
+
+const (
+	workflowRunStatusRunning uint8 = iota + 1
+	workflowRunStatusSuccess
+	workflowRunStatusFailed
+)
+
+const (
+	workflowStepStatusPending uint8 = iota
+	workflowStepStatusRunning
+	workflowStepStatusSuccess
+	workflowStepStatusFailed
+	workflowStepStatusSkipped
+)
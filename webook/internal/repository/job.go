@@ -2,43 +2,195 @@ package repository
 
 import (
 	"context"
+	"github.com/ecodeclub/ekit/slice"
 	"time"
 	"xiaoweishu/webook/internal/domain"
 	"xiaoweishu/webook/internal/repository/dao"
 )
 
+var (
+	ErrJobNotFound       = dao.ErrRecordNotFound
+	ErrJobDuplicateName  = dao.ErrJobDuplicateName
+	ErrJobStatusConflict = dao.ErrJobStatusConflict
+	ErrJobPreempted      = dao.ErrJobPreempted
+)
+
+//go:generate mockgen -source=./job.go -package=repomocks -destination=mocks/job.mock.go CronJobRepository
 type CronJobRepository interface {
-	Preempt(ctx context.Context) (domain.Job, error)
-	Release(ctx context.Context, jid int64) error
-	UpdateUtime(ctx context.Context, id int64) error
-	UpdateNextTime(ctx context.Context, id int64, time time.Time) error
+	// Preempt 执行中但是 utime 早于 staleBefore 的任务也会被抢占
+	Preempt(ctx context.Context, owner string, staleBefore time.Time) (domain.Job, error)
+	Release(ctx context.Context, jid int64, version int) error
+	UpdateUtime(ctx context.Context, id int64, version int) error
+	// UpdateUtime UpdateNextTime 和 UpdateRetry 在任务已经被别的实例重新抢占的时候返回 ErrJobPreempted
+	UpdateNextTime(ctx context.Context, id int64, version int, time time.Time) error
+	UpdateRetry(ctx context.Context, id int64, version int, time time.Time, retries int) error
+	Trigger(ctx context.Context, jid int64, runId int64) (bool, error)
+	UpdateRunId(ctx context.Context, jid int64, version int, runId int64) error
+	// FindUpstreams key 是任务 id，value 是它的上游
+	FindUpstreams(ctx context.Context, jids []int64) (map[int64][]int64, error)
+	FindDownstreams(ctx context.Context, jid int64) ([]int64, error)
+
+	Create(ctx context.Context, j domain.Job) (int64, error)
+	Update(ctx context.Context, j domain.Job) error
+	Delete(ctx context.Context, id int64) error
+	FindById(ctx context.Context, id int64) (domain.Job, error)
+	List(ctx context.Context, offset, limit int) ([]domain.Job, error)
+	Pause(ctx context.Context, id int64) error
+	Resume(ctx context.Context, id int64, next time.Time) error
+	RunNow(ctx context.Context, id int64) error
 }
 
 type PreemptJobRepository struct {
 	dao dao.JobDAO
 }
 
-func (p *PreemptJobRepository) Preempt(ctx context.Context) (domain.Job, error) {
-	j, err := p.dao.Preempt(ctx)
-	return domain.Job{
-		Id:         j.Id,
-		Expression: j.Expression,
-		Executor:   j.Executor,
-		Name:       j.Name,
-	}, err
+func (p *PreemptJobRepository) Preempt(ctx context.Context, owner string, staleBefore time.Time) (domain.Job, error) {
+	j, err := p.dao.Preempt(ctx, owner, staleBefore)
+	if err != nil {
+		return domain.Job{}, err
+	}
+	//dao 返回的是抢占之前的数据
+	res := p.toDomain(j)
+	res.Status = domain.JobStatusRunning
+	res.Owner = owner
+	res.Version = j.Version + 1
+	return res, nil
 }
 
-func (p *PreemptJobRepository) Release(ctx context.Context, jid int64) error {
-	return p.dao.Release(ctx, jid)
+func (p *PreemptJobRepository) Release(ctx context.Context, jid int64, version int) error {
+	return p.dao.Release(ctx, jid, version)
 }
 
 // UpdateUtime 续约，保持活跃
-func (p *PreemptJobRepository) UpdateUtime(ctx context.Context, id int64) error {
-	return p.dao.UpdateUtime(ctx, id)
+func (p *PreemptJobRepository) UpdateUtime(ctx context.Context, id int64, version int) error {
+	return p.dao.UpdateUtime(ctx, id, version)
+}
+
+func (p *PreemptJobRepository) UpdateNextTime(ctx context.Context, id int64, version int, time time.Time) error {
+	return p.dao.UpdateNextTime(ctx, id, version, time)
+}
+
+func (p *PreemptJobRepository) UpdateRetry(ctx context.Context, id int64, version int, time time.Time, retries int) error {
+	return p.dao.UpdateRetry(ctx, id, version, time, retries)
+}
+
+func (p *PreemptJobRepository) Trigger(ctx context.Context, jid int64, runId int64) (bool, error) {
+	return p.dao.Trigger(ctx, jid, runId)
+}
+
+func (p *PreemptJobRepository) UpdateRunId(ctx context.Context, jid int64, version int, runId int64) error {
+	return p.dao.UpdateRunId(ctx, jid, version, runId)
+}
+
+func (p *PreemptJobRepository) FindUpstreams(ctx context.Context, jids []int64) (map[int64][]int64, error) {
+	deps, err := p.dao.FindUpstreams(ctx, jids)
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[int64][]int64, len(jids))
+	for _, dep := range deps {
+		res[dep.Jid] = append(res[dep.Jid], dep.Upstream)
+	}
+	return res, nil
+}
+
+func (p *PreemptJobRepository) FindDownstreams(ctx context.Context, jid int64) ([]int64, error) {
+	return p.dao.FindDownstreams(ctx, jid)
+}
+
+func (p *PreemptJobRepository) Create(ctx context.Context, j domain.Job) (int64, error) {
+	return p.dao.Insert(ctx, p.toEntity(j), j.Upstreams)
+}
+
+func (p *PreemptJobRepository) Update(ctx context.Context, j domain.Job) error {
+	return p.dao.Update(ctx, p.toEntity(j), j.Upstreams)
+}
+
+func (p *PreemptJobRepository) Delete(ctx context.Context, id int64) error {
+	return p.dao.Delete(ctx, id)
+}
+
+func (p *PreemptJobRepository) FindById(ctx context.Context, id int64) (domain.Job, error) {
+	j, err := p.dao.FindById(ctx, id)
+	if err != nil {
+		return domain.Job{}, err
+	}
+	ups, err := p.FindUpstreams(ctx, []int64{id})
+	if err != nil {
+		return domain.Job{}, err
+	}
+	res := p.toDomain(j)
+	res.Upstreams = ups[id]
+	return res, nil
+}
+
+func (p *PreemptJobRepository) List(ctx context.Context, offset, limit int) ([]domain.Job, error) {
+	jobs, err := p.dao.List(ctx, offset, limit)
+	if err != nil || len(jobs) == 0 {
+		return nil, err
+	}
+	ups, err := p.FindUpstreams(ctx, slice.Map(jobs, func(idx int, src dao.Job) int64 {
+		return src.Id
+	}))
+	if err != nil {
+		return nil, err
+	}
+	return slice.Map(jobs, func(idx int, src dao.Job) domain.Job {
+		res := p.toDomain(src)
+		res.Upstreams = ups[src.Id]
+		return res
+	}), nil
 }
 
-func (p *PreemptJobRepository) UpdateNextTime(ctx context.Context, id int64, time time.Time) error {
-	return p.dao.UpdateNextTime(ctx, id, time)
+func (p *PreemptJobRepository) Pause(ctx context.Context, id int64) error {
+	return p.dao.Pause(ctx, id)
+}
+
+func (p *PreemptJobRepository) Resume(ctx context.Context, id int64, next time.Time) error {
+	return p.dao.Resume(ctx, id, next)
+}
+
+func (p *PreemptJobRepository) RunNow(ctx context.Context, id int64) error {
+	return p.dao.RunNow(ctx, id)
+}
+
+func (p *PreemptJobRepository) toEntity(j domain.Job) dao.Job {
+	return dao.Job{
+		Id:            j.Id,
+		Name:          j.Name,
+		Executor:      j.Executor,
+		Expression:    j.Expression,
+		Cfg:           j.Cfg,
+		Status:        int(j.Status),
+		NextTime:      j.NextExecTime.UnixMilli(),
+		MaxRetries:    j.MaxRetries,
+		Retries:       j.Retries,
+		Timeout:       j.Timeout.Milliseconds(),
+		MisfirePolicy: uint8(j.MisfirePolicy),
+		Shards:        j.Shards,
+	}
+}
+
+func (p *PreemptJobRepository) toDomain(j dao.Job) domain.Job {
+	return domain.Job{
+		Id:            j.Id,
+		Name:          j.Name,
+		Expression:    j.Expression,
+		Executor:      j.Executor,
+		Cfg:           j.Cfg,
+		Status:        domain.JobStatus(j.Status),
+		NextExecTime:  time.UnixMilli(j.NextTime),
+		Owner:         j.Owner,
+		Version:       j.Version,
+		MaxRetries:    j.MaxRetries,
+		Retries:       j.Retries,
+		Timeout:       time.Duration(j.Timeout) * time.Millisecond,
+		MisfirePolicy: domain.JobMisfirePolicy(j.MisfirePolicy),
+		Shards:        j.Shards,
+		RunId:         j.RunId,
+		Ctime:         time.UnixMilli(j.Ctime),
+		Utime:         time.UnixMilli(j.Utime),
+	}
 }
 
 func NewPreemptJobRepository(dao dao.JobDAO) CronJobRepository {
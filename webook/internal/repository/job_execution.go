@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"github.com/ecodeclub/ekit/slice"
+	"time"
+	"xiaoweishu/webook/internal/domain"
+	"xiaoweishu/webook/internal/repository/dao"
+)
+
+//go:generate mockgen -source=./job_execution.go -package=repomocks -destination=mocks/job_execution.mock.go JobExecutionRepository
+type JobExecutionRepository interface {
+	Create(ctx context.Context, e domain.JobExecution) (int64, error)
+	Finish(ctx context.Context, e domain.JobExecution) error
+	ListByJob(ctx context.Context, jid int64, offset, limit int) ([]domain.JobExecution, error)
+	DeleteBefore(ctx context.Context, t time.Time) (int64, error)
+}
+
+type jobExecutionRepository struct {
+	dao dao.JobExecutionDAO
+}
+
+func NewJobExecutionRepository(dao dao.JobExecutionDAO) JobExecutionRepository {
+	return &jobExecutionRepository{dao: dao}
+}
+
+func (r *jobExecutionRepository) Create(ctx context.Context, e domain.JobExecution) (int64, error) {
+	return r.dao.Insert(ctx, r.toEntity(e))
+}
+
+func (r *jobExecutionRepository) Finish(ctx context.Context, e domain.JobExecution) error {
+	return r.dao.Finish(ctx, r.toEntity(e))
+}
+
+func (r *jobExecutionRepository) ListByJob(ctx context.Context, jid int64, offset, limit int) ([]domain.JobExecution, error) {
+	res, err := r.dao.ListByJob(ctx, jid, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	return slice.Map(res, func(idx int, src dao.JobExecution) domain.JobExecution {
+		return r.toDomain(src)
+	}), nil
+}
+
+func (r *jobExecutionRepository) DeleteBefore(ctx context.Context, t time.Time) (int64, error) {
+	return r.dao.DeleteBefore(ctx, t)
+}
+
+func (r *jobExecutionRepository) toEntity(e domain.JobExecution) dao.JobExecution {
+	res := dao.JobExecution{
+		Id:       e.Id,
+		Jid:      e.Jid,
+		Name:     e.Name,
+		Instance: e.Instance,
+		Attempt:  e.Attempt,
+		Status:   uint8(e.Status),
+		Err:      e.Err,
+		Output:   e.Output,
+		Stime:    e.Stime.UnixMilli(),
+	}
+	if !e.Etime.IsZero() {
+		res.Etime = e.Etime.UnixMilli()
+	}
+	return res
+}
+
+func (r *jobExecutionRepository) toDomain(e dao.JobExecution) domain.JobExecution {
+	res := domain.JobExecution{
+		Id:       e.Id,
+		Jid:      e.Jid,
+		Name:     e.Name,
+		Instance: e.Instance,
+		Attempt:  e.Attempt,
+		Status:   domain.JobExecutionStatus(e.Status),
+		Err:      e.Err,
+		Output:   e.Output,
+		Stime:    time.UnixMilli(e.Stime),
+	}
+	if e.Etime > 0 {
+		res.Etime = time.UnixMilli(e.Etime)
+	}
+	return res
+}
@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"context"
+	"time"
+	"xiaoweishu/webook/internal/repository/cache"
+)
+
+// JobLoadRepository 实例的负载只放在 redis 里面，实例一直在上报，丢了也很快就会补上
+type JobLoadRepository interface {
+	Report(ctx context.Context, instance string, load int, expiration time.Duration) error
+	Rank(ctx context.Context, instance string) (int, error)
+}
+
+type CachedJobLoadRepository struct {
+	cache cache.JobLoadCache
+}
+
+func NewCachedJobLoadRepository(c cache.JobLoadCache) JobLoadRepository {
+	return &CachedJobLoadRepository{cache: c}
+}
+
+func (repo *CachedJobLoadRepository) Report(ctx context.Context, instance string, load int, expiration time.Duration) error {
+	return repo.cache.Report(ctx, instance, load, expiration)
+}
+
+func (repo *CachedJobLoadRepository) Rank(ctx context.Context, instance string) (int, error) {
+	return repo.cache.Rank(ctx, instance)
+}
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"github.com/ecodeclub/ekit/slice"
+	"time"
+	"xiaoweishu/webook/internal/domain"
+	"xiaoweishu/webook/internal/repository/dao"
+)
+
+type JobShardRepository interface {
+	Split(ctx context.Context, jid int64, round time.Time, total int) error
+	Preempt(ctx context.Context, owner string, staleBefore time.Time) (domain.JobShard, error)
+	UpdateUtime(ctx context.Context, id int64, version int) error
+	// Finish 用 s 的状态、错误、重试次数和下一次执行时间更新分片
+	Finish(ctx context.Context, s domain.JobShard) error
+	ListByRound(ctx context.Context, jid int64, round time.Time) ([]domain.JobShard, error)
+	ListLatest(ctx context.Context, jid int64) ([]domain.JobShard, error)
+}
+
+type jobShardRepository struct {
+	dao dao.JobShardDAO
+}
+
+func NewJobShardRepository(dao dao.JobShardDAO) JobShardRepository {
+	return &jobShardRepository{dao: dao}
+}
+
+func (r *jobShardRepository) Split(ctx context.Context, jid int64, round time.Time, total int) error {
+	return r.dao.Split(ctx, jid, round.UnixMilli(), total)
+}
+
+func (r *jobShardRepository) Preempt(ctx context.Context, owner string, staleBefore time.Time) (domain.JobShard, error) {
+	s, err := r.dao.Preempt(ctx, owner, staleBefore)
+	if err != nil {
+		return domain.JobShard{}, err
+	}
+	//dao 返回的是抢占之前的数据
+	res := r.toDomain(s)
+	res.Status = domain.JobShardStatusRunning
+	res.Owner = owner
+	res.Version = s.Version + 1
+	return res, nil
+}
+
+func (r *jobShardRepository) UpdateUtime(ctx context.Context, id int64, version int) error {
+	return r.dao.UpdateUtime(ctx, id, version)
+}
+
+func (r *jobShardRepository) Finish(ctx context.Context, s domain.JobShard) error {
+	var next int64
+	if !s.NextExecTime.IsZero() {
+		next = s.NextExecTime.UnixMilli()
+	}
+	return r.dao.Finish(ctx, s.Id, s.Version, uint8(s.Status), s.Err, s.Retries, next)
+}
+
+func (r *jobShardRepository) ListByRound(ctx context.Context, jid int64, round time.Time) ([]domain.JobShard, error) {
+	res, err := r.dao.ListByRound(ctx, jid, round.UnixMilli())
+	if err != nil {
+		return nil, err
+	}
+	return r.toDomains(res), nil
+}
+
+func (r *jobShardRepository) ListLatest(ctx context.Context, jid int64) ([]domain.JobShard, error) {
+	res, err := r.dao.ListLatest(ctx, jid)
+	if err != nil {
+		return nil, err
+	}
+	return r.toDomains(res), nil
+}
+
+func (r *jobShardRepository) toDomains(shards []dao.JobShard) []domain.JobShard {
+	return slice.Map(shards, func(idx int, src dao.JobShard) domain.JobShard {
+		return r.toDomain(src)
+	})
+}
+
+func (r *jobShardRepository) toDomain(s dao.JobShard) domain.JobShard {
+	return domain.JobShard{
+		Id:           s.Id,
+		Jid:          s.Jid,
+		Round:        time.UnixMilli(s.Round),
+		Idx:          s.Idx,
+		Total:        s.Total,
+		Status:       domain.JobShardStatus(s.Status),
+		Owner:        s.Owner,
+		Version:      s.Version,
+		Retries:      s.Retries,
+		Err:          s.Err,
+		NextExecTime: time.UnixMilli(s.NextTime),
+		Ctime:        time.UnixMilli(s.Ctime),
+		Utime:        time.UnixMilli(s.Utime),
+	}
+}
@@ -0,0 +1,287 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./job.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./job.go -package=repomocks -destination=mocks/job.mock.go CronJobRepository
+//
+
+// Package repomocks is a generated GoMock package.
+package repomocks
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+	domain "xiaoweishu/webook/internal/domain"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockCronJobRepository is a mock of CronJobRepository interface.
+type MockCronJobRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockCronJobRepositoryMockRecorder
+}
+
+// MockCronJobRepositoryMockRecorder is the mock recorder for MockCronJobRepository.
+type MockCronJobRepositoryMockRecorder struct {
+	mock *MockCronJobRepository
+}
+
+// NewMockCronJobRepository creates a new mock instance.
+func NewMockCronJobRepository(ctrl *gomock.Controller) *MockCronJobRepository {
+	mock := &MockCronJobRepository{ctrl: ctrl}
+	mock.recorder = &MockCronJobRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockCronJobRepository) EXPECT() *MockCronJobRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockCronJobRepository) Create(ctx context.Context, j domain.Job) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", ctx, j)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockCronJobRepositoryMockRecorder) Create(ctx, j any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockCronJobRepository)(nil).Create), ctx, j)
+}
+
+// Delete mocks base method.
+func (m *MockCronJobRepository) Delete(ctx context.Context, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockCronJobRepositoryMockRecorder) Delete(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockCronJobRepository)(nil).Delete), ctx, id)
+}
+
+// FindById mocks base method.
+func (m *MockCronJobRepository) FindById(ctx context.Context, id int64) (domain.Job, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindById", ctx, id)
+	ret0, _ := ret[0].(domain.Job)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindById indicates an expected call of FindById.
+func (mr *MockCronJobRepositoryMockRecorder) FindById(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindById", reflect.TypeOf((*MockCronJobRepository)(nil).FindById), ctx, id)
+}
+
+// FindDownstreams mocks base method.
+func (m *MockCronJobRepository) FindDownstreams(ctx context.Context, jid int64) ([]int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindDownstreams", ctx, jid)
+	ret0, _ := ret[0].([]int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindDownstreams indicates an expected call of FindDownstreams.
+func (mr *MockCronJobRepositoryMockRecorder) FindDownstreams(ctx, jid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindDownstreams", reflect.TypeOf((*MockCronJobRepository)(nil).FindDownstreams), ctx, jid)
+}
+
+// FindUpstreams mocks base method.
+func (m *MockCronJobRepository) FindUpstreams(ctx context.Context, jids []int64) (map[int64][]int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindUpstreams", ctx, jids)
+	ret0, _ := ret[0].(map[int64][]int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindUpstreams indicates an expected call of FindUpstreams.
+func (mr *MockCronJobRepositoryMockRecorder) FindUpstreams(ctx, jids any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindUpstreams", reflect.TypeOf((*MockCronJobRepository)(nil).FindUpstreams), ctx, jids)
+}
+
+// List mocks base method.
+func (m *MockCronJobRepository) List(ctx context.Context, offset, limit int) ([]domain.Job, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", ctx, offset, limit)
+	ret0, _ := ret[0].([]domain.Job)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockCronJobRepositoryMockRecorder) List(ctx, offset, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockCronJobRepository)(nil).List), ctx, offset, limit)
+}
+
+// Pause mocks base method.
+func (m *MockCronJobRepository) Pause(ctx context.Context, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Pause", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Pause indicates an expected call of Pause.
+func (mr *MockCronJobRepositoryMockRecorder) Pause(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Pause", reflect.TypeOf((*MockCronJobRepository)(nil).Pause), ctx, id)
+}
+
+// Preempt mocks base method.
+func (m *MockCronJobRepository) Preempt(ctx context.Context, owner string, staleBefore time.Time) (domain.Job, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Preempt", ctx, owner, staleBefore)
+	ret0, _ := ret[0].(domain.Job)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Preempt indicates an expected call of Preempt.
+func (mr *MockCronJobRepositoryMockRecorder) Preempt(ctx, owner, staleBefore any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Preempt", reflect.TypeOf((*MockCronJobRepository)(nil).Preempt), ctx, owner, staleBefore)
+}
+
+// Release mocks base method.
+func (m *MockCronJobRepository) Release(ctx context.Context, jid int64, version int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Release", ctx, jid, version)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Release indicates an expected call of Release.
+func (mr *MockCronJobRepositoryMockRecorder) Release(ctx, jid, version any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Release", reflect.TypeOf((*MockCronJobRepository)(nil).Release), ctx, jid, version)
+}
+
+// Resume mocks base method.
+func (m *MockCronJobRepository) Resume(ctx context.Context, id int64, next time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Resume", ctx, id, next)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Resume indicates an expected call of Resume.
+func (mr *MockCronJobRepositoryMockRecorder) Resume(ctx, id, next any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Resume", reflect.TypeOf((*MockCronJobRepository)(nil).Resume), ctx, id, next)
+}
+
+// RunNow mocks base method.
+func (m *MockCronJobRepository) RunNow(ctx context.Context, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RunNow", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RunNow indicates an expected call of RunNow.
+func (mr *MockCronJobRepositoryMockRecorder) RunNow(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RunNow", reflect.TypeOf((*MockCronJobRepository)(nil).RunNow), ctx, id)
+}
+
+// Trigger mocks base method.
+func (m *MockCronJobRepository) Trigger(ctx context.Context, jid, runId int64) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Trigger", ctx, jid, runId)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Trigger indicates an expected call of Trigger.
+func (mr *MockCronJobRepositoryMockRecorder) Trigger(ctx, jid, runId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Trigger", reflect.TypeOf((*MockCronJobRepository)(nil).Trigger), ctx, jid, runId)
+}
+
+// Update mocks base method.
+func (m *MockCronJobRepository) Update(ctx context.Context, j domain.Job) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", ctx, j)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockCronJobRepositoryMockRecorder) Update(ctx, j any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockCronJobRepository)(nil).Update), ctx, j)
+}
+
+// UpdateNextTime mocks base method.
+func (m *MockCronJobRepository) UpdateNextTime(ctx context.Context, id int64, version int, time time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateNextTime", ctx, id, version, time)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateNextTime indicates an expected call of UpdateNextTime.
+func (mr *MockCronJobRepositoryMockRecorder) UpdateNextTime(ctx, id, version, time any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateNextTime", reflect.TypeOf((*MockCronJobRepository)(nil).UpdateNextTime), ctx, id, version, time)
+}
+
+// UpdateRetry mocks base method.
+func (m *MockCronJobRepository) UpdateRetry(ctx context.Context, id int64, version int, time time.Time, retries int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateRetry", ctx, id, version, time, retries)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateRetry indicates an expected call of UpdateRetry.
+func (mr *MockCronJobRepositoryMockRecorder) UpdateRetry(ctx, id, version, time, retries any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateRetry", reflect.TypeOf((*MockCronJobRepository)(nil).UpdateRetry), ctx, id, version, time, retries)
+}
+
+// UpdateRunId mocks base method.
+func (m *MockCronJobRepository) UpdateRunId(ctx context.Context, jid int64, version int, runId int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateRunId", ctx, jid, version, runId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateRunId indicates an expected call of UpdateRunId.
+func (mr *MockCronJobRepositoryMockRecorder) UpdateRunId(ctx, jid, version, runId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateRunId", reflect.TypeOf((*MockCronJobRepository)(nil).UpdateRunId), ctx, jid, version, runId)
+}
+
+// UpdateUtime mocks base method.
+func (m *MockCronJobRepository) UpdateUtime(ctx context.Context, id int64, version int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateUtime", ctx, id, version)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateUtime indicates an expected call of UpdateUtime.
+func (mr *MockCronJobRepositoryMockRecorder) UpdateUtime(ctx, id, version any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUtime", reflect.TypeOf((*MockCronJobRepository)(nil).UpdateUtime), ctx, id, version)
+}
@@ -0,0 +1,101 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./job_execution.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./job_execution.go -package=repomocks -destination=mocks/job_execution.mock.go JobExecutionRepository
+//
+
+// Package repomocks is a generated GoMock package.
+package repomocks
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+	domain "xiaoweishu/webook/internal/domain"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockJobExecutionRepository is a mock of JobExecutionRepository interface.
+type MockJobExecutionRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockJobExecutionRepositoryMockRecorder
+}
+
+// MockJobExecutionRepositoryMockRecorder is the mock recorder for MockJobExecutionRepository.
+type MockJobExecutionRepositoryMockRecorder struct {
+	mock *MockJobExecutionRepository
+}
+
+// NewMockJobExecutionRepository creates a new mock instance.
+func NewMockJobExecutionRepository(ctrl *gomock.Controller) *MockJobExecutionRepository {
+	mock := &MockJobExecutionRepository{ctrl: ctrl}
+	mock.recorder = &MockJobExecutionRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockJobExecutionRepository) EXPECT() *MockJobExecutionRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockJobExecutionRepository) Create(ctx context.Context, e domain.JobExecution) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", ctx, e)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockJobExecutionRepositoryMockRecorder) Create(ctx, e any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockJobExecutionRepository)(nil).Create), ctx, e)
+}
+
+// DeleteBefore mocks base method.
+func (m *MockJobExecutionRepository) DeleteBefore(ctx context.Context, t time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteBefore", ctx, t)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteBefore indicates an expected call of DeleteBefore.
+func (mr *MockJobExecutionRepositoryMockRecorder) DeleteBefore(ctx, t any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteBefore", reflect.TypeOf((*MockJobExecutionRepository)(nil).DeleteBefore), ctx, t)
+}
+
+// Finish mocks base method.
+func (m *MockJobExecutionRepository) Finish(ctx context.Context, e domain.JobExecution) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Finish", ctx, e)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Finish indicates an expected call of Finish.
+func (mr *MockJobExecutionRepositoryMockRecorder) Finish(ctx, e any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Finish", reflect.TypeOf((*MockJobExecutionRepository)(nil).Finish), ctx, e)
+}
+
+// ListByJob mocks base method.
+func (m *MockJobExecutionRepository) ListByJob(ctx context.Context, jid int64, offset, limit int) ([]domain.JobExecution, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListByJob", ctx, jid, offset, limit)
+	ret0, _ := ret[0].([]domain.JobExecution)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListByJob indicates an expected call of ListByJob.
+func (mr *MockJobExecutionRepositoryMockRecorder) ListByJob(ctx, jid, offset, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListByJob", reflect.TypeOf((*MockJobExecutionRepository)(nil).ListByJob), ctx, jid, offset, limit)
+}
@@ -0,0 +1,70 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./ranking_board.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./ranking_board.go -package=repomocks -destination=mocks/ranking_board.mock.go RankingBoardRepository
+//
+
+// Package repomocks is a generated GoMock package.
+package repomocks
+
+import (
+	context "context"
+	reflect "reflect"
+	domain "xiaoweishu/webook/internal/domain"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockRankingBoardRepository is a mock of RankingBoardRepository interface.
+type MockRankingBoardRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockRankingBoardRepositoryMockRecorder
+}
+
+// MockRankingBoardRepositoryMockRecorder is the mock recorder for MockRankingBoardRepository.
+type MockRankingBoardRepositoryMockRecorder struct {
+	mock *MockRankingBoardRepository
+}
+
+// NewMockRankingBoardRepository creates a new mock instance.
+func NewMockRankingBoardRepository(ctrl *gomock.Controller) *MockRankingBoardRepository {
+	mock := &MockRankingBoardRepository{ctrl: ctrl}
+	mock.recorder = &MockRankingBoardRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRankingBoardRepository) EXPECT() *MockRankingBoardRepositoryMockRecorder {
+	return m.recorder
+}
+
+// GetTopN mocks base method.
+func (m *MockRankingBoardRepository) GetTopN(ctx context.Context, board string) ([]domain.Article, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTopN", ctx, board)
+	ret0, _ := ret[0].([]domain.Article)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTopN indicates an expected call of GetTopN.
+func (mr *MockRankingBoardRepositoryMockRecorder) GetTopN(ctx, board any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTopN", reflect.TypeOf((*MockRankingBoardRepository)(nil).GetTopN), ctx, board)
+}
+
+// ReplaceTopN mocks base method.
+func (m *MockRankingBoardRepository) ReplaceTopN(ctx context.Context, board string, arts []domain.Article) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReplaceTopN", ctx, board, arts)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReplaceTopN indicates an expected call of ReplaceTopN.
+func (mr *MockRankingBoardRepositoryMockRecorder) ReplaceTopN(ctx, board, arts any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReplaceTopN", reflect.TypeOf((*MockRankingBoardRepository)(nil).ReplaceTopN), ctx, board, arts)
+}
@@ -0,0 +1,128 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./ranking_stream.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./ranking_stream.go -package=repomocks -destination=mocks/ranking_stream.mock.go RankingStreamRepository
+//
+
+// Package repomocks is a generated GoMock package.
+package repomocks
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+	domain "xiaoweishu/webook/internal/domain"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockRankingStreamRepository is a mock of RankingStreamRepository interface.
+type MockRankingStreamRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockRankingStreamRepositoryMockRecorder
+}
+
+// MockRankingStreamRepositoryMockRecorder is the mock recorder for MockRankingStreamRepository.
+type MockRankingStreamRepositoryMockRecorder struct {
+	mock *MockRankingStreamRepository
+}
+
+// NewMockRankingStreamRepository creates a new mock instance.
+func NewMockRankingStreamRepository(ctrl *gomock.Controller) *MockRankingStreamRepository {
+	mock := &MockRankingStreamRepository{ctrl: ctrl}
+	mock.recorder = &MockRankingStreamRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRankingStreamRepository) EXPECT() *MockRankingStreamRepositoryMockRecorder {
+	return m.recorder
+}
+
+// AddCandidate mocks base method.
+func (m *MockRankingStreamRepository) AddCandidate(ctx context.Context, aid int64, utime time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddCandidate", ctx, aid, utime)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddCandidate indicates an expected call of AddCandidate.
+func (mr *MockRankingStreamRepositoryMockRecorder) AddCandidate(ctx, aid, utime any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddCandidate", reflect.TypeOf((*MockRankingStreamRepository)(nil).AddCandidate), ctx, aid, utime)
+}
+
+// BatchIncr mocks base method.
+func (m *MockRankingStreamRepository) BatchIncr(ctx context.Context, deltas []domain.RankingDelta) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BatchIncr", ctx, deltas)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// BatchIncr indicates an expected call of BatchIncr.
+func (mr *MockRankingStreamRepositoryMockRecorder) BatchIncr(ctx, deltas any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BatchIncr", reflect.TypeOf((*MockRankingStreamRepository)(nil).BatchIncr), ctx, deltas)
+}
+
+// PruneBefore mocks base method.
+func (m *MockRankingStreamRepository) PruneBefore(ctx context.Context, before time.Time) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PruneBefore", ctx, before)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PruneBefore indicates an expected call of PruneBefore.
+func (mr *MockRankingStreamRepositoryMockRecorder) PruneBefore(ctx, before any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PruneBefore", reflect.TypeOf((*MockRankingStreamRepository)(nil).PruneBefore), ctx, before)
+}
+
+// RemoveCandidate mocks base method.
+func (m *MockRankingStreamRepository) RemoveCandidate(ctx context.Context, aid int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveCandidate", ctx, aid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemoveCandidate indicates an expected call of RemoveCandidate.
+func (mr *MockRankingStreamRepositoryMockRecorder) RemoveCandidate(ctx, aid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveCandidate", reflect.TypeOf((*MockRankingStreamRepository)(nil).RemoveCandidate), ctx, aid)
+}
+
+// ResetCandidates mocks base method.
+func (m *MockRankingStreamRepository) ResetCandidates(ctx context.Context, candidates []domain.RankingCandidate) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResetCandidates", ctx, candidates)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResetCandidates indicates an expected call of ResetCandidates.
+func (mr *MockRankingStreamRepositoryMockRecorder) ResetCandidates(ctx, candidates any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetCandidates", reflect.TypeOf((*MockRankingStreamRepository)(nil).ResetCandidates), ctx, candidates)
+}
+
+// Top mocks base method.
+func (m *MockRankingStreamRepository) Top(ctx context.Context, n int) ([]int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Top", ctx, n)
+	ret0, _ := ret[0].([]int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Top indicates an expected call of Top.
+func (mr *MockRankingStreamRepositoryMockRecorder) Top(ctx, n any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Top", reflect.TypeOf((*MockRankingStreamRepository)(nil).Top), ctx, n)
+}
@@ -0,0 +1,171 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./sms_template.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./sms_template.go -package=repomocks -destination=mocks/sms_template.mock.go SmsTemplateRepository
+//
+
+// Package repomocks is a generated GoMock package.
+package repomocks
+
+import (
+	context "context"
+	reflect "reflect"
+	domain "xiaoweishu/webook/internal/domain"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockSmsTemplateRepository is a mock of SmsTemplateRepository interface.
+type MockSmsTemplateRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockSmsTemplateRepositoryMockRecorder
+}
+
+// MockSmsTemplateRepositoryMockRecorder is the mock recorder for MockSmsTemplateRepository.
+type MockSmsTemplateRepositoryMockRecorder struct {
+	mock *MockSmsTemplateRepository
+}
+
+// NewMockSmsTemplateRepository creates a new mock instance.
+func NewMockSmsTemplateRepository(ctrl *gomock.Controller) *MockSmsTemplateRepository {
+	mock := &MockSmsTemplateRepository{ctrl: ctrl}
+	mock.recorder = &MockSmsTemplateRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSmsTemplateRepository) EXPECT() *MockSmsTemplateRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockSmsTemplateRepository) Create(ctx context.Context, t domain.SmsTemplate) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", ctx, t)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockSmsTemplateRepositoryMockRecorder) Create(ctx, t any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockSmsTemplateRepository)(nil).Create), ctx, t)
+}
+
+// Delete mocks base method.
+func (m *MockSmsTemplateRepository) Delete(ctx context.Context, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockSmsTemplateRepositoryMockRecorder) Delete(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockSmsTemplateRepository)(nil).Delete), ctx, id)
+}
+
+// DeleteProvider mocks base method.
+func (m *MockSmsTemplateRepository) DeleteProvider(ctx context.Context, tid int64, provider string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteProvider", ctx, tid, provider)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteProvider indicates an expected call of DeleteProvider.
+func (mr *MockSmsTemplateRepositoryMockRecorder) DeleteProvider(ctx, tid, provider any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteProvider", reflect.TypeOf((*MockSmsTemplateRepository)(nil).DeleteProvider), ctx, tid, provider)
+}
+
+// FindById mocks base method.
+func (m *MockSmsTemplateRepository) FindById(ctx context.Context, id int64) (domain.SmsTemplate, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindById", ctx, id)
+	ret0, _ := ret[0].(domain.SmsTemplate)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindById indicates an expected call of FindById.
+func (mr *MockSmsTemplateRepositoryMockRecorder) FindById(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindById", reflect.TypeOf((*MockSmsTemplateRepository)(nil).FindById), ctx, id)
+}
+
+// FindByName mocks base method.
+func (m *MockSmsTemplateRepository) FindByName(ctx context.Context, name string) (domain.SmsTemplate, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindByName", ctx, name)
+	ret0, _ := ret[0].(domain.SmsTemplate)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindByName indicates an expected call of FindByName.
+func (mr *MockSmsTemplateRepositoryMockRecorder) FindByName(ctx, name any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByName", reflect.TypeOf((*MockSmsTemplateRepository)(nil).FindByName), ctx, name)
+}
+
+// List mocks base method.
+func (m *MockSmsTemplateRepository) List(ctx context.Context, offset, limit int) ([]domain.SmsTemplate, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", ctx, offset, limit)
+	ret0, _ := ret[0].([]domain.SmsTemplate)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockSmsTemplateRepositoryMockRecorder) List(ctx, offset, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockSmsTemplateRepository)(nil).List), ctx, offset, limit)
+}
+
+// SaveProvider mocks base method.
+func (m *MockSmsTemplateRepository) SaveProvider(ctx context.Context, tid int64, p domain.SmsProviderTemplate) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveProvider", ctx, tid, p)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveProvider indicates an expected call of SaveProvider.
+func (mr *MockSmsTemplateRepositoryMockRecorder) SaveProvider(ctx, tid, p any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveProvider", reflect.TypeOf((*MockSmsTemplateRepository)(nil).SaveProvider), ctx, tid, p)
+}
+
+// Update mocks base method.
+func (m *MockSmsTemplateRepository) Update(ctx context.Context, t domain.SmsTemplate) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", ctx, t)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockSmsTemplateRepositoryMockRecorder) Update(ctx, t any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockSmsTemplateRepository)(nil).Update), ctx, t)
+}
+
+// UpdateProviderStatus mocks base method.
+func (m *MockSmsTemplateRepository) UpdateProviderStatus(ctx context.Context, tid int64, provider string, status domain.SmsTemplateStatus, reason string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateProviderStatus", ctx, tid, provider, status, reason)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateProviderStatus indicates an expected call of UpdateProviderStatus.
+func (mr *MockSmsTemplateRepositoryMockRecorder) UpdateProviderStatus(ctx, tid, provider, status, reason any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateProviderStatus", reflect.TypeOf((*MockSmsTemplateRepository)(nil).UpdateProviderStatus), ctx, tid, provider, status, reason)
+}
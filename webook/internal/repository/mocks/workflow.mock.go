@@ -0,0 +1,187 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./workflow.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./workflow.go -package=repomocks -destination=mocks/workflow.mock.go WorkflowRepository
+//
+
+// Package repomocks is a generated GoMock package.
+package repomocks
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+	domain "xiaoweishu/webook/internal/domain"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockWorkflowRepository is a mock of WorkflowRepository interface.
+type MockWorkflowRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockWorkflowRepositoryMockRecorder
+}
+
+// MockWorkflowRepositoryMockRecorder is the mock recorder for MockWorkflowRepository.
+type MockWorkflowRepositoryMockRecorder struct {
+	mock *MockWorkflowRepository
+}
+
+// NewMockWorkflowRepository creates a new mock instance.
+func NewMockWorkflowRepository(ctrl *gomock.Controller) *MockWorkflowRepository {
+	mock := &MockWorkflowRepository{ctrl: ctrl}
+	mock.recorder = &MockWorkflowRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockWorkflowRepository) EXPECT() *MockWorkflowRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CreateRun mocks base method.
+func (m *MockWorkflowRepository) CreateRun(ctx context.Context, run domain.WorkflowRun) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateRun", ctx, run)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateRun indicates an expected call of CreateRun.
+func (mr *MockWorkflowRepositoryMockRecorder) CreateRun(ctx, run any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateRun", reflect.TypeOf((*MockWorkflowRepository)(nil).CreateRun), ctx, run)
+}
+
+// FailRun mocks base method.
+func (m *MockWorkflowRepository) FailRun(ctx context.Context, runId int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FailRun", ctx, runId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// FailRun indicates an expected call of FailRun.
+func (mr *MockWorkflowRepositoryMockRecorder) FailRun(ctx, runId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FailRun", reflect.TypeOf((*MockWorkflowRepository)(nil).FailRun), ctx, runId)
+}
+
+// FindRun mocks base method.
+func (m *MockWorkflowRepository) FindRun(ctx context.Context, id int64) (domain.WorkflowRun, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindRun", ctx, id)
+	ret0, _ := ret[0].(domain.WorkflowRun)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindRun indicates an expected call of FindRun.
+func (mr *MockWorkflowRepositoryMockRecorder) FindRun(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindRun", reflect.TypeOf((*MockWorkflowRepository)(nil).FindRun), ctx, id)
+}
+
+// FindStaleRuns mocks base method.
+func (m *MockWorkflowRepository) FindStaleRuns(ctx context.Context, before time.Time, limit int) ([]domain.WorkflowRun, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindStaleRuns", ctx, before, limit)
+	ret0, _ := ret[0].([]domain.WorkflowRun)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindStaleRuns indicates an expected call of FindStaleRuns.
+func (mr *MockWorkflowRepositoryMockRecorder) FindStaleRuns(ctx, before, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindStaleRuns", reflect.TypeOf((*MockWorkflowRepository)(nil).FindStaleRuns), ctx, before, limit)
+}
+
+// FindSteps mocks base method.
+func (m *MockWorkflowRepository) FindSteps(ctx context.Context, runId int64) ([]domain.WorkflowStep, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindSteps", ctx, runId)
+	ret0, _ := ret[0].([]domain.WorkflowStep)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindSteps indicates an expected call of FindSteps.
+func (mr *MockWorkflowRepositoryMockRecorder) FindSteps(ctx, runId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindSteps", reflect.TypeOf((*MockWorkflowRepository)(nil).FindSteps), ctx, runId)
+}
+
+// FinishStep mocks base method.
+func (m *MockWorkflowRepository) FinishStep(ctx context.Context, step domain.WorkflowStep) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FinishStep", ctx, step)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// FinishStep indicates an expected call of FinishStep.
+func (mr *MockWorkflowRepositoryMockRecorder) FinishStep(ctx, step any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FinishStep", reflect.TypeOf((*MockWorkflowRepository)(nil).FinishStep), ctx, step)
+}
+
+// ListRuns mocks base method.
+func (m *MockWorkflowRepository) ListRuns(ctx context.Context, rootJid int64, offset, limit int) ([]domain.WorkflowRun, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListRuns", ctx, rootJid, offset, limit)
+	ret0, _ := ret[0].([]domain.WorkflowRun)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListRuns indicates an expected call of ListRuns.
+func (mr *MockWorkflowRepositoryMockRecorder) ListRuns(ctx, rootJid, offset, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListRuns", reflect.TypeOf((*MockWorkflowRepository)(nil).ListRuns), ctx, rootJid, offset, limit)
+}
+
+// StartStep mocks base method.
+func (m *MockWorkflowRepository) StartStep(ctx context.Context, runId, jid int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StartStep", ctx, runId, jid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// StartStep indicates an expected call of StartStep.
+func (mr *MockWorkflowRepositoryMockRecorder) StartStep(ctx, runId, jid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartStep", reflect.TypeOf((*MockWorkflowRepository)(nil).StartStep), ctx, runId, jid)
+}
+
+// SucceedRun mocks base method.
+func (m *MockWorkflowRepository) SucceedRun(ctx context.Context, runId int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SucceedRun", ctx, runId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SucceedRun indicates an expected call of SucceedRun.
+func (mr *MockWorkflowRepositoryMockRecorder) SucceedRun(ctx, runId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SucceedRun", reflect.TypeOf((*MockWorkflowRepository)(nil).SucceedRun), ctx, runId)
+}
+
+// TimeoutRun mocks base method.
+func (m *MockWorkflowRepository) TimeoutRun(ctx context.Context, runId int64, errMsg string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TimeoutRun", ctx, runId, errMsg)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// TimeoutRun indicates an expected call of TimeoutRun.
+func (mr *MockWorkflowRepositoryMockRecorder) TimeoutRun(ctx, runId, errMsg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TimeoutRun", reflect.TypeOf((*MockWorkflowRepository)(nil).TimeoutRun), ctx, runId, errMsg)
+}
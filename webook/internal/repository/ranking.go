@@ -2,10 +2,20 @@ package repository
 
 import (
 	"context"
+	"github.com/prometheus/client_golang/prometheus"
 	"xiaoweishu/webook/internal/domain"
 	"xiaoweishu/webook/internal/repository/cache"
 )
 
+// 热榜是从哪一层读到的
+const (
+	rankingTierLocal      = "local"
+	rankingTierRedis      = "redis"
+	rankingTierLocalStale = "local_stale"
+	rankingTierMiss       = "miss"
+)
+
+//go:generate mockgen -source=./ranking.go -package=repomocks -destination=mocks/ranking.mock.go RankingRepository
 type RankingRepository interface {
 	ReplaceTopN(ctx context.Context, arts []domain.Article) error
 	GetTopN(ctx context.Context) ([]domain.Article, error)
@@ -14,29 +24,48 @@ type CachedRankingRepository struct {
 	cache cache.RankingCache
 
 	// 下面是给 v1 用的
-	redisCache *cache.RankingRedisCache
+	redisCache cache.RankingCache
 	localCache *cache.RankingLocalCache
+	// vector 每一层命中了多少次
+	vector *prometheus.CounterVec
 }
 
-func NewCachedRankingRepositoryV1(redisCache *cache.RankingRedisCache, localCache *cache.RankingLocalCache) *CachedRankingRepository {
-	return &CachedRankingRepository{redisCache: redisCache, localCache: localCache}
+// NewCachedRankingRepositoryV1 先查本地缓存，再查 redis，redis 出问题的时候用本地缓存里面过期的数据兜底，
+// 每一层的命中次数都会上报
+func NewCachedRankingRepositoryV1(redisCache cache.RankingCache, localCache *cache.RankingLocalCache,
+	opt prometheus.CounterOpts) *CachedRankingRepository {
+	vector := prometheus.NewCounterVec(opt, []string{"tier"})
+	prometheus.MustRegister(vector)
+	return &CachedRankingRepository{redisCache: redisCache, localCache: localCache, vector: vector}
 
 }
 
 func (repo *CachedRankingRepository) GetTopNV1(ctx context.Context) ([]domain.Article, error) {
 	res, err := repo.localCache.Get(ctx)
 	if err == nil {
+		repo.vector.WithLabelValues(rankingTierLocal).Inc()
 		return res, nil
 	}
 	res, err = repo.redisCache.Get(ctx)
-	if err != nil {
-		return repo.localCache.ForceGet(ctx)
+	if err == nil {
+		repo.vector.WithLabelValues(rankingTierRedis).Inc()
+		_ = repo.localCache.Set(ctx, res)
+		return res, nil
 	}
-	_ = repo.localCache.Set(ctx, res)
+	//热榜短时间内变化不大，旧的数据总比没有好
+	res, er := repo.localCache.ForceGet(ctx)
+	if er != nil {
+		repo.vector.WithLabelValues(rankingTierMiss).Inc()
+		return nil, err
+	}
+	repo.vector.WithLabelValues(rankingTierLocalStale).Inc()
 	return res, nil
 }
 
 func (repo *CachedRankingRepository) GetTopN(ctx context.Context) ([]domain.Article, error) {
+	if repo.localCache != nil {
+		return repo.GetTopNV1(ctx)
+	}
 	return repo.cache.Get(ctx)
 }
 
@@ -44,11 +73,15 @@ func NewCachedRankingRepository(cache cache.RankingCache) RankingRepository {
 	return &CachedRankingRepository{cache: cache}
 }
 
+// ReplaceTopNV1 计算热榜的实例先把本地缓存预热了，自己处理读请求的时候不用再查 redis
 func (repo *CachedRankingRepository) ReplaceTopNV1(ctx context.Context, arts []domain.Article) error {
 	_ = repo.localCache.Set(ctx, arts)
 	return repo.redisCache.Set(ctx, arts)
 }
 
 func (repo *CachedRankingRepository) ReplaceTopN(ctx context.Context, arts []domain.Article) error {
+	if repo.localCache != nil {
+		return repo.ReplaceTopNV1(ctx, arts)
+	}
 	return repo.cache.Set(ctx, arts)
 }
@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"context"
+	"xiaoweishu/webook/internal/domain"
+	"xiaoweishu/webook/internal/repository/cache"
+)
+
+//go:generate mockgen -source=./ranking_board.go -package=repomocks -destination=mocks/ranking_board.mock.go RankingBoardRepository
+type RankingBoardRepository interface {
+	ReplaceTopN(ctx context.Context, board string, arts []domain.Article) error
+	// GetTopN 先查本地缓存，再查 redis
+	GetTopN(ctx context.Context, board string) ([]domain.Article, error)
+}
+
+type CachedRankingBoardRepository struct {
+	redisCache *cache.RankingBoardRedisCache
+	localCache *cache.RankingBoardLocalCache
+}
+
+func NewCachedRankingBoardRepository(redisCache *cache.RankingBoardRedisCache,
+	localCache *cache.RankingBoardLocalCache) RankingBoardRepository {
+	return &CachedRankingBoardRepository{
+		redisCache: redisCache,
+		localCache: localCache,
+	}
+}
+
+// ReplaceTopN 计算的实例顺便把本地缓存也更新了
+func (repo *CachedRankingBoardRepository) ReplaceTopN(ctx context.Context, board string, arts []domain.Article) error {
+	_ = repo.localCache.Set(ctx, board, arts)
+	return repo.redisCache.Set(ctx, board, arts)
+}
+
+func (repo *CachedRankingBoardRepository) GetTopN(ctx context.Context, board string) ([]domain.Article, error) {
+	res, err := repo.localCache.Get(ctx, board)
+	if err == nil {
+		return res, nil
+	}
+	res, err = repo.redisCache.Get(ctx, board)
+	if err != nil {
+		return nil, err
+	}
+	_ = repo.localCache.Set(ctx, board, res)
+	return res, nil
+}
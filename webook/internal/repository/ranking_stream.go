@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"context"
+	"time"
+	"xiaoweishu/webook/internal/domain"
+	"xiaoweishu/webook/internal/repository/cache"
+)
+
+//go:generate mockgen -source=./ranking_stream.go -package=repomocks -destination=mocks/ranking_stream.mock.go RankingStreamRepository
+
+// RankingStreamRepository 增量热榜的候选文章，数据只在 redis 里面，
+// 丢了也没关系，校正任务会从数据库重新算出来
+type RankingStreamRepository interface {
+	AddCandidate(ctx context.Context, aid int64, utime time.Time) error
+	RemoveCandidate(ctx context.Context, aid int64) error
+	BatchIncr(ctx context.Context, deltas []domain.RankingDelta) error
+	ResetCandidates(ctx context.Context, candidates []domain.RankingCandidate) error
+	PruneBefore(ctx context.Context, before time.Time) (int, error)
+	Top(ctx context.Context, n int) ([]int64, error)
+}
+
+type CachedRankingStreamRepository struct {
+	cache cache.RankingStreamCache
+}
+
+func NewCachedRankingStreamRepository(c cache.RankingStreamCache) RankingStreamRepository {
+	return &CachedRankingStreamRepository{cache: c}
+}
+
+func (repo *CachedRankingStreamRepository) AddCandidate(ctx context.Context, aid int64, utime time.Time) error {
+	return repo.cache.Put(ctx, aid, utime)
+}
+
+func (repo *CachedRankingStreamRepository) RemoveCandidate(ctx context.Context, aid int64) error {
+	return repo.cache.Remove(ctx, aid)
+}
+
+func (repo *CachedRankingStreamRepository) BatchIncr(ctx context.Context, deltas []domain.RankingDelta) error {
+	return repo.cache.BatchIncr(ctx, deltas)
+}
+
+func (repo *CachedRankingStreamRepository) ResetCandidates(ctx context.Context, candidates []domain.RankingCandidate) error {
+	return repo.cache.Reset(ctx, candidates)
+}
+
+func (repo *CachedRankingStreamRepository) PruneBefore(ctx context.Context, before time.Time) (int, error) {
+	return repo.cache.Prune(ctx, before)
+}
+
+func (repo *CachedRankingStreamRepository) Top(ctx context.Context, n int) ([]int64, error) {
+	return repo.cache.Top(ctx, n)
+}
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"github.com/ecodeclub/ekit/syncx/atomicx"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+	"xiaoweishu/webook/internal/domain"
+	"xiaoweishu/webook/internal/repository/cache"
+	cachemocks "xiaoweishu/webook/internal/repository/cache/mocks"
+)
+
+func TestCachedRankingRepository_GetTopN(t *testing.T) {
+	arts := []domain.Article{{Id: 1}, {Id: 2}}
+	testCases := []struct {
+		name  string
+		local func(t *testing.T) *cache.RankingLocalCache
+		redis func(ctrl *gomock.Controller) cache.RankingCache
+
+		wantArts []domain.Article
+		wantTier string
+		wantErr  bool
+	}{
+		{
+			name: "本地缓存命中",
+			local: func(t *testing.T) *cache.RankingLocalCache {
+				c := cache.NewRankingLocalCacheV1()
+				require.NoError(t, c.Set(context.Background(), arts))
+				return c
+			},
+			redis: func(ctrl *gomock.Controller) cache.RankingCache {
+				//本地命中就不查 redis
+				return cachemocks.NewMockRankingCache(ctrl)
+			},
+			wantArts: arts,
+			wantTier: rankingTierLocal,
+		},
+		{
+			name: "本地缓存没有，redis 命中",
+			local: func(t *testing.T) *cache.RankingLocalCache {
+				return cache.NewRankingLocalCacheV1()
+			},
+			redis: func(ctrl *gomock.Controller) cache.RankingCache {
+				c := cachemocks.NewMockRankingCache(ctrl)
+				c.EXPECT().Get(gomock.Any()).Return(arts, nil)
+				return c
+			},
+			wantArts: arts,
+			wantTier: rankingTierRedis,
+		},
+		{
+			name: "redis 出错，用本地过期的数据",
+			local: func(t *testing.T) *cache.RankingLocalCache {
+				c := cache.NewRankingLocalCache(atomicx.NewValue[[]domain.Article](),
+					atomicx.NewValueOf(time.Now()), -time.Minute).(*cache.RankingLocalCache)
+				require.NoError(t, c.Set(context.Background(), arts))
+				return c
+			},
+			redis: func(ctrl *gomock.Controller) cache.RankingCache {
+				c := cachemocks.NewMockRankingCache(ctrl)
+				c.EXPECT().Get(gomock.Any()).Return(nil, errors.New("redis 崩了"))
+				return c
+			},
+			wantArts: arts,
+			wantTier: rankingTierLocalStale,
+		},
+		{
+			name: "都没有",
+			local: func(t *testing.T) *cache.RankingLocalCache {
+				return cache.NewRankingLocalCacheV1()
+			},
+			redis: func(ctrl *gomock.Controller) cache.RankingCache {
+				c := cachemocks.NewMockRankingCache(ctrl)
+				c.EXPECT().Get(gomock.Any()).Return(nil, errors.New("redis 崩了"))
+				return c
+			},
+			wantTier: rankingTierMiss,
+			wantErr:  true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			//不注册到全局，避免重复注册
+			vector := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "ranking_read_tier"}, []string{"tier"})
+			repo := &CachedRankingRepository{
+				redisCache: tc.redis(ctrl),
+				localCache: tc.local(t),
+				vector:     vector,
+			}
+			res, err := repo.GetTopN(context.Background())
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.wantArts, res)
+			assert.Equal(t, float64(1), testutil.ToFloat64(vector.WithLabelValues(tc.wantTier)))
+		})
+	}
+}
+
+func TestCachedRankingRepository_ReplaceTopN(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	arts := []domain.Article{{Id: 1}}
+	local := cache.NewRankingLocalCacheV1()
+	redis := cachemocks.NewMockRankingCache(ctrl)
+	redis.EXPECT().Set(gomock.Any(), arts).Return(nil)
+	repo := &CachedRankingRepository{redisCache: redis, localCache: local}
+	require.NoError(t, repo.ReplaceTopN(context.Background(), arts))
+	//计算的实例顺便预热本地缓存
+	res, err := local.Get(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, arts, res)
+}
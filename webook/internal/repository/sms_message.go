@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"github.com/ecodeclub/ekit/slice"
+	"time"
+	"xiaoweishu/webook/internal/domain"
+	"xiaoweishu/webook/internal/repository/dao"
+)
+
+type SmsMessageRepository interface {
+	Create(ctx context.Context, msgs []domain.SmsMessage) error
+	// UpdateStatus 只更新还在等回执的短信，phone 为空就只按照 bizId 找。
+	// 返回 false 说明没找到，或者已经有结果了
+	UpdateStatus(ctx context.Context, provider, bizId, phone string, status domain.SmsMessageStatus,
+		reason string, reportTime time.Time) (bool, error)
+	List(ctx context.Context, q domain.SmsMessageQuery) ([]domain.SmsMessage, error)
+	Stats(ctx context.Context, start, end time.Time) ([]domain.SmsDeliveryStats, error)
+}
+
+type smsMessageRepository struct {
+	dao dao.SmsMessageDAO
+}
+
+func NewSmsMessageRepository(dao dao.SmsMessageDAO) SmsMessageRepository {
+	return &smsMessageRepository{dao: dao}
+}
+
+func (r *smsMessageRepository) Create(ctx context.Context, msgs []domain.SmsMessage) error {
+	return r.dao.Insert(ctx, slice.Map(msgs, func(idx int, src domain.SmsMessage) dao.SmsMessage {
+		return r.toEntity(src)
+	}))
+}
+
+func (r *smsMessageRepository) UpdateStatus(ctx context.Context, provider, bizId, phone string,
+	status domain.SmsMessageStatus, reason string, reportTime time.Time) (bool, error) {
+	cnt, err := r.dao.UpdateStatus(ctx, provider, bizId, phone, uint8(status), reason, reportTime.UnixMilli())
+	return cnt > 0, err
+}
+
+func (r *smsMessageRepository) List(ctx context.Context, q domain.SmsMessageQuery) ([]domain.SmsMessage, error) {
+	msgs, err := r.dao.List(ctx, q.Phone, q.Start.UnixMilli(), q.End.UnixMilli(), q.Offset, q.Limit)
+	if err != nil {
+		return nil, err
+	}
+	return slice.Map(msgs, func(idx int, src dao.SmsMessage) domain.SmsMessage {
+		return r.toDomain(src)
+	}), nil
+}
+
+func (r *smsMessageRepository) Stats(ctx context.Context, start, end time.Time) ([]domain.SmsDeliveryStats, error) {
+	cnts, err := r.dao.CountByStatus(ctx, start.UnixMilli(), end.UnixMilli())
+	if err != nil {
+		return nil, err
+	}
+	// 按照供应商第一次出现的顺序
+	idx := make(map[string]int, len(cnts))
+	res := make([]domain.SmsDeliveryStats, 0, len(cnts))
+	for _, c := range cnts {
+		i, ok := idx[c.Provider]
+		if !ok {
+			i = len(res)
+			idx[c.Provider] = i
+			res = append(res, domain.SmsDeliveryStats{Provider: c.Provider})
+		}
+		switch domain.SmsMessageStatus(c.Status) {
+		case domain.SmsMessageStatusSent:
+			res[i].Sent += c.Cnt
+		case domain.SmsMessageStatusDelivered:
+			res[i].Delivered += c.Cnt
+		case domain.SmsMessageStatusFailed:
+			res[i].Failed += c.Cnt
+		}
+	}
+	return res, nil
+}
+
+func (r *smsMessageRepository) toEntity(m domain.SmsMessage) dao.SmsMessage {
+	var reportTime int64
+	if !m.ReportTime.IsZero() {
+		reportTime = m.ReportTime.UnixMilli()
+	}
+	return dao.SmsMessage{
+		Id:         m.Id,
+		Provider:   m.Provider,
+		BizId:      m.BizId,
+		Phone:      m.Phone,
+		TplName:    m.TplName,
+		Status:     uint8(m.Status),
+		Reason:     m.Reason,
+		ReportTime: reportTime,
+	}
+}
+
+func (r *smsMessageRepository) toDomain(m dao.SmsMessage) domain.SmsMessage {
+	res := domain.SmsMessage{
+		Id:       m.Id,
+		Provider: m.Provider,
+		BizId:    m.BizId,
+		Phone:    m.Phone,
+		TplName:  m.TplName,
+		Status:   domain.SmsMessageStatus(m.Status),
+		Reason:   m.Reason,
+		Ctime:    time.UnixMilli(m.Ctime),
+		Utime:    time.UnixMilli(m.Utime),
+	}
+	if m.ReportTime > 0 {
+		res.ReportTime = time.UnixMilli(m.ReportTime)
+	}
+	return res
+}
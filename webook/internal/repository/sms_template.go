@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"context"
+	"github.com/ecodeclub/ekit/slice"
+	"github.com/ecodeclub/ekit/sqlx"
+	"time"
+	"xiaoweishu/webook/internal/domain"
+	"xiaoweishu/webook/internal/repository/dao"
+)
+
+var (
+	ErrSmsTemplateNotFound      = dao.ErrRecordNotFound
+	ErrSmsTemplateDuplicateName = dao.ErrSmsTemplateDuplicateName
+)
+
+//go:generate mockgen -source=./sms_template.go -package=repomocks -destination=mocks/sms_template.mock.go SmsTemplateRepository
+type SmsTemplateRepository interface {
+	Create(ctx context.Context, t domain.SmsTemplate) (int64, error)
+	Update(ctx context.Context, t domain.SmsTemplate) error
+	Delete(ctx context.Context, id int64) error
+	// FindById 和 FindByName 都带上各个供应商的模板
+	FindById(ctx context.Context, id int64) (domain.SmsTemplate, error)
+	FindByName(ctx context.Context, name string) (domain.SmsTemplate, error)
+	List(ctx context.Context, offset, limit int) ([]domain.SmsTemplate, error)
+	SaveProvider(ctx context.Context, tid int64, p domain.SmsProviderTemplate) error
+	DeleteProvider(ctx context.Context, tid int64, provider string) error
+	UpdateProviderStatus(ctx context.Context, tid int64, provider string,
+		status domain.SmsTemplateStatus, reason string) error
+}
+
+type smsTemplateRepository struct {
+	dao dao.SmsTemplateDAO
+}
+
+func NewSmsTemplateRepository(dao dao.SmsTemplateDAO) SmsTemplateRepository {
+	return &smsTemplateRepository{dao: dao}
+}
+
+func (r *smsTemplateRepository) Create(ctx context.Context, t domain.SmsTemplate) (int64, error) {
+	return r.dao.Insert(ctx, r.toEntity(t))
+}
+
+func (r *smsTemplateRepository) Update(ctx context.Context, t domain.SmsTemplate) error {
+	return r.dao.Update(ctx, r.toEntity(t))
+}
+
+func (r *smsTemplateRepository) Delete(ctx context.Context, id int64) error {
+	return r.dao.Delete(ctx, id)
+}
+
+func (r *smsTemplateRepository) FindById(ctx context.Context, id int64) (domain.SmsTemplate, error) {
+	t, err := r.dao.FindById(ctx, id)
+	if err != nil {
+		return domain.SmsTemplate{}, err
+	}
+	return r.withProviders(ctx, t)
+}
+
+func (r *smsTemplateRepository) FindByName(ctx context.Context, name string) (domain.SmsTemplate, error) {
+	t, err := r.dao.FindByName(ctx, name)
+	if err != nil {
+		return domain.SmsTemplate{}, err
+	}
+	return r.withProviders(ctx, t)
+}
+
+func (r *smsTemplateRepository) withProviders(ctx context.Context, t dao.SmsTemplate) (domain.SmsTemplate, error) {
+	ps, err := r.dao.FindProviders(ctx, []int64{t.Id})
+	if err != nil {
+		return domain.SmsTemplate{}, err
+	}
+	res := r.toDomain(t)
+	res.Providers = slice.Map(ps, func(idx int, src dao.SmsProviderTemplate) domain.SmsProviderTemplate {
+		return r.providerToDomain(src)
+	})
+	return res, nil
+}
+
+func (r *smsTemplateRepository) List(ctx context.Context, offset, limit int) ([]domain.SmsTemplate, error) {
+	ts, err := r.dao.List(ctx, offset, limit)
+	if err != nil || len(ts) == 0 {
+		return nil, err
+	}
+	ps, err := r.dao.FindProviders(ctx, slice.Map(ts, func(idx int, src dao.SmsTemplate) int64 {
+		return src.Id
+	}))
+	if err != nil {
+		return nil, err
+	}
+	providers := make(map[int64][]domain.SmsProviderTemplate, len(ts))
+	for _, p := range ps {
+		providers[p.Tid] = append(providers[p.Tid], r.providerToDomain(p))
+	}
+	return slice.Map(ts, func(idx int, src dao.SmsTemplate) domain.SmsTemplate {
+		res := r.toDomain(src)
+		res.Providers = providers[src.Id]
+		return res
+	}), nil
+}
+
+func (r *smsTemplateRepository) SaveProvider(ctx context.Context, tid int64, p domain.SmsProviderTemplate) error {
+	return r.dao.UpsertProvider(ctx, dao.SmsProviderTemplate{
+		Tid:      tid,
+		Provider: p.Provider,
+		TplId:    p.TplId,
+		SignName: p.SignName,
+		Status:   uint8(p.Status),
+		Reason:   p.Reason,
+	})
+}
+
+func (r *smsTemplateRepository) DeleteProvider(ctx context.Context, tid int64, provider string) error {
+	return r.dao.DeleteProvider(ctx, tid, provider)
+}
+
+func (r *smsTemplateRepository) UpdateProviderStatus(ctx context.Context, tid int64, provider string,
+	status domain.SmsTemplateStatus, reason string) error {
+	return r.dao.UpdateProviderStatus(ctx, tid, provider, uint8(status), reason)
+}
+
+func (r *smsTemplateRepository) toEntity(t domain.SmsTemplate) dao.SmsTemplate {
+	return dao.SmsTemplate{
+		Id:      t.Id,
+		Name:    t.Name,
+		Content: t.Content,
+		Args: sqlx.JsonColumn[[]dao.SmsTemplateArg]{
+			Val: slice.Map(t.Args, func(idx int, src domain.SmsTemplateArg) dao.SmsTemplateArg {
+				return dao.SmsTemplateArg{
+					Name:    src.Name,
+					Pattern: src.Pattern,
+					MaxLen:  src.MaxLen,
+				}
+			}),
+			Valid: true,
+		},
+	}
+}
+
+func (r *smsTemplateRepository) toDomain(t dao.SmsTemplate) domain.SmsTemplate {
+	return domain.SmsTemplate{
+		Id:      t.Id,
+		Name:    t.Name,
+		Content: t.Content,
+		Args: slice.Map(t.Args.Val, func(idx int, src dao.SmsTemplateArg) domain.SmsTemplateArg {
+			return domain.SmsTemplateArg{
+				Name:    src.Name,
+				Pattern: src.Pattern,
+				MaxLen:  src.MaxLen,
+			}
+		}),
+		Ctime: time.UnixMilli(t.Ctime),
+		Utime: time.UnixMilli(t.Utime),
+	}
+}
+
+func (r *smsTemplateRepository) providerToDomain(p dao.SmsProviderTemplate) domain.SmsProviderTemplate {
+	return domain.SmsProviderTemplate{
+		Provider: p.Provider,
+		TplId:    p.TplId,
+		SignName: p.SignName,
+		Status:   domain.SmsTemplateStatus(p.Status),
+		Reason:   p.Reason,
+		Utime:    time.UnixMilli(p.Utime),
+	}
+}
@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"context"
+	"github.com/ecodeclub/ekit/slice"
+	"time"
+	"xiaoweishu/webook/internal/domain"
+	"xiaoweishu/webook/internal/repository/dao"
+)
+
+var ErrWorkflowRunNotFound = dao.ErrRecordNotFound
+
+//go:generate mockgen -source=./workflow.go -package=repomocks -destination=mocks/workflow.mock.go WorkflowRepository
+type WorkflowRepository interface {
+	// CreateRun run 的 Steps 一起创建
+	CreateRun(ctx context.Context, run domain.WorkflowRun) (int64, error)
+	StartStep(ctx context.Context, runId int64, jid int64) error
+	FinishStep(ctx context.Context, step domain.WorkflowStep) error
+	FailRun(ctx context.Context, runId int64) error
+	// TimeoutRun 执行中的步骤也算失败，errMsg 是它们失败的原因
+	TimeoutRun(ctx context.Context, runId int64, errMsg string) error
+	// FindStaleRuns before 之前开始，到现在还在执行中的，不带步骤
+	FindStaleRuns(ctx context.Context, before time.Time, limit int) ([]domain.WorkflowRun, error)
+	SucceedRun(ctx context.Context, runId int64) error
+	// FindRun 带上所有的步骤
+	FindRun(ctx context.Context, id int64) (domain.WorkflowRun, error)
+	FindSteps(ctx context.Context, runId int64) ([]domain.WorkflowStep, error)
+	// ListRuns 不带步骤
+	ListRuns(ctx context.Context, rootJid int64, offset, limit int) ([]domain.WorkflowRun, error)
+}
+
+type workflowRepository struct {
+	dao dao.WorkflowDAO
+}
+
+func NewWorkflowRepository(dao dao.WorkflowDAO) WorkflowRepository {
+	return &workflowRepository{dao: dao}
+}
+
+func (r *workflowRepository) CreateRun(ctx context.Context, run domain.WorkflowRun) (int64, error) {
+	steps := slice.Map(run.Steps, func(idx int, src domain.WorkflowStep) dao.WorkflowStep {
+		return r.stepToEntity(src)
+	})
+	return r.dao.CreateRun(ctx, dao.WorkflowRun{
+		RootJid: run.RootJid,
+		Name:    run.Name,
+		Status:  uint8(run.Status),
+		Stime:   run.Stime.UnixMilli(),
+	}, steps)
+}
+
+func (r *workflowRepository) StartStep(ctx context.Context, runId int64, jid int64) error {
+	return r.dao.StartStep(ctx, runId, jid)
+}
+
+func (r *workflowRepository) FinishStep(ctx context.Context, step domain.WorkflowStep) error {
+	return r.dao.FinishStep(ctx, step.RunId, step.Jid, uint8(step.Status), step.Err)
+}
+
+func (r *workflowRepository) FailRun(ctx context.Context, runId int64) error {
+	return r.dao.FailRun(ctx, runId)
+}
+
+func (r *workflowRepository) TimeoutRun(ctx context.Context, runId int64, errMsg string) error {
+	return r.dao.TimeoutRun(ctx, runId, errMsg)
+}
+
+func (r *workflowRepository) FindStaleRuns(ctx context.Context, before time.Time, limit int) ([]domain.WorkflowRun, error) {
+	runs, err := r.dao.FindStaleRuns(ctx, before.UnixMilli(), limit)
+	if err != nil {
+		return nil, err
+	}
+	return slice.Map(runs, func(idx int, src dao.WorkflowRun) domain.WorkflowRun {
+		return r.runToDomain(src)
+	}), nil
+}
+
+func (r *workflowRepository) SucceedRun(ctx context.Context, runId int64) error {
+	return r.dao.SucceedRun(ctx, runId)
+}
+
+func (r *workflowRepository) FindRun(ctx context.Context, id int64) (domain.WorkflowRun, error) {
+	run, err := r.dao.FindRun(ctx, id)
+	if err != nil {
+		return domain.WorkflowRun{}, err
+	}
+	steps, err := r.FindSteps(ctx, id)
+	if err != nil {
+		return domain.WorkflowRun{}, err
+	}
+	res := r.runToDomain(run)
+	res.Steps = steps
+	return res, nil
+}
+
+func (r *workflowRepository) FindSteps(ctx context.Context, runId int64) ([]domain.WorkflowStep, error) {
+	steps, err := r.dao.FindSteps(ctx, runId)
+	if err != nil {
+		return nil, err
+	}
+	return slice.Map(steps, func(idx int, src dao.WorkflowStep) domain.WorkflowStep {
+		return r.stepToDomain(src)
+	}), nil
+}
+
+func (r *workflowRepository) ListRuns(ctx context.Context, rootJid int64, offset, limit int) ([]domain.WorkflowRun, error) {
+	runs, err := r.dao.ListRuns(ctx, rootJid, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	return slice.Map(runs, func(idx int, src dao.WorkflowRun) domain.WorkflowRun {
+		return r.runToDomain(src)
+	}), nil
+}
+
+func (r *workflowRepository) stepToEntity(s domain.WorkflowStep) dao.WorkflowStep {
+	res := dao.WorkflowStep{
+		Jid:    s.Jid,
+		Name:   s.Name,
+		Status: uint8(s.Status),
+		Err:    s.Err,
+	}
+	if !s.Stime.IsZero() {
+		res.Stime = s.Stime.UnixMilli()
+	}
+	return res
+}
+
+func (r *workflowRepository) runToDomain(run dao.WorkflowRun) domain.WorkflowRun {
+	return domain.WorkflowRun{
+		Id:      run.Id,
+		RootJid: run.RootJid,
+		Name:    run.Name,
+		Status:  domain.WorkflowRunStatus(run.Status),
+		Stime:   time.UnixMilli(run.Stime),
+		Etime:   r.toTime(run.Etime),
+	}
+}
+
+func (r *workflowRepository) stepToDomain(s dao.WorkflowStep) domain.WorkflowStep {
+	return domain.WorkflowStep{
+		Id:     s.Id,
+		RunId:  s.RunId,
+		Jid:    s.Jid,
+		Name:   s.Name,
+		Status: domain.WorkflowStepStatus(s.Status),
+		Err:    s.Err,
+		Stime:  r.toTime(s.Stime),
+		Etime:  r.toTime(s.Etime),
+	}
+}
+
+// toTime 0 表示还没有发生，转成零值
+func (r *workflowRepository) toTime(ms int64) time.Time {
+	if ms == 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(ms)
+}
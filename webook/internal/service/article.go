@@ -2,8 +2,6 @@ package service
 
 import (
 	"context"
-	"fmt"
-	"github.com/gin-gonic/gin"
 	"time"
 	"xiaoweishu/webook/internal/domain"
 	"xiaoweishu/webook/internal/events/article"
@@ -11,16 +9,15 @@ import (
 	logger2 "xiaoweishu/webook/pkg/logger"
 )
 
+//go:generate mockgen -source=./article.go -package=svcmocks -destination=mocks/article.mock.go ArticleService
 type ArticleService interface {
 	Save(ctx context.Context, art domain.Article) (int64, error)
 	Publish(ctx context.Context, art domain.Article) (int64, error)
 	Withdraw(ctx context.Context, uid int64, id int64) error
 	GetByAuthor(ctx context.Context, uid int64, offset int, limit int) ([]domain.Article, error)
 	GetById(ctx context.Context, id int64) (domain.Article, error)
-	GetPubById(ctx context.Context, id, uid int64) (domain.Article, error)
+	GetPubById(ctx context.Context, id, uid int64, client domain.ClientInfo) (domain.Article, error)
 	ListPub(ctx context.Context, start time.Time, offset, limit int) ([]domain.Article, error)
-	Like100(ctx *gin.Context, biz string) ([]domain.Like100, error)
-	UpdateTop200Articles(ctx context.Context) error
 }
 type articleService struct {
 	repo     repository.ArticleRepository
@@ -28,18 +25,6 @@ type articleService struct {
 	l        logger2.LoggerV1
 }
 
-func (a *articleService) UpdateTop200Articles(ctx context.Context) error {
-	return a.repo.GetTopArticles(ctx, "article", 200)
-
-}
-
-func (a *articleService) Like100(ctx *gin.Context, biz string) ([]domain.Like100, error) {
-	if biz != "article" {
-		return []domain.Like100{}, fmt.Errorf("unsupported biz type")
-	} //健壮性。一般都不会触发，因为在web层已经写死了
-	return a.repo.Like100(ctx, "article")
-}
-
 func (a *articleService) ListPub(ctx context.Context, start time.Time, offset, limit int) ([]domain.Article, error) {
 	return a.repo.ListPub(ctx, start, offset, limit)
 }
@@ -68,14 +53,43 @@ func (a *articleService) Save(ctx context.Context, art domain.Article) (int64, e
 // Publish 也就是同步的意思，将制作库的东西同步到线上库中
 func (a *articleService) Publish(ctx context.Context, art domain.Article) (int64, error) {
 	art.Status = domain.ArticleStatusPublished
-	return a.repo.Sync(ctx, art)
-
+	id, err := a.repo.Sync(ctx, art)
+	if err == nil {
+		a.producePublishEvent(article.PublishEvent{
+			Aid:      id,
+			Uid:      art.Author.Id,
+			Category: art.Category,
+			Utime:    time.Now().UnixMilli(),
+		})
+	}
+	return id, err
 }
 
 func (a *articleService) Withdraw(ctx context.Context, uid int64, id int64) error {
 	//隐藏文章，直接状态改成不可见或私人即可
-	return a.repo.SyncStatus(ctx, uid, id, domain.ArticleStatusPrivate)
+	err := a.repo.SyncStatus(ctx, uid, id, domain.ArticleStatusPrivate)
+	if err == nil {
+		a.producePublishEvent(article.PublishEvent{
+			Aid:       id,
+			Uid:       uid,
+			Withdrawn: true,
+			Utime:     time.Now().UnixMilli(),
+		})
+	}
+	return err
+}
 
+// producePublishEvent 发送失败不影响发表，增量热榜会在定时校正的时候补上
+func (a *articleService) producePublishEvent(evt article.PublishEvent) {
+	go func() {
+		er := a.producer.ProducePublishEvent(evt)
+		if er != nil {
+			a.l.Error("发送PublishEvent 失败",
+				logger2.Int64("aid", evt.Aid),
+				logger2.Int64("uid", evt.Uid),
+				logger2.Error(er))
+		}
+	}()
 }
 
 func (a *articleService) GetByAuthor(ctx context.Context, uid int64, offset int, limit int) ([]domain.Article, error) {
@@ -86,13 +100,16 @@ func (a *articleService) GetById(ctx context.Context, id int64) (domain.Article,
 	return a.repo.GetById(ctx, id)
 }
 
-func (a *articleService) GetPubById(ctx context.Context, id, uid int64) (domain.Article, error) {
+func (a *articleService) GetPubById(ctx context.Context, id, uid int64, client domain.ClientInfo) (domain.Article, error) {
 	art, err := a.repo.GetPubById(ctx, id)
 	go func() {
 		if err == nil {
 			er := a.producer.ProduceReadEvent(article.ReadEvent{
-				Aid: id,
-				Uid: uid,
+				Aid:       id,
+				Uid:       uid,
+				IP:        client.IP,
+				UserAgent: client.UserAgent,
+				Rtime:     time.Now().UnixMilli(),
 			})
 			if er != nil {
 				a.l.Error("发送ReadEvent 失败",
@@ -9,7 +9,9 @@ import (
 	"xiaoweishu/webook/internal/service/sms"
 )
 
-const codeTplid = "1877556"
+// codeTplName 验证码的短信模板，各个供应商的模板 id 在短信模板管理里面配置，
+// 模板有一个 6 位数字的参数
+const codeTplName = "verify_code"
 
 var (
 	ErrCodeVerifyTooManyTimes = repository.ErrCodeVerifyTooMany
@@ -47,7 +49,7 @@ func (svc *codeService) Send(ctx context.Context, biz string, phone string) erro
 		return err
 	}
 	//塞进去redis
-	err = svc.smsSVC.Send(ctx, codeTplid, []string{code}, phone)
+	err = svc.smsSVC.Send(ctx, codeTplName, []string{code}, phone)
 	if err != nil {
 		//这里说明写入redis成功，但是发送失败了
 		//可以在struct结构定义一个可以重发的接口，直接调用
@@ -2,6 +2,10 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
 	"time"
 	"xiaoweishu/webook/internal/domain"
 	"xiaoweishu/webook/internal/repository"
@@ -12,65 +16,315 @@ import (
 //如果成功抢占到锁，那么任务就会顺利执行。如果抢不到锁，说明有其他实例或线程已经在执行该任务，
 //此时当前实例或线程就会放弃执行，等待下一个调度周期再次尝试抢占锁。
 
+//go:generate mockgen -source=./job.go -package=svcmocks -destination=mocks/job.mock.go CronJobService
 type CronJobService interface {
 	Preempt(ctx context.Context) (domain.Job, error) //抢占分布式锁
 	//抢占分布式锁也算是job一部分，定时任务需要先去抢锁，再执行
 	ResetNextTime(ctx context.Context, j domain.Job) error //设置下次定时任务调度的时间
+	// Retry 执行失败之后调用，还能重试就按照指数退避安排重试，返回 true；否则等下一次调度
+	Retry(ctx context.Context, j domain.Job) (bool, error)
 	//Release(ctx context.Context, job domain.Job) error
-	// 暴露 job 的增删改查方法
+
+	// 下面是给管理后台用的
+	Create(ctx context.Context, j domain.Job) (int64, error)
+	// Update 修改任务的定义，下一次调度时间按照新的表达式重新计算
+	Update(ctx context.Context, j domain.Job) error
+	Delete(ctx context.Context, id int64) error
+	List(ctx context.Context, offset, limit int) ([]domain.Job, error)
+	// Pause 暂停之后不再调度，正在执行的这一次不受影响
+	Pause(ctx context.Context, id int64) error
+	// Resume 从现在开始按照表达式继续调度
+	Resume(ctx context.Context, id int64) error
+	// RunNow 下一轮抢占的时候就会被执行
+	RunNow(ctx context.Context, id int64) error
 }
+
+var (
+	ErrInvalidJob        = errors.New("任务的定义不合法")
+	ErrJobNotFound       = repository.ErrJobNotFound
+	ErrJobDuplicateName  = repository.ErrJobDuplicateName
+	ErrJobStatusConflict = repository.ErrJobStatusConflict
+	ErrJobPreempted      = repository.ErrJobPreempted
+)
+
+const (
+	// jobRetryBaseInterval 第一次重试的间隔，之后每次翻倍
+	jobRetryBaseInterval = 10 * time.Second
+	jobRetryMaxInterval  = 10 * time.Minute
+)
+
 type cronJobService struct {
 	repo            repository.CronJobRepository
 	l               logger2.LoggerV1
 	refreshInterval time.Duration
+	// instance 当前实例的名字，抢占到任务之后记录在任务上
+	instance string
+	// executors 注册过的执行器和它们的配置校验，为空的时候不校验
+	executors map[string]JobCfgValidator
 }
 
+// JobCfgValidator 校验任务的 Cfg 是不是执行器能用的，为 nil 就是执行器不需要配置
+type JobCfgValidator func(cfg string) error
+
 func (c *cronJobService) Preempt(ctx context.Context) (domain.Job, error) {
-	j, err := c.repo.Preempt(ctx)
+	//续约间隔的三倍都没有续约，就认为执行的实例已经挂了，任务可以被别人抢过来
+	staleBefore := time.Now().Add(-c.refreshInterval * 3)
+	j, err := c.repo.Preempt(ctx, c.instance, staleBefore)
 	if err != nil {
 		return domain.Job{}, err
 	}
 	//到这肯定是已经拿到了锁，所以这里可以开启一个定时器，定时更细utime，相当于续约锁
 	//只有当主动调用cancelfunc时才会释放锁
-	ticker := time.NewTicker(c.refreshInterval)
-	go func() {
-		for range ticker.C {
-			c.refresh(j.Id)
-		}
-	}()
+	lost, stop := keepLease(c.refreshInterval, func() error {
+		return c.refresh(j.Id, j.Version)
+	})
+	j.Lost = lost
+	var once sync.Once
 	j.CancelFunc = func() {
-		ticker.Stop()
-		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
-		defer cancel()
-		err := c.repo.Release(ctx, j.Id)
-		if err != nil {
-			c.l.Error("释放Job失败",
-				logger2.Error(err),
-				logger2.Int64("jid", j.Id))
-		}
+		once.Do(func() {
+			stop()
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			err := c.repo.Release(ctx, j.Id, j.Version)
+			if err != nil {
+				c.l.Error("释放Job失败",
+					logger2.Error(err),
+					logger2.Int64("jid", j.Id))
+			}
+		})
 	}
 	return j, err
 }
 
 func (c *cronJobService) ResetNextTime(ctx context.Context, j domain.Job) error {
-	nextTime := j.NextTime()
-	return c.repo.UpdateNextTime(ctx, j.Id, nextTime)
+	return c.repo.UpdateNextTime(ctx, j.Id, j.Version, c.nextTime(j))
+}
+
+// nextTime catch_up 从预定的时间往后算，错过的会一次次补上；其它的从现在往后算
+func (c *cronJobService) nextTime(j domain.Job) time.Time {
+	if j.MisfirePolicy == domain.JobMisfireCatchUp && !j.NextExecTime.IsZero() {
+		return j.NextTimeAfter(j.NextExecTime)
+	}
+	return j.NextTime()
+}
+
+func (c *cronJobService) Retry(ctx context.Context, j domain.Job) (bool, error) {
+	if j.Retries >= j.MaxRetries {
+		//重试次数用完了，放弃这一次
+		c.l.Warn("任务重试次数用完了",
+			logger2.Int64("jid", j.Id),
+			logger2.Int("retries", j.Retries))
+		return false, c.ResetNextTime(ctx, j)
+	}
+	return true, c.repo.UpdateRetry(ctx, j.Id, j.Version, time.Now().Add(retryInterval(j.Retries)), j.Retries+1)
+}
+
+// retryInterval 第 retries+1 次重试之前等多久，每次翻倍，最多等 jobRetryMaxInterval
+func retryInterval(retries int) time.Duration {
+	interval := jobRetryBaseInterval << retries
+	if interval > jobRetryMaxInterval || interval <= 0 {
+		return jobRetryMaxInterval
+	}
+	return interval
 }
-func (c *cronJobService) refresh(id int64) {
+
+// keepLease 每隔 interval 调用 refresh 续约，直到调用 stop。
+// 续约的时候发现已经被别人抢走了，或者太久没续约成功、别人随时会抢走，都会关闭 lost，执行的一方不能再执行下去了。
+// 别人抢占的条件是三倍续约间隔没有续约，这里留一个续约间隔的余量
+func keepLease(interval time.Duration, refresh func() error) (<-chan struct{}, func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	lost := make(chan struct{})
+	go func() {
+		defer ticker.Stop()
+		lastRefresh := time.Now()
+		for {
+			select {
+			case <-ticker.C:
+			case <-done:
+				return
+			}
+			err := refresh()
+			if err == nil {
+				lastRefresh = time.Now()
+				continue
+			}
+			if errors.Is(err, ErrJobPreempted) || time.Since(lastRefresh) >= interval*2 {
+				close(lost)
+				return
+			}
+		}
+	}()
+	return lost, func() {
+		close(done)
+	}
+}
+
+func (c *cronJobService) refresh(id int64, version int) error {
 	//本质上就是更新时间
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 	defer cancel()
-	err := c.repo.UpdateUtime(ctx, id)
+	err := c.repo.UpdateUtime(ctx, id, version)
 	if err != nil {
 		c.l.Error("续约失败", logger2.Error(err),
 			logger2.Int64("jid", id))
 	}
+	return err
+}
+func (c *cronJobService) Create(ctx context.Context, j domain.Job) (int64, error) {
+	next, err := c.validate(ctx, j)
+	if err != nil {
+		return 0, err
+	}
+	j.Status = domain.JobStatusWaiting
+	j.NextExecTime = next
+	return c.repo.Create(ctx, j)
+}
+
+func (c *cronJobService) Update(ctx context.Context, j domain.Job) error {
+	next, err := c.validate(ctx, j)
+	if err != nil {
+		return err
+	}
+	j.NextExecTime = next
+	return c.repo.Update(ctx, j)
+}
+
+func (c *cronJobService) Delete(ctx context.Context, id int64) error {
+	return c.repo.Delete(ctx, id)
+}
+
+func (c *cronJobService) List(ctx context.Context, offset, limit int) ([]domain.Job, error) {
+	return c.repo.List(ctx, offset, limit)
+}
+
+func (c *cronJobService) Pause(ctx context.Context, id int64) error {
+	return c.repo.Pause(ctx, id)
+}
+
+func (c *cronJobService) Resume(ctx context.Context, id int64) error {
+	j, err := c.repo.FindById(ctx, id)
+	if err != nil {
+		return err
+	}
+	return c.repo.Resume(ctx, id, j.NextTime())
+}
 
+func (c *cronJobService) RunNow(ctx context.Context, id int64) error {
+	return c.repo.RunNow(ctx, id)
 }
+
+// validate 返回按照表达式计算出来的下一次调度时间，有上游的任务不会自己调度
+func (c *cronJobService) validate(ctx context.Context, j domain.Job) (time.Time, error) {
+	if j.Name == "" {
+		return time.Time{}, fmt.Errorf("%w: 名字不能为空", ErrInvalidJob)
+	}
+	next := domain.JobNeverExecTime
+	if len(j.Upstreams) > 0 {
+		if j.Expression != "" {
+			return time.Time{}, fmt.Errorf("%w: 有上游的任务由上游触发，不能配置 cron 表达式", ErrInvalidJob)
+		}
+		err := c.validateUpstreams(ctx, j)
+		if err != nil {
+			return time.Time{}, err
+		}
+	} else {
+		sch, err := domain.ParseJobExpression(j.Expression)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("%w: cron 表达式 %s 不对 %s", ErrInvalidJob, j.Expression, err.Error())
+		}
+		next = sch.Next(time.Now())
+	}
+	if j.MaxRetries < 0 {
+		return time.Time{}, fmt.Errorf("%w: 重试次数不能小于 0", ErrInvalidJob)
+	}
+	if j.Timeout < 0 {
+		return time.Time{}, fmt.Errorf("%w: 超时时间不能小于 0", ErrInvalidJob)
+	}
+	if j.Shards < 0 {
+		return time.Time{}, fmt.Errorf("%w: 分片数不能小于 0", ErrInvalidJob)
+	}
+	if len(c.executors) > 0 {
+		validate, ok := c.executors[j.Executor]
+		if !ok {
+			return time.Time{}, fmt.Errorf("%w: 没有注册执行器 %s", ErrInvalidJob, j.Executor)
+		}
+		if validate != nil {
+			err := validate(j.Cfg)
+			if err != nil {
+				return time.Time{}, fmt.Errorf("%w: 执行器 %s 的配置不对 %s", ErrInvalidJob, j.Executor, err.Error())
+			}
+		}
+	}
+	return next, nil
+}
+
+// validateUpstreams 上游必须存在，并且不能形成环
+func (c *cronJobService) validateUpstreams(ctx context.Context, j domain.Job) error {
+	seen := make(map[int64]struct{}, len(j.Upstreams))
+	for _, up := range j.Upstreams {
+		if up == j.Id {
+			return fmt.Errorf("%w: 不能依赖自己", ErrInvalidJob)
+		}
+		if _, ok := seen[up]; ok {
+			return fmt.Errorf("%w: 上游 %d 重复了", ErrInvalidJob, up)
+		}
+		seen[up] = struct{}{}
+		_, err := c.repo.FindById(ctx, up)
+		if errors.Is(err, repository.ErrJobNotFound) {
+			return fmt.Errorf("%w: 上游 %d 不存在", ErrInvalidJob, up)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if j.Id == 0 {
+		//新建的任务还没有下游，不会有环
+		return nil
+	}
+	//顺着上游往上找，找到自己就是有环
+	visited := map[int64]struct{}{}
+	cur := j.Upstreams
+	for len(cur) > 0 {
+		ups, err := c.repo.FindUpstreams(ctx, cur)
+		if err != nil {
+			return err
+		}
+		var next []int64
+		for _, jid := range cur {
+			visited[jid] = struct{}{}
+			for _, up := range ups[jid] {
+				if up == j.Id {
+					return fmt.Errorf("%w: 依赖形成了环", ErrInvalidJob)
+				}
+				if _, ok := visited[up]; !ok {
+					next = append(next, up)
+				}
+			}
+		}
+		cur = next
+	}
+	return nil
+}
+
 func NewCronJobService(l logger2.LoggerV1, refreshInterval time.Duration, repo repository.CronJobRepository) CronJobService {
+	instance, _ := os.Hostname()
+	return &cronJobService{
+		repo:            repo,
+		l:               l,
+		refreshInterval: refreshInterval,
+		instance:        instance,
+	}
+}
+
+// NewCronJobServiceV1 创建和修改任务的时候会检查执行器是不是在 executors 里面，以及任务的配置能不能被执行器用
+func NewCronJobServiceV1(l logger2.LoggerV1, refreshInterval time.Duration,
+	repo repository.CronJobRepository, instance string, executors map[string]JobCfgValidator) CronJobService {
 	return &cronJobService{
 		repo:            repo,
 		l:               l,
 		refreshInterval: refreshInterval,
+		instance:        instance,
+		executors:       executors,
 	}
 }
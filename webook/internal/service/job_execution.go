@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"xiaoweishu/webook/internal/domain"
+	"xiaoweishu/webook/internal/repository"
+)
+
+// JobExecutionService 任务的执行历史
+type JobExecutionService interface {
+	// Start 记录开始执行，返回执行记录的 id
+	Start(ctx context.Context, j domain.Job, attempt int) (int64, error)
+	// Finish execErr 为 nil 就是执行成功
+	Finish(ctx context.Context, id int64, output string, execErr error) error
+	List(ctx context.Context, jid int64, offset, limit int) ([]domain.JobExecution, error)
+	// Clean 删除超过保留期限的记录，返回删除了多少条
+	Clean(ctx context.Context) (int64, error)
+	// CleanBefore 删除 t 之前开始的记录，管理后台手动清理用
+	CleanBefore(ctx context.Context, t time.Time) (int64, error)
+}
+
+// jobExecutionMaxLen 错误和结果摘要最多保存这么多个字符
+const jobExecutionMaxLen = 1000
+
+type jobExecutionService struct {
+	repo      repository.JobExecutionRepository
+	retention time.Duration
+}
+
+func NewJobExecutionService(repo repository.JobExecutionRepository, retention time.Duration) JobExecutionService {
+	return &jobExecutionService{
+		repo:      repo,
+		retention: retention,
+	}
+}
+
+func (s *jobExecutionService) Start(ctx context.Context, j domain.Job, attempt int) (int64, error) {
+	name := j.Name
+	if j.Shard.Total > 0 {
+		//分片的执行记录挂在父任务下面，名字带上是第几片
+		name = fmt.Sprintf("%s#%d", j.Name, j.Shard.Idx)
+	}
+	return s.repo.Create(ctx, domain.JobExecution{
+		Jid:      j.Id,
+		Name:     name,
+		Instance: j.Owner,
+		Attempt:  attempt,
+		Status:   domain.JobExecutionStatusRunning,
+		Stime:    time.Now(),
+	})
+}
+
+func (s *jobExecutionService) Finish(ctx context.Context, id int64, output string, execErr error) error {
+	e := domain.JobExecution{
+		Id:     id,
+		Status: domain.JobExecutionStatusSuccess,
+		Output: truncate(output, jobExecutionMaxLen),
+		Etime:  time.Now(),
+	}
+	if execErr != nil {
+		e.Status = domain.JobExecutionStatusFailed
+		e.Err = truncate(execErr.Error(), jobExecutionMaxLen)
+	}
+	return s.repo.Finish(ctx, e)
+}
+
+func (s *jobExecutionService) List(ctx context.Context, jid int64, offset, limit int) ([]domain.JobExecution, error) {
+	return s.repo.ListByJob(ctx, jid, offset, limit)
+}
+
+func (s *jobExecutionService) Clean(ctx context.Context) (int64, error) {
+	return s.repo.DeleteBefore(ctx, time.Now().Add(-s.retention))
+}
+
+func (s *jobExecutionService) CleanBefore(ctx context.Context, t time.Time) (int64, error) {
+	return s.repo.DeleteBefore(ctx, t)
+}
+
+// truncate 按照字符截断，不会把中文截断成乱码
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n])
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"strings"
+	"testing"
+	"time"
+	"xiaoweishu/webook/internal/domain"
+	repomocks "xiaoweishu/webook/internal/repository/mocks"
+)
+
+func TestJobExecutionService_Start(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	repo := repomocks.NewMockJobExecutionRepository(ctrl)
+	repo.EXPECT().Create(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, e domain.JobExecution) (int64, error) {
+			assert.Equal(t, "job", e.Name)
+			assert.Equal(t, "a", e.Instance)
+			assert.Equal(t, domain.JobExecutionStatusRunning, e.Status)
+			return 1, nil
+		})
+	// 分片的记录名字带上是第几片
+	repo.EXPECT().Create(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, e domain.JobExecution) (int64, error) {
+			assert.Equal(t, "job#2", e.Name)
+			return 2, nil
+		})
+	svc := NewJobExecutionService(repo, time.Hour)
+	_, err := svc.Start(context.Background(), domain.Job{Id: 1, Name: "job", Owner: "a"}, 1)
+	require.NoError(t, err)
+	_, err = svc.Start(context.Background(), domain.Job{Id: 1, Name: "job",
+		Shard: domain.JobShard{Idx: 2, Total: 4}}, 1)
+	require.NoError(t, err)
+}
+
+func TestJobExecutionService_Finish(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	repo := repomocks.NewMockJobExecutionRepository(ctrl)
+	repo.EXPECT().Finish(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, e domain.JobExecution) error {
+			assert.Equal(t, domain.JobExecutionStatusSuccess, e.Status)
+			assert.Equal(t, "done", e.Output)
+			return nil
+		})
+	// 太长的错误按照字符截断，中文不会变成乱码
+	repo.EXPECT().Finish(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, e domain.JobExecution) error {
+			assert.Equal(t, domain.JobExecutionStatusFailed, e.Status)
+			assert.Equal(t, strings.Repeat("错", jobExecutionMaxLen), e.Err)
+			return nil
+		})
+	svc := NewJobExecutionService(repo, time.Hour)
+	err := svc.Finish(context.Background(), 1, "done", nil)
+	require.NoError(t, err)
+	long := strings.Repeat("错", jobExecutionMaxLen+10)
+	err = svc.Finish(context.Background(), 1, "", errors.New(long))
+	require.NoError(t, err)
+}
+
+func TestJobExecutionService_Clean(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	repo := repomocks.NewMockJobExecutionRepository(ctrl)
+	repo.EXPECT().DeleteBefore(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, before time.Time) (int64, error) {
+			assert.WithinDuration(t, time.Now().Add(-time.Hour), before, time.Second)
+			return 3, nil
+		})
+	svc := NewJobExecutionService(repo, time.Hour)
+	cnt, err := svc.Clean(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), cnt)
+}
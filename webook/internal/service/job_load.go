@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+	"xiaoweishu/webook/internal/repository"
+)
+
+//go:generate mockgen -source=./job_load.go -package=svcmocks -destination=mocks/job_load.mock.go JobLoadService
+
+// JobLoadService 上报当前实例的负载，并且知道自己在所有实例里面排第几。
+// 负载低的实例优先抢占任务
+type JobLoadService interface {
+	// Report load 是 0 到 100 的百分比
+	Report(ctx context.Context, load int) error
+	// Rank 0 就是负载最低的实例。用的是最近一次上报的时候查到的排名，不会访问 redis
+	Rank() int
+}
+
+type jobLoadService struct {
+	repo     repository.JobLoadRepository
+	instance string
+	// expiration 这么久没有上报的实例就不参与排名了
+	expiration time.Duration
+	rank       atomic.Int32
+}
+
+func NewJobLoadService(repo repository.JobLoadRepository, instance string, expiration time.Duration) JobLoadService {
+	return &jobLoadService{
+		repo:       repo,
+		instance:   instance,
+		expiration: expiration,
+	}
+}
+
+func (s *jobLoadService) Report(ctx context.Context, load int) error {
+	rank, err := s.report(ctx, load)
+	if err != nil {
+		//查不到排名就当自己是负载最低的，不然 redis 出问题的时候谁都不去抢任务
+		s.rank.Store(0)
+		return err
+	}
+	s.rank.Store(int32(rank))
+	return nil
+}
+
+func (s *jobLoadService) report(ctx context.Context, load int) (int, error) {
+	err := s.repo.Report(ctx, s.instance, load, s.expiration)
+	if err != nil {
+		return 0, err
+	}
+	return s.repo.Rank(ctx, s.instance)
+}
+
+func (s *jobLoadService) Rank() int {
+	return int(s.rank.Load())
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+	"xiaoweishu/webook/internal/domain"
+	"xiaoweishu/webook/internal/repository"
+	logger2 "xiaoweishu/webook/pkg/logger"
+)
+
+//go:generate mockgen -source=./job_shard.go -package=svcmocks -destination=mocks/job_shard.mock.go JobShardService
+
+// JobShardService 大任务每一轮拆成多个分片，分片和任务一样被各个实例抢占执行，
+// 抢到父任务的实例负责拆分，然后等所有的分片执行完
+type JobShardService interface {
+	// Split 把 j 这一轮拆成 j.Shards 片，重复拆分不会产生新的分片
+	Split(ctx context.Context, j domain.Job) error
+	// Preempt 抢占一个分片，返回的是父任务，Shard 字段是抢到的分片。执行完调用 CancelFunc 停止续约
+	Preempt(ctx context.Context) (domain.Job, error)
+	// Finish 失败了按照父任务的重试次数重试
+	Finish(ctx context.Context, j domain.Job, execErr error) error
+	// Progress j 这一轮各个分片的执行情况
+	Progress(ctx context.Context, j domain.Job) (domain.JobShardProgress, error)
+	// Latest 最近一轮的分片，管理后台用
+	Latest(ctx context.Context, jid int64) ([]domain.JobShard, error)
+}
+
+type jobShardService struct {
+	repo            repository.JobShardRepository
+	jobRepo         repository.CronJobRepository
+	l               logger2.LoggerV1
+	refreshInterval time.Duration
+	instance        string
+}
+
+func NewJobShardService(repo repository.JobShardRepository, jobRepo repository.CronJobRepository,
+	l logger2.LoggerV1, refreshInterval time.Duration, instance string) JobShardService {
+	return &jobShardService{
+		repo:            repo,
+		jobRepo:         jobRepo,
+		l:               l,
+		refreshInterval: refreshInterval,
+		instance:        instance,
+	}
+}
+
+func (s *jobShardService) Split(ctx context.Context, j domain.Job) error {
+	return s.repo.Split(ctx, j.Id, j.NextExecTime, j.Shards)
+}
+
+func (s *jobShardService) Preempt(ctx context.Context) (domain.Job, error) {
+	staleBefore := time.Now().Add(-s.refreshInterval * 3)
+	shard, err := s.repo.Preempt(ctx, s.instance, staleBefore)
+	if err != nil {
+		return domain.Job{}, err
+	}
+	j, err := s.jobRepo.FindById(ctx, shard.Jid)
+	if err != nil {
+		if errors.Is(err, repository.ErrJobNotFound) {
+			//父任务被删了，这个分片也不用执行了
+			s.finish(shard, domain.JobShardStatusFailed, "任务不存在")
+		}
+		//别的错误就等续约过期，让别人重新抢
+		return domain.Job{}, fmt.Errorf("分片 %d 的任务 %d 找不到 %w", shard.Id, shard.Jid, err)
+	}
+	j.Shard = shard
+	j.Owner = s.instance
+	lost, stop := keepLease(s.refreshInterval, func() error {
+		return s.refresh(shard)
+	})
+	j.Lost = lost
+	var once sync.Once
+	j.CancelFunc = func() {
+		once.Do(stop)
+	}
+	return j, nil
+}
+
+func (s *jobShardService) refresh(shard domain.JobShard) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	err := s.repo.UpdateUtime(ctx, shard.Id, shard.Version)
+	if err != nil {
+		s.l.Error("分片续约失败", logger2.Error(err),
+			logger2.Int64("sid", shard.Id))
+	}
+	return err
+}
+
+func (s *jobShardService) Finish(ctx context.Context, j domain.Job, execErr error) error {
+	shard := j.Shard
+	shard.Err = ""
+	shard.NextExecTime = time.Time{}
+	switch {
+	case execErr == nil:
+		shard.Status = domain.JobShardStatusSuccess
+	case shard.Retries < j.MaxRetries:
+		//和任务的重试一样指数退避
+		shard.Status = domain.JobShardStatusWaiting
+		shard.NextExecTime = time.Now().Add(retryInterval(shard.Retries))
+		shard.Retries++
+		shard.Err = truncate(execErr.Error(), jobExecutionMaxLen)
+	default:
+		shard.Status = domain.JobShardStatusFailed
+		shard.Err = truncate(execErr.Error(), jobExecutionMaxLen)
+	}
+	return s.repo.Finish(ctx, shard)
+}
+
+// finish 找不到父任务的时候直接结束分片
+func (s *jobShardService) finish(shard domain.JobShard, status domain.JobShardStatus, msg string) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	shard.Status = status
+	shard.Err = msg
+	err := s.repo.Finish(ctx, shard)
+	if err != nil {
+		s.l.Error("结束分片失败", logger2.Error(err),
+			logger2.Int64("sid", shard.Id))
+	}
+}
+
+func (s *jobShardService) Progress(ctx context.Context, j domain.Job) (domain.JobShardProgress, error) {
+	shards, err := s.repo.ListByRound(ctx, j.Id, j.NextExecTime)
+	if err != nil {
+		return domain.JobShardProgress{}, err
+	}
+	res := domain.JobShardProgress{Total: len(shards)}
+	for _, shard := range shards {
+		switch shard.Status {
+		case domain.JobShardStatusWaiting:
+			res.Waiting++
+		case domain.JobShardStatusRunning:
+			res.Running++
+		case domain.JobShardStatusSuccess:
+			res.Success++
+		case domain.JobShardStatusFailed:
+			res.Failed++
+		}
+	}
+	return res, nil
+}
+
+func (s *jobShardService) Latest(ctx context.Context, jid int64) ([]domain.JobShard, error) {
+	return s.repo.ListLatest(ctx, jid)
+}
@@ -0,0 +1,190 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+	"xiaoweishu/webook/internal/domain"
+	"xiaoweishu/webook/internal/repository"
+	repomocks "xiaoweishu/webook/internal/repository/mocks"
+	"xiaoweishu/webook/pkg/logger"
+)
+
+func TestCronJobService_Create(t *testing.T) {
+	// created 校验通过之后按照等待调度的状态保存
+	created := func(ctrl *gomock.Controller) repository.CronJobRepository {
+		repo := repomocks.NewMockCronJobRepository(ctrl)
+		repo.EXPECT().Create(gomock.Any(), gomock.Cond(func(x any) bool {
+			j := x.(domain.Job)
+			return j.Status == domain.JobStatusWaiting && j.NextExecTime.After(time.Now())
+		})).Return(int64(1), nil)
+		return repo
+	}
+	invalid := func(ctrl *gomock.Controller) repository.CronJobRepository {
+		return repomocks.NewMockCronJobRepository(ctrl)
+	}
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) repository.CronJobRepository
+		job  domain.Job
+
+		wantErr error
+	}{
+		{
+			name: "创建成功",
+			mock: created,
+			job:  domain.Job{Name: "job", Executor: "local", Expression: "0 */5 * * * ?"},
+		},
+		{
+			name:    "名字为空",
+			mock:    invalid,
+			job:     domain.Job{Executor: "local", Expression: "0 */5 * * * ?"},
+			wantErr: ErrInvalidJob,
+		},
+		{
+			name:    "cron 表达式不对",
+			mock:    invalid,
+			job:     domain.Job{Name: "job", Executor: "local", Expression: "abc"},
+			wantErr: ErrInvalidJob,
+		},
+		{
+			name:    "没有注册的执行器",
+			mock:    invalid,
+			job:     domain.Job{Name: "job", Executor: "unknown", Expression: "0 */5 * * * ?"},
+			wantErr: ErrInvalidJob,
+		},
+		{
+			name: "由上游触发",
+			mock: func(ctrl *gomock.Controller) repository.CronJobRepository {
+				repo := repomocks.NewMockCronJobRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), int64(1)).Return(domain.Job{Id: 1, Name: "upstream"}, nil)
+				//由上游触发，自己不调度
+				repo.EXPECT().Create(gomock.Any(), gomock.Cond(func(x any) bool {
+					j := x.(domain.Job)
+					return j.Status == domain.JobStatusWaiting && j.NextExecTime.Equal(domain.JobNeverExecTime)
+				})).Return(int64(1), nil)
+				return repo
+			},
+			job: domain.Job{Name: "job", Executor: "local", Upstreams: []int64{1}},
+		},
+		{
+			name:    "有上游还配置了表达式",
+			mock:    invalid,
+			job:     domain.Job{Name: "job", Executor: "local", Expression: "0 */5 * * * ?", Upstreams: []int64{1}},
+			wantErr: ErrInvalidJob,
+		},
+		{
+			name: "上游不存在",
+			mock: func(ctrl *gomock.Controller) repository.CronJobRepository {
+				repo := repomocks.NewMockCronJobRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), int64(2)).Return(domain.Job{}, repository.ErrJobNotFound)
+				return repo
+			},
+			job:     domain.Job{Name: "job", Executor: "local", Upstreams: []int64{2}},
+			wantErr: ErrInvalidJob,
+		},
+		{
+			name: "执行器的配置正确",
+			mock: created,
+			job:  domain.Job{Name: "job", Executor: "http", Cfg: "ok", Expression: "0 */5 * * * ?"},
+		},
+		{
+			name:    "执行器的配置不对",
+			mock:    invalid,
+			job:     domain.Job{Name: "job", Executor: "http", Expression: "0 */5 * * * ?"},
+			wantErr: ErrInvalidJob,
+		},
+		{
+			name:    "重试次数是负数",
+			mock:    invalid,
+			job:     domain.Job{Name: "job", Executor: "local", Expression: "0 */5 * * * ?", MaxRetries: -1},
+			wantErr: ErrInvalidJob,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := NewCronJobServiceV1(logger.NewNopLogger(), time.Second, tc.mock(ctrl), "test", map[string]JobCfgValidator{
+				"local": nil,
+				"http": func(cfg string) error {
+					if cfg == "" {
+						return errors.New("没有配置")
+					}
+					return nil
+				},
+			})
+			_, err := svc.Create(context.Background(), tc.job)
+			assert.ErrorIs(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestCronJobService_Resume(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	repo := repomocks.NewMockCronJobRepository(ctrl)
+	repo.EXPECT().FindById(gomock.Any(), int64(1)).
+		Return(domain.Job{Id: 1, Name: "job", Expression: "0 */5 * * * ?", Status: domain.JobStatusPaused}, nil)
+	// 从现在开始按照表达式调度，暂停期间错过的不补
+	repo.EXPECT().Resume(gomock.Any(), int64(1), gomock.Cond(func(x any) bool {
+		next := x.(time.Time)
+		return next.After(time.Now()) && next.Before(time.Now().Add(time.Minute*5))
+	})).Return(nil)
+	repo.EXPECT().FindById(gomock.Any(), int64(2)).Return(domain.Job{}, repository.ErrJobNotFound)
+	svc := NewCronJobServiceV1(logger.NewNopLogger(), time.Second, repo, "test", nil)
+	err := svc.Resume(context.Background(), 1)
+	require.NoError(t, err)
+	assert.ErrorIs(t, svc.Resume(context.Background(), 2), ErrJobNotFound)
+}
+
+func TestCronJobService_Preempt(t *testing.T) {
+	testCases := []struct {
+		name       string
+		refreshErr error
+
+		wantLost bool
+	}{
+		{
+			name: "续约成功",
+		},
+		{
+			name:       "任务被别人重新抢占了",
+			refreshErr: ErrJobPreempted,
+			wantLost:   true,
+		},
+		{
+			// 数据库一直不可用，太久没续约成功，别人随时会抢走
+			name:       "一直续约失败",
+			refreshErr: errors.New("mock db error"),
+			wantLost:   true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			repo := repomocks.NewMockCronJobRepository(ctrl)
+			repo.EXPECT().Preempt(gomock.Any(), "test", gomock.Any()).
+				Return(domain.Job{Id: 1, Name: "job", Owner: "test", Version: 2}, nil)
+			repo.EXPECT().UpdateUtime(gomock.Any(), int64(1), 2).Return(tc.refreshErr).AnyTimes()
+			// 多次调用只释放一次
+			repo.EXPECT().Release(gomock.Any(), int64(1), 2).Return(nil)
+			svc := NewCronJobServiceV1(logger.NewNopLogger(), time.Millisecond*10, repo, "test", nil)
+			j, err := svc.Preempt(context.Background())
+			require.NoError(t, err)
+			select {
+			case <-j.Lost:
+				assert.True(t, tc.wantLost)
+			case <-time.After(time.Millisecond * 100):
+				assert.False(t, tc.wantLost)
+			}
+			j.CancelFunc()
+			j.CancelFunc()
+		})
+	}
+}
@@ -0,0 +1,68 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./job_load.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./job_load.go -package=svcmocks -destination=mocks/job_load.mock.go JobLoadService
+//
+
+// Package svcmocks is a generated GoMock package.
+package svcmocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockJobLoadService is a mock of JobLoadService interface.
+type MockJobLoadService struct {
+	ctrl     *gomock.Controller
+	recorder *MockJobLoadServiceMockRecorder
+}
+
+// MockJobLoadServiceMockRecorder is the mock recorder for MockJobLoadService.
+type MockJobLoadServiceMockRecorder struct {
+	mock *MockJobLoadService
+}
+
+// NewMockJobLoadService creates a new mock instance.
+func NewMockJobLoadService(ctrl *gomock.Controller) *MockJobLoadService {
+	mock := &MockJobLoadService{ctrl: ctrl}
+	mock.recorder = &MockJobLoadServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockJobLoadService) EXPECT() *MockJobLoadServiceMockRecorder {
+	return m.recorder
+}
+
+// Rank mocks base method.
+func (m *MockJobLoadService) Rank() int {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Rank")
+	ret0, _ := ret[0].(int)
+	return ret0
+}
+
+// Rank indicates an expected call of Rank.
+func (mr *MockJobLoadServiceMockRecorder) Rank() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Rank", reflect.TypeOf((*MockJobLoadService)(nil).Rank))
+}
+
+// Report mocks base method.
+func (m *MockJobLoadService) Report(ctx context.Context, load int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Report", ctx, load)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Report indicates an expected call of Report.
+func (mr *MockJobLoadServiceMockRecorder) Report(ctx, load any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Report", reflect.TypeOf((*MockJobLoadService)(nil).Report), ctx, load)
+}
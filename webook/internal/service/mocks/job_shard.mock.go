@@ -0,0 +1,114 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./job_shard.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./job_shard.go -package=svcmocks -destination=mocks/job_shard.mock.go JobShardService
+//
+
+// Package svcmocks is a generated GoMock package.
+package svcmocks
+
+import (
+	context "context"
+	reflect "reflect"
+	domain "xiaoweishu/webook/internal/domain"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockJobShardService is a mock of JobShardService interface.
+type MockJobShardService struct {
+	ctrl     *gomock.Controller
+	recorder *MockJobShardServiceMockRecorder
+}
+
+// MockJobShardServiceMockRecorder is the mock recorder for MockJobShardService.
+type MockJobShardServiceMockRecorder struct {
+	mock *MockJobShardService
+}
+
+// NewMockJobShardService creates a new mock instance.
+func NewMockJobShardService(ctrl *gomock.Controller) *MockJobShardService {
+	mock := &MockJobShardService{ctrl: ctrl}
+	mock.recorder = &MockJobShardServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockJobShardService) EXPECT() *MockJobShardServiceMockRecorder {
+	return m.recorder
+}
+
+// Finish mocks base method.
+func (m *MockJobShardService) Finish(ctx context.Context, j domain.Job, execErr error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Finish", ctx, j, execErr)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Finish indicates an expected call of Finish.
+func (mr *MockJobShardServiceMockRecorder) Finish(ctx, j, execErr any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Finish", reflect.TypeOf((*MockJobShardService)(nil).Finish), ctx, j, execErr)
+}
+
+// Latest mocks base method.
+func (m *MockJobShardService) Latest(ctx context.Context, jid int64) ([]domain.JobShard, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Latest", ctx, jid)
+	ret0, _ := ret[0].([]domain.JobShard)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Latest indicates an expected call of Latest.
+func (mr *MockJobShardServiceMockRecorder) Latest(ctx, jid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Latest", reflect.TypeOf((*MockJobShardService)(nil).Latest), ctx, jid)
+}
+
+// Preempt mocks base method.
+func (m *MockJobShardService) Preempt(ctx context.Context) (domain.Job, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Preempt", ctx)
+	ret0, _ := ret[0].(domain.Job)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Preempt indicates an expected call of Preempt.
+func (mr *MockJobShardServiceMockRecorder) Preempt(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Preempt", reflect.TypeOf((*MockJobShardService)(nil).Preempt), ctx)
+}
+
+// Progress mocks base method.
+func (m *MockJobShardService) Progress(ctx context.Context, j domain.Job) (domain.JobShardProgress, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Progress", ctx, j)
+	ret0, _ := ret[0].(domain.JobShardProgress)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Progress indicates an expected call of Progress.
+func (mr *MockJobShardServiceMockRecorder) Progress(ctx, j any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Progress", reflect.TypeOf((*MockJobShardService)(nil).Progress), ctx, j)
+}
+
+// Split mocks base method.
+func (m *MockJobShardService) Split(ctx context.Context, j domain.Job) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Split", ctx, j)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Split indicates an expected call of Split.
+func (mr *MockJobShardServiceMockRecorder) Split(ctx, j any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Split", reflect.TypeOf((*MockJobShardService)(nil).Split), ctx, j)
+}
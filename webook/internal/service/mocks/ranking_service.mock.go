@@ -0,0 +1,130 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./ranking_service.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./ranking_service.go -package=svcmocks -destination=mocks/ranking_service.mock.go RankingService
+//
+
+// Package svcmocks is a generated GoMock package.
+package svcmocks
+
+import (
+	context "context"
+	reflect "reflect"
+	domain "xiaoweishu/webook/internal/domain"
+	service "xiaoweishu/webook/internal/service"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockRankingService is a mock of RankingService interface.
+type MockRankingService struct {
+	ctrl     *gomock.Controller
+	recorder *MockRankingServiceMockRecorder
+}
+
+// MockRankingServiceMockRecorder is the mock recorder for MockRankingService.
+type MockRankingServiceMockRecorder struct {
+	mock *MockRankingService
+}
+
+// NewMockRankingService creates a new mock instance.
+func NewMockRankingService(ctrl *gomock.Controller) *MockRankingService {
+	mock := &MockRankingService{ctrl: ctrl}
+	mock.recorder = &MockRankingServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRankingService) EXPECT() *MockRankingServiceMockRecorder {
+	return m.recorder
+}
+
+// BoardTopN mocks base method.
+func (m *MockRankingService) BoardTopN(ctx context.Context, board string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BoardTopN", ctx, board)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// BoardTopN indicates an expected call of BoardTopN.
+func (mr *MockRankingServiceMockRecorder) BoardTopN(ctx, board any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BoardTopN", reflect.TypeOf((*MockRankingService)(nil).BoardTopN), ctx, board)
+}
+
+// DryRun mocks base method.
+func (m *MockRankingService) DryRun(ctx context.Context, strategy service.RankingStrategy, n int) ([]service.RankingScore, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DryRun", ctx, strategy, n)
+	ret0, _ := ret[0].([]service.RankingScore)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DryRun indicates an expected call of DryRun.
+func (mr *MockRankingServiceMockRecorder) DryRun(ctx, strategy, n any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DryRun", reflect.TypeOf((*MockRankingService)(nil).DryRun), ctx, strategy, n)
+}
+
+// GetBoard mocks base method.
+func (m *MockRankingService) GetBoard(ctx context.Context, board string) ([]domain.Article, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBoard", ctx, board)
+	ret0, _ := ret[0].([]domain.Article)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBoard indicates an expected call of GetBoard.
+func (mr *MockRankingServiceMockRecorder) GetBoard(ctx, board any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBoard", reflect.TypeOf((*MockRankingService)(nil).GetBoard), ctx, board)
+}
+
+// GetTopN mocks base method.
+func (m *MockRankingService) GetTopN(ctx context.Context) ([]domain.Article, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTopN", ctx)
+	ret0, _ := ret[0].([]domain.Article)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTopN indicates an expected call of GetTopN.
+func (mr *MockRankingServiceMockRecorder) GetTopN(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTopN", reflect.TypeOf((*MockRankingService)(nil).GetTopN), ctx)
+}
+
+// Rank mocks base method.
+func (m *MockRankingService) Rank(ctx context.Context, arts []domain.Article, n int) ([]domain.Article, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Rank", ctx, arts, n)
+	ret0, _ := ret[0].([]domain.Article)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Rank indicates an expected call of Rank.
+func (mr *MockRankingServiceMockRecorder) Rank(ctx, arts, n any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Rank", reflect.TypeOf((*MockRankingService)(nil).Rank), ctx, arts, n)
+}
+
+// TopN mocks base method.
+func (m *MockRankingService) TopN(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TopN", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// TopN indicates an expected call of TopN.
+func (mr *MockRankingServiceMockRecorder) TopN(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TopN", reflect.TypeOf((*MockRankingService)(nil).TopN), ctx)
+}
@@ -0,0 +1,112 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./ranking_stream.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./ranking_stream.go -package=svcmocks -destination=mocks/ranking_stream.mock.go StreamRankingService
+//
+
+// Package svcmocks is a generated GoMock package.
+package svcmocks
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+	domain "xiaoweishu/webook/internal/domain"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockStreamRankingService is a mock of StreamRankingService interface.
+type MockStreamRankingService struct {
+	ctrl     *gomock.Controller
+	recorder *MockStreamRankingServiceMockRecorder
+}
+
+// MockStreamRankingServiceMockRecorder is the mock recorder for MockStreamRankingService.
+type MockStreamRankingServiceMockRecorder struct {
+	mock *MockStreamRankingService
+}
+
+// NewMockStreamRankingService creates a new mock instance.
+func NewMockStreamRankingService(ctrl *gomock.Controller) *MockStreamRankingService {
+	mock := &MockStreamRankingService{ctrl: ctrl}
+	mock.recorder = &MockStreamRankingServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockStreamRankingService) EXPECT() *MockStreamRankingServiceMockRecorder {
+	return m.recorder
+}
+
+// Correct mocks base method.
+func (m *MockStreamRankingService) Correct(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Correct", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Correct indicates an expected call of Correct.
+func (mr *MockStreamRankingServiceMockRecorder) Correct(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Correct", reflect.TypeOf((*MockStreamRankingService)(nil).Correct), ctx)
+}
+
+// Publish mocks base method.
+func (m *MockStreamRankingService) Publish(ctx context.Context, aid int64, utime time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Publish", ctx, aid, utime)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Publish indicates an expected call of Publish.
+func (mr *MockStreamRankingServiceMockRecorder) Publish(ctx, aid, utime any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Publish", reflect.TypeOf((*MockStreamRankingService)(nil).Publish), ctx, aid, utime)
+}
+
+// Record mocks base method.
+func (m *MockStreamRankingService) Record(ctx context.Context, deltas []domain.RankingDelta) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Record", ctx, deltas)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Record indicates an expected call of Record.
+func (mr *MockStreamRankingServiceMockRecorder) Record(ctx, deltas any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Record", reflect.TypeOf((*MockStreamRankingService)(nil).Record), ctx, deltas)
+}
+
+// Snapshot mocks base method.
+func (m *MockStreamRankingService) Snapshot(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Snapshot", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Snapshot indicates an expected call of Snapshot.
+func (mr *MockStreamRankingServiceMockRecorder) Snapshot(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Snapshot", reflect.TypeOf((*MockStreamRankingService)(nil).Snapshot), ctx)
+}
+
+// Withdraw mocks base method.
+func (m *MockStreamRankingService) Withdraw(ctx context.Context, aid int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Withdraw", ctx, aid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Withdraw indicates an expected call of Withdraw.
+func (mr *MockStreamRankingServiceMockRecorder) Withdraw(ctx, aid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Withdraw", reflect.TypeOf((*MockStreamRankingService)(nil).Withdraw), ctx, aid)
+}
@@ -0,0 +1,101 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./sms_message.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./sms_message.go -package=svcmocks -destination=mocks/sms_message.mock.go SmsMessageService
+//
+
+// Package svcmocks is a generated GoMock package.
+package svcmocks
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+	domain "xiaoweishu/webook/internal/domain"
+	sms "xiaoweishu/webook/internal/service/sms"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockSmsMessageService is a mock of SmsMessageService interface.
+type MockSmsMessageService struct {
+	ctrl     *gomock.Controller
+	recorder *MockSmsMessageServiceMockRecorder
+}
+
+// MockSmsMessageServiceMockRecorder is the mock recorder for MockSmsMessageService.
+type MockSmsMessageServiceMockRecorder struct {
+	mock *MockSmsMessageService
+}
+
+// NewMockSmsMessageService creates a new mock instance.
+func NewMockSmsMessageService(ctrl *gomock.Controller) *MockSmsMessageService {
+	mock := &MockSmsMessageService{ctrl: ctrl}
+	mock.recorder = &MockSmsMessageServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSmsMessageService) EXPECT() *MockSmsMessageServiceMockRecorder {
+	return m.recorder
+}
+
+// HandleReceipts mocks base method.
+func (m *MockSmsMessageService) HandleReceipts(ctx context.Context, provider string, receipts []sms.Receipt) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HandleReceipts", ctx, provider, receipts)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// HandleReceipts indicates an expected call of HandleReceipts.
+func (mr *MockSmsMessageServiceMockRecorder) HandleReceipts(ctx, provider, receipts any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandleReceipts", reflect.TypeOf((*MockSmsMessageService)(nil).HandleReceipts), ctx, provider, receipts)
+}
+
+// List mocks base method.
+func (m *MockSmsMessageService) List(ctx context.Context, q domain.SmsMessageQuery) ([]domain.SmsMessage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", ctx, q)
+	ret0, _ := ret[0].([]domain.SmsMessage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockSmsMessageServiceMockRecorder) List(ctx, q any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockSmsMessageService)(nil).List), ctx, q)
+}
+
+// Record mocks base method.
+func (m *MockSmsMessageService) Record(ctx context.Context, msgs []domain.SmsMessage) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Record", ctx, msgs)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Record indicates an expected call of Record.
+func (mr *MockSmsMessageServiceMockRecorder) Record(ctx, msgs any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Record", reflect.TypeOf((*MockSmsMessageService)(nil).Record), ctx, msgs)
+}
+
+// Stats mocks base method.
+func (m *MockSmsMessageService) Stats(ctx context.Context, start, end time.Time) ([]domain.SmsDeliveryStats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Stats", ctx, start, end)
+	ret0, _ := ret[0].([]domain.SmsDeliveryStats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Stats indicates an expected call of Stats.
+func (mr *MockSmsMessageServiceMockRecorder) Stats(ctx, start, end any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stats", reflect.TypeOf((*MockSmsMessageService)(nil).Stats), ctx, start, end)
+}
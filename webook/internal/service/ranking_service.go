@@ -5,45 +5,126 @@ import (
 	"errors"
 	"github.com/ecodeclub/ekit/queue"
 	"github.com/ecodeclub/ekit/slice"
-	"math"
+	"golang.org/x/sync/singleflight"
+	"sort"
+	"sync"
 	"time"
+	commentv1 "xiaoweishu/webook/api/proto/gen/comment/v1"
 	intrv1 "xiaoweishu/webook/api/proto/gen/intr/v1"
 	"xiaoweishu/webook/internal/domain"
 	"xiaoweishu/webook/internal/repository"
 )
 
+//go:generate mockgen -source=./ranking_service.go -package=svcmocks -destination=mocks/ranking_service.mock.go RankingService
 type RankingService interface {
 	TopN(ctx context.Context) error
 	GetTopN(ctx context.Context) ([]domain.Article, error) //这个是方便用于测试
+	// DryRun 用 strategy 算一遍前 n 名，只返回结果，不替换线上的热榜
+	DryRun(ctx context.Context, strategy RankingStrategy, n int) ([]RankingScore, error)
+	// BoardTopN 计算 board 这个榜并且替换缓存里面的
+	BoardTopN(ctx context.Context, board string) error
+	// GetBoard 缓存里面都没有的时候现场算一次
+	GetBoard(ctx context.Context, board string) ([]domain.Article, error)
+	// Rank 用线上的算法和阅读数口径给 arts 打分，返回分数最高的 n 篇，
+	// 增量热榜选出候选之后用它排出最终的顺序
+	Rank(ctx context.Context, arts []domain.Article, n int) ([]domain.Article, error)
 }
+
+var ErrUnknownRankingBoard = errors.New("没有这个榜")
+
+// defaultRankingWindow 总榜只看七天以内的文章
+const defaultRankingWindow = 7 * 24 * time.Hour
+
+// RankingScore 热榜上的文章和它的分数
+type RankingScore struct {
+	Art   domain.Article
+	Score float64
+}
+
+// ReadCntMode 计算热榜的时候用哪一种阅读数
+type ReadCntMode uint8
+
+const (
+	// ReadCntPV 原始的阅读数，每次打开详情页都算
+	ReadCntPV ReadCntMode = iota
+	// ReadCntUV 去重之后的阅读数，不容易被刷
+	ReadCntUV
+)
+
 type BatchRankingService struct {
 	//用来取点赞数
 	intrSvc intrv1.InteractiveServiceClient
+	//用来取评论数，没有的时候评论数都是 0
+	commentSvc commentv1.CommentServiceClient
 	//用来查找文章
 	artSvc    ArticleService
 	batchSize int
-	scoreFunc func(likeCnt int64, utime time.Time) float64 //计算分数的算法，utime是用于筛查数据的，七天以前的就不需要了
 	n         int
 	repo      repository.RankingRepository
+	readMode  ReadCntMode
+	boardRepo repository.RankingBoardRepository
+	boards    map[string]domain.RankingBoard
+	//同一个榜同时只现场算一次
+	group singleflight.Group
+	//计算分数的算法，配置变更的时候会被替换
+	lock     sync.RWMutex
+	strategy RankingStrategy
 }
 
 func NewBatchRankingService(intrSvc intrv1.InteractiveServiceClient,
-	artSvc ArticleService) RankingService {
+	artSvc ArticleService, readMode ReadCntMode) RankingService {
 	return &BatchRankingService{
 		intrSvc:   intrSvc,
 		artSvc:    artSvc,
 		batchSize: 100,
 		n:         100,
-		scoreFunc: func(likeCnt int64, utime time.Time) float64 {
-			// 时间
-			duration := time.Since(utime).Seconds()
-			return float64(likeCnt-1) / math.Pow(duration+2, 1.5)
+		readMode:  readMode,
+		strategy: &HackerNewsStrategy{
+			weights: rankingWeights{like: 1},
+			gravity: 1.5,
 		},
 	}
 }
 
+// NewBatchRankingServiceV1 算法可以在运行期间通过 UpdateStrategy 替换，
+// 除了总榜之外还支持 boards 这些榜
+func NewBatchRankingServiceV1(intrSvc intrv1.InteractiveServiceClient,
+	commentSvc commentv1.CommentServiceClient, artSvc ArticleService, repo repository.RankingRepository,
+	boardRepo repository.RankingBoardRepository, readMode ReadCntMode,
+	strategy RankingStrategy, boards []domain.RankingBoard) *BatchRankingService {
+	m := make(map[string]domain.RankingBoard, len(boards))
+	for _, board := range boards {
+		m[board.Name] = board
+	}
+	return &BatchRankingService{
+		intrSvc:    intrSvc,
+		commentSvc: commentSvc,
+		artSvc:     artSvc,
+		batchSize:  100,
+		n:          100,
+		repo:       repo,
+		boardRepo:  boardRepo,
+		boards:     m,
+		readMode:   readMode,
+		strategy:   strategy,
+	}
+}
+
+// UpdateStrategy 替换线上使用的算法，下一次计算热榜的时候生效
+func (b *BatchRankingService) UpdateStrategy(strategy RankingStrategy) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.strategy = strategy
+}
+
+func (b *BatchRankingService) currentStrategy() RankingStrategy {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	return b.strategy
+}
+
 func (b *BatchRankingService) TopN(ctx context.Context) error {
-	arts, err := b.topN(ctx)
+	arts, err := b.compute(ctx, domain.RankingBoard{Window: defaultRankingWindow, Size: b.n})
 	if err != nil {
 		return err
 	}
@@ -51,24 +132,106 @@ func (b *BatchRankingService) TopN(ctx context.Context) error {
 	return b.repo.ReplaceTopN(ctx, arts)
 }
 
+func (b *BatchRankingService) DryRun(ctx context.Context, strategy RankingStrategy, n int) ([]RankingScore, error) {
+	return b.topN(ctx, strategy, domain.RankingBoard{Window: defaultRankingWindow, Size: n})
+}
+
+func (b *BatchRankingService) BoardTopN(ctx context.Context, board string) error {
+	bd, ok := b.boards[board]
+	if !ok {
+		return ErrUnknownRankingBoard
+	}
+	arts, err := b.compute(ctx, bd)
+	if err != nil {
+		return err
+	}
+	return b.boardRepo.ReplaceTopN(ctx, board, arts)
+}
+
+func (b *BatchRankingService) GetBoard(ctx context.Context, board string) ([]domain.Article, error) {
+	bd, ok := b.boards[board]
+	if !ok {
+		return nil, ErrUnknownRankingBoard
+	}
+	arts, err := b.boardRepo.GetTopN(ctx, board)
+	if err == nil {
+		return arts, nil
+	}
+	//本地缓存和 redis 都没有，比如刚上线任务还没跑过，或者 redis 崩了
+	val, err, _ := b.group.Do(board, func() (interface{}, error) {
+		res, er := b.compute(ctx, bd)
+		if er != nil {
+			return nil, er
+		}
+		//这里只是兜底，写缓存失败没关系，等任务下一次计算
+		_ = b.boardRepo.ReplaceTopN(ctx, board, res)
+		return res, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return val.([]domain.Article), nil
+}
+
+func (b *BatchRankingService) compute(ctx context.Context, board domain.RankingBoard) ([]domain.Article, error) {
+	scores, err := b.topN(ctx, b.currentStrategy(), board)
+	if err != nil {
+		return nil, err
+	}
+	return slice.Map(scores, func(idx int, src RankingScore) domain.Article {
+		return src.Art
+	}), nil
+}
+
+func (b *BatchRankingService) Rank(ctx context.Context, arts []domain.Article, n int) ([]domain.Article, error) {
+	if len(arts) == 0 {
+		return nil, nil
+	}
+	ids := slice.Map(arts, func(idx int, art domain.Article) int64 {
+		return art.Id
+	})
+	intrResp, err := b.intrSvc.GetByIds(ctx, &intrv1.GetByIdsRequest{
+		Biz: "article",
+		Ids: ids,
+	})
+	if err != nil {
+		return nil, err
+	}
+	commentCnts, err := b.commentCnts(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	strategy := b.currentStrategy()
+	now := time.Now()
+	scores := slice.Map(arts, func(idx int, art domain.Article) RankingScore {
+		f := b.features(intrResp.Intrs[art.Id], commentCnts[art.Id], art)
+		return RankingScore{Art: art, Score: strategy.Score(f, now)}
+	})
+	sort.SliceStable(scores, func(i, j int) bool {
+		return scores[i].Score > scores[j].Score
+	})
+	if len(scores) > n {
+		scores = scores[:n]
+	}
+	return slice.Map(scores, func(idx int, src RankingScore) domain.Article {
+		return src.Art
+	}), nil
+}
+
 // 通过redis缓存查找热榜
 func (b *BatchRankingService) GetTopN(ctx context.Context) ([]domain.Article, error) {
 	return b.repo.GetTopN(ctx)
 }
 
-func (b *BatchRankingService) topN(ctx context.Context) ([]domain.Article, error) {
+func (b *BatchRankingService) topN(ctx context.Context, strategy RankingStrategy, board domain.RankingBoard) ([]RankingScore, error) {
 	offset := 0
 	start := time.Now()
-	ddl := start.Add(-7 * 24 * time.Hour) //七天以前的数据就不需要了
-	type Score struct {
-		score float64
-		art   domain.Article
-	}
+	ddl := start.Add(-board.Window) //窗口以前的数据就不需要了
 	//排序算法，用的小根堆
-	topN := queue.NewConcurrentPriorityQueue[Score](b.n, func(src Score, dst Score) int {
-		if src.score > dst.score {
+	topN := queue.NewConcurrentPriorityQueue[RankingScore](board.Size, func(src RankingScore, dst RankingScore) int {
+		if src.Score > dst.Score {
 			return 1
-		} else if src.score == dst.score {
+		} else if src.Score == dst.Score {
 			return 0
 		} else {
 			return -1
@@ -76,37 +239,49 @@ func (b *BatchRankingService) topN(ctx context.Context) ([]domain.Article, error
 	})
 
 	for {
-		arts, err := b.artSvc.ListPub(ctx, start, offset, b.batchSize)
+		batch, err := b.artSvc.ListPub(ctx, start, offset, b.batchSize)
 		if err != nil {
 			return nil, err
 		}
+		//窗口之外的和不满足这个榜的条件的，连交互数据都不用查
+		arts := slice.FilterMap(batch, func(idx int, art domain.Article) (domain.Article, bool) {
+			return art, !art.Utime.Before(ddl) && board.Match(art)
+		})
 		//slice.map的核心仍然转换成另一个类型的切片
 		ids := slice.Map(arts, func(idx int, art domain.Article) int64 {
 			return art.Id
 		})
 		//取点赞数
 		//要求取出来的数据是 map[art.id]domain.article
-		intrResp, err := b.intrSvc.GetByIds(ctx, &intrv1.GetByIdsRequest{
-			Biz: "article",
-			Ids: ids,
-		})
-		if err != nil {
-			return nil, err
+		var intrMap map[int64]*intrv1.Interactive
+		var commentCnts map[int64]int64
+		if len(ids) > 0 {
+			intrResp, err := b.intrSvc.GetByIds(ctx, &intrv1.GetByIdsRequest{
+				Biz: "article",
+				Ids: ids,
+			})
+			if err != nil {
+				return nil, err
+			}
+			intrMap = intrResp.Intrs
+			commentCnts, err = b.commentCnts(ctx, ids)
+			if err != nil {
+				return nil, err
+			}
 		}
-		intrMap := intrResp.Intrs
 		for _, art := range arts {
 			intr := intrMap[art.Id]
-			score := b.scoreFunc(intr.LikeCnt, art.Utime)
-			element := Score{
-				score: score,
-				art:   art,
+			score := strategy.Score(b.features(intr, commentCnts[art.Id], art), start)
+			element := RankingScore{
+				Score: score,
+				Art:   art,
 			}
 			err = topN.Enqueue(element) //新元素入栈
 			if errors.Is(err, queue.ErrOutOfCapacity) {
 				//说明此时堆已经满了
 				//此时需要让对对顶元素出去
 				minEle, _ := topN.Dequeue()
-				if minEle.score < score {
+				if minEle.Score < score {
 					_ = topN.Enqueue(element) //新元素个更大，新元素入堆
 				} else {
 					_ = topN.Enqueue(minEle) // 老元素更大，把老元素放回去
@@ -115,19 +290,51 @@ func (b *BatchRankingService) topN(ctx context.Context) ([]domain.Article, error
 			}
 		}
 		//进行下一批偏移量的计算
-		offset = offset + len(arts)
+		offset = offset + len(batch)
 		//如果这一批没有到达batchsize，说明数据已经取完了，
-		//或者这一批的最一个数据的更新时间已经大于七天，那么就不需要继续往下取了
-		if len(arts) < b.batchSize || arts[len(arts)-1].Utime.Before(ddl) {
+		//或者这一批的最一个数据的更新时间已经在窗口之外，那么就不需要继续往下取了
+		if len(batch) < b.batchSize || batch[len(batch)-1].Utime.Before(ddl) {
 			break
 		}
 	}
 	//可以用len函数，也可以直接用b.n ，因为创建topn优先队列时，就固定了容量N
-	res := make([]domain.Article, topN.Len())
-	for i := topN.Len() - 1; i >= 0; i++ {
+	res := make([]RankingScore, topN.Len())
+	for i := topN.Len() - 1; i >= 0; i-- {
 		ele, _ := topN.Dequeue()
-		res[i] = ele.art
+		res[i] = ele
 	}
 	//因为先出来的是小的元素，所以需要反转一下，最后得出的res就是最大的元素在前面，符合热榜的功能
 	return res, nil
 }
+
+func (b *BatchRankingService) features(intr *intrv1.Interactive, commentCnt int64, art domain.Article) RankingFeatures {
+	return RankingFeatures{
+		ReadCnt:    b.readCnt(intr),
+		LikeCnt:    intr.GetLikeCnt(),
+		CollectCnt: intr.GetCollectCnt(),
+		CommentCnt: commentCnt,
+		Utime:      art.Utime,
+	}
+}
+
+// commentCnts 一批文章一次查出评论数，没有评论的文章不在结果里面
+func (b *BatchRankingService) commentCnts(ctx context.Context, ids []int64) (map[int64]int64, error) {
+	if b.commentSvc == nil {
+		return nil, nil
+	}
+	resp, err := b.commentSvc.CountByBizIds(ctx, &commentv1.CountByBizIdsRequest{
+		Biz:    "article",
+		Bizids: ids,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.GetCnts(), nil
+}
+
+func (b *BatchRankingService) readCnt(intr *intrv1.Interactive) int64 {
+	if b.readMode == ReadCntUV {
+		return intr.GetUvCnt()
+	}
+	return intr.GetReadCnt()
+}
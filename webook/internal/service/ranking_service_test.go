@@ -0,0 +1,207 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+	commentv1 "xiaoweishu/webook/api/proto/gen/comment/v1"
+	commentmocks "xiaoweishu/webook/api/proto/gen/comment/v1/mocks"
+	intrv1 "xiaoweishu/webook/api/proto/gen/intr/v1"
+	intrmocks "xiaoweishu/webook/api/proto/gen/intr/v1/mocks"
+	"xiaoweishu/webook/internal/domain"
+	"xiaoweishu/webook/internal/repository"
+	repomocks "xiaoweishu/webook/internal/repository/mocks"
+	"xiaoweishu/webook/internal/service"
+	svcmocks "xiaoweishu/webook/internal/service/mocks"
+)
+
+func TestBatchRankingService_GetBoard(t *testing.T) {
+	now := time.Now()
+	arts := []domain.Article{
+		{Id: 1, Category: "go", Utime: now},
+		{Id: 2, Category: "java", Utime: now},
+		{Id: 3, Category: "go", Utime: now.Add(-time.Minute)},
+		//窗口之外的
+		{Id: 4, Category: "go", Utime: now.Add(-time.Hour * 48)},
+	}
+	boards := []domain.RankingBoard{
+		{Name: "go_daily", Window: time.Hour * 24, Size: 10, Categories: []string{"go"}},
+	}
+	testCases := []struct {
+		name  string
+		mock  func(ctrl *gomock.Controller) (intrv1.InteractiveServiceClient, service.ArticleService, repository.RankingBoardRepository)
+		board string
+
+		wantIds []int64
+		wantErr error
+	}{
+		{
+			name: "缓存命中",
+			mock: func(ctrl *gomock.Controller) (intrv1.InteractiveServiceClient, service.ArticleService, repository.RankingBoardRepository) {
+				boardRepo := repomocks.NewMockRankingBoardRepository(ctrl)
+				boardRepo.EXPECT().GetTopN(gomock.Any(), "go_daily").
+					Return([]domain.Article{{Id: 1}}, nil)
+				return intrmocks.NewMockInteractiveServiceClient(ctrl), svcmocks.NewMockArticleService(ctrl), boardRepo
+			},
+			board:   "go_daily",
+			wantIds: []int64{1},
+		},
+		{
+			name: "没有这个榜",
+			mock: func(ctrl *gomock.Controller) (intrv1.InteractiveServiceClient, service.ArticleService, repository.RankingBoardRepository) {
+				return intrmocks.NewMockInteractiveServiceClient(ctrl), svcmocks.NewMockArticleService(ctrl),
+					repomocks.NewMockRankingBoardRepository(ctrl)
+			},
+			board:   "weekly",
+			wantErr: service.ErrUnknownRankingBoard,
+		},
+		{
+			name: "缓存都没有，现场计算并且回写",
+			mock: func(ctrl *gomock.Controller) (intrv1.InteractiveServiceClient, service.ArticleService, repository.RankingBoardRepository) {
+				artSvc := svcmocks.NewMockArticleService(ctrl)
+				//不满一批，说明取完了
+				artSvc.EXPECT().ListPub(gomock.Any(), gomock.Any(), 0, 100).Return(arts, nil)
+				intrSvc := intrmocks.NewMockInteractiveServiceClient(ctrl)
+				//只要 go 分类的、一天以内的文章
+				intrSvc.EXPECT().GetByIds(gomock.Any(), &intrv1.GetByIdsRequest{
+					Biz: "article",
+					Ids: []int64{1, 3},
+				}).Return(&intrv1.GetByIdsResponse{Intrs: map[int64]*intrv1.Interactive{
+					1: {LikeCnt: 2},
+					3: {LikeCnt: 50},
+				}}, nil)
+				boardRepo := repomocks.NewMockRankingBoardRepository(ctrl)
+				boardRepo.EXPECT().GetTopN(gomock.Any(), "go_daily").Return(nil, errors.New("缓存未命中"))
+				//按照点赞数排序
+				boardRepo.EXPECT().ReplaceTopN(gomock.Any(), "go_daily", []domain.Article{arts[2], arts[0]}).Return(nil)
+				return intrSvc, artSvc, boardRepo
+			},
+			board:   "go_daily",
+			wantIds: []int64{3, 1},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			intrSvc, artSvc, boardRepo := tc.mock(ctrl)
+			svc := service.NewBatchRankingServiceV1(intrSvc, nil, artSvc,
+				nil, boardRepo, service.ReadCntPV, likesOnlyStrategy(t), boards)
+			res, err := svc.GetBoard(context.Background(), tc.board)
+			assert.True(t, errors.Is(err, tc.wantErr))
+			if err != nil {
+				return
+			}
+			ids := make([]int64, 0, len(res))
+			for _, art := range res {
+				ids = append(ids, art.Id)
+			}
+			assert.Equal(t, tc.wantIds, ids)
+		})
+	}
+}
+
+func TestBatchRankingService_Rank(t *testing.T) {
+	now := time.Now()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	intrSvc := intrmocks.NewMockInteractiveServiceClient(ctrl)
+	intrSvc.EXPECT().GetByIds(gomock.Any(), &intrv1.GetByIdsRequest{Biz: "article", Ids: []int64{1, 2, 3}}).
+		Return(&intrv1.GetByIdsResponse{Intrs: map[int64]*intrv1.Interactive{
+			//按照 UV 算，刷出来的阅读数不算
+			1: {LikeCnt: 1, ReadCnt: 1000, UvCnt: 1},
+			2: {LikeCnt: 5},
+			3: {LikeCnt: 3},
+		}}, nil)
+	svc := service.NewBatchRankingServiceV1(intrSvc, nil, nil, nil, nil, service.ReadCntUV, likesOnlyStrategy(t), nil)
+	res, err := svc.Rank(context.Background(), []domain.Article{
+		{Id: 1, Utime: now}, {Id: 2, Utime: now}, {Id: 3, Utime: now},
+	}, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []domain.Article{{Id: 2, Utime: now}, {Id: 3, Utime: now}}, res)
+}
+
+func TestBatchRankingService_RankCommentCnt(t *testing.T) {
+	now := time.Now()
+	arts := []domain.Article{{Id: 1, Utime: now}, {Id: 2, Utime: now}, {Id: 3, Utime: now}}
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) commentv1.CommentServiceClient
+
+		wantIds []int64
+		wantErr error
+	}{
+		{
+			name: "评论数参与排序",
+			mock: func(ctrl *gomock.Controller) commentv1.CommentServiceClient {
+				client := commentmocks.NewMockCommentServiceClient(ctrl)
+				client.EXPECT().CountByBizIds(gomock.Any(), &commentv1.CountByBizIdsRequest{
+					Biz:    "article",
+					Bizids: []int64{1, 2, 3},
+				}).Return(&commentv1.CountByBizIdsResponse{
+					//3 没有评论
+					Cnts: map[int64]int64{1: 10, 2: 1},
+				}, nil)
+				return client
+			},
+			wantIds: []int64{1, 2},
+		},
+		{
+			name: "查询评论数失败",
+			mock: func(ctrl *gomock.Controller) commentv1.CommentServiceClient {
+				client := commentmocks.NewMockCommentServiceClient(ctrl)
+				client.EXPECT().CountByBizIds(gomock.Any(), gomock.Any()).
+					Return(nil, errors.New("评论服务不可用"))
+				return client
+			},
+			wantErr: errors.New("评论服务不可用"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			intrSvc := intrmocks.NewMockInteractiveServiceClient(ctrl)
+			intrSvc.EXPECT().GetByIds(gomock.Any(), gomock.Any()).
+				Return(&intrv1.GetByIdsResponse{Intrs: map[int64]*intrv1.Interactive{
+					1: {LikeCnt: 1},
+					2: {LikeCnt: 5},
+					3: {LikeCnt: 3},
+				}}, nil)
+			strategy, err := service.NewRankingStrategy(service.RankingStrategyConfig{
+				Name:          service.RankingStrategyDecay,
+				LikeWeight:    1,
+				CommentWeight: 1,
+				HalfLifeHours: 24 * 365,
+			})
+			require.NoError(t, err)
+			svc := service.NewBatchRankingServiceV1(intrSvc, tc.mock(ctrl), nil, nil, nil, service.ReadCntUV, strategy, nil)
+			res, err := svc.Rank(context.Background(), arts, 2)
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			ids := make([]int64, 0, len(res))
+			for _, art := range res {
+				ids = append(ids, art.Id)
+			}
+			assert.Equal(t, tc.wantIds, ids)
+		})
+	}
+}
+
+// likesOnlyStrategy 只看点赞，方便按照点赞数判断顺序
+func likesOnlyStrategy(t *testing.T) service.RankingStrategy {
+	s, err := service.NewRankingStrategy(service.RankingStrategyConfig{
+		Name:       service.RankingStrategyDecay,
+		LikeWeight: 1,
+		//半衰期很长，排序只看点赞数
+		HalfLifeHours: 24 * 365,
+	})
+	require.NoError(t, err)
+	return s
+}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+var ErrInvalidRankingStrategy = errors.New("热榜算法的配置不合法")
+
+const (
+	RankingStrategyHackerNews = "hacker_news"
+	RankingStrategyDecay      = "decay"
+	RankingStrategyWilson     = "wilson"
+)
+
+// RankingFeatures 计算热度用到的数据
+type RankingFeatures struct {
+	ReadCnt    int64
+	LikeCnt    int64
+	CollectCnt int64
+	// CommentCnt 评论数，包括回复，从评论服务批量查出来
+	CommentCnt int64
+	Utime      time.Time
+}
+
+// RankingStrategy 热度的计算方法，分数越高越靠前
+type RankingStrategy interface {
+	Name() string
+	Score(f RankingFeatures, now time.Time) float64
+}
+
+// RankingStrategyConfig 所有算法共用一个配置，每种算法只用到其中的一部分
+type RankingStrategyConfig struct {
+	// Name hacker_news、decay 或者 wilson
+	Name          string  `yaml:"name" json:"name"`
+	ReadWeight    float64 `yaml:"readWeight" json:"readWeight"`
+	LikeWeight    float64 `yaml:"likeWeight" json:"likeWeight"`
+	CollectWeight float64 `yaml:"collectWeight" json:"collectWeight"`
+	CommentWeight float64 `yaml:"commentWeight" json:"commentWeight"`
+	// Gravity 时间的惩罚力度，越大旧的文章掉得越快，hacker_news 和 wilson 用。
+	// hacker_news 和原来的算法一样按秒计算时间，wilson 按小时
+	Gravity float64 `yaml:"gravity" json:"gravity"`
+	// HalfLifeHours 半衰期，decay 用
+	HalfLifeHours float64 `yaml:"halfLifeHours" json:"halfLifeHours"`
+	// Z 置信度对应的正态分布分位数，1.96 就是 95%，wilson 用
+	Z float64 `yaml:"z" json:"z"`
+}
+
+// DefaultRankingStrategyConfig 和原来一样，只看点赞。
+// 阅读数要配置了 readWeight 或者用 wilson 才会参与计算，这时候才用得上 ranking.readCnt
+func DefaultRankingStrategyConfig() RankingStrategyConfig {
+	return RankingStrategyConfig{
+		Name:       RankingStrategyHackerNews,
+		LikeWeight: 1,
+		Gravity:    1.5,
+	}
+}
+
+func NewRankingStrategy(cfg RankingStrategyConfig) (RankingStrategy, error) {
+	w := rankingWeights{
+		read:    cfg.ReadWeight,
+		like:    cfg.LikeWeight,
+		collect: cfg.CollectWeight,
+		comment: cfg.CommentWeight,
+	}
+	if cfg.Gravity < 0 {
+		return nil, fmt.Errorf("%w: gravity 不能是负数", ErrInvalidRankingStrategy)
+	}
+	switch cfg.Name {
+	case RankingStrategyHackerNews:
+		return &HackerNewsStrategy{weights: w, gravity: cfg.Gravity}, nil
+	case RankingStrategyDecay:
+		if cfg.HalfLifeHours <= 0 {
+			return nil, fmt.Errorf("%w: halfLifeHours 必须大于 0", ErrInvalidRankingStrategy)
+		}
+		return &DecayStrategy{weights: w, halfLife: cfg.HalfLifeHours}, nil
+	case RankingStrategyWilson:
+		if cfg.Z <= 0 {
+			return nil, fmt.Errorf("%w: z 必须大于 0", ErrInvalidRankingStrategy)
+		}
+		return &WilsonStrategy{z: cfg.Z, gravity: cfg.Gravity}, nil
+	default:
+		return nil, fmt.Errorf("%w: 未知的算法 %s", ErrInvalidRankingStrategy, cfg.Name)
+	}
+}
+
+type rankingWeights struct {
+	read    float64
+	like    float64
+	collect float64
+	comment float64
+}
+
+func (w rankingWeights) sum(f RankingFeatures) float64 {
+	return float64(f.ReadCnt)*w.read + float64(f.LikeCnt)*w.like +
+		float64(f.CollectCnt)*w.collect + float64(f.CommentCnt)*w.comment
+}
+
+// ageHours 文章更新了多久，时间不对的时候当成刚更新
+func ageHours(utime, now time.Time) float64 {
+	return math.Max(now.Sub(utime).Hours(), 0)
+}
+
+func ageSeconds(utime, now time.Time) float64 {
+	return math.Max(now.Sub(utime).Seconds(), 0)
+}
+
+// HackerNewsStrategy 加权和 / (秒数 + 2) ^ gravity，和原来的算法保持一致，
+// 点赞数先减一，去掉作者自己的点赞
+type HackerNewsStrategy struct {
+	weights rankingWeights
+	gravity float64
+}
+
+func (s *HackerNewsStrategy) Name() string {
+	return RankingStrategyHackerNews
+}
+
+func (s *HackerNewsStrategy) Score(f RankingFeatures, now time.Time) float64 {
+	f.LikeCnt--
+	return s.weights.sum(f) / math.Pow(ageSeconds(f.Utime, now)+2, s.gravity)
+}
+
+// DecayStrategy 加权和按照半衰期指数衰减，每过一个半衰期分数减半
+type DecayStrategy struct {
+	weights  rankingWeights
+	halfLife float64
+}
+
+func (s *DecayStrategy) Name() string {
+	return RankingStrategyDecay
+}
+
+func (s *DecayStrategy) Score(f RankingFeatures, now time.Time) float64 {
+	return s.weights.sum(f) * math.Exp2(-ageHours(f.Utime, now)/s.halfLife)
+}
+
+// WilsonStrategy 把点赞和收藏看成阅读之后的好评，算好评率的威尔逊区间下界，
+// 阅读数少的文章不会因为一两个赞就排到前面。gravity 大于 0 的时候再加上时间惩罚
+type WilsonStrategy struct {
+	z       float64
+	gravity float64
+}
+
+func (s *WilsonStrategy) Name() string {
+	return RankingStrategyWilson
+}
+
+func (s *WilsonStrategy) Score(f RankingFeatures, now time.Time) float64 {
+	n := float64(f.ReadCnt)
+	if n <= 0 {
+		return 0
+	}
+	//没有阅读就点赞的情况（比如从列表页点赞），好评数不能超过阅读数
+	p := math.Min(float64(f.LikeCnt+f.CollectCnt), n) / n
+	z2 := s.z * s.z
+	lower := (p + z2/(2*n) - s.z*math.Sqrt(p*(1-p)/n+z2/(4*n*n))) / (1 + z2/n)
+	if s.gravity == 0 {
+		return lower
+	}
+	return lower / math.Pow(ageHours(f.Utime, now)+2, s.gravity)
+}
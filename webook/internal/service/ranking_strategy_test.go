@@ -0,0 +1,95 @@
+package service
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestNewRankingStrategy(t *testing.T) {
+	testCases := []struct {
+		name     string
+		cfg      RankingStrategyConfig
+		wantName string
+		wantErr  error
+	}{
+		{
+			name:     "默认配置",
+			cfg:      DefaultRankingStrategyConfig(),
+			wantName: RankingStrategyHackerNews,
+		},
+		{
+			name:     "decay",
+			cfg:      RankingStrategyConfig{Name: RankingStrategyDecay, LikeWeight: 1, HalfLifeHours: 24},
+			wantName: RankingStrategyDecay,
+		},
+		{
+			name:    "decay 没有半衰期",
+			cfg:     RankingStrategyConfig{Name: RankingStrategyDecay, LikeWeight: 1},
+			wantErr: ErrInvalidRankingStrategy,
+		},
+		{
+			name:    "wilson 没有 z",
+			cfg:     RankingStrategyConfig{Name: RankingStrategyWilson},
+			wantErr: ErrInvalidRankingStrategy,
+		},
+		{
+			name:    "gravity 是负数",
+			cfg:     RankingStrategyConfig{Name: RankingStrategyHackerNews, Gravity: -1},
+			wantErr: ErrInvalidRankingStrategy,
+		},
+		{
+			name:    "未知的算法",
+			cfg:     RankingStrategyConfig{Name: "abc"},
+			wantErr: ErrInvalidRankingStrategy,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := NewRankingStrategy(tc.cfg)
+			assert.True(t, errors.Is(err, tc.wantErr))
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantName, s.Name())
+		})
+	}
+}
+
+func TestHackerNewsStrategy_Score(t *testing.T) {
+	now := time.Now()
+	utime := now.Add(-time.Hour)
+	s := &HackerNewsStrategy{weights: rankingWeights{read: 0.01, like: 1}, gravity: 1.5}
+	score := s.Score(RankingFeatures{ReadCnt: 100, LikeCnt: 11, Utime: utime}, now)
+	//和原来的算法一样：只有点赞数减一，时间按秒算
+	want := (float64(11-1) + 100*0.01) / math.Pow(3600+2, 1.5)
+	assert.InDelta(t, want, score, 1e-12)
+}
+
+func TestDecayStrategy_Score(t *testing.T) {
+	now := time.Now()
+	s, err := NewRankingStrategy(RankingStrategyConfig{
+		Name: RankingStrategyDecay, LikeWeight: 1, CollectWeight: 2, HalfLifeHours: 24,
+	})
+	require.NoError(t, err)
+	f := RankingFeatures{LikeCnt: 10, CollectCnt: 5, Utime: now}
+	assert.InDelta(t, 20, s.Score(f, now), 1e-9)
+	//过了一个半衰期，分数减半
+	assert.InDelta(t, 10, s.Score(f, now.Add(time.Hour*24)), 1e-9)
+}
+
+func TestWilsonStrategy_Score(t *testing.T) {
+	now := time.Now()
+	s, err := NewRankingStrategy(RankingStrategyConfig{Name: RankingStrategyWilson, Z: 1.96})
+	require.NoError(t, err)
+	assert.Equal(t, float64(0), s.Score(RankingFeatures{LikeCnt: 3, Utime: now}, now))
+	//好评率一样，阅读数多的更可信
+	few := s.Score(RankingFeatures{ReadCnt: 2, LikeCnt: 2, Utime: now}, now)
+	many := s.Score(RankingFeatures{ReadCnt: 200, LikeCnt: 200, Utime: now}, now)
+	assert.Less(t, few, many)
+	//点赞数超过阅读数的时候按照全部好评算，不会超过 1
+	assert.LessOrEqual(t, s.Score(RankingFeatures{ReadCnt: 10, LikeCnt: 50, Utime: now}, now), float64(1))
+}
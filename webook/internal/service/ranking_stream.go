@@ -0,0 +1,182 @@
+package service
+
+import (
+	"context"
+	"github.com/ecodeclub/ekit/slice"
+	"time"
+	intrv1 "xiaoweishu/webook/api/proto/gen/intr/v1"
+	"xiaoweishu/webook/internal/domain"
+	"xiaoweishu/webook/internal/repository"
+)
+
+//go:generate mockgen -source=./ranking_stream.go -package=svcmocks -destination=mocks/ranking_stream.mock.go StreamRankingService
+
+// StreamRankingService 增量热榜，消费交互和发表事件更新候选文章的分数，
+// 定时把前 n 名写到总榜的缓存里面，不用每次都把窗口内的文章全部扫一遍
+type StreamRankingService interface {
+	// Record 交互数据的变化，同一篇文章的会先合并
+	Record(ctx context.Context, deltas []domain.RankingDelta) error
+	// Publish 文章发表或者更新之后加入候选
+	Publish(ctx context.Context, aid int64, utime time.Time) error
+	// Withdraw 文章撤回之后从候选里面删掉
+	Withdraw(ctx context.Context, aid int64) error
+	// Snapshot 把当前的前 n 名替换到总榜
+	Snapshot(ctx context.Context) error
+	// Correct 从数据库全量重新算一遍候选文章，修正丢消息和重复消费造成的偏差
+	Correct(ctx context.Context) error
+}
+
+type streamRankingService struct {
+	streamRepo repository.RankingStreamRepository
+	repo       repository.RankingRepository
+	//查文章详情不能用 ArticleService，它会发阅读事件
+	artRepo repository.ArticleRepository
+	intrSvc intrv1.InteractiveServiceClient
+	// rankingSvc 增量分数只用来选候选，最终的顺序还是线上配置的算法说了算
+	rankingSvc RankingService
+	readMode   ReadCntMode
+	window     time.Duration
+	n          int
+	// poolSize 快照的时候按照增量分数取多少篇候选重新打分
+	poolSize int
+	//校正的时候一批查多少篇文章
+	batchSize int
+}
+
+func NewStreamRankingService(streamRepo repository.RankingStreamRepository,
+	repo repository.RankingRepository, artRepo repository.ArticleRepository,
+	intrSvc intrv1.InteractiveServiceClient, rankingSvc RankingService,
+	readMode ReadCntMode) StreamRankingService {
+	return &streamRankingService{
+		streamRepo: streamRepo,
+		repo:       repo,
+		artRepo:    artRepo,
+		intrSvc:    intrSvc,
+		rankingSvc: rankingSvc,
+		readMode:   readMode,
+		window:     defaultRankingWindow,
+		n:          100,
+		poolSize:   500,
+		batchSize:  100,
+	}
+}
+
+func (s *streamRankingService) Record(ctx context.Context, deltas []domain.RankingDelta) error {
+	merged := make(map[int64]domain.RankingDelta, len(deltas))
+	for _, d := range deltas {
+		m := merged[d.Aid]
+		m.Aid = d.Aid
+		m.ReadCnt += d.ReadCnt
+		m.LikeCnt += d.LikeCnt
+		m.CollectCnt += d.CollectCnt
+		merged[d.Aid] = m
+	}
+	res := make([]domain.RankingDelta, 0, len(merged))
+	for _, d := range merged {
+		if d.ReadCnt == 0 && d.LikeCnt == 0 && d.CollectCnt == 0 {
+			continue
+		}
+		res = append(res, d)
+	}
+	return s.streamRepo.BatchIncr(ctx, res)
+}
+
+func (s *streamRankingService) Publish(ctx context.Context, aid int64, utime time.Time) error {
+	if utime.Before(time.Now().Add(-s.window)) {
+		return nil
+	}
+	return s.streamRepo.AddCandidate(ctx, aid, utime)
+}
+
+func (s *streamRankingService) Withdraw(ctx context.Context, aid int64) error {
+	return s.streamRepo.RemoveCandidate(ctx, aid)
+}
+
+// Snapshot 增量分数用的是固定的衰减公式，和线上配置的算法不一定一致，
+// 所以只用它选出候选，再用线上的算法和阅读数口径重新排序
+func (s *streamRankingService) Snapshot(ctx context.Context) error {
+	_, err := s.streamRepo.PruneBefore(ctx, time.Now().Add(-s.window))
+	if err != nil {
+		return err
+	}
+	ids, err := s.streamRepo.Top(ctx, s.poolSize)
+	if err != nil {
+		return err
+	}
+	arts, err := s.artRepo.ListPubByIds(ctx, ids)
+	if err != nil {
+		return err
+	}
+	//撤回了但是没收到事件的文章从候选里面删掉
+	if len(arts) < len(ids) {
+		published := make(map[int64]struct{}, len(arts))
+		for _, art := range arts {
+			published[art.Id] = struct{}{}
+		}
+		for _, id := range ids {
+			if _, ok := published[id]; !ok {
+				_ = s.streamRepo.RemoveCandidate(ctx, id)
+			}
+		}
+	}
+	res, err := s.rankingSvc.Rank(ctx, arts, s.n)
+	if err != nil {
+		return err
+	}
+	//候选是空的，比如 redis 刚刚重启还没校正过，不能把线上的热榜清空
+	if len(res) == 0 {
+		return nil
+	}
+	return s.repo.ReplaceTopN(ctx, res)
+}
+
+// Correct 先用数据库里的计数覆盖候选，再走一遍原来的全量计算
+func (s *streamRankingService) Correct(ctx context.Context) error {
+	start := time.Now()
+	ddl := start.Add(-s.window)
+	offset := 0
+	for {
+		batch, err := s.artRepo.ListPub(ctx, start, offset, s.batchSize)
+		if err != nil {
+			return err
+		}
+		arts := slice.FilterMap(batch, func(idx int, art domain.Article) (domain.Article, bool) {
+			return art, !art.Utime.Before(ddl)
+		})
+		if len(arts) > 0 {
+			intrResp, err := s.intrSvc.GetByIds(ctx, &intrv1.GetByIdsRequest{
+				Biz: "article",
+				Ids: slice.Map(arts, func(idx int, art domain.Article) int64 {
+					return art.Id
+				}),
+			})
+			if err != nil {
+				return err
+			}
+			candidates := slice.Map(arts, func(idx int, art domain.Article) domain.RankingCandidate {
+				intr := intrResp.Intrs[art.Id]
+				//和全量计算用同一种阅读数
+				readCnt := intr.GetUvCnt()
+				if s.readMode == ReadCntPV {
+					readCnt = intr.GetReadCnt()
+				}
+				return domain.RankingCandidate{
+					Aid:        art.Id,
+					Utime:      art.Utime,
+					ReadCnt:    readCnt,
+					LikeCnt:    intr.GetLikeCnt(),
+					CollectCnt: intr.GetCollectCnt(),
+				}
+			})
+			err = s.streamRepo.ResetCandidates(ctx, candidates)
+			if err != nil {
+				return err
+			}
+		}
+		offset = offset + len(batch)
+		if len(batch) < s.batchSize || batch[len(batch)-1].Utime.Before(ddl) {
+			break
+		}
+	}
+	return s.rankingSvc.TopN(ctx)
+}
@@ -0,0 +1,100 @@
+package service_test
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+	intrv1 "xiaoweishu/webook/api/proto/gen/intr/v1"
+	intrmocks "xiaoweishu/webook/api/proto/gen/intr/v1/mocks"
+	"xiaoweishu/webook/internal/domain"
+	"xiaoweishu/webook/internal/repository"
+	repomocks "xiaoweishu/webook/internal/repository/mocks"
+	"xiaoweishu/webook/internal/service"
+	svcmocks "xiaoweishu/webook/internal/service/mocks"
+)
+
+func TestStreamRankingService_Snapshot(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (repository.RankingStreamRepository,
+			repository.RankingRepository, repository.ArticleRepository, service.RankingService)
+
+		wantErr error
+	}{
+		{
+			name: "按照线上的算法重新排序，撤回的文章从候选里面删掉",
+			mock: func(ctrl *gomock.Controller) (repository.RankingStreamRepository,
+				repository.RankingRepository, repository.ArticleRepository, service.RankingService) {
+				streamRepo := repomocks.NewMockRankingStreamRepository(ctrl)
+				streamRepo.EXPECT().PruneBefore(gomock.Any(), gomock.Any()).Return(0, nil)
+				streamRepo.EXPECT().Top(gomock.Any(), 500).Return([]int64{1, 2, 3}, nil)
+				streamRepo.EXPECT().RemoveCandidate(gomock.Any(), int64(2)).Return(nil)
+				arts := []domain.Article{{Id: 1}, {Id: 3}}
+				artRepo := repomocks.NewMockArticleRepository(ctrl)
+				//快照不能一篇一篇地查
+				artRepo.EXPECT().ListPubByIds(gomock.Any(), []int64{1, 2, 3}).Return(arts, nil)
+				rankingSvc := svcmocks.NewMockRankingService(ctrl)
+				//最终的顺序是线上的算法决定的
+				rankingSvc.EXPECT().Rank(gomock.Any(), arts, 100).
+					Return([]domain.Article{{Id: 3}, {Id: 1}}, nil)
+				repo := repomocks.NewMockRankingRepository(ctrl)
+				repo.EXPECT().ReplaceTopN(gomock.Any(), []domain.Article{{Id: 3}, {Id: 1}}).Return(nil)
+				return streamRepo, repo, artRepo, rankingSvc
+			},
+		},
+		{
+			name: "候选是空的，不替换线上的热榜",
+			mock: func(ctrl *gomock.Controller) (repository.RankingStreamRepository,
+				repository.RankingRepository, repository.ArticleRepository, service.RankingService) {
+				streamRepo := repomocks.NewMockRankingStreamRepository(ctrl)
+				streamRepo.EXPECT().PruneBefore(gomock.Any(), gomock.Any()).Return(0, nil)
+				streamRepo.EXPECT().Top(gomock.Any(), 500).Return(nil, nil)
+				artRepo := repomocks.NewMockArticleRepository(ctrl)
+				artRepo.EXPECT().ListPubByIds(gomock.Any(), gomock.Nil()).Return(nil, nil)
+				rankingSvc := svcmocks.NewMockRankingService(ctrl)
+				rankingSvc.EXPECT().Rank(gomock.Any(), gomock.Nil(), 100).Return(nil, nil)
+				return streamRepo, repomocks.NewMockRankingRepository(ctrl), artRepo, rankingSvc
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			streamRepo, repo, artRepo, rankingSvc := tc.mock(ctrl)
+			svc := service.NewStreamRankingService(streamRepo, repo, artRepo, nil,
+				rankingSvc, service.ReadCntUV)
+			err := svc.Snapshot(context.Background())
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
+
+func TestStreamRankingService_Correct(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	now := time.Now()
+	artRepo := repomocks.NewMockArticleRepository(ctrl)
+	//不满一批，说明取完了
+	artRepo.EXPECT().ListPub(gomock.Any(), gomock.Any(), 0, 100).
+		Return([]domain.Article{{Id: 1, Utime: now}}, nil)
+	intrSvc := intrmocks.NewMockInteractiveServiceClient(ctrl)
+	intrSvc.EXPECT().GetByIds(gomock.Any(), &intrv1.GetByIdsRequest{Biz: "article", Ids: []int64{1}}).
+		Return(&intrv1.GetByIdsResponse{Intrs: map[int64]*intrv1.Interactive{
+			1: {ReadCnt: 100, UvCnt: 10, LikeCnt: 2, CollectCnt: 1},
+		}}, nil)
+	streamRepo := repomocks.NewMockRankingStreamRepository(ctrl)
+	//和全量计算用同一种阅读数
+	streamRepo.EXPECT().ResetCandidates(gomock.Any(), []domain.RankingCandidate{
+		{Aid: 1, Utime: now, ReadCnt: 10, LikeCnt: 2, CollectCnt: 1},
+	}).Return(nil)
+	rankingSvc := svcmocks.NewMockRankingService(ctrl)
+	//校正的时候走全量计算
+	rankingSvc.EXPECT().TopN(gomock.Any()).Return(nil)
+	svc := service.NewStreamRankingService(streamRepo, repomocks.NewMockRankingRepository(ctrl),
+		artRepo, intrSvc, rankingSvc, service.ReadCntUV)
+	err := svc.Correct(context.Background())
+	assert.NoError(t, err)
+}
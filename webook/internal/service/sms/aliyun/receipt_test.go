@@ -0,0 +1,61 @@
+package aliyun
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+	"xiaoweishu/webook/internal/service/sms"
+)
+
+func TestService_ParseReceipts(t *testing.T) {
+	testCases := []struct {
+		name string
+		body string
+
+		wantReceipts []sms.Receipt
+		wantAck      any
+		wantErr      bool
+	}{
+		{
+			name: "送达和没有送达",
+			body: `[{"phone_number":"15212345678","send_time":"2024-05-01 10:00:00","report_time":"2024-05-01 10:00:05",
+"success":true,"err_code":"DELIVERED","err_msg":"用户接收成功","sms_size":"1","biz_id":"biz-1","out_id":""},
+{"phone_number":"15287654321","send_time":"2024-05-01 10:00:00","report_time":"2024-05-01 10:00:06",
+"success":false,"err_code":"MK:0001","err_msg":"用户关机","sms_size":"1","biz_id":"biz-1","out_id":""}]`,
+			wantReceipts: []sms.Receipt{
+				{BizId: "biz-1", Number: "15212345678", Delivered: true,
+					Time: time.Date(2024, 5, 1, 2, 0, 5, 0, time.UTC)},
+				{BizId: "biz-1", Number: "15287654321", Reason: "MK:0001 用户关机",
+					Time: time.Date(2024, 5, 1, 2, 0, 6, 0, time.UTC)},
+			},
+			wantAck: receiptAck{Code: 0, Msg: "成功"},
+		},
+		{
+			name:    "不是 JSON",
+			body:    `abc`,
+			wantErr: true,
+		},
+		{
+			name:    "时间格式不对",
+			body:    `[{"phone_number":"15212345678","report_time":"2024/05/01","success":true,"biz_id":"biz-1"}]`,
+			wantErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			svc := NewService(nil, "", "ak", "sk", "webook", nil)
+			receipts, ack, err := svc.ParseReceipts([]byte(tc.body))
+			assert.Equal(t, tc.wantErr, err != nil)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantAck, ack)
+			assert.Len(t, receipts, len(tc.wantReceipts))
+			for i, r := range receipts {
+				assert.True(t, tc.wantReceipts[i].Time.Equal(r.Time))
+				r.Time = tc.wantReceipts[i].Time
+				assert.Equal(t, tc.wantReceipts[i], r)
+			}
+		})
+	}
+}
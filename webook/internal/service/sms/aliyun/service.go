@@ -0,0 +1,204 @@
+// Package aliyun 阿里云短信。没有引入阿里云的 SDK，直接按照 RPC 风格的签名调用 SendSms 接口
+package aliyun
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/ecodeclub/ekit/slice"
+	"github.com/google/uuid"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+	"xiaoweishu/webook/internal/service/sms"
+)
+
+// Provider 短信模板管理里面阿里云的名字
+const Provider = "aliyun"
+
+// DefaultEndpoint 阿里云短信的接口地址
+const DefaultEndpoint = "https://dysmsapi.aliyuncs.com"
+
+type Service struct {
+	client          *http.Client
+	endpoint        string
+	accessKeyId     string
+	accessKeySecret string
+	// signName 默认的签名
+	signName string
+	resolver sms.TemplateResolver
+}
+
+// NewService endpoint 为空的时候用 DefaultEndpoint，测试的时候可以换成 mockgw 的地址
+func NewService(client *http.Client, endpoint string, accessKeyId string, accessKeySecret string,
+	signName string, resolver sms.TemplateResolver) *Service {
+	if endpoint == "" {
+		endpoint = DefaultEndpoint
+	}
+	return &Service{
+		client:          client,
+		endpoint:        endpoint,
+		accessKeyId:     accessKeyId,
+		accessKeySecret: accessKeySecret,
+		signName:        signName,
+		resolver:        resolver,
+	}
+}
+
+type sendResp struct {
+	Code      string `json:"Code"`
+	Message   string `json:"Message"`
+	BizId     string `json:"BizId"`
+	RequestId string `json:"RequestId"`
+}
+
+func (s *Service) Send(ctx context.Context, tplName string, args []string, numbers ...string) error {
+	_, err := s.SendV1(ctx, tplName, args, numbers...)
+	return err
+}
+
+// SendV1 阿里云对一批短信只返回一个 BizId，回执里面用手机号区分
+func (s *Service) SendV1(ctx context.Context, tplName string, args []string,
+	numbers ...string) ([]sms.SentMessage, error) {
+	tpl, err := s.resolver.Resolve(ctx, Provider, tplName, args)
+	if err != nil {
+		return nil, err
+	}
+	params := map[string]string{
+		"Action":           "SendSms",
+		"Version":          "2017-05-25",
+		"RegionId":         "cn-hangzhou",
+		"Format":           "JSON",
+		"AccessKeyId":      s.accessKeyId,
+		"SignatureMethod":  "HMAC-SHA1",
+		"SignatureVersion": "1.0",
+		"SignatureNonce":   uuid.New().String(),
+		"Timestamp":        time.Now().UTC().Format("2006-01-02T15:04:05Z"),
+		"PhoneNumbers":     strings.Join(numbers, ","),
+		"SignName":         s.signName,
+		"TemplateCode":     tpl.Id,
+	}
+	if tpl.SignName != "" {
+		params["SignName"] = tpl.SignName
+	}
+	if len(args) > 0 {
+		//阿里云的参数是按照名字传的
+		if len(tpl.ArgNames) != len(args) {
+			return nil, fmt.Errorf("阿里云短信模板 %s 的参数名字和参数个数对不上", tplName)
+		}
+		argMap := make(map[string]string, len(args))
+		for i, name := range tpl.ArgNames {
+			argMap[name] = args[i]
+		}
+		param, err := json.Marshal(argMap)
+		if err != nil {
+			return nil, err
+		}
+		params["TemplateParam"] = string(param)
+	}
+	query := canonicalize(params)
+	body := query + "&Signature=" + percentEncode(sign(http.MethodPost, query, s.accessKeySecret))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint+"/", strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("调用阿里云短信接口失败 %w", err)
+	}
+	defer resp.Body.Close()
+	var res sendResp
+	err = json.NewDecoder(resp.Body).Decode(&res)
+	if err != nil {
+		return nil, fmt.Errorf("阿里云短信接口返回的不是 JSON, status: %d, err: %w", resp.StatusCode, err)
+	}
+	if res.Code != "OK" {
+		return nil, fmt.Errorf("发送短信失败 code: %s, msg: %s, requestId: %s", res.Code, res.Message, res.RequestId)
+	}
+	return slice.Map(numbers, func(idx int, src string) sms.SentMessage {
+		return sms.SentMessage{Number: src, BizId: res.BizId}
+	}), nil
+}
+
+// receiptItem 阿里云推过来的短信状态报告，是一个 JSON 数组
+type receiptItem struct {
+	PhoneNumber string `json:"phone_number"`
+	SendTime    string `json:"send_time"`
+	ReportTime  string `json:"report_time"`
+	Success     bool   `json:"success"`
+	ErrCode     string `json:"err_code"`
+	ErrMsg      string `json:"err_msg"`
+	BizId       string `json:"biz_id"`
+}
+
+// receiptAck 处理成功之后返回给阿里云的，不返回这个阿里云会重新推送
+type receiptAck struct {
+	Code int    `json:"code"`
+	Msg  string `json:"msg"`
+}
+
+// receiptLocation 回执里面的时间是北京时间
+var receiptLocation = time.FixedZone("CST", 8*3600)
+
+// ParseReceipts 解析阿里云推过来的短信状态报告
+func (s *Service) ParseReceipts(body []byte) ([]sms.Receipt, any, error) {
+	var items []receiptItem
+	err := json.Unmarshal(body, &items)
+	if err != nil {
+		return nil, nil, err
+	}
+	res := make([]sms.Receipt, 0, len(items))
+	for _, item := range items {
+		t, err := time.ParseInLocation(time.DateTime, item.ReportTime, receiptLocation)
+		if err != nil {
+			return nil, nil, fmt.Errorf("阿里云回执的时间格式不对 %w", err)
+		}
+		r := sms.Receipt{
+			BizId:     item.BizId,
+			Number:    item.PhoneNumber,
+			Delivered: item.Success,
+			Time:      t,
+		}
+		if !item.Success {
+			r.Reason = item.ErrCode + " " + item.ErrMsg
+		}
+		res = append(res, r)
+	}
+	return res, receiptAck{Code: 0, Msg: "成功"}, nil
+}
+
+// sign 阿里云 RPC 风格的签名，query 是 canonicalize 之后的参数
+func sign(method string, query string, accessKeySecret string) string {
+	stringToSign := method + "&" + percentEncode("/") + "&" + percentEncode(query)
+	mac := hmac.New(sha1.New, []byte(accessKeySecret+"&"))
+	mac.Write([]byte(stringToSign))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// canonicalize 按照参数名字排序之后拼起来
+func canonicalize(params map[string]string) string {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, percentEncode(k)+"="+percentEncode(params[k]))
+	}
+	return strings.Join(pairs, "&")
+}
+
+// percentEncode 阿里云要求的编码，和 url.QueryEscape 的区别是空格、星号和波浪线
+func percentEncode(s string) string {
+	res := url.QueryEscape(s)
+	res = strings.ReplaceAll(res, "+", "%20")
+	res = strings.ReplaceAll(res, "*", "%2A")
+	return strings.ReplaceAll(res, "%7E", "~")
+}
@@ -2,6 +2,10 @@ package async
 
 import (
 	"context"
+	"errors"
+	"math/rand"
+	"strconv"
+	"sync"
 	"time"
 	"xiaoweishu/webook/internal/domain"
 	"xiaoweishu/webook/internal/repository"
@@ -9,104 +13,290 @@ import (
 	"xiaoweishu/webook/pkg/logger"
 )
 
+type Config struct {
+	// Workers 并发发送异步短信的 goroutine 数量
+	Workers int `yaml:"workers"`
+	// SendTimeout 每一次发送的超时时间
+	SendTimeout time.Duration `yaml:"sendTimeout"`
+	// PollInterval 没有待发送的短信，或者数据库出错的时候等多久再抢占
+	PollInterval time.Duration `yaml:"pollInterval"`
+
+	// WindowSize 统计最近多少个请求的响应时间和错误率
+	WindowSize int `yaml:"windowSize"`
+	// MinRequests 统计的请求少于这个数的时候不切换
+	MinRequests int `yaml:"minRequests"`
+	// LatencyThreshold 平均响应时间超过这个就转异步
+	LatencyThreshold time.Duration `yaml:"latencyThreshold"`
+	// ErrorRateThreshold 错误率超过这个就转异步
+	ErrorRateThreshold float64 `yaml:"errorRateThreshold"`
+	// AsyncDuration 转异步之后至少保持多久，然后才根据统计判断要不要退出
+	AsyncDuration time.Duration `yaml:"asyncDuration"`
+	// ProbeRate 异步期间保留多少比例的流量继续同步发送，用来判断供应商有没有恢复。
+	// 不能是 0，不然异步期间只能靠重试的结果判断有没有恢复
+	ProbeRate float64 `yaml:"probeRate"`
+
+	// RetryMax 异步短信最多发送几次
+	RetryMax int `yaml:"retryMax"`
+	// BaseBackoff 第一次失败之后等多久重试，之后每次翻倍，不超过 MaxBackoff
+	BaseBackoff time.Duration `yaml:"baseBackoff"`
+	MaxBackoff  time.Duration `yaml:"maxBackoff"`
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Workers:            4,
+		SendTimeout:        time.Second,
+		PollInterval:       time.Second,
+		WindowSize:         100,
+		MinRequests:        20,
+		LatencyThreshold:   time.Millisecond * 500,
+		ErrorRateThreshold: 0.3,
+		AsyncDuration:      time.Minute,
+		ProbeRate:          0.01,
+		RetryMax:           3,
+		BaseBackoff:        time.Second * 10,
+		MaxBackoff:         time.Minute * 10,
+	}
+}
+
 type Service struct {
 	svc sms.Service
 	// 转异步，存储发短信请求的 repository
 	repo repository.AsyncSmsRepository
+	cfg  Config
 	l    logger.LoggerV1
+
+	mu    sync.Mutex
+	stats *stats
+	// async 当前是不是异步模式
+	async bool
+	// asyncUntil 异步模式至少保持到这个时候
+	asyncUntil time.Time
+
+	// cancel 通知 worker 不要再抢占新的短信
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	// now 和 rand 测试的时候替换
+	now  func() time.Time
+	rand func() float64
 }
 
+// NewService cfg 里面没有配置的字段用 DefaultConfig 的。要调用 Start 才会发送异步短信
 func NewService(svc sms.Service,
 	repo repository.AsyncSmsRepository,
+	cfg Config,
 	l logger.LoggerV1) *Service {
-	res := &Service{
-		svc:  svc,
-		repo: repo,
-		l:    l,
+	def := DefaultConfig()
+	if cfg.Workers <= 0 {
+		cfg.Workers = def.Workers
+	}
+	if cfg.SendTimeout <= 0 {
+		cfg.SendTimeout = def.SendTimeout
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = def.PollInterval
+	}
+	if cfg.WindowSize <= 0 {
+		cfg.WindowSize = def.WindowSize
+	}
+	if cfg.MinRequests <= 0 {
+		cfg.MinRequests = def.MinRequests
+	}
+	if cfg.LatencyThreshold <= 0 {
+		cfg.LatencyThreshold = def.LatencyThreshold
+	}
+	if cfg.ErrorRateThreshold <= 0 {
+		cfg.ErrorRateThreshold = def.ErrorRateThreshold
+	}
+	if cfg.AsyncDuration <= 0 {
+		cfg.AsyncDuration = def.AsyncDuration
+	}
+	if cfg.ProbeRate <= 0 || cfg.ProbeRate > 1 {
+		cfg.ProbeRate = def.ProbeRate
+	}
+	if cfg.RetryMax <= 0 {
+		cfg.RetryMax = def.RetryMax
+	}
+	if cfg.BaseBackoff <= 0 {
+		cfg.BaseBackoff = def.BaseBackoff
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = def.MaxBackoff
+	}
+	return &Service{
+		svc:   svc,
+		repo:  repo,
+		cfg:   cfg,
+		l:     l,
+		stats: newStats(cfg.WindowSize),
+		now:   time.Now,
+		rand:  rand.Float64,
+	}
+}
+
+// Start 启动 Workers 个 goroutine 抢占并发送异步短信
+// 原理：这是最简单的抢占式调度，部署多个实例也只有一个实例能抢到同一条短信
+func (s *Service) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cancel != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	for i := 0; i < s.cfg.Workers; i++ {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.loop(ctx)
+		}()
 	}
+}
+
+// Stop 不再抢占新的短信，等正在发送的短信发完并且上报结果。
+// ctx 超时了就不等了，没发完的短信过一会会被别的实例重新抢占
+func (s *Service) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	cancel := s.cancel
+	s.mu.Unlock()
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+	done := make(chan struct{})
 	go func() {
-		res.StartAsyncCycle()
+		s.wg.Wait()
+		close(done)
 	}()
-	return res
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
-// StartAsyncCycle 异步发送消息
-// 这里我们没有设计退出机制，是因为没啥必要
-// 因为程序停止的时候，它自然就停止了
-// 原理：这是最简单的抢占式调度
-func (s *Service) StartAsyncCycle() {
-	// 这个是我为了测试而引入的，防止你在运行测试的时候，会出现偶发性的失败
-	time.Sleep(time.Second * 3)
+func (s *Service) loop(ctx context.Context) {
 	for {
-		s.AsyncSend()
+		if ctx.Err() != nil {
+			return
+		}
+		err := s.AsyncSend()
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, repository.ErrWaitingSMSNotFound) {
+			// 正常来说应该是数据库那边出了问题，
+			// 睡眠一下可以帮你规避掉短时间的网络抖动问题
+			s.l.Error("抢占异步发送短信任务失败", logger.Error(err))
+		}
+		timer := time.NewTimer(s.cfg.PollInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
 	}
 }
 
-func (s *Service) AsyncSend() {
+// AsyncSend 抢占一条异步短信并且发送。没有待发送的短信返回 repository.ErrWaitingSMSNotFound
+func (s *Service) AsyncSend() error {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 	// 抢占一个异步发送的消息，确保在非常多个实例
 	// 比如 k8s 部署了三个 pod，一个请求，只有一个实例能拿到
 	as, err := s.repo.PreemptWaitingSMS(ctx)
 	cancel()
-	switch err {
-	case nil:
-		// 执行发送
-		// 这个也可以做成配置的
-		ctx, cancel = context.WithTimeout(context.Background(), time.Second)
-		defer cancel()
-		err = s.svc.Send(ctx, as.TplId, as.Args, as.Numbers...)
-		if err != nil {
-			// 啥也不需要干
-			s.l.Error("执行异步发送短信失败",
-				logger.Error(err),
-				logger.Int64("id", as.Id))
-		}
-		res := err == nil
-		// 通知 repository 我这一次的执行结果
-		err = s.repo.ReportScheduleResult(ctx, as.Id, res)
-		if err != nil {
-			s.l.Error("执行异步发送短信成功，但是标记数据库失败",
-				logger.Error(err),
-				logger.Bool("res", res),
-				logger.Int64("id", as.Id))
-		}
-	case repository.ErrWaitingSMSNotFound:
-		// 睡一秒。这个你可以自己决定
-		time.Sleep(time.Second)
-	default:
-		// 正常来说应该是数据库那边出了问题，
-		// 但是为了尽量运行，还是要继续的
-		// 你可以稍微睡眠，也可以不睡眠
-		// 睡眠的话可以帮你规避掉短时间的网络抖动问题
-		s.l.Error("抢占异步发送短信任务失败",
-			logger.Error(err))
-		time.Sleep(time.Second)
+	if err != nil {
+		return err
 	}
+	// 发送和上报结果都不受 Stop 影响，保证已经抢占的短信能发完
+	ctx, cancel = context.WithTimeout(context.Background(), s.cfg.SendTimeout)
+	defer cancel()
+	err = s.send(ctx, as.TplId, as.Args, as.Numbers...)
+	if err != nil {
+		s.l.Error("执行异步发送短信失败",
+			logger.Error(err),
+			logger.Int64("id", as.Id),
+			logger.Int("retryCnt", as.RetryCnt))
+	}
+	res := err == nil
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	// 通知 repository 我这一次的执行结果
+	err = s.repo.ReportScheduleResult(ctx, as.Id, res, s.backoff(as.RetryCnt))
+	if err != nil {
+		s.l.Error("执行异步发送短信成功，但是标记数据库失败",
+			logger.Error(err),
+			logger.Bool("res", res),
+			logger.Int64("id", as.Id))
+	}
+	return nil
+}
+
+// backoff 第 retryCnt 次发送失败之后等多久再重试
+func (s *Service) backoff(retryCnt int) time.Duration {
+	res := s.cfg.BaseBackoff
+	for i := 1; i < retryCnt && res < s.cfg.MaxBackoff; i++ {
+		res *= 2
+	}
+	return min(res, s.cfg.MaxBackoff)
 }
 
 func (s *Service) Send(ctx context.Context, tplId string, args []string, numbers ...string) error {
 	if s.needAsync() {
 		// 需要异步发送，直接转储到数据库
-		err := s.repo.Add(ctx, domain.AsyncSms{
-			TplId:   tplId,
-			Args:    args,
-			Numbers: numbers,
-			// 设置可以重试三次
-			RetryMax: 3,
+		return s.repo.Add(ctx, domain.AsyncSms{
+			TplId:    tplId,
+			Args:     args,
+			Numbers:  numbers,
+			RetryMax: s.cfg.RetryMax,
 		})
+	}
+	return s.send(ctx, tplId, args, numbers...)
+}
+
+// send 同步和异步发送都统计响应时间和错误率
+func (s *Service) send(ctx context.Context, tplId string, args []string, numbers ...string) error {
+	start := s.now()
+	err := s.svc.Send(ctx, tplId, args, numbers...)
+	// 模板和参数不对是调用方的问题，换成异步也没用
+	if errors.Is(err, sms.ErrResolveTemplate) {
 		return err
 	}
-	return s.svc.Send(ctx, tplId, args, numbers...)
+	s.mu.Lock()
+	s.stats.add(s.now().Sub(start), err != nil)
+	s.mu.Unlock()
+	return err
 }
+
+// needAsync 同步发送的平均响应时间或者错误率超过阈值就转异步。
+// 异步至少保持 AsyncDuration，期间保留 ProbeRate 的流量同步发送，
+// 加上异步发送的结果，统计恢复了就退出异步
 func (s *Service) needAsync() bool {
-	// 这边就是你要设计的，各种判定要不要触发异步的方案
-	// 1. 基于响应时间的，平均响应时间
-	// 1.1 使用绝对阈值，比如说直接发送的时候，（连续一段时间，或者连续N个请求）响应时间超过了 500ms，然后后续请求转异步
-	// 1.2 变化趋势，比如说当前一秒钟内的所有请求的响应时间比上一秒钟增长了 X%，就转异步
-	// 2. 基于错误率：一段时间内，收到 err 的请求比率大于 X%，转异步
-
-	// 什么时候退出异步
-	// 1. 进入异步 N 分钟后
-	// 2. 保留 1% 的流量（或者更少），继续同步发送，判定响应时间/错误率
-	return true
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	st := s.stats
+	unhealthy := st.cnt >= s.cfg.MinRequests &&
+		(st.avgLatency() > s.cfg.LatencyThreshold || st.errRate() > s.cfg.ErrorRateThreshold)
+	if !s.async {
+		if !unhealthy {
+			return false
+		}
+		s.l.Warn("短信服务转异步",
+			logger.Int64("avgLatency", st.avgLatency().Milliseconds()),
+			logger.String("errRate", strconv.FormatFloat(st.errRate(), 'f', 2, 64)))
+		s.async = true
+		s.asyncUntil = now.Add(s.cfg.AsyncDuration)
+		// 重新统计，退出异步的时候只看转异步之后的情况
+		st.reset()
+		return true
+	}
+	if now.Before(s.asyncUntil) || st.cnt < s.cfg.MinRequests || unhealthy {
+		return s.rand() >= s.cfg.ProbeRate
+	}
+	s.async = false
+	s.l.Info("短信服务恢复同步发送")
+	return false
 }
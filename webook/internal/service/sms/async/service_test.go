@@ -0,0 +1,135 @@
+package async
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"sync/atomic"
+	"testing"
+	"time"
+	"xiaoweishu/webook/internal/domain"
+	"xiaoweishu/webook/internal/repository"
+	repomocks "xiaoweishu/webook/internal/repository/mocks"
+	smsmocks "xiaoweishu/webook/internal/service/sms/mocks"
+	"xiaoweishu/webook/pkg/logger"
+)
+
+// TestService_Switch 错误率太高转异步，异步期间保持一段时间，恢复之后转回同步
+func TestService_Switch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	smsSvc := smsmocks.NewMockService(ctrl)
+	repo := repomocks.NewMockAsyncSmsRepository(ctrl)
+	svc := NewService(smsSvc, repo, Config{
+		WindowSize:         4,
+		MinRequests:        4,
+		ErrorRateThreshold: 0.5,
+		AsyncDuration:      time.Minute,
+		ProbeRate:          0.1,
+		RetryMax:           5,
+	}, logger.NewNopLogger())
+	now := time.Now()
+	svc.now = func() time.Time { return now }
+	probe := 0.5
+	svc.rand = func() float64 { return probe }
+	send := func() error {
+		return svc.Send(context.Background(), "verify_code", []string{"123456"}, "15212345678")
+	}
+
+	//同步发送，失败三次成功一次，错误率 0.75
+	smsSvc.EXPECT().Send(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(errors.New("供应商出错")).Times(3)
+	smsSvc.EXPECT().Send(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+	for i := 0; i < 4; i++ {
+		_ = send()
+	}
+
+	//转异步，存到数据库里面
+	repo.EXPECT().Add(gomock.Any(), domain.AsyncSms{
+		TplId:    "verify_code",
+		Args:     []string{"123456"},
+		Numbers:  []string{"15212345678"},
+		RetryMax: 5,
+	}).Return(nil).Times(2)
+	require.NoError(t, send())
+	assert.True(t, svc.async)
+
+	//保留一部分流量同步发送，用来探测
+	probe = 0.05
+	smsSvc.EXPECT().Send(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(4)
+	for i := 0; i < 4; i++ {
+		require.NoError(t, send())
+	}
+	//还没到 AsyncDuration，继续异步
+	probe = 0.5
+	require.NoError(t, send())
+
+	//恢复同步
+	now = now.Add(time.Minute)
+	smsSvc.EXPECT().Send(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+	require.NoError(t, send())
+	assert.False(t, svc.async)
+}
+
+func TestService_Backoff(t *testing.T) {
+	svc := NewService(nil, nil, Config{
+		BaseBackoff: time.Second,
+		MaxBackoff:  time.Second * 5,
+	}, logger.NewNopLogger())
+	assert.Equal(t, time.Second, svc.backoff(1))
+	assert.Equal(t, time.Second*2, svc.backoff(2))
+	assert.Equal(t, time.Second*4, svc.backoff(3))
+	assert.Equal(t, time.Second*5, svc.backoff(4))
+	assert.Equal(t, time.Second*5, svc.backoff(100))
+}
+
+// TestNewService_ProbeRate 没有配置 ProbeRate 的时候也要保留探测的流量
+func TestNewService_ProbeRate(t *testing.T) {
+	svc := NewService(nil, nil, Config{}, logger.NewNopLogger())
+	assert.Equal(t, DefaultConfig().ProbeRate, svc.cfg.ProbeRate)
+	svc = NewService(nil, nil, Config{ProbeRate: 2}, logger.NewNopLogger())
+	assert.Equal(t, DefaultConfig().ProbeRate, svc.cfg.ProbeRate)
+	svc = NewService(nil, nil, Config{ProbeRate: 0.5}, logger.NewNopLogger())
+	assert.Equal(t, 0.5, svc.cfg.ProbeRate)
+}
+
+// TestService_Stop Stop 之后不再抢占，但是已经抢占的短信会发完并且上报结果
+func TestService_Stop(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	smsSvc := smsmocks.NewMockService(ctrl)
+	repo := repomocks.NewMockAsyncSmsRepository(ctrl)
+	svc := NewService(smsSvc, repo, Config{
+		Workers:      2,
+		PollInterval: time.Millisecond * 10,
+		BaseBackoff:  time.Second,
+	}, logger.NewNopLogger())
+
+	var preempted atomic.Int64
+	started := make(chan struct{})
+	repo.EXPECT().PreemptWaitingSMS(gomock.Any()).DoAndReturn(func(ctx context.Context) (domain.AsyncSms, error) {
+		if preempted.Add(1) == 1 {
+			return domain.AsyncSms{Id: 1, TplId: "verify_code", RetryCnt: 2}, nil
+		}
+		return domain.AsyncSms{}, repository.ErrWaitingSMSNotFound
+	}).MinTimes(1)
+	smsSvc.EXPECT().Send(gomock.Any(), "verify_code", gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, tplId string, args []string, numbers ...string) error {
+			close(started)
+			time.Sleep(time.Millisecond * 100)
+			return errors.New("供应商出错")
+		})
+	// 第二次发送失败，退避两秒
+	repo.EXPECT().ReportScheduleResult(gomock.Any(), int64(1), false, time.Second*2).Return(nil)
+
+	svc.Start()
+	<-started
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, svc.Stop(ctx))
+	cnt := preempted.Load()
+	time.Sleep(time.Millisecond * 50)
+	assert.Equal(t, cnt, preempted.Load())
+}
@@ -0,0 +1,59 @@
+package async
+
+import "time"
+
+// stats 最近 N 个请求的响应时间和错误率，不是并发安全的
+type stats struct {
+	latencies []time.Duration
+	failures  []bool
+	// idx 下一个写入的位置
+	idx int
+	// cnt 窗口里面有多少个请求，最多是窗口大小
+	cnt        int
+	failCnt    int
+	latencySum time.Duration
+}
+
+func newStats(size int) *stats {
+	return &stats{
+		latencies: make([]time.Duration, size),
+		failures:  make([]bool, size),
+	}
+}
+
+func (s *stats) add(latency time.Duration, failed bool) {
+	if s.cnt == len(s.latencies) {
+		// 满了，把最老的挤出去
+		s.latencySum -= s.latencies[s.idx]
+		if s.failures[s.idx] {
+			s.failCnt--
+		}
+	} else {
+		s.cnt++
+	}
+	s.latencies[s.idx] = latency
+	s.failures[s.idx] = failed
+	s.latencySum += latency
+	if failed {
+		s.failCnt++
+	}
+	s.idx = (s.idx + 1) % len(s.latencies)
+}
+
+func (s *stats) avgLatency() time.Duration {
+	if s.cnt == 0 {
+		return 0
+	}
+	return s.latencySum / time.Duration(s.cnt)
+}
+
+func (s *stats) errRate() float64 {
+	if s.cnt == 0 {
+		return 0
+	}
+	return float64(s.failCnt) / float64(s.cnt)
+}
+
+func (s *stats) reset() {
+	s.idx, s.cnt, s.failCnt, s.latencySum = 0, 0, 0, 0
+}
@@ -25,6 +25,7 @@ func (s *SMSService) Send(ctx context.Context, tplToken string, args []string, n
 
 type SMSClaims struct {
 	jwt.RegisteredClaims
+	// Tpl 逻辑上的模板名字
 	Tpl string
 	// 额外加字段
 }
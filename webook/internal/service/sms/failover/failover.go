@@ -44,10 +44,10 @@ func (f *FailOverSMSService) SendV1(ctx context.Context, tplId string, args []st
 		// 取余数来计算下标
 		svc := f.svcs[i%length]
 		err := svc.Send(ctx, tplId, args, numbers...)
-		switch err {
-		case nil:
+		switch {
+		case err == nil:
 			return nil
-		case context.Canceled, context.DeadlineExceeded:
+		case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
 			// 前者是被取消，后者是超时
 			return err
 		}
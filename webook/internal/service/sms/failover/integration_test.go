@@ -0,0 +1,165 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"net/http"
+	"testing"
+	"time"
+	"xiaoweishu/webook/internal/service/sms"
+	"xiaoweishu/webook/internal/service/sms/aliyun"
+	"xiaoweishu/webook/internal/service/sms/mockgw"
+	smsmocks "xiaoweishu/webook/internal/service/sms/mocks"
+	"xiaoweishu/webook/internal/service/sms/webhook"
+)
+
+// 下面的测试用 mockgw 模拟供应商，走真的 HTTP 请求，不需要访问外网
+
+func newResolver(ctrl *gomock.Controller) sms.TemplateResolver {
+	resolver := smsmocks.NewMockTemplateResolver(ctrl)
+	resolver.EXPECT().Resolve(gomock.Any(), gomock.Any(), "verify_code", gomock.Any()).
+		DoAndReturn(func(ctx context.Context, provider string, name string, args []string) (sms.Template, error) {
+			return sms.Template{Id: provider + "-tpl", ArgNames: []string{"code"}}, nil
+		}).AnyTimes()
+	return resolver
+}
+
+func newWebhook(ctrl *gomock.Controller, provider string, gw *mockgw.Server) sms.Service {
+	return webhook.NewService(http.DefaultClient, provider, gw.URL()+"/send", "", newResolver(ctrl))
+}
+
+func TestFailOverSMSService_Integration(t *testing.T) {
+	testCases := []struct {
+		name string
+		// before 注入错误，返回供应商
+		before func(ctrl *gomock.Controller, gw0, gw1 *mockgw.Server) []sms.Service
+		after  func(t *testing.T, gw0, gw1 *mockgw.Server)
+
+		wantErr bool
+	}{
+		{
+			name: "第一个供应商发送成功",
+			before: func(ctrl *gomock.Controller, gw0, gw1 *mockgw.Server) []sms.Service {
+				return []sms.Service{newWebhook(ctrl, "gw0", gw0), newWebhook(ctrl, "gw1", gw1)}
+			},
+			after: func(t *testing.T, gw0, gw1 *mockgw.Server) {
+				msgs := gw0.Messages()
+				require.Len(t, msgs, 1)
+				assert.Equal(t, "gw0-tpl", msgs[0].TplId)
+				assert.Equal(t, []string{"123456"}, msgs[0].Args)
+				assert.Equal(t, []string{"15212345678"}, msgs[0].Numbers)
+				assert.Equal(t, 0, gw1.Requests())
+			},
+		},
+		{
+			name: "第一个供应商出错，切换到第二个",
+			before: func(ctrl *gomock.Controller, gw0, gw1 *mockgw.Server) []sms.Service {
+				gw0.FailNext(1)
+				return []sms.Service{newWebhook(ctrl, "gw0", gw0), newWebhook(ctrl, "gw1", gw1)}
+			},
+			after: func(t *testing.T, gw0, gw1 *mockgw.Server) {
+				assert.Equal(t, 1, gw0.Requests())
+				assert.Len(t, gw0.Messages(), 0)
+				msgs := gw1.Messages()
+				require.Len(t, msgs, 1)
+				assert.Equal(t, "gw1-tpl", msgs[0].TplId)
+			},
+		},
+		{
+			name: "阿里云出错，切换到 webhook",
+			before: func(ctrl *gomock.Controller, gw0, gw1 *mockgw.Server) []sms.Service {
+				gw0.FailNext(1)
+				return []sms.Service{
+					aliyun.NewService(http.DefaultClient, gw0.URL(), "ak", "sk", "webook", newResolver(ctrl)),
+					newWebhook(ctrl, "gw1", gw1),
+				}
+			},
+			after: func(t *testing.T, gw0, gw1 *mockgw.Server) {
+				assert.Equal(t, 1, gw0.Requests())
+				assert.Len(t, gw1.Messages(), 1)
+			},
+		},
+		{
+			name: "阿里云发送成功",
+			before: func(ctrl *gomock.Controller, gw0, gw1 *mockgw.Server) []sms.Service {
+				return []sms.Service{
+					aliyun.NewService(http.DefaultClient, gw0.URL(), "ak", "sk", "webook", newResolver(ctrl)),
+					newWebhook(ctrl, "gw1", gw1),
+				}
+			},
+			after: func(t *testing.T, gw0, gw1 *mockgw.Server) {
+				msgs := gw0.Messages()
+				require.Len(t, msgs, 1)
+				assert.Equal(t, mockgw.ProtocolAliyun, msgs[0].Protocol)
+				assert.Equal(t, "aliyun-tpl", msgs[0].TplId)
+				assert.Equal(t, "webook", msgs[0].SignName)
+				assert.Equal(t, map[string]string{"code": "123456"}, msgs[0].ArgMap)
+				assert.Equal(t, []string{"15212345678"}, msgs[0].Numbers)
+				assert.Equal(t, 0, gw1.Requests())
+			},
+		},
+		{
+			name: "全部供应商都出错",
+			before: func(ctrl *gomock.Controller, gw0, gw1 *mockgw.Server) []sms.Service {
+				gw0.FailNext(1)
+				gw1.SetErrorRate(1)
+				return []sms.Service{newWebhook(ctrl, "gw0", gw0), newWebhook(ctrl, "gw1", gw1)}
+			},
+			after: func(t *testing.T, gw0, gw1 *mockgw.Server) {
+				assert.Equal(t, 1, gw0.Requests())
+				assert.Equal(t, 1, gw1.Requests())
+				assert.Len(t, gw1.Messages(), 0)
+			},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			gw0, gw1 := mockgw.NewServer(), mockgw.NewServer()
+			defer gw0.Close()
+			defer gw1.Close()
+			svc := NewFailOverSMSService(tc.before(ctrl, gw0, gw1))
+			err := svc.Send(context.Background(), "verify_code", []string{"123456"}, "15212345678")
+			assert.Equal(t, tc.wantErr, err != nil)
+			tc.after(t, gw0, gw1)
+		})
+	}
+}
+
+func TestTimeoutFailoverSMSService_Integration(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	gw0, gw1 := mockgw.NewServer(), mockgw.NewServer()
+	defer gw0.Close()
+	defer gw1.Close()
+	//第一个供应商很慢，每次都超时
+	gw0.SetLatency(time.Second)
+	svc := NewTimeoutFailoverSMSService([]sms.Service{
+		newWebhook(ctrl, "gw0", gw0),
+		newWebhook(ctrl, "gw1", gw1),
+	}, 2)
+	send := func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+		defer cancel()
+		return svc.Send(ctx, "verify_code", []string{"123456"}, "15212345678")
+	}
+	//连续超时两次，还没有切换
+	for i := 0; i < 2; i++ {
+		err := send()
+		assert.True(t, errors.Is(err, context.DeadlineExceeded), "第 %d 次应该超时 %v", i, err)
+	}
+	assert.Equal(t, int32(0), svc.idx)
+	assert.Equal(t, int32(2), svc.cnt)
+	//达到阈值，切换到第二个供应商
+	assert.NoError(t, send())
+	assert.Equal(t, int32(1), svc.idx)
+	assert.Equal(t, int32(0), svc.cnt)
+	assert.Equal(t, 2, gw0.Requests())
+	assert.Len(t, gw0.Messages(), 0)
+	assert.Len(t, gw1.Messages(), 1)
+}
@@ -2,6 +2,7 @@ package failover
 
 import (
 	"context"
+	"errors"
 	"sync/atomic"
 	"xiaoweishu/webook/internal/service/sms"
 )
@@ -37,12 +38,13 @@ func (t *TimeoutFailoverSMSService) Send(ctx context.Context, tplId string, args
 	}
 	svc := t.svcs[idx]
 	err := svc.Send(ctx, tplId, args, numbers...)
-	switch err {
-	case nil:
+	switch {
+	case err == nil:
 		// 连续超时，所以不超时的时候要重置到 0
 		atomic.StoreInt32(&t.cnt, 0)
 		return nil
-	case context.DeadlineExceeded:
+	case errors.Is(err, context.DeadlineExceeded):
+		// 供应商一般会把超时包装一下再返回
 		atomic.AddInt32(&t.cnt, 1)
 	default:
 		// 遇到了错误，但是又不是超时错误，这个时候，你要考虑怎么搞
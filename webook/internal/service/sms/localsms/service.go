@@ -2,17 +2,63 @@ package localsms
 
 import (
 	"context"
+	"github.com/google/uuid"
 	"log"
+	"sync"
+	"time"
+	"xiaoweishu/webook/internal/service/sms"
 )
 
+// Provider 短信模板管理里面本地开发用的名字
+const Provider = "local"
+
+// maxPendingReceipts 没有人来拉回执的时候最多保留多少条，多了就丢掉最早的
+const maxPendingReceipts = 1000
+
+// Service 本地开发用的，不真的发短信，只打印出来。
+// 每一条都当作送达了，回执在 PullReceipts 的时候返回
 type Service struct {
+	resolver sms.TemplateResolver
+
+	mu       sync.Mutex
+	receipts []sms.Receipt
+}
+
+func NewService(resolver sms.TemplateResolver) *Service {
+	return &Service{resolver: resolver}
+}
+
+func (s *Service) Send(ctx context.Context, tplName string, args []string, numbers ...string) error {
+	_, err := s.SendV1(ctx, tplName, args, numbers...)
+	return err
 }
 
-func NewService() *Service {
-	return &Service{}
+func (s *Service) SendV1(ctx context.Context, tplName string, args []string,
+	numbers ...string) ([]sms.SentMessage, error) {
+	tpl, err := s.resolver.Resolve(ctx, Provider, tplName, args)
+	if err != nil {
+		return nil, err
+	}
+	log.Println("模板是", tpl.Id, "验证码是", args)
+	res := make([]sms.SentMessage, 0, len(numbers))
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, n := range numbers {
+		bizId := uuid.New().String()
+		res = append(res, sms.SentMessage{Number: n, BizId: bizId})
+		s.receipts = append(s.receipts, sms.Receipt{BizId: bizId, Number: n, Delivered: true, Time: now})
+	}
+	if len(s.receipts) > maxPendingReceipts {
+		s.receipts = s.receipts[len(s.receipts)-maxPendingReceipts:]
+	}
+	return res, nil
 }
 
-func (s *Service) Send(ctx context.Context, tplId string, args []string, numbers ...string) error {
-	log.Println("验证码是", args)
-	return nil
+func (s *Service) PullReceipts(ctx context.Context) ([]sms.Receipt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := s.receipts
+	s.receipts = nil
+	return res, nil
 }
@@ -0,0 +1,292 @@
+// Package mockgw 本地的短信网关，在进程内起一个 HTTP 服务，记录收到的短信，可以注入延迟和错误。
+// webhook 协议发送成功的短信会生成回执，GET /receipts 拉取。
+// 测试 failover 这些装饰器的时候不用访问外网，本地开发也可以用它代替真的供应商
+package mockgw
+
+import (
+	"context"
+	"encoding/json"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"xiaoweishu/webook/internal/service/sms/webhook"
+)
+
+const (
+	// ProtocolWebhook webhook 供应商的协议，POST JSON 到 /send
+	ProtocolWebhook = "webhook"
+	// ProtocolAliyun 阿里云的 SendSms 接口
+	ProtocolAliyun = "aliyun"
+)
+
+// Message 网关收到并且发送成功的短信
+type Message struct {
+	Protocol string
+	BizId    string
+	TplId    string
+	SignName string
+	// Args webhook 协议按照顺序传的参数
+	Args []string
+	// ArgMap 阿里云按照名字传的参数
+	ArgMap  map[string]string
+	Numbers []string
+	Time    time.Time
+}
+
+type Server struct {
+	srv *httptest.Server
+
+	mu       sync.Mutex
+	messages []Message
+	token    string
+	latency  time.Duration
+	errRate  float64
+	failNext int
+	// undeliveredRate 生成回执的时候有多大的概率是没有送达
+	undeliveredRate float64
+	// receipts 还没有被拉走的回执
+	receipts []webhook.ReceiptItem
+	// requests 包括失败的请求
+	requests atomic.Int64
+	seq      atomic.Int64
+}
+
+// NewServer 启动之后就可以接收请求，用完了要 Close
+func NewServer() *Server {
+	s := &Server{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/send", s.handleWebhook)
+	mux.HandleFunc("/receipts", s.handleReceipts)
+	mux.HandleFunc("/", s.handleAliyun)
+	s.srv = httptest.NewServer(mux)
+	return s
+}
+
+// URL webhook 协议的地址是 URL() + "/send"，拉回执的地址是 URL() + "/receipts"，阿里云的 endpoint 是 URL()
+func (s *Server) URL() string {
+	return s.srv.URL
+}
+
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// SetToken 设置之后 webhook 协议要在 Authorization 里面带上 token
+func (s *Server) SetToken(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.token = token
+}
+
+// SetLatency 每个请求都先等这么久再处理，客户端超时了就直接返回
+func (s *Server) SetLatency(latency time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latency = latency
+}
+
+// SetErrorRate 按照 rate 的概率返回错误，0 到 1 之间
+func (s *Server) SetErrorRate(rate float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errRate = rate
+}
+
+// FailNext 接下来的 n 个请求都返回错误
+func (s *Server) FailNext(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failNext = n
+}
+
+// SetUndeliveredRate 按照 rate 的概率生成没有送达的回执，0 到 1 之间
+func (s *Server) SetUndeliveredRate(rate float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.undeliveredRate = rate
+}
+
+// Messages 发送成功的短信，按照收到的顺序
+func (s *Server) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := make([]Message, len(s.messages))
+	copy(res, s.messages)
+	return res
+}
+
+// Requests 收到了多少个请求，包括注入了错误的
+func (s *Server) Requests() int {
+	return int(s.requests.Load())
+}
+
+// Reset 清空记录的短信和注入的延迟、错误
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = nil
+	s.latency = 0
+	s.errRate = 0
+	s.failNext = 0
+	s.undeliveredRate = 0
+	s.receipts = nil
+	s.requests.Store(0)
+}
+
+// prepare 等待注入的延迟，返回这次要不要失败，客户端已经断开了的时候返回 ctx 的错误
+func (s *Server) prepare(ctx context.Context) (bool, error) {
+	s.requests.Add(1)
+	s.mu.Lock()
+	latency := s.latency
+	fail := s.failNext > 0
+	if fail {
+		s.failNext--
+	} else if s.errRate > 0 {
+		fail = rand.Float64() < s.errRate
+	}
+	s.mu.Unlock()
+	if latency > 0 {
+		select {
+		case <-time.After(latency):
+		case <-ctx.Done():
+			return false, ctx.Err()
+		}
+	}
+	return fail, nil
+}
+
+func (s *Server) record(msg Message) string {
+	msg.BizId = strconv.FormatInt(s.seq.Add(1), 10)
+	msg.Time = time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = append(s.messages, msg)
+	if msg.Protocol == ProtocolWebhook {
+		for _, n := range msg.Numbers {
+			item := webhook.ReceiptItem{
+				BizId:  msg.BizId,
+				Number: n,
+				Status: webhook.ReceiptDelivered,
+				Time:   msg.Time.UnixMilli(),
+			}
+			if s.undeliveredRate > 0 && rand.Float64() < s.undeliveredRate {
+				item.Status = webhook.ReceiptFailed
+				item.Reason = "注入的未送达"
+			}
+			s.receipts = append(s.receipts, item)
+		}
+	}
+	return msg.BizId
+}
+
+// handleReceipts 返回还没有被拉走的回执
+func (s *Server) handleReceipts(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	if s.token != "" && r.Header.Get("Authorization") != s.token {
+		s.mu.Unlock()
+		s.writeJSON(w, http.StatusUnauthorized, webhook.ReceiptResp{Code: "Unauthorized", Msg: "token 不对"})
+		return
+	}
+	receipts := s.receipts
+	s.receipts = nil
+	s.mu.Unlock()
+	s.writeJSON(w, http.StatusOK, webhook.ReceiptResp{Code: webhook.CodeOK, Msg: "OK", Receipts: receipts})
+}
+
+func (s *Server) handleWebhook(w http.ResponseWriter, r *http.Request) {
+	fail, err := s.prepare(r.Context())
+	if err != nil {
+		return
+	}
+	s.mu.Lock()
+	token := s.token
+	s.mu.Unlock()
+	if token != "" && r.Header.Get("Authorization") != token {
+		s.writeJSON(w, http.StatusUnauthorized, webhook.Resp{Code: "Unauthorized", Msg: "token 不对"})
+		return
+	}
+	var req webhook.Req
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		s.writeJSON(w, http.StatusBadRequest, webhook.Resp{Code: "BadRequest", Msg: err.Error()})
+		return
+	}
+	if fail {
+		s.writeJSON(w, http.StatusInternalServerError, webhook.Resp{Code: "MockError", Msg: "注入的错误"})
+		return
+	}
+	bizId := s.record(Message{
+		Protocol: ProtocolWebhook,
+		TplId:    req.TplId,
+		SignName: req.SignName,
+		Args:     req.Args,
+		Numbers:  req.Numbers,
+	})
+	s.writeJSON(w, http.StatusOK, webhook.Resp{Code: webhook.CodeOK, Msg: "OK", BizId: bizId})
+}
+
+type aliyunResp struct {
+	Code      string `json:"Code"`
+	Message   string `json:"Message"`
+	BizId     string `json:"BizId,omitempty"`
+	RequestId string `json:"RequestId"`
+}
+
+// handleAliyun 只模拟 SendSms，不校验签名
+func (s *Server) handleAliyun(w http.ResponseWriter, r *http.Request) {
+	fail, err := s.prepare(r.Context())
+	if err != nil {
+		return
+	}
+	err = r.ParseForm()
+	if err != nil {
+		s.writeJSON(w, http.StatusBadRequest, aliyunResp{Code: "InvalidParameter", Message: err.Error()})
+		return
+	}
+	reqId := strconv.FormatInt(time.Now().UnixNano(), 10)
+	if r.Form.Get("Action") != "SendSms" {
+		s.writeJSON(w, http.StatusNotFound, aliyunResp{Code: "InvalidAction.NotFound",
+			Message: "只支持 SendSms", RequestId: reqId})
+		return
+	}
+	if fail {
+		s.writeJSON(w, http.StatusOK, aliyunResp{Code: "isv.MOCK_ERROR",
+			Message: "注入的错误", RequestId: reqId})
+		return
+	}
+	var args map[string]string
+	if param := r.Form.Get("TemplateParam"); param != "" {
+		err = json.Unmarshal([]byte(param), &args)
+		if err != nil {
+			s.writeJSON(w, http.StatusOK, aliyunResp{Code: "isv.INVALID_JSON_PARAM",
+				Message: err.Error(), RequestId: reqId})
+			return
+		}
+	}
+	bizId := s.record(Message{
+		Protocol: ProtocolAliyun,
+		TplId:    r.Form.Get("TemplateCode"),
+		SignName: r.Form.Get("SignName"),
+		ArgMap:   args,
+		Numbers:  splitNumbers(r.Form.Get("PhoneNumbers")),
+	})
+	s.writeJSON(w, http.StatusOK, aliyunResp{Code: "OK", Message: "OK", BizId: bizId, RequestId: reqId})
+}
+
+func (s *Server) writeJSON(w http.ResponseWriter, status int, val any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(val)
+}
+
+func splitNumbers(numbers string) []string {
+	if numbers == "" {
+		return nil
+	}
+	return strings.Split(numbers, ",")
+}
@@ -3,14 +3,17 @@
 //
 // Generated by this command:
 //
-//	mockgen -source=./types.go -package=smsmocks -destination=./mocks/sms.mock.go Service
+//	mockgen -source=./types.go -package=smsmocks -destination=./mocks/sms.mock.go Service TemplateResolver TrackableService ReceiptParser ReceiptVerifier ReceiptPuller
 //
+
 // Package smsmocks is a generated GoMock package.
 package smsmocks
 
 import (
 	context "context"
+	http "net/http"
 	reflect "reflect"
+	sms "xiaoweishu/webook/internal/service/sms"
 
 	gomock "go.uber.org/mock/gomock"
 )
@@ -56,3 +59,217 @@ func (mr *MockServiceMockRecorder) Send(ctx, tplId, args any, numbers ...any) *g
 	varargs := append([]any{ctx, tplId, args}, numbers...)
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Send", reflect.TypeOf((*MockService)(nil).Send), varargs...)
 }
+
+// MockTemplateResolver is a mock of TemplateResolver interface.
+type MockTemplateResolver struct {
+	ctrl     *gomock.Controller
+	recorder *MockTemplateResolverMockRecorder
+}
+
+// MockTemplateResolverMockRecorder is the mock recorder for MockTemplateResolver.
+type MockTemplateResolverMockRecorder struct {
+	mock *MockTemplateResolver
+}
+
+// NewMockTemplateResolver creates a new mock instance.
+func NewMockTemplateResolver(ctrl *gomock.Controller) *MockTemplateResolver {
+	mock := &MockTemplateResolver{ctrl: ctrl}
+	mock.recorder = &MockTemplateResolverMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTemplateResolver) EXPECT() *MockTemplateResolverMockRecorder {
+	return m.recorder
+}
+
+// Resolve mocks base method.
+func (m *MockTemplateResolver) Resolve(ctx context.Context, provider, name string, args []string) (sms.Template, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Resolve", ctx, provider, name, args)
+	ret0, _ := ret[0].(sms.Template)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Resolve indicates an expected call of Resolve.
+func (mr *MockTemplateResolverMockRecorder) Resolve(ctx, provider, name, args any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Resolve", reflect.TypeOf((*MockTemplateResolver)(nil).Resolve), ctx, provider, name, args)
+}
+
+// MockTrackableService is a mock of TrackableService interface.
+type MockTrackableService struct {
+	ctrl     *gomock.Controller
+	recorder *MockTrackableServiceMockRecorder
+}
+
+// MockTrackableServiceMockRecorder is the mock recorder for MockTrackableService.
+type MockTrackableServiceMockRecorder struct {
+	mock *MockTrackableService
+}
+
+// NewMockTrackableService creates a new mock instance.
+func NewMockTrackableService(ctrl *gomock.Controller) *MockTrackableService {
+	mock := &MockTrackableService{ctrl: ctrl}
+	mock.recorder = &MockTrackableServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTrackableService) EXPECT() *MockTrackableServiceMockRecorder {
+	return m.recorder
+}
+
+// Send mocks base method.
+func (m *MockTrackableService) Send(ctx context.Context, tplId string, args []string, numbers ...string) error {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx, tplId, args}
+	for _, a := range numbers {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Send", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Send indicates an expected call of Send.
+func (mr *MockTrackableServiceMockRecorder) Send(ctx, tplId, args any, numbers ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx, tplId, args}, numbers...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Send", reflect.TypeOf((*MockTrackableService)(nil).Send), varargs...)
+}
+
+// SendV1 mocks base method.
+func (m *MockTrackableService) SendV1(ctx context.Context, tplId string, args []string, numbers ...string) ([]sms.SentMessage, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx, tplId, args}
+	for _, a := range numbers {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "SendV1", varargs...)
+	ret0, _ := ret[0].([]sms.SentMessage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SendV1 indicates an expected call of SendV1.
+func (mr *MockTrackableServiceMockRecorder) SendV1(ctx, tplId, args any, numbers ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx, tplId, args}, numbers...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendV1", reflect.TypeOf((*MockTrackableService)(nil).SendV1), varargs...)
+}
+
+// MockReceiptParser is a mock of ReceiptParser interface.
+type MockReceiptParser struct {
+	ctrl     *gomock.Controller
+	recorder *MockReceiptParserMockRecorder
+}
+
+// MockReceiptParserMockRecorder is the mock recorder for MockReceiptParser.
+type MockReceiptParserMockRecorder struct {
+	mock *MockReceiptParser
+}
+
+// NewMockReceiptParser creates a new mock instance.
+func NewMockReceiptParser(ctrl *gomock.Controller) *MockReceiptParser {
+	mock := &MockReceiptParser{ctrl: ctrl}
+	mock.recorder = &MockReceiptParserMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockReceiptParser) EXPECT() *MockReceiptParserMockRecorder {
+	return m.recorder
+}
+
+// ParseReceipts mocks base method.
+func (m *MockReceiptParser) ParseReceipts(body []byte) ([]sms.Receipt, any, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ParseReceipts", body)
+	ret0, _ := ret[0].([]sms.Receipt)
+	ret1, _ := ret[1].(any)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ParseReceipts indicates an expected call of ParseReceipts.
+func (mr *MockReceiptParserMockRecorder) ParseReceipts(body any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ParseReceipts", reflect.TypeOf((*MockReceiptParser)(nil).ParseReceipts), body)
+}
+
+// MockReceiptVerifier is a mock of ReceiptVerifier interface.
+type MockReceiptVerifier struct {
+	ctrl     *gomock.Controller
+	recorder *MockReceiptVerifierMockRecorder
+}
+
+// MockReceiptVerifierMockRecorder is the mock recorder for MockReceiptVerifier.
+type MockReceiptVerifierMockRecorder struct {
+	mock *MockReceiptVerifier
+}
+
+// NewMockReceiptVerifier creates a new mock instance.
+func NewMockReceiptVerifier(ctrl *gomock.Controller) *MockReceiptVerifier {
+	mock := &MockReceiptVerifier{ctrl: ctrl}
+	mock.recorder = &MockReceiptVerifierMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockReceiptVerifier) EXPECT() *MockReceiptVerifierMockRecorder {
+	return m.recorder
+}
+
+// VerifyReceipts mocks base method.
+func (m *MockReceiptVerifier) VerifyReceipts(header http.Header, body []byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyReceipts", header, body)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// VerifyReceipts indicates an expected call of VerifyReceipts.
+func (mr *MockReceiptVerifierMockRecorder) VerifyReceipts(header, body any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyReceipts", reflect.TypeOf((*MockReceiptVerifier)(nil).VerifyReceipts), header, body)
+}
+
+// MockReceiptPuller is a mock of ReceiptPuller interface.
+type MockReceiptPuller struct {
+	ctrl     *gomock.Controller
+	recorder *MockReceiptPullerMockRecorder
+}
+
+// MockReceiptPullerMockRecorder is the mock recorder for MockReceiptPuller.
+type MockReceiptPullerMockRecorder struct {
+	mock *MockReceiptPuller
+}
+
+// NewMockReceiptPuller creates a new mock instance.
+func NewMockReceiptPuller(ctrl *gomock.Controller) *MockReceiptPuller {
+	mock := &MockReceiptPuller{ctrl: ctrl}
+	mock.recorder = &MockReceiptPullerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockReceiptPuller) EXPECT() *MockReceiptPullerMockRecorder {
+	return m.recorder
+}
+
+// PullReceipts mocks base method.
+func (m *MockReceiptPuller) PullReceipts(ctx context.Context) ([]sms.Receipt, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PullReceipts", ctx)
+	ret0, _ := ret[0].([]sms.Receipt)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PullReceipts indicates an expected call of PullReceipts.
+func (mr *MockReceiptPullerMockRecorder) PullReceipts(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PullReceipts", reflect.TypeOf((*MockReceiptPuller)(nil).PullReceipts), ctx)
+}
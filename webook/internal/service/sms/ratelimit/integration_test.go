@@ -0,0 +1,73 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"net/http"
+	"testing"
+	"xiaoweishu/webook/internal/service/sms"
+	"xiaoweishu/webook/internal/service/sms/mockgw"
+	smsmocks "xiaoweishu/webook/internal/service/sms/mocks"
+	"xiaoweishu/webook/internal/service/sms/webhook"
+	"xiaoweishu/webook/pkg/limiter"
+	limitermocks "xiaoweishu/webook/pkg/limiter/mocks"
+)
+
+// TestRateLimitSMSService_Integration 用 mockgw 模拟供应商，被限流的短信不会发到供应商那里
+func TestRateLimitSMSService_Integration(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) limiter.Limiter
+
+		wantErr  error
+		wantMsgs int
+	}{
+		{
+			name: "没有限流",
+			mock: func(ctrl *gomock.Controller) limiter.Limiter {
+				l := limitermocks.NewMockLimiter(ctrl)
+				l.EXPECT().Limit(gomock.Any(), "sms-limiter").Return(false, nil)
+				return l
+			},
+			wantMsgs: 1,
+		},
+		{
+			name: "触发限流",
+			mock: func(ctrl *gomock.Controller) limiter.Limiter {
+				l := limitermocks.NewMockLimiter(ctrl)
+				l.EXPECT().Limit(gomock.Any(), "sms-limiter").Return(true, nil)
+				return l
+			},
+			wantErr: errLimited,
+		},
+		{
+			name: "限流器出错",
+			mock: func(ctrl *gomock.Controller) limiter.Limiter {
+				l := limitermocks.NewMockLimiter(ctrl)
+				l.EXPECT().Limit(gomock.Any(), "sms-limiter").Return(false, errors.New("redis 崩了"))
+				return l
+			},
+			wantErr: errors.New("redis 崩了"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			gw := mockgw.NewServer()
+			defer gw.Close()
+			resolver := smsmocks.NewMockTemplateResolver(ctrl)
+			resolver.EXPECT().Resolve(gomock.Any(), "gw", "verify_code", gomock.Any()).
+				Return(sms.Template{Id: "gw-tpl"}, nil).AnyTimes()
+			svc := NewRateLimitSMSService(
+				webhook.NewService(http.DefaultClient, "gw", gw.URL()+"/send", "", resolver),
+				tc.mock(ctrl))
+			err := svc.Send(context.Background(), "verify_code", []string{"123456"}, "15212345678")
+			assert.Equal(t, tc.wantErr, err)
+			assert.Len(t, gw.Messages(), tc.wantMsgs)
+			assert.Equal(t, tc.wantMsgs, gw.Requests())
+		})
+	}
+}
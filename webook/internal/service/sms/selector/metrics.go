@@ -0,0 +1,49 @@
+package selector
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// Collector 采集的时候才去算各个供应商的状况，不用每发一条短信更新一次
+type Collector struct {
+	svc       *Service
+	state     *prometheus.Desc
+	requests  *prometheus.Desc
+	errorRate *prometheus.Desc
+	latency   *prometheus.Desc
+	score     *prometheus.Desc
+}
+
+// NewCollector 要自己注册到 prometheus
+func NewCollector(svc *Service, namespace string, subsystem string) *Collector {
+	labels := []string{"provider"}
+	return &Collector{
+		svc: svc,
+		state: prometheus.NewDesc(prometheus.BuildFQName(namespace, subsystem, "sms_provider_circuit_state"),
+			"短信供应商熔断器的状态，0 正常，1 半开，2 熔断", labels, nil),
+		requests: prometheus.NewDesc(prometheus.BuildFQName(namespace, subsystem, "sms_provider_window_requests"),
+			"短信供应商滑动窗口里面的请求数", labels, nil),
+		errorRate: prometheus.NewDesc(prometheus.BuildFQName(namespace, subsystem, "sms_provider_error_rate"),
+			"短信供应商滑动窗口里面的错误率", labels, nil),
+		latency: prometheus.NewDesc(prometheus.BuildFQName(namespace, subsystem, "sms_provider_latency_seconds"),
+			"短信供应商滑动窗口里面的平均延迟", labels, nil),
+		score: prometheus.NewDesc(prometheus.BuildFQName(namespace, subsystem, "sms_provider_score"),
+			"短信供应商的路由分，越高越优先", labels, nil),
+	}
+}
+
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.state
+	ch <- c.requests
+	ch <- c.errorRate
+	ch <- c.latency
+	ch <- c.score
+}
+
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	for _, st := range c.svc.States() {
+		ch <- prometheus.MustNewConstMetric(c.state, prometheus.GaugeValue, float64(st.Circuit), st.Name)
+		ch <- prometheus.MustNewConstMetric(c.requests, prometheus.GaugeValue, float64(st.Requests), st.Name)
+		ch <- prometheus.MustNewConstMetric(c.errorRate, prometheus.GaugeValue, st.ErrorRate, st.Name)
+		ch <- prometheus.MustNewConstMetric(c.latency, prometheus.GaugeValue, st.AvgLatency.Seconds(), st.Name)
+		ch <- prometheus.MustNewConstMetric(c.score, prometheus.GaugeValue, st.Score, st.Name)
+	}
+}
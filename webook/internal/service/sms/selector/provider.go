@@ -0,0 +1,202 @@
+package selector
+
+import (
+	"sync"
+	"time"
+	"xiaoweishu/webook/internal/service/sms"
+)
+
+// Provider 参与路由的一个供应商
+type Provider struct {
+	// Name 供应商的名字，用在监控和管理后台上
+	Name string
+	Svc  sms.Service
+	// Cost 发一条短信的相对成本，只和别的供应商比较，单位无所谓
+	Cost float64
+}
+
+type CircuitState int32
+
+const (
+	// CircuitClosed 正常状态
+	CircuitClosed CircuitState = iota
+	// CircuitHalfOpen 熔断一段时间之后，放少量的请求过去探测有没有恢复
+	CircuitHalfOpen
+	// CircuitOpen 熔断了，不会再把请求发给这个供应商
+	CircuitOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitHalfOpen:
+		return "half-open"
+	case CircuitOpen:
+		return "open"
+	default:
+		return "unknown"
+	}
+}
+
+// provider 一个供应商的滑动窗口和熔断器
+type provider struct {
+	Provider
+	cfg *Config
+
+	mu       sync.Mutex
+	window   *window
+	state    CircuitState
+	openedAt time.Time
+	// probing 半开状态下还没有返回的探测请求
+	probing int
+	// probeOK 半开状态下成功的探测请求
+	probeOK int
+}
+
+func newProvider(p Provider, cfg *Config) *provider {
+	return &provider{
+		Provider: p,
+		cfg:      cfg,
+		window:   newWindow(cfg.Window, cfg.Buckets),
+	}
+}
+
+// allow 能不能把请求发给这个供应商，probe 表示这是半开状态下的探测请求
+func (p *provider) allow(now time.Time) (ok bool, probe bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.advance(now)
+	switch p.state {
+	case CircuitClosed:
+		return true, false
+	case CircuitHalfOpen:
+		if p.probing+p.probeOK >= p.cfg.HalfOpenProbes {
+			return false, false
+		}
+		p.probing++
+		return true, true
+	default:
+		return false, false
+	}
+}
+
+// record 记录一次请求的结果，返回熔断器的状态有没有变化
+func (p *provider) record(now time.Time, probe bool, latency time.Duration, failed bool) (CircuitState, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.window.add(now, latency, failed)
+	old := p.state
+	switch {
+	case probe && p.state == CircuitHalfOpen:
+		p.probing--
+		if failed {
+			p.open(now)
+			break
+		}
+		p.probeOK++
+		if p.probeOK >= p.cfg.HalfOpenProbes {
+			//恢复了，之前的失败不再算进去
+			p.state = CircuitClosed
+			p.window.reset()
+		}
+	case p.state == CircuitClosed && failed:
+		st := p.window.stats(now)
+		if st.total >= p.cfg.MinRequests && st.errRate >= p.cfg.ErrorRate {
+			p.open(now)
+		}
+	}
+	return p.state, p.state != old
+}
+
+// release 请求没有发出去，或者失败了不怪供应商，不计入统计
+func (p *provider) release(probe bool) {
+	if !probe {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.state == CircuitHalfOpen {
+		p.probing--
+	}
+}
+
+// advance 熔断够了 OpenDuration 就进入半开状态，调用方要持有锁
+func (p *provider) advance(now time.Time) {
+	if p.state == CircuitOpen && now.Sub(p.openedAt) >= p.cfg.OpenDuration {
+		p.state = CircuitHalfOpen
+		p.probing, p.probeOK = 0, 0
+	}
+}
+
+// probeable 半开状态下还能不能再放探测请求过去，调用方要持有锁
+func (p *provider) probeable() bool {
+	return p.state == CircuitHalfOpen && p.probing+p.probeOK < p.cfg.HalfOpenProbes
+}
+
+func (p *provider) open(now time.Time) {
+	p.state = CircuitOpen
+	p.openedAt = now
+	p.probing, p.probeOK = 0, 0
+}
+
+// reset 管理后台手动恢复
+func (p *provider) reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.state = CircuitClosed
+	//还没返回的探测请求回来的时候已经不是半开状态了，按照普通的请求统计
+	p.probing, p.probeOK = 0, 0
+	p.window.reset()
+}
+
+// ProviderState 一个供应商当前的状况
+type ProviderState struct {
+	Name       string
+	Circuit    CircuitState
+	Requests   int
+	Failures   int
+	ErrorRate  float64
+	AvgLatency time.Duration
+	Cost       float64
+	// Health 只看错误率和延迟的健康分，0 到 1
+	Health float64
+	// Score 算上成本之后的路由分，越高越优先
+	Score float64
+	// OpenedAt 最近一次熔断的时间
+	OpenedAt time.Time
+	// Probeable 半开状态下还能放探测请求过去
+	Probeable bool
+}
+
+// snapshot 算健康分，maxCost 是所有供应商里面最高的成本
+func (p *provider) snapshot(now time.Time, maxCost float64) ProviderState {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.advance(now)
+	st := p.window.stats(now)
+	res := ProviderState{
+		Name:       p.Name,
+		Circuit:    p.state,
+		Requests:   st.total,
+		Failures:   st.failures,
+		ErrorRate:  st.errRate,
+		AvgLatency: st.avgLatency,
+		Cost:       p.Cost,
+		Health:     1,
+		OpenedAt:   p.openedAt,
+		Probeable:  p.probeable(),
+	}
+	//请求太少的时候统计不准，当作是健康的，这样新接入或者刚恢复的供应商也能分到流量。
+	//半开状态下窗口里面是熔断之前的请求，不能说明现在的健康状况，要等探测的结果
+	if p.state == CircuitClosed && st.total >= p.cfg.MinRequests {
+		//平均延迟等于 SlowLatency 的时候延迟分是 0.5
+		latencyScore := float64(p.cfg.SlowLatency) / float64(p.cfg.SlowLatency+st.avgLatency)
+		res.Health = (1 - st.errRate) * latencyScore
+	}
+	res.Score = res.Health
+	if maxCost > 0 {
+		res.Score -= p.cfg.CostFactor * p.Cost / maxCost
+	}
+	return res
+}
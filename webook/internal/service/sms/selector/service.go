@@ -0,0 +1,227 @@
+// Package selector 按照供应商的健康状况和成本挑选短信供应商。
+// 每个供应商用滑动窗口统计错误率和延迟，错误率太高就熔断，熔断一段时间之后半开探测
+package selector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+	"xiaoweishu/webook/internal/service/sms"
+	"xiaoweishu/webook/pkg/logger"
+)
+
+var (
+	ErrNoAvailableProvider = errors.New("没有可用的短信供应商")
+	ErrUnknownProvider     = errors.New("未知的短信供应商")
+	ErrInvalidConfig       = errors.New("短信供应商路由的配置不合法")
+)
+
+type Config struct {
+	// Window 统计错误率和延迟的时间窗口
+	Window time.Duration `yaml:"window"`
+	// Buckets 窗口切成多少个桶，越多越平滑
+	Buckets int `yaml:"buckets"`
+	// MinRequests 窗口里面的请求少于这个数的时候不熔断
+	MinRequests int `yaml:"minRequests"`
+	// ErrorRate 错误率达到这个就熔断
+	ErrorRate float64 `yaml:"errorRate"`
+	// SlowLatency 平均延迟达到这个的时候健康分减半
+	SlowLatency time.Duration `yaml:"slowLatency"`
+	// OpenDuration 熔断多久之后进入半开状态
+	OpenDuration time.Duration `yaml:"openDuration"`
+	// HalfOpenProbes 半开状态下放过去的探测请求，全部成功就恢复
+	HalfOpenProbes int `yaml:"halfOpenProbes"`
+	// CostFactor 成本在路由分里面的权重，0 就是不考虑成本
+	CostFactor float64 `yaml:"costFactor"`
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Window:         time.Second * 30,
+		Buckets:        10,
+		MinRequests:    10,
+		ErrorRate:      0.5,
+		SlowLatency:    time.Second,
+		OpenDuration:   time.Second * 30,
+		HalfOpenProbes: 3,
+		CostFactor:     0.2,
+	}
+}
+
+type Service struct {
+	providers []*provider
+	cfg       Config
+	l         logger.LoggerV1
+	// now 测试的时候替换
+	now func() time.Time
+}
+
+// withDefaults 没有配置的字段用 DefaultConfig 的
+func (c Config) withDefaults() Config {
+	def := DefaultConfig()
+	if c.Window <= 0 {
+		c.Window = def.Window
+	}
+	if c.Buckets <= 0 {
+		c.Buckets = def.Buckets
+	}
+	if c.MinRequests <= 0 {
+		c.MinRequests = def.MinRequests
+	}
+	if c.ErrorRate <= 0 {
+		c.ErrorRate = def.ErrorRate
+	}
+	if c.SlowLatency <= 0 {
+		c.SlowLatency = def.SlowLatency
+	}
+	if c.OpenDuration <= 0 {
+		c.OpenDuration = def.OpenDuration
+	}
+	if c.HalfOpenProbes <= 0 {
+		c.HalfOpenProbes = def.HalfOpenProbes
+	}
+	return c
+}
+
+// Validate 没有配置的字段按照默认值校验。
+// OpenDuration 不能比 Window 短，不然半开的时候窗口里面还有熔断之前的失败，
+// 探测成功之前又会被这些失败算成不健康
+func (c Config) Validate() error {
+	c = c.withDefaults()
+	if c.ErrorRate > 1 {
+		return fmt.Errorf("%w: errorRate 不能超过 1", ErrInvalidConfig)
+	}
+	if c.CostFactor < 0 {
+		return fmt.Errorf("%w: costFactor 不能是负数", ErrInvalidConfig)
+	}
+	if c.Window/time.Duration(c.Buckets) <= 0 {
+		return fmt.Errorf("%w: window 太短，不够切成 %d 个桶", ErrInvalidConfig, c.Buckets)
+	}
+	if c.OpenDuration < c.Window {
+		return fmt.Errorf("%w: openDuration %s 不能比 window %s 短", ErrInvalidConfig, c.OpenDuration, c.Window)
+	}
+	return nil
+}
+
+// NewService cfg 里面没有配置的字段用 DefaultConfig 的，cfg 要先用 Validate 校验
+func NewService(providers []Provider, cfg Config, l logger.LoggerV1) *Service {
+	cfg = cfg.withDefaults()
+	s := &Service{
+		cfg: cfg,
+		l:   l,
+		now: time.Now,
+	}
+	s.providers = make([]*provider, 0, len(providers))
+	for _, p := range providers {
+		s.providers = append(s.providers, newProvider(p, &s.cfg))
+	}
+	return s
+}
+
+// Send 按照路由分从高到低尝试，跳过熔断了的供应商
+func (s *Service) Send(ctx context.Context, tplId string, args []string, numbers ...string) error {
+	var lastErr error = ErrNoAvailableProvider
+	for _, p := range s.ranked() {
+		ok, probe := p.allow(s.now())
+		if !ok {
+			continue
+		}
+		start := s.now()
+		err := p.Svc.Send(ctx, tplId, args, numbers...)
+		switch {
+		case err == nil:
+			s.record(p, start, probe, false)
+			return nil
+		case errors.Is(err, sms.ErrTemplateUnavailable):
+			//这个供应商的模板还没审核，换一个
+			p.release(probe)
+		case errors.Is(err, sms.ErrResolveTemplate), errors.Is(err, context.Canceled):
+			//换供应商也没用，也不能怪供应商
+			p.release(probe)
+			return err
+		default:
+			s.record(p, start, probe, true)
+			s.l.Warn("短信供应商发送失败", logger.String("provider", p.Name), logger.Error(err))
+		}
+		lastErr = err
+		if ctx.Err() != nil {
+			//超时了，没有时间再试下一个
+			return err
+		}
+	}
+	return lastErr
+}
+
+func (s *Service) record(p *provider, start time.Time, probe bool, failed bool) {
+	now := s.now()
+	state, changed := p.record(now, probe, now.Sub(start), failed)
+	if !changed {
+		return
+	}
+	if state == CircuitOpen {
+		s.l.Warn("短信供应商熔断", logger.String("provider", p.Name))
+	} else {
+		s.l.Info("短信供应商恢复", logger.String("provider", p.Name),
+			logger.String("state", state.String()))
+	}
+}
+
+// ranked 还能探测的半开供应商排在最前面，不然有别的供应商可用的时候它永远恢复不了，
+// 探测失败了会换下一个。然后是没有熔断的按照路由分从高到低，熔断了的排在最后。
+// 分数一样的保持配置的顺序
+func (s *Service) ranked() []*provider {
+	states := s.States()
+	res := make([]*provider, len(s.providers))
+	copy(res, s.providers)
+	byProvider := make(map[*provider]ProviderState, len(res))
+	for i, p := range s.providers {
+		byProvider[p] = states[i]
+	}
+	group := func(st ProviderState) int {
+		switch {
+		case st.Probeable:
+			return 0
+		case st.Circuit == CircuitClosed:
+			return 1
+		default:
+			return 2
+		}
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		si, sj := byProvider[res[i]], byProvider[res[j]]
+		gi, gj := group(si), group(sj)
+		if gi != gj {
+			return gi < gj
+		}
+		return si.Score > sj.Score
+	})
+	return res
+}
+
+// States 所有供应商当前的状况，顺序和配置的一样
+func (s *Service) States() []ProviderState {
+	now := s.now()
+	var maxCost float64
+	for _, p := range s.providers {
+		maxCost = max(maxCost, p.Cost)
+	}
+	res := make([]ProviderState, 0, len(s.providers))
+	for _, p := range s.providers {
+		res = append(res, p.snapshot(now, maxCost))
+	}
+	return res
+}
+
+// Reset 手动关闭熔断器并且清空统计，供应商修好了不想等半开探测的时候用
+func (s *Service) Reset(name string) error {
+	for _, p := range s.providers {
+		if p.Name == name {
+			p.reset()
+			s.l.Info("手动恢复短信供应商", logger.String("provider", name))
+			return nil
+		}
+	}
+	return fmt.Errorf("%w %s", ErrUnknownProvider, name)
+}
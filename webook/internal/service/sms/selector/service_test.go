@@ -0,0 +1,245 @@
+package selector
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+	"xiaoweishu/webook/internal/service/sms"
+	smsmocks "xiaoweishu/webook/internal/service/sms/mocks"
+	"xiaoweishu/webook/pkg/logger"
+)
+
+func TestService_Send(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) []Provider
+
+		wantErr error
+	}{
+		{
+			name: "成本低的优先",
+			mock: func(ctrl *gomock.Controller) []Provider {
+				expensive := smsmocks.NewMockService(ctrl)
+				cheap := smsmocks.NewMockService(ctrl)
+				cheap.EXPECT().Send(gomock.Any(), "verify_code", gomock.Any(), gomock.Any()).Return(nil)
+				return []Provider{{Name: "expensive", Svc: expensive, Cost: 10}, {Name: "cheap", Svc: cheap, Cost: 5}}
+			},
+		},
+		{
+			name: "模板没审核，换下一个",
+			mock: func(ctrl *gomock.Controller) []Provider {
+				p0 := smsmocks.NewMockService(ctrl)
+				p0.EXPECT().Send(gomock.Any(), "verify_code", gomock.Any(), gomock.Any()).
+					Return(sms.ErrTemplateUnavailable)
+				p1 := smsmocks.NewMockService(ctrl)
+				p1.EXPECT().Send(gomock.Any(), "verify_code", gomock.Any(), gomock.Any()).Return(nil)
+				return []Provider{{Name: "p0", Svc: p0}, {Name: "p1", Svc: p1}}
+			},
+		},
+		{
+			name: "模板解析失败，不换供应商",
+			mock: func(ctrl *gomock.Controller) []Provider {
+				p0 := smsmocks.NewMockService(ctrl)
+				p0.EXPECT().Send(gomock.Any(), "verify_code", gomock.Any(), gomock.Any()).
+					Return(sms.ErrResolveTemplate)
+				p1 := smsmocks.NewMockService(ctrl)
+				return []Provider{{Name: "p0", Svc: p0}, {Name: "p1", Svc: p1}}
+			},
+			wantErr: sms.ErrResolveTemplate,
+		},
+		{
+			name: "全部失败",
+			mock: func(ctrl *gomock.Controller) []Provider {
+				p0 := smsmocks.NewMockService(ctrl)
+				p0.EXPECT().Send(gomock.Any(), "verify_code", gomock.Any(), gomock.Any()).
+					Return(errors.New("p0 出错"))
+				p1 := smsmocks.NewMockService(ctrl)
+				p1.EXPECT().Send(gomock.Any(), "verify_code", gomock.Any(), gomock.Any()).
+					Return(errors.New("p1 出错"))
+				return []Provider{{Name: "p0", Svc: p0}, {Name: "p1", Svc: p1}}
+			},
+			wantErr: errors.New("p1 出错"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := NewService(tc.mock(ctrl), Config{CostFactor: 0.2}, logger.NewNopLogger())
+			err := svc.Send(context.Background(), "verify_code", []string{"123456"}, "15212345678")
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
+
+// TestService_Circuit 错误率太高熔断，过了 OpenDuration 半开探测，探测成功恢复
+func TestService_Circuit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	p0 := smsmocks.NewMockService(ctrl)
+	p1 := smsmocks.NewMockService(ctrl)
+	svc := NewService([]Provider{{Name: "p0", Svc: p0}, {Name: "p1", Svc: p1}}, Config{
+		MinRequests:    4,
+		ErrorRate:      0.5,
+		OpenDuration:   time.Minute,
+		HalfOpenProbes: 2,
+	}, logger.NewNopLogger())
+	now := time.Now()
+	svc.now = func() time.Time { return now }
+	send := func() error {
+		return svc.Send(context.Background(), "verify_code", []string{"123456"}, "15212345678")
+	}
+
+	//p0 连续失败四次，每次都换到 p1
+	p0.EXPECT().Send(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(errors.New("p0 出错")).Times(4)
+	p1.EXPECT().Send(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(4)
+	for i := 0; i < 4; i++ {
+		require.NoError(t, send())
+	}
+	states := svc.States()
+	assert.Equal(t, CircuitOpen, states[0].Circuit)
+	assert.Equal(t, 4, states[0].Failures)
+	assert.Equal(t, CircuitClosed, states[1].Circuit)
+
+	//熔断期间 p1 出错了也不会发给 p0
+	p1.EXPECT().Send(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(errors.New("p1 出错"))
+	assert.Equal(t, errors.New("p1 出错"), send())
+
+	//半开之后放两个探测请求给 p0，都成功了就恢复
+	now = now.Add(time.Minute)
+	p0.EXPECT().Send(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(2)
+	for i := 0; i < 2; i++ {
+		require.NoError(t, send())
+	}
+	states = svc.States()
+	assert.Equal(t, CircuitClosed, states[0].Circuit)
+	//恢复之后清空了统计
+	assert.Equal(t, 0, states[0].Requests)
+
+	//手动恢复
+	require.NoError(t, svc.Reset("p1"))
+	assert.ErrorIs(t, svc.Reset("p2"), ErrUnknownProvider)
+}
+
+// TestService_HalfOpenRank 半开的供应商不按照熔断之前的统计排序，先放探测请求过去
+func TestService_HalfOpenRank(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	p0 := smsmocks.NewMockService(ctrl)
+	p1 := smsmocks.NewMockService(ctrl)
+	svc := NewService([]Provider{{Name: "p0", Svc: p0, Cost: 10}, {Name: "p1", Svc: p1, Cost: 1}}, Config{
+		MinRequests:    2,
+		OpenDuration:   time.Minute,
+		HalfOpenProbes: 1,
+		CostFactor:     0.2,
+	}, logger.NewNopLogger())
+	now := time.Now()
+	svc.now = func() time.Time { return now }
+	send := func() error {
+		return svc.Send(context.Background(), "verify_code", []string{"123456"}, "15212345678")
+	}
+
+	//p1 便宜，但是失败到熔断了
+	p1.EXPECT().Send(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(errors.New("p1 出错")).Times(2)
+	p0.EXPECT().Send(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(2)
+	for i := 0; i < 2; i++ {
+		require.NoError(t, send())
+	}
+	assert.Equal(t, CircuitOpen, svc.States()[1].Circuit)
+
+	//半开之后 p1 先探测，探测失败了换 p0，再次熔断
+	now = now.Add(time.Minute)
+	states := svc.States()
+	assert.Equal(t, CircuitHalfOpen, states[1].Circuit)
+	assert.True(t, states[1].Probeable)
+	assert.Equal(t, float64(1), states[1].Health)
+	gomock.InOrder(
+		p1.EXPECT().Send(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(errors.New("p1 出错")),
+		p0.EXPECT().Send(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil),
+	)
+	require.NoError(t, send())
+	assert.Equal(t, CircuitOpen, svc.States()[1].Circuit)
+
+	//再次半开，探测成功就恢复了，之后按照路由分 p1 便宜优先
+	now = now.Add(time.Minute)
+	p1.EXPECT().Send(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(2)
+	for i := 0; i < 2; i++ {
+		require.NoError(t, send())
+	}
+	assert.Equal(t, CircuitClosed, svc.States()[1].Circuit)
+}
+
+func TestProvider_Reset(t *testing.T) {
+	cfg := DefaultConfig()
+	p := newProvider(Provider{Name: "p0"}, &cfg)
+	now := time.Now()
+	p.open(now)
+	ok, probe := p.allow(now.Add(cfg.OpenDuration))
+	require.True(t, ok)
+	require.True(t, probe)
+
+	p.reset()
+	assert.Equal(t, 0, p.probing)
+	assert.Equal(t, 0, p.probeOK)
+	st := p.snapshot(now.Add(cfg.OpenDuration), 0)
+	assert.Equal(t, CircuitClosed, st.Circuit)
+	assert.False(t, st.Probeable)
+	//重置之前发出去的探测请求回来了，按照普通的请求统计
+	state, changed := p.record(now.Add(cfg.OpenDuration), probe, time.Millisecond, false)
+	assert.Equal(t, CircuitClosed, state)
+	assert.False(t, changed)
+	assert.Equal(t, 0, p.probing)
+}
+
+func TestConfig_Validate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		cfg     Config
+		wantErr error
+	}{
+		{
+			name: "默认配置",
+			cfg:  DefaultConfig(),
+		},
+		{
+			name: "没有配置的用默认值",
+			cfg:  Config{},
+		},
+		{
+			name:    "熔断时间比窗口短",
+			cfg:     Config{Window: time.Minute, OpenDuration: time.Second * 30},
+			wantErr: ErrInvalidConfig,
+		},
+		{
+			name:    "只配置了窗口，比默认的熔断时间长",
+			cfg:     Config{Window: time.Minute},
+			wantErr: ErrInvalidConfig,
+		},
+		{
+			name:    "错误率超过 1",
+			cfg:     Config{ErrorRate: 1.5},
+			wantErr: ErrInvalidConfig,
+		},
+		{
+			name:    "成本权重是负数",
+			cfg:     Config{CostFactor: -1},
+			wantErr: ErrInvalidConfig,
+		},
+		{
+			name:    "窗口不够切成桶",
+			cfg:     Config{Window: time.Nanosecond * 5, Buckets: 10, OpenDuration: time.Minute},
+			wantErr: ErrInvalidConfig,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.ErrorIs(t, tc.cfg.Validate(), tc.wantErr)
+		})
+	}
+}
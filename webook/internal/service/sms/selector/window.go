@@ -0,0 +1,72 @@
+package selector
+
+import "time"
+
+// bucket 窗口里面一小段时间的统计
+type bucket struct {
+	// start 这个桶从什么时候开始，过期的桶复用之前要清空
+	start      time.Time
+	total      int
+	failures   int
+	latencySum time.Duration
+}
+
+// window 滑动窗口，按照时间切成一个个桶循环使用，不是并发安全的
+type window struct {
+	buckets []bucket
+	// size 每个桶多长时间
+	size time.Duration
+}
+
+func newWindow(length time.Duration, cnt int) *window {
+	return &window{
+		buckets: make([]bucket, cnt),
+		size:    length / time.Duration(cnt),
+	}
+}
+
+func (w *window) add(now time.Time, latency time.Duration, failed bool) {
+	start := now.Truncate(w.size)
+	b := &w.buckets[int(start.UnixNano()/int64(w.size))%len(w.buckets)]
+	if !b.start.Equal(start) {
+		*b = bucket{start: start}
+	}
+	b.total++
+	b.latencySum += latency
+	if failed {
+		b.failures++
+	}
+}
+
+type stats struct {
+	total      int
+	failures   int
+	errRate    float64
+	avgLatency time.Duration
+}
+
+// stats 汇总还在窗口里面的桶
+func (w *window) stats(now time.Time) stats {
+	var res stats
+	var latencySum time.Duration
+	oldest := now.Truncate(w.size).Add(-w.size * time.Duration(len(w.buckets)-1))
+	for _, b := range w.buckets {
+		if b.start.Before(oldest) || b.start.After(now) {
+			continue
+		}
+		res.total += b.total
+		res.failures += b.failures
+		latencySum += b.latencySum
+	}
+	if res.total > 0 {
+		res.errRate = float64(res.failures) / float64(res.total)
+		res.avgLatency = latencySum / time.Duration(res.total)
+	}
+	return res
+}
+
+func (w *window) reset() {
+	for i := range w.buckets {
+		w.buckets[i] = bucket{}
+	}
+}
@@ -2,25 +2,47 @@ package tencent
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"github.com/ecodeclub/ekit"
 	"github.com/ecodeclub/ekit/slice"
 	sms "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/sms/v20210111"
 	"go.uber.org/zap"
+	"strings"
+	"time"
+	smsx "xiaoweishu/webook/internal/service/sms"
 )
 
+// Provider 短信模板管理里面腾讯云的名字
+const Provider = "tencent"
+
 type Service struct {
 	client   *sms.Client
 	appId    *string
 	signName *string
+	resolver smsx.TemplateResolver
+}
+
+func (s *Service) Send(ctx context.Context, tplName string, args []string, numbers ...string) error {
+	_, err := s.SendV1(ctx, tplName, args, numbers...)
+	return err
 }
 
-func (s *Service) Send(ctx context.Context, tplId string, args []string, numbers ...string) error {
+// SendV1 腾讯云每个手机号一个 SerialNo
+func (s *Service) SendV1(ctx context.Context, tplName string, args []string,
+	numbers ...string) ([]smsx.SentMessage, error) {
+	tpl, err := s.resolver.Resolve(ctx, Provider, tplName, args)
+	if err != nil {
+		return nil, err
+	}
 	request := sms.NewSendSmsRequest()
 	request.SetContext(ctx)
 	request.SmsSdkAppId = s.appId
 	request.SignName = s.signName
-	request.TemplateId = ekit.ToPtr[string](tplId)
+	if tpl.SignName != "" {
+		request.SignName = ekit.ToPtr[string](tpl.SignName)
+	}
+	request.TemplateId = ekit.ToPtr[string](tpl.Id)
 	request.TemplateParamSet = s.toPtrSlice(args)
 	request.PhoneNumberSet = s.toPtrSlice(numbers)
 	response, err := s.client.SendSms(request)
@@ -29,8 +51,9 @@ func (s *Service) Send(ctx context.Context, tplId string, args []string, numbers
 		zap.Any("resp", response))
 	// 处理异常
 	if err != nil {
-		return err
+		return nil, err
 	}
+	res := make([]smsx.SentMessage, 0, len(numbers))
 	for _, statusPtr := range response.Response.SendStatusSet {
 		if statusPtr == nil {
 			// 不可能进来这里
@@ -39,10 +62,106 @@ func (s *Service) Send(ctx context.Context, tplId string, args []string, numbers
 		status := *statusPtr
 		if status.Code == nil || *(status.Code) != "Ok" {
 			// 发送失败
-			return fmt.Errorf("发送短信失败 code: %s, msg: %s", *status.Code, *status.Message)
+			return nil, fmt.Errorf("发送短信失败 code: %s, msg: %s", *status.Code, *status.Message)
+		}
+		res = append(res, smsx.SentMessage{
+			Number: s.matchNumber(deref(status.PhoneNumber), numbers),
+			BizId:  deref(status.SerialNo),
+		})
+	}
+	return res, nil
+}
+
+// matchNumber 腾讯云返回的手机号带了 +86 这种国家码，换回调用方传的
+func (s *Service) matchNumber(phone string, numbers []string) string {
+	for _, n := range numbers {
+		if strings.HasSuffix(phone, strings.TrimPrefix(n, "+")) {
+			return n
 		}
 	}
-	return nil
+	return phone
+}
+
+// receiptItem 腾讯云推过来的短信下发状态，是一个 JSON 数组
+type receiptItem struct {
+	UserReceiveTime string `json:"user_receive_time"`
+	NationCode      string `json:"nationcode"`
+	Mobile          string `json:"mobile"`
+	// ReportStatus SUCCESS 或者 FAIL
+	ReportStatus string `json:"report_status"`
+	ErrMsg       string `json:"errmsg"`
+	Description  string `json:"description"`
+	Sid          string `json:"sid"`
+}
+
+// receiptAck 处理成功之后返回给腾讯云的
+type receiptAck struct {
+	Result int    `json:"result"`
+	ErrMsg string `json:"errmsg"`
+}
+
+// receiptLocation 回执里面的时间是北京时间
+var receiptLocation = time.FixedZone("CST", 8*3600)
+
+// ParseReceipts SerialNo 一个手机号一个，所以回执不需要手机号
+func (s *Service) ParseReceipts(body []byte) ([]smsx.Receipt, any, error) {
+	var items []receiptItem
+	err := json.Unmarshal(body, &items)
+	if err != nil {
+		return nil, nil, err
+	}
+	res := make([]smsx.Receipt, 0, len(items))
+	for _, item := range items {
+		t, err := time.ParseInLocation(time.DateTime, item.UserReceiveTime, receiptLocation)
+		if err != nil {
+			return nil, nil, fmt.Errorf("腾讯云回执的时间格式不对 %w", err)
+		}
+		res = append(res, s.toReceipt(item.Sid, item.ReportStatus, item.ErrMsg+" "+item.Description, t))
+	}
+	return res, receiptAck{Result: 0, ErrMsg: "OK"}, nil
+}
+
+// PullReceipts 拉取短信下发状态，一次最多一百条，拉过的不会再返回
+func (s *Service) PullReceipts(ctx context.Context) ([]smsx.Receipt, error) {
+	request := sms.NewPullSmsSendStatusRequest()
+	request.SetContext(ctx)
+	request.SmsSdkAppId = s.appId
+	request.Limit = ekit.ToPtr[uint64](100)
+	response, err := s.client.PullSmsSendStatus(request)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]smsx.Receipt, 0, len(response.Response.PullSmsSendStatusSet))
+	for _, item := range response.Response.PullSmsSendStatusSet {
+		if item == nil || item.SerialNo == nil {
+			continue
+		}
+		var t time.Time
+		if item.UserReceiveTime != nil {
+			t = time.Unix(int64(*item.UserReceiveTime), 0)
+		}
+		res = append(res, s.toReceipt(*item.SerialNo, deref(item.ReportStatus), deref(item.Description), t))
+	}
+	return res, nil
+}
+
+func (s *Service) toReceipt(serialNo string, status string, reason string, t time.Time) smsx.Receipt {
+	r := smsx.Receipt{
+		BizId:     serialNo,
+		Delivered: status == "SUCCESS",
+		Time:      t,
+	}
+	if !r.Delivered {
+		r.Reason = strings.TrimSpace(reason)
+	}
+	return r
+}
+
+func deref(val *string) string {
+	if val == nil {
+		return ""
+	}
+	return *val
 }
 
 func (s *Service) toPtrSlice(data []string) []*string {
@@ -52,10 +171,12 @@ func (s *Service) toPtrSlice(data []string) []*string {
 		})
 }
 
-func NewService(client *sms.Client, appId string, signName string) *Service {
+// NewService signName 是默认的签名，模板自己配置了签名的时候用模板的
+func NewService(client *sms.Client, appId string, signName string, resolver smsx.TemplateResolver) *Service {
 	return &Service{
 		client:   client,
 		appId:    &appId,
 		signName: &signName,
+		resolver: resolver,
 	}
 }
@@ -8,8 +8,11 @@ import (
 	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
 	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/profile"
 	sms "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/sms/v20210111"
+	"go.uber.org/mock/gomock"
 	"os"
 	"testing"
+	smsx "xiaoweishu/webook/internal/service/sms"
+	smsmocks "xiaoweishu/webook/internal/service/sms/mocks"
 )
 
 // 这个需要手动跑，也就是你需要在本地搞好这些环境变量
@@ -27,7 +30,12 @@ func TestSender(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	s := NewService(c, "1400842696", "妙影科技")
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	resolver := smsmocks.NewMockTemplateResolver(ctrl)
+	resolver.EXPECT().Resolve(gomock.Any(), Provider, "verify_code", gomock.Any()).
+		Return(smsx.Template{Id: "1877556"}, nil).AnyTimes()
+	s := NewService(c, "1400842696", "妙影科技", resolver)
 
 	testCases := []struct {
 		name    string
@@ -38,7 +46,7 @@ func TestSender(t *testing.T) {
 	}{
 		{
 			name:   "发送验证码",
-			tplId:  "1877556",
+			tplId:  "verify_code",
 			params: []string{"123456"},
 			// 改成你的手机号码
 			numbers: []string{""},
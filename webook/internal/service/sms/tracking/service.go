@@ -0,0 +1,97 @@
+// Package tracking 记录每一条发出去的短信和供应商的消息 id，之后靠回执更新有没有送达
+package tracking
+
+import (
+	"context"
+	"errors"
+	"time"
+	"unicode/utf8"
+	"xiaoweishu/webook/internal/domain"
+	"xiaoweishu/webook/internal/service/sms"
+	"xiaoweishu/webook/pkg/logger"
+)
+
+// maxReasonLen 失败原因最多保留多少个字符
+const maxReasonLen = 512
+
+// Recorder 保存发出去的短信
+type Recorder interface {
+	Record(ctx context.Context, msgs []domain.SmsMessage) error
+}
+
+// Service 装饰单个供应商，要放在 failover 或者 selector 这些选择供应商的装饰器里面
+type Service struct {
+	provider string
+	svc      sms.TrackableService
+	recorder Recorder
+	l        logger.LoggerV1
+}
+
+func NewService(provider string, svc sms.TrackableService, recorder Recorder, l logger.LoggerV1) *Service {
+	return &Service{
+		provider: provider,
+		svc:      svc,
+		recorder: recorder,
+		l:        l,
+	}
+}
+
+// Send 记录失败不影响发送的结果
+func (s *Service) Send(ctx context.Context, tplId string, args []string, numbers ...string) error {
+	sent, err := s.svc.SendV1(ctx, tplId, args, numbers...)
+	var msgs []domain.SmsMessage
+	switch {
+	case err == nil:
+		msgs = make([]domain.SmsMessage, 0, len(sent))
+		for _, m := range sent {
+			msgs = append(msgs, domain.SmsMessage{
+				Provider: s.provider,
+				BizId:    m.BizId,
+				Phone:    m.Number,
+				TplName:  tplId,
+				Status:   domain.SmsMessageStatusSent,
+			})
+		}
+	case errors.Is(err, sms.ErrTemplateUnavailable), errors.Is(err, sms.ErrResolveTemplate),
+		errors.Is(err, context.Canceled):
+		// 没有发给供应商
+		return err
+	default:
+		reason := truncate(err.Error())
+		msgs = make([]domain.SmsMessage, 0, len(numbers))
+		for _, n := range numbers {
+			msgs = append(msgs, domain.SmsMessage{
+				Provider: s.provider,
+				Phone:    n,
+				TplName:  tplId,
+				Status:   domain.SmsMessageStatusFailed,
+				Reason:   reason,
+			})
+		}
+	}
+	s.record(ctx, msgs)
+	return err
+}
+
+func (s *Service) record(ctx context.Context, msgs []domain.SmsMessage) {
+	if len(msgs) == 0 {
+		return
+	}
+	// 发送超时了也要记录下来
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), time.Second)
+	defer cancel()
+	err := s.recorder.Record(ctx, msgs)
+	if err != nil {
+		s.l.Error("记录发出去的短信失败",
+			logger.String("provider", s.provider),
+			logger.Int("cnt", len(msgs)),
+			logger.Error(err))
+	}
+}
+
+func truncate(reason string) string {
+	if utf8.RuneCountInString(reason) <= maxReasonLen {
+		return reason
+	}
+	return string([]rune(reason)[:maxReasonLen])
+}
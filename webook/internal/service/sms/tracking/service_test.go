@@ -0,0 +1,107 @@
+package tracking
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"net/http"
+	"sync"
+	"testing"
+	"xiaoweishu/webook/internal/domain"
+	"xiaoweishu/webook/internal/service/sms"
+	"xiaoweishu/webook/internal/service/sms/mockgw"
+	smsmocks "xiaoweishu/webook/internal/service/sms/mocks"
+	"xiaoweishu/webook/internal/service/sms/webhook"
+	"xiaoweishu/webook/pkg/logger"
+)
+
+type memoryRecorder struct {
+	mu   sync.Mutex
+	msgs []domain.SmsMessage
+}
+
+func (r *memoryRecorder) Record(ctx context.Context, msgs []domain.SmsMessage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.msgs = append(r.msgs, msgs...)
+	return nil
+}
+
+// TestService_Send 用 mockgw 模拟供应商，发送之后记录短信，再拉回执
+func TestService_Send(t *testing.T) {
+	testCases := []struct {
+		name   string
+		before func(gw *mockgw.Server, resolver *smsmocks.MockTemplateResolver)
+
+		wantErr      bool
+		wantStatus   []domain.SmsMessageStatus
+		wantReceipts int
+	}{
+		{
+			name: "发送成功",
+			before: func(gw *mockgw.Server, resolver *smsmocks.MockTemplateResolver) {
+				resolver.EXPECT().Resolve(gomock.Any(), "gw", "verify_code", gomock.Any()).
+					Return(sms.Template{Id: "gw-tpl"}, nil)
+			},
+			wantStatus:   []domain.SmsMessageStatus{domain.SmsMessageStatusSent, domain.SmsMessageStatusSent},
+			wantReceipts: 2,
+		},
+		{
+			name: "供应商出错",
+			before: func(gw *mockgw.Server, resolver *smsmocks.MockTemplateResolver) {
+				gw.FailNext(1)
+				resolver.EXPECT().Resolve(gomock.Any(), "gw", "verify_code", gomock.Any()).
+					Return(sms.Template{Id: "gw-tpl"}, nil)
+			},
+			wantErr:    true,
+			wantStatus: []domain.SmsMessageStatus{domain.SmsMessageStatusFailed, domain.SmsMessageStatusFailed},
+		},
+		{
+			name: "模板没审核，不记录",
+			before: func(gw *mockgw.Server, resolver *smsmocks.MockTemplateResolver) {
+				resolver.EXPECT().Resolve(gomock.Any(), "gw", "verify_code", gomock.Any()).
+					Return(sms.Template{}, sms.ErrTemplateUnavailable)
+			},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			gw := mockgw.NewServer()
+			defer gw.Close()
+			resolver := smsmocks.NewMockTemplateResolver(ctrl)
+			tc.before(gw, resolver)
+			raw := webhook.NewServiceV1(http.DefaultClient, "gw", gw.URL()+"/send", gw.URL()+"/receipts", "", resolver)
+			recorder := &memoryRecorder{}
+			svc := NewService("gw", raw, recorder, logger.NewNopLogger())
+
+			err := svc.Send(context.Background(), "verify_code", []string{"123456"}, "15212345678", "15287654321")
+			assert.Equal(t, tc.wantErr, err != nil)
+			require.Len(t, recorder.msgs, len(tc.wantStatus))
+			for i, m := range recorder.msgs {
+				assert.Equal(t, tc.wantStatus[i], m.Status)
+				assert.Equal(t, "gw", m.Provider)
+				assert.Equal(t, "verify_code", m.TplName)
+				if m.Status == domain.SmsMessageStatusFailed {
+					assert.NotEmpty(t, m.Reason)
+				}
+			}
+
+			receipts, err := raw.PullReceipts(context.Background())
+			require.NoError(t, err)
+			require.Len(t, receipts, tc.wantReceipts)
+			for i, r := range receipts {
+				assert.True(t, r.Delivered)
+				assert.Equal(t, recorder.msgs[i].BizId, r.BizId)
+				assert.Equal(t, recorder.msgs[i].Phone, r.Number)
+			}
+			//拉过的不会再返回
+			receipts, err = raw.PullReceipts(context.Background())
+			require.NoError(t, err)
+			assert.Len(t, receipts, 0)
+		})
+	}
+}
@@ -1,12 +1,27 @@
 package sms
 
-import "context"
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+var (
+	// ErrTemplateUnavailable 这个供应商没有可用的模板，比如说还没审核通过，可以换一个供应商
+	ErrTemplateUnavailable = errors.New("供应商的短信模板不可用")
+	// ErrResolveTemplate 模板不存在、参数不对或者查询模板出错，和供应商无关，换供应商也没用
+	ErrResolveTemplate = errors.New("解析短信模板失败")
+	// ErrInvalidReceiptSignature 推过来的回执验签失败，可能是伪造的
+	ErrInvalidReceiptSignature = errors.New("短信回执的签名不对")
+)
 
 // Service 发送短信的抽象
 // 屏蔽不同供应商之间的区别
 //
-//go:generate mockgen -source=./types.go -package=smsmocks -destination=./mocks/sms.mock.go Service
+//go:generate mockgen -source=./types.go -package=smsmocks -destination=./mocks/sms.mock.go Service TemplateResolver TrackableService ReceiptParser ReceiptVerifier ReceiptPuller
 type Service interface {
+	// Send tplId 是逻辑上的模板名字，各个供应商的实现自己用 TemplateResolver 换成自己的模板 id
 	Send(ctx context.Context, tplId string,
 		args []string, numbers ...string) error
 	//A()
@@ -15,3 +30,61 @@ type Service interface {
 
 type Req struct {
 }
+
+// Template 解析之后某个供应商的模板
+type Template struct {
+	Id string
+	// SignName 为空的时候用供应商默认的签名
+	SignName string
+	// ArgNames 参数的名字，和参数的顺序一致。阿里云这种按照名字传参数的供应商要用
+	ArgNames []string
+}
+
+// TemplateResolver 把逻辑上的模板名字换成供应商的模板 id 和签名，顺便校验参数。
+// 返回的 error 是 ErrTemplateUnavailable 或者 ErrResolveTemplate
+type TemplateResolver interface {
+	Resolve(ctx context.Context, provider string, name string, args []string) (Template, error)
+}
+
+// SentMessage 供应商受理了的一条短信，一个手机号一条
+type SentMessage struct {
+	Number string
+	// BizId 供应商的消息 id，回执里面靠它找到是哪一条短信
+	BizId string
+}
+
+// TrackableService 能返回供应商消息 id 的供应商，用来跟踪短信有没有送达
+type TrackableService interface {
+	Service
+	// SendV1 和 Send 一样，成功的时候返回每个手机号的消息 id
+	SendV1(ctx context.Context, tplId string, args []string, numbers ...string) ([]SentMessage, error)
+}
+
+// Receipt 供应商的送达回执
+type Receipt struct {
+	BizId string
+	// Number 供应商的 BizId 是一批短信共用的时候用来区分手机号，BizId 一个手机号一个的时候可以为空
+	Number string
+	// Delivered 用户收到了
+	Delivered bool
+	// Reason 没有送达的原因
+	Reason string
+	Time   time.Time
+}
+
+// ReceiptParser 供应商推过来的回执格式各不相同，各个供应商自己解析
+type ReceiptParser interface {
+	// ParseReceipts ack 是处理成功之后返回给供应商的 JSON
+	ParseReceipts(body []byte) (receipts []Receipt, ack any, err error)
+}
+
+// ReceiptVerifier 推回执的时候带了签名的供应商，解析之前先验签。
+// 验签失败返回 ErrInvalidReceiptSignature
+type ReceiptVerifier interface {
+	VerifyReceipts(header http.Header, body []byte) error
+}
+
+// ReceiptPuller 需要主动去拉回执的供应商，拉过的回执不会再返回
+type ReceiptPuller interface {
+	PullReceipts(ctx context.Context) ([]Receipt, error)
+}
@@ -0,0 +1,60 @@
+package webhook
+
+import (
+	"encoding/hex"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"xiaoweishu/webook/internal/service/sms"
+)
+
+func TestService_VerifyReceipts(t *testing.T) {
+	body := []byte(`[{"bizId":"biz-1","number":"15212345678","status":"DELIVERED","time":1714528805000}]`)
+	testCases := []struct {
+		name   string
+		token  string
+		header http.Header
+
+		wantErr error
+	}{
+		{
+			name:  "签名正确",
+			token: "secret",
+			header: http.Header{
+				http.CanonicalHeaderKey(SignatureHeader): {hex.EncodeToString(Sign("secret", body))},
+			},
+		},
+		{
+			name:  "用别的 token 签名",
+			token: "secret",
+			header: http.Header{
+				http.CanonicalHeaderKey(SignatureHeader): {hex.EncodeToString(Sign("other", body))},
+			},
+			wantErr: sms.ErrInvalidReceiptSignature,
+		},
+		{
+			name:    "没有签名",
+			token:   "secret",
+			header:  http.Header{},
+			wantErr: sms.ErrInvalidReceiptSignature,
+		},
+		{
+			name:  "签名不是十六进制",
+			token: "secret",
+			header: http.Header{
+				http.CanonicalHeaderKey(SignatureHeader): {"xyz"},
+			},
+			wantErr: sms.ErrInvalidReceiptSignature,
+		},
+		{
+			name:   "没有配置 token 不验签",
+			header: http.Header{},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			svc := NewService(http.DefaultClient, "gw", "http://localhost/send", tc.token, nil)
+			assert.Equal(t, tc.wantErr, svc.VerifyReceipts(tc.header, body))
+		})
+	}
+}
@@ -0,0 +1,208 @@
+// Package webhook 通用的 HTTP 短信供应商，把短信 POST 到一个地址，对接自建的短信网关或者没有 SDK 的供应商
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/ecodeclub/ekit/slice"
+	"net/http"
+	"time"
+	"xiaoweishu/webook/internal/service/sms"
+)
+
+// Req POST 过去的 JSON
+type Req struct {
+	TplId    string   `json:"tplId"`
+	SignName string   `json:"signName"`
+	Args     []string `json:"args"`
+	Numbers  []string `json:"numbers"`
+}
+
+// Resp 对方返回的 JSON，HTTP 状态码是 200 并且 Code 是 OK 才是发送成功
+type Resp struct {
+	Code  string `json:"code"`
+	Msg   string `json:"msg"`
+	BizId string `json:"bizId"`
+}
+
+// CodeOK 发送成功的 Code
+const CodeOK = "OK"
+
+// ReceiptItem 一条回执，推过来的时候是 JSON 数组，拉回来的时候放在 ReceiptResp 里面
+type ReceiptItem struct {
+	BizId  string `json:"bizId"`
+	Number string `json:"number"`
+	// Status DELIVERED 或者 FAILED
+	Status string `json:"status"`
+	Reason string `json:"reason"`
+	// Time 送达时间，毫秒数
+	Time int64 `json:"time"`
+}
+
+// ReceiptResp 拉回执的时候对方返回的 JSON
+type ReceiptResp struct {
+	Code     string        `json:"code"`
+	Msg      string        `json:"msg"`
+	Receipts []ReceiptItem `json:"receipts"`
+}
+
+// SignatureHeader 推回执的时候放签名的 header，签名是用 token 对 body 做 HMAC-SHA256 之后的十六进制
+const SignatureHeader = "X-Sms-Signature"
+
+const (
+	ReceiptDelivered = "DELIVERED"
+	ReceiptFailed    = "FAILED"
+)
+
+type Service struct {
+	client *http.Client
+	// provider 短信模板管理里面的名字，接了多个 webhook 供应商的时候用来区分
+	provider string
+	url      string
+	// receiptUrl 拉回执的地址，为空就是对方会推过来
+	receiptUrl string
+	// token 放在 Authorization 里面，为空就不放。对方推回执的时候也用它签名
+	token    string
+	resolver sms.TemplateResolver
+}
+
+func NewService(client *http.Client, provider string, url string, token string,
+	resolver sms.TemplateResolver) *Service {
+	return NewServiceV1(client, provider, url, "", token, resolver)
+}
+
+// NewServiceV1 receiptUrl 不为空的时候 PullReceipts 用 GET 去这个地址拉回执
+func NewServiceV1(client *http.Client, provider string, url string, receiptUrl string, token string,
+	resolver sms.TemplateResolver) *Service {
+	return &Service{
+		client:     client,
+		provider:   provider,
+		url:        url,
+		receiptUrl: receiptUrl,
+		token:      token,
+		resolver:   resolver,
+	}
+}
+
+func (s *Service) Send(ctx context.Context, tplName string, args []string, numbers ...string) error {
+	_, err := s.SendV1(ctx, tplName, args, numbers...)
+	return err
+}
+
+// SendV1 对方对一批短信只返回一个 BizId，回执里面用手机号区分
+func (s *Service) SendV1(ctx context.Context, tplName string, args []string,
+	numbers ...string) ([]sms.SentMessage, error) {
+	tpl, err := s.resolver.Resolve(ctx, s.provider, tplName, args)
+	if err != nil {
+		return nil, err
+	}
+	body, err := json.Marshal(Req{
+		TplId:    tpl.Id,
+		SignName: tpl.SignName,
+		Args:     args,
+		Numbers:  numbers,
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	var res Resp
+	status, err := s.do(req, &res)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK || res.Code != CodeOK {
+		return nil, fmt.Errorf("发送短信失败 status: %d, code: %s, msg: %s", status, res.Code, res.Msg)
+	}
+	return slice.Map(numbers, func(idx int, src string) sms.SentMessage {
+		return sms.SentMessage{Number: src, BizId: res.BizId}
+	}), nil
+}
+
+// do 带上 token 发请求，把返回的 JSON 解析到 val 里面
+func (s *Service) do(req *http.Request, val any) (int, error) {
+	if s.token != "" {
+		req.Header.Set("Authorization", s.token)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("调用短信 webhook %s 失败 %w", s.provider, err)
+	}
+	defer resp.Body.Close()
+	err = json.NewDecoder(resp.Body).Decode(val)
+	if err != nil {
+		return resp.StatusCode, fmt.Errorf("短信 webhook %s 返回的不是 JSON, status: %d, err: %w",
+			s.provider, resp.StatusCode, err)
+	}
+	return resp.StatusCode, nil
+}
+
+// ParseReceipts 对方推过来的是 ReceiptItem 的 JSON 数组
+func (s *Service) ParseReceipts(body []byte) ([]sms.Receipt, any, error) {
+	var items []ReceiptItem
+	err := json.Unmarshal(body, &items)
+	if err != nil {
+		return nil, nil, err
+	}
+	return toReceipts(items), Resp{Code: CodeOK, Msg: "OK"}, nil
+}
+
+// VerifyReceipts 没有配置 token 的时候对方没法签名，只靠回执地址上的 token 校验
+func (s *Service) VerifyReceipts(header http.Header, body []byte) error {
+	if s.token == "" {
+		return nil
+	}
+	sig, err := hex.DecodeString(header.Get(SignatureHeader))
+	if err != nil || !hmac.Equal(sig, Sign(s.token, body)) {
+		return sms.ErrInvalidReceiptSignature
+	}
+	return nil
+}
+
+// Sign 推回执的一方用这个签名
+func Sign(token string, body []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(token))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+// PullReceipts 没有配置 receiptUrl 的时候什么也不做
+func (s *Service) PullReceipts(ctx context.Context) ([]sms.Receipt, error) {
+	if s.receiptUrl == "" {
+		return nil, nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.receiptUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	var res ReceiptResp
+	status, err := s.do(req, &res)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK || res.Code != CodeOK {
+		return nil, fmt.Errorf("拉取回执失败 status: %d, code: %s, msg: %s", status, res.Code, res.Msg)
+	}
+	return toReceipts(res.Receipts), nil
+}
+
+func toReceipts(items []ReceiptItem) []sms.Receipt {
+	return slice.Map(items, func(idx int, src ReceiptItem) sms.Receipt {
+		return sms.Receipt{
+			BizId:     src.BizId,
+			Number:    src.Number,
+			Delivered: src.Status == ReceiptDelivered,
+			Reason:    src.Reason,
+			Time:      time.UnixMilli(src.Time),
+		}
+	})
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"time"
+	"xiaoweishu/webook/internal/domain"
+	"xiaoweishu/webook/internal/repository"
+	"xiaoweishu/webook/internal/service/sms"
+	"xiaoweishu/webook/pkg/logger"
+)
+
+//go:generate mockgen -source=./sms_message.go -package=svcmocks -destination=mocks/sms_message.mock.go SmsMessageService
+
+// SmsMessageService 跟踪发出去的每一条短信有没有送达
+type SmsMessageService interface {
+	// Record 记录发出去的短信，发送失败的也要记录
+	Record(ctx context.Context, msgs []domain.SmsMessage) error
+	// HandleReceipts 处理供应商推过来或者拉回来的回执
+	HandleReceipts(ctx context.Context, provider string, receipts []sms.Receipt) error
+	List(ctx context.Context, q domain.SmsMessageQuery) ([]domain.SmsMessage, error)
+	// Stats 按照供应商统计一段时间内发送的短信的送达情况
+	Stats(ctx context.Context, start, end time.Time) ([]domain.SmsDeliveryStats, error)
+}
+
+type smsMessageService struct {
+	repo repository.SmsMessageRepository
+	l    logger.LoggerV1
+	// counter 按照供应商和状态统计。sent 是供应商受理了的，delivered 来自回执，
+	// 送达率就是 delivered 除以 sent。failed 包括发送失败的和回执说没送达的
+	counter *prometheus.CounterVec
+}
+
+func NewSmsMessageService(repo repository.SmsMessageRepository, l logger.LoggerV1,
+	opts prometheus.CounterOpts) SmsMessageService {
+	counter := prometheus.NewCounterVec(opts, []string{"provider", "status"})
+	prometheus.MustRegister(counter)
+	return &smsMessageService{
+		repo:    repo,
+		l:       l,
+		counter: counter,
+	}
+}
+
+func (s *smsMessageService) Record(ctx context.Context, msgs []domain.SmsMessage) error {
+	err := s.repo.Create(ctx, msgs)
+	if err != nil {
+		return err
+	}
+	for _, m := range msgs {
+		s.counter.WithLabelValues(m.Provider, m.Status.String()).Inc()
+	}
+	return nil
+}
+
+func (s *smsMessageService) HandleReceipts(ctx context.Context, provider string, receipts []sms.Receipt) error {
+	var errs []error
+	for _, r := range receipts {
+		status := domain.SmsMessageStatusDelivered
+		if !r.Delivered {
+			status = domain.SmsMessageStatusFailed
+		}
+		ok, err := s.repo.UpdateStatus(ctx, provider, r.BizId, r.Number, status, r.Reason, r.Time)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !ok {
+			// 回执重复推送，或者回执比发送记录先到
+			s.l.Info("找不到等待回执的短信",
+				logger.String("provider", provider),
+				logger.String("bizId", r.BizId))
+			continue
+		}
+		// 这一条在发送的时候已经按照 sent 统计过了
+		s.counter.WithLabelValues(provider, status.String()).Inc()
+	}
+	return errors.Join(errs...)
+}
+
+func (s *smsMessageService) List(ctx context.Context, q domain.SmsMessageQuery) ([]domain.SmsMessage, error) {
+	return s.repo.List(ctx, q)
+}
+
+func (s *smsMessageService) Stats(ctx context.Context, start, end time.Time) ([]domain.SmsDeliveryStats, error) {
+	return s.repo.Stats(ctx, start, end)
+}
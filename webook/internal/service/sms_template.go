@@ -0,0 +1,219 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ecodeclub/ekit/slice"
+	"regexp"
+	"sync"
+	"time"
+	"xiaoweishu/webook/internal/domain"
+	"xiaoweishu/webook/internal/repository"
+	"xiaoweishu/webook/internal/service/sms"
+)
+
+var (
+	ErrInvalidSmsTemplate       = errors.New("短信模板的定义不合法")
+	ErrSmsTemplateNotFound      = repository.ErrSmsTemplateNotFound
+	ErrSmsTemplateDuplicateName = repository.ErrSmsTemplateDuplicateName
+	// ErrSmsTemplateNotApproved 供应商没有这个模板，或者还没有审核通过
+	ErrSmsTemplateNotApproved = sms.ErrTemplateUnavailable
+)
+
+// SmsTemplateService 管理逻辑上的短信模板，同时负责发送的时候把模板名字换成供应商的模板
+type SmsTemplateService interface {
+	sms.TemplateResolver
+	Create(ctx context.Context, t domain.SmsTemplate) (int64, error)
+	// Update 不能修改名字，也不会修改供应商的模板
+	Update(ctx context.Context, t domain.SmsTemplate) error
+	Delete(ctx context.Context, id int64) error
+	Get(ctx context.Context, id int64) (domain.SmsTemplate, error)
+	List(ctx context.Context, offset, limit int) ([]domain.SmsTemplate, error)
+	// SaveProvider 新增或者修改供应商的模板，模板 id 或者签名变了就要重新审核
+	SaveProvider(ctx context.Context, tid int64, p domain.SmsProviderTemplate) error
+	DeleteProvider(ctx context.Context, tid int64, provider string) error
+	// Review 供应商那边审核通过之后，管理员在这里标记通过，不通过的要写原因
+	Review(ctx context.Context, tid int64, provider string, approved bool, reason string) error
+}
+
+type smsTemplateService struct {
+	repo repository.SmsTemplateRepository
+	// expiration 发送的时候解析模板用的本地缓存多久过期，修改了模板之后别的实例最多这么久之后生效
+	expiration time.Duration
+	mu         sync.RWMutex
+	cache      map[string]cachedSmsTemplate
+	// fallbacks 短信模板管理里面没有这个模板的时候用的，比如说上线模板管理之前就在用的验证码模板
+	fallbacks map[string]domain.SmsTemplate
+}
+
+type cachedSmsTemplate struct {
+	tpl      domain.SmsTemplate
+	deadline time.Time
+}
+
+func NewSmsTemplateService(repo repository.SmsTemplateRepository, expiration time.Duration) SmsTemplateService {
+	return NewSmsTemplateServiceV1(repo, expiration, nil)
+}
+
+// NewSmsTemplateServiceV1 fallbacks 里面的模板在短信模板管理里面找不到的时候用，
+// 它们的供应商模板都当作已经审核通过了。管理员在模板管理里面建了同名的模板之后就用模板管理的
+func NewSmsTemplateServiceV1(repo repository.SmsTemplateRepository, expiration time.Duration,
+	fallbacks []domain.SmsTemplate) SmsTemplateService {
+	res := &smsTemplateService{
+		repo:       repo,
+		expiration: expiration,
+		cache:      make(map[string]cachedSmsTemplate),
+		fallbacks:  make(map[string]domain.SmsTemplate, len(fallbacks)),
+	}
+	for _, t := range fallbacks {
+		t.Providers = slice.Map(t.Providers, func(idx int, src domain.SmsProviderTemplate) domain.SmsProviderTemplate {
+			src.Status = domain.SmsTemplateStatusApproved
+			return src
+		})
+		res.fallbacks[t.Name] = t
+	}
+	return res
+}
+
+func (s *smsTemplateService) Resolve(ctx context.Context, provider string, name string,
+	args []string) (sms.Template, error) {
+	t, err := s.find(ctx, name)
+	if err != nil {
+		return sms.Template{}, fmt.Errorf("%w, 模板 %s: %w", sms.ErrResolveTemplate, name, err)
+	}
+	p, ok := t.Provider(provider)
+	if !ok || p.Status != domain.SmsTemplateStatusApproved {
+		return sms.Template{}, fmt.Errorf("%w, 模板 %s, 供应商 %s", ErrSmsTemplateNotApproved, name, provider)
+	}
+	err = t.Validate(args)
+	if err != nil {
+		return sms.Template{}, fmt.Errorf("%w: %w", sms.ErrResolveTemplate, err)
+	}
+	return sms.Template{
+		Id:       p.TplId,
+		SignName: p.SignName,
+		ArgNames: slice.Map(t.Args, func(idx int, src domain.SmsTemplateArg) string {
+			return src.Name
+		}),
+	}, nil
+}
+
+func (s *smsTemplateService) find(ctx context.Context, name string) (domain.SmsTemplate, error) {
+	s.mu.RLock()
+	c, ok := s.cache[name]
+	s.mu.RUnlock()
+	if ok && time.Now().Before(c.deadline) {
+		return c.tpl, nil
+	}
+	t, err := s.repo.FindByName(ctx, name)
+	if errors.Is(err, ErrSmsTemplateNotFound) {
+		fallback, ok := s.fallbacks[name]
+		if ok {
+			t, err = fallback, nil
+		}
+	}
+	if err != nil {
+		return domain.SmsTemplate{}, err
+	}
+	s.mu.Lock()
+	s.cache[name] = cachedSmsTemplate{tpl: t, deadline: time.Now().Add(s.expiration)}
+	s.mu.Unlock()
+	return t, nil
+}
+
+// invalidate 只能清掉本地的缓存，别的实例等缓存过期
+func (s *smsTemplateService) invalidate() {
+	s.mu.Lock()
+	s.cache = make(map[string]cachedSmsTemplate)
+	s.mu.Unlock()
+}
+
+func (s *smsTemplateService) Create(ctx context.Context, t domain.SmsTemplate) (int64, error) {
+	if t.Name == "" {
+		return 0, fmt.Errorf("%w: 名字不能为空", ErrInvalidSmsTemplate)
+	}
+	err := s.validate(t)
+	if err != nil {
+		return 0, err
+	}
+	return s.repo.Create(ctx, t)
+}
+
+func (s *smsTemplateService) Update(ctx context.Context, t domain.SmsTemplate) error {
+	err := s.validate(t)
+	if err != nil {
+		return err
+	}
+	defer s.invalidate()
+	return s.repo.Update(ctx, t)
+}
+
+func (s *smsTemplateService) validate(t domain.SmsTemplate) error {
+	names := make(map[string]struct{}, len(t.Args))
+	for _, arg := range t.Args {
+		if arg.Name == "" {
+			return fmt.Errorf("%w: 参数的名字不能为空", ErrInvalidSmsTemplate)
+		}
+		if _, ok := names[arg.Name]; ok {
+			return fmt.Errorf("%w: 参数 %s 重复了", ErrInvalidSmsTemplate, arg.Name)
+		}
+		names[arg.Name] = struct{}{}
+		if arg.MaxLen < 0 {
+			return fmt.Errorf("%w: 参数 %s 的长度限制不能是负数", ErrInvalidSmsTemplate, arg.Name)
+		}
+		if arg.Pattern == "" {
+			continue
+		}
+		_, err := regexp.Compile(arg.Pattern)
+		if err != nil {
+			return fmt.Errorf("%w: 参数 %s 的正则表达式不对 %v", ErrInvalidSmsTemplate, arg.Name, err)
+		}
+	}
+	return nil
+}
+
+func (s *smsTemplateService) Delete(ctx context.Context, id int64) error {
+	defer s.invalidate()
+	return s.repo.Delete(ctx, id)
+}
+
+func (s *smsTemplateService) Get(ctx context.Context, id int64) (domain.SmsTemplate, error) {
+	return s.repo.FindById(ctx, id)
+}
+
+func (s *smsTemplateService) List(ctx context.Context, offset, limit int) ([]domain.SmsTemplate, error) {
+	return s.repo.List(ctx, offset, limit)
+}
+
+func (s *smsTemplateService) SaveProvider(ctx context.Context, tid int64, p domain.SmsProviderTemplate) error {
+	if p.Provider == "" || p.TplId == "" {
+		return fmt.Errorf("%w: 供应商和模板 id 不能为空", ErrInvalidSmsTemplate)
+	}
+	_, err := s.repo.FindById(ctx, tid)
+	if err != nil {
+		return err
+	}
+	p.Status = domain.SmsTemplateStatusPending
+	p.Reason = ""
+	defer s.invalidate()
+	return s.repo.SaveProvider(ctx, tid, p)
+}
+
+func (s *smsTemplateService) DeleteProvider(ctx context.Context, tid int64, provider string) error {
+	defer s.invalidate()
+	return s.repo.DeleteProvider(ctx, tid, provider)
+}
+
+func (s *smsTemplateService) Review(ctx context.Context, tid int64, provider string,
+	approved bool, reason string) error {
+	status := domain.SmsTemplateStatusApproved
+	if !approved {
+		if reason == "" {
+			return fmt.Errorf("%w: 审核不通过要写原因", ErrInvalidSmsTemplate)
+		}
+		status = domain.SmsTemplateStatusRejected
+	}
+	defer s.invalidate()
+	return s.repo.UpdateProviderStatus(ctx, tid, provider, status, reason)
+}
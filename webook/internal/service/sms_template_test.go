@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+	"xiaoweishu/webook/internal/domain"
+	"xiaoweishu/webook/internal/repository"
+	repomocks "xiaoweishu/webook/internal/repository/mocks"
+	"xiaoweishu/webook/internal/service/sms"
+)
+
+func TestSmsTemplateService_Resolve(t *testing.T) {
+	codeArgs := []domain.SmsTemplateArg{{Name: "code", Pattern: `^\d{6}$`, MaxLen: 6}}
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	repo := repomocks.NewMockSmsTemplateRepository(ctrl)
+	// 找到了的会缓存起来，只查一次
+	repo.EXPECT().FindByName(gomock.Any(), "verify_code").Return(domain.SmsTemplate{
+		Name: "verify_code",
+		Args: codeArgs,
+		Providers: []domain.SmsProviderTemplate{
+			{Provider: "tencent", TplId: "123", SignName: "小微书", Status: domain.SmsTemplateStatusApproved},
+			{Provider: "aliyun", TplId: "SMS_1", Status: domain.SmsTemplateStatusPending},
+		},
+	}, nil)
+	repo.EXPECT().FindByName(gomock.Any(), "notice").Return(domain.SmsTemplate{}, repository.ErrSmsTemplateNotFound)
+	repo.EXPECT().FindByName(gomock.Any(), "unknown").Return(domain.SmsTemplate{}, repository.ErrSmsTemplateNotFound)
+	fallbacks := []domain.SmsTemplate{
+		{Name: "verify_code", Args: codeArgs, Providers: []domain.SmsProviderTemplate{
+			{Provider: "tencent", TplId: "1877556"},
+		}},
+		{Name: "notice", Providers: []domain.SmsProviderTemplate{
+			{Provider: "tencent", TplId: "100"},
+		}},
+	}
+	svc := NewSmsTemplateServiceV1(repo, time.Minute, fallbacks)
+	testCases := []struct {
+		name     string
+		provider string
+		tpl      string
+		args     []string
+		wantTpl  sms.Template
+		wantErr  error
+	}{
+		{
+			name:     "审核通过了",
+			provider: "tencent",
+			tpl:      "verify_code",
+			args:     []string{"123456"},
+			// 模板管理里面有的时候不用 fallback
+			wantTpl: sms.Template{Id: "123", SignName: "小微书", ArgNames: []string{"code"}},
+		},
+		{
+			name:     "还没审核通过",
+			provider: "aliyun",
+			tpl:      "verify_code",
+			args:     []string{"123456"},
+			wantErr:  ErrSmsTemplateNotApproved,
+		},
+		{
+			name:     "供应商没有这个模板",
+			provider: "local",
+			tpl:      "verify_code",
+			args:     []string{"123456"},
+			wantErr:  ErrSmsTemplateNotApproved,
+		},
+		{
+			name:     "参数不对",
+			provider: "tencent",
+			tpl:      "verify_code",
+			args:     []string{"1234"},
+			wantErr:  sms.ErrResolveTemplate,
+		},
+		{
+			name:     "模板管理里面没有，用 fallback",
+			provider: "tencent",
+			tpl:      "notice",
+			wantTpl:  sms.Template{Id: "100", ArgNames: []string{}},
+		},
+		{
+			name:     "哪里都没有",
+			provider: "tencent",
+			tpl:      "unknown",
+			wantErr:  ErrSmsTemplateNotFound,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tpl, err := svc.Resolve(context.Background(), tc.provider, tc.tpl, tc.args)
+			if tc.wantErr != nil {
+				assert.True(t, errors.Is(err, tc.wantErr), err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantTpl, tpl)
+		})
+	}
+}
+
+func TestSmsTemplateService_ResolveFallback(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	repo := repomocks.NewMockSmsTemplateRepository(ctrl)
+	gomock.InOrder(
+		// fallback 也缓存起来了，只查一次
+		repo.EXPECT().FindByName(gomock.Any(), "verify_code").
+			Return(domain.SmsTemplate{}, repository.ErrSmsTemplateNotFound),
+		// 管理员在模板管理里面配置了之后，缓存清掉就用模板管理的
+		repo.EXPECT().FindByName(gomock.Any(), "verify_code").Return(domain.SmsTemplate{Name: "verify_code",
+			Providers: []domain.SmsProviderTemplate{
+				{Provider: "tencent", TplId: "2000000", Status: domain.SmsTemplateStatusApproved},
+			}}, nil),
+	)
+	svc := NewSmsTemplateServiceV1(repo, time.Minute, []domain.SmsTemplate{
+		{Name: "verify_code", Args: []domain.SmsTemplateArg{{Name: "code", Pattern: `^\d{6}$`}},
+			Providers: []domain.SmsProviderTemplate{{Provider: "tencent", TplId: "1877556"}}},
+	})
+	tpl, err := svc.Resolve(context.Background(), "tencent", "verify_code", []string{"123456"})
+	require.NoError(t, err)
+	assert.Equal(t, "1877556", tpl.Id)
+	_, err = svc.Resolve(context.Background(), "tencent", "verify_code", []string{"654321"})
+	require.NoError(t, err)
+
+	svc.(*smsTemplateService).invalidate()
+	tpl, err = svc.Resolve(context.Background(), "tencent", "verify_code", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "2000000", tpl.Id)
+}
@@ -0,0 +1,303 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/ecodeclub/ekit/slice"
+	"time"
+	"xiaoweishu/webook/internal/domain"
+	"xiaoweishu/webook/internal/repository"
+	logger2 "xiaoweishu/webook/pkg/logger"
+)
+
+var ErrWorkflowRunNotFound = repository.ErrWorkflowRunNotFound
+
+// WorkflowService 有依赖的任务组成工作流。没有上游、有下游的任务按照 cron 表达式执行的时候，
+// 开始一次工作流的执行，从它出发能到达的任务都是这次执行的步骤。
+// 一个步骤的上游（只算这次执行里面的）都成功了就立刻触发它，有一个步骤失败了，后面的都不再执行
+type WorkflowService interface {
+	// StartStep 任务每次开始执行的时候调用，返回任务所属的工作流执行记录，不在工作流里面的返回 0
+	StartStep(ctx context.Context, j domain.Job) (int64, error)
+	// FinishStep 任务成功了，或者重试次数用完了还是失败的时候调用，j.RunId 是 StartStep 返回的
+	FinishStep(ctx context.Context, j domain.Job, execErr error) error
+	// GetRun 带上所有步骤的状态
+	GetRun(ctx context.Context, id int64) (domain.WorkflowRun, error)
+	ListRuns(ctx context.Context, rootJid int64, offset, limit int) ([]domain.WorkflowRun, error)
+	// Timeout 开始了太久还没结束的执行直接失败，返回失败了多少次执行。
+	// 步骤被暂停、删除，或者一直触发不了的时候，执行会一直停在执行中
+	Timeout(ctx context.Context) (int64, error)
+}
+
+// workflowRunTimeout 默认一次执行最多一天
+const workflowRunTimeout = time.Hour * 24
+
+type workflowService struct {
+	repo    repository.WorkflowRepository
+	jobRepo repository.CronJobRepository
+	l       logger2.LoggerV1
+	timeout time.Duration
+}
+
+func NewWorkflowService(repo repository.WorkflowRepository, jobRepo repository.CronJobRepository,
+	l logger2.LoggerV1) WorkflowService {
+	return NewWorkflowServiceV1(repo, jobRepo, l, workflowRunTimeout)
+}
+
+// NewWorkflowServiceV1 开始了超过 timeout 还没结束的执行会被 Timeout 结束掉
+func NewWorkflowServiceV1(repo repository.WorkflowRepository, jobRepo repository.CronJobRepository,
+	l logger2.LoggerV1, timeout time.Duration) WorkflowService {
+	return &workflowService{
+		repo:    repo,
+		jobRepo: jobRepo,
+		l:       l,
+		timeout: timeout,
+	}
+}
+
+func (s *workflowService) StartStep(ctx context.Context, j domain.Job) (int64, error) {
+	if j.RunId > 0 {
+		//重试的，或者被上游触发的，都属于原来的那次执行
+		continued := j.Retries > 0
+		if !continued {
+			ups, err := s.jobRepo.FindUpstreams(ctx, []int64{j.Id})
+			if err != nil {
+				return 0, err
+			}
+			continued = len(ups[j.Id]) > 0
+		}
+		if !continued {
+			//上一次执行到一半被打断了，比如实例挂了、任务被别人抢走了，接着原来的那次执行
+			interrupted, err := s.interrupted(ctx, j)
+			if err != nil {
+				return 0, err
+			}
+			continued = interrupted
+		}
+		if continued {
+			return j.RunId, s.repo.StartStep(ctx, j.RunId, j.Id)
+		}
+	}
+	return s.createRun(ctx, j)
+}
+
+// interrupted j 在 j.RunId 这次执行里面的步骤还在执行中
+func (s *workflowService) interrupted(ctx context.Context, j domain.Job) (bool, error) {
+	steps, err := s.repo.FindSteps(ctx, j.RunId)
+	if err != nil {
+		return false, err
+	}
+	for _, step := range steps {
+		if step.Jid == j.Id {
+			return step.Status == domain.WorkflowStepStatusRunning, nil
+		}
+	}
+	return false, nil
+}
+
+// createRun j 没有下游的话就不是工作流，返回 0
+func (s *workflowService) createRun(ctx context.Context, j domain.Job) (int64, error) {
+	jids, err := s.reachable(ctx, j.Id)
+	if err != nil || len(jids) == 0 {
+		return 0, err
+	}
+	now := time.Now()
+	steps := []domain.WorkflowStep{{
+		Jid:    j.Id,
+		Name:   j.Name,
+		Status: domain.WorkflowStepStatusRunning,
+		Stime:  now,
+	}}
+	for _, jid := range jids {
+		down, err := s.jobRepo.FindById(ctx, jid)
+		if err != nil {
+			return 0, err
+		}
+		steps = append(steps, domain.WorkflowStep{
+			Jid:    jid,
+			Name:   down.Name,
+			Status: domain.WorkflowStepStatusPending,
+		})
+	}
+	runId, err := s.repo.CreateRun(ctx, domain.WorkflowRun{
+		RootJid: j.Id,
+		Name:    j.Name,
+		Status:  domain.WorkflowRunStatusRunning,
+		Steps:   steps,
+		Stime:   now,
+	})
+	if err != nil {
+		return 0, err
+	}
+	//记在任务上，重试的时候还是这次执行
+	return runId, s.jobRepo.UpdateRunId(ctx, j.Id, j.Version, runId)
+}
+
+// reachable 从 jid 出发能到达的所有下游，不包括 jid 自己
+func (s *workflowService) reachable(ctx context.Context, jid int64) ([]int64, error) {
+	visited := map[int64]struct{}{jid: {}}
+	var res []int64
+	queue := []int64{jid}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		downs, err := s.jobRepo.FindDownstreams(ctx, cur)
+		if err != nil {
+			return nil, err
+		}
+		for _, down := range downs {
+			if _, ok := visited[down]; ok {
+				continue
+			}
+			visited[down] = struct{}{}
+			res = append(res, down)
+			queue = append(queue, down)
+		}
+	}
+	return res, nil
+}
+
+func (s *workflowService) FinishStep(ctx context.Context, j domain.Job, execErr error) error {
+	if j.RunId == 0 {
+		return nil
+	}
+	step := domain.WorkflowStep{
+		RunId:  j.RunId,
+		Jid:    j.Id,
+		Status: domain.WorkflowStepStatusSuccess,
+	}
+	if execErr != nil {
+		step.Status = domain.WorkflowStepStatusFailed
+		step.Err = truncate(execErr.Error(), jobExecutionMaxLen)
+	}
+	err := s.repo.FinishStep(ctx, step)
+	if err != nil {
+		return err
+	}
+	if execErr != nil {
+		return s.repo.FailRun(ctx, j.RunId)
+	}
+	return s.advance(ctx, j.RunId)
+}
+
+// advance 触发所有上游都成功了的步骤，所有步骤都结束了的话这次执行就成功了。
+// 重复触发是安全的，所以几个上游同时成功的时候不需要加锁
+func (s *workflowService) advance(ctx context.Context, runId int64) error {
+	steps, err := s.repo.FindSteps(ctx, runId)
+	if err != nil {
+		return err
+	}
+	status := make(map[int64]domain.WorkflowStepStatus, len(steps))
+	for _, step := range steps {
+		status[step.Jid] = step.Status
+	}
+	pending := slice.FilterMap(steps, func(idx int, src domain.WorkflowStep) (int64, bool) {
+		return src.Jid, src.Status == domain.WorkflowStepStatusPending
+	})
+	if len(pending) == 0 {
+		for _, step := range steps {
+			if !step.Status.Finished() {
+				return nil
+			}
+		}
+		return s.repo.SucceedRun(ctx, runId)
+	}
+	ups, err := s.jobRepo.FindUpstreams(ctx, pending)
+	if err != nil {
+		return err
+	}
+	for _, jid := range pending {
+		if !s.ready(ups[jid], status) {
+			continue
+		}
+		ok, err := s.jobRepo.Trigger(ctx, jid, runId)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			err = s.checkTriggered(ctx, runId, jid)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ready 不在这次执行里面的上游不算
+func (s *workflowService) ready(ups []int64, status map[int64]domain.WorkflowStepStatus) bool {
+	for _, up := range ups {
+		st, ok := status[up]
+		if ok && st != domain.WorkflowStepStatusSuccess {
+			return false
+		}
+	}
+	return true
+}
+
+// checkTriggered 触发失败一般是已经被别的上游触发过了。
+// 任务被删了、暂停了，或者还在执行别的，这一步就执行不了，这次执行也就失败了
+func (s *workflowService) checkTriggered(ctx context.Context, runId int64, jid int64) error {
+	j, err := s.jobRepo.FindById(ctx, jid)
+	var reason string
+	switch {
+	case errors.Is(err, repository.ErrJobNotFound):
+		reason = "任务已经被删除了"
+	case err != nil:
+		return err
+	case j.RunId == runId:
+		return nil
+	case j.Status == domain.JobStatusPaused:
+		reason = "任务被暂停了"
+	case j.Status == domain.JobStatusWaiting:
+		//刚刚执行完释放了，再触发一次
+		ok, err := s.jobRepo.Trigger(ctx, jid, runId)
+		if err != nil || ok {
+			return err
+		}
+		reason = "任务正在执行，不能被触发"
+	default:
+		reason = "任务正在执行，不能被触发"
+	}
+	s.l.Warn("工作流的任务不能被触发", logger2.Int64("runId", runId),
+		logger2.Int64("jid", jid), logger2.String("reason", reason))
+	err = s.repo.FinishStep(ctx, domain.WorkflowStep{
+		RunId:  runId,
+		Jid:    jid,
+		Status: domain.WorkflowStepStatusFailed,
+		Err:    reason,
+	})
+	if err != nil {
+		return err
+	}
+	return s.repo.FailRun(ctx, runId)
+}
+
+func (s *workflowService) Timeout(ctx context.Context) (int64, error) {
+	const batch = 100
+	before := time.Now().Add(-s.timeout)
+	var cnt int64
+	for {
+		runs, err := s.repo.FindStaleRuns(ctx, before, batch)
+		if err != nil {
+			return cnt, err
+		}
+		for _, run := range runs {
+			err = s.repo.TimeoutRun(ctx, run.Id, "工作流执行超时了")
+			if err != nil {
+				return cnt, err
+			}
+			cnt++
+		}
+		if len(runs) < batch {
+			return cnt, nil
+		}
+	}
+}
+
+func (s *workflowService) GetRun(ctx context.Context, id int64) (domain.WorkflowRun, error) {
+	return s.repo.FindRun(ctx, id)
+}
+
+func (s *workflowService) ListRuns(ctx context.Context, rootJid int64, offset, limit int) ([]domain.WorkflowRun, error) {
+	return s.repo.ListRuns(ctx, rootJid, offset, limit)
+}
@@ -0,0 +1,229 @@
+package service
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+	"xiaoweishu/webook/internal/domain"
+	"xiaoweishu/webook/internal/repository"
+	repomocks "xiaoweishu/webook/internal/repository/mocks"
+	"xiaoweishu/webook/pkg/logger"
+)
+
+func TestWorkflowService_FinishStep(t *testing.T) {
+	const runId = 10
+	// 1 -> 2 -> 4，1 -> 3 -> 4
+	ups := map[int64][]int64{2: {1}, 3: {1}, 4: {2, 3}}
+	// waitingFor4 1、2、3 都成功了，只剩 4 还没触发
+	waitingFor4 := []domain.WorkflowStep{
+		{Jid: 1, Status: domain.WorkflowStepStatusSuccess},
+		{Jid: 2, Status: domain.WorkflowStepStatusSuccess},
+		{Jid: 3, Status: domain.WorkflowStepStatusSuccess},
+		{Jid: 4, Status: domain.WorkflowStepStatusPending},
+	}
+	// triggerFailed 3 成功了，触发 4 没成功，findErr 是查询 4 的时候返回的错误
+	triggerFailed := func(ctrl *gomock.Controller, down domain.Job, findErr error) (*repomocks.MockWorkflowRepository,
+		*repomocks.MockCronJobRepository) {
+		repo := repomocks.NewMockWorkflowRepository(ctrl)
+		jobRepo := repomocks.NewMockCronJobRepository(ctrl)
+		repo.EXPECT().FinishStep(gomock.Any(),
+			domain.WorkflowStep{RunId: runId, Jid: 3, Status: domain.WorkflowStepStatusSuccess}).Return(nil)
+		repo.EXPECT().FindSteps(gomock.Any(), int64(runId)).Return(waitingFor4, nil)
+		jobRepo.EXPECT().FindUpstreams(gomock.Any(), []int64{4}).Return(ups, nil)
+		jobRepo.EXPECT().Trigger(gomock.Any(), int64(4), int64(runId)).Return(false, nil)
+		jobRepo.EXPECT().FindById(gomock.Any(), int64(4)).Return(down, findErr)
+		return repo, jobRepo
+	}
+	// stepFailed 4 触发不了，这一步失败，这次执行也就失败了
+	stepFailed := func(repo *repomocks.MockWorkflowRepository, reason string) {
+		repo.EXPECT().FinishStep(gomock.Any(), domain.WorkflowStep{RunId: runId, Jid: 4,
+			Status: domain.WorkflowStepStatusFailed, Err: reason}).Return(nil)
+		repo.EXPECT().FailRun(gomock.Any(), int64(runId)).Return(nil)
+	}
+	testCases := []struct {
+		name    string
+		mock    func(ctrl *gomock.Controller) (repository.WorkflowRepository, repository.CronJobRepository)
+		jid     int64
+		execErr error
+	}{
+		{
+			name: "触发上游都成功了的下游",
+			mock: func(ctrl *gomock.Controller) (repository.WorkflowRepository, repository.CronJobRepository) {
+				repo := repomocks.NewMockWorkflowRepository(ctrl)
+				jobRepo := repomocks.NewMockCronJobRepository(ctrl)
+				repo.EXPECT().FinishStep(gomock.Any(),
+					domain.WorkflowStep{RunId: runId, Jid: 1, Status: domain.WorkflowStepStatusSuccess}).Return(nil)
+				repo.EXPECT().FindSteps(gomock.Any(), int64(runId)).Return([]domain.WorkflowStep{
+					{Jid: 1, Status: domain.WorkflowStepStatusSuccess},
+					{Jid: 2, Status: domain.WorkflowStepStatusPending},
+					{Jid: 3, Status: domain.WorkflowStepStatusPending},
+					{Jid: 4, Status: domain.WorkflowStepStatusPending},
+				}, nil)
+				jobRepo.EXPECT().FindUpstreams(gomock.Any(), []int64{2, 3, 4}).Return(ups, nil)
+				// 4 的上游还没执行完，不触发
+				jobRepo.EXPECT().Trigger(gomock.Any(), int64(2), int64(runId)).Return(true, nil)
+				jobRepo.EXPECT().Trigger(gomock.Any(), int64(3), int64(runId)).Return(true, nil)
+				return repo, jobRepo
+			},
+			jid: 1,
+		},
+		{
+			name: "最后一步成功了",
+			mock: func(ctrl *gomock.Controller) (repository.WorkflowRepository, repository.CronJobRepository) {
+				repo := repomocks.NewMockWorkflowRepository(ctrl)
+				repo.EXPECT().FinishStep(gomock.Any(),
+					domain.WorkflowStep{RunId: runId, Jid: 4, Status: domain.WorkflowStepStatusSuccess}).Return(nil)
+				repo.EXPECT().FindSteps(gomock.Any(), int64(runId)).Return([]domain.WorkflowStep{
+					{Jid: 1, Status: domain.WorkflowStepStatusSuccess},
+					{Jid: 2, Status: domain.WorkflowStepStatusSuccess},
+					{Jid: 3, Status: domain.WorkflowStepStatusSuccess},
+					{Jid: 4, Status: domain.WorkflowStepStatusSuccess},
+				}, nil)
+				repo.EXPECT().SucceedRun(gomock.Any(), int64(runId)).Return(nil)
+				return repo, repomocks.NewMockCronJobRepository(ctrl)
+			},
+			jid: 4,
+		},
+		{
+			name: "有一步失败了",
+			mock: func(ctrl *gomock.Controller) (repository.WorkflowRepository, repository.CronJobRepository) {
+				repo := repomocks.NewMockWorkflowRepository(ctrl)
+				repo.EXPECT().FinishStep(gomock.Any(), domain.WorkflowStep{RunId: runId, Jid: 2,
+					Status: domain.WorkflowStepStatusFailed, Err: assert.AnError.Error()}).Return(nil)
+				repo.EXPECT().FailRun(gomock.Any(), int64(runId)).Return(nil)
+				return repo, repomocks.NewMockCronJobRepository(ctrl)
+			},
+			jid:     2,
+			execErr: assert.AnError,
+		},
+		{
+			name: "已经被别的上游触发过了",
+			mock: func(ctrl *gomock.Controller) (repository.WorkflowRepository, repository.CronJobRepository) {
+				return triggerFailed(ctrl, domain.Job{Id: 4, RunId: runId, Status: domain.JobStatusRunning}, nil)
+			},
+			jid: 3,
+		},
+		{
+			name: "下游被暂停了",
+			mock: func(ctrl *gomock.Controller) (repository.WorkflowRepository, repository.CronJobRepository) {
+				repo, jobRepo := triggerFailed(ctrl, domain.Job{Id: 4, Status: domain.JobStatusPaused}, nil)
+				stepFailed(repo, "任务被暂停了")
+				return repo, jobRepo
+			},
+			jid: 3,
+		},
+		{
+			name: "下游还在执行上一次的",
+			mock: func(ctrl *gomock.Controller) (repository.WorkflowRepository, repository.CronJobRepository) {
+				repo, jobRepo := triggerFailed(ctrl,
+					domain.Job{Id: 4, RunId: runId - 1, Status: domain.JobStatusRunning}, nil)
+				stepFailed(repo, "任务正在执行，不能被触发")
+				return repo, jobRepo
+			},
+			jid: 3,
+		},
+		{
+			name: "下游被删除了",
+			mock: func(ctrl *gomock.Controller) (repository.WorkflowRepository, repository.CronJobRepository) {
+				repo, jobRepo := triggerFailed(ctrl, domain.Job{}, repository.ErrJobNotFound)
+				stepFailed(repo, "任务已经被删除了")
+				return repo, jobRepo
+			},
+			jid: 3,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			repo, jobRepo := tc.mock(ctrl)
+			svc := NewWorkflowService(repo, jobRepo, logger.NewNopLogger())
+			err := svc.FinishStep(context.Background(), domain.Job{Id: tc.jid, RunId: runId}, tc.execErr)
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestWorkflowService_StartStep(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (repository.WorkflowRepository, repository.CronJobRepository)
+		job  domain.Job
+
+		wantRunId int64
+	}{
+		{
+			name: "重试的时候还是原来的那次执行",
+			mock: func(ctrl *gomock.Controller) (repository.WorkflowRepository, repository.CronJobRepository) {
+				repo := repomocks.NewMockWorkflowRepository(ctrl)
+				repo.EXPECT().StartStep(gomock.Any(), int64(10), int64(1)).Return(nil)
+				return repo, repomocks.NewMockCronJobRepository(ctrl)
+			},
+			job:       domain.Job{Id: 1, RunId: 10, Retries: 1},
+			wantRunId: 10,
+		},
+		{
+			name: "被上游触发的",
+			mock: func(ctrl *gomock.Controller) (repository.WorkflowRepository, repository.CronJobRepository) {
+				repo := repomocks.NewMockWorkflowRepository(ctrl)
+				jobRepo := repomocks.NewMockCronJobRepository(ctrl)
+				jobRepo.EXPECT().FindUpstreams(gomock.Any(), []int64{2}).
+					Return(map[int64][]int64{2: {1}}, nil)
+				repo.EXPECT().StartStep(gomock.Any(), int64(10), int64(2)).Return(nil)
+				return repo, jobRepo
+			},
+			job:       domain.Job{Id: 2, RunId: 10},
+			wantRunId: 10,
+		},
+		{
+			name: "上一次被打断了，接着原来的那次执行",
+			mock: func(ctrl *gomock.Controller) (repository.WorkflowRepository, repository.CronJobRepository) {
+				repo := repomocks.NewMockWorkflowRepository(ctrl)
+				jobRepo := repomocks.NewMockCronJobRepository(ctrl)
+				jobRepo.EXPECT().FindUpstreams(gomock.Any(), []int64{1}).Return(map[int64][]int64{}, nil)
+				repo.EXPECT().FindSteps(gomock.Any(), int64(10)).Return([]domain.WorkflowStep{
+					{Jid: 1, Status: domain.WorkflowStepStatusRunning},
+					{Jid: 2, Status: domain.WorkflowStepStatusPending},
+				}, nil)
+				repo.EXPECT().StartStep(gomock.Any(), int64(10), int64(1)).Return(nil)
+				return repo, jobRepo
+			},
+			job:       domain.Job{Id: 1, RunId: 10},
+			wantRunId: 10,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			repo, jobRepo := tc.mock(ctrl)
+			svc := NewWorkflowService(repo, jobRepo, logger.NewNopLogger())
+			runId, err := svc.StartStep(context.Background(), tc.job)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantRunId, runId)
+		})
+	}
+}
+
+func TestWorkflowService_Timeout(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	var stale []domain.WorkflowRun
+	for i := 1; i <= 150; i++ {
+		stale = append(stale, domain.WorkflowRun{Id: int64(i)})
+	}
+	repo := repomocks.NewMockWorkflowRepository(ctrl)
+	// 一批一批地查，查到的不满一批就结束了
+	gomock.InOrder(
+		repo.EXPECT().FindStaleRuns(gomock.Any(), gomock.Any(), 100).Return(stale[:100], nil),
+		repo.EXPECT().FindStaleRuns(gomock.Any(), gomock.Any(), 100).Return(stale[100:], nil),
+	)
+	repo.EXPECT().TimeoutRun(gomock.Any(), gomock.Any(), "工作流执行超时了").Return(nil).Times(150)
+	svc := NewWorkflowServiceV1(repo, repomocks.NewMockCronJobRepository(ctrl), logger.NewNopLogger(), time.Hour)
+	cnt, err := svc.Timeout(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(150), cnt)
+}
@@ -4,6 +4,8 @@ import (
 	"github.com/ecodeclub/ekit/slice"
 	"github.com/gin-gonic/gin"
 	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"net/http"
 	"strconv"
 	"time"
@@ -47,17 +49,18 @@ func (h *ArticleHandler) RegisterRoutes(server *gin.Engine) {
 	pub := g.Group("/pub")
 	pub.GET("/:id", h.PubDetail)
 	pub.POST("/like", h.Like)
+	pub.POST("/react", h.React)
 	pub.POST("/collect", h.Collect)
-	pub.POST("/like100", h.Like100)
 
 }
 
 // 时刻记住web层/handler层是要跟前端打交道的，要的数据都 可以从前端去拿
 func (h *ArticleHandler) Publish(ctx *gin.Context) {
 	type Req struct {
-		Id      int64
-		Title   string
-		Content string
+		Id       int64
+		Title    string
+		Content  string
+		Category string
 	}
 	var req Req
 	err := ctx.Bind(&req)
@@ -66,9 +69,10 @@ func (h *ArticleHandler) Publish(ctx *gin.Context) {
 	}
 	uc := ctx.MustGet("claims").(*ijwt.UserClaims)
 	id, err := h.svc.Publish(ctx, domain.Article{
-		Id:      req.Id,
-		Title:   req.Title,
-		Content: req.Content,
+		Id:       req.Id,
+		Title:    req.Title,
+		Content:  req.Content,
+		Category: req.Category,
 		Author: domain.Author{
 			Id: uc.Uid,
 		},
@@ -92,9 +96,10 @@ func (h *ArticleHandler) Publish(ctx *gin.Context) {
 
 func (h *ArticleHandler) Edit(ctx *gin.Context) {
 	type Req struct {
-		Id      int64
-		Content string
-		Title   string
+		Id       int64
+		Content  string
+		Title    string
+		Category string
 	}
 	var req Req
 	err := ctx.Bind(&req)
@@ -103,9 +108,10 @@ func (h *ArticleHandler) Edit(ctx *gin.Context) {
 	}
 	uc := ctx.MustGet("user").(ijwt.UserClaims)
 	id, err := h.svc.Save(ctx, domain.Article{
-		Id:      req.Id,
-		Content: req.Content,
-		Title:   req.Title,
+		Id:       req.Id,
+		Content:  req.Content,
+		Title:    req.Title,
+		Category: req.Category,
 		Author: domain.Author{
 			Id: uc.Uid,
 		},
@@ -185,6 +191,7 @@ func (h *ArticleHandler) Detail(ctx *gin.Context) {
 		Id:       art.Id,
 		Title:    art.Title,
 		Content:  art.Content,
+		Category: art.Category,
 		AuthorId: art.Author.Id, //这个字段没有也行，创作者不会在意自己的uid
 		Status:   art.Status.ToUint8(),
 		//这是给前端交互的，所以不能直接设置成time.time,需要转换成string
@@ -253,7 +260,10 @@ func (h *ArticleHandler) PubDetail(ctx *gin.Context) {
 	uc := ctx.MustGet("user").(ijwt.UserClaims)
 	eg.Go(func() error {
 		var er error
-		art, er = h.svc.GetPubById(ctx, id, uc.Uid)
+		art, er = h.svc.GetPubById(ctx, id, uc.Uid, domain.ClientInfo{
+			IP:        ctx.ClientIP(),
+			UserAgent: ctx.Request.UserAgent(),
+		})
 		return er
 	})
 	//异步中尽量少一些操作
@@ -299,20 +309,24 @@ func (h *ArticleHandler) PubDetail(ctx *gin.Context) {
 	//}()
 	ctx.JSON(http.StatusOK, Result{
 		Data: ArticleVo{
-			Id:         art.Id,
-			Title:      art.Title,
-			Abstract:   art.Abstract(),
-			Content:    art.Content,
-			AuthorId:   art.Author.Id,
-			AuthorName: art.Author.Name,
-			Status:     art.Status.ToUint8(),
-			Ctime:      art.Ctime.Format(time.DateTime),
-			Utime:      art.Utime.Format(time.DateTime),
-			ReadCnt:    intr.Intr.ReadCnt,
-			LikeCnt:    intr.Intr.LikeCnt,
-			CollectCnt: intr.Intr.CollectCnt,
-			Liked:      intr.Intr.Liked,
-			Collected:  intr.Intr.Collected,
+			Id:           art.Id,
+			Title:        art.Title,
+			Abstract:     art.Abstract(),
+			Content:      art.Content,
+			Category:     art.Category,
+			AuthorId:     art.Author.Id,
+			AuthorName:   art.Author.Name,
+			Status:       art.Status.ToUint8(),
+			Ctime:        art.Ctime.Format(time.DateTime),
+			Utime:        art.Utime.Format(time.DateTime),
+			ReadCnt:      intr.Intr.ReadCnt,
+			UvCnt:        intr.Intr.UvCnt,
+			LikeCnt:      intr.Intr.LikeCnt,
+			CollectCnt:   intr.Intr.CollectCnt,
+			Liked:        intr.Intr.Liked,
+			Collected:    intr.Intr.Collected,
+			Reaction:     intr.Intr.Reaction,
+			ReactionCnts: intr.Intr.ReactionCnts,
 		},
 	})
 
@@ -355,6 +369,55 @@ func (h *ArticleHandler) Like(c *gin.Context) {
 	c.JSON(http.StatusOK, Result{Msg: "ok"})
 }
 
+// React 表态，reaction 为空就是取消表态
+func (h *ArticleHandler) React(c *gin.Context) {
+	type Req struct {
+		Id       int64  `json:"id"`
+		Reaction string `json:"reaction"`
+	}
+	var req Req
+	err := c.Bind(&req)
+	if err != nil {
+		return
+	}
+	uc := c.MustGet("user").(ijwt.UserClaims)
+	if req.Reaction != "" {
+		_, err = h.intrSvc.React(c, &intrv1.ReactRequest{
+			Biz:      h.biz,
+			BizId:    req.Id,
+			Uid:      uc.Uid,
+			Reaction: req.Reaction,
+		})
+	} else {
+		_, err = h.intrSvc.CancelReaction(c, &intrv1.CancelReactionRequest{
+			Biz:   h.biz,
+			BizId: req.Id,
+			Uid:   uc.Uid,
+		})
+	}
+	if status.Code(err) == codes.InvalidArgument {
+		//前端传了不认识的表态，不是系统错误
+		c.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "不支持的表态",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "表态失败",
+		})
+		h.l.Error("表态失败",
+			logger2.Int64("artid", req.Id),
+			logger2.Int64("uid", uc.Uid),
+			logger2.String("reaction", req.Reaction),
+			logger2.Error(err))
+		return
+	}
+	c.JSON(http.StatusOK, Result{Msg: "ok"})
+}
+
 // 收藏和点赞都没有做到限制重复的功能，应该调用liked函数判断用户是否重复点赞
 func (h *ArticleHandler) Collect(ctx *gin.Context) {
 	type Req struct {
@@ -383,27 +446,6 @@ func (h *ArticleHandler) Collect(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, Result{Msg: "ok"})
 }
 
-func (h *ArticleHandler) Like100(ctx *gin.Context) {
-	articles, err := h.svc.Like100(ctx, "article")
-	if err != nil {
-		ctx.JSON(http.StatusOK, Result{
-			Code: 5,
-			Msg:  "系统错误",
-		})
-		return
-	}
-	//转成切片后返回
-	ctx.JSON(http.StatusOK, Result{
-		Data: slice.Map[domain.Like100, ArticleLike100](articles, func(idx int, src domain.Like100) ArticleLike100 {
-			return ArticleLike100{
-				LikeCnt: src.LikeCnt,
-				Biz:     src.Biz,
-				BizId:   src.BizId,
-			}
-		}),
-	})
-}
-
 type Page struct {
 	offset int
 	limit  int
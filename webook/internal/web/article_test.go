@@ -0,0 +1,88 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	intrv1 "xiaoweishu/webook/api/proto/gen/intr/v1"
+	intrmocks "xiaoweishu/webook/api/proto/gen/intr/v1/mocks"
+	ijwt "xiaoweishu/webook/internal/web/jwt"
+	"xiaoweishu/webook/pkg/logger"
+)
+
+func TestArticleHandler_React(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) intrv1.InteractiveServiceClient
+		body string
+
+		wantRes Result
+	}{
+		{
+			name: "表态成功",
+			mock: func(ctrl *gomock.Controller) intrv1.InteractiveServiceClient {
+				svc := intrmocks.NewMockInteractiveServiceClient(ctrl)
+				svc.EXPECT().React(gomock.Any(), &intrv1.ReactRequest{
+					Biz: "article", BizId: 1, Uid: 123, Reaction: "heart",
+				}).Return(&intrv1.ReactResponse{}, nil)
+				return svc
+			},
+			body:    `{"id":1,"reaction":"heart"}`,
+			wantRes: Result{Msg: "ok"},
+		},
+		{
+			name: "不支持的表态",
+			mock: func(ctrl *gomock.Controller) intrv1.InteractiveServiceClient {
+				svc := intrmocks.NewMockInteractiveServiceClient(ctrl)
+				svc.EXPECT().React(gomock.Any(), gomock.Any()).
+					Return(nil, status.Error(codes.InvalidArgument, "不支持的表态"))
+				return svc
+			},
+			body:    `{"id":1,"reaction":"abc"}`,
+			wantRes: Result{Code: 4, Msg: "不支持的表态"},
+		},
+		{
+			name: "系统错误",
+			mock: func(ctrl *gomock.Controller) intrv1.InteractiveServiceClient {
+				svc := intrmocks.NewMockInteractiveServiceClient(ctrl)
+				svc.EXPECT().CancelReaction(gomock.Any(), gomock.Any()).
+					Return(nil, errors.New("网络错误"))
+				return svc
+			},
+			body:    `{"id":1}`,
+			wantRes: Result{Code: 5, Msg: "表态失败"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			h := NewArticleHandler(logger.NewNopLogger(), nil, tc.mock(ctrl))
+			server := gin.Default()
+			server.Use(func(ctx *gin.Context) {
+				ctx.Set("user", ijwt.UserClaims{Uid: 123})
+			})
+			h.RegisterRoutes(server)
+
+			req, err := http.NewRequest(http.MethodPost, "/articles/pub/react", bytes.NewReader([]byte(tc.body)))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+			server.ServeHTTP(resp, req)
+			assert.Equal(t, http.StatusOK, resp.Code)
+			var res Result
+			err = json.Unmarshal(resp.Body.Bytes(), &res)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantRes, res)
+		})
+	}
+}
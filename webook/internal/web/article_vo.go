@@ -5,6 +5,7 @@ type ArticleVo struct {
 	Title      string `json:"title,omitempty"`
 	Abstract   string `json:"abstract,omitempty"`
 	Content    string `json:"content,omitempty"`
+	Category   string `json:"category,omitempty"`
 	AuthorId   int64  `json:"authorId,omitempty"`
 	AuthorName string `json:"authorName,omitempty"`
 	Status     uint8  `json:"status,omitempty"`
@@ -12,13 +13,141 @@ type ArticleVo struct {
 	Utime      string `json:"utime,omitempty"`
 
 	ReadCnt    int64 `json:"readCnt"`
+	UvCnt      int64 `json:"uvCnt"`
 	LikeCnt    int64 `json:"likeCnt"`
 	CollectCnt int64 `json:"collectCnt"`
 	Liked      bool  `json:"liked"`
 	Collected  bool  `json:"collected"`
+	// Reaction 当前用户的表态
+	Reaction string `json:"reaction"`
+	// ReactionCnts 每种表态的人数
+	ReactionCnts map[string]int64 `json:"reactionCnts"`
 }
 type ArticleLike100 struct {
-	LikeCnt int64 `json:"likeCnt"`
-	Biz     string
-	BizId   int64
+	Biz     string `json:"biz"`
+	BizId   int64  `json:"bizId"`
+	LikeCnt int64  `json:"likeCnt"`
+}
+
+type ReadHistoryVo struct {
+	Biz   string `json:"biz"`
+	BizId int64  `json:"bizId"`
+	Rtime string `json:"rtime"`
+}
+
+type ReadHistoryListVo struct {
+	Histories []ReadHistoryVo `json:"histories"`
+	Paused    bool            `json:"paused"`
+}
+
+type StatsBucketVo struct {
+	Time       string `json:"time"`
+	ReadCnt    int64  `json:"readCnt"`
+	LikeCnt    int64  `json:"likeCnt"`
+	CollectCnt int64  `json:"collectCnt"`
+}
+
+type StatsItemVo struct {
+	ArticleId  int64 `json:"articleId"`
+	ReadCnt    int64 `json:"readCnt"`
+	LikeCnt    int64 `json:"likeCnt"`
+	CollectCnt int64 `json:"collectCnt"`
+}
+
+// StatsChangeVo 和上一个周期相比的变化百分比，上一个周期为 0 的时候是 null
+type StatsChangeVo struct {
+	ReadCnt    *float64 `json:"readCnt"`
+	LikeCnt    *float64 `json:"likeCnt"`
+	CollectCnt *float64 `json:"collectCnt"`
+}
+
+type CreatorDashboardVo struct {
+	Trend     []StatsBucketVo `json:"trend"`
+	Total     StatsBucketVo   `json:"total"`
+	PrevTotal StatsBucketVo   `json:"prevTotal"`
+	Change    StatsChangeVo   `json:"change"`
+	Top       []StatsItemVo   `json:"top"`
+}
+
+type RankingArticleVo struct {
+	Id       int64   `json:"id"`
+	Title    string  `json:"title"`
+	AuthorId int64   `json:"authorId"`
+	Score    float64 `json:"score"`
+	Utime    string  `json:"utime"`
+}
+
+// JobVo 管理后台看到的定时任务，时间都是毫秒数
+type JobVo struct {
+	Id         int64  `json:"id"`
+	Name       string `json:"name"`
+	Executor   string `json:"executor"`
+	Expression string `json:"expression"`
+	Cfg        string `json:"cfg"`
+	Status     string `json:"status"`
+	NextTime   int64  `json:"nextTime"`
+	// Owner 正在执行的实例，没有在执行的时候为空
+	Owner      string `json:"owner"`
+	MaxRetries int    `json:"maxRetries"`
+	Retries    int    `json:"retries"`
+	// Timeout 毫秒数
+	Timeout       int64  `json:"timeout"`
+	MisfirePolicy string `json:"misfirePolicy"`
+	Shards        int    `json:"shards"`
+	// Upstreams 上游任务的 id
+	Upstreams []int64 `json:"upstreams"`
+	Ctime     int64   `json:"ctime"`
+	Utime     int64   `json:"utime"`
+}
+
+// JobExecutionVo 任务的一次执行，时间都是毫秒数，还没结束的 etime 是 0
+type JobExecutionVo struct {
+	Id       int64  `json:"id"`
+	Jid      int64  `json:"jid"`
+	Name     string `json:"name"`
+	Instance string `json:"instance"`
+	Attempt  int    `json:"attempt"`
+	Status   string `json:"status"`
+	Err      string `json:"err"`
+	Output   string `json:"output"`
+	Stime    int64  `json:"stime"`
+	Etime    int64  `json:"etime"`
+}
+
+// JobShardsVo 分片任务最近一轮的执行情况，round 是这一轮预定的调度时间
+type JobShardsVo struct {
+	Round   int64        `json:"round"`
+	Total   int          `json:"total"`
+	Success int          `json:"success"`
+	Failed  int          `json:"failed"`
+	Shards  []JobShardVo `json:"shards"`
+}
+
+type JobShardVo struct {
+	Idx     int    `json:"idx"`
+	Status  string `json:"status"`
+	Owner   string `json:"owner"`
+	Retries int    `json:"retries"`
+	Err     string `json:"err"`
+	Utime   int64  `json:"utime"`
+}
+
+// WorkflowRunVo 工作流的一次执行，时间都是毫秒数，没有开始或者没有结束的是 0
+type WorkflowRunVo struct {
+	Id      int64            `json:"id"`
+	RootJid int64            `json:"rootJid"`
+	Name    string           `json:"name"`
+	Status  string           `json:"status"`
+	Stime   int64            `json:"stime"`
+	Etime   int64            `json:"etime"`
+	Steps   []WorkflowStepVo `json:"steps,omitempty"`
+}
+
+type WorkflowStepVo struct {
+	Jid    int64  `json:"jid"`
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Err    string `json:"err"`
+	Stime  int64  `json:"stime"`
+	Etime  int64  `json:"etime"`
 }
@@ -0,0 +1,151 @@
+package web
+
+import (
+	"github.com/ecodeclub/ekit/slice"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"time"
+	intrv1 "xiaoweishu/webook/api/proto/gen/intr/v1"
+	ijwt "xiaoweishu/webook/internal/web/jwt"
+	logger2 "xiaoweishu/webook/pkg/logger"
+)
+
+// ReadHistoryHandler 读者的阅读历史
+type ReadHistoryHandler struct {
+	svc intrv1.ReadHistoryServiceClient
+	l   logger2.LoggerV1
+}
+
+func NewReadHistoryHandler(svc intrv1.ReadHistoryServiceClient, l logger2.LoggerV1) *ReadHistoryHandler {
+	return &ReadHistoryHandler{
+		svc: svc,
+		l:   l,
+	}
+}
+
+func (h *ReadHistoryHandler) RegisterRoutes(server *gin.Engine) {
+	g := server.Group("/history")
+	g.POST("/list", h.List)
+	g.POST("/delete", h.Delete)
+	g.POST("/clear", h.Clear)
+	g.POST("/pause", h.Pause)
+}
+
+func (h *ReadHistoryHandler) List(ctx *gin.Context) {
+	type Req struct {
+		Offset int32 `json:"offset"`
+		Limit  int32 `json:"limit"`
+	}
+	var req Req
+	err := ctx.Bind(&req)
+	if err != nil {
+		return
+	}
+	if req.Limit <= 0 || req.Limit > 100 {
+		req.Limit = 100
+	}
+	uc := ctx.MustGet("user").(ijwt.UserClaims)
+	resp, err := h.svc.List(ctx, &intrv1.ListReadHistoryRequest{
+		Uid:    uc.Uid,
+		Offset: req.Offset,
+		Limit:  req.Limit,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.l.Error("查询阅读历史失败",
+			logger2.Int64("uid", uc.Uid),
+			logger2.Error(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Data: ReadHistoryListVo{
+			Paused: resp.GetPaused(),
+			Histories: slice.Map(resp.GetHistories(), func(idx int, src *intrv1.ReadHistory) ReadHistoryVo {
+				return ReadHistoryVo{
+					Biz:   src.GetBiz(),
+					BizId: src.GetBizId(),
+					Rtime: time.UnixMilli(src.GetRtime()).Format(time.DateTime),
+				}
+			}),
+		},
+	})
+}
+
+func (h *ReadHistoryHandler) Delete(ctx *gin.Context) {
+	type Req struct {
+		Biz   string `json:"biz"`
+		BizId int64  `json:"bizId"`
+	}
+	var req Req
+	err := ctx.Bind(&req)
+	if err != nil {
+		return
+	}
+	uc := ctx.MustGet("user").(ijwt.UserClaims)
+	_, err = h.svc.Delete(ctx, &intrv1.DeleteReadHistoryRequest{
+		Uid:   uc.Uid,
+		Biz:   req.Biz,
+		BizId: req.BizId,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.l.Error("删除阅读历史失败",
+			logger2.Int64("uid", uc.Uid),
+			logger2.String("biz", req.Biz),
+			logger2.Int64("bizId", req.BizId),
+			logger2.Error(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, Result{Msg: "ok"})
+}
+
+func (h *ReadHistoryHandler) Clear(ctx *gin.Context) {
+	uc := ctx.MustGet("user").(ijwt.UserClaims)
+	_, err := h.svc.Clear(ctx, &intrv1.ClearReadHistoryRequest{
+		Uid: uc.Uid,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.l.Error("清空阅读历史失败",
+			logger2.Int64("uid", uc.Uid),
+			logger2.Error(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, Result{Msg: "ok"})
+}
+
+func (h *ReadHistoryHandler) Pause(ctx *gin.Context) {
+	type Req struct {
+		Paused bool `json:"paused"`
+	}
+	var req Req
+	err := ctx.Bind(&req)
+	if err != nil {
+		return
+	}
+	uc := ctx.MustGet("user").(ijwt.UserClaims)
+	_, err = h.svc.Pause(ctx, &intrv1.PauseReadHistoryRequest{
+		Uid:    uc.Uid,
+		Paused: req.Paused,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.l.Error("暂停/恢复阅读历史失败",
+			logger2.Int64("uid", uc.Uid),
+			logger2.Error(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, Result{Msg: "ok"})
+}
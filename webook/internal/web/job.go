@@ -0,0 +1,364 @@
+package web
+
+import (
+	"errors"
+	"github.com/ecodeclub/ekit/slice"
+	"github.com/gin-gonic/gin"
+	"time"
+	"xiaoweishu/webook/internal/domain"
+	"xiaoweishu/webook/internal/service"
+	"xiaoweishu/webook/pkg/ginx"
+	logger2 "xiaoweishu/webook/pkg/logger"
+)
+
+// CronJobHandler 分布式定时任务的管理接口，只挂在 admin server 上，不对外暴露
+type CronJobHandler struct {
+	svc      service.CronJobService
+	execSvc  service.JobExecutionService
+	shardSvc service.JobShardService
+	wfSvc    service.WorkflowService
+	l        logger2.LoggerV1
+}
+
+func NewCronJobHandler(svc service.CronJobService, execSvc service.JobExecutionService,
+	shardSvc service.JobShardService, wfSvc service.WorkflowService, l logger2.LoggerV1) *CronJobHandler {
+	return &CronJobHandler{
+		svc:      svc,
+		execSvc:  execSvc,
+		shardSvc: shardSvc,
+		wfSvc:    wfSvc,
+		l:        l,
+	}
+}
+
+func (h *CronJobHandler) RegisterRoutes(server *gin.RouterGroup) {
+	server.POST("/create", ginx.WrapBody[JobReq](h.Create))
+	server.POST("/update", ginx.WrapBody[JobReq](h.Update))
+	server.POST("/delete", ginx.WrapBody[JobIdReq](h.Delete))
+	server.POST("/pause", ginx.WrapBody[JobIdReq](h.Pause))
+	server.POST("/resume", ginx.WrapBody[JobIdReq](h.Resume))
+	server.POST("/run", ginx.WrapBody[JobIdReq](h.RunNow))
+	server.POST("/list", ginx.WrapBody[JobListReq](h.List))
+	server.POST("/history", ginx.WrapBody[JobHistoryReq](h.History))
+	server.POST("/history/clean", ginx.WrapBody[JobHistoryCleanReq](h.CleanHistory))
+	server.POST("/shards", ginx.WrapBody[JobIdReq](h.Shards))
+	server.POST("/workflow/runs", ginx.WrapBody[WorkflowRunListReq](h.WorkflowRuns))
+	server.POST("/workflow/run", ginx.WrapBody[WorkflowRunReq](h.WorkflowRun))
+}
+
+type JobReq struct {
+	// Id 创建的时候不用传
+	Id         int64  `json:"id"`
+	Name       string `json:"name"`
+	Executor   string `json:"executor"`
+	Expression string `json:"expression"`
+	Cfg        string `json:"cfg"`
+	// MaxRetries 失败之后最多重试几次，不传就是不重试
+	MaxRetries int `json:"maxRetries"`
+	// Timeout 毫秒数，不传就是不限制
+	Timeout int64 `json:"timeout"`
+	// MisfirePolicy fire_once, skip 或者 catch_up，不传就是 fire_once
+	MisfirePolicy string `json:"misfirePolicy"`
+	// Shards 大于 1 的时候每一轮拆成这么多片并行执行
+	Shards int `json:"shards"`
+	// Upstreams 上游任务的 id，有上游的任务不能配置 cron 表达式，上游都成功了才会执行
+	Upstreams []int64 `json:"upstreams"`
+}
+
+type JobIdReq struct {
+	Id int64 `json:"id"`
+}
+
+type JobListReq struct {
+	Offset int `json:"offset"`
+	Limit  int `json:"limit"`
+}
+
+type JobHistoryReq struct {
+	Id     int64 `json:"id"`
+	Offset int   `json:"offset"`
+	Limit  int   `json:"limit"`
+}
+
+type WorkflowRunListReq struct {
+	// Id 工作流第一个任务的 id
+	Id     int64 `json:"id"`
+	Offset int   `json:"offset"`
+	Limit  int   `json:"limit"`
+}
+
+type WorkflowRunReq struct {
+	// Id 执行记录的 id
+	Id int64 `json:"id"`
+}
+
+type JobHistoryCleanReq struct {
+	// Before 毫秒数，删除这个时间之前开始的执行记录
+	Before int64 `json:"before"`
+}
+
+func (h *CronJobHandler) Create(ctx *gin.Context, req JobReq) (ginx.Result, error) {
+	j, ok := h.toDomain(req)
+	if !ok {
+		return ginx.Result{Code: 4, Msg: "misfirePolicy 不对"}, nil
+	}
+	id, err := h.svc.Create(ctx, j)
+	if err != nil {
+		return h.errResult(err)
+	}
+	return ginx.Result{Data: id}, nil
+}
+
+func (h *CronJobHandler) Update(ctx *gin.Context, req JobReq) (ginx.Result, error) {
+	if req.Id <= 0 {
+		return ginx.Result{Code: 4, Msg: "id 不能为空"}, nil
+	}
+	j, ok := h.toDomain(req)
+	if !ok {
+		return ginx.Result{Code: 4, Msg: "misfirePolicy 不对"}, nil
+	}
+	err := h.svc.Update(ctx, j)
+	if err != nil {
+		return h.errResult(err)
+	}
+	return ginx.Result{Msg: "OK"}, nil
+}
+
+func (h *CronJobHandler) Delete(ctx *gin.Context, req JobIdReq) (ginx.Result, error) {
+	err := h.svc.Delete(ctx, req.Id)
+	if err != nil {
+		return h.errResult(err)
+	}
+	return ginx.Result{Msg: "OK"}, nil
+}
+
+func (h *CronJobHandler) Pause(ctx *gin.Context, req JobIdReq) (ginx.Result, error) {
+	err := h.svc.Pause(ctx, req.Id)
+	if err != nil {
+		return h.errResult(err)
+	}
+	return ginx.Result{Msg: "OK"}, nil
+}
+
+func (h *CronJobHandler) Resume(ctx *gin.Context, req JobIdReq) (ginx.Result, error) {
+	err := h.svc.Resume(ctx, req.Id)
+	if err != nil {
+		return h.errResult(err)
+	}
+	return ginx.Result{Msg: "OK"}, nil
+}
+
+func (h *CronJobHandler) RunNow(ctx *gin.Context, req JobIdReq) (ginx.Result, error) {
+	err := h.svc.RunNow(ctx, req.Id)
+	if err != nil {
+		return h.errResult(err)
+	}
+	return ginx.Result{Msg: "OK"}, nil
+}
+
+func (h *CronJobHandler) List(ctx *gin.Context, req JobListReq) (ginx.Result, error) {
+	if req.Limit <= 0 || req.Limit > 100 {
+		req.Limit = 100
+	}
+	jobs, err := h.svc.List(ctx, req.Offset, req.Limit)
+	if err != nil {
+		return h.errResult(err)
+	}
+	return ginx.Result{
+		Data: slice.Map(jobs, func(idx int, src domain.Job) JobVo {
+			return JobVo{
+				Id:            src.Id,
+				Name:          src.Name,
+				Executor:      src.Executor,
+				Expression:    src.Expression,
+				Cfg:           src.Cfg,
+				Status:        src.Status.String(),
+				NextTime:      toNextMilli(src.NextExecTime),
+				Owner:         src.Owner,
+				MaxRetries:    src.MaxRetries,
+				Retries:       src.Retries,
+				Timeout:       src.Timeout.Milliseconds(),
+				MisfirePolicy: src.MisfirePolicy.String(),
+				Shards:        src.Shards,
+				Upstreams:     src.Upstreams,
+				Ctime:         src.Ctime.UnixMilli(),
+				Utime:         src.Utime.UnixMilli(),
+			}
+		}),
+	}, nil
+}
+
+// History 最近开始的在前面
+func (h *CronJobHandler) History(ctx *gin.Context, req JobHistoryReq) (ginx.Result, error) {
+	if req.Limit <= 0 || req.Limit > 100 {
+		req.Limit = 100
+	}
+	execs, err := h.execSvc.List(ctx, req.Id, req.Offset, req.Limit)
+	if err != nil {
+		return ginx.Result{Code: 5, Msg: "系统错误"}, err
+	}
+	return ginx.Result{
+		Data: slice.Map(execs, func(idx int, src domain.JobExecution) JobExecutionVo {
+			vo := JobExecutionVo{
+				Id:       src.Id,
+				Jid:      src.Jid,
+				Name:     src.Name,
+				Instance: src.Instance,
+				Attempt:  src.Attempt,
+				Status:   src.Status.String(),
+				Err:      src.Err,
+				Output:   src.Output,
+				Stime:    src.Stime.UnixMilli(),
+			}
+			if !src.Etime.IsZero() {
+				vo.Etime = src.Etime.UnixMilli()
+			}
+			return vo
+		}),
+	}, nil
+}
+
+// CleanHistory 除了定时清理之外，也可以手动清理
+func (h *CronJobHandler) CleanHistory(ctx *gin.Context, req JobHistoryCleanReq) (ginx.Result, error) {
+	if req.Before <= 0 {
+		return ginx.Result{Code: 4, Msg: "before 不能为空"}, nil
+	}
+	cnt, err := h.execSvc.CleanBefore(ctx, time.UnixMilli(req.Before))
+	if err != nil {
+		return ginx.Result{Code: 5, Msg: "系统错误"}, err
+	}
+	return ginx.Result{Data: cnt}, nil
+}
+
+// Shards 最近一轮的分片和汇总的执行情况
+func (h *CronJobHandler) Shards(ctx *gin.Context, req JobIdReq) (ginx.Result, error) {
+	shards, err := h.shardSvc.Latest(ctx, req.Id)
+	switch {
+	case errors.Is(err, service.ErrJobNotFound):
+		//还没有拆分过
+		return ginx.Result{Data: JobShardsVo{}}, nil
+	case err != nil:
+		return ginx.Result{Code: 5, Msg: "系统错误"}, err
+	}
+	vo := JobShardsVo{
+		Round: shards[0].Round.UnixMilli(),
+		Total: len(shards),
+		Shards: slice.Map(shards, func(idx int, src domain.JobShard) JobShardVo {
+			return JobShardVo{
+				Idx:     src.Idx,
+				Status:  src.Status.String(),
+				Owner:   src.Owner,
+				Retries: src.Retries,
+				Err:     src.Err,
+				Utime:   src.Utime.UnixMilli(),
+			}
+		}),
+	}
+	for _, shard := range shards {
+		switch shard.Status {
+		case domain.JobShardStatusSuccess:
+			vo.Success++
+		case domain.JobShardStatusFailed:
+			vo.Failed++
+		}
+	}
+	return ginx.Result{Data: vo}, nil
+}
+
+// WorkflowRuns 最近开始的在前面，不带步骤
+func (h *CronJobHandler) WorkflowRuns(ctx *gin.Context, req WorkflowRunListReq) (ginx.Result, error) {
+	if req.Limit <= 0 || req.Limit > 100 {
+		req.Limit = 100
+	}
+	runs, err := h.wfSvc.ListRuns(ctx, req.Id, req.Offset, req.Limit)
+	if err != nil {
+		return ginx.Result{Code: 5, Msg: "系统错误"}, err
+	}
+	return ginx.Result{
+		Data: slice.Map(runs, func(idx int, src domain.WorkflowRun) WorkflowRunVo {
+			return h.toWorkflowRunVo(src)
+		}),
+	}, nil
+}
+
+// WorkflowRun 一次执行里面每一步的状态、时间和错误
+func (h *CronJobHandler) WorkflowRun(ctx *gin.Context, req WorkflowRunReq) (ginx.Result, error) {
+	run, err := h.wfSvc.GetRun(ctx, req.Id)
+	switch {
+	case errors.Is(err, service.ErrWorkflowRunNotFound):
+		return ginx.Result{Code: 4, Msg: "执行记录不存在"}, nil
+	case err != nil:
+		return ginx.Result{Code: 5, Msg: "系统错误"}, err
+	}
+	return ginx.Result{Data: h.toWorkflowRunVo(run)}, nil
+}
+
+func (h *CronJobHandler) toWorkflowRunVo(run domain.WorkflowRun) WorkflowRunVo {
+	return WorkflowRunVo{
+		Id:      run.Id,
+		RootJid: run.RootJid,
+		Name:    run.Name,
+		Status:  run.Status.String(),
+		Stime:   toMilli(run.Stime),
+		Etime:   toMilli(run.Etime),
+		Steps: slice.Map(run.Steps, func(idx int, src domain.WorkflowStep) WorkflowStepVo {
+			return WorkflowStepVo{
+				Jid:    src.Jid,
+				Name:   src.Name,
+				Status: src.Status.String(),
+				Err:    src.Err,
+				Stime:  toMilli(src.Stime),
+				Etime:  toMilli(src.Etime),
+			}
+		}),
+	}
+}
+
+// toMilli 零值转成 0
+func toMilli(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixMilli()
+}
+
+// toNextMilli 只由上游触发的任务没有下一次调度时间，转成 0
+func toNextMilli(t time.Time) int64 {
+	if t.Equal(domain.JobNeverExecTime) {
+		return 0
+	}
+	return t.UnixMilli()
+}
+
+// errResult 输入有问题的返回 4，其它的都是系统错误
+func (h *CronJobHandler) errResult(err error) (ginx.Result, error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidJob):
+		return ginx.Result{Code: 4, Msg: err.Error()}, nil
+	case errors.Is(err, service.ErrJobNotFound):
+		return ginx.Result{Code: 4, Msg: "任务不存在"}, nil
+	case errors.Is(err, service.ErrJobDuplicateName):
+		return ginx.Result{Code: 4, Msg: "任务名字已经被使用"}, nil
+	case errors.Is(err, service.ErrJobStatusConflict):
+		return ginx.Result{Code: 4, Msg: "任务当前的状态不能执行这个操作"}, nil
+	default:
+		return ginx.Result{Code: 5, Msg: "系统错误"}, err
+	}
+}
+
+// toDomain misfirePolicy 不认识的时候返回 false
+func (h *CronJobHandler) toDomain(req JobReq) (domain.Job, bool) {
+	policy, ok := domain.ParseJobMisfirePolicy(req.MisfirePolicy)
+	return domain.Job{
+		Id:            req.Id,
+		Name:          req.Name,
+		Executor:      req.Executor,
+		Expression:    req.Expression,
+		Cfg:           req.Cfg,
+		MaxRetries:    req.MaxRetries,
+		Timeout:       time.Duration(req.Timeout) * time.Millisecond,
+		MisfirePolicy: policy,
+		Shards:        req.Shards,
+		Upstreams:     req.Upstreams,
+	}, ok
+}
@@ -0,0 +1,80 @@
+package web
+
+import (
+	"github.com/ecodeclub/ekit/slice"
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"net/http"
+	intrv1 "xiaoweishu/webook/api/proto/gen/intr/v1"
+	logger2 "xiaoweishu/webook/pkg/logger"
+)
+
+// LikeRankHandler 文章点赞榜
+type LikeRankHandler struct {
+	svc intrv1.LikeRankServiceClient
+	l   logger2.LoggerV1
+}
+
+func NewLikeRankHandler(svc intrv1.LikeRankServiceClient, l logger2.LoggerV1) *LikeRankHandler {
+	return &LikeRankHandler{
+		svc: svc,
+		l:   l,
+	}
+}
+
+func (h *LikeRankHandler) RegisterRoutes(server *gin.Engine) {
+	server.POST("/articles/pub/like100", h.Like100)
+}
+
+func (h *LikeRankHandler) Like100(ctx *gin.Context) {
+	type Req struct {
+		// Window day、week 或者 all，默认是总榜
+		Window string `json:"window"`
+		Offset int32  `json:"offset"`
+		Limit  int32  `json:"limit"`
+	}
+	var req Req
+	err := ctx.Bind(&req)
+	if err != nil {
+		return
+	}
+	if req.Window == "" {
+		req.Window = "all"
+	}
+	if req.Limit <= 0 || req.Limit > 100 {
+		req.Limit = 100
+	}
+	resp, err := h.svc.TopN(ctx, &intrv1.LikeRankTopNRequest{
+		Biz:    "article",
+		Window: req.Window,
+		Offset: req.Offset,
+		Limit:  req.Limit,
+	})
+	if status.Code(err) == codes.InvalidArgument {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "输入有误",
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.l.Error("查询点赞榜失败",
+			logger2.String("window", req.Window),
+			logger2.Error(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Data: slice.Map(resp.GetItems(), func(idx int, src *intrv1.LikeRankItem) ArticleLike100 {
+			return ArticleLike100{
+				Biz:     src.GetBiz(),
+				BizId:   src.GetBizId(),
+				LikeCnt: src.GetLikeCnt(),
+			}
+		}),
+	})
+}
@@ -0,0 +1,139 @@
+package web
+
+import (
+	"errors"
+	"github.com/ecodeclub/ekit/slice"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"time"
+	"xiaoweishu/webook/internal/domain"
+	"xiaoweishu/webook/internal/service"
+	logger2 "xiaoweishu/webook/pkg/logger"
+)
+
+// RankingHandler 热榜
+type RankingHandler struct {
+	svc service.RankingService
+	l   logger2.LoggerV1
+}
+
+func NewRankingHandler(svc service.RankingService, l logger2.LoggerV1) *RankingHandler {
+	return &RankingHandler{
+		svc: svc,
+		l:   l,
+	}
+}
+
+func (h *RankingHandler) RegisterRoutes(server *gin.Engine) {
+	g := server.Group("/ranking")
+	g.GET("", h.TopN)
+	g.GET("/:board", h.Board)
+}
+
+// RegisterAdminRoutes 试算要扫全表，只挂在 admin server 上
+func (h *RankingHandler) RegisterAdminRoutes(server *gin.RouterGroup) {
+	server.POST("/dry_run", h.DryRun)
+}
+
+// TopN 总榜，计算热榜的任务定时刷新
+func (h *RankingHandler) TopN(ctx *gin.Context) {
+	arts, err := h.svc.GetTopN(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.l.Error("查询热榜失败", logger2.Error(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Data: slice.Map(arts, func(idx int, src domain.Article) ArticleVo {
+			return h.toVo(src)
+		}),
+	})
+}
+
+// Board 日榜、周榜、分类榜这些
+func (h *RankingHandler) Board(ctx *gin.Context) {
+	board := ctx.Param("board")
+	arts, err := h.svc.GetBoard(ctx, board)
+	if errors.Is(err, service.ErrUnknownRankingBoard) {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "没有这个榜",
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.l.Error("查询热榜失败",
+			logger2.String("board", board),
+			logger2.Error(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Data: slice.Map(arts, func(idx int, src domain.Article) ArticleVo {
+			return h.toVo(src)
+		}),
+	})
+}
+
+func (h *RankingHandler) toVo(art domain.Article) ArticleVo {
+	return ArticleVo{
+		Id:       art.Id,
+		Title:    art.Title,
+		Abstract: art.Abstract(),
+		Category: art.Category,
+		AuthorId: art.Author.Id,
+		Utime:    art.Utime.Format(time.DateTime),
+	}
+}
+
+// DryRun 用候选的算法算一遍热榜，用来和线上的算法对比，不会影响线上的热榜
+func (h *RankingHandler) DryRun(ctx *gin.Context) {
+	type Req struct {
+		Strategy service.RankingStrategyConfig `json:"strategy"`
+		N        int                           `json:"n"`
+	}
+	var req Req
+	err := ctx.Bind(&req)
+	if err != nil {
+		return
+	}
+	if req.N <= 0 || req.N > 100 {
+		req.N = 100
+	}
+	strategy, err := service.NewRankingStrategy(req.Strategy)
+	if errors.Is(err, service.ErrInvalidRankingStrategy) {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  err.Error(),
+		})
+		return
+	}
+	scores, err := h.svc.DryRun(ctx, strategy, req.N)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.l.Error("试算热榜失败",
+			logger2.String("strategy", req.Strategy.Name),
+			logger2.Error(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Data: slice.Map(scores, func(idx int, src service.RankingScore) RankingArticleVo {
+			return RankingArticleVo{
+				Id:       src.Art.Id,
+				Title:    src.Art.Title,
+				AuthorId: src.Art.Author.Id,
+				Score:    src.Score,
+				Utime:    src.Art.Utime.Format(time.DateTime),
+			}
+		}),
+	})
+}
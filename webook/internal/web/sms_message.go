@@ -0,0 +1,213 @@
+package web
+
+import (
+	"crypto/subtle"
+	"github.com/ecodeclub/ekit/slice"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+	"time"
+	"xiaoweishu/webook/internal/domain"
+	"xiaoweishu/webook/internal/service"
+	"xiaoweishu/webook/internal/service/sms"
+	"xiaoweishu/webook/pkg/ginx"
+	logger2 "xiaoweishu/webook/pkg/logger"
+)
+
+// SmsReceiptHandler 接收短信供应商推过来的回执，挂在对外的 server 上。
+// 推送的地址是 /sms/receipts/{供应商的名字}?token=xxx，这个地址不需要登录，
+// 所以一定要配置 token，供应商自己带了签名的还要验签
+type SmsReceiptHandler struct {
+	svc service.SmsMessageService
+	// parsers 供应商的名字到 parser
+	parsers map[string]sms.ReceiptParser
+	// token 为空的时候不接收推过来的回执，只能主动去拉
+	token string
+	l     logger2.LoggerV1
+}
+
+func NewSmsReceiptHandler(svc service.SmsMessageService, parsers map[string]sms.ReceiptParser,
+	token string, l logger2.LoggerV1) *SmsReceiptHandler {
+	return &SmsReceiptHandler{
+		svc:     svc,
+		parsers: parsers,
+		token:   token,
+		l:       l,
+	}
+}
+
+func (h *SmsReceiptHandler) RegisterRoutes(server *gin.Engine) {
+	if h.token == "" {
+		h.l.Warn("没有配置接收短信回执的 token，不接收供应商推过来的回执")
+		return
+	}
+	server.POST("/sms/receipts/:provider", h.Receive)
+}
+
+// Receive 供应商要求的返回格式各不相同，所以不用 ginx 的 Result。
+// 处理失败返回 500，供应商会重新推送
+func (h *SmsReceiptHandler) Receive(ctx *gin.Context) {
+	provider := ctx.Param("provider")
+	parser, ok := h.parsers[provider]
+	if !ok {
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	if h.token == "" || subtle.ConstantTimeCompare([]byte(ctx.Query("token")), []byte(h.token)) != 1 {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	body, err := io.ReadAll(ctx.Request.Body)
+	if err != nil {
+		ctx.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+	if verifier, ok := parser.(sms.ReceiptVerifier); ok {
+		err = verifier.VerifyReceipts(ctx.Request.Header, body)
+		if err != nil {
+			h.l.Warn("短信回执验签失败", logger2.String("provider", provider), logger2.Error(err))
+			ctx.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+	}
+	receipts, ack, err := parser.ParseReceipts(body)
+	if err != nil {
+		h.l.Warn("解析短信回执失败", logger2.String("provider", provider), logger2.Error(err))
+		ctx.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+	err = h.svc.HandleReceipts(ctx, provider, receipts)
+	if err != nil {
+		h.l.Error("处理短信回执失败", logger2.String("provider", provider), logger2.Error(err))
+		ctx.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	ctx.JSON(http.StatusOK, ack)
+}
+
+// SmsMessageHandler 查询发出去的短信和送达率，只挂在 admin server 上
+type SmsMessageHandler struct {
+	svc service.SmsMessageService
+	l   logger2.LoggerV1
+}
+
+func NewSmsMessageHandler(svc service.SmsMessageService, l logger2.LoggerV1) *SmsMessageHandler {
+	return &SmsMessageHandler{
+		svc: svc,
+		l:   l,
+	}
+}
+
+func (h *SmsMessageHandler) RegisterRoutes(server *gin.RouterGroup) {
+	server.POST("/list", ginx.WrapBody[SmsMessageListReq](h.List))
+	server.POST("/stats", ginx.WrapBody[SmsMessageStatsReq](h.Stats))
+}
+
+type SmsMessageListReq struct {
+	// Phone 为空就是不限手机号
+	Phone string `json:"phone"`
+	// Start 和 End 是发送时间的毫秒数，不传就是最近一天
+	Start  int64 `json:"start"`
+	End    int64 `json:"end"`
+	Offset int   `json:"offset"`
+	Limit  int   `json:"limit"`
+}
+
+type SmsMessageStatsReq struct {
+	// Start 和 End 是发送时间的毫秒数，不传就是最近一天
+	Start int64 `json:"start"`
+	End   int64 `json:"end"`
+}
+
+type SmsMessageVo struct {
+	Id       int64  `json:"id"`
+	Provider string `json:"provider"`
+	BizId    string `json:"bizId"`
+	Phone    string `json:"phone"`
+	TplName  string `json:"tplName"`
+	// Status sent, delivered 或者 failed
+	Status     string `json:"status"`
+	Reason     string `json:"reason"`
+	ReportTime int64  `json:"reportTime"`
+	Ctime      int64  `json:"ctime"`
+	Utime      int64  `json:"utime"`
+}
+
+type SmsDeliveryStatsVo struct {
+	Provider     string  `json:"provider"`
+	Total        int64   `json:"total"`
+	Sent         int64   `json:"sent"`
+	Delivered    int64   `json:"delivered"`
+	Failed       int64   `json:"failed"`
+	DeliveryRate float64 `json:"deliveryRate"`
+}
+
+func (h *SmsMessageHandler) List(ctx *gin.Context, req SmsMessageListReq) (ginx.Result, error) {
+	if req.Limit <= 0 || req.Limit > 100 {
+		req.Limit = 100
+	}
+	start, end := h.timeRange(req.Start, req.End)
+	msgs, err := h.svc.List(ctx, domain.SmsMessageQuery{
+		Phone:  req.Phone,
+		Start:  start,
+		End:    end,
+		Offset: req.Offset,
+		Limit:  req.Limit,
+	})
+	if err != nil {
+		return ginx.Result{Code: 5, Msg: "系统错误"}, err
+	}
+	return ginx.Result{
+		Data: slice.Map(msgs, func(idx int, src domain.SmsMessage) SmsMessageVo {
+			var reportTime int64
+			if !src.ReportTime.IsZero() {
+				reportTime = src.ReportTime.UnixMilli()
+			}
+			return SmsMessageVo{
+				Id:         src.Id,
+				Provider:   src.Provider,
+				BizId:      src.BizId,
+				Phone:      src.Phone,
+				TplName:    src.TplName,
+				Status:     src.Status.String(),
+				Reason:     src.Reason,
+				ReportTime: reportTime,
+				Ctime:      src.Ctime.UnixMilli(),
+				Utime:      src.Utime.UnixMilli(),
+			}
+		}),
+	}, nil
+}
+
+func (h *SmsMessageHandler) Stats(ctx *gin.Context, req SmsMessageStatsReq) (ginx.Result, error) {
+	start, end := h.timeRange(req.Start, req.End)
+	stats, err := h.svc.Stats(ctx, start, end)
+	if err != nil {
+		return ginx.Result{Code: 5, Msg: "系统错误"}, err
+	}
+	return ginx.Result{
+		Data: slice.Map(stats, func(idx int, src domain.SmsDeliveryStats) SmsDeliveryStatsVo {
+			return SmsDeliveryStatsVo{
+				Provider:     src.Provider,
+				Total:        src.Total(),
+				Sent:         src.Sent,
+				Delivered:    src.Delivered,
+				Failed:       src.Failed,
+				DeliveryRate: src.DeliveryRate(),
+			}
+		}),
+	}, nil
+}
+
+// timeRange 没有传结束时间就是现在，没有传开始时间就是结束时间的前一天
+func (h *SmsMessageHandler) timeRange(start, end int64) (time.Time, time.Time) {
+	endTime := time.Now()
+	if end > 0 {
+		endTime = time.UnixMilli(end)
+	}
+	startTime := endTime.Add(-time.Hour * 24)
+	if start > 0 {
+		startTime = time.UnixMilli(start)
+	}
+	return startTime, endTime
+}
@@ -0,0 +1,105 @@
+package web
+
+import (
+	"bytes"
+	"encoding/hex"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	svcmocks "xiaoweishu/webook/internal/service/mocks"
+	"xiaoweishu/webook/internal/service/sms"
+	"xiaoweishu/webook/internal/service/sms/webhook"
+	"xiaoweishu/webook/pkg/logger"
+)
+
+func TestSmsReceiptHandler_Receive(t *testing.T) {
+	body := []byte(`[{"bizId":"biz-1","number":"15212345678","status":"DELIVERED","time":1714528805000}]`)
+	signature := hex.EncodeToString(webhook.Sign("gw-token", body))
+	testCases := []struct {
+		name      string
+		token     string
+		url       string
+		signature string
+
+		wantCode     int
+		wantReceipts int
+	}{
+		{
+			name:         "token 和签名都对",
+			token:        "receipt-token",
+			url:          "/sms/receipts/gw?token=receipt-token",
+			signature:    signature,
+			wantCode:     http.StatusOK,
+			wantReceipts: 1,
+		},
+		{
+			name:      "没有配置 token，不接收推过来的回执",
+			url:       "/sms/receipts/gw?token=",
+			signature: signature,
+			wantCode:  http.StatusNotFound,
+		},
+		{
+			name:      "token 不对",
+			token:     "receipt-token",
+			url:       "/sms/receipts/gw?token=abc",
+			signature: signature,
+			wantCode:  http.StatusUnauthorized,
+		},
+		{
+			name:      "签名不对",
+			token:     "receipt-token",
+			url:       "/sms/receipts/gw?token=receipt-token",
+			signature: hex.EncodeToString(webhook.Sign("abc", body)),
+			wantCode:  http.StatusUnauthorized,
+		},
+		{
+			name:      "未知的供应商",
+			token:     "receipt-token",
+			url:       "/sms/receipts/unknown?token=receipt-token",
+			signature: signature,
+			wantCode:  http.StatusNotFound,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := svcmocks.NewMockSmsMessageService(ctrl)
+			svc.EXPECT().HandleReceipts(gomock.Any(), "gw", gomock.Cond(func(x any) bool {
+				receipts := x.([]sms.Receipt)
+				return len(receipts) == 1 && receipts[0].BizId == "biz-1"
+			})).Return(nil).Times(tc.wantReceipts)
+			gw := webhook.NewService(http.DefaultClient, "gw", "http://localhost/send", "gw-token", nil)
+			h := NewSmsReceiptHandler(svc, map[string]sms.ReceiptParser{"gw": gw}, tc.token, logger.NewNopLogger())
+			server := gin.Default()
+			h.RegisterRoutes(server)
+
+			req, err := http.NewRequest(http.MethodPost, tc.url, bytes.NewReader(body))
+			assert.NoError(t, err)
+			req.Header.Set(webhook.SignatureHeader, tc.signature)
+			resp := httptest.NewRecorder()
+			server.ServeHTTP(resp, req)
+			assert.Equal(t, tc.wantCode, resp.Code)
+		})
+	}
+}
+
+// TestSmsReceiptHandler_EmptyToken 直接调用 Receive 也不能绕过 token
+func TestSmsReceiptHandler_EmptyToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	// 不会处理回执
+	svc := svcmocks.NewMockSmsMessageService(ctrl)
+	parser := webhook.NewService(http.DefaultClient, "gw", "http://localhost/send", "", nil)
+	h := NewSmsReceiptHandler(svc, map[string]sms.ReceiptParser{"gw": parser}, "", logger.NewNopLogger())
+	server := gin.Default()
+	server.POST("/sms/receipts/:provider", h.Receive)
+	req, err := http.NewRequest(http.MethodPost, "/sms/receipts/gw", bytes.NewReader([]byte(`[]`)))
+	assert.NoError(t, err)
+	resp := httptest.NewRecorder()
+	server.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+}
@@ -0,0 +1,83 @@
+package web
+
+import (
+	"errors"
+	"github.com/ecodeclub/ekit/slice"
+	"github.com/gin-gonic/gin"
+	"xiaoweishu/webook/internal/service/sms/selector"
+	"xiaoweishu/webook/pkg/ginx"
+	logger2 "xiaoweishu/webook/pkg/logger"
+)
+
+// SmsProviderHandler 查看短信供应商的健康状况和熔断器，只挂在 admin server 上
+type SmsProviderHandler struct {
+	svc *selector.Service
+	l   logger2.LoggerV1
+}
+
+func NewSmsProviderHandler(svc *selector.Service, l logger2.LoggerV1) *SmsProviderHandler {
+	return &SmsProviderHandler{
+		svc: svc,
+		l:   l,
+	}
+}
+
+func (h *SmsProviderHandler) RegisterRoutes(server *gin.RouterGroup) {
+	server.GET("/list", ginx.Wrap(h.List))
+	server.POST("/reset", ginx.WrapBody[SmsProviderResetReq](h.Reset))
+}
+
+type SmsProviderResetReq struct {
+	Name string `json:"name"`
+}
+
+type SmsProviderStateVo struct {
+	Name string `json:"name"`
+	// Circuit closed, half-open 或者 open
+	Circuit   string  `json:"circuit"`
+	Requests  int     `json:"requests"`
+	Failures  int     `json:"failures"`
+	ErrorRate float64 `json:"errorRate"`
+	// AvgLatency 毫秒
+	AvgLatency int64   `json:"avgLatency"`
+	Cost       float64 `json:"cost"`
+	Health     float64 `json:"health"`
+	Score      float64 `json:"score"`
+	// OpenedAt 最近一次熔断的时间，没有熔断过是 0
+	OpenedAt int64 `json:"openedAt"`
+}
+
+func (h *SmsProviderHandler) List(ctx *gin.Context) (ginx.Result, error) {
+	return ginx.Result{
+		Data: slice.Map(h.svc.States(), func(idx int, src selector.ProviderState) SmsProviderStateVo {
+			var openedAt int64
+			if !src.OpenedAt.IsZero() {
+				openedAt = src.OpenedAt.UnixMilli()
+			}
+			return SmsProviderStateVo{
+				Name:       src.Name,
+				Circuit:    src.Circuit.String(),
+				Requests:   src.Requests,
+				Failures:   src.Failures,
+				ErrorRate:  src.ErrorRate,
+				AvgLatency: src.AvgLatency.Milliseconds(),
+				Cost:       src.Cost,
+				Health:     src.Health,
+				Score:      src.Score,
+				OpenedAt:   openedAt,
+			}
+		}),
+	}, nil
+}
+
+// Reset 手动关闭熔断器
+func (h *SmsProviderHandler) Reset(ctx *gin.Context, req SmsProviderResetReq) (ginx.Result, error) {
+	err := h.svc.Reset(req.Name)
+	if errors.Is(err, selector.ErrUnknownProvider) {
+		return ginx.Result{Code: 4, Msg: "短信供应商不存在"}, nil
+	}
+	if err != nil {
+		return ginx.Result{Code: 5, Msg: "系统错误"}, err
+	}
+	return ginx.Result{Msg: "OK"}, nil
+}
@@ -0,0 +1,235 @@
+package web
+
+import (
+	"errors"
+	"github.com/ecodeclub/ekit/slice"
+	"github.com/gin-gonic/gin"
+	"xiaoweishu/webook/internal/domain"
+	"xiaoweishu/webook/internal/service"
+	"xiaoweishu/webook/pkg/ginx"
+	logger2 "xiaoweishu/webook/pkg/logger"
+)
+
+// SmsTemplateHandler 短信模板的管理接口，只挂在 admin server 上
+type SmsTemplateHandler struct {
+	svc service.SmsTemplateService
+	l   logger2.LoggerV1
+}
+
+func NewSmsTemplateHandler(svc service.SmsTemplateService, l logger2.LoggerV1) *SmsTemplateHandler {
+	return &SmsTemplateHandler{
+		svc: svc,
+		l:   l,
+	}
+}
+
+func (h *SmsTemplateHandler) RegisterRoutes(server *gin.RouterGroup) {
+	server.POST("/create", ginx.WrapBody[SmsTemplateReq](h.Create))
+	server.POST("/update", ginx.WrapBody[SmsTemplateReq](h.Update))
+	server.POST("/delete", ginx.WrapBody[SmsTemplateIdReq](h.Delete))
+	server.POST("/detail", ginx.WrapBody[SmsTemplateIdReq](h.Detail))
+	server.POST("/list", ginx.WrapBody[SmsTemplateListReq](h.List))
+	server.POST("/provider/save", ginx.WrapBody[SmsProviderTemplateReq](h.SaveProvider))
+	server.POST("/provider/delete", ginx.WrapBody[SmsProviderTemplateIdReq](h.DeleteProvider))
+	server.POST("/provider/review", ginx.WrapBody[SmsProviderTemplateReviewReq](h.Review))
+}
+
+type SmsTemplateReq struct {
+	// Id 创建的时候不用传
+	Id int64 `json:"id"`
+	// Name 业务方发短信的时候用的名字，创建之后不能修改
+	Name    string              `json:"name"`
+	Content string              `json:"content"`
+	Args    []SmsTemplateArgDTO `json:"args"`
+}
+
+type SmsTemplateArgDTO struct {
+	Name    string `json:"name"`
+	Pattern string `json:"pattern"`
+	MaxLen  int    `json:"maxLen"`
+}
+
+type SmsTemplateIdReq struct {
+	Id int64 `json:"id"`
+}
+
+type SmsTemplateListReq struct {
+	Offset int `json:"offset"`
+	Limit  int `json:"limit"`
+}
+
+type SmsProviderTemplateReq struct {
+	// Tid 短信模板的 id
+	Tid      int64  `json:"tid"`
+	Provider string `json:"provider"`
+	TplId    string `json:"tplId"`
+	// SignName 不传就用供应商默认的签名
+	SignName string `json:"signName"`
+}
+
+type SmsProviderTemplateIdReq struct {
+	Tid      int64  `json:"tid"`
+	Provider string `json:"provider"`
+}
+
+type SmsProviderTemplateReviewReq struct {
+	Tid      int64  `json:"tid"`
+	Provider string `json:"provider"`
+	Approved bool   `json:"approved"`
+	// Reason 不通过的时候必须写
+	Reason string `json:"reason"`
+}
+
+type SmsTemplateVo struct {
+	Id        int64                   `json:"id"`
+	Name      string                  `json:"name"`
+	Content   string                  `json:"content"`
+	Args      []SmsTemplateArgDTO     `json:"args"`
+	Providers []SmsProviderTemplateVo `json:"providers"`
+	Ctime     int64                   `json:"ctime"`
+	Utime     int64                   `json:"utime"`
+}
+
+type SmsProviderTemplateVo struct {
+	Provider string `json:"provider"`
+	TplId    string `json:"tplId"`
+	SignName string `json:"signName"`
+	// Status pending, approved 或者 rejected
+	Status string `json:"status"`
+	Reason string `json:"reason"`
+	Utime  int64  `json:"utime"`
+}
+
+func (h *SmsTemplateHandler) Create(ctx *gin.Context, req SmsTemplateReq) (ginx.Result, error) {
+	id, err := h.svc.Create(ctx, h.toDomain(req))
+	if err != nil {
+		return h.errResult(err)
+	}
+	return ginx.Result{Data: id}, nil
+}
+
+func (h *SmsTemplateHandler) Update(ctx *gin.Context, req SmsTemplateReq) (ginx.Result, error) {
+	if req.Id <= 0 {
+		return ginx.Result{Code: 4, Msg: "id 不能为空"}, nil
+	}
+	err := h.svc.Update(ctx, h.toDomain(req))
+	if err != nil {
+		return h.errResult(err)
+	}
+	return ginx.Result{Msg: "OK"}, nil
+}
+
+func (h *SmsTemplateHandler) Delete(ctx *gin.Context, req SmsTemplateIdReq) (ginx.Result, error) {
+	err := h.svc.Delete(ctx, req.Id)
+	if err != nil {
+		return h.errResult(err)
+	}
+	return ginx.Result{Msg: "OK"}, nil
+}
+
+func (h *SmsTemplateHandler) Detail(ctx *gin.Context, req SmsTemplateIdReq) (ginx.Result, error) {
+	t, err := h.svc.Get(ctx, req.Id)
+	if err != nil {
+		return h.errResult(err)
+	}
+	return ginx.Result{Data: h.toVo(t)}, nil
+}
+
+func (h *SmsTemplateHandler) List(ctx *gin.Context, req SmsTemplateListReq) (ginx.Result, error) {
+	if req.Limit <= 0 || req.Limit > 100 {
+		req.Limit = 100
+	}
+	ts, err := h.svc.List(ctx, req.Offset, req.Limit)
+	if err != nil {
+		return h.errResult(err)
+	}
+	return ginx.Result{
+		Data: slice.Map(ts, func(idx int, src domain.SmsTemplate) SmsTemplateVo {
+			return h.toVo(src)
+		}),
+	}, nil
+}
+
+func (h *SmsTemplateHandler) SaveProvider(ctx *gin.Context, req SmsProviderTemplateReq) (ginx.Result, error) {
+	err := h.svc.SaveProvider(ctx, req.Tid, domain.SmsProviderTemplate{
+		Provider: req.Provider,
+		TplId:    req.TplId,
+		SignName: req.SignName,
+	})
+	if err != nil {
+		return h.errResult(err)
+	}
+	return ginx.Result{Msg: "OK"}, nil
+}
+
+func (h *SmsTemplateHandler) DeleteProvider(ctx *gin.Context, req SmsProviderTemplateIdReq) (ginx.Result, error) {
+	err := h.svc.DeleteProvider(ctx, req.Tid, req.Provider)
+	if err != nil {
+		return h.errResult(err)
+	}
+	return ginx.Result{Msg: "OK"}, nil
+}
+
+func (h *SmsTemplateHandler) Review(ctx *gin.Context, req SmsProviderTemplateReviewReq) (ginx.Result, error) {
+	err := h.svc.Review(ctx, req.Tid, req.Provider, req.Approved, req.Reason)
+	if err != nil {
+		return h.errResult(err)
+	}
+	return ginx.Result{Msg: "OK"}, nil
+}
+
+// errResult 输入有问题的返回 4，其它的都是系统错误
+func (h *SmsTemplateHandler) errResult(err error) (ginx.Result, error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidSmsTemplate):
+		return ginx.Result{Code: 4, Msg: err.Error()}, nil
+	case errors.Is(err, service.ErrSmsTemplateNotFound):
+		return ginx.Result{Code: 4, Msg: "短信模板不存在"}, nil
+	case errors.Is(err, service.ErrSmsTemplateDuplicateName):
+		return ginx.Result{Code: 4, Msg: "短信模板名字已经被使用"}, nil
+	default:
+		return ginx.Result{Code: 5, Msg: "系统错误"}, err
+	}
+}
+
+func (h *SmsTemplateHandler) toDomain(req SmsTemplateReq) domain.SmsTemplate {
+	return domain.SmsTemplate{
+		Id:      req.Id,
+		Name:    req.Name,
+		Content: req.Content,
+		Args: slice.Map(req.Args, func(idx int, src SmsTemplateArgDTO) domain.SmsTemplateArg {
+			return domain.SmsTemplateArg{
+				Name:    src.Name,
+				Pattern: src.Pattern,
+				MaxLen:  src.MaxLen,
+			}
+		}),
+	}
+}
+
+func (h *SmsTemplateHandler) toVo(t domain.SmsTemplate) SmsTemplateVo {
+	return SmsTemplateVo{
+		Id:      t.Id,
+		Name:    t.Name,
+		Content: t.Content,
+		Args: slice.Map(t.Args, func(idx int, src domain.SmsTemplateArg) SmsTemplateArgDTO {
+			return SmsTemplateArgDTO{
+				Name:    src.Name,
+				Pattern: src.Pattern,
+				MaxLen:  src.MaxLen,
+			}
+		}),
+		Providers: slice.Map(t.Providers, func(idx int, src domain.SmsProviderTemplate) SmsProviderTemplateVo {
+			return SmsProviderTemplateVo{
+				Provider: src.Provider,
+				TplId:    src.TplId,
+				SignName: src.SignName,
+				Status:   src.Status.String(),
+				Reason:   src.Reason,
+				Utime:    src.Utime.UnixMilli(),
+			}
+		}),
+		Ctime: t.Ctime.UnixMilli(),
+		Utime: t.Utime.UnixMilli(),
+	}
+}
@@ -0,0 +1,202 @@
+package web
+
+import (
+	"github.com/ecodeclub/ekit/slice"
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"time"
+	intrv1 "xiaoweishu/webook/api/proto/gen/intr/v1"
+	"xiaoweishu/webook/internal/domain"
+	"xiaoweishu/webook/internal/service"
+	ijwt "xiaoweishu/webook/internal/web/jwt"
+	logger2 "xiaoweishu/webook/pkg/logger"
+)
+
+const (
+	// statsArticlePageSize 查作者所有文章 id 的时候一页的大小
+	statsArticlePageSize = 100
+	// statsMaxArticles 和交互服务一次最多能查的数量保持一致，再多的文章不统计
+	statsMaxArticles = 10000
+)
+
+// CreatorStatsHandler 创作者看板
+type CreatorStatsHandler struct {
+	svc    intrv1.InteractiveStatsServiceClient
+	artSvc service.ArticleService
+	l      logger2.LoggerV1
+}
+
+func NewCreatorStatsHandler(svc intrv1.InteractiveStatsServiceClient,
+	artSvc service.ArticleService, l logger2.LoggerV1) *CreatorStatsHandler {
+	return &CreatorStatsHandler{
+		svc:    svc,
+		artSvc: artSvc,
+		l:      l,
+	}
+}
+
+func (h *CreatorStatsHandler) RegisterRoutes(server *gin.Engine) {
+	g := server.Group("/articles/stats")
+	g.POST("/dashboard", h.Dashboard)
+}
+
+func (h *CreatorStatsHandler) Dashboard(ctx *gin.Context) {
+	type Req struct {
+		// Granularity hour 或者 day
+		Granularity string `json:"granularity"`
+		Periods     int32  `json:"periods"`
+		// ArticleId 不传就是作者所有文章的数据
+		ArticleId int64 `json:"articleId"`
+		TopN      int32 `json:"topN"`
+	}
+	var req Req
+	err := ctx.Bind(&req)
+	if err != nil {
+		return
+	}
+	if req.Granularity == "" {
+		req.Granularity = "day"
+	}
+	if req.Periods <= 0 {
+		//默认看最近 24 小时或者最近 7 天
+		req.Periods = 7
+		if req.Granularity == "hour" {
+			req.Periods = 24
+		}
+	}
+	uc := ctx.MustGet("user").(ijwt.UserClaims)
+	var ids []int64
+	if req.ArticleId > 0 {
+		art, err := h.artSvc.GetById(ctx, req.ArticleId)
+		if err != nil {
+			ctx.JSON(http.StatusOK, Result{
+				Code: 5,
+				Msg:  "系统错误",
+			})
+			h.l.Error("查询文章失败",
+				logger2.Int64("aid", req.ArticleId),
+				logger2.Error(err))
+			return
+		}
+		if art.Author.Id != uc.Uid {
+			ctx.JSON(http.StatusOK, Result{
+				Code: 4,
+				Msg:  "输入有误",
+			})
+			h.l.Error("非法查看别人文章的数据",
+				logger2.Int64("aid", req.ArticleId),
+				logger2.Int64("uid", uc.Uid))
+			return
+		}
+		ids = []int64{req.ArticleId}
+	} else {
+		ids, err = h.authorArticleIds(ctx, uc.Uid)
+		if err != nil {
+			ctx.JSON(http.StatusOK, Result{
+				Code: 5,
+				Msg:  "系统错误",
+			})
+			h.l.Error("查询作者的文章失败",
+				logger2.Int64("uid", uc.Uid),
+				logger2.Error(err))
+			return
+		}
+	}
+	if len(ids) == 0 {
+		ctx.JSON(http.StatusOK, Result{Data: CreatorDashboardVo{}})
+		return
+	}
+	resp, err := h.svc.Dashboard(ctx, &intrv1.DashboardRequest{
+		Biz:         "article",
+		BizIds:      ids,
+		Granularity: req.Granularity,
+		Periods:     req.Periods,
+		TopN:        req.TopN,
+	})
+	if status.Code(err) == codes.InvalidArgument {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "输入有误",
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.l.Error("查询创作者看板失败",
+			logger2.Int64("uid", uc.Uid),
+			logger2.String("granularity", req.Granularity),
+			logger2.Error(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, Result{Data: h.toDashboardVo(resp)})
+}
+
+// authorArticleIds 作者的所有文章，包括还没有发表的，没发表的数据都是 0 不影响结果。
+// 最多取 statsMaxArticles 篇
+func (h *CreatorStatsHandler) authorArticleIds(ctx *gin.Context, uid int64) ([]int64, error) {
+	var ids []int64
+	for offset := 0; offset < statsMaxArticles; offset += statsArticlePageSize {
+		arts, err := h.artSvc.GetByAuthor(ctx, uid, offset, statsArticlePageSize)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, slice.Map(arts, func(idx int, src domain.Article) int64 {
+			return src.Id
+		})...)
+		if len(arts) < statsArticlePageSize {
+			return ids, nil
+		}
+	}
+	h.l.Warn("作者的文章太多，只统计一部分",
+		logger2.Int64("uid", uid),
+		logger2.Int("cnt", len(ids)))
+	return ids, nil
+}
+
+func (h *CreatorStatsHandler) toDashboardVo(resp *intrv1.DashboardResponse) CreatorDashboardVo {
+	total := h.toBucketVo(resp.GetTotal())
+	prev := h.toBucketVo(resp.GetPrevTotal())
+	return CreatorDashboardVo{
+		Trend: slice.Map(resp.GetTrend(), func(idx int, src *intrv1.StatsBucket) StatsBucketVo {
+			return h.toBucketVo(src)
+		}),
+		Total:     total,
+		PrevTotal: prev,
+		Change: StatsChangeVo{
+			ReadCnt:    changeRate(total.ReadCnt, prev.ReadCnt),
+			LikeCnt:    changeRate(total.LikeCnt, prev.LikeCnt),
+			CollectCnt: changeRate(total.CollectCnt, prev.CollectCnt),
+		},
+		Top: slice.Map(resp.GetTop(), func(idx int, src *intrv1.StatsItem) StatsItemVo {
+			return StatsItemVo{
+				ArticleId:  src.GetBizId(),
+				ReadCnt:    src.GetReadCnt(),
+				LikeCnt:    src.GetLikeCnt(),
+				CollectCnt: src.GetCollectCnt(),
+			}
+		}),
+	}
+}
+
+func (h *CreatorStatsHandler) toBucketVo(b *intrv1.StatsBucket) StatsBucketVo {
+	return StatsBucketVo{
+		Time:       time.UnixMilli(b.GetTime()).Format(time.DateTime),
+		ReadCnt:    b.GetReadCnt(),
+		LikeCnt:    b.GetLikeCnt(),
+		CollectCnt: b.GetCollectCnt(),
+	}
+}
+
+// changeRate 环比，百分比。上一个周期是 0 的时候没法算，返回 nil
+func changeRate(cur, prev int64) *float64 {
+	if prev == 0 {
+		return nil
+	}
+	res := float64(cur-prev) * 100 / float64(prev)
+	return &res
+}
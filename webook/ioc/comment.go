@@ -0,0 +1,40 @@
+package ioc
+
+import (
+	"github.com/spf13/viper"
+	etcdv3 "go.etcd.io/etcd/client/v3"
+	resolver2 "go.etcd.io/etcd/client/v3/naming/resolver"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	commentv1 "xiaoweishu/webook/api/proto/gen/comment/v1"
+)
+
+// InitCommentClient 热榜从评论服务批量查询评论数，评论服务注册在 etcd 的 service/comment 下面
+func InitCommentClient(client *etcdv3.Client) commentv1.CommentServiceClient {
+	type config struct {
+		Addr   string `yaml:"addr"`
+		Secure bool   `yaml:"secure"`
+	}
+	cfg := config{Addr: "etcd:///service/comment"}
+	if viper.IsSet("grpc.client.comment") {
+		err := viper.UnmarshalKey("grpc.client.comment", &cfg)
+		if err != nil {
+			panic(err)
+		}
+	}
+	resolver, err := resolver2.NewBuilder(client)
+	if err != nil {
+		panic(err)
+	}
+	opts := []grpc.DialOption{
+		grpc.WithResolvers(resolver),
+	}
+	if !cfg.Secure {
+		opts = append(opts, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	}
+	cc, err := grpc.Dial(cfg.Addr, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return commentv1.NewCommentServiceClient(cc)
+}
@@ -0,0 +1,34 @@
+package ioc
+
+import (
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+	"sync"
+)
+
+// viper.OnConfigChange 只会保留最后一次注册的回调，
+// 所以需要热更新的配置都通过 OnConfigChange 注册，由这里统一分发
+var configWatchers struct {
+	once sync.Once
+	lock sync.RWMutex
+	fns  []func(in fsnotify.Event)
+}
+
+// OnConfigChange 注册配置变更的回调，可以注册多个，按照注册的顺序执行
+func OnConfigChange(fn func(in fsnotify.Event)) {
+	configWatchers.once.Do(func() {
+		viper.OnConfigChange(dispatchConfigChange)
+	})
+	configWatchers.lock.Lock()
+	defer configWatchers.lock.Unlock()
+	configWatchers.fns = append(configWatchers.fns, fn)
+}
+
+func dispatchConfigChange(in fsnotify.Event) {
+	configWatchers.lock.RLock()
+	fns := configWatchers.fns
+	configWatchers.lock.RUnlock()
+	for _, fn := range fns {
+		fn(in)
+	}
+}
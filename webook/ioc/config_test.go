@@ -0,0 +1,20 @@
+package ioc
+
+import (
+	"github.com/fsnotify/fsnotify"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestOnConfigChange(t *testing.T) {
+	var calls []string
+	OnConfigChange(func(in fsnotify.Event) {
+		calls = append(calls, "ranking")
+	})
+	OnConfigChange(func(in fsnotify.Event) {
+		calls = append(calls, "intr")
+	})
+	//后注册的回调不会把前面的覆盖掉
+	dispatchConfigChange(fsnotify.Event{Name: "dev.yaml"})
+	assert.Equal(t, []string{"ranking", "intr"}, calls)
+}
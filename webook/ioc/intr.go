@@ -12,8 +12,32 @@ import (
 	"xiaoweishu/webook/internal/client"
 )
 
+// IntrConn interactive 服务的连接，交互、阅读历史、看板和点赞榜的客户端共用一个
+type IntrConn *grpc.ClientConn
+
 // 初始化用于interactive grpc客户端
-func InitIntrClientV1(client *etcdv3.Client) intrv1.InteractiveServiceClient {
+func InitIntrClientV1(conn IntrConn) intrv1.InteractiveServiceClient {
+	remote := intrv1.NewInteractiveServiceClient((*grpc.ClientConn)(conn))
+	return remote //初始化远程客户端
+	//这里已经用不上本地的客户端了，本地客户端只有在服务刚上线进行灰度发布的时候才会用到
+}
+
+// InitReadHistoryClient 阅读历史也是 interactive 服务提供的
+func InitReadHistoryClient(conn IntrConn) intrv1.ReadHistoryServiceClient {
+	return intrv1.NewReadHistoryServiceClient((*grpc.ClientConn)(conn))
+}
+
+// InitStatsClient 创作者看板的数据
+func InitStatsClient(conn IntrConn) intrv1.InteractiveStatsServiceClient {
+	return intrv1.NewInteractiveStatsServiceClient((*grpc.ClientConn)(conn))
+}
+
+// InitLikeRankClient 点赞榜
+func InitLikeRankClient(conn IntrConn) intrv1.LikeRankServiceClient {
+	return intrv1.NewLikeRankServiceClient((*grpc.ClientConn)(conn))
+}
+
+func InitIntrConn(client *etcdv3.Client) IntrConn {
 	type config struct {
 		Addr   string `yaml:"addr"`
 		Secure bool   `yaml:"secure"`
@@ -37,9 +61,7 @@ func InitIntrClientV1(client *etcdv3.Client) intrv1.InteractiveServiceClient {
 	if err != nil {
 		panic(err)
 	}
-	remote := intrv1.NewInteractiveServiceClient(cc)
-	return remote //初始化远程客户端
-	//这里已经用不上本地的客户端了，本地客户端只有在服务刚上线进行灰度发布的时候才会用到
+	return cc
 }
 
 // 初始化grpc客户端,用于刚拆分时的通信和灰度流量控制
@@ -65,7 +87,7 @@ func InitIntrClient(svc service.InteractiveService) intrv1.InteractiveServiceCli
 	remote := intrv1.NewInteractiveServiceClient(cc)       //初始化远程客户端
 	local := client.NewLocalInteractiveServiceAdapter(svc) //初始化本地客户端
 	res := client.NewInteractiveClient(remote, local)
-	viper.OnConfigChange(func(in fsnotify.Event) {
+	OnConfigChange(func(in fsnotify.Event) {
 		cfg = config{}
 		err := viper.UnmarshalKey("grpc.client.intr", &cfg)
 		if err != nil {
@@ -1,23 +1,67 @@
 package ioc
 
 import (
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
 	rlock "github.com/gotomicro/redis-lock"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/robfig/cron/v3"
+	"github.com/spf13/viper"
+	etcdv3 "go.etcd.io/etcd/client/v3"
+	"net/http"
+	"os"
 	"time"
 	"xiaoweishu/webook/internal/job"
+	"xiaoweishu/webook/internal/repository"
 	"xiaoweishu/webook/internal/service"
+	"xiaoweishu/webook/internal/web"
+	"xiaoweishu/webook/pkg/ginx"
 	"xiaoweishu/webook/pkg/logger"
 )
 
-func InitRankingJob(svc service.RankingService, client *rlock.Client, l logger.LoggerV1) *job.RankingJob {
-	return job.NewRankingJob(svc, l, time.Second*30, client)
+// JobLeaderFactory 给每个只能在一个实例上执行的定时任务创建一个 Leader，name 是任务的名字
+type JobLeaderFactory func(name string) job.Leader
+
+// InitJobLeaderFactory job.leader.type 是 redis 或者 etcd，默认用 redis 的分布式锁。
+// job.leader.expiration 是锁或者租约的过期时间，默认 30 秒。
+// 没有选上的实例里面只有负载最低的才会去竞争
+func InitJobLeaderFactory(client *rlock.Client, etcdClient *etcdv3.Client,
+	loadSvc service.JobLoadService, l logger.LoggerV1) JobLeaderFactory {
+	type config struct {
+		Type       string        `yaml:"type"`
+		Expiration time.Duration `yaml:"expiration"`
+	}
+	cfg := config{Type: "redis", Expiration: time.Second * 30}
+	err := viper.UnmarshalKey("job.leader", &cfg)
+	if err != nil {
+		panic(err)
+	}
+	instance := InitInstanceName()
+	switch cfg.Type {
+	case "redis":
+		return func(name string) job.Leader {
+			return job.NewLoadAwareLeader(job.NewRedisLeader(client, "job:"+name, cfg.Expiration, l), loadSvc)
+		}
+	case "etcd":
+		ttl := int(cfg.Expiration.Seconds())
+		return func(name string) job.Leader {
+			return job.NewLoadAwareLeader(job.NewEtcdLeader(etcdClient, "/webook/leader/"+name, instance, ttl, l), loadSvc)
+		}
+	default:
+		panic(fmt.Errorf("不支持的选主方式 %s", cfg.Type))
+	}
 }
-func InitLikeJob(svc service.ArticleService, client *rlock.Client, l logger.LoggerV1) *job.UpdateLikeJob {
-	return job.NewUpdateRankingJob(svc, l, time.Second*30, client)
+
+func InitRankingJob(svc service.RankingService, leaders JobLeaderFactory, l logger.LoggerV1) *job.LeaderJob {
+	j := job.NewRankingJob(svc, l, time.Second*30)
+	return job.NewLeaderJob(j, leaders(j.Name()), l)
 }
 
-func InitJobs(l logger.LoggerV1, rjob *job.RankingJob, ljob *job.UpdateLikeJob) *cron.Cron {
+// InitJobs 开了增量热榜之后总榜由快照任务写入，全量计算只作为校正任务低频执行
+func InitJobs(l logger.LoggerV1, rjob *job.LeaderJob, boardJobs []RankingBoardJob,
+	streamJobs StreamRankingJobs, cleanJob *job.JobExecutionCleanJob, wfTimeoutJob *job.WorkflowTimeoutJob,
+	smsReceiptJob SmsReceiptPullJob) *CronJobs {
 	builder := job.NewCronJobBuilder(l, prometheus.SummaryOpts{
 		Namespace: "zx",
 		Subsystem: "webook",
@@ -31,15 +75,215 @@ func InitJobs(l logger.LoggerV1, rjob *job.RankingJob, ljob *job.UpdateLikeJob)
 			0.999: 0.0001,
 		},
 	})
-	//一秒执行一次
 	expr := cron.New(cron.WithSeconds())
-	_, err := expr.AddJob("@every 1s", builder.Build(rjob))
+	var err error
+	//开了增量热榜之后，全量计算由校正任务来做，快照也是用同一套算法排序
+	if streamJobs.Snapshot != nil {
+		_, err = expr.AddJob(streamJobs.SnapshotInterval, builder.Build(streamJobs.Snapshot))
+		if err != nil {
+			panic(err)
+		}
+		_, err = expr.AddJob(streamJobs.CorrectionInterval, builder.Build(streamJobs.Correction))
+		if err != nil {
+			panic(err)
+		}
+	} else {
+		//一秒执行一次
+		_, err = expr.AddJob("@every 1s", builder.Build(rjob))
+		if err != nil {
+			panic(err)
+		}
+	}
+	_, err = expr.AddJob("@every 1h", builder.Build(cleanJob))
+	if err != nil {
+		panic(err)
+	}
+	_, err = expr.AddJob("@every 1m", builder.Build(wfTimeoutJob))
+	if err != nil {
+		panic(err)
+	}
+	_, err = expr.AddJob(smsReceiptJob.Interval, builder.Build(smsReceiptJob.Job))
+	if err != nil {
+		panic(err)
+	}
+	for _, bj := range boardJobs {
+		_, err = expr.AddJob(bj.Interval, builder.Build(bj.Job))
+		if err != nil {
+			panic(err)
+		}
+	}
+	leaders := []*job.LeaderJob{rjob, smsReceiptJob.Job}
+	if streamJobs.Snapshot != nil {
+		leaders = append(leaders, streamJobs.Snapshot, streamJobs.Correction)
+	}
+	for _, bj := range boardJobs {
+		leaders = append(leaders, bj.Job)
+	}
+	return &CronJobs{Cron: expr, leaders: leaders}
+}
+
+// CronJobs 本地的定时任务。退出的时候先等 cron 里面正在执行的任务结束，
+// 再 Close 释放 leader 身份，别的实例不用等锁或者租约过期就能接手
+type CronJobs struct {
+	*cron.Cron
+	leaders []*job.LeaderJob
+}
+
+func (c *CronJobs) Close() error {
+	var errs []error
+	for _, j := range c.leaders {
+		err := j.Close()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", j.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// InitInstanceName 实例的名字，没有配置的时候用机器名
+func InitInstanceName() string {
+	name := viper.GetString("instance")
+	if name != "" {
+		return name
+	}
+	name, _ = os.Hostname()
+	return name
+}
+
+func InitLocalFuncExecutor() *job.LocalFuncExecutor {
+	return job.NewLocalFuncExecutor()
+}
+
+// InitJobExecutors 分布式任务调度支持的执行器，任务的 executor 字段必须是这里面的一个。
+// http 和 grpc 执行器让别的团队不用把任务编译进 webook 里面，
+// 只能调用白名单里面的域名和服务，没有配置白名单的时候这两种任务都创建不了
+func InitJobExecutors(local *job.LocalFuncExecutor, client *etcdv3.Client) []job.Executor {
+	type httpConfig struct {
+		AllowedHosts []string      `yaml:"allowedHosts"`
+		Timeout      time.Duration `yaml:"timeout"`
+	}
+	type grpcConfig struct {
+		AllowedServices []string      `yaml:"allowedServices"`
+		Timeout         time.Duration `yaml:"timeout"`
+	}
+	httpCfg := httpConfig{Timeout: time.Minute}
+	err := viper.UnmarshalKey("job.executors.http", &httpCfg)
 	if err != nil {
 		panic(err)
 	}
-	_, err = expr.AddJob("@every 10m", builder.Build(ljob))
+	grpcCfg := grpcConfig{Timeout: time.Minute}
+	err = viper.UnmarshalKey("job.executors.grpc", &grpcCfg)
 	if err != nil {
 		panic(err)
 	}
-	return expr
+	return []job.Executor{
+		local,
+		job.NewHTTPExecutor(&http.Client{}, httpCfg.AllowedHosts, httpCfg.Timeout),
+		job.NewGRPCExecutor(client, grpcCfg.AllowedServices, grpcCfg.Timeout),
+	}
+}
+
+func InitCronJobService(repo repository.CronJobRepository,
+	executors []job.Executor, l logger.LoggerV1) service.CronJobService {
+	validators := make(map[string]service.JobCfgValidator, len(executors))
+	for _, exec := range executors {
+		var validate service.JobCfgValidator
+		if v, ok := exec.(job.CfgValidator); ok {
+			validate = v.ValidateCfg
+		}
+		validators[exec.Name()] = validate
+	}
+	return service.NewCronJobServiceV1(l, time.Second*10, repo, InitInstanceName(), validators)
+}
+
+// InitJobExecutionService 执行历史默认保留 30 天
+func InitJobExecutionService(repo repository.JobExecutionRepository) service.JobExecutionService {
+	retention := time.Hour * 24 * 30
+	if viper.IsSet("job.history.retention") {
+		retention = viper.GetDuration("job.history.retention")
+	}
+	return service.NewJobExecutionService(repo, retention)
+}
+
+func InitJobExecutionCleanJob(svc service.JobExecutionService, l logger.LoggerV1) *job.JobExecutionCleanJob {
+	return job.NewJobExecutionCleanJob(svc, l, time.Minute)
+}
+
+// InitWorkflowService 工作流一次执行默认最多一天，job.workflow.timeout 可以改
+func InitWorkflowService(repo repository.WorkflowRepository, jobRepo repository.CronJobRepository,
+	l logger.LoggerV1) service.WorkflowService {
+	timeout := time.Hour * 24
+	if viper.IsSet("job.workflow.timeout") {
+		timeout = viper.GetDuration("job.workflow.timeout")
+	}
+	return service.NewWorkflowServiceV1(repo, jobRepo, l, timeout)
+}
+
+func InitWorkflowTimeoutJob(svc service.WorkflowService, l logger.LoggerV1) *job.WorkflowTimeoutJob {
+	return job.NewWorkflowTimeoutJob(svc, l, time.Minute)
+}
+
+// InitJobLoadService 15 秒没有上报负载的实例就当它下线了
+func InitJobLoadService(repo repository.JobLoadRepository) service.JobLoadService {
+	return service.NewJobLoadService(repo, InitInstanceName(), time.Second*15)
+}
+
+// InitJobShardService 续约间隔和任务的一样
+func InitJobShardService(repo repository.JobShardRepository, jobRepo repository.CronJobRepository,
+	l logger.LoggerV1) service.JobShardService {
+	return service.NewJobShardService(repo, jobRepo, l, time.Second*10, InitInstanceName())
+}
+
+// InitScheduler job.scheduler.capacity 是一个实例最多同时执行的任务数，默认 100
+func InitScheduler(svc service.CronJobService, execSvc service.JobExecutionService,
+	shardSvc service.JobShardService, loadSvc service.JobLoadService, wfSvc service.WorkflowService,
+	executors []job.Executor, l logger.LoggerV1) *job.Scheduler {
+	capacity := int64(100)
+	if viper.IsSet("job.scheduler.capacity") {
+		capacity = viper.GetInt64("job.scheduler.capacity")
+	}
+	s := job.NewSchedulerV2(svc, execSvc, shardSvc, loadSvc, wfSvc, l, prometheus.SummaryOpts{
+		Namespace: "zx",
+		Subsystem: "webook",
+		Name:      "scheduler_job",
+		Help:      "分布式任务执行",
+		Objectives: map[float64]float64{
+			0.5:   0.01,
+			0.75:  0.01,
+			0.9:   0.01,
+			0.99:  0.001,
+			0.999: 0.0001,
+		},
+	}, capacity)
+	for _, exec := range executors {
+		s.RegisterExecutor(exec)
+	}
+	return s
+}
+
+// InitAdminServer 运维用的接口单独一个端口，不对外暴露
+func InitAdminServer(jobHdl *web.CronJobHandler, tplHdl *web.SmsTemplateHandler,
+	providerHdl *web.SmsProviderHandler, msgHdl *web.SmsMessageHandler,
+	rankingHdl *web.RankingHandler) *ginx.Server {
+	engine := gin.Default()
+	ginx.InitCounter(prometheus.CounterOpts{
+		Namespace: "zx",
+		Subsystem: "webook_admin",
+		Name:      "biz_code",
+		Help:      "统计业务错误码",
+	})
+	jobHdl.RegisterRoutes(engine.Group("/jobs"))
+	tplHdl.RegisterRoutes(engine.Group("/sms/templates"))
+	providerHdl.RegisterRoutes(engine.Group("/sms/providers"))
+	msgHdl.RegisterRoutes(engine.Group("/sms/messages"))
+	rankingHdl.RegisterAdminRoutes(engine.Group("/ranking"))
+	//这些接口没有鉴权，没有配置的时候只监听本机
+	addr := "127.0.0.1:8083"
+	if viper.IsSet("admin.http.addr") {
+		addr = viper.GetString("admin.http.addr")
+	}
+	return &ginx.Server{
+		Engine: engine,
+		Addr:   addr,
+	}
 }
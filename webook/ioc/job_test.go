@@ -0,0 +1,35 @@
+package ioc
+
+import (
+	"errors"
+	"github.com/robfig/cron/v3"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"xiaoweishu/webook/internal/job"
+	jobmocks "xiaoweishu/webook/internal/job/mocks"
+	"xiaoweishu/webook/pkg/logger"
+)
+
+func TestCronJobs_Close(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	newLeaderJob := func(name string, releaseErr error) *job.LeaderJob {
+		j := jobmocks.NewMockContextJob(ctrl)
+		j.EXPECT().Name().Return(name).AnyTimes()
+		leader := jobmocks.NewMockLeader(ctrl)
+		leader.EXPECT().Release(gomock.Any()).Return(releaseErr)
+		return job.NewLeaderJob(j, leader, logger.NewNopLogger())
+	}
+	jobs := &CronJobs{
+		Cron: cron.New(),
+		leaders: []*job.LeaderJob{
+			newLeaderJob("ranking", nil),
+			newLeaderJob("sms_receipt", errors.New("redis 不可用")),
+		},
+	}
+	<-jobs.Stop().Done()
+	//一个释放失败了，别的照样释放
+	err := jobs.Close()
+	assert.EqualError(t, err, "sms_receipt: redis 不可用")
+}
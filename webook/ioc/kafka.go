@@ -5,6 +5,7 @@ import (
 	"github.com/spf13/viper"
 	events2 "xiaoweishu/webook/interactive/events"
 	"xiaoweishu/webook/internal/events"
+	"xiaoweishu/webook/internal/events/ranking"
 )
 
 func InitSaramaClient() sarama.Client {
@@ -32,6 +33,16 @@ func InitSyncProducer(c sarama.Client) sarama.SyncProducer {
 	return p
 }
 
-func InitConsumers(c1 *events2.InteractiveReadEventConsumer) []events.Consumer {
-	return []events.Consumer{c1}
+// InitConsumers 增量热榜没开的时候不消费，免得白白占用 redis。
+// 交互服务开了写回模式的时候阅读计数由它的 WriteBehindConsumer 负责，这边再消费就重复计数了
+func InitConsumers(c1 *events2.InteractiveReadEventConsumer,
+	c2 *ranking.StreamRankingConsumer) []events.Consumer {
+	res := make([]events.Consumer, 0, 2)
+	if !viper.GetBool("interactive.writeBehind") {
+		res = append(res, c1)
+	}
+	if loadRankingStream().Enabled {
+		res = append(res, c2)
+	}
+	return res
 }
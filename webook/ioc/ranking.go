@@ -0,0 +1,213 @@
+package ioc
+
+import (
+	"fmt"
+	"github.com/IBM/sarama"
+	"github.com/ecodeclub/ekit/slice"
+	"github.com/fsnotify/fsnotify"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/spf13/viper"
+	"time"
+	commentv1 "xiaoweishu/webook/api/proto/gen/comment/v1"
+	intrv1 "xiaoweishu/webook/api/proto/gen/intr/v1"
+	"xiaoweishu/webook/interactive/events"
+	"xiaoweishu/webook/internal/domain"
+	"xiaoweishu/webook/internal/events/ranking"
+	"xiaoweishu/webook/internal/job"
+	"xiaoweishu/webook/internal/repository"
+	"xiaoweishu/webook/internal/repository/cache"
+	"xiaoweishu/webook/internal/service"
+	"xiaoweishu/webook/pkg/logger"
+)
+
+// InitRankingReadCntMode 热榜用 PV 还是 UV，默认用 UV，防止刷阅读数
+func InitRankingReadCntMode() service.ReadCntMode {
+	if viper.GetString("ranking.readCnt") == "pv" {
+		return service.ReadCntPV
+	}
+	return service.ReadCntUV
+}
+
+// InitRankingRepository 总榜先读本地缓存再读 redis，每一层的命中次数都会上报
+func InitRankingRepository(redisCache cache.RankingCache, localCache *cache.RankingLocalCache) repository.RankingRepository {
+	return repository.NewCachedRankingRepositoryV1(redisCache, localCache, prometheus.CounterOpts{
+		Namespace: "zx",
+		Subsystem: "webook",
+		Name:      "ranking_read_tier",
+		Help:      "热榜从哪一层缓存读到",
+	})
+}
+
+// rankingBoardConfig 一个榜的配置，Interval 是 cron 表达式
+type rankingBoardConfig struct {
+	Name       string        `yaml:"name"`
+	Window     time.Duration `yaml:"window"`
+	Size       int           `yaml:"size"`
+	Categories []string      `yaml:"categories"`
+	AuthorIds  []int64       `yaml:"authorIds"`
+	Interval   string        `yaml:"interval"`
+}
+
+// loadRankingBoards 没有配置的时候默认有日榜、周榜和月榜
+func loadRankingBoards() []rankingBoardConfig {
+	if !viper.IsSet("ranking.boards") {
+		return []rankingBoardConfig{
+			{Name: "daily", Window: time.Hour * 24, Size: 100, Interval: "@every 1m"},
+			{Name: "weekly", Window: time.Hour * 24 * 7, Size: 100, Interval: "@every 5m"},
+			{Name: "monthly", Window: time.Hour * 24 * 30, Size: 100, Interval: "@every 30m"},
+		}
+	}
+	var cfgs []rankingBoardConfig
+	err := viper.UnmarshalKey("ranking.boards", &cfgs)
+	if err != nil {
+		panic(err)
+	}
+	for _, cfg := range cfgs {
+		if cfg.Name == "" || cfg.Window <= 0 || cfg.Size <= 0 || cfg.Interval == "" {
+			panic(fmt.Errorf("热榜 %s 的配置不完整", cfg.Name))
+		}
+	}
+	return cfgs
+}
+
+func InitRankingBoards() []domain.RankingBoard {
+	return slice.Map(loadRankingBoards(), func(idx int, src rankingBoardConfig) domain.RankingBoard {
+		return domain.RankingBoard{
+			Name:       src.Name,
+			Window:     src.Window,
+			Size:       src.Size,
+			Categories: src.Categories,
+			AuthorIds:  src.AuthorIds,
+		}
+	})
+}
+
+// RankingBoardJob 每个榜一个任务，按照各自的间隔调度
+type RankingBoardJob struct {
+	Job      *job.LeaderJob
+	Interval string
+}
+
+func InitRankingBoardJobs(svc service.RankingService, leaders JobLeaderFactory,
+	l logger.LoggerV1) []RankingBoardJob {
+	return slice.Map(loadRankingBoards(), func(idx int, src rankingBoardConfig) RankingBoardJob {
+		j := job.NewRankingJobV1(svc, src.Name, l, time.Second*30)
+		return RankingBoardJob{
+			Job:      job.NewLeaderJob(j, leaders(j.Name()), l),
+			Interval: src.Interval,
+		}
+	})
+}
+
+// InitRankingService 热榜的算法从 ranking.strategy 读取，配置变更之后下一次计算热榜就会用新的算法
+func InitRankingService(intrSvc intrv1.InteractiveServiceClient,
+	commentSvc commentv1.CommentServiceClient, artSvc service.ArticleService,
+	repo repository.RankingRepository, boardRepo repository.RankingBoardRepository, readMode service.ReadCntMode,
+	boards []domain.RankingBoard, l logger.LoggerV1) service.RankingService {
+	strategy, err := loadRankingStrategy()
+	if err != nil {
+		panic(err)
+	}
+	svc := service.NewBatchRankingServiceV1(intrSvc, commentSvc, artSvc, repo, boardRepo, readMode, strategy, boards)
+	OnConfigChange(func(in fsnotify.Event) {
+		strategy, err := loadRankingStrategy()
+		if err != nil {
+			//配置写错了就继续用原来的算法
+			l.Error("热榜算法配置错误，继续使用原来的算法", logger.Error(err))
+			return
+		}
+		svc.UpdateStrategy(strategy)
+		l.Info("热榜算法已更新", logger.String("strategy", strategy.Name()))
+	})
+	return svc
+}
+
+func loadRankingStrategy() (service.RankingStrategy, error) {
+	cfg := service.DefaultRankingStrategyConfig()
+	if viper.IsSet("ranking.strategy") {
+		cfg = service.RankingStrategyConfig{}
+		err := viper.UnmarshalKey("ranking.strategy", &cfg)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return service.NewRankingStrategy(cfg)
+}
+
+// rankingStreamConfig 增量热榜的配置，Interval 都是 cron 表达式
+type rankingStreamConfig struct {
+	Enabled       bool    `yaml:"enabled"`
+	ReadWeight    float64 `yaml:"readWeight"`
+	LikeWeight    float64 `yaml:"likeWeight"`
+	CollectWeight float64 `yaml:"collectWeight"`
+	HalfLifeHours float64 `yaml:"halfLifeHours"`
+	// SnapshotInterval 多久把前 n 名写到总榜一次
+	SnapshotInterval string `yaml:"snapshotInterval"`
+	// CorrectionInterval 多久从数据库全量校正一次
+	CorrectionInterval string `yaml:"correctionInterval"`
+}
+
+// loadRankingStream 没有配置的时候不开增量热榜，总榜还是每次全量计算
+func loadRankingStream() rankingStreamConfig {
+	cfg := rankingStreamConfig{
+		ReadWeight:         0.01,
+		LikeWeight:         1,
+		CollectWeight:      2,
+		HalfLifeHours:      24,
+		SnapshotInterval:   "@every 10s",
+		CorrectionInterval: "@every 1h",
+	}
+	err := viper.UnmarshalKey("ranking.stream", &cfg)
+	if err != nil {
+		panic(err)
+	}
+	if cfg.HalfLifeHours <= 0 {
+		panic(fmt.Errorf("增量热榜的 halfLifeHours 必须大于 0"))
+	}
+	return cfg
+}
+
+func InitRankingDecay() domain.RankingDecay {
+	cfg := loadRankingStream()
+	return domain.RankingDecay{
+		ReadWeight:    cfg.ReadWeight,
+		LikeWeight:    cfg.LikeWeight,
+		CollectWeight: cfg.CollectWeight,
+		HalfLife:      time.Duration(cfg.HalfLifeHours * float64(time.Hour)),
+	}
+}
+
+// InitStreamRankingConsumer 写入失败丢掉的增量会上报，丢多了要看一下 redis
+func InitStreamRankingConsumer(svc service.StreamRankingService, client sarama.Client,
+	filter events.ReadEventFilter, l logger.LoggerV1) *ranking.StreamRankingConsumer {
+	return ranking.NewStreamRankingConsumer(svc, client, filter, l, prometheus.CounterOpts{
+		Namespace: "zx",
+		Subsystem: "webook",
+		Name:      "ranking_stream_dropped",
+		Help:      "增量热榜丢弃的增量条数",
+	})
+}
+
+// StreamRankingJobs 增量热榜的快照任务和校正任务，没有开增量热榜的时候都是 nil
+type StreamRankingJobs struct {
+	Snapshot           *job.LeaderJob
+	SnapshotInterval   string
+	Correction         *job.LeaderJob
+	CorrectionInterval string
+}
+
+func InitStreamRankingJobs(svc service.StreamRankingService, leaders JobLeaderFactory,
+	l logger.LoggerV1) StreamRankingJobs {
+	cfg := loadRankingStream()
+	if !cfg.Enabled {
+		return StreamRankingJobs{}
+	}
+	snapshot := job.NewRankingJobV2("ranking:stream:snapshot", svc.Snapshot, l, time.Second*30)
+	correction := job.NewRankingJobV2("ranking:stream:correction", svc.Correct, l, time.Minute*5)
+	return StreamRankingJobs{
+		Snapshot:           job.NewLeaderJob(snapshot, leaders(snapshot.Name()), l),
+		SnapshotInterval:   cfg.SnapshotInterval,
+		Correction:         job.NewLeaderJob(correction, leaders(correction.Name()), l),
+		CorrectionInterval: cfg.CorrectionInterval,
+	}
+}
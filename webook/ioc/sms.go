@@ -1,12 +1,274 @@
 package ioc
 
 import (
+	"fmt"
+	"github.com/ecodeclub/ekit/slice"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/spf13/viper"
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/profile"
+	tencentsms "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/sms/v20210111"
+	"net/http"
+	"os"
+	"time"
+	"xiaoweishu/webook/internal/domain"
+	"xiaoweishu/webook/internal/job"
+	"xiaoweishu/webook/internal/repository"
+	"xiaoweishu/webook/internal/service"
 	"xiaoweishu/webook/internal/service/sms"
-	"xiaoweishu/webook/internal/service/sms/memory"
+	"xiaoweishu/webook/internal/service/sms/aliyun"
+	"xiaoweishu/webook/internal/service/sms/async"
+	"xiaoweishu/webook/internal/service/sms/localsms"
+	"xiaoweishu/webook/internal/service/sms/mockgw"
+	"xiaoweishu/webook/internal/service/sms/selector"
+	"xiaoweishu/webook/internal/service/sms/tencent"
+	"xiaoweishu/webook/internal/service/sms/tracking"
+	"xiaoweishu/webook/internal/service/sms/webhook"
+	"xiaoweishu/webook/internal/web"
+	"xiaoweishu/webook/pkg/logger"
 )
 
-func InitSMSService() sms.Service {
-	return memory.NewService()
-	// 如果有需要，就可以用这个
-	//return initTencentSMSService()
+// smsProviderConfig 一个短信供应商的配置，密钥这些从环境变量里面读
+type smsProviderConfig struct {
+	// Type local, tencent, aliyun, webhook 或者 mock
+	Type string `yaml:"type"`
+	// Name 短信模板管理里面的名字，webhook 和 mock 要配置，别的固定用供应商自己的名字
+	Name string `yaml:"name"`
+	// Endpoint webhook 的地址，阿里云不配置就用默认的地址
+	Endpoint string `yaml:"endpoint"`
+	// Token webhook 放在 Authorization 里面的 token
+	Token string `yaml:"token"`
+	// ReceiptUrl webhook 拉回执的地址，不配置就是对方推过来
+	ReceiptUrl string `yaml:"receiptUrl"`
+	// PullReceipts 腾讯云主动拉回执，不然要在腾讯云的控制台配置推送的地址
+	PullReceipts bool `yaml:"pullReceipts"`
+	// AppId 腾讯云的 SmsSdkAppId
+	AppId    string        `yaml:"appId"`
+	SignName string        `yaml:"signName"`
+	Timeout  time.Duration `yaml:"timeout"`
+	// Cost 发一条短信的相对成本，健康状况差不多的时候优先用便宜的
+	Cost float64 `yaml:"cost"`
+}
+
+// SmsProvider 一个配置好的短信供应商
+type SmsProvider struct {
+	Name string
+	Cost float64
+	// Svc 套上了 tracking，发出去的每一条短信都会记录下来
+	Svc sms.Service
+	// Raw 供应商自己的实现，用来接收和拉取回执
+	Raw sms.TrackableService
+	// Pull 要不要主动去拉回执
+	Pull bool
+}
+
+type SmsProviders []SmsProvider
+
+// InitSMSService 发短信统一走 selector，只有一个供应商的时候也能熔断，快速失败。
+// 所有供应商都慢或者错误率高的时候转异步，存到数据库里面慢慢发
+func InitSMSService(svc *async.Service) sms.Service {
+	return svc
+}
+
+// InitAsyncSmsService sms.async 调整转异步的阈值和重试。要调用 Start 才会发送异步短信
+func InitAsyncSmsService(sel *selector.Service, repo repository.AsyncSmsRepository,
+	l logger.LoggerV1) *async.Service {
+	cfg := async.DefaultConfig()
+	err := viper.UnmarshalKey("sms.async", &cfg)
+	if err != nil {
+		panic(err)
+	}
+	return async.NewService(sel, repo, cfg, l)
+}
+
+// InitSmsSelector 按照健康状况和成本挑选供应商，sms.selector 调整熔断的参数
+func InitSmsSelector(providers SmsProviders, l logger.LoggerV1) *selector.Service {
+	cfg := selector.DefaultConfig()
+	err := viper.UnmarshalKey("sms.selector", &cfg)
+	if err != nil {
+		panic(err)
+	}
+	err = cfg.Validate()
+	if err != nil {
+		panic(err)
+	}
+	sel := selector.NewService(slice.Map(providers, func(idx int, src SmsProvider) selector.Provider {
+		return selector.Provider{Name: src.Name, Svc: src.Svc, Cost: src.Cost}
+	}), cfg, l)
+	prometheus.MustRegister(selector.NewCollector(sel, "zx", "webook"))
+	return sel
+}
+
+// InitSmsProviders sms.providers 配置供应商，没有配置的时候用本地的。
+// 各个供应商都要用短信模板管理把模板名字换成自己的模板 id
+func InitSmsProviders(tplSvc service.SmsTemplateService, msgSvc service.SmsMessageService,
+	l logger.LoggerV1) SmsProviders {
+	cfgs := []smsProviderConfig{{Type: "local"}}
+	if viper.IsSet("sms.providers") {
+		cfgs = nil
+		err := viper.UnmarshalKey("sms.providers", &cfgs)
+		if err != nil {
+			panic(err)
+		}
+	}
+	if len(cfgs) == 0 {
+		panic(fmt.Errorf("至少要配置一个短信供应商"))
+	}
+	res := make(SmsProviders, 0, len(cfgs))
+	for _, cfg := range cfgs {
+		p := initSmsProvider(cfg, tplSvc)
+		p.Svc = tracking.NewService(p.Name, p.Raw, msgSvc, l)
+		res = append(res, p)
+	}
+	return res
+}
+
+// initSmsProvider 供应商的名字和短信模板管理里面的一样
+func initSmsProvider(cfg smsProviderConfig, tplSvc service.SmsTemplateService) SmsProvider {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = time.Second * 5
+	}
+	client := &http.Client{Timeout: cfg.Timeout}
+	switch cfg.Type {
+	case "local":
+		//本地的回执只能拉
+		return SmsProvider{Name: localsms.Provider, Cost: cfg.Cost, Raw: localsms.NewService(tplSvc), Pull: true}
+	case "tencent":
+		return SmsProvider{Name: tencent.Provider, Cost: cfg.Cost,
+			Raw: initTencentSMSService(cfg, tplSvc), Pull: cfg.PullReceipts}
+	case "aliyun":
+		return SmsProvider{Name: aliyun.Provider, Cost: cfg.Cost,
+			Raw: aliyun.NewService(client, cfg.Endpoint, mustGetenv("ALIYUN_SMS_ACCESS_KEY_ID"),
+				mustGetenv("ALIYUN_SMS_ACCESS_KEY_SECRET"), cfg.SignName, tplSvc)}
+	case "webhook":
+		if cfg.Name == "" || cfg.Endpoint == "" {
+			panic(fmt.Errorf("webhook 短信供应商要配置 name 和 endpoint"))
+		}
+		return SmsProvider{Name: cfg.Name, Cost: cfg.Cost,
+			Raw:  webhook.NewServiceV1(client, cfg.Name, cfg.Endpoint, cfg.ReceiptUrl, cfg.Token, tplSvc),
+			Pull: cfg.ReceiptUrl != ""}
+	case "mock":
+		//本地开发用的，在进程里面起一个假的短信网关，进程退出的时候跟着退出
+		if cfg.Name == "" {
+			cfg.Name = "mock"
+		}
+		gw := mockgw.NewServer()
+		return SmsProvider{Name: cfg.Name, Cost: cfg.Cost,
+			Raw:  webhook.NewServiceV1(client, cfg.Name, gw.URL()+"/send", gw.URL()+"/receipts", "", tplSvc),
+			Pull: true}
+	default:
+		panic(fmt.Errorf("不支持的短信供应商 %s", cfg.Type))
+	}
+}
+
+func initTencentSMSService(cfg smsProviderConfig, tplSvc service.SmsTemplateService) *tencent.Service {
+	c, err := tencentsms.NewClient(common.NewCredential(mustGetenv("SMS_SECRET_ID"), mustGetenv("SMS_SECRET_KEY")),
+		"ap-nanjing", profile.NewClientProfile())
+	if err != nil {
+		panic(err)
+	}
+	return tencent.NewService(c, cfg.AppId, cfg.SignName, tplSvc)
+}
+
+func mustGetenv(key string) string {
+	val, ok := os.LookupEnv(key)
+	if !ok {
+		panic(fmt.Errorf("找不到环境变量 %s", key))
+	}
+	return val
+}
+
+// smsTemplateFallbackConfig 短信模板管理里面找不到的时候用的模板
+type smsTemplateFallbackConfig struct {
+	Name string                  `yaml:"name"`
+	Args []domain.SmsTemplateArg `yaml:"args"`
+	// Providers 供应商的名字到供应商的模板 id
+	Providers map[string]string `yaml:"providers"`
+}
+
+// defaultSmsTemplateFallbacks 上线短信模板管理之前验证码直接用的是腾讯云的模板 1877556，
+// 模板管理里面还没有配置 verify_code 的时候继续用它，不然发不出验证码
+func defaultSmsTemplateFallbacks() []smsTemplateFallbackConfig {
+	return []smsTemplateFallbackConfig{
+		{
+			Name: "verify_code",
+			Args: []domain.SmsTemplateArg{{Name: "code", Pattern: `^\d{6}$`, MaxLen: 6}},
+			Providers: map[string]string{
+				tencent.Provider:  "1877556",
+				localsms.Provider: "verify_code",
+			},
+		},
+	}
+}
+
+// InitSmsTemplateService sms.template.expiration 是解析模板的本地缓存多久过期，默认一分钟。
+// sms.template.fallbacks 是模板管理里面找不到的时候用的模板，默认只有验证码的
+func InitSmsTemplateService(repo repository.SmsTemplateRepository) service.SmsTemplateService {
+	expiration := time.Minute
+	if viper.IsSet("sms.template.expiration") {
+		expiration = viper.GetDuration("sms.template.expiration")
+	}
+	cfgs := defaultSmsTemplateFallbacks()
+	if viper.IsSet("sms.template.fallbacks") {
+		cfgs = nil
+		err := viper.UnmarshalKey("sms.template.fallbacks", &cfgs)
+		if err != nil {
+			panic(err)
+		}
+	}
+	fallbacks := slice.Map(cfgs, func(idx int, src smsTemplateFallbackConfig) domain.SmsTemplate {
+		t := domain.SmsTemplate{Name: src.Name, Args: src.Args}
+		for provider, tplId := range src.Providers {
+			t.Providers = append(t.Providers, domain.SmsProviderTemplate{Provider: provider, TplId: tplId})
+		}
+		return t
+	})
+	return service.NewSmsTemplateServiceV1(repo, expiration, fallbacks)
+}
+
+func InitSmsMessageService(repo repository.SmsMessageRepository, l logger.LoggerV1) service.SmsMessageService {
+	return service.NewSmsMessageService(repo, l, prometheus.CounterOpts{
+		Namespace: "zx",
+		Subsystem: "webook",
+		Name:      "sms_message_total",
+		Help:      "按照供应商和状态统计短信，送达率是 delivered 除以 sent",
+	})
+}
+
+// InitSmsReceiptHandler 供应商推送回执的地址是 /sms/receipts/{供应商的名字}?token={sms.receipt.token}。
+// 没有配置 sms.receipt.token 的时候不接收推过来的回执
+func InitSmsReceiptHandler(providers SmsProviders, svc service.SmsMessageService,
+	l logger.LoggerV1) *web.SmsReceiptHandler {
+	parsers := make(map[string]sms.ReceiptParser, len(providers))
+	for _, p := range providers {
+		if parser, ok := p.Raw.(sms.ReceiptParser); ok {
+			parsers[p.Name] = parser
+		}
+	}
+	return web.NewSmsReceiptHandler(svc, parsers, viper.GetString("sms.receipt.token"), l)
+}
+
+type SmsReceiptPullJob struct {
+	Job      *job.LeaderJob
+	Interval string
+}
+
+// InitSmsReceiptPullJob sms.receipt.pullInterval 是拉回执的间隔，默认三十秒
+func InitSmsReceiptPullJob(providers SmsProviders, svc service.SmsMessageService,
+	leaders JobLeaderFactory, l logger.LoggerV1) SmsReceiptPullJob {
+	pullers := make(map[string]sms.ReceiptPuller, len(providers))
+	for _, p := range providers {
+		if puller, ok := p.Raw.(sms.ReceiptPuller); ok && p.Pull {
+			pullers[p.Name] = puller
+		}
+	}
+	interval := "@every 30s"
+	if viper.IsSet("sms.receipt.pullInterval") {
+		interval = "@every " + viper.GetDuration("sms.receipt.pullInterval").String()
+	}
+	j := job.NewSmsReceiptPullJob(pullers, svc, l, time.Second*10)
+	return SmsReceiptPullJob{
+		Job:      job.NewLeaderJob(j, leaders(j.Name()), l),
+		Interval: interval,
+	}
 }
@@ -16,12 +16,22 @@ import (
 
 func InitWebServer(mdls []gin.HandlerFunc, userHdl *web.UserHandLer,
 	oauth2WechatHdl *web.OAuth2WechatHandLer,
-	artHdl *web.ArticleHandler) *gin.Engine {
+	artHdl *web.ArticleHandler,
+	historyHdl *web.ReadHistoryHandler,
+	statsHdl *web.CreatorStatsHandler,
+	likeRankHdl *web.LikeRankHandler,
+	rankingHdl *web.RankingHandler,
+	smsReceiptHdl *web.SmsReceiptHandler) *gin.Engine {
 	server := gin.Default()
 	server.Use(mdls...)
 	userHdl.RegisterUsersRoutes(server)
 	oauth2WechatHdl.RegisterRoutes(server)
 	artHdl.RegisterRoutes(server)
+	historyHdl.RegisterRoutes(server)
+	statsHdl.RegisterRoutes(server)
+	likeRankHdl.RegisterRoutes(server)
+	rankingHdl.RegisterRoutes(server)
+	smsReceiptHdl.RegisterRoutes(server)
 	return server
 }
 
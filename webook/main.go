@@ -5,25 +5,30 @@ import (
 	"github.com/fsnotify/fsnotify"
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
-	"github.com/robfig/cron/v3"
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
 	"log"
 	"net/http"
+	"os/signal"
+	"syscall"
 	"time"
 	events2 "xiaoweishu/webook/interactive/events"
+	ioc2 "xiaoweishu/webook/interactive/ioc"
 	repository2 "xiaoweishu/webook/interactive/repository"
 	cache2 "xiaoweishu/webook/interactive/repository/cache"
 	dao2 "xiaoweishu/webook/interactive/repository/dao"
 	"xiaoweishu/webook/internal/events"
 	"xiaoweishu/webook/internal/events/article"
+	"xiaoweishu/webook/internal/job"
 	"xiaoweishu/webook/internal/repository"
 	"xiaoweishu/webook/internal/repository/cache"
 	"xiaoweishu/webook/internal/repository/dao"
 	"xiaoweishu/webook/internal/service"
+	"xiaoweishu/webook/internal/service/sms/async"
 	"xiaoweishu/webook/internal/web"
 	"xiaoweishu/webook/internal/web/jwt"
 	"xiaoweishu/webook/ioc"
+	"xiaoweishu/webook/pkg/ginx"
 )
 
 func main() {
@@ -47,14 +52,52 @@ func main() {
 			panic(err)
 		}
 	}
+	app.cron.Start()
+	defer func() {
+		//不再触发新的任务，等正在执行的任务结束之后再放弃 leader 身份
+		<-app.cron.Stop().Done()
+		er := app.cron.Close()
+		if er != nil {
+			log.Println("释放定时任务的 leader 失败", er)
+		}
+	}()
+	app.asyncSms.Start()
+	defer func() {
+		//不再抢占新的短信，等已经抢占的发完，超时了没发完的过一会别的实例会重新抢占
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+		defer cancel()
+		er := app.asyncSms.Stop(ctx)
+		if er != nil {
+			log.Println("异步短信没有发完", er)
+		}
+	}()
+	go func() {
+		er := app.scheduler.Schedule(context.Background())
+		if er != nil {
+			log.Println("任务调度退出", er)
+		}
+	}()
+	go func() {
+		er := app.adminServer.Start()
+		if er != nil {
+			log.Println("admin server 退出", er)
+		}
+	}()
 	server := app.server
 	server.GET("/hello", func(ctx *gin.Context) {
 		ctx.String(http.StatusOK, "hello，启动成功了！")
 	})
-	err := server.Run(":8080")
-	if err != nil {
-		return
-	}
+	//收到退出信号，或者 web 服务退出了，执行上面 defer 的清理
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		er := server.Run(":8080")
+		if er != nil {
+			log.Println("web server 退出", er)
+		}
+		stop()
+	}()
+	<-ctx.Done()
 }
 
 func initPrometheus() {
@@ -70,7 +113,14 @@ func initPrometheus() {
 type App struct {
 	server    *gin.Engine
 	consumers []events.Consumer
-	cron      *cron.Cron
+	// cron 本地的定时任务，退出之前要等正在执行的任务结束并且释放 leader 身份
+	cron *ioc.CronJobs
+	// adminServer 运维接口，和对外的接口分开
+	adminServer *ginx.Server
+	// scheduler 基于 MySQL 的分布式任务调度
+	scheduler *job.Scheduler
+	// asyncSms 发送转成异步的短信，退出之前要等正在发的短信发完
+	asyncSms *async.Service
 }
 
 func InitWebServerv1() *App {
@@ -85,7 +135,18 @@ func InitWebServerv1() *App {
 	userService := service.NewUserService(userRepository)
 	codeCache := cache.NewCodeCache(cmdable)
 	codeRepository := repository.NewCodeRepository(codeCache)
-	smsService := ioc.InitSMSService()
+	smsTemplateDAO := dao.NewGORMSmsTemplateDAO(db)
+	smsTemplateRepository := repository.NewSmsTemplateRepository(smsTemplateDAO)
+	smsTemplateService := ioc.InitSmsTemplateService(smsTemplateRepository)
+	smsMessageDAO := dao.NewGORMSmsMessageDAO(db)
+	smsMessageRepository := repository.NewSmsMessageRepository(smsMessageDAO)
+	smsMessageService := ioc.InitSmsMessageService(smsMessageRepository, loggerV1)
+	smsProviders := ioc.InitSmsProviders(smsTemplateService, smsMessageService, loggerV1)
+	selectorService := ioc.InitSmsSelector(smsProviders, loggerV1)
+	asyncSmsDAO := dao.NewGORMAsyncSmsDAO(db)
+	asyncSmsRepository := repository.NewAsyncSMSRepository(asyncSmsDAO)
+	asyncService := ioc.InitAsyncSmsService(selectorService, asyncSmsRepository, loggerV1)
+	smsService := ioc.InitSMSService(asyncService)
 	codeSerVice := service.NewCodeService(codeRepository, smsService)
 	userHandLer := web.NewUserHandLer(userService, codeSerVice, handler)
 	wechatService := ioc.InitWechatService(loggerV1)
@@ -98,23 +159,78 @@ func InitWebServerv1() *App {
 	producer := article.NewSaramaSyncProducer(syncProducer)
 	articleService := service.NewArticleService(articleRepository, producer, loggerV1)
 	clientv3Client := ioc.InitEtcd()
-	interactiveServiceClient := ioc.InitIntrClientV1(clientv3Client)
+	intrConn := ioc.InitIntrConn(clientv3Client)
+	interactiveServiceClient := ioc.InitIntrClientV1(intrConn)
 	articleHandler := web.NewArticleHandler(loggerV1, articleService, interactiveServiceClient)
-	engine := ioc.InitWebServer(v, userHandLer, oAuth2WechatHandLer, articleHandler)
+	readHistoryServiceClient := ioc.InitReadHistoryClient(intrConn)
+	readHistoryHandler := web.NewReadHistoryHandler(readHistoryServiceClient, loggerV1)
+	interactiveStatsServiceClient := ioc.InitStatsClient(intrConn)
+	creatorStatsHandler := web.NewCreatorStatsHandler(interactiveStatsServiceClient, articleService, loggerV1)
+	likeRankServiceClient := ioc.InitLikeRankClient(intrConn)
+	likeRankHandler := web.NewLikeRankHandler(likeRankServiceClient, loggerV1)
+	readCntMode := ioc.InitRankingReadCntMode()
+	rankingCache := cache.NewRankingRedisCache(cmdable)
+	rankingLocalCache := cache.NewRankingLocalCacheV1()
+	rankingRepository := ioc.InitRankingRepository(rankingCache, rankingLocalCache)
+	rankingBoardRedisCache := cache.NewRankingBoardRedisCache(cmdable)
+	rankingBoardLocalCache := cache.NewRankingBoardLocalCache()
+	rankingBoardRepository := repository.NewCachedRankingBoardRepository(rankingBoardRedisCache, rankingBoardLocalCache)
+	v2 := ioc.InitRankingBoards()
+	commentServiceClient := ioc.InitCommentClient(clientv3Client)
+	rankingService := ioc.InitRankingService(interactiveServiceClient, commentServiceClient, articleService, rankingRepository, rankingBoardRepository, readCntMode, v2, loggerV1)
+	rankingHandler := web.NewRankingHandler(rankingService, loggerV1)
+	smsReceiptHandler := ioc.InitSmsReceiptHandler(smsProviders, smsMessageService, loggerV1)
+	engine := ioc.InitWebServer(v, userHandLer, oAuth2WechatHandLer, articleHandler, readHistoryHandler, creatorStatsHandler, likeRankHandler, rankingHandler, smsReceiptHandler)
 	interactiveDAO := dao2.NewGORMInteractiveDAO(db)
 	interactiveCache := cache2.NewInteractiveRedisCache(cmdable)
 	interactiveRepository := repository2.NewCachedInteractiveRepository(interactiveDAO, interactiveCache, loggerV1)
-	interactiveReadEventConsumer := events2.NewInteractiveReadEventConsumer(interactiveRepository, client, loggerV1)
-	v2 := ioc.InitConsumers(interactiveReadEventConsumer)
-	rankingService := service.NewBatchRankingService(interactiveServiceClient, articleService)
+	readEventFilter := ioc2.InitReadEventFilter(cmdable, loggerV1)
+	interactiveReadEventConsumer := events2.NewInteractiveReadEventConsumer(interactiveRepository, client, readEventFilter, loggerV1)
+	rankingDecay := ioc.InitRankingDecay()
+	rankingStreamCache := cache.NewRankingStreamRedisCache(cmdable, rankingDecay)
+	rankingStreamRepository := repository.NewCachedRankingStreamRepository(rankingStreamCache)
+	streamRankingService := service.NewStreamRankingService(rankingStreamRepository, rankingRepository, articleRepository, interactiveServiceClient, rankingService, readCntMode)
+	streamRankingConsumer := ioc.InitStreamRankingConsumer(streamRankingService, client, readEventFilter, loggerV1)
+	v3 := ioc.InitConsumers(interactiveReadEventConsumer, streamRankingConsumer)
 	rlockClient := ioc.InitRlockClient(cmdable)
-	rankingJob := ioc.InitRankingJob(rankingService, rlockClient, loggerV1)
-	updateLikeJob := ioc.InitLikeJob(articleService, rlockClient, loggerV1)
-	cron := ioc.InitJobs(loggerV1, rankingJob, updateLikeJob)
+	jobLoadCache := cache.NewJobLoadRedisCache(cmdable)
+	jobLoadRepository := repository.NewCachedJobLoadRepository(jobLoadCache)
+	jobLoadService := ioc.InitJobLoadService(jobLoadRepository)
+	jobLeaderFactory := ioc.InitJobLeaderFactory(rlockClient, clientv3Client, jobLoadService, loggerV1)
+	leaderJob := ioc.InitRankingJob(rankingService, jobLeaderFactory, loggerV1)
+	v4 := ioc.InitRankingBoardJobs(rankingService, jobLeaderFactory, loggerV1)
+	streamRankingJobs := ioc.InitStreamRankingJobs(streamRankingService, jobLeaderFactory, loggerV1)
+	jobExecutionDAO := dao.NewGORMJobExecutionDAO(db)
+	jobExecutionRepository := repository.NewJobExecutionRepository(jobExecutionDAO)
+	jobExecutionService := ioc.InitJobExecutionService(jobExecutionRepository)
+	jobExecutionCleanJob := ioc.InitJobExecutionCleanJob(jobExecutionService, loggerV1)
+	smsReceiptPullJob := ioc.InitSmsReceiptPullJob(smsProviders, smsMessageService, jobLeaderFactory, loggerV1)
+	jobDAO := dao.NewGORMJobDAO(db)
+	cronJobRepository := repository.NewPreemptJobRepository(jobDAO)
+	workflowDAO := dao.NewGORMWorkflowDAO(db)
+	workflowRepository := repository.NewWorkflowRepository(workflowDAO)
+	workflowService := ioc.InitWorkflowService(workflowRepository, cronJobRepository, loggerV1)
+	workflowTimeoutJob := ioc.InitWorkflowTimeoutJob(workflowService, loggerV1)
+	cronJobs := ioc.InitJobs(loggerV1, leaderJob, v4, streamRankingJobs, jobExecutionCleanJob, workflowTimeoutJob, smsReceiptPullJob)
+	localFuncExecutor := ioc.InitLocalFuncExecutor()
+	v5 := ioc.InitJobExecutors(localFuncExecutor, clientv3Client)
+	cronJobService := ioc.InitCronJobService(cronJobRepository, v5, loggerV1)
+	jobShardDAO := dao.NewGORMJobShardDAO(db)
+	jobShardRepository := repository.NewJobShardRepository(jobShardDAO)
+	jobShardService := ioc.InitJobShardService(jobShardRepository, cronJobRepository, loggerV1)
+	cronJobHandler := web.NewCronJobHandler(cronJobService, jobExecutionService, jobShardService, workflowService, loggerV1)
+	smsTemplateHandler := web.NewSmsTemplateHandler(smsTemplateService, loggerV1)
+	smsProviderHandler := web.NewSmsProviderHandler(selectorService, loggerV1)
+	smsMessageHandler := web.NewSmsMessageHandler(smsMessageService, loggerV1)
+	server := ioc.InitAdminServer(cronJobHandler, smsTemplateHandler, smsProviderHandler, smsMessageHandler, rankingHandler)
+	scheduler := ioc.InitScheduler(cronJobService, jobExecutionService, jobShardService, jobLoadService, workflowService, v5, loggerV1)
 	app := &App{
-		server:    engine,
-		consumers: v2,
-		cron:      cron,
+		server:      engine,
+		consumers:   v3,
+		cron:        cronJobs,
+		adminServer: server,
+		scheduler:   scheduler,
+		asyncSms:    asyncService,
 	}
 	return app
 }
@@ -136,6 +252,8 @@ func initViper() {
 	if err != nil {
 		panic(err)
 	}
+	//热榜算法这些配置支持热更新
+	viper.WatchConfig()
 	val := viper.Get("test.key")
 	log.Println(val)
 }
@@ -148,7 +266,7 @@ func initViperRemote() {
 		panic(err)
 	}
 	viper.SetConfigType("yaml")
-	viper.OnConfigChange(func(in fsnotify.Event) {
+	ioc.OnConfigChange(func(in fsnotify.Event) {
 		log.Println("远程配置中心发生变更")
 	})
 	go func() {
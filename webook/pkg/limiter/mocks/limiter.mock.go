@@ -3,14 +3,16 @@
 //
 // Generated by this command:
 //
-//	mockgen -source=./types.go -package=limitermocks -destination=./mocks/limiter.mock.go Limiter
+//	mockgen -source=./types.go -package=limitermocks -destination=./mocks/limiter.mock.go Limiter,EventTimeLimiter
 //
+
 // Package limitermocks is a generated GoMock package.
 package limitermocks
 
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	gomock "go.uber.org/mock/gomock"
 )
@@ -52,3 +54,56 @@ func (mr *MockLimiterMockRecorder) Limit(ctx, key any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Limit", reflect.TypeOf((*MockLimiter)(nil).Limit), ctx, key)
 }
+
+// MockEventTimeLimiter is a mock of EventTimeLimiter interface.
+type MockEventTimeLimiter struct {
+	ctrl     *gomock.Controller
+	recorder *MockEventTimeLimiterMockRecorder
+}
+
+// MockEventTimeLimiterMockRecorder is the mock recorder for MockEventTimeLimiter.
+type MockEventTimeLimiterMockRecorder struct {
+	mock *MockEventTimeLimiter
+}
+
+// NewMockEventTimeLimiter creates a new mock instance.
+func NewMockEventTimeLimiter(ctrl *gomock.Controller) *MockEventTimeLimiter {
+	mock := &MockEventTimeLimiter{ctrl: ctrl}
+	mock.recorder = &MockEventTimeLimiterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockEventTimeLimiter) EXPECT() *MockEventTimeLimiterMockRecorder {
+	return m.recorder
+}
+
+// Limit mocks base method.
+func (m *MockEventTimeLimiter) Limit(ctx context.Context, key string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Limit", ctx, key)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Limit indicates an expected call of Limit.
+func (mr *MockEventTimeLimiterMockRecorder) Limit(ctx, key any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Limit", reflect.TypeOf((*MockEventTimeLimiter)(nil).Limit), ctx, key)
+}
+
+// LimitAt mocks base method.
+func (m *MockEventTimeLimiter) LimitAt(ctx context.Context, key string, t time.Time) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LimitAt", ctx, key, t)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LimitAt indicates an expected call of LimitAt.
+func (mr *MockEventTimeLimiterMockRecorder) LimitAt(ctx, key, t any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LimitAt", reflect.TypeOf((*MockEventTimeLimiter)(nil).LimitAt), ctx, key, t)
+}
@@ -4,11 +4,17 @@ import (
 	"context"
 	_ "embed"
 	"github.com/redis/go-redis/v9"
+	"math/rand"
+	"strconv"
 	"time"
 )
 
-//go:embed slide_window.lua
-var luaScript string
+var (
+	//go:embed slide_window.lua
+	luaScript string
+	//go:embed slide_window_at.lua
+	luaScriptAt string
+)
 
 type RedisSlidingWindowLimiter struct {
 	cmd      redis.Cmdable
@@ -29,3 +35,10 @@ func (b *RedisSlidingWindowLimiter) Limit(ctx context.Context, key string) (bool
 	return b.cmd.Eval(ctx, luaScript, []string{key},
 		b.interval.Milliseconds(), b.rate, time.Now().UnixMilli()).Bool()
 }
+
+func (b *RedisSlidingWindowLimiter) LimitAt(ctx context.Context, key string, t time.Time) (bool, error) {
+	now := t.UnixMilli()
+	member := strconv.FormatInt(now, 10) + ":" + strconv.FormatInt(rand.Int63(), 10)
+	return b.cmd.Eval(ctx, luaScriptAt, []string{key},
+		b.interval.Milliseconds(), b.rate, now, member).Bool()
+}
@@ -0,0 +1,24 @@
+-- 按照事件时间的滑动窗口，事件可能乱序到达，所以只统计 (t - window, t] 里面的事件
+
+-- 限流对象
+local key = KEYS[1]
+-- 窗口大小
+local window = tonumber(ARGV[1])
+-- 阈值
+local threshold = tonumber(ARGV[2])
+-- 事件发生的时间
+local t = tonumber(ARGV[3])
+-- 事件的唯一标识，同一毫秒的几个事件不能合并成一个
+local member = ARGV[4]
+
+-- 多留一个窗口给迟到的事件
+redis.call('ZREMRANGEBYSCORE', key, '-inf', t - 2 * window)
+local cnt = redis.call('ZCOUNT', key, '(' .. (t - window), t)
+if cnt >= threshold then
+    -- 执行限流
+    return "true"
+else
+    redis.call('ZADD', key, t, member)
+    redis.call('PEXPIRE', key, 2 * window)
+    return "false"
+end
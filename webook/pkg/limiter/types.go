@@ -1,10 +1,21 @@
 package limiter
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
-//go:generate mockgen -source=./types.go -package=limitermocks -destination=./mocks/limiter.mock.go Limiter
+//go:generate mockgen -source=./types.go -package=limitermocks -destination=./mocks/limiter.mock.go Limiter,EventTimeLimiter
 type Limiter interface {
 	// Limit 是否触发限流
 	// 返回 true，就是触发限流
 	Limit(ctx context.Context, key string) (bool, error)
 }
+
+// EventTimeLimiter 按照事件发生的时间限流，而不是判断的时间。
+// 消费积压之后再消费，一下子涌进来的事件不会被当成同一个窗口里面的
+type EventTimeLimiter interface {
+	Limiter
+	// LimitAt 判断 t 这个时刻发生的事件是否触发限流
+	LimitAt(ctx context.Context, key string, t time.Time) (bool, error)
+}
@@ -6,6 +6,8 @@ import (
 	"xiaoweishu/webook/pkg/logger"
 )
 
+//go:generate mockgen -package=saramamocks -destination=mocks/sarama.mock.go github.com/IBM/sarama ConsumerGroupSession,ConsumerGroupClaim
+
 type Handler[T any] struct {
 	l  logger.LoggerV1
 	fn func(msg *sarama.ConsumerMessage, event T) error
@@ -5,6 +5,7 @@ package main
 import (
 	"github.com/google/wire"
 	"xiaoweishu/webook/interactive/events"
+	ioc2 "xiaoweishu/webook/interactive/ioc"
 	repository2 "xiaoweishu/webook/interactive/repository"
 	cache2 "xiaoweishu/webook/interactive/repository/cache"
 	dao2 "xiaoweishu/webook/interactive/repository/dao"
@@ -27,8 +28,18 @@ var interactiveSvcSet = wire.NewSet(dao2.NewGORMInteractiveDAO,
 
 var rankingSvcSet = wire.NewSet(
 	cache.NewRankingRedisCache,
-	repository.NewCachedRankingRepository,
-	service.NewBatchRankingService,
+	cache.NewRankingLocalCacheV1,
+	ioc.InitRankingRepository,
+	cache.NewRankingBoardRedisCache,
+	cache.NewRankingBoardLocalCache,
+	repository.NewCachedRankingBoardRepository,
+	ioc.InitRankingBoards,
+	ioc.InitRankingService,
+	ioc.InitRankingReadCntMode,
+	ioc.InitRankingDecay,
+	cache.NewRankingStreamRedisCache,
+	repository.NewCachedRankingStreamRepository,
+	service.NewStreamRankingService,
 )
 
 func InitWebServer() *App {
@@ -45,14 +56,67 @@ func InitWebServer() *App {
 		dao.NewArticleGORMDAO,
 
 		interactiveSvcSet,
+		ioc.InitIntrConn,
 		ioc.InitIntrClientV1,
+		ioc.InitCommentClient,
+		ioc.InitReadHistoryClient,
+		ioc.InitStatsClient,
+		ioc.InitLikeRankClient,
 		rankingSvcSet,
 		ioc.InitJobs,
+		ioc.InitJobLeaderFactory,
 		ioc.InitRankingJob,
-		ioc.InitLikeJob,
+		ioc.InitRankingBoardJobs,
+		ioc.InitStreamRankingJobs,
+
+		// 分布式任务调度
+		dao.NewGORMJobDAO,
+		repository.NewPreemptJobRepository,
+		ioc.InitLocalFuncExecutor,
+		ioc.InitJobExecutors,
+		ioc.InitCronJobService,
+		ioc.InitScheduler,
+		dao.NewGORMJobExecutionDAO,
+		repository.NewJobExecutionRepository,
+		ioc.InitJobExecutionService,
+		ioc.InitJobExecutionCleanJob,
+		dao.NewGORMJobShardDAO,
+		repository.NewJobShardRepository,
+		ioc.InitJobShardService,
+		cache.NewJobLoadRedisCache,
+		repository.NewCachedJobLoadRepository,
+		ioc.InitJobLoadService,
+		dao.NewGORMWorkflowDAO,
+		repository.NewWorkflowRepository,
+		ioc.InitWorkflowService,
+		ioc.InitWorkflowTimeoutJob,
+		web.NewCronJobHandler,
+		ioc.InitAdminServer,
+
+		// 短信模板
+		dao.NewGORMSmsTemplateDAO,
+		repository.NewSmsTemplateRepository,
+		ioc.InitSmsTemplateService,
+		web.NewSmsTemplateHandler,
+		ioc.InitSmsSelector,
+		web.NewSmsProviderHandler,
+		dao.NewGORMAsyncSmsDAO,
+		repository.NewAsyncSMSRepository,
+		ioc.InitAsyncSmsService,
+
+		// 短信回执
+		dao.NewGORMSmsMessageDAO,
+		repository.NewSmsMessageRepository,
+		ioc.InitSmsMessageService,
+		ioc.InitSmsProviders,
+		ioc.InitSmsReceiptHandler,
+		ioc.InitSmsReceiptPullJob,
+		web.NewSmsMessageHandler,
 
 		article.NewSaramaSyncProducer,
+		ioc2.InitReadEventFilter,
 		events.NewInteractiveReadEventConsumer,
+		ioc.InitStreamRankingConsumer,
 		ioc.InitConsumers,
 
 		// cache 部分
@@ -74,6 +138,10 @@ func InitWebServer() *App {
 		// handler 部分
 		web.NewUserHandLer,
 		web.NewArticleHandler,
+		web.NewReadHistoryHandler,
+		web.NewCreatorStatsHandler,
+		web.NewLikeRankHandler,
+		web.NewRankingHandler,
 		ijwt.NewRedisJWTHandler,
 		web.NewOAuth2WechatHandler,
 		ioc.InitGinMiddlewares,
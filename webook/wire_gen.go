@@ -9,6 +9,7 @@ package main
 import (
 	"github.com/google/wire"
 	"xiaoweishu/webook/interactive/events"
+	ioc2 "xiaoweishu/webook/interactive/ioc"
 	repository2 "xiaoweishu/webook/interactive/repository"
 	cache2 "xiaoweishu/webook/interactive/repository/cache"
 	dao2 "xiaoweishu/webook/interactive/repository/dao"
@@ -37,7 +38,18 @@ func InitWebServer() *App {
 	userService := service.NewUserService(userRepository)
 	codeCache := cache.NewCodeCache(cmdable)
 	codeRepository := repository.NewCodeRepository(codeCache)
-	smsService := ioc.InitSMSService()
+	smsTemplateDAO := dao.NewGORMSmsTemplateDAO(db)
+	smsTemplateRepository := repository.NewSmsTemplateRepository(smsTemplateDAO)
+	smsTemplateService := ioc.InitSmsTemplateService(smsTemplateRepository)
+	smsMessageDAO := dao.NewGORMSmsMessageDAO(db)
+	smsMessageRepository := repository.NewSmsMessageRepository(smsMessageDAO)
+	smsMessageService := ioc.InitSmsMessageService(smsMessageRepository, loggerV1)
+	smsProviders := ioc.InitSmsProviders(smsTemplateService, smsMessageService, loggerV1)
+	selectorService := ioc.InitSmsSelector(smsProviders, loggerV1)
+	asyncSmsDAO := dao.NewGORMAsyncSmsDAO(db)
+	asyncSmsRepository := repository.NewAsyncSMSRepository(asyncSmsDAO)
+	asyncService := ioc.InitAsyncSmsService(selectorService, asyncSmsRepository, loggerV1)
+	smsService := ioc.InitSMSService(asyncService)
 	codeSerVice := service.NewCodeService(codeRepository, smsService)
 	userHandLer := web.NewUserHandLer(userService, codeSerVice, handler)
 	wechatService := ioc.InitWechatService(loggerV1)
@@ -50,23 +62,78 @@ func InitWebServer() *App {
 	producer := article.NewSaramaSyncProducer(syncProducer)
 	articleService := service.NewArticleService(articleRepository, producer, loggerV1)
 	clientv3Client := ioc.InitEtcd()
-	interactiveServiceClient := ioc.InitIntrClientV1(clientv3Client)
+	intrConn := ioc.InitIntrConn(clientv3Client)
+	interactiveServiceClient := ioc.InitIntrClientV1(intrConn)
 	articleHandler := web.NewArticleHandler(loggerV1, articleService, interactiveServiceClient)
-	engine := ioc.InitWebServer(v, userHandLer, oAuth2WechatHandLer, articleHandler)
+	readHistoryServiceClient := ioc.InitReadHistoryClient(intrConn)
+	readHistoryHandler := web.NewReadHistoryHandler(readHistoryServiceClient, loggerV1)
+	interactiveStatsServiceClient := ioc.InitStatsClient(intrConn)
+	creatorStatsHandler := web.NewCreatorStatsHandler(interactiveStatsServiceClient, articleService, loggerV1)
+	likeRankServiceClient := ioc.InitLikeRankClient(intrConn)
+	likeRankHandler := web.NewLikeRankHandler(likeRankServiceClient, loggerV1)
+	readCntMode := ioc.InitRankingReadCntMode()
+	rankingCache := cache.NewRankingRedisCache(cmdable)
+	rankingLocalCache := cache.NewRankingLocalCacheV1()
+	rankingRepository := ioc.InitRankingRepository(rankingCache, rankingLocalCache)
+	rankingBoardRedisCache := cache.NewRankingBoardRedisCache(cmdable)
+	rankingBoardLocalCache := cache.NewRankingBoardLocalCache()
+	rankingBoardRepository := repository.NewCachedRankingBoardRepository(rankingBoardRedisCache, rankingBoardLocalCache)
+	v2 := ioc.InitRankingBoards()
+	commentServiceClient := ioc.InitCommentClient(clientv3Client)
+	rankingService := ioc.InitRankingService(interactiveServiceClient, commentServiceClient, articleService, rankingRepository, rankingBoardRepository, readCntMode, v2, loggerV1)
+	rankingHandler := web.NewRankingHandler(rankingService, loggerV1)
+	smsReceiptHandler := ioc.InitSmsReceiptHandler(smsProviders, smsMessageService, loggerV1)
+	engine := ioc.InitWebServer(v, userHandLer, oAuth2WechatHandLer, articleHandler, readHistoryHandler, creatorStatsHandler, likeRankHandler, rankingHandler, smsReceiptHandler)
 	interactiveDAO := dao2.NewGORMInteractiveDAO(db)
 	interactiveCache := cache2.NewInteractiveRedisCache(cmdable)
 	interactiveRepository := repository2.NewCachedInteractiveRepository(interactiveDAO, interactiveCache, loggerV1)
-	interactiveReadEventConsumer := events.NewInteractiveReadEventConsumer(interactiveRepository, client, loggerV1)
-	v2 := ioc.InitConsumers(interactiveReadEventConsumer)
-	rankingService := service.NewBatchRankingService(interactiveServiceClient, articleService)
+	readEventFilter := ioc2.InitReadEventFilter(cmdable, loggerV1)
+	interactiveReadEventConsumer := events.NewInteractiveReadEventConsumer(interactiveRepository, client, readEventFilter, loggerV1)
+	rankingDecay := ioc.InitRankingDecay()
+	rankingStreamCache := cache.NewRankingStreamRedisCache(cmdable, rankingDecay)
+	rankingStreamRepository := repository.NewCachedRankingStreamRepository(rankingStreamCache)
+	streamRankingService := service.NewStreamRankingService(rankingStreamRepository, rankingRepository, articleRepository, interactiveServiceClient, rankingService, readCntMode)
+	streamRankingConsumer := ioc.InitStreamRankingConsumer(streamRankingService, client, readEventFilter, loggerV1)
+	v3 := ioc.InitConsumers(interactiveReadEventConsumer, streamRankingConsumer)
 	rlockClient := ioc.InitRlockClient(cmdable)
-	rankingJob := ioc.InitRankingJob(rankingService, rlockClient, loggerV1)
-	updateLikeJob := ioc.InitLikeJob(articleService, rlockClient, loggerV1)
-	cron := ioc.InitJobs(loggerV1, rankingJob, updateLikeJob)
+	jobLoadCache := cache.NewJobLoadRedisCache(cmdable)
+	jobLoadRepository := repository.NewCachedJobLoadRepository(jobLoadCache)
+	jobLoadService := ioc.InitJobLoadService(jobLoadRepository)
+	jobLeaderFactory := ioc.InitJobLeaderFactory(rlockClient, clientv3Client, jobLoadService, loggerV1)
+	leaderJob := ioc.InitRankingJob(rankingService, jobLeaderFactory, loggerV1)
+	v4 := ioc.InitRankingBoardJobs(rankingService, jobLeaderFactory, loggerV1)
+	streamRankingJobs := ioc.InitStreamRankingJobs(streamRankingService, jobLeaderFactory, loggerV1)
+	jobExecutionDAO := dao.NewGORMJobExecutionDAO(db)
+	jobExecutionRepository := repository.NewJobExecutionRepository(jobExecutionDAO)
+	jobExecutionService := ioc.InitJobExecutionService(jobExecutionRepository)
+	jobExecutionCleanJob := ioc.InitJobExecutionCleanJob(jobExecutionService, loggerV1)
+	smsReceiptPullJob := ioc.InitSmsReceiptPullJob(smsProviders, smsMessageService, jobLeaderFactory, loggerV1)
+	jobDAO := dao.NewGORMJobDAO(db)
+	cronJobRepository := repository.NewPreemptJobRepository(jobDAO)
+	workflowDAO := dao.NewGORMWorkflowDAO(db)
+	workflowRepository := repository.NewWorkflowRepository(workflowDAO)
+	workflowService := ioc.InitWorkflowService(workflowRepository, cronJobRepository, loggerV1)
+	workflowTimeoutJob := ioc.InitWorkflowTimeoutJob(workflowService, loggerV1)
+	cronJobs := ioc.InitJobs(loggerV1, leaderJob, v4, streamRankingJobs, jobExecutionCleanJob, workflowTimeoutJob, smsReceiptPullJob)
+	localFuncExecutor := ioc.InitLocalFuncExecutor()
+	v5 := ioc.InitJobExecutors(localFuncExecutor, clientv3Client)
+	cronJobService := ioc.InitCronJobService(cronJobRepository, v5, loggerV1)
+	jobShardDAO := dao.NewGORMJobShardDAO(db)
+	jobShardRepository := repository.NewJobShardRepository(jobShardDAO)
+	jobShardService := ioc.InitJobShardService(jobShardRepository, cronJobRepository, loggerV1)
+	cronJobHandler := web.NewCronJobHandler(cronJobService, jobExecutionService, jobShardService, workflowService, loggerV1)
+	smsTemplateHandler := web.NewSmsTemplateHandler(smsTemplateService, loggerV1)
+	smsProviderHandler := web.NewSmsProviderHandler(selectorService, loggerV1)
+	smsMessageHandler := web.NewSmsMessageHandler(smsMessageService, loggerV1)
+	server := ioc.InitAdminServer(cronJobHandler, smsTemplateHandler, smsProviderHandler, smsMessageHandler, rankingHandler)
+	scheduler := ioc.InitScheduler(cronJobService, jobExecutionService, jobShardService, jobLoadService, workflowService, v5, loggerV1)
 	app := &App{
-		server:    engine,
-		consumers: v2,
-		cron:      cron,
+		server:      engine,
+		consumers:   v3,
+		cron:        cronJobs,
+		adminServer: server,
+		scheduler:   scheduler,
+		asyncSms:    asyncService,
 	}
 	return app
 }
@@ -75,4 +142,4 @@ func InitWebServer() *App {
 
 var interactiveSvcSet = wire.NewSet(dao2.NewGORMInteractiveDAO, cache2.NewInteractiveRedisCache, repository2.NewCachedInteractiveRepository, service2.NewInteractiveService)
 
-var rankingSvcSet = wire.NewSet(cache.NewRankingRedisCache, repository.NewCachedRankingRepository, service.NewBatchRankingService)
+var rankingSvcSet = wire.NewSet(cache.NewRankingRedisCache, cache.NewRankingLocalCacheV1, ioc.InitRankingRepository, cache.NewRankingBoardRedisCache, cache.NewRankingBoardLocalCache, repository.NewCachedRankingBoardRepository, ioc.InitRankingBoards, ioc.InitRankingService, ioc.InitRankingReadCntMode, ioc.InitRankingDecay, cache.NewRankingStreamRedisCache, repository.NewCachedRankingStreamRepository, service.NewStreamRankingService)